@@ -0,0 +1,140 @@
+// ABOUTME: Windowed rollup of records matched by transform aggregation rules.
+// ABOUTME: Collects each group per app and emits one record carrying the count when the window ends.
+
+package aggregate
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+
+	"otlp-mock-receiver/transform"
+)
+
+// Rollup stands in for every record collected by one group during its window
+type Rollup struct {
+	Resource *resourcepb.Resource
+	Scope    *commonpb.InstrumentationScope
+	Log      *logspb.LogRecord // Copy of the first record, tagged with the rule's count attribute
+	Rule     string
+	Count    int // Records collected, including the first
+}
+
+// group tracks one rule/app/group-by combination within its window
+type group struct {
+	rollup Rollup
+	rule   *transform.AggregateRule
+	first  time.Time
+}
+
+// groupKey identifies a group; the app is always part of it so rollups keep their resource
+type groupKey struct {
+	rule, app, values string
+}
+
+// Aggregator holds records collected by aggregation rules until their window ends
+type Aggregator struct {
+	Rules []*transform.AggregateRule
+
+	mu     sync.Mutex
+	groups map[groupKey]*group
+	ready  []Rollup // Rollups of windows closed by a later record, awaiting Expired
+}
+
+// New creates an aggregator for the given rules
+func New(rules []*transform.AggregateRule) *Aggregator {
+	return &Aggregator{Rules: rules, groups: make(map[groupKey]*group)}
+}
+
+// Add collects the record into the group of the first matching rule and returns
+// that rule, or nil if no rule matches and the record should continue on
+func (a *Aggregator) Add(resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, app string, lr *logspb.LogRecord) *transform.AggregateRule {
+	return a.add(resource, scope, app, lr, time.Now())
+}
+
+func (a *Aggregator) add(resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, app string, lr *logspb.LogRecord, now time.Time) *transform.AggregateRule {
+	rule := a.match(resource, lr)
+	if rule == nil {
+		return nil
+	}
+	key := groupKey{rule: rule.Name, app: app, values: rule.GroupKey(lr)}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if g, ok := a.groups[key]; ok {
+		if now.Sub(g.first) <= rule.Window {
+			g.rollup.Count++
+			return rule
+		}
+		// The window ended before the flusher saw it: close it and start a new one
+		a.ready = append(a.ready, g.finish())
+	}
+	a.groups[key] = &group{
+		rollup: Rollup{Resource: resource, Scope: scope, Log: proto.Clone(lr).(*logspb.LogRecord), Rule: rule.Name, Count: 1},
+		rule:   rule,
+		first:  now,
+	}
+	return rule
+}
+
+// match returns the first rule collecting the record
+func (a *Aggregator) match(resource *resourcepb.Resource, lr *logspb.LogRecord) *transform.AggregateRule {
+	for _, rule := range a.Rules {
+		if rule.Matches(resource, lr) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Expired returns rollups for groups whose window has ended
+func (a *Aggregator) Expired() []Rollup {
+	return a.expired(time.Now())
+}
+
+func (a *Aggregator) expired(now time.Time) []Rollup {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.drain(func(g *group) bool { return now.Sub(g.first) > g.rule.Window })
+}
+
+// Flush returns rollups for every open group, e.g. at shutdown
+func (a *Aggregator) Flush() []Rollup {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.drain(func(*group) bool { return true })
+}
+
+// drain removes groups selected by done and returns their rollups, oldest
+// first. Caller holds the lock.
+func (a *Aggregator) drain(done func(*group) bool) []Rollup {
+	var finished []*group
+	for key, g := range a.groups {
+		if done(g) {
+			delete(a.groups, key)
+			finished = append(finished, g)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].first.Before(finished[j].first) })
+
+	rollups := a.ready
+	a.ready = nil
+	for _, g := range finished {
+		rollups = append(rollups, g.finish())
+	}
+	return rollups
+}
+
+// finish tags the rollup record with the collected count
+func (g *group) finish() Rollup {
+	r := g.rollup
+	transform.SetAttribute(r.Log, g.rule.CountAttribute, strconv.Itoa(r.Count))
+	return r
+}
@@ -0,0 +1,115 @@
+// ABOUTME: Tests for windowed aggregation.
+// ABOUTME: Covers grouping, per-app separation, rule order, and window rollover.
+
+package aggregate
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/transform"
+)
+
+func record(body string) *logspb.LogRecord {
+	return &logspb.LogRecord{Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}}}
+}
+
+func attr(lr *logspb.LogRecord, key string) string {
+	for _, kv := range lr.GetAttributes() {
+		if kv.GetKey() == key {
+			return kv.GetValue().GetStringValue()
+		}
+	}
+	return ""
+}
+
+func notFoundRule() *transform.AggregateRule {
+	return &transform.AggregateRule{
+		Name:           "not-found",
+		Body:           regexp.MustCompile(`" 404 `),
+		GroupBy:        []string{"body"},
+		Window:         time.Second,
+		CountAttribute: transform.DefaultAggregateCountAttribute,
+	}
+}
+
+func TestAggregator_RollsUpGroup(t *testing.T) {
+	a := New([]*transform.AggregateRule{notFoundRule()})
+	now := time.Now()
+	line := `"GET /missing HTTP/1.1" 404 0`
+
+	for i := 0; i < 4; i++ {
+		if a.add(nil, nil, "app", record(line), now.Add(time.Duration(i)*100*time.Millisecond)) == nil {
+			t.Fatalf("record %d should be collected", i)
+		}
+	}
+	if a.add(nil, nil, "app", record(`"GET / HTTP/1.1" 200 12`), now) != nil {
+		t.Error("non-matching record should pass")
+	}
+	a.add(nil, nil, "other-app", record(line), now)
+
+	if got := a.expired(now.Add(500 * time.Millisecond)); len(got) != 0 {
+		t.Fatalf("window still open, got %d rollups", len(got))
+	}
+	rollups := a.expired(now.Add(2 * time.Second))
+	if len(rollups) != 2 {
+		t.Fatalf("expected 2 rollups (one per app), got %d", len(rollups))
+	}
+	counts := map[string]string{}
+	for _, r := range rollups {
+		counts[r.Rule+"/"+attr(r.Log, "aggregate_count")] = r.Log.GetBody().GetStringValue()
+	}
+	if counts["not-found/4"] != line || counts["not-found/1"] != line {
+		t.Errorf("unexpected rollups: %v", counts)
+	}
+}
+
+func TestAggregator_GroupByAttribute(t *testing.T) {
+	rule := &transform.AggregateRule{
+		Name:           "by-status",
+		When:           &transform.Match{Attributes: map[string]*regexp.Regexp{"status": regexp.MustCompile(`^5`)}},
+		GroupBy:        []string{"status"},
+		Window:         time.Second,
+		CountAttribute: "hits",
+	}
+	a := New([]*transform.AggregateRule{rule})
+	now := time.Now()
+
+	for _, status := range []string{"500", "503", "500"} {
+		lr := record("upstream failed at " + status)
+		transform.SetAttribute(lr, "status", status)
+		a.add(nil, nil, "app", lr, now)
+	}
+
+	counts := map[string]string{}
+	for _, r := range a.Flush() {
+		counts[attr(r.Log, "status")] = attr(r.Log, "hits")
+	}
+	if counts["500"] != "2" || counts["503"] != "1" {
+		t.Errorf("counts by status = %v, want 500:2 503:1", counts)
+	}
+}
+
+func TestAggregator_WindowRolloverBeforeFlush(t *testing.T) {
+	a := New([]*transform.AggregateRule{notFoundRule()})
+	now := time.Now()
+	line := `"GET /x HTTP/1.1" 404 0`
+
+	a.add(nil, nil, "app", record(line), now)
+	a.add(nil, nil, "app", record(line), now.Add(500*time.Millisecond))
+	// Arrives after the window closed but before the flusher ran: starts a new window
+	a.add(nil, nil, "app", record(line), now.Add(1500*time.Millisecond))
+
+	rollups := a.expired(now.Add(1600 * time.Millisecond))
+	if len(rollups) != 1 || rollups[0].Count != 2 {
+		t.Fatalf("expected the closed window with 2 records, got %+v", rollups)
+	}
+	rollups = a.Flush()
+	if len(rollups) != 1 || rollups[0].Count != 1 {
+		t.Errorf("expected the new window with 1 record, got %+v", rollups)
+	}
+}
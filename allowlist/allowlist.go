@@ -1,49 +1,130 @@
-// ABOUTME: App allowlist filtering with file loading and hot-reload.
-// ABOUTME: Filters logs based on application name against a configurable list.
+// ABOUTME: App allowlist and denylist filtering with file loading.
+// ABOUTME: Filters logs based on application name against configurable allow and deny lists.
 
 package allowlist
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/fsnotify/fsnotify"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
 )
 
-// Allowlist manages a list of allowed application names
+// Allowlist manages lists of allowed and denied application names. A denied
+// app is dropped even if it is also allowed.
 type Allowlist struct {
-	mu   sync.RWMutex
-	apps map[string]bool // lowercase app names for case-insensitive matching
+	mu       sync.RWMutex
+	entries  []*AppEntry // The allowed apps as listed, which apps and policies are built from
+	groups   []*Group    // Policy groups as listed, also built into apps and policies
+	apps     appSet      // Case-insensitive names and patterns
+	denied   appSet      // Apps dropped regardless of apps
+	policies []appPolicy // Per-app policy from a YAML allowlist, in lookup order
+	path     string      // Edits are saved here if set
+
+	pollInterval time.Duration    // How often to check files fsnotify cannot watch
+	metrics      *metrics.Metrics // Reports the watch mode and reloads, if set
 }
 
-// NewAllowlist creates an allowlist from a slice of app names
+// NewAllowlist creates an allowlist from a slice of app names, globs, and
+// /regex/ entries; invalid regexes are skipped
 func NewAllowlist(apps []string) *Allowlist {
-	al := &Allowlist{
-		apps: make(map[string]bool),
-	}
-	for _, app := range apps {
-		trimmed := strings.TrimSpace(app)
-		if trimmed != "" {
-			al.apps[strings.ToLower(trimmed)] = true
-		}
-	}
-	return al
+	set, _ := newAppSet(apps)
+	return &Allowlist{entries: plainEntries(apps), apps: set}
+}
+
+// NewDenylist creates a list that allows every app except the given ones
+func NewDenylist(apps []string) *Allowlist {
+	denied, _ := newAppSet(apps)
+	return &Allowlist{denied: denied}
 }
 
 // LoadFromFile loads an allowlist from a file.
-// File format: one app name per line, lines starting with # are comments.
+// File format: one entry per line, lines starting with # are comments. An
+// entry is an app name, a glob like payment-*, or a regex like /^team-a-.*$/.
+// A .yaml or .yml file is read as a FileConfig instead, with per-app policy.
 func LoadFromFile(path string) (*Allowlist, error) {
-	file, err := os.Open(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	al, err := parse(data, isYAML(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return al, nil
+}
+
+// parse builds an allowlist from a YAML FileConfig or a plain list
+func parse(data []byte, yamlFormat bool) (*Allowlist, error) {
+	if yamlFormat {
+		fc, err := ParseConfig(data)
+		if err != nil {
+			return nil, err
+		}
+		return fc.Build()
+	}
+	apps, err := parseEntries(data)
+	if err != nil {
+		return nil, err
+	}
+	set, err := newAppSet(apps)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return &Allowlist{entries: plainEntries(apps), apps: set}, nil
+}
+
+// isYAML reports whether path names a YAML allowlist rather than a plain list
+func isYAML(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// LoadDenylist replaces the denied apps with those in a file, in the same
+// format as LoadFromFile
+func (al *Allowlist) LoadDenylist(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	apps, err := parseEntries(data)
+	if err != nil {
+		return err
+	}
+	denied, err := newAppSet(apps)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	al.mu.Lock()
+	al.denied = denied
+	al.mu.Unlock()
+	return nil
+}
+
+// SetDenied replaces the denied apps; invalid regexes are skipped
+func (al *Allowlist) SetDenied(apps []string) {
+	denied, _ := newAppSet(apps)
+	al.mu.Lock()
+	al.denied = denied
+	al.mu.Unlock()
+}
 
+// parseEntries reads list entries, one per line, skipping blank lines and #
+// comments
+func parseEntries(data []byte) ([]string, error) {
 	var apps []string
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		// Skip empty lines and comments
@@ -56,103 +137,101 @@ func LoadFromFile(path string) (*Allowlist, error) {
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	return apps, nil
+}
 
-	return NewAllowlist(apps), nil
+// plainEntries lists entries without options, skipping blank and invalid ones
+func plainEntries(apps []string) []*AppEntry {
+	var entries []*AppEntry
+	for _, app := range apps {
+		app = strings.TrimSpace(app)
+		if app == "" {
+			continue
+		}
+		if _, err := compileEntry(app); err == nil {
+			entries = append(entries, &AppEntry{App: app})
+		}
+	}
+	return entries
+}
+
+// IsAllowed checks if a log record's app passes the lists. The app name
+// (cf_app_name or application_name), and the org and space for scoped entries
+// (cf_org_name and cf_space_name), come from the record, or else its resource.
+// See IsAppAllowed.
+func (al *Allowlist) IsAllowed(resource *resourcepb.Resource, lr *logspb.LogRecord) bool {
+	return al.allows(recordApp(resource, lr))
 }
 
-// IsAllowed checks if a log record's app is in the allowlist.
-// Returns true if the allowlist is empty (allow all) or if the app is in the list.
-func (al *Allowlist) IsAllowed(lr *logspb.LogRecord) bool {
+// IsDenied reports whether a log record's app is on the denylist
+func (al *Allowlist) IsDenied(resource *resourcepb.Resource, lr *logspb.LogRecord) bool {
 	al.mu.RLock()
 	defer al.mu.RUnlock()
 
-	// Empty allowlist means allow all
-	if len(al.apps) == 0 {
-		return true
-	}
+	return al.denied.contains(recordApp(resource, lr))
+}
 
-	appName := getAttributeValue(lr, "cf_app_name")
-	if appName == "" {
-		appName = getAttributeValue(lr, "application_name")
-	}
+// IsAppAllowed checks an app name directly against the lists.
+// Returns false if the app is denied; otherwise true if the allowlist is
+// empty (allow all) or if the app is in the list. With no org or space to
+// go on, scoped entries only match if their org and space are wildcards.
+func (al *Allowlist) IsAppAllowed(appName string) bool {
+	return al.allows(appID{app: appName})
+}
 
-	return al.apps[strings.ToLower(appName)]
+// IsAppDenied reports whether an app is on the denylist
+func (al *Allowlist) IsAppDenied(appName string) bool {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+
+	return al.denied.contains(appID{app: appName})
 }
 
-// Apps returns a copy of the current allowed apps list
-func (al *Allowlist) Apps() []string {
+// allows reports whether an app is not denied and is allowed or there is no allowlist
+func (al *Allowlist) allows(id appID) bool {
 	al.mu.RLock()
 	defer al.mu.RUnlock()
 
-	apps := make([]string, 0, len(al.apps))
-	for app := range al.apps {
-		apps = append(apps, app)
+	if al.denied.contains(id) {
+		return false
 	}
-	return apps
+	return al.apps.empty() || al.apps.contains(id)
 }
 
-// WatchFile watches the allowlist file for changes and reloads when modified.
-// Runs until stop channel is closed. Accepts optional channels:
-//   - reloaded: signals after each successful reload
-//   - ready: signals when watcher is initialized and listening
-func (al *Allowlist) WatchFile(path string, stop <-chan struct{}, reloaded chan<- struct{}, ready chan<- struct{}) {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return
-	}
-	defer watcher.Close()
+// Apps returns a copy of the current allowed apps list, patterns included
+func (al *Allowlist) Apps() []string {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
 
-	if err := watcher.Add(path); err != nil {
-		return
-	}
+	return al.apps.entries()
+}
 
-	// Signal that watcher is ready
-	if ready != nil {
-		close(ready)
-	}
+// Denied returns a copy of the current denied apps list, patterns included
+func (al *Allowlist) Denied() []string {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
 
-	for {
-		select {
-		case <-stop:
-			return
-		case event, ok := <-watcher.Events:
-			if !ok {
-				return
-			}
-			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
-				al.reload(path)
-				if reloaded != nil {
-					select {
-					case reloaded <- struct{}{}:
-					default:
-					}
-				}
-			}
-		case _, ok := <-watcher.Errors:
-			if !ok {
-				return
-			}
-		}
-	}
+	return al.denied.entries()
 }
 
-// reload reads the file and updates the allowlist
-func (al *Allowlist) reload(path string) {
-	newList, err := LoadFromFile(path)
-	if err != nil {
-		return // Keep existing list on error
+// recordApp identifies a log record's app, org, and space from its attributes,
+// or else its resource's, where TAS puts them under their unrenamed names:
+// application_name, organization_name, and space_name
+func recordApp(resource *resourcepb.Resource, lr *logspb.LogRecord) appID {
+	return appID{
+		org:   findAttribute(resource, lr, "cf_org_name", "organization_name"),
+		space: findAttribute(resource, lr, "cf_space_name", "space_name"),
+		app:   findAttribute(resource, lr, "cf_app_name", "application_name"),
 	}
-
-	al.mu.Lock()
-	al.apps = newList.apps
-	al.mu.Unlock()
 }
 
-// getAttributeValue retrieves a string attribute value by key
-func getAttributeValue(lr *logspb.LogRecord, key string) string {
-	for _, attr := range lr.GetAttributes() {
-		if attr.GetKey() == key {
-			return attr.GetValue().GetStringValue()
+// findAttribute returns the first of keys found on the record, then on the resource
+func findAttribute(resource *resourcepb.Resource, lr *logspb.LogRecord, keys ...string) string {
+	for _, attrs := range [][]*commonpb.KeyValue{lr.GetAttributes(), resource.GetAttributes()} {
+		for _, attr := range attrs {
+			if slices.Contains(keys, attr.GetKey()) {
+				return attr.GetValue().GetStringValue()
+			}
 		}
 	}
 	return ""
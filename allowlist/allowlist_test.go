@@ -1,16 +1,22 @@
 // ABOUTME: Tests for app allowlist filtering.
-// ABOUTME: Covers file loading, comments, case-insensitivity, and hot-reload.
+// ABOUTME: Covers file loading, comments, case-insensitivity, denylists, and hot-reload after atomic saves.
 
 package allowlist
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
 )
 
 // Helper to create a log record with app name
@@ -30,24 +36,38 @@ func makeLogRecord(appName string) *logspb.LogRecord {
 func TestAllowlist_SpecificAppsOnly(t *testing.T) {
 	al := NewAllowlist([]string{"my-app", "other-app"})
 
-	if !al.IsAllowed(makeLogRecord("my-app")) {
+	if !al.IsAllowed(nil, makeLogRecord("my-app")) {
 		t.Error("my-app should be allowed")
 	}
-	if !al.IsAllowed(makeLogRecord("other-app")) {
+	if !al.IsAllowed(nil, makeLogRecord("other-app")) {
 		t.Error("other-app should be allowed")
 	}
-	if al.IsAllowed(makeLogRecord("unknown-app")) {
+	if al.IsAllowed(nil, makeLogRecord("unknown-app")) {
 		t.Error("unknown-app should NOT be allowed")
 	}
 }
 
+func TestAllowlist_IsAppAllowed(t *testing.T) {
+	al := NewAllowlist([]string{"My-App"})
+
+	if !al.IsAppAllowed("my-app") {
+		t.Error("my-app should be allowed")
+	}
+	if al.IsAppAllowed("other-app") {
+		t.Error("other-app should NOT be allowed")
+	}
+	if !NewAllowlist(nil).IsAppAllowed("any-app") {
+		t.Error("empty allowlist should allow all apps")
+	}
+}
+
 func TestAllowlist_EmptyAllowsAll(t *testing.T) {
 	al := NewAllowlist([]string{})
 
-	if !al.IsAllowed(makeLogRecord("any-app")) {
+	if !al.IsAllowed(nil, makeLogRecord("any-app")) {
 		t.Error("empty allowlist should allow all apps")
 	}
-	if !al.IsAllowed(makeLogRecord("another-app")) {
+	if !al.IsAllowed(nil, makeLogRecord("another-app")) {
 		t.Error("empty allowlist should allow all apps")
 	}
 }
@@ -55,7 +75,7 @@ func TestAllowlist_EmptyAllowsAll(t *testing.T) {
 func TestAllowlist_NilAllowsAll(t *testing.T) {
 	al := NewAllowlist(nil)
 
-	if !al.IsAllowed(makeLogRecord("any-app")) {
+	if !al.IsAllowed(nil, makeLogRecord("any-app")) {
 		t.Error("nil allowlist should allow all apps")
 	}
 }
@@ -63,13 +83,13 @@ func TestAllowlist_NilAllowsAll(t *testing.T) {
 func TestAllowlist_CaseInsensitive(t *testing.T) {
 	al := NewAllowlist([]string{"My-App"})
 
-	if !al.IsAllowed(makeLogRecord("my-app")) {
+	if !al.IsAllowed(nil, makeLogRecord("my-app")) {
 		t.Error("should match lowercase")
 	}
-	if !al.IsAllowed(makeLogRecord("MY-APP")) {
+	if !al.IsAllowed(nil, makeLogRecord("MY-APP")) {
 		t.Error("should match uppercase")
 	}
-	if !al.IsAllowed(makeLogRecord("My-App")) {
+	if !al.IsAllowed(nil, makeLogRecord("My-App")) {
 		t.Error("should match exact case")
 	}
 }
@@ -88,13 +108,13 @@ func TestLoadFromFile_BasicFile(t *testing.T) {
 		t.Fatalf("LoadFromFile failed: %v", err)
 	}
 
-	if !al.IsAllowed(makeLogRecord("app-one")) {
+	if !al.IsAllowed(nil, makeLogRecord("app-one")) {
 		t.Error("app-one should be allowed")
 	}
-	if !al.IsAllowed(makeLogRecord("app-two")) {
+	if !al.IsAllowed(nil, makeLogRecord("app-two")) {
 		t.Error("app-two should be allowed")
 	}
-	if al.IsAllowed(makeLogRecord("app-four")) {
+	if al.IsAllowed(nil, makeLogRecord("app-four")) {
 		t.Error("app-four should NOT be allowed")
 	}
 }
@@ -113,14 +133,14 @@ func TestLoadFromFile_CommentsIgnored(t *testing.T) {
 		t.Fatalf("LoadFromFile failed: %v", err)
 	}
 
-	if !al.IsAllowed(makeLogRecord("my-app")) {
+	if !al.IsAllowed(nil, makeLogRecord("my-app")) {
 		t.Error("my-app should be allowed")
 	}
-	if !al.IsAllowed(makeLogRecord("other-app")) {
+	if !al.IsAllowed(nil, makeLogRecord("other-app")) {
 		t.Error("other-app should be allowed")
 	}
 	// Comments should not be treated as app names
-	if al.IsAllowed(makeLogRecord("# This is a comment")) {
+	if al.IsAllowed(nil, makeLogRecord("# This is a comment")) {
 		t.Error("comment line should NOT be an allowed app")
 	}
 }
@@ -158,7 +178,7 @@ func TestLoadFromFile_EmptyFileAllowsAll(t *testing.T) {
 		t.Fatalf("LoadFromFile failed: %v", err)
 	}
 
-	if !al.IsAllowed(makeLogRecord("any-app")) {
+	if !al.IsAllowed(nil, makeLogRecord("any-app")) {
 		t.Error("empty file should allow all apps")
 	}
 }
@@ -188,10 +208,10 @@ func TestHotReload_UpdatesAllowlist(t *testing.T) {
 	<-ready
 
 	// Verify initial state
-	if !al.IsAllowed(makeLogRecord("app-one")) {
+	if !al.IsAllowed(nil, makeLogRecord("app-one")) {
 		t.Error("app-one should be allowed initially")
 	}
-	if al.IsAllowed(makeLogRecord("app-two")) {
+	if al.IsAllowed(nil, makeLogRecord("app-two")) {
 		t.Error("app-two should NOT be allowed initially")
 	}
 
@@ -209,7 +229,289 @@ func TestHotReload_UpdatesAllowlist(t *testing.T) {
 	}
 
 	// Verify updated state
-	if !al.IsAllowed(makeLogRecord("app-two")) {
+	if !al.IsAllowed(nil, makeLogRecord("app-two")) {
 		t.Error("app-two should be allowed after reload")
 	}
 }
+
+func TestHotReload_PollingFallback(t *testing.T) {
+	newWatcher = func() (*fsnotify.Watcher, error) { return nil, errors.New("inotify unavailable") }
+	defer func() { newWatcher = fsnotify.NewWatcher }()
+
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(path, []byte("app-one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	al, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.New()
+	al.SetMetrics(m)
+	al.SetPollInterval(10 * time.Millisecond)
+
+	stop := make(chan struct{})
+	reloaded := make(chan struct{}, 1)
+	ready := make(chan struct{})
+	defer close(stop)
+	go al.WatchFile(path, stop, reloaded, ready)
+	<-ready
+
+	if got := testutil.ToFloat64(m.AllowlistWatchMode.WithLabelValues(path, WatchPoll)); got != 1 {
+		t.Errorf("poll watch mode = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.AllowlistWatchMode.WithLabelValues(path, WatchFsnotify)); got != 0 {
+		t.Errorf("fsnotify watch mode = %v, want 0", got)
+	}
+
+	// A longer file changes the size, whatever the clock's resolution
+	if err := os.WriteFile(path, []byte("app-one\napp-two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for polled reload")
+	}
+	if !al.IsAppAllowed("app-two") {
+		t.Error("app-two should be allowed after the polled reload")
+	}
+}
+
+func TestHotReload_AtomicSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "allowlist.txt")
+	if err := os.WriteFile(path, []byte("app-one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	al, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.New()
+	al.SetMetrics(m)
+
+	stop := make(chan struct{})
+	reloaded := make(chan struct{}, 1)
+	ready := make(chan struct{})
+	defer close(stop)
+	go al.WatchFile(path, stop, reloaded, ready)
+	<-ready
+
+	// Editors write a temporary file and rename it over the list; the
+	// second save only reloads if the watch followed the first
+	for _, app := range []string{"app-two", "app-three"} {
+		tmp := filepath.Join(dir, "allowlist.txt.swp")
+		if err := os.WriteFile(tmp, []byte(app+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.After(2 * time.Second)
+		for !al.IsAppAllowed(app) {
+			select {
+			case <-reloaded:
+			case <-deadline:
+				t.Fatalf("timeout waiting for %s after an atomic save", app)
+			}
+		}
+	}
+	if al.IsAppAllowed("app-one") {
+		t.Error("app-one should be gone after the saves")
+	}
+	if got := testutil.ToFloat64(m.AllowlistReloads.WithLabelValues(path)); got < 2 {
+		t.Errorf("reloads = %v, want at least 2", got)
+	}
+}
+
+func TestHotReload_RemoveAndCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(path, []byte("app-one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	al, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	reloaded := make(chan struct{}, 1)
+	ready := make(chan struct{})
+	defer close(stop)
+	go al.WatchFile(path, stop, reloaded, ready)
+	<-ready
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("app-two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(2 * time.Second)
+	for !al.IsAppAllowed("app-two") || al.IsAppAllowed("app-one") {
+		select {
+		case <-reloaded:
+		case <-deadline:
+			t.Fatal("timeout waiting for the recreated file to load")
+		}
+	}
+}
+
+func TestHotReload_CountsErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(path, []byte("app-one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	al, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.New()
+	al.SetMetrics(m)
+
+	if err := os.WriteFile(path, []byte("/[z/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if al.reload(path) {
+		t.Fatal("reload of an invalid list should fail")
+	}
+	if !al.IsAppAllowed("app-one") {
+		t.Error("a failed reload should keep the current list")
+	}
+	if got := testutil.ToFloat64(m.AllowlistReloadErrors.WithLabelValues(path)); got != 1 {
+		t.Errorf("reload errors = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.AllowlistReloads.WithLabelValues(path)); got != 0 {
+		t.Errorf("reloads = %v, want 0", got)
+	}
+}
+
+func TestDenylist_DropsOnlyListedApps(t *testing.T) {
+	al := NewDenylist([]string{"Noisy-App"})
+
+	if al.IsAllowed(nil, makeLogRecord("noisy-app")) {
+		t.Error("noisy-app should be denied")
+	}
+	if !al.IsAllowed(nil, makeLogRecord("other-app")) {
+		t.Error("other-app should be allowed")
+	}
+	if !al.IsAppDenied("NOISY-APP") || al.IsAppDenied("other-app") {
+		t.Error("IsAppDenied should match denied apps case-insensitively")
+	}
+}
+
+func TestDenylist_WinsOverAllowlist(t *testing.T) {
+	al := NewAllowlist([]string{"my-app", "noisy-app"})
+	al.SetDenied([]string{"noisy-app", "unlisted-app"})
+
+	for app, want := range map[string]bool{"my-app": true, "noisy-app": false, "unlisted-app": false, "other-app": false} {
+		if got := al.IsAppAllowed(app); got != want {
+			t.Errorf("IsAppAllowed(%s) = %v, want %v", app, got, want)
+		}
+	}
+}
+
+func TestDenylist_HotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	if err := os.WriteFile(path, []byte("# noisy\napp-one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	al := NewDenylist(nil)
+	if err := al.LoadDenylist(path); err != nil {
+		t.Fatalf("LoadDenylist failed: %v", err)
+	}
+	if al.IsAppAllowed("app-one") || !al.IsAppAllowed("app-two") {
+		t.Fatalf("initial denylist = %v, want [app-one]", al.Denied())
+	}
+
+	stop := make(chan struct{})
+	reloaded := make(chan struct{}, 1)
+	ready := make(chan struct{})
+	defer close(stop)
+	go al.WatchDenylist(path, stop, reloaded, ready)
+	<-ready
+
+	if err := os.WriteFile(path, []byte("app-two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for reload")
+	}
+
+	if !al.IsAppAllowed("app-one") || al.IsAppAllowed("app-two") {
+		t.Errorf("reloaded denylist = %v, want [app-two]", al.Denied())
+	}
+}
+
+func TestAllowlist_OrgAndSpaceFromRecordOrResource(t *testing.T) {
+	al := NewAllowlist([]string{"acme/prod/*"})
+	al.SetDenied([]string{"*/prod/noisy-app"})
+
+	resource := &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		{Key: "cf_org_name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "acme"}}},
+		{Key: "cf_space_name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "prod"}}},
+	}}
+	if !al.IsAllowed(resource, makeLogRecord("my-app")) {
+		t.Error("my-app in acme/prod should be allowed using resource org and space")
+	}
+	if al.IsAllowed(nil, makeLogRecord("my-app")) {
+		t.Error("my-app with no org or space should NOT be allowed")
+	}
+	if !al.IsDenied(resource, makeLogRecord("noisy-app")) || al.IsAllowed(resource, makeLogRecord("noisy-app")) {
+		t.Error("noisy-app in acme/prod should be denied")
+	}
+
+	// Record attributes take precedence over the resource
+	lr := makeLogRecord("my-app")
+	lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{Key: "cf_space_name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "dev"}}})
+	if al.IsAllowed(resource, lr) {
+		t.Error("my-app whose record says space dev should NOT be allowed")
+	}
+}
+
+func TestAllowlist_ScopedEntryMatchesTASResource(t *testing.T) {
+	al := NewAllowlist([]string{"acme-prod/production/payment-service"})
+	str := func(s string) *commonpb.AnyValue {
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+	}
+	// As the TAS collector sends it, and as cmd/testlog does: unrenamed names on the resource
+	resource := func(app string) *resourcepb.Resource {
+		return &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			{Key: "application_name", Value: str(app)},
+			{Key: "organization_name", Value: str("acme-prod")},
+			{Key: "space_name", Value: str("production")},
+			{Key: "instance_id", Value: str("0")},
+		}}
+	}
+
+	if !al.IsAllowed(resource("payment-service"), &logspb.LogRecord{}) {
+		t.Error("payment-service in acme-prod/production should be allowed")
+	}
+	if al.IsAllowed(resource("order-service"), &logspb.LogRecord{}) {
+		t.Error("order-service should NOT be allowed")
+	}
+}
+
+func TestAllowlist_AppNameFromResource(t *testing.T) {
+	al := NewAllowlist([]string{"payment-service"})
+	resource := func(app string) *resourcepb.Resource {
+		return &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			{Key: "application_name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: app}}},
+		}}
+	}
+
+	if !al.IsAllowed(resource("payment-service"), &logspb.LogRecord{}) {
+		t.Error("payment-service on the resource should be allowed")
+	}
+	if al.IsAllowed(resource("order-service"), &logspb.LogRecord{}) {
+		t.Error("order-service on the resource should NOT be allowed")
+	}
+	if al.IsAllowed(resource("payment-service"), makeLogRecord("order-service")) {
+		t.Error("the record's cf_app_name should take precedence over the resource")
+	}
+}
@@ -0,0 +1,220 @@
+// ABOUTME: Allowlists synchronized from the Cloud Foundry API: every app carrying a label is allowed.
+// ABOUTME: Authenticates with UAA, lists labeled apps with their space and org, and allows each as org/space/app.
+
+package allowlist
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CFSync allows the apps the Cloud Foundry API lists for a label selector,
+// each scoped to its org and space. Credentials are a user's, for a password
+// grant, or else a UAA client's.
+type CFSync struct {
+	API                string // e.g. https://api.sys.example.com
+	LabelSelector      string // e.g. logging=splunk
+	ClientID           string // UAA client; cf for a user login
+	ClientSecret       string
+	Username, Password string       // Used if Username is set
+	SkipSSLValidation  bool         // For labs with self-signed certificates
+	Client             *http.Client // Defaults to a client with a 30 second timeout
+
+	last []string // Entries from the last good sync
+}
+
+// cfApps is one page of GET /v3/apps with its spaces and orgs included
+type cfApps struct {
+	Pagination struct {
+		Next *struct {
+			Href string `json:"href"`
+		} `json:"next"`
+	} `json:"pagination"`
+	Resources []struct {
+		Name          string `json:"name"`
+		Relationships struct {
+			Space cfRelationship `json:"space"`
+		} `json:"relationships"`
+	} `json:"resources"`
+	Included struct {
+		Spaces []struct {
+			GUID          string `json:"guid"`
+			Name          string `json:"name"`
+			Relationships struct {
+				Organization cfRelationship `json:"organization"`
+			} `json:"relationships"`
+		} `json:"spaces"`
+		Organizations []struct {
+			GUID string `json:"guid"`
+			Name string `json:"name"`
+		} `json:"organizations"`
+	} `json:"included"`
+}
+
+// cfRelationship is a to-one relationship in the CF v3 API
+type cfRelationship struct {
+	Data struct {
+		GUID string `json:"guid"`
+	} `json:"data"`
+}
+
+// Load syncs the allowlist for the first time
+func (cs *CFSync) Load() (*Allowlist, error) {
+	al, err := cs.sync()
+	if err != nil {
+		return nil, err
+	}
+	if len(cs.last) == 0 {
+		slog.Warn("Allowlist: no apps carry the label, so every app is allowed", "selector", cs.LabelSelector, "api", cs.API)
+	}
+	return al, nil
+}
+
+// WatchCF syncs the allowlist every interval, replacing the allowed apps
+// whenever the labeled apps change and keeping the last good list if a sync
+// fails. Runs until stop is closed; reloaded, if not nil, signals after each change.
+func (al *Allowlist) WatchCF(cs *CFSync, interval time.Duration, stop <-chan struct{}, reloaded chan<- struct{}) {
+	al.poll(cs.API, cs.sync, interval, stop, reloaded)
+}
+
+// sync lists the labeled apps as org/space/app entries, returning
+// errNotModified if they are the same as last time
+func (cs *CFSync) sync() (*Allowlist, error) {
+	client := cs.httpClient()
+	token, err := cs.token(client)
+	if err != nil {
+		return nil, fmt.Errorf("cf auth: %w", err)
+	}
+	entries, err := cs.labeledApps(client, token)
+	if err != nil {
+		return nil, fmt.Errorf("cf apps: %w", err)
+	}
+	if cs.last != nil && slices.Equal(entries, cs.last) {
+		return nil, errNotModified
+	}
+	set, err := newAppSet(entries)
+	if err != nil {
+		return nil, err
+	}
+	cs.last = entries
+	return &Allowlist{entries: plainEntries(entries), apps: set}, nil
+}
+
+// httpClient returns the configured client, or a default one
+func (cs *CFSync) httpClient() *http.Client {
+	if cs.Client != nil {
+		return cs.Client
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	if cs.SkipSSLValidation {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	return client
+}
+
+// token logs in to the UAA the API's root document links to
+func (cs *CFSync) token(client *http.Client) (string, error) {
+	var root struct {
+		Links map[string]struct {
+			Href string `json:"href"`
+		} `json:"links"`
+	}
+	if err := cfGet(client, strings.TrimSuffix(cs.API, "/")+"/", "", &root); err != nil {
+		return "", err
+	}
+	uaa := root.Links["uaa"].Href
+	if uaa == "" {
+		uaa = root.Links["login"].Href
+	}
+	if uaa == "" {
+		return "", fmt.Errorf("%s links to no UAA", cs.API)
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if cs.Username != "" {
+		form = url.Values{"grant_type": {"password"}, "username": {cs.Username}, "password": {cs.Password}}
+	}
+	req, err := http.NewRequest(http.MethodPost, uaa+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(cs.ClientID, cs.ClientSecret)
+	var tok struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := cfDo(client, req, &tok); err != nil {
+		return "", err
+	}
+	return tok.AccessToken, nil
+}
+
+// labeledApps lists every app matching the label selector as a sorted
+// org/space/app entry, following pagination
+func (cs *CFSync) labeledApps(client *http.Client, token string) ([]string, error) {
+	query := url.Values{"label_selector": {cs.LabelSelector}, "include": {"space.organization"}, "per_page": {"5000"}}
+	next := strings.TrimSuffix(cs.API, "/") + "/v3/apps?" + query.Encode()
+
+	entries := []string{}
+	for next != "" {
+		var page cfApps
+		if err := cfGet(client, next, token, &page); err != nil {
+			return nil, err
+		}
+		type space struct{ orgGUID, name string }
+		spaces := make(map[string]space)
+		for _, s := range page.Included.Spaces {
+			spaces[s.GUID] = space{s.Relationships.Organization.Data.GUID, s.Name}
+		}
+		orgs := make(map[string]string)
+		for _, o := range page.Included.Organizations {
+			orgs[o.GUID] = o.Name
+		}
+		for _, app := range page.Resources {
+			s := spaces[app.Relationships.Space.Data.GUID]
+			if org := orgs[s.orgGUID]; org != "" && s.name != "" {
+				entries = append(entries, org+"/"+s.name+"/"+app.Name)
+			}
+		}
+
+		next = ""
+		if page.Pagination.Next != nil {
+			next = page.Pagination.Next.Href
+		}
+	}
+	slices.Sort(entries)
+	return entries, nil
+}
+
+// cfGet fetches a JSON document, with a bearer token if one is given
+func cfGet(client *http.Client, target, token string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return cfDo(client, req, v)
+}
+
+// cfDo sends a request and decodes its JSON response, failing on any status but 200
+func cfDo(client *http.Client, req *http.Request, v any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
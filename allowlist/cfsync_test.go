@@ -0,0 +1,179 @@
+// ABOUTME: Tests for syncing the allowlist from a fake Cloud Foundry API.
+// ABOUTME: Covers UAA login, label selection, pagination, org/space scoping of TAS records, and unchanged syncs.
+
+package allowlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// fakeCF serves the CF API root, UAA token endpoint, and /v3/apps in pages of one app
+type fakeCF struct {
+	mu     sync.Mutex
+	url    string
+	apps   []string // space/app, all in org acme
+	grants []string
+	labels []string
+}
+
+func (f *fakeCF) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/":
+		json.NewEncoder(w).Encode(map[string]any{"links": map[string]any{"uaa": map[string]string{"href": f.url + "/uaa"}}})
+	case "/uaa/oauth/token":
+		r.ParseForm()
+		f.grants = append(f.grants, r.PostForm.Get("grant_type")+":"+r.PostForm.Get("username"))
+		fmt.Fprint(w, `{"access_token": "secret-token"}`)
+	case "/v3/apps":
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.labels = append(f.labels, r.URL.Query().Get("label_selector"))
+		page := 0
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		w.Write([]byte(f.page(page)))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// page renders the apps page, with a next link while apps remain
+func (f *fakeCF) page(n int) string {
+	next := "null"
+	if n+1 < len(f.apps) {
+		next = fmt.Sprintf(`{"href": "%s/v3/apps?page=%d"}`, f.url, n+1)
+	}
+	if n >= len(f.apps) {
+		return `{"pagination": {"next": null}, "resources": [], "included": {}}`
+	}
+	space, app, _ := strings.Cut(f.apps[n], "/")
+	return fmt.Sprintf(`{
+		"pagination": {"next": %s},
+		"resources": [{"name": %q, "relationships": {"space": {"data": {"guid": "space-%s"}}}}],
+		"included": {
+			"spaces": [{"guid": "space-%s", "name": %q, "relationships": {"organization": {"data": {"guid": "org-1"}}}}],
+			"organizations": [{"guid": "org-1", "name": "acme"}]
+		}
+	}`, next, app, space, space, space)
+}
+
+func (f *fakeCF) setApps(apps ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.apps = apps
+}
+
+func newFakeCF(t *testing.T, apps ...string) *fakeCF {
+	f := &fakeCF{apps: apps}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	f.url = srv.URL
+	return f
+}
+
+func TestCFSync_LabeledApps(t *testing.T) {
+	f := newFakeCF(t, "prod/payment-api", "dev/orders")
+	cs := &CFSync{API: f.url, LabelSelector: "logging=splunk", ClientID: "cf", Username: "admin", Password: "pw"}
+
+	al, err := cs.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := strings.Join(f.grants, ","); got != "password:admin" {
+		t.Errorf("grants = %s, want a password grant for admin", got)
+	}
+	if got := f.labels[0]; got != "logging=splunk" {
+		t.Errorf("label_selector = %q", got)
+	}
+
+	for _, tc := range []struct {
+		id   appID
+		want bool
+	}{
+		{appID{"acme", "prod", "payment-api"}, true},
+		{appID{"acme", "dev", "orders"}, true},
+		{appID{"acme", "dev", "payment-api"}, false},
+		{appID{"", "", "orders"}, false},
+	} {
+		if got := al.allows(tc.id); got != tc.want {
+			t.Errorf("allows(%+v) = %v, want %v", tc.id, got, tc.want)
+		}
+	}
+	if got := al.Apps(); len(got) != 2 {
+		t.Errorf("Apps() = %v, want both pages", got)
+	}
+}
+
+func TestCFSync_AllowsTASRecords(t *testing.T) {
+	f := newFakeCF(t, "prod/payment-api")
+	cs := &CFSync{API: f.url, LabelSelector: "logging=splunk", ClientID: "sync", ClientSecret: "s3cret"}
+	al, err := cs.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// Records as the TAS collector sends them, before any renames
+	resource := func(space, app string) *resourcepb.Resource {
+		attr := func(key, value string) *commonpb.KeyValue {
+			return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+		}
+		return &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			attr("application_name", app), attr("organization_name", "acme"), attr("space_name", space),
+		}}
+	}
+	if !al.IsAllowed(resource("prod", "payment-api"), &logspb.LogRecord{}) {
+		t.Error("the labeled app's records should be allowed")
+	}
+	if al.IsAllowed(resource("dev", "payment-api"), &logspb.LogRecord{}) {
+		t.Error("the same app name in another space should NOT be allowed")
+	}
+}
+
+func TestCFSync_Changes(t *testing.T) {
+	f := newFakeCF(t, "prod/payment-api")
+	cs := &CFSync{API: f.url, LabelSelector: "logging=splunk", ClientID: "sync", ClientSecret: "s3cret"}
+	if _, err := cs.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if f.grants[0] != "client_credentials:" {
+		t.Errorf("grant = %s, want client_credentials", f.grants[0])
+	}
+
+	if _, err := cs.sync(); err != errNotModified {
+		t.Errorf("unchanged sync error = %v, want errNotModified", err)
+	}
+
+	f.setApps("prod/payment-api", "prod/checkout")
+	al, err := cs.sync()
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if !al.allows(appID{"acme", "prod", "checkout"}) {
+		t.Error("newly labeled app should be allowed")
+	}
+}
+
+func TestCFSync_AuthFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := (&CFSync{API: srv.URL, LabelSelector: "logging=splunk"}).Load()
+	if err == nil || !strings.Contains(err.Error(), "cf auth") {
+		t.Errorf("error = %v, want a cf auth failure", err)
+	}
+}
@@ -0,0 +1,122 @@
+// ABOUTME: Runtime editing of the allowed apps: replace the list and its groups, add entries, and remove them.
+// ABOUTME: Each edit is validated before it takes effect, and optionally saved back to the allowlist file.
+
+package allowlist
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// Editing errors callers can distinguish from validation failures
+var (
+	ErrEntryNotFound = errors.New("entry not found")
+	ErrEntryExists   = errors.New("entry already exists")
+	ErrPersist       = errors.New("saving allowlist failed")
+)
+
+// PersistTo saves each later edit to path: as YAML if it ends in .yaml or
+// .yml, otherwise as a plain list, which cannot hold per-app options
+func (al *Allowlist) PersistTo(path string) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.path = path
+}
+
+// Config returns the allowed apps and groups as listed, in YAML allowlist form
+func (al *Allowlist) Config() FileConfig {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+	return FileConfig{Apps: slices.Clone(al.entries), Groups: slices.Clone(al.groups)}
+}
+
+// Replace sets the allowed apps and groups to fc's; none allows every app
+func (al *Allowlist) Replace(fc FileConfig) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.apply(slices.Clone(fc.Apps), slices.Clone(fc.Groups))
+}
+
+// Add appends entries to the allowed apps, failing if any is already listed
+func (al *Allowlist) Add(entries []*AppEntry) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	next := slices.Clone(al.entries)
+	for _, entry := range entries {
+		if entry != nil && find(next, entry.App) >= 0 {
+			return fmt.Errorf("entry %s: %w", entry.App, ErrEntryExists)
+		}
+		next = append(next, entry)
+	}
+	return al.apply(next, al.groups)
+}
+
+// Remove deletes the listed entries, matched as written but ignoring case
+func (al *Allowlist) Remove(apps []string) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	next := slices.Clone(al.entries)
+	for _, app := range apps {
+		i := find(next, app)
+		if i < 0 {
+			return fmt.Errorf("entry %s: %w", app, ErrEntryNotFound)
+		}
+		next = slices.Delete(next, i, i+1)
+	}
+	return al.apply(next, al.groups)
+}
+
+// find returns the index of the entry written as app, or -1
+func find(entries []*AppEntry, app string) int {
+	return slices.IndexFunc(entries, func(e *AppEntry) bool { return e != nil && strings.EqualFold(e.App, app) })
+}
+
+// apply builds the edited entries and groups, saves them if persisting, and
+// puts them in effect. The caller holds the write lock.
+func (al *Allowlist) apply(entries []*AppEntry, groups []*Group) error {
+	apps, policies, err := compile(entries, groups)
+	if err != nil {
+		return err
+	}
+	if al.path != "" {
+		if err := save(al.path, FileConfig{Apps: entries, Groups: groups}); err != nil {
+			return err
+		}
+	}
+	al.entries, al.groups, al.apps, al.policies = entries, groups, apps, policies
+	return nil
+}
+
+// save writes fc to path in the format its extension selects. The file is
+// rewritten in place, rather than replaced, so its watcher keeps following it.
+func save(path string, fc FileConfig) error {
+	var data []byte
+	if isYAML(path) {
+		out, err := yaml.Marshal(fc)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrPersist, err)
+		}
+		data = out
+	} else {
+		if len(fc.Groups) > 0 {
+			return fmt.Errorf("groups need a YAML allowlist file, not %s", path)
+		}
+		var b strings.Builder
+		for _, entry := range fc.Apps {
+			if *entry != (AppEntry{App: entry.App}) {
+				return fmt.Errorf("app %q: options need a YAML allowlist file, not %s", entry.App, path)
+			}
+			b.WriteString(entry.App + "\n")
+		}
+		data = []byte(b.String())
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersist, err)
+	}
+	return nil
+}
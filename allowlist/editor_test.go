@@ -0,0 +1,121 @@
+// ABOUTME: Tests for editing the allowed apps at runtime.
+// ABOUTME: Covers replace, add, and remove, rejected edits, and saving to plain and YAML files.
+
+package allowlist
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAllowlist_Edits(t *testing.T) {
+	al := NewAllowlist([]string{"checkout"})
+
+	if err := al.Add([]*AppEntry{{App: "payment-*", Index: "tas_payments"}}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if !al.IsAppAllowed("payment-api") {
+		t.Error("added glob should allow payment-api")
+	}
+	if p, _ := al.Policy(nil, makeLogRecord("payment-api")); p.Index != "tas_payments" {
+		t.Errorf("Policy index = %q, want tas_payments", p.Index)
+	}
+
+	if err := al.Remove([]string{"CHECKOUT"}); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if al.IsAppAllowed("checkout") {
+		t.Error("removed app should no longer be allowed")
+	}
+
+	if err := al.Replace(FileConfig{}); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	if fc := al.Config(); len(fc.Apps) != 0 || !al.IsAppAllowed("anything") {
+		t.Errorf("empty list = %+v, want every app allowed", fc.Apps)
+	}
+}
+
+func TestAllowlist_EditsRejected(t *testing.T) {
+	al := NewAllowlist([]string{"checkout"})
+
+	if err := al.Add([]*AppEntry{{App: "Checkout"}}); !errors.Is(err, ErrEntryExists) {
+		t.Errorf("Add duplicate error = %v, want ErrEntryExists", err)
+	}
+	if err := al.Remove([]string{"orders"}); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("Remove missing error = %v, want ErrEntryNotFound", err)
+	}
+	if err := al.Add([]*AppEntry{{App: "api", MinSeverity: "loud"}}); err == nil {
+		t.Error("Add with an invalid severity should fail")
+	}
+	if got := al.Config().Apps; len(got) != 1 || got[0].App != "checkout" {
+		t.Errorf("entries after rejected edits = %+v, want only checkout", got)
+	}
+}
+
+func TestAllowlist_PersistPlain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(path, []byte("# apps\ncheckout\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	al, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	al.PersistTo(path)
+
+	if err := al.Add([]*AppEntry{{App: "payment-*"}}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "checkout\npayment-*\n" {
+		t.Errorf("saved file = %q", data)
+	}
+
+	// A plain file cannot hold options, so the edit is rejected
+	err = al.Add([]*AppEntry{{App: "orders", SampleRate: 10}})
+	if err == nil || !strings.Contains(err.Error(), "YAML") {
+		t.Errorf("error = %v, want options needing a YAML file", err)
+	}
+	if al.IsAppAllowed("orders") {
+		t.Error("rejected edit should not take effect")
+	}
+
+	err = al.Replace(FileConfig{Groups: []*Group{{Name: "orders", Apps: []string{"orders-*"}}}})
+	if err == nil || !strings.Contains(err.Error(), "YAML") {
+		t.Errorf("error = %v, want groups needing a YAML file", err)
+	}
+}
+
+func TestAllowlist_PersistYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.yaml")
+	al := NewAllowlist(nil)
+	al.PersistTo(path)
+
+	if err := al.Replace(FileConfig{Apps: []*AppEntry{{App: "payment-*", MinSeverity: "warn"}}}); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	loaded, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("reloading saved file: %v", err)
+	}
+	if p, _ := loaded.Policy(nil, makeLogRecord("payment-api")); p.MinSeverity == 0 {
+		t.Error("saved YAML should keep the entry's min_severity")
+	}
+
+	// Edits to the apps keep the groups
+	if err := al.Replace(FileConfig{Groups: []*Group{{Name: "noisy", Filter: FilterDeny, Apps: []string{"noisy-*"}}}}); err != nil {
+		t.Fatalf("Replace with groups failed: %v", err)
+	}
+	if err := al.Add([]*AppEntry{{App: "checkout"}}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if loaded, err = LoadFromFile(path); err != nil {
+		t.Fatalf("reloading saved file: %v", err)
+	}
+	if groups := loaded.Config().Groups; len(groups) != 1 {
+		t.Errorf("saved groups = %+v, want the noisy group kept", groups)
+	}
+}
@@ -0,0 +1,145 @@
+// ABOUTME: App name sets with glob, regex, and org/space/app entries alongside exact names.
+// ABOUTME: Exact names are a map lookup; only names missing from it are tried against the patterns.
+
+package allowlist
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// appSet matches app names case-insensitively against exact names, globs like
+// payment-*, regexes written between slashes like /^team-a-.*$/, and entries
+// scoped to an org and space like acme/prod-*/payment-api. The zero value is
+// an empty set.
+type appSet struct {
+	names    map[string]bool // lowercase exact names
+	patterns []appPattern
+}
+
+// appPattern is a glob, regex, or org/space/app entry and its compiled form
+type appPattern struct {
+	entry      string
+	org, space *regexp.Regexp // nil unless the entry is scoped as org/space/app
+	app        *regexp.Regexp
+}
+
+// matches reports whether the app, and its org and space for a scoped entry, match
+func (p appPattern) matches(id appID) bool {
+	return (p.org == nil || p.org.MatchString(id.org)) &&
+		(p.space == nil || p.space.MatchString(id.space)) &&
+		p.app.MatchString(id.app)
+}
+
+// appID is an app and the org and space it runs in, empty where unknown
+type appID struct {
+	org, space, app string
+}
+
+// newAppSet builds a set from list entries, skipping blank ones. Invalid
+// entries are left out of the set, and the first is returned as the error.
+func newAppSet(entries []string) (appSet, error) {
+	set := appSet{names: make(map[string]bool)}
+	var firstErr error
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		p, err := compileEntry(entry)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if p == nil {
+			set.names[strings.ToLower(entry)] = true
+		} else {
+			set.patterns = append(set.patterns, *p)
+		}
+	}
+	return set, firstErr
+}
+
+// compileEntry compiles a regex, org/space/app, or glob entry, returning nil
+// for a plain app name
+func compileEntry(entry string) (*appPattern, error) {
+	if len(entry) > 1 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+		re, err := regexp.Compile("(?i)" + entry[1:len(entry)-1])
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: %w", entry, err)
+		}
+		return &appPattern{entry: entry, app: re}, nil
+	}
+	if strings.Contains(entry, "/") {
+		parts := strings.Split(entry, "/")
+		if len(parts) != 3 || slices.Contains(parts, "") {
+			return nil, fmt.Errorf("entry %s: want org/space/app", entry)
+		}
+		return &appPattern{entry: entry, org: globRegexp(parts[0]), space: globRegexp(parts[1]), app: globRegexp(parts[2])}, nil
+	}
+	if !strings.ContainsAny(entry, "*?") {
+		return nil, nil
+	}
+	return &appPattern{entry: entry, app: globRegexp(entry)}, nil
+}
+
+// globRegexp compiles a glob matching whole names case-insensitively, where *
+// matches any run of characters and ? one character
+func globRegexp(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// add merges other's names and patterns into s
+func (s *appSet) add(other appSet) {
+	for name := range other.names {
+		s.names[name] = true
+	}
+	s.patterns = append(s.patterns, other.patterns...)
+}
+
+// empty reports whether the set has no entries
+func (s appSet) empty() bool {
+	return len(s.names) == 0 && len(s.patterns) == 0
+}
+
+// contains reports whether an app matches an exact entry or a pattern
+func (s appSet) contains(id appID) bool {
+	if s.names[strings.ToLower(id.app)] {
+		return true
+	}
+	for _, p := range s.patterns {
+		if p.matches(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// entries returns the set's exact names and patterns as written
+func (s appSet) entries() []string {
+	entries := make([]string, 0, len(s.names)+len(s.patterns))
+	for name := range s.names {
+		entries = append(entries, name)
+	}
+	for _, p := range s.patterns {
+		entries = append(entries, p.entry)
+	}
+	return entries
+}
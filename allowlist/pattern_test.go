@@ -0,0 +1,118 @@
+// ABOUTME: Tests for glob, regex, and org/space/app allowlist entries.
+// ABOUTME: Covers pattern syntax, org/space scoping, case-insensitivity, invalid entries, and patterns in lists and files.
+
+package allowlist
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAppSet_Patterns(t *testing.T) {
+	set, err := newAppSet([]string{"checkout", "payment-*", "api-v?", "/^team-a-.*$/", "/worker/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for app, want := range map[string]bool{
+		"checkout":        true,
+		"Checkout":        true,
+		"checkout-api":    false,
+		"payment-service": true,
+		"PAYMENT-gateway": true,
+		"payment":         false,
+		"my-payment-api":  false,
+		"api-v2":          true,
+		"api-v10":         false,
+		"team-a-orders":   true,
+		"team-b-orders":   false,
+		"batch-worker-3":  true,
+	} {
+		if got := set.contains(appID{app: app}); got != want {
+			t.Errorf("contains(%q) = %v, want %v", app, got, want)
+		}
+	}
+}
+
+func TestAppSet_GlobQuotesRegexSyntax(t *testing.T) {
+	set, _ := newAppSet([]string{"app.v1-*"})
+	if !set.contains(appID{app: "app.v1-blue"}) || set.contains(appID{app: "appxv1-blue"}) {
+		t.Error("glob should treat . literally")
+	}
+}
+
+func TestAppSet_InvalidRegex(t *testing.T) {
+	set, err := newAppSet([]string{"/^(unclosed/", "good-app"})
+	if err == nil || !strings.Contains(err.Error(), "/^(unclosed/") {
+		t.Errorf("error = %v, want it to name the entry", err)
+	}
+	if !set.contains(appID{app: "good-app"}) {
+		t.Error("valid entries should still be added")
+	}
+}
+
+func TestAllowlist_Patterns(t *testing.T) {
+	al := NewAllowlist([]string{"payment-*"})
+	al.SetDenied([]string{"/-canary$/"})
+
+	for app, want := range map[string]bool{"payment-api": true, "payment-api-canary": false, "orders": false} {
+		if got := al.IsAppAllowed(app); got != want {
+			t.Errorf("IsAppAllowed(%s) = %v, want %v", app, got, want)
+		}
+	}
+	if got := al.Apps(); !slices.Equal(got, []string{"payment-*"}) {
+		t.Errorf("Apps() = %v, want [payment-*]", got)
+	}
+}
+
+func TestLoadFromFile_InvalidRegex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(path, []byte("app-one\n/[z/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFromFile(path); err == nil || !strings.Contains(err.Error(), "/[z/") {
+		t.Errorf("LoadFromFile error = %v, want invalid entry /[z/", err)
+	}
+
+	al := NewAllowlist([]string{"app-one"})
+	al.reload(path)
+	if !al.IsAppAllowed("app-one") || al.IsAppAllowed("app-two") {
+		t.Error("a file with an invalid entry should not replace the list on reload")
+	}
+}
+
+func TestAppSet_Scoped(t *testing.T) {
+	set, err := newAppSet([]string{"acme/prod/payment-api", "acme/staging-*/*", "*/*/audit-?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		id   appID
+		want bool
+	}{
+		{appID{"acme", "prod", "payment-api"}, true},
+		{appID{"ACME", "Prod", "Payment-API"}, true},
+		{appID{"acme", "dev", "payment-api"}, false},
+		{appID{"other", "prod", "payment-api"}, false},
+		{appID{"", "", "payment-api"}, false},
+		{appID{"acme", "staging-eu", "anything"}, true},
+		{appID{"acme", "staging", "anything"}, false},
+		{appID{"", "", "audit-1"}, true},
+	} {
+		if got := set.contains(tc.id); got != tc.want {
+			t.Errorf("contains(%+v) = %v, want %v", tc.id, got, tc.want)
+		}
+	}
+}
+
+func TestAppSet_InvalidScoped(t *testing.T) {
+	for _, entry := range []string{"acme/payment-api", "acme//app", "a/b/c/d"} {
+		if _, err := newAppSet([]string{entry}); err == nil || !strings.Contains(err.Error(), "want org/space/app") {
+			t.Errorf("%s: error = %v, want org/space/app", entry, err)
+		}
+	}
+}
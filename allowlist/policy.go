@@ -0,0 +1,189 @@
+// ABOUTME: YAML allowlists whose entries and named groups carry policy: filtering, minimum severity, sample rate, and index.
+// ABOUTME: Lets one hot-reloadable file say both which apps are kept and how each app or tenant is handled.
+
+package allowlist
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.yaml.in/yaml/v2"
+
+	"otlp-mock-receiver/transform"
+)
+
+// Policy is how records from an app are handled, set by its YAML allowlist entry or group
+type Policy struct {
+	MinSeverity logspb.SeverityNumber // Records below are dropped; 0 keeps all
+	SampleRate  int                   // Keep 1 in N records, replacing the receiver's sample rate; 0 keeps it
+	Index       string                // Route records here instead of through the routing rules, if set
+	Group       string                // The group the policy comes from, if any
+	Deny        bool                  // Drop every record, for apps in a deny group
+}
+
+// Group filters
+const (
+	FilterAllow = "allow"
+	FilterDeny  = "deny"
+)
+
+// FileConfig is the YAML representation of an allowlist file
+type FileConfig struct {
+	Apps   []*AppEntry `yaml:"apps" json:"apps"`
+	Groups []*Group    `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
+// AppEntry is one allowed app in a YAML allowlist, with optional policy
+type AppEntry struct {
+	App         string `yaml:"app" json:"app"`                                       // App name, glob, /regex/, or org/space/app, as in a plain allowlist
+	MinSeverity string `yaml:"min_severity,omitempty" json:"min_severity,omitempty"` // e.g. warn; lower severities are dropped
+	SampleRate  int    `yaml:"sample_rate,omitempty" json:"sample_rate,omitempty"`   // Keep 1 in N of the app's records
+	Index       string `yaml:"index,omitempty" json:"index,omitempty"`               // Route the app's records here, bypassing the routing rules
+}
+
+// Group is a named set of apps, such as a tenant's, sharing one policy. An
+// allow group's apps are allowed as if listed under apps; a deny group's are
+// dropped, even if listed there too.
+type Group struct {
+	Name        string   `yaml:"name" json:"name"`
+	Apps        []string `yaml:"apps" json:"apps"`                                     // Entries as in a plain allowlist
+	Filter      string   `yaml:"filter,omitempty" json:"filter,omitempty"`             // allow, the default, or deny
+	MinSeverity string   `yaml:"min_severity,omitempty" json:"min_severity,omitempty"` // As for an app entry
+	SampleRate  int      `yaml:"sample_rate,omitempty" json:"sample_rate,omitempty"`
+	Index       string   `yaml:"index,omitempty" json:"index,omitempty"`
+}
+
+// appPolicy is an entry's policy and the apps it applies to
+type appPolicy struct {
+	apps   appSet
+	policy Policy
+}
+
+// ParseConfig decodes an allowlist from YAML or JSON, rejecting unknown fields
+func ParseConfig(data []byte) (*FileConfig, error) {
+	var fc FileConfig
+	if err := yaml.UnmarshalStrict(data, &fc); err != nil {
+		return nil, err
+	}
+	return &fc, nil
+}
+
+// Build validates every entry and group and builds the allowlist
+func (fc *FileConfig) Build() (*Allowlist, error) {
+	apps, policies, err := compile(fc.Apps, fc.Groups)
+	if err != nil {
+		return nil, err
+	}
+	return &Allowlist{entries: slices.Clone(fc.Apps), groups: slices.Clone(fc.Groups), apps: apps, policies: policies}, nil
+}
+
+// compile builds the allowed apps and the policies from entries and groups.
+// Policies are looked up in order: deny groups, so they always win, then
+// entries, which are more specific than the allow groups after them.
+func compile(entries []*AppEntry, groups []*Group) (appSet, []appPolicy, error) {
+	all := appSet{names: make(map[string]bool)}
+	var policies, denies []appPolicy
+	for i, entry := range entries {
+		if entry == nil || entry.App == "" {
+			return appSet{}, nil, fmt.Errorf("apps[%d]: app is required", i)
+		}
+		apps, err := newAppSet([]string{entry.App})
+		if err != nil {
+			return appSet{}, nil, fmt.Errorf("apps[%d]: %w", i, err)
+		}
+		all.add(apps)
+
+		policy, err := entry.policy()
+		if err != nil {
+			return appSet{}, nil, fmt.Errorf("app %q: %w", entry.App, err)
+		}
+		if policy != (Policy{}) {
+			policies = append(policies, appPolicy{apps: apps, policy: policy})
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, group := range groups {
+		if group == nil || group.Name == "" {
+			return appSet{}, nil, fmt.Errorf("groups[%d]: name is required", i)
+		}
+		if names[group.Name] {
+			return appSet{}, nil, fmt.Errorf("group %q: listed twice", group.Name)
+		}
+		names[group.Name] = true
+		if len(group.Apps) == 0 {
+			return appSet{}, nil, fmt.Errorf("group %q: apps are required", group.Name)
+		}
+		apps, err := newAppSet(group.Apps)
+		if err != nil {
+			return appSet{}, nil, fmt.Errorf("group %q: %w", group.Name, err)
+		}
+		policy, err := group.policy()
+		if err != nil {
+			return appSet{}, nil, fmt.Errorf("group %q: %w", group.Name, err)
+		}
+		if policy.Deny {
+			denies = append(denies, appPolicy{apps: apps, policy: policy})
+			continue
+		}
+		all.add(apps)
+		policies = append(policies, appPolicy{apps: apps, policy: policy})
+	}
+	return all, append(denies, policies...), nil
+}
+
+// policy parses and checks the entry's options
+func (e *AppEntry) policy() (Policy, error) {
+	p := Policy{SampleRate: e.SampleRate, Index: e.Index}
+	if e.MinSeverity != "" {
+		sev, err := transform.ParseSeverity(e.MinSeverity)
+		if err != nil {
+			return Policy{}, fmt.Errorf("min_severity: %w", err)
+		}
+		p.MinSeverity = sev
+	}
+	if e.SampleRate < 0 {
+		return Policy{}, fmt.Errorf("sample_rate %d must not be negative", e.SampleRate)
+	}
+	return p, nil
+}
+
+// policy parses and checks the group's filter and options
+func (g *Group) policy() (Policy, error) {
+	entry := AppEntry{MinSeverity: g.MinSeverity, SampleRate: g.SampleRate, Index: g.Index}
+	p, err := entry.policy()
+	if err != nil {
+		return Policy{}, err
+	}
+	p.Group = g.Name
+	switch g.Filter {
+	case "", FilterAllow:
+	case FilterDeny:
+		if entry != (AppEntry{}) {
+			return Policy{}, errors.New("a deny group's apps are dropped, so it takes no other options")
+		}
+		p.Deny = true
+	default:
+		return Policy{}, fmt.Errorf("filter %q must be %s or %s", g.Filter, FilterAllow, FilterDeny)
+	}
+	return p, nil
+}
+
+// Policy returns the policy of the first deny group, YAML entry with
+// options, or allow group that the record's app matches, identified as for
+// IsAllowed
+func (al *Allowlist) Policy(resource *resourcepb.Resource, lr *logspb.LogRecord) (Policy, bool) {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+
+	id := recordApp(resource, lr)
+	for _, ap := range al.policies {
+		if ap.apps.contains(id) {
+			return ap.policy, true
+		}
+	}
+	return Policy{}, false
+}
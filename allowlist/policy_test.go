@@ -0,0 +1,201 @@
+// ABOUTME: Tests for YAML allowlists with per-app and per-group policy.
+// ABOUTME: Covers loading, policy lookup order, validation errors, and hot-reload.
+
+package allowlist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+const policyYAML = `
+apps:
+  - app: payment-api
+    min_severity: warn
+    index: tas_payments
+  - app: payment-*
+    sample_rate: 10
+  - app: acme/prod/*
+  - app: checkout
+`
+
+func TestLoadFromFile_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.yaml")
+	if err := os.WriteFile(path, []byte(policyYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	al, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+
+	for app, want := range map[string]bool{"payment-api": true, "payment-worker": true, "checkout": true, "orders": false} {
+		if got := al.IsAllowed(nil, makeLogRecord(app)); got != want {
+			t.Errorf("IsAllowed(%s) = %v, want %v", app, got, want)
+		}
+	}
+
+	// The first entry with options that matches wins
+	for app, want := range map[string]Policy{
+		"payment-api":    {MinSeverity: logspb.SeverityNumber_SEVERITY_NUMBER_WARN, Index: "tas_payments"},
+		"payment-worker": {SampleRate: 10},
+	} {
+		if got, ok := al.Policy(nil, makeLogRecord(app)); !ok || got != want {
+			t.Errorf("Policy(%s) = %+v, %v, want %+v", app, got, ok, want)
+		}
+	}
+	if _, ok := al.Policy(nil, makeLogRecord("checkout")); ok {
+		t.Error("checkout has no options, so should have no policy")
+	}
+}
+
+func TestFileConfig_BuildErrors(t *testing.T) {
+	for _, tc := range []struct {
+		entry AppEntry
+		want  string
+	}{
+		{AppEntry{}, "apps[0]: app is required"},
+		{AppEntry{App: "/[z/"}, "apps[0]: entry /[z/"},
+		{AppEntry{App: "api", MinSeverity: "loud"}, `app "api": min_severity: unknown severity "loud"`},
+		{AppEntry{App: "api", SampleRate: -1}, `app "api": sample_rate -1 must not be negative`},
+	} {
+		fc := FileConfig{Apps: []*AppEntry{&tc.entry}}
+		if _, err := fc.Build(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: error = %v, want %q", tc.entry, err, tc.want)
+		}
+	}
+}
+
+const groupsYAML = `
+apps:
+  - app: payment-api
+    index: tas_payment_api
+groups:
+  - name: payments
+    apps: [payment-*, acme/prod/checkout]
+    min_severity: info
+    sample_rate: 10
+    index: tas_payments
+  - name: batch
+    filter: deny
+    apps: [batch-*, payment-batch]
+`
+
+func TestLoadFromFile_Groups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(groupsYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	al, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+
+	// An allow group's apps are allowed like entries; a deny group's are not
+	for app, want := range map[string]bool{"payment-api": true, "payment-worker": true, "batch-nightly": false, "orders": false} {
+		if got := al.IsAllowed(nil, makeLogRecord(app)); got != want {
+			t.Errorf("IsAllowed(%s) = %v, want %v", app, got, want)
+		}
+	}
+
+	payments := Policy{MinSeverity: logspb.SeverityNumber_SEVERITY_NUMBER_INFO, SampleRate: 10, Index: "tas_payments", Group: "payments"}
+	for app, want := range map[string]Policy{
+		"payment-api":    {Index: "tas_payment_api"}, // Entries win over allow groups
+		"payment-worker": payments,
+		"payment-batch":  {Group: "batch", Deny: true}, // Deny groups win over everything
+		"batch-nightly":  {Group: "batch", Deny: true},
+	} {
+		if got, ok := al.Policy(nil, makeLogRecord(app)); !ok || got != want {
+			t.Errorf("Policy(%s) = %+v, %v, want %+v", app, got, ok, want)
+		}
+	}
+
+	fc := al.Config()
+	if len(fc.Groups) != 2 || fc.Groups[0].Name != "payments" {
+		t.Errorf("Config().Groups = %+v, want both groups as listed", fc.Groups)
+	}
+}
+
+func TestFileConfig_DenyGroupsOnly(t *testing.T) {
+	fc := FileConfig{Groups: []*Group{{Name: "noisy", Filter: FilterDeny, Apps: []string{"noisy-*"}}}}
+	al, err := fc.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !al.IsAppAllowed("orders") {
+		t.Error("with only deny groups, other apps should be allowed")
+	}
+	if p, _ := al.Policy(nil, makeLogRecord("noisy-api")); !p.Deny {
+		t.Errorf("Policy(noisy-api) = %+v, want Deny", p)
+	}
+}
+
+func TestFileConfig_GroupErrors(t *testing.T) {
+	for _, tc := range []struct {
+		group Group
+		want  string
+	}{
+		{Group{Apps: []string{"api"}}, "groups[0]: name is required"},
+		{Group{Name: "g"}, `group "g": apps are required`},
+		{Group{Name: "g", Apps: []string{"/[z/"}}, `group "g": entry /[z/`},
+		{Group{Name: "g", Apps: []string{"api"}, Filter: "drop"}, `group "g": filter "drop" must be allow or deny`},
+		{Group{Name: "g", Apps: []string{"api"}, Filter: FilterDeny, Index: "main"}, `group "g": a deny group's apps are dropped`},
+		{Group{Name: "g", Apps: []string{"api"}, SampleRate: -1}, `group "g": sample_rate -1 must not be negative`},
+	} {
+		fc := FileConfig{Groups: []*Group{&tc.group}}
+		if _, err := fc.Build(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: error = %v, want %q", tc.group, err, tc.want)
+		}
+	}
+
+	twice := FileConfig{Groups: []*Group{{Name: "g", Apps: []string{"a"}}, {Name: "g", Apps: []string{"b"}}}}
+	if _, err := twice.Build(); err == nil || !strings.Contains(err.Error(), "listed twice") {
+		t.Errorf("duplicate group error = %v, want listed twice", err)
+	}
+}
+
+func TestLoadFromFile_YAMLUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.yml")
+	if err := os.WriteFile(path, []byte("apps:\n  - app: api\n    min_sev: warn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFromFile(path); err == nil || !strings.Contains(err.Error(), "min_sev") {
+		t.Errorf("error = %v, want unknown field min_sev", err)
+	}
+}
+
+func TestHotReload_YAMLPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.yaml")
+	if err := os.WriteFile(path, []byte("apps:\n  - app: api\n    index: tas_one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	al, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	reloaded := make(chan struct{}, 1)
+	ready := make(chan struct{})
+	defer close(stop)
+	go al.WatchFile(path, stop, reloaded, ready)
+	<-ready
+
+	if err := os.WriteFile(path, []byte("apps:\n  - app: api\n    index: tas_two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for reload")
+	}
+
+	if p, _ := al.Policy(nil, makeLogRecord("api")); p.Index != "tas_two" {
+		t.Errorf("Policy index after reload = %q, want tas_two", p.Index)
+	}
+}
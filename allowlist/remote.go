@@ -0,0 +1,145 @@
+// ABOUTME: Allowlists fetched over HTTP from a central service and polled for changes.
+// ABOUTME: Polls send the last ETag, and a failed fetch keeps the last good copy, optionally cached on disk.
+
+package allowlist
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// errNotModified means the server's allowlist has not changed since the last fetch
+var errNotModified = errors.New("not modified")
+
+// Remote is an allowlist served over HTTP. Its format follows the URL path:
+// YAML if it ends in .yaml or .yml, a plain list otherwise.
+type Remote struct {
+	URL    string
+	Cache  string       // Each good copy is saved here, and loaded if the first fetch fails, if set
+	Client *http.Client // Defaults to a client with a 10 second timeout
+
+	etag string // From the last good fetch, sent as If-None-Match
+	last []byte // The last good copy, for servers that send no ETag
+}
+
+// IsURL reports whether an allowlist source is an http or https URL rather than a file
+func IsURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// Load fetches the allowlist, falling back to the cached copy if the fetch fails
+func (rm *Remote) Load() (*Allowlist, error) {
+	al, err := rm.fetch()
+	if err == nil {
+		return al, nil
+	}
+	if rm.Cache == "" {
+		return nil, err
+	}
+	data, cacheErr := os.ReadFile(rm.Cache)
+	if cacheErr != nil {
+		return nil, fmt.Errorf("%w (no cached copy: %v)", err, cacheErr)
+	}
+	al, cacheErr = parse(data, rm.yamlFormat())
+	if cacheErr != nil {
+		return nil, fmt.Errorf("%w (cached copy %s: %v)", err, rm.Cache, cacheErr)
+	}
+	slog.Warn("Allowlist: fetch failed, using the cached copy", "err", err, "cache", rm.Cache)
+	return al, nil
+}
+
+// yamlFormat reports whether the URL names a YAML allowlist
+func (rm *Remote) yamlFormat() bool {
+	u, err := url.Parse(rm.URL)
+	return err == nil && isYAML(u.Path)
+}
+
+// fetch downloads and parses the allowlist, saving it to the cache. It
+// returns errNotModified if the server reports the ETag is still current, or
+// sends the same copy again.
+func (rm *Remote) fetch() (*Allowlist, error) {
+	req, err := http.NewRequest(http.MethodGet, rm.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rm.etag != "" {
+		req.Header.Set("If-None-Match", rm.etag)
+	}
+	client := rm.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err // Names the URL already
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil, errNotModified
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("fetch %s: %s", rm.URL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", rm.URL, err)
+	}
+	if rm.last != nil && bytes.Equal(data, rm.last) {
+		return nil, errNotModified
+	}
+	al, err := parse(data, rm.yamlFormat())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rm.URL, err)
+	}
+
+	rm.etag, rm.last = resp.Header.Get("ETag"), data
+	if rm.Cache != "" {
+		if err := os.WriteFile(rm.Cache, data, 0o644); err != nil {
+			slog.Warn("Allowlist: failed to cache", "cache", rm.Cache, "err", err)
+		}
+	}
+	return al, nil
+}
+
+// WatchRemote fetches the allowlist every interval, replacing the allowed
+// apps whenever it changes and keeping the last good copy if a fetch fails.
+// Runs until stop is closed; reloaded, if not nil, signals after each change.
+func (al *Allowlist) WatchRemote(rm *Remote, interval time.Duration, stop <-chan struct{}, reloaded chan<- struct{}) {
+	al.poll(rm.URL, rm.fetch, interval, stop, reloaded)
+}
+
+// poll calls fetch every interval, replacing the allowed apps with each new
+// list from source. Fetches returning errNotModified or failing leave the list alone.
+func (al *Allowlist) poll(source string, fetch func() (*Allowlist, error), interval time.Duration, stop <-chan struct{}, reloaded chan<- struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			newList, err := fetch()
+			if errors.Is(err, errNotModified) {
+				continue
+			}
+			if err != nil {
+				al.reloadFailed(source, err)
+				continue
+			}
+			before := al.Apps()
+			al.update(newList)
+			al.reloaded(source, before, al.Apps())
+			signal(reloaded)
+		}
+	}
+}
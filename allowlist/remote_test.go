@@ -0,0 +1,166 @@
+// ABOUTME: Tests for allowlists fetched over HTTP.
+// ABOUTME: Covers ETag polling, YAML by URL path, keeping the last good copy, and the startup cache fallback.
+
+package allowlist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listServer serves an allowlist with an ETag, or fails with status if set
+type listServer struct {
+	mu       sync.Mutex
+	body     string
+	etag     string
+	status   int
+	requests int
+	notMod   int
+}
+
+func (ls *listServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.requests++
+	switch {
+	case ls.status != 0:
+		w.WriteHeader(ls.status)
+	case ls.etag != "" && r.Header.Get("If-None-Match") == ls.etag:
+		ls.notMod++
+		w.WriteHeader(http.StatusNotModified)
+	default:
+		w.Header().Set("ETag", ls.etag)
+		w.Write([]byte(ls.body))
+	}
+}
+
+func (ls *listServer) set(body, etag string, status int) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.body, ls.etag, ls.status = body, etag, status
+}
+
+func TestRemote_WatchETag(t *testing.T) {
+	ls := &listServer{body: "checkout\n", etag: `"v1"`}
+	srv := httptest.NewServer(ls)
+	defer srv.Close()
+
+	rm := &Remote{URL: srv.URL + "/allowlist.txt"}
+	al, err := rm.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !al.IsAppAllowed("checkout") {
+		t.Fatal("fetched list should allow checkout")
+	}
+
+	// An unchanged list is answered with 304 and left alone
+	if _, err := rm.fetch(); err != errNotModified || ls.notMod != 1 {
+		t.Errorf("fetch = %v after %d 304s, want errNotModified", err, ls.notMod)
+	}
+
+	stop := make(chan struct{})
+	reloaded := make(chan struct{}, 1)
+	defer close(stop)
+	go al.WatchRemote(rm, 10*time.Millisecond, stop, reloaded)
+
+	ls.set("orders\n", `"v2"`, 0)
+	select {
+	case <-reloaded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the new list")
+	}
+	if al.IsAppAllowed("checkout") || !al.IsAppAllowed("orders") {
+		t.Error("changed list should replace the allowed apps")
+	}
+}
+
+func TestRemote_KeepsLastGoodCopy(t *testing.T) {
+	ls := &listServer{body: "checkout\n"}
+	srv := httptest.NewServer(ls)
+	defer srv.Close()
+
+	rm := &Remote{URL: srv.URL + "/apps"}
+	al, err := rm.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	reloaded := make(chan struct{}, 1)
+	defer close(stop)
+	go al.WatchRemote(rm, 10*time.Millisecond, stop, reloaded)
+
+	// Server errors and invalid lists are both ignored
+	ls.set("", "", http.StatusInternalServerError)
+	time.Sleep(50 * time.Millisecond)
+	ls.set("/[z/\n", "", 0)
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-reloaded:
+		t.Fatal("failed fetches should not replace the list")
+	default:
+	}
+	if !al.IsAppAllowed("checkout") || al.IsAppAllowed("orders") {
+		t.Error("the last good copy should stay in effect")
+	}
+}
+
+func TestRemote_YAMLByPath(t *testing.T) {
+	srv := httptest.NewServer(&listServer{body: "apps:\n  - app: payment-*\n    index: tas_payments\n"})
+	defer srv.Close()
+
+	al, err := (&Remote{URL: srv.URL + "/allowlist.yaml?team=payments"}).Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if p, _ := al.Policy(nil, makeLogRecord("payment-api")); p.Index != "tas_payments" {
+		t.Errorf("Policy index = %q, want tas_payments", p.Index)
+	}
+}
+
+func TestRemote_CacheFallback(t *testing.T) {
+	ls := &listServer{body: "checkout\n"}
+	srv := httptest.NewServer(ls)
+	defer srv.Close()
+	cache := filepath.Join(t.TempDir(), "allowlist.cache")
+
+	if _, err := (&Remote{URL: srv.URL, Cache: cache}).Load(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(cache); string(data) != "checkout\n" {
+		t.Fatalf("cache = %q, want the fetched list", data)
+	}
+
+	// With the server down, startup uses the cached copy
+	ls.set("", "", http.StatusServiceUnavailable)
+	al, err := (&Remote{URL: srv.URL, Cache: cache}).Load()
+	if err != nil {
+		t.Fatalf("Load with cache failed: %v", err)
+	}
+	if !al.IsAppAllowed("checkout") {
+		t.Error("cached copy should allow checkout")
+	}
+
+	if _, err := (&Remote{URL: srv.URL}).Load(); err == nil {
+		t.Error("Load without a cache should fail when the server does")
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	for source, want := range map[string]bool{
+		"https://lists.example.com/apps": true,
+		"http://localhost:8080/apps":     true,
+		"/etc/otlp/allowlist.txt":        false,
+		"allowlist.yaml":                 false,
+	} {
+		if got := IsURL(source); got != want {
+			t.Errorf("IsURL(%s) = %v, want %v", source, got, want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// ABOUTME: Hot-reload of list files, with fsnotify or by polling where it is unavailable.
+// ABOUTME: Survives editors that save by replacing the file, and logs and counts every reload.
+
+package allowlist
+
+import (
+	"cmp"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// Ways a list file is watched for changes
+const (
+	WatchFsnotify = "fsnotify"
+	WatchPoll     = "poll"
+)
+
+// defaultPollInterval is how often unwatchable files are checked unless SetPollInterval is called
+const defaultPollInterval = 2 * time.Second
+
+// rewatchTimeout is how long a replaced file may take to reappear before polling for it
+const rewatchTimeout = 2 * time.Second
+
+// newWatcher creates file watchers; tests replace it to force polling
+var newWatcher = fsnotify.NewWatcher
+
+// SetPollInterval sets how often files are checked for changes when fsnotify
+// cannot watch them, as on some network volumes
+func (al *Allowlist) SetPollInterval(d time.Duration) {
+	al.pollInterval = d
+}
+
+// SetMetrics reports each watched file's watch mode and reloads to m
+func (al *Allowlist) SetMetrics(m *metrics.Metrics) {
+	al.metrics = m
+}
+
+// WatchFile watches the allowlist file for changes and reloads when modified.
+// If fsnotify cannot watch it, the file's modification time and size are
+// polled instead. Runs until stop channel is closed. Accepts optional channels:
+//   - reloaded: signals after each successful reload
+//   - ready: signals when watcher is initialized and listening
+func (al *Allowlist) WatchFile(path string, stop <-chan struct{}, reloaded chan<- struct{}, ready chan<- struct{}) {
+	al.watchFile(path, al.reload, stop, reloaded, ready)
+}
+
+// WatchDenylist watches the denylist file like WatchFile, reloading the denied apps
+func (al *Allowlist) WatchDenylist(path string, stop <-chan struct{}, reloaded chan<- struct{}, ready chan<- struct{}) {
+	al.watchFile(path, al.reloadDenylist, stop, reloaded, ready)
+}
+
+// watchFile calls reload with path each time the file is written or
+// replaced, falling back to polling if fsnotify cannot watch it. reload
+// reports whether the file loaded.
+func (al *Allowlist) watchFile(path string, reload func(string) bool, stop <-chan struct{}, reloaded chan<- struct{}, ready chan<- struct{}) {
+	interval := cmp.Or(al.pollInterval, defaultPollInterval)
+	watcher, err := newWatcher()
+	if err == nil {
+		if err = watcher.Add(path); err != nil {
+			watcher.Close()
+		}
+	}
+	if err != nil {
+		slog.Warn("Allowlist: cannot watch, polling instead", "path", path, "err", err, "interval", interval)
+		al.setWatchMode(path, WatchPoll)
+		pollFile(path, interval, reload, stop, reloaded, ready)
+		return
+	}
+	defer watcher.Close()
+	al.setWatchMode(path, WatchFsnotify)
+
+	// Signal that watcher is ready
+	if ready != nil {
+		close(ready)
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			switch {
+			case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+				// Editors often save by renaming a new file over the old
+				// one, which ends the watch; follow the new file instead
+				if !rewatch(watcher, path, stop) {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					slog.Warn("Allowlist: file did not reappear, polling for it", "path", path, "interval", interval)
+					al.setWatchMode(path, WatchPoll)
+					pollFile(path, interval, reload, stop, reloaded, nil)
+					return
+				}
+				if reload(path) {
+					signal(reloaded)
+				}
+			case event.Has(fsnotify.Write) || event.Has(fsnotify.Create):
+				if reload(path) {
+					signal(reloaded)
+				}
+			}
+		case _, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// rewatch adds path back to the watcher once a file exists there again,
+// giving up after rewatchTimeout or when stop is closed
+func rewatch(watcher *fsnotify.Watcher, path string, stop <-chan struct{}) bool {
+	deadline := time.After(rewatchTimeout)
+	for {
+		if err := watcher.Add(path); err == nil {
+			return true
+		}
+		select {
+		case <-stop:
+			return false
+		case <-deadline:
+			return false
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+// pollFile calls reload with path each time the file's modification time or
+// size changes, checking every interval
+func pollFile(path string, interval time.Duration, reload func(string) bool, stop <-chan struct{}, reloaded chan<- struct{}, ready chan<- struct{}) {
+	last, _ := os.Stat(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	if ready != nil {
+		close(ready)
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+				continue
+			}
+			last = info
+			if reload(path) {
+				signal(reloaded)
+			}
+		}
+	}
+}
+
+// signal notifies ch, if not nil, without blocking
+func signal(ch chan<- struct{}) {
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// setWatchMode reports how path is being watched
+func (al *Allowlist) setWatchMode(path, mode string) {
+	if al.metrics == nil {
+		return
+	}
+	for _, m := range []string{WatchFsnotify, WatchPoll} {
+		active := 0.0
+		if m == mode {
+			active = 1
+		}
+		al.metrics.AllowlistWatchMode.WithLabelValues(path, m).Set(active)
+	}
+}
+
+// reload reads the file and updates the allowlist, keeping the existing list on error
+func (al *Allowlist) reload(path string) bool {
+	newList, err := LoadFromFile(path)
+	if err != nil {
+		al.reloadFailed(path, err)
+		return false
+	}
+	before := al.Apps()
+	al.update(newList)
+	al.reloaded(path, before, al.Apps())
+	return true
+}
+
+// update replaces the allowed apps and their policies with newList's
+func (al *Allowlist) update(newList *Allowlist) {
+	al.mu.Lock()
+	al.entries = newList.entries
+	al.groups = newList.groups
+	al.apps = newList.apps
+	al.policies = newList.policies
+	al.mu.Unlock()
+}
+
+// reloadDenylist reads the file and updates the denied apps, keeping the existing list on error
+func (al *Allowlist) reloadDenylist(path string) bool {
+	before := al.Denied()
+	if err := al.LoadDenylist(path); err != nil {
+		al.reloadFailed(path, err)
+		return false
+	}
+	al.reloaded(path, before, al.Denied())
+	return true
+}
+
+// reloaded logs and counts a reload of source, with how many entries it added and removed
+func (al *Allowlist) reloaded(source string, before, after []string) {
+	added, removed := 0, 0
+	for _, entry := range after {
+		if !slices.Contains(before, entry) {
+			added++
+		}
+	}
+	for _, entry := range before {
+		if !slices.Contains(after, entry) {
+			removed++
+		}
+	}
+	slog.Info("Allowlist: reloaded", "source", source, "entries", len(after), "added", added, "removed", removed)
+	if al.metrics != nil {
+		al.metrics.AllowlistReloads.WithLabelValues(source).Inc()
+	}
+}
+
+// reloadFailed logs and counts a reload of source that failed
+func (al *Allowlist) reloadFailed(source string, err error) {
+	slog.Error("Allowlist: reload failed, keeping the current list", "source", source, "err", err)
+	if al.metrics != nil {
+		al.metrics.AllowlistReloadErrors.WithLabelValues(source).Inc()
+	}
+}
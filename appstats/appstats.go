@@ -0,0 +1,159 @@
+// ABOUTME: Per-app session tracking for onboarding checks.
+// ABOUTME: Records when each app was seen, its volume, severity mix, routing, and data-quality issues.
+
+package appstats
+
+import (
+	"maps"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UnknownApp is the name used for records without an app name
+const UnknownApp = "(unknown)"
+
+// App holds the tracked statistics for one app
+type App struct {
+	Name      string    `json:"name"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Records   int64     `json:"records"`
+}
+
+// Report is the onboarding summary for one app
+type Report struct {
+	App
+	BodyBytes        int64            `json:"body_bytes"`
+	Delivered        int64            `json:"delivered"`
+	Dropped          map[string]int64 `json:"dropped"`
+	SeverityMix      map[string]int64 `json:"severity_mix"`
+	Indexes          map[string]int64 `json:"index_distribution"`
+	Redactions       int64            `json:"redactions"`
+	SchemaViolations map[string]int64 `json:"schema_violations"`
+	AllowlistStatus  string           `json:"allowlist_status,omitempty"`
+}
+
+// Tracker accumulates per-app statistics for the session
+type Tracker struct {
+	mu   sync.RWMutex
+	apps map[string]*Report
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{apps: make(map[string]*Report)}
+}
+
+// Observe records that a log record from app was received at the given time,
+// with its severity, body size, and any schema violations found
+func (t *Tracker) Observe(app string, at time.Time, severity string, bodyBytes int, violations []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := t.getLocked(app, at)
+	if at.Before(r.FirstSeen) {
+		r.FirstSeen = at
+	}
+	if at.After(r.LastSeen) {
+		r.LastSeen = at
+	}
+	r.Records++
+	r.BodyBytes += int64(bodyBytes)
+	r.SeverityMix[severity]++
+	for _, v := range violations {
+		r.SchemaViolations[v]++
+	}
+}
+
+// RecordDrop records that a record from app was dropped for the given reason
+func (t *Tracker) RecordDrop(app, reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.getLocked(app, time.Now().UTC()).Dropped[reason]++
+}
+
+// RecordDelivery records that a record from app was routed to indexes after
+// the given number of redactions
+func (t *Tracker) RecordDelivery(app string, indexes []string, redactions int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := t.getLocked(app, time.Now().UTC())
+	r.Delivered++
+	for _, index := range indexes {
+		r.Indexes[index]++
+	}
+	r.Redactions += int64(redactions)
+}
+
+// RecordAllowlist records the allowlist's decision for app's latest record:
+// allowed, not allowed, denied, or no allowlist
+func (t *Tracker) RecordAllowlist(app, status string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.getLocked(app, time.Now().UTC()).AllowlistStatus = status
+}
+
+// getLocked returns the report for app, creating it if needed. Caller must hold mu.
+func (t *Tracker) getLocked(app string, at time.Time) *Report {
+	if app == "" {
+		app = UnknownApp
+	}
+	r, ok := t.apps[app]
+	if !ok {
+		r = &Report{
+			App:              App{Name: app, FirstSeen: at, LastSeen: at},
+			Dropped:          make(map[string]int64),
+			SeverityMix:      make(map[string]int64),
+			Indexes:          make(map[string]int64),
+			SchemaViolations: make(map[string]int64),
+		}
+		t.apps[app] = r
+	}
+	return r
+}
+
+// Get returns a copy of the statistics for one app
+func (t *Tracker) Get(app string) (App, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	r, ok := t.apps[app]
+	if !ok {
+		return App{}, false
+	}
+	return r.App, true
+}
+
+// Report returns a copy of the full onboarding report for one app
+func (t *Tracker) Report(app string) (Report, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	r, ok := t.apps[app]
+	if !ok {
+		return Report{}, false
+	}
+	cp := *r
+	cp.Dropped = maps.Clone(r.Dropped)
+	cp.SeverityMix = maps.Clone(r.SeverityMix)
+	cp.Indexes = maps.Clone(r.Indexes)
+	cp.SchemaViolations = maps.Clone(r.SchemaViolations)
+	return cp, true
+}
+
+// Apps returns a copy of every tracked app, sorted by name
+func (t *Tracker) Apps() []App {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	apps := make([]App, 0, len(t.apps))
+	for _, r := range t.apps {
+		apps = append(apps, r.App)
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+	return apps
+}
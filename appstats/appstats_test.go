@@ -0,0 +1,105 @@
+// ABOUTME: Tests for per-app session tracking.
+// ABOUTME: Covers first/last seen timestamps, record counts, and ordering.
+
+package appstats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTracker_FirstAndLastSeen(t *testing.T) {
+	tr := NewTracker()
+	t0 := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tr.Observe("payment-service", t0.Add(time.Minute), "INFO", 10, nil)
+	tr.Observe("payment-service", t0, "INFO", 10, nil)
+	tr.Observe("payment-service", t0.Add(5*time.Minute), "ERROR", 10, nil)
+
+	a, ok := tr.Get("payment-service")
+	if !ok {
+		t.Fatal("payment-service should be tracked")
+	}
+	if !a.FirstSeen.Equal(t0) {
+		t.Errorf("FirstSeen = %v, want %v", a.FirstSeen, t0)
+	}
+	if !a.LastSeen.Equal(t0.Add(5 * time.Minute)) {
+		t.Errorf("LastSeen = %v, want %v", a.LastSeen, t0.Add(5*time.Minute))
+	}
+	if a.Records != 3 {
+		t.Errorf("Records = %d, want 3", a.Records)
+	}
+}
+
+func TestTracker_UnknownAppAndSorting(t *testing.T) {
+	tr := NewTracker()
+	now := time.Now()
+	tr.Observe("zeta", now, "INFO", 0, nil)
+	tr.Observe("", now, "INFO", 0, nil)
+	tr.Observe("alpha", now, "INFO", 0, nil)
+
+	apps := tr.Apps()
+	if len(apps) != 3 {
+		t.Fatalf("expected 3 apps, got %d", len(apps))
+	}
+	if apps[0].Name != UnknownApp || apps[1].Name != "alpha" || apps[2].Name != "zeta" {
+		t.Errorf("unexpected order: %v, %v, %v", apps[0].Name, apps[1].Name, apps[2].Name)
+	}
+}
+
+func TestTracker_GetMissing(t *testing.T) {
+	if _, ok := NewTracker().Get("nope"); ok {
+		t.Error("untracked app should not be found")
+	}
+}
+
+func TestTracker_Report(t *testing.T) {
+	tr := NewTracker()
+	now := time.Now()
+	tr.Observe("payment-service", now, "INFO", 100, nil)
+	tr.Observe("payment-service", now, "ERROR", 50, []string{"missing_timestamp"})
+	tr.Observe("payment-service", now, "DEBUG", 20, []string{"missing_timestamp", "empty_body"})
+	tr.RecordDelivery("payment-service", []string{"tas_logs", "tas_archive"}, 1)
+	tr.RecordDelivery("payment-service", []string{"tas_errors"}, 0)
+	tr.RecordDrop("payment-service", "sampled")
+
+	r, ok := tr.Report("payment-service")
+	if !ok {
+		t.Fatal("report should exist")
+	}
+	if r.Records != 3 || r.BodyBytes != 170 || r.Delivered != 2 {
+		t.Errorf("Records/BodyBytes/Delivered = %d/%d/%d, want 3/170/2", r.Records, r.BodyBytes, r.Delivered)
+	}
+	if r.SeverityMix["ERROR"] != 1 || r.SeverityMix["DEBUG"] != 1 {
+		t.Errorf("SeverityMix = %v", r.SeverityMix)
+	}
+	if r.Indexes["tas_logs"] != 1 || r.Indexes["tas_errors"] != 1 || r.Indexes["tas_archive"] != 1 {
+		t.Errorf("Indexes = %v", r.Indexes)
+	}
+	if r.Redactions != 1 {
+		t.Errorf("Redactions = %d, want 1", r.Redactions)
+	}
+	if r.Dropped["sampled"] != 1 {
+		t.Errorf("Dropped = %v", r.Dropped)
+	}
+	if r.SchemaViolations["missing_timestamp"] != 2 || r.SchemaViolations["empty_body"] != 1 {
+		t.Errorf("SchemaViolations = %v", r.SchemaViolations)
+	}
+
+	// Report must be a copy
+	r.Indexes["tas_logs"] = 99
+	again, _ := tr.Report("payment-service")
+	if again.Indexes["tas_logs"] != 1 {
+		t.Error("Report should return an independent copy")
+	}
+}
+
+func TestTracker_RecordAllowlist(t *testing.T) {
+	tr := NewTracker()
+	tr.RecordAllowlist("payment-service", "allowed")
+	tr.RecordAllowlist("payment-service", "not allowed")
+
+	if r, _ := tr.Report("payment-service"); r.AllowlistStatus != "not allowed" {
+		t.Errorf("AllowlistStatus = %q, want the latest decision", r.AllowlistStatus)
+	}
+}
@@ -0,0 +1,62 @@
+// ABOUTME: Startup banner summarizing the receiver's configuration.
+// ABOUTME: Printed as aligned console lines with -log-format pretty, or logged as one record with an attribute per label.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// bannerRule frames the pretty banner
+const bannerRule = "========================================"
+
+// bannerLine is one labelled line of the banner
+type bannerLine struct {
+	label string
+	value string
+}
+
+// banner collects the startup summary
+type banner struct {
+	lines []bannerLine
+}
+
+// add appends a line, its value formatted as by fmt.Sprintf
+func (b *banner) add(label, format string, args ...any) {
+	b.lines = append(b.lines, bannerLine{label, fmt.Sprintf(format, args...)})
+}
+
+// log prints the banner as framed console lines, or logs it as one record
+// with an attribute per label, repeated labels joined with "; "
+func (b *banner) log(pretty bool) {
+	if pretty {
+		slog.Info(bannerRule)
+		slog.Info("  OTLP Mock Receiver")
+		slog.Info("  Practice environment for TAS logging")
+		slog.Info(bannerRule)
+		for _, l := range b.lines {
+			slog.Info(fmt.Sprintf("  %-15s%s", l.label+":", l.value))
+		}
+		slog.Info(bannerRule)
+		slog.Info("")
+		return
+	}
+
+	var keys []string
+	values := make(map[string][]string)
+	for _, l := range b.lines {
+		key := strings.ReplaceAll(strings.ToLower(l.label), " ", "_")
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = append(values[key], l.value)
+	}
+	attrs := make([]slog.Attr, len(keys))
+	for i, key := range keys {
+		attrs[i] = slog.String(key, strings.Join(values[key], "; "))
+	}
+	slog.LogAttrs(context.Background(), slog.LevelInfo, "OTLP Mock Receiver starting", attrs...)
+}
@@ -0,0 +1,146 @@
+// ABOUTME: Compiles and evaluates Common Expression Language expressions over log records with cel-go.
+// ABOUTME: Programs read severity, body, attributes, and resource attributes and evaluate to a bool.
+
+package cel
+
+import (
+	"fmt"
+	"sync"
+
+	celgo "github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"github.com/google/cel-go/interpreter"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Activation is the log record, and the resource it belongs to, that an
+// expression reads. Attribute maps are converted on first use.
+type Activation struct {
+	Resource *resourcepb.Resource
+	Log      *logspb.LogRecord
+
+	attributes, resource map[string]any
+}
+
+// NewActivation creates an activation for a record and its resource, which may be nil
+func NewActivation(resource *resourcepb.Resource, lr *logspb.LogRecord) *Activation {
+	return &Activation{Resource: resource, Log: lr}
+}
+
+// ResolveName returns the value of a record variable
+func (act *Activation) ResolveName(name string) (any, bool) {
+	switch name {
+	case "severity":
+		return int64(act.Log.GetSeverityNumber()), true
+	case "severity_text":
+		return act.Log.GetSeverityText(), true
+	case "body":
+		return fromAnyValue(act.Log.GetBody()), true
+	case "attributes":
+		if act.attributes == nil {
+			act.attributes = fromKeyValues(act.Log.GetAttributes())
+		}
+		return act.attributes, true
+	case "resource":
+		if act.resource == nil {
+			act.resource = fromKeyValues(act.Resource.GetAttributes())
+		}
+		return act.resource, true
+	}
+	return nil, false
+}
+
+// Parent returns nil: the record variables are the only scope
+func (act *Activation) Parent() interpreter.Activation {
+	return nil
+}
+
+// env declares the record variables, with the standard library and the
+// strings extension. Literal regexes are checked at compile time.
+var env = sync.OnceValues(func() (*celgo.Env, error) {
+	return celgo.NewEnv(
+		celgo.Variable("severity", celgo.IntType),
+		celgo.Variable("severity_text", celgo.StringType),
+		celgo.Variable("body", celgo.DynType),
+		celgo.Variable("attributes", celgo.MapType(celgo.StringType, celgo.DynType)),
+		celgo.Variable("resource", celgo.MapType(celgo.StringType, celgo.DynType)),
+		ext.Strings(),
+		celgo.ASTValidators(celgo.ValidateRegexLiterals()),
+	)
+})
+
+// Program is a compiled expression
+type Program struct {
+	Text string
+
+	prg celgo.Program
+}
+
+// Compile parses and checks an expression, which must evaluate to a bool
+func Compile(text string) (*Program, error) {
+	e, err := env()
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := e.Compile(text)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if t := ast.OutputType(); !t.IsExactType(celgo.BoolType) && !t.IsExactType(celgo.DynType) {
+		return nil, fmt.Errorf("expression is %s, not bool", t)
+	}
+	prg, err := e.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{Text: text, prg: prg}, nil
+}
+
+// Eval evaluates the program against a record. Runtime errors, such as a
+// missing map key, are returned rather than treated as false.
+func (p *Program) Eval(act *Activation) (bool, error) {
+	v, _, err := p.prg.Eval(act)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression is %s, not bool", v.Type())
+	}
+	return b, nil
+}
+
+// fromAnyValue converts an OTLP value to its Go representation
+func fromAnyValue(v *commonpb.AnyValue) any {
+	switch x := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return x.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return x.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return x.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return x.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return x.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, len(x.ArrayValue.GetValues()))
+		for i, item := range x.ArrayValue.GetValues() {
+			values[i] = fromAnyValue(item)
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		return fromKeyValues(x.KvlistValue.GetValues())
+	}
+	return nil
+}
+
+func fromKeyValues(kvs []*commonpb.KeyValue) map[string]any {
+	m := make(map[string]any, len(kvs))
+	for _, kv := range kvs {
+		m[kv.GetKey()] = fromAnyValue(kv.GetValue())
+	}
+	return m
+}
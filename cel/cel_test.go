@@ -0,0 +1,151 @@
+// ABOUTME: Tests for evaluating CEL expressions against log records.
+// ABOUTME: Covers record variables, operators, functions, compile errors, and runtime error handling.
+
+package cel
+
+import (
+	"strings"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func kv(key string, value *commonpb.AnyValue) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: value}
+}
+
+func str(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func newActivation() *Activation {
+	return NewActivation(
+		&resourcepb.Resource{Attributes: []*commonpb.KeyValue{kv("cf_space_name", str("production")), kv("cf_org_name", str("acme"))}},
+		&logspb.LogRecord{
+			SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+			SeverityText:   "WARN",
+			Body:           str("payment 1234 declined: timeout"),
+			Attributes: []*commonpb.KeyValue{
+				kv("cf_app_name", str("payments-api")),
+				kv("status", &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 503}}),
+				kv("latency", &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 1.5}}),
+				kv("cf.source", str("APP/PROC/WEB/0")),
+			},
+		},
+	)
+}
+
+func eval(t *testing.T, expr string) (bool, error) {
+	t.Helper()
+	p, err := Compile(expr)
+	if err != nil {
+		t.Fatalf("Compile(%s): %v", expr, err)
+	}
+	return p.Eval(newActivation())
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`severity >= 13`, true},
+		{`severity_text == "WARN"`, true},
+		{`body.contains("declined")`, true},
+		{`body.matches("^payment \\d+")`, true},
+		{`body.matches(r"^payment \d+")`, true},
+		{`matches(body, '[0-9]{5}')`, false},
+		{`attributes.cf_app_name.startsWith("payments-")`, true},
+		{`attributes["cf.source"].endsWith("/0")`, true},
+		{`resource.cf_space_name == "production" && attributes.status >= 500`, true},
+		{`resource.cf_space_name != "production" || attributes.status == 200`, false},
+		{`attributes.status in [500, 502, 503]`, true},
+		{`"cf_app_name" in attributes`, true},
+		{`"env" in attributes`, false},
+		{`has(attributes.cf_app_name) && !has(attributes.env)`, true},
+		{`attributes.latency > 1`, true},
+		{`attributes.status == 503.0`, true},
+		{`attributes.status / 100 == 5 && attributes.status % 100 == 3`, true},
+		{`size(body) > 10 && body.size() < 100`, true},
+		{`int("42") + 1 == 43 && double(attributes.status) == 503.0 && string(severity) == "13"`, true},
+		{`attributes.cf_app_name.upperAscii() == "PAYMENTS-API"`, true},
+		{`(severity >= 17 ? "high" : "low") == "low"`, true},
+		{`-severity < 0 && -1 < 0`, true},
+		{`"a" + 'b' == "ab" && [1] + [2] == [1, 2]`, true},
+		{`'it\'s' == "it's"`, true},
+		// A missing key is an error unless the other side of && or || decides
+		{`has(attributes.env) && attributes.env == "prod"`, false},
+		{`attributes.env == "prod" || severity >= 13`, true},
+		{`attributes.env == "prod" && false`, false},
+	}
+	for _, tt := range tests {
+		got, err := eval(t, tt.expr)
+		if err != nil {
+			t.Errorf("Eval(%s): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%s) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEval_RuntimeErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`attributes.env == "prod"`, "no such key: env"},
+		{`attributes["env"] == "prod"`, "no such key: env"},
+		{`attributes.status.startsWith("5")`, "no such overload"},
+		{`attributes.status + 1.0 > 0.0`, "no such overload"},
+		{`attributes.status / 0 == 1`, "division by zero"},
+		{`attributes.cf_app_name`, "expression is string, not bool"},
+		{`body.matches(attributes.cf_app_name + "(")`, "error parsing regexp"},
+	}
+	for _, tt := range tests {
+		_, err := eval(t, tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Eval(%s) error = %v, want it to contain %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestEval_NilResourceAndStructuredBody(t *testing.T) {
+	p, err := Compile(`body.user == "alice" && size(resource) == 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+		Values: []*commonpb.KeyValue{kv("user", str("alice"))},
+	}}}
+	ok, err := p.Eval(NewActivation(nil, &logspb.LogRecord{Body: body}))
+	if err != nil || !ok {
+		t.Errorf("Eval = %v, %v, want true", ok, err)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`severity`, "expression is int, not bool"},
+		{`body.contains("x"`, "Syntax error"},
+		{`level == "warn"`, "undeclared reference to 'level'"},
+		{`now() > 0`, "undeclared reference to 'now'"},
+		{`severity.startsWith("1")`, "found no matching overload for 'startsWith'"},
+		{`severity < "5"`, "found no matching overload for '_<_'"},
+		{`has(attributes)`, "invalid argument to has() macro"},
+		{`body.matches("(")`, "invalid matches argument"},
+		{`severity = 1`, "Syntax error"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%s) error = %v, want it to contain %q", tt.expr, err, tt.want)
+		}
+	}
+}
@@ -16,12 +16,22 @@ import (
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
 	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/idgen"
 )
 
 func main() {
 	endpoint := flag.String("endpoint", "localhost:4317", "OTLP gRPC endpoint")
+	count := flag.Int("count", 1, "Number of log records to send")
+	idStrategy := flag.String("id-strategy", idgen.StrategyNone, "Trace/span ID strategy: none, random, sequential, or derived (app + counter)")
 	flag.Parse()
 
+	ids, err := idgen.New(*idStrategy)
+	if err != nil {
+		log.Fatalf("Invalid -id-strategy: %v", err)
+	}
+	const appName = "payment-service"
+
 	conn, err := grpc.Dial(*endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
@@ -30,12 +40,31 @@ func main() {
 
 	client := collogspb.NewLogsServiceClient(conn)
 
+	var records []*logspb.LogRecord
+	for i := 0; i < *count; i++ {
+		traceID, spanID := ids.Next(appName)
+		records = append(records, &logspb.LogRecord{
+			TimeUnixNano:   uint64(time.Now().UnixNano()),
+			SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+			SeverityText:   "INFO",
+			Body:           strVal("Payment processed for order #12345. Card: 4111-1111-1111-1111"),
+			Attributes: []*commonpb.KeyValue{
+				{Key: "source_type", Value: strVal("APP/PROC/WEB")},
+			},
+			TraceId: traceID,
+			SpanId:  spanID,
+		})
+		if traceID != nil {
+			log.Printf("Record %d: trace_id=%s span_id=%s", i+1, idgen.Format(traceID), idgen.Format(spanID))
+		}
+	}
+
 	req := &collogspb.ExportLogsServiceRequest{
 		ResourceLogs: []*logspb.ResourceLogs{
 			{
 				Resource: &resourcepb.Resource{
 					Attributes: []*commonpb.KeyValue{
-						{Key: "application_name", Value: strVal("payment-service")},
+						{Key: "application_name", Value: strVal(appName)},
 						{Key: "organization_name", Value: strVal("acme-prod")},
 						{Key: "space_name", Value: strVal("production")},
 						{Key: "instance_id", Value: strVal("0")},
@@ -49,17 +78,7 @@ func main() {
 							Name:    "cf.loggregator",
 							Version: "1.0.0",
 						},
-						LogRecords: []*logspb.LogRecord{
-							{
-								TimeUnixNano:   uint64(time.Now().UnixNano()),
-								SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
-								SeverityText:   "INFO",
-								Body:           strVal("Payment processed for order #12345. Card: 4111-1111-1111-1111"),
-								Attributes: []*commonpb.KeyValue{
-									{Key: "source_type", Value: strVal("APP/PROC/WEB")},
-								},
-							},
-						},
+						LogRecords: records,
 					},
 				},
 			},
@@ -74,7 +93,7 @@ func main() {
 		log.Fatalf("Failed to export: %v", err)
 	}
 
-	log.Println("Successfully sent test log")
+	log.Printf("Successfully sent %d test log(s)", *count)
 }
 
 func strVal(s string) *commonpb.AnyValue {
@@ -0,0 +1,151 @@
+// ABOUTME: CLI command tree and subcommand dispatch.
+// ABOUTME: Running without a subcommand (or with only flags) starts the receiver.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const programName = "otlp-mock-receiver"
+
+// command is a node in the CLI command tree
+type command struct {
+	name    string
+	summary string
+	usage   string   // positional argument synopsis, e.g. "bash|zsh|fish"
+	args    []string // positional argument values offered by shell completion
+	// setup registers the command's flags and returns the function that runs it
+	setup func(fs *flag.FlagSet) func(args []string) error
+}
+
+// defaultCommand runs when no subcommand is given
+const defaultCommand = "serve"
+
+// commands returns the CLI command tree in display order
+func commands() []*command {
+	return []*command{
+		{
+			name:    "serve",
+			summary: "Start the OTLP receiver (default when no command is given)",
+			setup:   serveFlags,
+		},
+		{
+			name:    "gen-fixtures",
+			summary: "Write synthetic records covering every routing rule as JSONL",
+			setup:   genFixturesFlags,
+		},
+		{
+			name:    "route-test",
+			summary: "Report where sample records or fixtures would be routed",
+			usage:   "[FILE...]",
+			setup:   routeTestFlags,
+		},
+		{
+			name:    "validate",
+			summary: "Lint routing config files for invalid, conflicting, and shadowed rules",
+			usage:   "FILE...",
+			setup:   validateFlags,
+		},
+		{
+			name:    "completion",
+			summary: "Generate a shell completion script",
+			usage:   "bash|zsh|fish",
+			args:    []string{"bash", "zsh", "fish"},
+			setup:   completionFlags,
+		},
+		{
+			name:    "docs",
+			summary: "Generate man pages or Markdown reference docs",
+			setup:   docsFlags,
+		},
+		{
+			name:    "help",
+			summary: "Show available commands",
+			setup: func(fs *flag.FlagSet) func(args []string) error {
+				return func(args []string) error {
+					printUsage(os.Stdout)
+					return nil
+				}
+			},
+		},
+	}
+}
+
+// findCommand looks up a command by name
+func findCommand(name string) *command {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// newFlagSet builds the flag set for a command with its usage message
+func newFlagSet(cmd *command) (*flag.FlagSet, func(args []string) error) {
+	fs := flag.NewFlagSet(programName+" "+cmd.name, flag.ExitOnError)
+	run := cmd.setup(fs)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s\n\n%s\n", synopsis(cmd), cmd.summary)
+		if hasFlags(fs) {
+			fmt.Fprintln(out, "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs, run
+}
+
+// execute dispatches to the subcommand named by the first argument.
+// Flags without a subcommand are passed to serve for backwards compatibility.
+func execute(args []string) error {
+	name := defaultCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name = args[0]
+		args = args[1:]
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		printUsage(os.Stderr)
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	fs, run := newFlagSet(cmd)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return run(fs.Args())
+}
+
+// synopsis returns the one-line invocation for a command
+func synopsis(cmd *command) string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s [flags] %s", programName, cmd.name, cmd.usage))
+}
+
+// printUsage lists the available commands
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [command] [flags]\n\nCommands:\n", programName)
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for command flags.\n", programName)
+}
+
+// hasFlags reports whether any flags are registered on fs
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// isBoolFlag reports whether a flag takes no value
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
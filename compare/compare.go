@@ -0,0 +1,208 @@
+// ABOUTME: Cross-checks records received over OTLP against a collector's file exporter output.
+// ABOUTME: Matches records by timestamp and body checksum to detect loss between pipelines.
+
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// DefaultGrace is how long an unmatched record may wait for its counterpart before it counts as lost
+const DefaultGrace = 30 * time.Second
+
+// MaxPending caps the unmatched records held; past it the oldest settle early, as lost
+const MaxPending = 100000
+
+// Comparer matches records seen by the receiver with records read from the collector's output
+type Comparer struct {
+	mu      sync.Mutex
+	grace   time.Duration
+	started time.Time
+	pending map[uint64]*entry
+	order   []pendingRef // Pending entries in the order first seen, including some matched since
+	max     int
+
+	receiver  side
+	collector side
+	matched   int64
+}
+
+// side accumulates totals for one source of records
+type side struct {
+	count    int64
+	checksum uint64 // Order-independent sum of record checksums
+	lost     int64  // Settled records never seen by the other side
+}
+
+// entry counts unmatched sightings of one record checksum
+type entry struct {
+	receiver  int
+	collector int
+	firstSeen time.Time
+}
+
+// pendingRef locates a pending entry in first-seen order
+type pendingRef struct {
+	sum uint64
+	e   *entry
+}
+
+// Report summarizes the comparison since the comparer started
+type Report struct {
+	Since              time.Time `json:"since"`
+	GraceSeconds       float64   `json:"grace_seconds"`
+	ReceiverRecords    int64     `json:"receiver_records"`
+	CollectorRecords   int64     `json:"collector_records"`
+	Matched            int64     `json:"matched"`
+	MissingAtReceiver  int64     `json:"missing_at_receiver"`  // In the collector output, never received over OTLP
+	MissingAtCollector int64     `json:"missing_at_collector"` // Received over OTLP, never written by the collector
+	Pending            int       `json:"pending"`              // Unmatched records still within the grace period
+	ReceiverChecksum   string    `json:"receiver_checksum"`
+	CollectorChecksum  string    `json:"collector_checksum"`
+}
+
+// New creates a comparer. Unmatched records settle as lost after grace.
+func New(grace time.Duration) *Comparer {
+	return &Comparer{
+		grace:   grace,
+		started: time.Now().UTC(),
+		pending: make(map[uint64]*entry),
+		max:     MaxPending,
+	}
+}
+
+// ObserveReceived records a log record as received over OTLP, before any transforms
+func (c *Comparer) ObserveReceived(lr *logspb.LogRecord) {
+	c.observe(Checksum(lr.GetTimeUnixNano(), bodyJSON(lr.GetBody())), false, time.Now())
+}
+
+// ObserveCollector records a log record read from the collector's output
+func (c *Comparer) ObserveCollector(sum uint64) {
+	c.observe(sum, true, time.Now())
+}
+
+// observe counts a record from one side and matches it against the other
+func (c *Comparer) observe(sum uint64, fromCollector bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := &c.receiver
+	if fromCollector {
+		s = &c.collector
+	}
+	s.count++
+	s.checksum += sum
+
+	e, ok := c.pending[sum]
+	if !ok {
+		e = &entry{firstSeen: now}
+		c.pending[sum] = e
+		c.order = append(c.order, pendingRef{sum, e})
+	}
+	if fromCollector {
+		e.collector++
+	} else {
+		e.receiver++
+	}
+	if e.receiver > 0 && e.collector > 0 {
+		e.receiver--
+		e.collector--
+		c.matched++
+	}
+	if e.receiver == 0 && e.collector == 0 {
+		delete(c.pending, sum)
+	}
+	c.settle(now)
+}
+
+// Report settles records older than the grace period and returns the current totals
+func (c *Comparer) Report() Report {
+	return c.report(time.Now())
+}
+
+func (c *Comparer) report(now time.Time) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.settle(now)
+	return Report{
+		Since:              c.started,
+		GraceSeconds:       c.grace.Seconds(),
+		ReceiverRecords:    c.receiver.count,
+		CollectorRecords:   c.collector.count,
+		Matched:            c.matched,
+		MissingAtReceiver:  c.collector.lost,
+		MissingAtCollector: c.receiver.lost,
+		Pending:            len(c.pending),
+		ReceiverChecksum:   fmt.Sprintf("%016x", c.receiver.checksum),
+		CollectorChecksum:  fmt.Sprintf("%016x", c.collector.checksum),
+	}
+}
+
+// settle counts records unmatched past the grace period, or past the cap on
+// pending records, as lost, oldest first. Caller holds mu.
+func (c *Comparer) settle(now time.Time) {
+	for len(c.order) > 0 {
+		ref := c.order[0]
+		if c.pending[ref.sum] == ref.e {
+			if now.Sub(ref.e.firstSeen) < c.grace && len(c.pending) <= c.max {
+				break
+			}
+			c.receiver.lost += int64(ref.e.receiver)
+			c.collector.lost += int64(ref.e.collector)
+			delete(c.pending, ref.sum)
+		}
+		c.order[0] = pendingRef{}
+		c.order = c.order[1:]
+	}
+}
+
+// String summarizes the report for logs
+func (r Report) String() string {
+	return fmt.Sprintf("receiver=%d collector=%d matched=%d missing_at_receiver=%d missing_at_collector=%d pending=%d",
+		r.ReceiverRecords, r.CollectorRecords, r.Matched, r.MissingAtReceiver, r.MissingAtCollector, r.Pending)
+}
+
+// Checksum identifies a record by its timestamp and canonical JSON body
+func Checksum(timeUnixNano uint64, body string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatUint(timeUnixNano, 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(body))
+	return h.Sum64()
+}
+
+// bodyJSON renders a body the same way the collector's file exporter does, canonicalized
+func bodyJSON(body *commonpb.AnyValue) string {
+	if body == nil {
+		return ""
+	}
+	data, err := protojson.Marshal(body)
+	if err != nil {
+		return ""
+	}
+	return canonicalJSON(data)
+}
+
+// canonicalJSON re-encodes JSON with sorted keys and no whitespace so equivalent
+// documents from different encoders compare equal
+func canonicalJSON(data []byte) string {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return string(data)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
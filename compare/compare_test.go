@@ -0,0 +1,114 @@
+// ABOUTME: Tests for receiver/collector record comparison.
+// ABOUTME: Covers matching, grace-period settlement, and body canonicalization.
+
+package compare
+
+import (
+	"testing"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func makeRecord(ts uint64, body string) *logspb.LogRecord {
+	return &logspb.LogRecord{
+		TimeUnixNano: ts,
+		Body:         &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}},
+	}
+}
+
+func TestComparer_MatchesReceiverAndCollector(t *testing.T) {
+	c := New(time.Minute)
+	lr := makeRecord(1700000000000000001, "hello")
+
+	c.ObserveReceived(lr)
+	c.ObserveCollector(Checksum(1700000000000000001, `{"stringValue":"hello"}`))
+
+	r := c.Report()
+	if r.Matched != 1 || r.Pending != 0 {
+		t.Errorf("Matched/Pending = %d/%d, want 1/0", r.Matched, r.Pending)
+	}
+	if r.ReceiverChecksum != r.CollectorChecksum {
+		t.Errorf("checksums differ: %s vs %s", r.ReceiverChecksum, r.CollectorChecksum)
+	}
+}
+
+func TestComparer_SettlesAfterGrace(t *testing.T) {
+	c := New(time.Second)
+	start := time.Now()
+
+	c.observe(Checksum(1, `{"stringValue":"only-received"}`), false, start)
+	c.observe(Checksum(2, `{"stringValue":"only-collected"}`), true, start)
+	c.observe(Checksum(3, `{"stringValue":"late"}`), true, start.Add(900*time.Millisecond))
+
+	r := c.report(start.Add(500 * time.Millisecond))
+	if r.Pending != 3 || r.MissingAtReceiver != 0 || r.MissingAtCollector != 0 {
+		t.Errorf("within grace: %+v", r)
+	}
+
+	r = c.report(start.Add(1500 * time.Millisecond))
+	if r.MissingAtCollector != 1 || r.MissingAtReceiver != 1 || r.Pending != 1 {
+		t.Errorf("after grace: %+v", r)
+	}
+}
+
+func TestComparer_SettlesWithoutReports(t *testing.T) {
+	c := New(time.Second)
+	start := time.Now()
+	for i := range 100 {
+		c.observe(Checksum(uint64(i), `{"stringValue":"unpolled"}`), false, start.Add(time.Duration(i)*100*time.Millisecond))
+	}
+	// Records past the grace period settled as later ones arrived
+	if n := len(c.pending); n > 11 {
+		t.Errorf("pending = %d, want only the last second's records", n)
+	}
+	if c.receiver.lost < 89 {
+		t.Errorf("lost = %d, want the records past grace counted", c.receiver.lost)
+	}
+}
+
+func TestComparer_CapsPending(t *testing.T) {
+	c := New(time.Hour)
+	c.max = 10
+	now := time.Now()
+	for i := range 25 {
+		c.observe(Checksum(uint64(i), `{"stringValue":"flood"}`), true, now)
+	}
+	r := c.report(now)
+	if r.Pending != 10 || r.MissingAtReceiver != 15 {
+		t.Errorf("Pending/MissingAtReceiver = %d/%d, want 10/15", r.Pending, r.MissingAtReceiver)
+	}
+	// The oldest settled first, so the newest can still match
+	c.observe(Checksum(24, `{"stringValue":"flood"}`), false, now)
+	if r := c.report(now); r.Matched != 1 {
+		t.Errorf("Matched = %d, want the newest record matched", r.Matched)
+	}
+}
+
+func TestComparer_DuplicatesCountedSeparately(t *testing.T) {
+	c := New(time.Minute)
+	sum := Checksum(5, `{"stringValue":"dup"}`)
+
+	c.observe(sum, false, time.Now())
+	c.observe(sum, false, time.Now())
+	c.observe(sum, true, time.Now())
+
+	r := c.Report()
+	if r.Matched != 1 || r.Pending != 1 {
+		t.Errorf("Matched/Pending = %d/%d, want 1/1", r.Matched, r.Pending)
+	}
+}
+
+func TestBodyJSON_MatchesCollectorEncoding(t *testing.T) {
+	body := &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+		Values: []*commonpb.KeyValue{
+			{Key: "count", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 42}}},
+		},
+	}}}
+	collector := `{ "kvlistValue": { "values": [ { "key": "count", "value": { "intValue": "42" } } ] } }`
+
+	if got, want := bodyJSON(body), canonicalJSON([]byte(collector)); got != want {
+		t.Errorf("bodyJSON = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,130 @@
+// ABOUTME: Tails a collector file exporter's OTLP/JSON output.
+// ABOUTME: Feeds each log record's checksum to a Comparer, following truncation and rotation.
+
+package compare
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportLine is the subset of an OTLP/JSON ExportLogsServiceRequest needed for checksums
+type exportLine struct {
+	ResourceLogs []struct {
+		ScopeLogs []struct {
+			LogRecords []struct {
+				TimeUnixNano json.RawMessage `json:"timeUnixNano"`
+				Body         json.RawMessage `json:"body"`
+			} `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+// Tailer reads newly appended lines from a collector output file
+type Tailer struct {
+	path     string
+	comparer *Comparer
+	offset   int64
+	partial  []byte // Incomplete trailing line from the last read
+}
+
+// NewTailer creates a tailer that starts at the current end of the file, so only
+// records written after startup are compared. A missing file is read from the start once it appears.
+func NewTailer(path string, c *Comparer) *Tailer {
+	t := &Tailer{path: path, comparer: c}
+	if info, err := os.Stat(path); err == nil {
+		t.offset = info.Size()
+	}
+	return t
+}
+
+// Run polls the file every interval until stop is closed
+func (t *Tailer) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := t.Poll(); err != nil && !os.IsNotExist(err) {
+				slog.Error("Compare: failed to read", "path", t.path, "err", err)
+			}
+		}
+	}
+}
+
+// Poll reads any complete lines appended since the last poll
+func (t *Tailer) Poll() error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	// Truncated or rotated: start over from the beginning
+	if info.Size() < t.offset {
+		t.offset = 0
+		t.partial = nil
+	}
+	if info.Size() == t.offset {
+		return nil
+	}
+
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	t.offset += int64(len(data))
+
+	data = append(t.partial, data...)
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		t.partial = data
+		return nil
+	}
+	t.partial = append([]byte(nil), data[end+1:]...)
+
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		t.readLine(line)
+	}
+	return nil
+}
+
+// readLine feeds every record in one exported request line to the comparer
+func (t *Tailer) readLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	var req exportLine
+	if err := json.Unmarshal(line, &req); err != nil {
+		slog.Warn("Compare: skipping unparseable line", "path", t.path, "err", err)
+		return
+	}
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			for _, lr := range sl.LogRecords {
+				ts, _ := strconv.ParseUint(strings.Trim(string(lr.TimeUnixNano), `"`), 10, 64)
+				body := ""
+				if len(lr.Body) > 0 {
+					body = canonicalJSON(lr.Body)
+				}
+				t.comparer.ObserveCollector(Checksum(ts, body))
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// ABOUTME: Tests for tailing collector file exporter output.
+// ABOUTME: Covers start-at-end, partial lines, truncation, and record extraction.
+
+package compare
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const exportedLine = `{"resourceLogs":[{"resource":{},"scopeLogs":[{"scope":{},"logRecords":[` +
+	`{"timeUnixNano":"1700000000000000001","body":{"stringValue":"hello"},"traceId":"5b8efff798038103d269b633813fc60c"},` +
+	`{"timeUnixNano":"1700000000000000002","body":{"stringValue":"world"}}]}]}]}` + "\n"
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTailer_StartsAtEndOfFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collector.json")
+	appendFile(t, path, exportedLine)
+
+	c := New(time.Minute)
+	tailer := NewTailer(path, c)
+	if err := tailer.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if r := c.Report(); r.CollectorRecords != 0 {
+		t.Errorf("existing lines should be skipped, got %d records", r.CollectorRecords)
+	}
+
+	appendFile(t, path, exportedLine)
+	tailer.Poll()
+	if r := c.Report(); r.CollectorRecords != 2 {
+		t.Errorf("CollectorRecords = %d, want 2", r.CollectorRecords)
+	}
+}
+
+func TestTailer_MatchesReceivedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collector.json")
+	c := New(time.Minute)
+	tailer := NewTailer(path, c)
+
+	c.ObserveReceived(makeRecord(1700000000000000001, "hello"))
+	c.ObserveReceived(makeRecord(1700000000000000002, "world"))
+	appendFile(t, path, exportedLine)
+	tailer.Poll()
+
+	if r := c.Report(); r.Matched != 2 || r.Pending != 0 {
+		t.Errorf("Matched/Pending = %d/%d, want 2/0", r.Matched, r.Pending)
+	}
+}
+
+func TestTailer_PartialLineWaitsForNewline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collector.json")
+	c := New(time.Minute)
+	tailer := NewTailer(path, c)
+
+	half := len(exportedLine) / 2
+	appendFile(t, path, exportedLine[:half])
+	tailer.Poll()
+	if r := c.Report(); r.CollectorRecords != 0 {
+		t.Fatalf("partial line should not be read, got %d records", r.CollectorRecords)
+	}
+
+	appendFile(t, path, exportedLine[half:])
+	tailer.Poll()
+	if r := c.Report(); r.CollectorRecords != 2 {
+		t.Errorf("CollectorRecords = %d, want 2", r.CollectorRecords)
+	}
+}
+
+func TestTailer_TruncationRestartsFromBeginning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collector.json")
+	c := New(time.Minute)
+	tailer := NewTailer(path, c)
+
+	appendFile(t, path, exportedLine+exportedLine)
+	tailer.Poll()
+
+	os.WriteFile(path, []byte(exportedLine), 0644)
+	tailer.Poll()
+	if r := c.Report(); r.CollectorRecords != 6 {
+		t.Errorf("CollectorRecords = %d, want 6 after truncation", r.CollectorRecords)
+	}
+}
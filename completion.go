@@ -0,0 +1,285 @@
+// ABOUTME: Shell completion and reference doc generation from the command tree.
+// ABOUTME: Supports bash, zsh, and fish completion plus man and Markdown docs.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// commandFlags returns the flags registered by a command, in lexical order
+func commandFlags(cmd *command) []*flag.Flag {
+	fs, _ := newFlagSet(cmd)
+	var flags []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+	return flags
+}
+
+// completionFlags registers the completion command
+func completionFlags(fs *flag.FlagSet) func(args []string) error {
+	return func(args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("completion requires one shell: bash, zsh, or fish")
+		}
+		return writeCompletion(os.Stdout, args[0])
+	}
+}
+
+// writeCompletion writes the completion script for the given shell
+func writeCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		writeBashCompletion(w)
+	case "zsh":
+		writeZshCompletion(w)
+	case "fish":
+		writeFishCompletion(w)
+	default:
+		return fmt.Errorf("unsupported shell %q (want bash, zsh, or fish)", shell)
+	}
+	return nil
+}
+
+// completionFuncName is the shell function name used by bash and zsh scripts
+var completionFuncName = "_" + strings.ReplaceAll(programName, "-", "_")
+
+func writeBashCompletion(w io.Writer) {
+	var names []string
+	for _, cmd := range commands() {
+		names = append(names, cmd.name)
+	}
+
+	fmt.Fprintf(w, "# bash completion for %s\n", programName)
+	fmt.Fprintf(w, "# Install: source <(%s completion bash)\n\n", programName)
+	fmt.Fprintf(w, "%s() {\n", completionFuncName)
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}"`)
+	fmt.Fprintf(w, "    local cmd=%q\n", defaultCommand)
+	fmt.Fprintln(w, `    if [[ ${COMP_CWORD} -gt 1 && "${COMP_WORDS[1]}" != -* ]]; then`)
+	fmt.Fprintln(w, `        cmd="${COMP_WORDS[1]}"`)
+	fmt.Fprintln(w, `    fi`)
+	fmt.Fprintln(w, `    if [[ ${COMP_CWORD} -eq 1 && "$cur" != -* ]]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=( $(compgen -W %q -- \"$cur\") )\n", strings.Join(names, " "))
+	fmt.Fprintln(w, `        return`)
+	fmt.Fprintln(w, `    fi`)
+	fmt.Fprintln(w, `    case "$cmd" in`)
+	for _, cmd := range commands() {
+		var words []string
+		for _, f := range commandFlags(cmd) {
+			words = append(words, "-"+f.Name)
+		}
+		words = append(words, cmd.args...)
+		fmt.Fprintf(w, "        %s)\n", cmd.name)
+		fmt.Fprintf(w, "            COMPREPLY=( $(compgen -W %q -- \"$cur\") )\n", strings.Join(words, " "))
+		fmt.Fprintln(w, `            ;;`)
+	}
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintf(w, "complete -o default -F %s %s\n", completionFuncName, programName)
+}
+
+// zshEscape escapes characters that are special inside _arguments specs
+func zshEscape(s string) string {
+	r := strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`)
+	return r.Replace(s)
+}
+
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintf(w, "#compdef %s\n", programName)
+	fmt.Fprintf(w, "# Install: %s completion zsh > \"${fpath[1]}/_%s\"\n\n", programName, programName)
+	fmt.Fprintf(w, "%s() {\n", completionFuncName)
+	fmt.Fprintln(w, `  local -a commands`)
+	fmt.Fprintln(w, `  commands=(`)
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "    '%s:%s'\n", cmd.name, zshEscape(cmd.summary))
+	}
+	fmt.Fprintln(w, `  )`)
+	fmt.Fprintln(w, `  if (( CURRENT == 2 )) && [[ $words[2] != -* ]]; then`)
+	fmt.Fprintln(w, `    _describe -t commands 'command' commands`)
+	fmt.Fprintln(w, `    return`)
+	fmt.Fprintln(w, `  fi`)
+	fmt.Fprintf(w, "  local cmd=%s\n", defaultCommand)
+	fmt.Fprintln(w, `  if [[ $words[2] != -* ]]; then`)
+	fmt.Fprintln(w, `    cmd=$words[2]`)
+	fmt.Fprintln(w, `    shift words`)
+	fmt.Fprintln(w, `    (( CURRENT-- ))`)
+	fmt.Fprintln(w, `  fi`)
+	fmt.Fprintln(w, `  case $cmd in`)
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "    %s)\n", cmd.name)
+		fmt.Fprint(w, "      _arguments")
+		for _, f := range commandFlags(cmd) {
+			spec := fmt.Sprintf("-%s[%s]", f.Name, zshEscape(f.Usage))
+			if !isBoolFlag(f) {
+				spec += ":" + f.Name + ":"
+			}
+			fmt.Fprintf(w, " \\\n        '%s'", spec)
+		}
+		if len(cmd.args) > 0 {
+			fmt.Fprintf(w, " \\\n        '1:%s:(%s)'", cmd.name, strings.Join(cmd.args, " "))
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, `      ;;`)
+	}
+	fmt.Fprintln(w, `  esac`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintf(w, "\ncompdef %s %s\n", completionFuncName, programName)
+}
+
+// fishEscape escapes single quotes for fish string literals
+func fishEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+func writeFishCompletion(w io.Writer) {
+	var names []string
+	for _, cmd := range commands() {
+		names = append(names, cmd.name)
+	}
+
+	fmt.Fprintf(w, "# fish completion for %s\n", programName)
+	fmt.Fprintf(w, "# Install: %s completion fish > ~/.config/fish/completions/%s.fish\n\n", programName, programName)
+	fmt.Fprintf(w, "complete -c %s -f\n", programName)
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "complete -c %s -n '__fish_use_subcommand' -a %s -d '%s'\n",
+			programName, cmd.name, fishEscape(cmd.summary))
+	}
+	for _, cmd := range commands() {
+		cond := "__fish_seen_subcommand_from " + cmd.name
+		if cmd.name == defaultCommand {
+			// Flags without a subcommand belong to the default command
+			cond = "not __fish_seen_subcommand_from " + strings.Join(names, " ")
+			cond += "; or __fish_seen_subcommand_from " + cmd.name
+		}
+		for _, f := range commandFlags(cmd) {
+			line := fmt.Sprintf("complete -c %s -n '%s' -o %s -d '%s'", programName, cond, f.Name, fishEscape(f.Usage))
+			if !isBoolFlag(f) {
+				line += " -r"
+			}
+			fmt.Fprintln(w, line)
+		}
+		if len(cmd.args) > 0 {
+			fmt.Fprintf(w, "complete -c %s -n '%s' -a '%s'\n", programName, cond, strings.Join(cmd.args, " "))
+		}
+	}
+}
+
+// docsFlags registers the docs command
+func docsFlags(fs *flag.FlagSet) func(args []string) error {
+	format := fs.String("format", "man", "Doc format: man or markdown")
+	dir := fs.String("dir", ".", "Directory to write generated docs into")
+
+	return func(args []string) error {
+		if err := os.MkdirAll(*dir, 0755); err != nil {
+			return err
+		}
+		switch *format {
+		case "man":
+			return writeDocs(*dir, ".1", writeManPage)
+		case "markdown":
+			return writeDocs(*dir, ".md", writeMarkdownPage)
+		default:
+			return fmt.Errorf("unsupported doc format %q (want man or markdown)", *format)
+		}
+	}
+}
+
+// writeDocs writes one page per command plus an overview page for the program
+func writeDocs(dir, ext string, render func(w io.Writer, cmd *command)) error {
+	pages := append([]*command{nil}, commands()...)
+	for _, cmd := range pages {
+		name := programName
+		if cmd != nil {
+			name += "-" + cmd.name
+		}
+		path := filepath.Join(dir, name+ext)
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		render(f, cmd)
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Println("Wrote", path)
+	}
+	return nil
+}
+
+// manEscape escapes roff control characters
+func manEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	return s
+}
+
+// writeManPage renders a roff man page; a nil command renders the overview page
+func writeManPage(w io.Writer, cmd *command) {
+	date := time.Now().Format("January 2006")
+	if cmd == nil {
+		fmt.Fprintf(w, ".TH %s 1 %q %q\n", strings.ToUpper(programName), date, programName)
+		fmt.Fprintf(w, ".SH NAME\n%s \\- OTLP mock receiver for practicing TAS log pipelines\n", manEscape(programName))
+		fmt.Fprintf(w, ".SH SYNOPSIS\n.B %s\n[\\fIcommand\\fR] [\\fIflags\\fR]\n", manEscape(programName))
+		fmt.Fprintln(w, ".SH COMMANDS")
+		for _, c := range commands() {
+			fmt.Fprintf(w, ".TP\n.B %s\n%s\n", c.name, manEscape(c.summary))
+		}
+		fmt.Fprintln(w, ".SH SEE ALSO")
+		var refs []string
+		for _, c := range commands() {
+			refs = append(refs, fmt.Sprintf(".BR %s\\-%s (1)", manEscape(programName), c.name))
+		}
+		fmt.Fprintln(w, strings.Join(refs, ",\n"))
+		return
+	}
+
+	title := strings.ToUpper(programName + "-" + cmd.name)
+	fmt.Fprintf(w, ".TH %s 1 %q %q\n", title, date, programName)
+	fmt.Fprintf(w, ".SH NAME\n%s\\-%s \\- %s\n", manEscape(programName), cmd.name, manEscape(cmd.summary))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.B %s %s\n[\\fIflags\\fR]", manEscape(programName), cmd.name)
+	if cmd.usage != "" {
+		fmt.Fprintf(w, " \\fI%s\\fR", manEscape(cmd.usage))
+	}
+	fmt.Fprintln(w)
+	if flags := commandFlags(cmd); len(flags) > 0 {
+		fmt.Fprintln(w, ".SH FLAGS")
+		for _, f := range flags {
+			fmt.Fprintf(w, ".TP\n.B \\-%s", manEscape(f.Name))
+			if !isBoolFlag(f) {
+				fmt.Fprint(w, " \\fIvalue\\fR")
+			}
+			fmt.Fprintf(w, "\n%s", manEscape(f.Usage))
+			if f.DefValue != "" {
+				fmt.Fprintf(w, " (default: %s)", manEscape(f.DefValue))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintf(w, ".SH SEE ALSO\n.BR %s (1)\n", manEscape(programName))
+}
+
+// writeMarkdownPage renders a Markdown reference page; a nil command renders the overview page
+func writeMarkdownPage(w io.Writer, cmd *command) {
+	if cmd == nil {
+		fmt.Fprintf(w, "# %s\n\n```text\n%s [command] [flags]\n```\n\n## Commands\n\n", programName, programName)
+		fmt.Fprintln(w, "| Command | Description |")
+		fmt.Fprintln(w, "| ------- | ----------- |")
+		for _, c := range commands() {
+			fmt.Fprintf(w, "| [`%s`](%s-%s.md) | %s |\n", c.name, programName, c.name, c.summary)
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "# %s %s\n\n%s\n\n```text\n%s\n```\n", programName, cmd.name, cmd.summary, synopsis(cmd))
+	if flags := commandFlags(cmd); len(flags) > 0 {
+		fmt.Fprintln(w, "\n## Flags\n\n| Flag | Default | Description |\n| ---- | ------- | ----------- |")
+		for _, f := range flags {
+			fmt.Fprintf(w, "| `-%s` | `%s` | %s |\n", f.Name, f.DefValue, f.Usage)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// ABOUTME: Windowed deduplication of identical log records.
+// ABOUTME: Suppresses repeats of the same app and body, then emits a summary with the repeat count.
+
+package dedup
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// CountAttribute is set on summary records. Records already carrying it pass through unchecked.
+const CountAttribute = "duplicate_count"
+
+// Summary stands in for the duplicates suppressed during one window
+type Summary struct {
+	Resource *resourcepb.Resource
+	Scope    *commonpb.InstrumentationScope
+	Log      *logspb.LogRecord // Copy of the first record, tagged with CountAttribute
+	Count    int               // Duplicates suppressed
+}
+
+// entry tracks one distinct record within its window
+type entry struct {
+	summary Summary
+	first   time.Time
+}
+
+// Deduper suppresses records identical to one seen within Window
+type Deduper struct {
+	Window time.Duration
+
+	mu    sync.Mutex
+	seen  map[uint64]*entry
+	ready []Summary // Summaries of windows closed by a later occurrence, awaiting Expired
+}
+
+// New creates a deduper with the given window
+func New(window time.Duration) *Deduper {
+	return &Deduper{Window: window, seen: make(map[uint64]*entry)}
+}
+
+// Check reports whether a record duplicates one from the same app within the
+// current window. The first occurrence passes and starts the window.
+func (d *Deduper) Check(resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, app string, lr *logspb.LogRecord) bool {
+	return d.check(resource, scope, app, lr, time.Now())
+}
+
+func (d *Deduper) check(resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, app string, lr *logspb.LogRecord, now time.Time) bool {
+	if hasAttribute(lr, CountAttribute) {
+		return false
+	}
+	key := hashRecord(app, lr)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e, ok := d.seen[key]; ok {
+		if now.Sub(e.first) <= d.Window {
+			e.summary.Count++
+			return true
+		}
+		// The window ended before the flusher saw it: close it and start a new one
+		if e.summary.Count > 0 {
+			d.ready = append(d.ready, e.finish())
+		}
+	}
+	d.seen[key] = &entry{
+		summary: Summary{Resource: resource, Scope: scope, Log: proto.Clone(lr).(*logspb.LogRecord)},
+		first:   now,
+	}
+	return false
+}
+
+// Expired returns summaries for windows that have ended with at least one duplicate
+func (d *Deduper) Expired() []Summary {
+	return d.expired(time.Now())
+}
+
+func (d *Deduper) expired(now time.Time) []Summary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.drain(func(e *entry) bool { return now.Sub(e.first) > d.Window })
+}
+
+// Flush returns summaries for every open window, e.g. at shutdown
+func (d *Deduper) Flush() []Summary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.drain(func(*entry) bool { return true })
+}
+
+// drain removes entries selected by done and returns summaries for those with
+// duplicates, oldest first. Caller holds the lock.
+func (d *Deduper) drain(done func(*entry) bool) []Summary {
+	var finished []*entry
+	for key, e := range d.seen {
+		if done(e) {
+			delete(d.seen, key)
+			if e.summary.Count > 0 {
+				finished = append(finished, e)
+			}
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].first.Before(finished[j].first) })
+
+	summaries := d.ready
+	d.ready = nil
+	for _, e := range finished {
+		summaries = append(summaries, e.finish())
+	}
+	return summaries
+}
+
+// finish tags the summary record with the duplicate count
+func (e *entry) finish() Summary {
+	s := e.summary
+	s.Log.Attributes = append(s.Log.Attributes, &commonpb.KeyValue{
+		Key:   CountAttribute,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: strconv.Itoa(s.Count)}},
+	})
+	return s
+}
+
+// hashRecord identifies a record by app name and body
+func hashRecord(app string, lr *logspb.LogRecord) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(app))
+	h.Write([]byte{0})
+	if sv, ok := lr.GetBody().GetValue().(*commonpb.AnyValue_StringValue); ok {
+		h.Write([]byte(sv.StringValue))
+	} else if data, err := protojson.Marshal(lr.GetBody()); err == nil {
+		h.Write(data)
+	}
+	return h.Sum64()
+}
+
+// hasAttribute reports whether the record has an attribute with the given key
+func hasAttribute(lr *logspb.LogRecord, key string) bool {
+	for _, attr := range lr.GetAttributes() {
+		if attr.GetKey() == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+// ABOUTME: Tests for windowed deduplication.
+// ABOUTME: Covers suppression, per-app keys, summaries, and window rollover.
+
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func record(body string) *logspb.LogRecord {
+	return &logspb.LogRecord{Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}}}
+}
+
+func countAttr(lr *logspb.LogRecord) string {
+	for _, attr := range lr.GetAttributes() {
+		if attr.GetKey() == CountAttribute {
+			return attr.GetValue().GetStringValue()
+		}
+	}
+	return ""
+}
+
+func TestDeduper_SuppressesWithinWindow(t *testing.T) {
+	d := New(time.Second)
+	now := time.Now()
+
+	if d.check(nil, nil, "app", record("db timeout"), now) {
+		t.Fatal("first occurrence should pass")
+	}
+	for i := 1; i <= 3; i++ {
+		if !d.check(nil, nil, "app", record("db timeout"), now.Add(time.Duration(i)*100*time.Millisecond)) {
+			t.Errorf("repeat %d should be suppressed", i)
+		}
+	}
+	if d.check(nil, nil, "other-app", record("db timeout"), now) {
+		t.Error("same body from another app should pass")
+	}
+
+	if got := d.expired(now.Add(500 * time.Millisecond)); len(got) != 0 {
+		t.Fatalf("window still open, got %d summaries", len(got))
+	}
+	summaries := d.expired(now.Add(2 * time.Second))
+	if len(summaries) != 1 {
+		t.Fatalf("expected 1 summary, got %d", len(summaries))
+	}
+	if summaries[0].Count != 3 || countAttr(summaries[0].Log) != "3" {
+		t.Errorf("summary count = %d (attr %q), want 3", summaries[0].Count, countAttr(summaries[0].Log))
+	}
+	if summaries[0].Log.GetBody().GetStringValue() != "db timeout" {
+		t.Errorf("summary body = %q", summaries[0].Log.GetBody().GetStringValue())
+	}
+}
+
+func TestDeduper_SummaryPassesThrough(t *testing.T) {
+	d := New(time.Second)
+	now := time.Now()
+	d.check(nil, nil, "app", record("storm"), now)
+	d.check(nil, nil, "app", record("storm"), now)
+
+	summary := d.Flush()[0]
+	d.check(nil, nil, "app", record("storm"), now)
+	if d.check(nil, nil, "app", summary.Log, now) {
+		t.Error("summary record should never be suppressed")
+	}
+}
+
+func TestDeduper_WindowRolloverBeforeFlush(t *testing.T) {
+	d := New(time.Second)
+	now := time.Now()
+
+	d.check(nil, nil, "app", record("storm"), now)
+	d.check(nil, nil, "app", record("storm"), now.Add(500*time.Millisecond))
+
+	// Arrives after the window closed but before the flusher ran: starts a new window
+	if d.check(nil, nil, "app", record("storm"), now.Add(1500*time.Millisecond)) {
+		t.Error("occurrence after the window should pass")
+	}
+	if !d.check(nil, nil, "app", record("storm"), now.Add(1600*time.Millisecond)) {
+		t.Error("repeat in the new window should be suppressed")
+	}
+
+	summaries := d.Flush()
+	if len(summaries) != 2 || summaries[0].Count != 1 || summaries[1].Count != 1 {
+		t.Errorf("expected two summaries of 1, got %+v", summaries)
+	}
+}
+
+func TestDeduper_NoSummaryWithoutDuplicates(t *testing.T) {
+	d := New(time.Second)
+	d.check(nil, nil, "app", record("unique"), time.Now())
+
+	if got := d.Flush(); len(got) != 0 {
+		t.Errorf("expected no summaries, got %d", len(got))
+	}
+}
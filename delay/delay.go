@@ -0,0 +1,249 @@
+// ABOUTME: Response delay injection following configurable latency distributions.
+// ABOUTME: Models backend latency per app or index with fixed, uniform, normal, or pareto delays.
+
+package delay
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// DefaultMax caps sampled delays so heavy-tailed distributions can't stall exporters indefinitely
+const DefaultMax = 10 * time.Second
+
+// Distribution produces delay samples
+type Distribution interface {
+	Sample(rng *rand.Rand) time.Duration
+	String() string
+}
+
+// Fixed always returns the same delay
+type Fixed struct{ D time.Duration }
+
+func (f Fixed) Sample(*rand.Rand) time.Duration { return f.D }
+func (f Fixed) String() string                  { return "fixed:" + f.D.String() }
+
+// Uniform returns delays evenly spread between Min and Max
+type Uniform struct{ Min, Max time.Duration }
+
+func (u Uniform) Sample(rng *rand.Rand) time.Duration {
+	return u.Min + time.Duration(rng.Float64()*float64(u.Max-u.Min))
+}
+func (u Uniform) String() string { return fmt.Sprintf("uniform:%s,%s", u.Min, u.Max) }
+
+// Normal returns delays around Mean, clamped at zero
+type Normal struct{ Mean, StdDev time.Duration }
+
+func (n Normal) Sample(rng *rand.Rand) time.Duration {
+	return max(time.Duration(float64(n.Mean)+rng.NormFloat64()*float64(n.StdDev)), 0)
+}
+func (n Normal) String() string { return fmt.Sprintf("normal:%s,%s", n.Mean, n.StdDev) }
+
+// Pareto returns heavy-tailed delays of at least Scale; smaller Shape means a longer tail
+type Pareto struct {
+	Scale time.Duration
+	Shape float64
+}
+
+func (p Pareto) Sample(rng *rand.Rand) time.Duration {
+	u := 1 - rng.Float64() // (0, 1]
+	d := float64(p.Scale) / math.Pow(u, 1/p.Shape)
+	// The tail passes the longest Duration for small u and shapes near or below 1
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+func (p Pareto) String() string { return fmt.Sprintf("pareto:%s,%g", p.Scale, p.Shape) }
+
+// Parse parses a distribution spec:
+//
+//	50ms | fixed:50ms
+//	uniform:MIN,MAX
+//	normal:MEAN,STDDEV
+//	pareto:SCALE,SHAPE
+//
+// "none" or an empty spec returns nil (no delay).
+func Parse(spec string) (Distribution, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "none" {
+		return nil, nil
+	}
+
+	kind, params, found := strings.Cut(spec, ":")
+	if !found {
+		kind, params = "fixed", spec
+	}
+	args := strings.Split(params, ",")
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+
+	want := map[string]int{"fixed": 1, "uniform": 2, "normal": 2, "pareto": 2}
+	n, ok := want[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown distribution %q (use fixed, uniform, normal, or pareto)", kind)
+	}
+	if len(args) != n {
+		return nil, fmt.Errorf("%s takes %d parameter(s), got %d", kind, n, len(args))
+	}
+
+	first, err := parseDuration(args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "fixed":
+		return Fixed{first}, nil
+	case "pareto":
+		shape, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || shape <= 0 {
+			return nil, fmt.Errorf("pareto shape must be a positive number, got %q", args[1])
+		}
+		if first <= 0 {
+			return nil, fmt.Errorf("pareto scale must be positive, got %s", first)
+		}
+		return Pareto{Scale: first, Shape: shape}, nil
+	}
+
+	second, err := parseDuration(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if kind == "uniform" {
+		if second < first {
+			return nil, fmt.Errorf("uniform max %s is less than min %s", second, first)
+		}
+		return Uniform{Min: first, Max: second}, nil
+	}
+	return Normal{Mean: first, StdDev: second}, nil
+}
+
+// parseDuration parses a non-negative duration
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %s", d)
+	}
+	return d, nil
+}
+
+// Config selects a distribution per app, then per index, then the default
+type Config struct {
+	Default Distribution
+	Apps    map[string]Distribution // Keyed by lowercase app name
+	Indexes map[string]Distribution
+	Max     time.Duration
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewConfig creates a config with only a default distribution
+func NewConfig(def Distribution) *Config {
+	return &Config{
+		Default: def,
+		Apps:    make(map[string]Distribution),
+		Indexes: make(map[string]Distribution),
+		Max:     DefaultMax,
+		rng:     rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+}
+
+// For returns the distribution for a record's app and index, or nil for no delay
+func (c *Config) For(app, index string) Distribution {
+	if d, ok := c.Apps[strings.ToLower(app)]; ok {
+		return d
+	}
+	if d, ok := c.Indexes[index]; ok {
+		return d
+	}
+	return c.Default
+}
+
+// Sample draws a delay for a record's app and index, capped at Max
+func (c *Config) Sample(app, index string) time.Duration {
+	dist := c.For(app, index)
+	if dist == nil {
+		return 0
+	}
+	c.mu.Lock()
+	d := dist.Sample(c.rng)
+	c.mu.Unlock()
+	if c.Max > 0 && d > c.Max {
+		d = c.Max
+	}
+	return max(d, 0)
+}
+
+// String summarizes the config for the startup banner
+func (c *Config) String() string {
+	def := "none"
+	if c.Default != nil {
+		def = c.Default.String()
+	}
+	return fmt.Sprintf("%s (%d app, %d index overrides, max %s)", def, len(c.Apps), len(c.Indexes), c.Max)
+}
+
+// FileConfig is the YAML representation of a delay config file
+type FileConfig struct {
+	Default string            `yaml:"default"`
+	Max     string            `yaml:"max"`
+	Apps    map[string]string `yaml:"apps"`
+	Indexes map[string]string `yaml:"indexes"`
+}
+
+// LoadConfig reads a YAML delay config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fc FileConfig
+	if err := yaml.UnmarshalStrict(data, &fc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return fc.Build()
+}
+
+// Build parses every distribution spec in the file
+func (fc *FileConfig) Build() (*Config, error) {
+	def, err := Parse(fc.Default)
+	if err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+	cfg := NewConfig(def)
+
+	if fc.Max != "" {
+		if cfg.Max, err = parseDuration(fc.Max); err != nil {
+			return nil, fmt.Errorf("max: %w", err)
+		}
+	}
+	for app, spec := range fc.Apps {
+		d, err := Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("apps.%s: %w", app, err)
+		}
+		cfg.Apps[strings.ToLower(app)] = d
+	}
+	for index, spec := range fc.Indexes {
+		d, err := Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("indexes.%s: %w", index, err)
+		}
+		cfg.Indexes[index] = d
+	}
+	return cfg, nil
+}
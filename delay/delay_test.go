@@ -0,0 +1,178 @@
+// ABOUTME: Tests for response delay distributions.
+// ABOUTME: Covers spec parsing, sample ranges, per-app/index selection, and config loading.
+
+package delay
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testRNG() *rand.Rand {
+	return rand.New(rand.NewPCG(1, 2))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"50ms", "fixed:50ms"},
+		{"fixed:1s", "fixed:1s"},
+		{"uniform:10ms,200ms", "uniform:10ms,200ms"},
+		{"normal: 80ms, 20ms", "normal:80ms,20ms"},
+		{"pareto:10ms,1.5", "pareto:10ms,1.5"},
+	}
+	for _, tt := range tests {
+		d, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got := d.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParse_None(t *testing.T) {
+	for _, spec := range []string{"", "none"} {
+		if d, err := Parse(spec); d != nil || err != nil {
+			t.Errorf("Parse(%q) = %v, %v; want nil, nil", spec, d, err)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, spec := range []string{
+		"gamma:1s,2",
+		"uniform:10ms",
+		"uniform:200ms,10ms",
+		"normal:abc,1ms",
+		"fixed:-5ms",
+		"pareto:10ms,0",
+		"pareto:0s,1.5",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) should fail", spec)
+		}
+	}
+}
+
+func TestSampleRanges(t *testing.T) {
+	rng := testRNG()
+	for i := 0; i < 1000; i++ {
+		if d := (Uniform{Min: 10 * time.Millisecond, Max: 20 * time.Millisecond}).Sample(rng); d < 10*time.Millisecond || d > 20*time.Millisecond {
+			t.Fatalf("uniform sample %s out of range", d)
+		}
+		if d := (Normal{Mean: time.Millisecond, StdDev: 10 * time.Millisecond}).Sample(rng); d < 0 {
+			t.Fatalf("normal sample %s is negative", d)
+		}
+		if d := (Pareto{Scale: 5 * time.Millisecond, Shape: 1.5}).Sample(rng); d < 5*time.Millisecond {
+			t.Fatalf("pareto sample %s below scale", d)
+		}
+	}
+}
+
+func TestPareto_LongTailDoesNotOverflow(t *testing.T) {
+	rng := testRNG()
+	for i := 0; i < 1000; i++ {
+		if d := (Pareto{Scale: 50 * time.Millisecond, Shape: 0.01}).Sample(rng); d < 50*time.Millisecond {
+			t.Fatalf("pareto sample %s below scale", d)
+		}
+	}
+}
+
+func TestConfig_ForPrecedence(t *testing.T) {
+	cfg := NewConfig(Fixed{time.Millisecond})
+	cfg.Indexes["tas_errors"] = Fixed{2 * time.Millisecond}
+	cfg.Apps["payment-service"] = Fixed{3 * time.Millisecond}
+
+	tests := []struct {
+		app, index string
+		want       time.Duration
+	}{
+		{"other", "main", time.Millisecond},
+		{"other", "tas_errors", 2 * time.Millisecond},
+		{"Payment-Service", "tas_errors", 3 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := cfg.Sample(tt.app, tt.index); got != tt.want {
+			t.Errorf("Sample(%q, %q) = %s, want %s", tt.app, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_SampleCappedAtMax(t *testing.T) {
+	cfg := NewConfig(Fixed{time.Minute})
+	cfg.Max = time.Second
+	if got := cfg.Sample("app", "main"); got != time.Second {
+		t.Errorf("Sample = %s, want capped at 1s", got)
+	}
+}
+
+func TestConfig_SampleCappedOnLongTail(t *testing.T) {
+	cfg := NewConfig(Pareto{Scale: 50 * time.Millisecond, Shape: 0.01})
+	cfg.Max = time.Second
+	capped := 0
+	for i := 0; i < 100; i++ {
+		got := cfg.Sample("app", "main")
+		if got < 50*time.Millisecond || got > time.Second {
+			t.Fatalf("Sample = %s, want between the scale and the 1s cap", got)
+		}
+		if got == time.Second {
+			capped++
+		}
+	}
+	if capped == 0 {
+		t.Error("no sample reached the cap")
+	}
+	if got := NewConfig(Fixed{-time.Second}).Sample("app", "main"); got != 0 {
+		t.Errorf("Sample = %s, want negative delays clamped to 0", got)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delay.yaml")
+	content := `
+default: normal:80ms,20ms
+max: 2s
+apps:
+  Payment-Service: pareto:20ms,1.2
+  quiet-app: none
+indexes:
+  tas_errors: uniform:100ms,300ms
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Max != 2*time.Second {
+		t.Errorf("Max = %s, want 2s", cfg.Max)
+	}
+	if d := cfg.For("payment-service", "main"); d == nil || d.String() != "pareto:20ms,1.2" {
+		t.Errorf("app override = %v", d)
+	}
+	if d := cfg.For("quiet-app", "main"); d != nil {
+		t.Errorf("quiet-app should have no delay, got %v", d)
+	}
+	if d := cfg.For("other", "tas_errors"); d == nil || d.String() != "uniform:100ms,300ms" {
+		t.Errorf("index override = %v", d)
+	}
+}
+
+func TestLoadConfig_InvalidSpec(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delay.yaml")
+	os.WriteFile(path, []byte("indexes:\n  main: bogus:1s\n"), 0644)
+
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for invalid distribution")
+	}
+}
@@ -0,0 +1,62 @@
+// ABOUTME: Taxonomy of reasons a log record is dropped rather than delivered.
+// ABOUTME: Reasons label stats, metrics, and partial-success messages, and are served at /api/drop-reasons.
+
+package drop
+
+import "strings"
+
+// Reason is why a record was dropped
+type Reason string
+
+// Drop reasons, in pipeline order
+const (
+	Sampled   Reason = "sampled"
+	Filtered  Reason = "filtered"
+	Rule      Reason = "rule"
+	Duplicate Reason = "duplicate"
+	Routed    Reason = "routed"
+	Throttled Reason = "throttled"
+	Quota     Reason = "quota"
+	Invalid   Reason = "invalid"
+)
+
+// Info documents one drop reason
+type Info struct {
+	Reason      Reason `json:"reason"`
+	Label       string `json:"label"` // As it appears in metric labels and partial-success messages
+	Stage       string `json:"stage"`
+	Description string `json:"description"`
+	Reserved    bool   `json:"reserved,omitempty"` // Not yet produced by any stage
+}
+
+// taxonomy lists every reason in pipeline order
+var taxonomy = []Info{
+	{Sampled, string(Sampled), "sampling", "Dropped by 1-in-N sampling", false},
+	{Filtered, string(Filtered), "allowlist", "App not in the allowlist, or in the denylist", false},
+	{Rule, string(Rule) + ":<name>", "drop rules", "Matched the named drop rule", false},
+	{Duplicate, string(Duplicate), "dedup", "Repeat of a record seen within the dedup window", false},
+	{Routed, string(Routed) + ":<rule>", "routing", "Routed to _drop by the named routing rule, or default", false},
+	{Throttled, string(Throttled) + ":<rule>", "routing", "Over the named routing rule's rate limit, with _drop as its overflow index", false},
+	{Quota, string(Quota), "", "Over a volume quota", true},
+	{Invalid, string(Invalid), "", "Record failed validation", true},
+}
+
+// Taxonomy returns every drop reason in pipeline order
+func Taxonomy() []Info {
+	return append([]Info(nil), taxonomy...)
+}
+
+// Label returns the reason as used in metric labels and partial-success
+// messages, qualified by detail (e.g. a drop rule's name) when given
+func (r Reason) Label(detail string) string {
+	if detail == "" {
+		return string(r)
+	}
+	return string(r) + ":" + detail
+}
+
+// Parse returns the reason of a label produced by Label
+func Parse(label string) Reason {
+	reason, _, _ := strings.Cut(label, ":")
+	return Reason(reason)
+}
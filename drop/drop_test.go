@@ -0,0 +1,56 @@
+// ABOUTME: Tests for the drop-reason taxonomy.
+// ABOUTME: Covers labels, parsing, and taxonomy completeness.
+
+package drop
+
+import "testing"
+
+func TestLabelAndParse(t *testing.T) {
+	tests := []struct {
+		reason Reason
+		detail string
+		want   string
+	}{
+		{Sampled, "", "sampled"},
+		{Rule, "health-checks", "rule:health-checks"},
+		{Duplicate, "", "duplicate"},
+		{Routed, "default", "routed:default"},
+	}
+	for _, tt := range tests {
+		label := tt.reason.Label(tt.detail)
+		if label != tt.want {
+			t.Errorf("%s.Label(%q) = %q, want %q", tt.reason, tt.detail, label, tt.want)
+		}
+		if got := Parse(label); got != tt.reason {
+			t.Errorf("Parse(%q) = %q, want %q", label, got, tt.reason)
+		}
+	}
+}
+
+func TestTaxonomy_CoversEveryReason(t *testing.T) {
+	seen := map[Reason]bool{}
+	for _, info := range Taxonomy() {
+		if seen[info.Reason] {
+			t.Errorf("%s listed twice", info.Reason)
+		}
+		seen[info.Reason] = true
+		if info.Description == "" {
+			t.Errorf("%s has no description", info.Reason)
+		}
+		if !info.Reserved && info.Stage == "" {
+			t.Errorf("%s is produced but names no stage", info.Reason)
+		}
+	}
+	for _, r := range []Reason{Sampled, Filtered, Rule, Duplicate, Routed, Throttled, Quota, Invalid} {
+		if !seen[r] {
+			t.Errorf("%s missing from the taxonomy", r)
+		}
+	}
+}
+
+func TestTaxonomy_ReturnsCopy(t *testing.T) {
+	Taxonomy()[0].Description = "changed"
+	if Taxonomy()[0].Description == "changed" {
+		t.Error("Taxonomy should return a copy")
+	}
+}
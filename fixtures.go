@@ -0,0 +1,61 @@
+// ABOUTME: gen-fixtures command that writes synthetic routing test records as JSONL.
+// ABOUTME: Bootstraps regression suites for a rule set with a match and near-misses per rule.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"otlp-mock-receiver/routing"
+)
+
+// genFixturesFlags registers the gen-fixtures command
+func genFixturesFlags(fs *flag.FlagSet) func(args []string) error {
+	out := fs.String("o", "-", "Output JSONL file (- for stdout)")
+	routingConfigFile := fs.String("routing-config", "", "Path to YAML or JSON routing rules file (default: the built-in rules)")
+
+	return func(args []string) error {
+		router := routing.DefaultRouter()
+		if *routingConfigFile != "" {
+			var err error
+			if router, err = routing.LoadConfig(*routingConfigFile); err != nil {
+				return err
+			}
+		}
+
+		if *out == "-" {
+			_, err := writeFixtures(os.Stdout, router)
+			return err
+		}
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		n, err := writeFixtures(f, router)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d fixtures to %s\n", n, *out)
+		return nil
+	}
+}
+
+// writeFixtures writes one JSON fixture per line and returns how many were written
+func writeFixtures(w io.Writer, router *routing.Router) (int, error) {
+	fixtures := router.GenerateFixtures()
+	enc := json.NewEncoder(w)
+	for _, f := range fixtures {
+		if err := enc.Encode(f); err != nil {
+			return 0, err
+		}
+	}
+	return len(fixtures), nil
+}
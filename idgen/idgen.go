@@ -0,0 +1,127 @@
+// ABOUTME: Trace and span ID generation strategies for synthetic log records.
+// ABOUTME: Supports random, sequential, and app-derived IDs for predictable correlation demos.
+
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// Generator produces trace and span IDs for synthetic records.
+// Next returns a 16-byte trace ID and an 8-byte span ID for the given app.
+type Generator interface {
+	Next(app string) (traceID, spanID []byte)
+}
+
+// Strategy names accepted by New
+const (
+	StrategyNone       = "none"
+	StrategyRandom     = "random"
+	StrategySequential = "sequential"
+	StrategyDerived    = "derived"
+)
+
+var constructors = map[string]func() Generator{
+	StrategyNone:       func() Generator { return noneGenerator{} },
+	StrategyRandom:     func() Generator { return randomGenerator{} },
+	StrategySequential: func() Generator { return &sequentialGenerator{} },
+	StrategyDerived:    func() Generator { return &derivedGenerator{counters: make(map[string]uint64)} },
+}
+
+// New creates a generator for the named strategy
+func New(strategy string) (Generator, error) {
+	ctor, ok := constructors[strategy]
+	if !ok {
+		return nil, fmt.Errorf("unknown ID strategy %q (want one of %v)", strategy, Strategies())
+	}
+	return ctor(), nil
+}
+
+// Strategies returns the available strategy names in sorted order
+func Strategies() []string {
+	names := make([]string, 0, len(constructors))
+	for name := range constructors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Format returns the lowercase hex form of an ID, as shown by OTLP tooling
+func Format(id []byte) string {
+	return hex.EncodeToString(id)
+}
+
+// noneGenerator leaves records without trace context
+type noneGenerator struct{}
+
+func (noneGenerator) Next(string) ([]byte, []byte) { return nil, nil }
+
+// randomGenerator produces W3C-style random IDs
+type randomGenerator struct{}
+
+func (randomGenerator) Next(string) ([]byte, []byte) {
+	traceID := make([]byte, 16)
+	spanID := make([]byte, 8)
+	rand.Read(traceID)
+	rand.Read(spanID)
+	return traceID, spanID
+}
+
+// sequentialGenerator numbers records 1, 2, 3... across all apps
+type sequentialGenerator struct {
+	mu sync.Mutex
+	n  uint64
+}
+
+func (g *sequentialGenerator) Next(string) ([]byte, []byte) {
+	g.mu.Lock()
+	g.n++
+	n := g.n
+	g.mu.Unlock()
+	return counterTraceID(0, n), counterSpanID(n)
+}
+
+// derivedGenerator combines a hash of the app name with a per-app counter, so
+// the Nth record of an app always gets the same IDs
+type derivedGenerator struct {
+	mu       sync.Mutex
+	counters map[string]uint64
+}
+
+func (g *derivedGenerator) Next(app string) ([]byte, []byte) {
+	g.mu.Lock()
+	g.counters[app]++
+	n := g.counters[app]
+	g.mu.Unlock()
+	return DerivedTraceID(app, n), counterSpanID(n)
+}
+
+// DerivedTraceID returns the trace ID the derived strategy assigns to the nth
+// record (starting at 1) of an app, so demo IDs can be looked up later
+func DerivedTraceID(app string, n uint64) []byte {
+	h := fnv.New64a()
+	h.Write([]byte(app))
+	return counterTraceID(h.Sum64(), n)
+}
+
+// counterTraceID packs a high word and counter into a 16-byte trace ID
+func counterTraceID(high, n uint64) []byte {
+	id := make([]byte, 16)
+	binary.BigEndian.PutUint64(id[:8], high)
+	binary.BigEndian.PutUint64(id[8:], n)
+	return id
+}
+
+// counterSpanID packs a counter into an 8-byte span ID
+func counterSpanID(n uint64) []byte {
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, n)
+	return id
+}
@@ -0,0 +1,66 @@
+// ABOUTME: Tests for trace and span ID generation strategies.
+// ABOUTME: Covers ID sizes, sequential numbering, and derived ID predictability.
+
+package idgen
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNew_UnknownStrategy(t *testing.T) {
+	if _, err := New("bogus"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestNone_NoIDs(t *testing.T) {
+	g, _ := New(StrategyNone)
+	traceID, spanID := g.Next("app")
+	if traceID != nil || spanID != nil {
+		t.Errorf("none strategy should return nil IDs, got %x/%x", traceID, spanID)
+	}
+}
+
+func TestRandom_SizesAndUniqueness(t *testing.T) {
+	g, _ := New(StrategyRandom)
+	t1, s1 := g.Next("app")
+	t2, _ := g.Next("app")
+
+	if len(t1) != 16 || len(s1) != 8 {
+		t.Errorf("unexpected sizes: trace=%d span=%d", len(t1), len(s1))
+	}
+	if bytes.Equal(t1, t2) {
+		t.Error("random trace IDs should differ")
+	}
+}
+
+func TestSequential_CountsAcrossApps(t *testing.T) {
+	g, _ := New(StrategySequential)
+	g.Next("a")
+	traceID, spanID := g.Next("b")
+
+	if got := Format(traceID); got != "00000000000000000000000000000002" {
+		t.Errorf("second trace ID = %s", got)
+	}
+	if got := Format(spanID); got != "0000000000000002" {
+		t.Errorf("second span ID = %s", got)
+	}
+}
+
+func TestDerived_PredictablePerApp(t *testing.T) {
+	g, _ := New(StrategyDerived)
+	g.Next("payment-service")
+	g.Next("other-app")
+	traceID, spanID := g.Next("payment-service")
+
+	if !bytes.Equal(traceID, DerivedTraceID("payment-service", 2)) {
+		t.Errorf("trace ID %s does not match DerivedTraceID", Format(traceID))
+	}
+	if got := Format(spanID); got != "0000000000000002" {
+		t.Errorf("span ID = %s, want per-app counter 2", got)
+	}
+	if bytes.Equal(DerivedTraceID("a", 1), DerivedTraceID("b", 1)) {
+		t.Error("different apps should get different trace IDs")
+	}
+}
@@ -0,0 +1,156 @@
+// ABOUTME: HDR-style latency histogram with bounded relative error.
+// ABOUTME: Provides exact-enough percentiles for comparing runs, independent of Prometheus buckets.
+
+package latency
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// subBucketBits sets the precision: 2^7 = 128 sub-buckets per power of two,
+// keeping relative error under 1%
+const subBucketBits = 7
+
+const subBucketCount = 1 << subBucketBits
+
+// bucketCount covers every non-negative int64 nanosecond value
+const bucketCount = (64 - subBucketBits + 1) * subBucketCount
+
+// Histogram records durations into log-linear buckets
+type Histogram struct {
+	mu     sync.Mutex
+	counts [bucketCount]uint64
+	count  uint64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+// New creates an empty histogram
+func New() *Histogram {
+	return &Histogram{}
+}
+
+// Record adds a single observation
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts[bucketIndex(uint64(d))]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+}
+
+// Quantile returns the value at quantile q (0 < q <= 1)
+func (h *Histogram) Quantile(q float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.quantileLocked(q)
+}
+
+func (h *Histogram) quantileLocked(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			v := time.Duration(bucketMidpoint(i))
+			// The bucket midpoint can overshoot the observed extremes
+			return min(max(v, h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+// Summary is a point-in-time percentile snapshot
+type Summary struct {
+	Count uint64
+	Min   time.Duration
+	Mean  time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Summary returns the current percentiles
+func (h *Histogram) Summary() Summary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := Summary{Count: h.count, Min: h.min, Max: h.max}
+	if h.count > 0 {
+		s.Mean = h.sum / time.Duration(h.count)
+		s.P50 = h.quantileLocked(0.50)
+		s.P95 = h.quantileLocked(0.95)
+		s.P99 = h.quantileLocked(0.99)
+	}
+	return s
+}
+
+// String formats the summary for the session report
+func (s Summary) String() string {
+	if s.Count == 0 {
+		return "no requests"
+	}
+	return fmt.Sprintf("p50=%v p95=%v p99=%v max=%v (n=%d)", s.P50, s.P95, s.P99, s.Max, s.Count)
+}
+
+// MarshalJSON reports durations in milliseconds for the stats API
+func (s Summary) MarshalJSON() ([]byte, error) {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return json.Marshal(struct {
+		Count  uint64  `json:"count"`
+		MinMs  float64 `json:"min_ms"`
+		MeanMs float64 `json:"mean_ms"`
+		P50Ms  float64 `json:"p50_ms"`
+		P95Ms  float64 `json:"p95_ms"`
+		P99Ms  float64 `json:"p99_ms"`
+		MaxMs  float64 `json:"max_ms"`
+	}{s.Count, ms(s.Min), ms(s.Mean), ms(s.P50), ms(s.P95), ms(s.P99), ms(s.Max)})
+}
+
+// bucketIndex maps a value to its bucket. Values below 2^subBucketBits get
+// exact buckets; larger values keep their top subBucketBits+1 significant bits.
+func bucketIndex(v uint64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(v) - subBucketBits - 1
+	return shift*subBucketCount + int(v>>shift)
+}
+
+// bucketMidpoint returns the middle of the value range covered by a bucket
+func bucketMidpoint(i int) uint64 {
+	if i < subBucketCount {
+		return uint64(i)
+	}
+	shift := i/subBucketCount - 1
+	mantissa := uint64(i - shift*subBucketCount)
+	lower := mantissa << shift
+	width := uint64(1) << shift
+	return lower + width/2
+}
@@ -0,0 +1,89 @@
+// ABOUTME: Tests for the HDR-style latency histogram.
+// ABOUTME: Verifies percentile accuracy, bucket mapping, and JSON output.
+
+package latency
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogram_EmptySummary(t *testing.T) {
+	h := New()
+	s := h.Summary()
+	if s.Count != 0 || s.P99 != 0 {
+		t.Errorf("empty summary should be zero, got %+v", s)
+	}
+	if s.String() != "no requests" {
+		t.Errorf("String() = %q", s.String())
+	}
+}
+
+func TestHistogram_PercentilesWithinOnePercent(t *testing.T) {
+	h := New()
+	// 1ms..1000ms uniformly: p50 ≈ 500ms, p95 ≈ 950ms, p99 ≈ 990ms
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	s := h.Summary()
+	checks := map[string]struct {
+		got  time.Duration
+		want time.Duration
+	}{
+		"p50": {s.P50, 500 * time.Millisecond},
+		"p95": {s.P95, 950 * time.Millisecond},
+		"p99": {s.P99, 990 * time.Millisecond},
+	}
+	for name, c := range checks {
+		relErr := math.Abs(float64(c.got-c.want)) / float64(c.want)
+		if relErr > 0.01 {
+			t.Errorf("%s = %v, want %v (±1%%), error %.2f%%", name, c.got, c.want, relErr*100)
+		}
+	}
+	if s.Min != time.Millisecond || s.Max != time.Second {
+		t.Errorf("Min/Max = %v/%v", s.Min, s.Max)
+	}
+	if s.Count != 1000 {
+		t.Errorf("Count = %d, want 1000", s.Count)
+	}
+}
+
+func TestHistogram_SingleValueExact(t *testing.T) {
+	h := New()
+	h.Record(1234567 * time.Nanosecond)
+
+	if got := h.Quantile(0.99); got != 1234567*time.Nanosecond {
+		t.Errorf("single observation quantile = %v, want exact value", got)
+	}
+}
+
+func TestBucketIndex_Monotonic(t *testing.T) {
+	prev := -1
+	for _, v := range []uint64{0, 1, 127, 128, 129, 255, 256, 1000, 1 << 20, 1 << 40, math.MaxInt64} {
+		idx := bucketIndex(v)
+		if idx < prev {
+			t.Errorf("bucketIndex(%d) = %d decreased from %d", v, idx, prev)
+		}
+		if idx >= bucketCount {
+			t.Errorf("bucketIndex(%d) = %d out of range", v, idx)
+		}
+		prev = idx
+	}
+}
+
+func TestSummary_JSONInMilliseconds(t *testing.T) {
+	h := New()
+	h.Record(2 * time.Millisecond)
+
+	data, err := json.Marshal(h.Summary())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"p50_ms":2`) {
+		t.Errorf("expected p50_ms of 2, got %s", data)
+	}
+}
@@ -0,0 +1,79 @@
+// ABOUTME: Tests for the receiver's leveled logging.
+// ABOUTME: Covers the pretty handler's lines, level filtering, formats, and level parsing.
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPrettyHandler_Line(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewPrettyHandler(&buf, nil))
+	logger.Info("Allowlist reloaded", "source", "apps.txt", "entries", 3)
+	logger.With("sink", "hec").WithGroup("batch").Warn("Retrying", "err", errors.New("connection refused"), "size", 0)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []*regexp.Regexp{
+		regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d{6} Allowlist reloaded source=apps.txt entries=3$`),
+		regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d{6} WARN Retrying sink=hec batch.err="connection refused" batch.size=0$`),
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i, re := range want {
+		if !re.MatchString(lines[i]) {
+			t.Errorf("line %d = %q, want match for %s", i, lines[i], re)
+		}
+	}
+}
+
+func TestPrettyHandler_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	logger.Info("hidden")
+	logger.Debug("hidden")
+	logger.Error("shown")
+	if got := buf.String(); strings.Contains(got, "hidden") || !strings.Contains(got, "ERROR shown") {
+		t.Errorf("output = %q, want only the error", got)
+	}
+}
+
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	logger.Info("Output failed", "op", "write")
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("not JSON: %q", buf.String())
+	}
+	if entry["msg"] != "Output failed" || entry["op"] != "write" || entry["level"] != "INFO" {
+		t.Errorf("entry = %v", entry)
+	}
+}
+
+func TestNew_RejectsUnknownFormat(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", slog.LevelInfo); err == nil {
+		t.Error("New should reject an unknown format")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLevel(s); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel should reject an unknown level")
+	}
+}
@@ -4,160 +4,1065 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
 
+	"otlp-mock-receiver/aggregate"
 	"otlp-mock-receiver/allowlist"
+	"otlp-mock-receiver/compare"
+	"otlp-mock-receiver/dedup"
+	"otlp-mock-receiver/delay"
+	"otlp-mock-receiver/logging"
 	"otlp-mock-receiver/metrics"
+	"otlp-mock-receiver/multiline"
 	"otlp-mock-receiver/output"
 	"otlp-mock-receiver/receiver"
+	"otlp-mock-receiver/routing"
+	"otlp-mock-receiver/statsd"
+	"otlp-mock-receiver/telemetry"
 	"otlp-mock-receiver/transform"
+	"otlp-mock-receiver/verify"
 )
 
 func main() {
-	grpcPort := flag.Int("grpc-port", 4317, "gRPC server port")
-	httpPort := flag.Int("http-port", 4318, "HTTP server port")
-	verbose := flag.Bool("verbose", false, "Show verbose output including transformed logs")
-	sampleRate := flag.Int("sample-rate", 1, "Keep 1 in N logs (1 = keep all, 10 = keep 10%)")
-	sampleDebugOnly := flag.Bool("sample-debug-only", true, "Only sample DEBUG logs (INFO+ always kept)")
-	allowlistFile := flag.String("allowlist", "", "Path to allowlist file (one app per line)")
-	enableMetrics := flag.Bool("metrics", true, "Enable Prometheus metrics endpoint at /metrics")
-	outputFile := flag.String("output-file", "", "Path to JSON output file")
-	outputFormat := flag.String("output-format", "jsonl", "Output format: jsonl (default) or json")
-	outputBufferSize := flag.Int("output-buffer-size", 100, "Number of logs to buffer before flushing")
-	outputFlushInterval := flag.Duration("output-flush-interval", 5*time.Second, "Flush interval for buffered logs")
-	flag.Parse()
-
-	// Cloud Foundry provides PORT env var - override HTTP port if set
-	if portEnv := os.Getenv("PORT"); portEnv != "" {
-		if port, err := strconv.Atoi(portEnv); err == nil {
-			*httpPort = port
-		}
-	}
-
-	// Configure sampling
-	if *sampleRate > 1 {
-		receiver.SetSamplingConfig(&transform.SamplingConfig{
-			SampleRate:      *sampleRate,
-			SampleDebugOnly: *sampleDebugOnly,
-		})
+	if err := execute(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+}
 
-	// Configure allowlist
-	var appAllowlist *allowlist.Allowlist
-	if *allowlistFile != "" {
-		var err error
-		appAllowlist, err = allowlist.LoadFromFile(*allowlistFile)
+// serveFlags registers the receiver flags and returns the function that starts it
+func serveFlags(fs *flag.FlagSet) func(args []string) error {
+	grpcPort := fs.Int("grpc-port", 4317, "gRPC server port")
+	httpPort := fs.Int("http-port", 4318, "HTTP server port")
+	verbose := fs.Bool("verbose", false, "Show verbose output including transformed logs")
+	partialSuccess := fs.Bool("partial-success", false, "Report records dropped on purpose (filtered, sampled, dropped by rule) as rejected in partial_success export responses")
+	logLevel := fs.String("log-level", "info", "Lowest level of the receiver's own log messages: debug, info, warn, or error")
+	logFormat := fs.String("log-format", logging.FormatPretty, "Receiver log format: pretty (console lines and per-record boxes), text, or json")
+	sampleRate := fs.Int("sample-rate", 1, "Keep 1 in N logs (1 = keep all, 10 = keep 10%)")
+	sampleDebugOnly := fs.Bool("sample-debug-only", true, "Only sample DEBUG logs (INFO+ always kept)")
+	sampleKeepSeverity := fs.String("sample-keep-severity", "", "Never sample logs at or above this severity (default ERROR, or INFO with -sample-debug-only)")
+	sampleSeverityRates := fs.String("sample-severity-rates", "", "Per-level 1-in-N rates overriding -sample-rate, e.g. INFO=10,DEBUG=100")
+	sampleTraceAware := fs.Bool("sample-trace-aware", true, "Sample records with a trace ID by trace, keeping or dropping a trace's logs together")
+	sampleMaxPerSecond := fs.Float64("sample-max-per-second", 0, "Keep at most N logs per second per app, absorbing short bursts (0 = disabled; replaces -sample-rate)")
+	transformConfigFile := fs.String("transform-config", "", "Path to YAML transform config file")
+	normalizeSeverity := fs.Bool("normalize-severity", false, "Infer a missing severity number from severity_text or the body's leading word")
+	scrubSecrets := fs.Bool("scrub-secrets", false, "Mask the values of password, authorization, api_key, and set-cookie keys in structured bodies")
+	bodyTypeAttribute := fs.String("body-type-attribute", "", "Tag each record with its body's type (json, logfmt, plaintext, ...) in this attribute")
+	routingConfigFile := fs.String("routing-config", "", "Path to YAML or JSON routing rules file (replaces the default rules)")
+	routingPersist := fs.Bool("routing-persist", false, "Save routing rules edited through /api/routing back to -routing-config")
+	adminAPI := fs.Bool("admin-api", false, "Enable the endpoints that edit routing rules and the allowlist while the receiver runs, on the ingest port, behind -admin-token")
+	adminToken := fs.String("admin-token", "", "Bearer token the -admin-api endpoints require (default ADMIN_TOKEN from the environment)")
+	quarantine := fs.Bool("quarantine", false, "Route records missing an app name or timestamp, or with an invalid UTF-8 body, to -quarantine-index instead of the routing rules")
+	quarantineIndex := fs.String("quarantine-index", receiver.DefaultQuarantineIndex, "Index for quarantined records")
+	allowlistFile := fs.String("allowlist", "", "Path or http(s) URL of the allowlist (one app per line, or .yaml with per-app policy)")
+	allowlistInterval := fs.Duration("allowlist-interval", time.Minute, "How often to fetch an -allowlist URL for changes")
+	allowlistCache := fs.String("allowlist-cache", "", "File to keep the last good copy of an -allowlist URL in, used if it cannot be fetched at startup")
+	cfAPI := fs.String("cf-api", "", "Cloud Foundry API to sync the allowlist from, allowing every app with -cf-label-selector (CF_PASSWORD or CF_CLIENT_SECRET in the environment)")
+	cfLabelSelector := fs.String("cf-label-selector", "logging=splunk", "Label selector for the apps -cf-api allows")
+	cfUsername := fs.String("cf-username", "", "CF user to sync as, with CF_PASSWORD; otherwise the -cf-client-id client is used")
+	cfClientID := fs.String("cf-client-id", "cf", "UAA client to sync with")
+	cfSyncInterval := fs.Duration("cf-sync-interval", 5*time.Minute, "How often to sync the allowlist from -cf-api")
+	cfSkipSSL := fs.Bool("cf-skip-ssl-validation", false, "Skip TLS verification for -cf-api and its UAA")
+	allowlistPollInterval := fs.Duration("allowlist-poll-interval", 2*time.Second, "How often to check -allowlist and -denylist files for changes when fsnotify cannot watch them")
+	allowlistReportOnly := fs.Bool("allowlist-report-only", false, "Keep records the allowlist or denylist would filter, only counting them in /admin/allowlist/stats")
+	allowlistPersist := fs.Bool("allowlist-persist", false, "Save allowlist edits made through /admin/allowlist back to -allowlist")
+	denylistFile := fs.String("denylist", "", "Path to denylist file of apps to drop (one app per line); wins over -allowlist")
+	enableMetrics := fs.Bool("metrics", true, "Enable Prometheus metrics endpoint at /metrics")
+	selfTelemetryEndpoint := fs.String("self-telemetry-endpoint", "", "Export the receiver's own metrics and processing spans over OTLP gRPC to this host:port (headers in OTEL_EXPORTER_OTLP_HEADERS)")
+	selfTelemetryInsecure := fs.Bool("self-telemetry-insecure", false, "Send self-telemetry over plaintext gRPC instead of TLS")
+	selfTelemetryInterval := fs.Duration("self-telemetry-interval", 15*time.Second, "How often self-telemetry metrics are exported")
+	statsdAddr := fs.String("statsd-addr", "", "Also send metrics over UDP to a StatsD server or Datadog agent at this host:port")
+	statsdFormat := fs.String("statsd-format", statsd.FormatDogStatsD, "StatsD line format: dogstatsd (labels as tags) or statsd (label values in the metric name)")
+	statsdPrefix := fs.String("statsd-prefix", statsd.DefaultPrefix, "Prefix replacing otlp_receiver_ in StatsD metric names")
+	statsdInterval := fs.Duration("statsd-interval", 10*time.Second, "How often metrics are sent to StatsD")
+	metricsAppLimit := fs.Int("metrics-app-limit", metrics.DefaultAppLimit, "Apps given their own series in the per-app ingest metrics, by records received, the rest summed under app=\"_other\" (0 = no limit)")
+	outputFile := fs.String("output-file", "", "Path to JSON output file, or - to stream entries to stdout in place of the console boxes")
+	outputFormat := fs.String("output-format", "jsonl", "Output format: jsonl (default), json, or otlp-json or otlp-proto to write the transformed records as raw OTLP")
+	outputSchema := fs.String("output-schema", "", "Reshape -output-file entries with a YAML field mapping file, or the hec-event preset")
+	outputNativeTypes := fs.Bool("output-native-types", false, "Keep attribute values' OTLP types in output entries (numbers, booleans, arrays, objects) instead of flattening them to strings")
+	outputBufferSize := fs.Int("output-buffer-size", 100, "Number of logs to buffer before flushing")
+	outputFlushInterval := fs.Duration("output-flush-interval", 5*time.Second, "Flush interval for buffered logs")
+	outputKeep := fs.Int("output-keep", 5, "Rotated output files to keep, deleting older ones (0 = keep all)")
+	outputCompress := fs.Bool("output-compress", false, "Gzip output files as they are rotated")
+	outputCheckpoint := fs.Bool("output-checkpoint", false, "Record a checkpoint beside each output file after every flush, so a flush a crash interrupted is cut back and counted on restart")
+	outputShards := fs.Int("output-shards", 1, "Write -output-file as this many shard files, logs-000.jsonl and on, round-robin, each with its own buffer and lock, for throughput under load")
+	outputGzipLevel := fs.Int("output-gzip-level", 6, "Compression level, 1 (fastest) to 9 (smallest), for an -output-file ending in .gz, which is gzipped as it is written")
+	outputRotateInterval := fs.String("output-rotate-interval", "", "Start a new output file every interval: hourly, daily, or a duration like 15m (default: only by size or -output-file date template)")
+	archiveBucket := fs.String("archive-bucket", "", "Upload each rotated -output-file to this S3-compatible bucket (AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY in the environment)")
+	archiveEndpoint := fs.String("archive-endpoint", "", "Object storage endpoint, e.g. https://storage.googleapis.com or http://localhost:9000 (default: AWS S3 in -archive-region)")
+	archiveRegion := fs.String("archive-region", "us-east-1", "Region to sign archive uploads for")
+	archiveKey := fs.String("archive-key", output.DefaultArchiveKey, "Object key pattern: %Y, %m, %d, %H, %M, %S from the file's last write (UTC), {file} its name, {index} to split it by routed index")
+	archiveMaxRetries := fs.Int("archive-max-retries", 5, "Retries of a failed upload before the file is given up on")
+	archiveSkipSSL := fs.Bool("archive-skip-ssl-validation", false, "Skip TLS verification for -archive-endpoint")
+	hecURL := fs.String("hec-url", "", "Forward transformed records to this Splunk HTTP Event Collector, e.g. https://splunk:8088 (token in HEC_TOKEN)")
+	hecSourcetype := fs.String("hec-sourcetype", "otel", "HEC sourcetype for records whose route sets none")
+	hecBatchSize := fs.Int("hec-batch-size", 100, "Events per HEC request")
+	hecFlushInterval := fs.Duration("hec-flush-interval", 5*time.Second, "Longest a partial HEC batch waits")
+	hecMaxRetries := fs.Int("hec-max-retries", 5, "Retries of a failed HEC batch before it is dropped")
+	hecSkipSSL := fs.Bool("hec-skip-ssl-validation", false, "Skip TLS verification for -hec-url")
+	esURL := fs.String("es-url", "", "Write transformed records to this Elasticsearch or OpenSearch cluster with the _bulk API, e.g. https://elastic:9200 (ES_PASSWORD or ES_API_KEY in the environment)")
+	esIndex := fs.String("es-index", output.DefaultESIndexPattern, "Index name pattern: {index} is the routed index, and %Y, %m, %d, %H are the record's UTC date")
+	esUsername := fs.String("es-username", "", "Elasticsearch user for basic auth, with ES_PASSWORD")
+	esBatchSize := fs.Int("es-batch-size", 100, "Documents per Elasticsearch bulk request")
+	esFlushInterval := fs.Duration("es-flush-interval", 5*time.Second, "Longest a partial Elasticsearch batch waits")
+	esMaxRetries := fs.Int("es-max-retries", 5, "Retries of documents the cluster pushes back on before they are dropped")
+	esSkipSSL := fs.Bool("es-skip-ssl-validation", false, "Skip TLS verification for -es-url")
+	kafkaBrokers := fs.String("kafka-brokers", "", "Publish transformed records to Kafka through these comma-separated brokers, e.g. kafka-1:9092,kafka-2:9092")
+	kafkaTopic := fs.String("kafka-topic", "otlp-logs", "Kafka topic to publish to")
+	kafkaFormat := fs.String("kafka-format", output.KafkaFormatJSON, "Kafka message value: json (the -output-file entry) or otlp (protobuf ExportLogsServiceRequest)")
+	kafkaAcks := fs.String("kafka-acks", "all", "Acknowledgment each produce waits for: none, leader, or all")
+	kafkaBatchSize := fs.Int("kafka-batch-size", 100, "Messages per Kafka produce")
+	kafkaFlushInterval := fs.Duration("kafka-flush-interval", 5*time.Second, "Longest a partial Kafka batch waits")
+	kafkaMaxRetries := fs.Int("kafka-max-retries", 5, "Retries of a failed Kafka produce before the messages are dropped")
+	otlpExportEndpoint := fs.String("otlp-export-endpoint", "", "Re-export transformed records over OTLP to this endpoint: host:port for gRPC, a URL for HTTP (headers in OTLP_EXPORT_HEADERS)")
+	otlpExportProtocol := fs.String("otlp-export-protocol", output.ProtocolGRPC, "OTLP export protocol: grpc or http")
+	otlpExportInsecure := fs.Bool("otlp-export-insecure", false, "Export over plaintext gRPC instead of TLS")
+	otlpExportSkipSSL := fs.Bool("otlp-export-skip-ssl-validation", false, "Skip TLS verification for -otlp-export-endpoint")
+	otlpExportBatchSize := fs.Int("otlp-export-batch-size", 100, "Records per OTLP export")
+	otlpExportFlushInterval := fs.Duration("otlp-export-flush-interval", 5*time.Second, "Longest a partial OTLP export batch waits")
+	otlpExportMaxRetries := fs.Int("otlp-export-max-retries", 5, "Retries of a failed OTLP export before the batch is dropped")
+	sqliteDB := fs.String("sqlite-db", "", "Store transformed records in this SQLite database file, searchable through /api/logs")
+	sqliteBatchSize := fs.Int("sqlite-batch-size", 100, "Records per SQLite transaction")
+	sqliteFlushInterval := fs.Duration("sqlite-flush-interval", time.Second, "Longest a partial SQLite batch waits before records can be queried")
+	streamPath := fs.String("stream-path", "", "Stream transformed records as JSON lines to this named pipe or Unix socket, for a local forwarder to read live")
+	streamSchema := fs.String("stream-schema", "", "Reshape -stream-path entries with a YAML field mapping file, or the hec-event preset")
+	streamFlushInterval := fs.Duration("stream-flush-interval", time.Second, "Longest a partial batch waits before it is written to -stream-path")
+	liveTail := fs.Bool("live-tail", false, "Serve /stream, pushing transformed entries to Server-Sent Events and WebSocket clients as they arrive")
+	outputQueueSize := fs.Int("output-queue-size", 10000, "Records queued for each output, written from its own goroutine (0 = write from the receiving goroutine)")
+	outputQueuePolicy := fs.String("output-queue-policy", output.QueueBlock, "When an output queue is full: block the receiver, or drop-oldest queued record")
+	var sinkURIs []string
+	fs.Func("output", "Also send transformed records to this sink URI, e.g. kafka://broker:9092/topic (repeatable; schemes: "+strings.Join(output.SinkSchemes(), ", ")+")", func(uri string) error {
+		sinkURIs = append(sinkURIs, uri)
+		return nil
+	})
+	outputFilters := make(map[string]*output.Filter)
+	fs.Func("output-filter", "Only send an output some records, as NAME:FILTER, e.g. file:include-index=tas_errors&min-severity=WARN (repeatable; NAME is file, hec, elasticsearch, kafka, otlp-export, sqlite, stream, or live-tail)", func(spec string) error {
+		name, params, ok := strings.Cut(spec, ":")
+		if !ok {
+			return fmt.Errorf("want NAME:FILTER, e.g. file:min-severity=WARN")
+		}
+		filter, err := output.ParseFilter(params)
 		if err != nil {
-			log.Fatalf("Failed to load allowlist: %v", err)
+			return err
 		}
-		receiver.SetAllowlist(appAllowlist)
-	}
+		outputFilters[name] = filter
+		return nil
+	})
+	verifyInterval := fs.Duration("verify-interval", 0, "Reconcile transformed counters with -output-file lines at this interval and log drift (0 = disabled)")
+	summaryInterval := fs.Duration("summary-interval", 0, "Log a one-line throughput summary (rates, drops by reason, output queue backlog) at this interval (0 = disabled)")
+	delaySpec := fs.String("delay", "", "Response delay distribution, e.g. 50ms, uniform:10ms,200ms, normal:80ms,20ms, pareto:10ms,1.5")
+	delayConfigFile := fs.String("delay-config", "", "Path to YAML response delay config file (per-app/index distributions)")
+	compareFile := fs.String("compare-file", "", "Path to a collector file exporter's JSON output to cross-check against received records")
+	compareGrace := fs.Duration("compare-grace", compare.DefaultGrace, "How long an unmatched record waits for its counterpart before counting as lost")
 
-	// Configure metrics
-	if *enableMetrics {
-		receiver.SetMetrics(metrics.New())
-	}
+	multilineStart := fs.String("multiline-start", "", "Regex matching the first line of a record; other lines are stitched onto the previous record from the same app instance")
+	multilineWindow := fs.Duration("multiline-window", 2*time.Second, "Maximum gap between stitched lines")
+	dedupWindow := fs.Duration("dedup-window", 0, "Suppress identical records (same app and body) within this window, emitting a summary with duplicate_count (0 = disabled)")
 
-	// Configure JSON output
-	var jsonWriter *output.JSONWriter
-	if *outputFile != "" {
-		format := output.FormatJSONL
-		if *outputFormat == "json" {
-			format = output.FormatJSON
+	return func(args []string) error {
+		// Cloud Foundry provides PORT env var - override HTTP port if set
+		if portEnv := os.Getenv("PORT"); portEnv != "" {
+			if port, err := strconv.Atoi(portEnv); err == nil {
+				*httpPort = port
+			}
 		}
-		var err error
-		jsonWriter, err = output.NewJSONWriter(*outputFile, format, *outputBufferSize, *outputFlushInterval, 100*1024*1024)
+
+		// Configure the receiver's own logging before anything is logged
+		level, err := logging.ParseLevel(*logLevel)
 		if err != nil {
-			log.Fatalf("Failed to create JSON writer: %v", err)
+			log.Fatalf("Invalid -log-level: %v", err)
 		}
-		receiver.SetJSONWriter(jsonWriter)
-	}
+		logger, err := logging.New(os.Stderr, *logFormat, level)
+		if err != nil {
+			log.Fatalf("Invalid -log-format: %v", err)
+		}
+		logging.Setup(logger)
+		receiver.SetConsoleBoxes(*logFormat == logging.FormatPretty)
 
-	log.SetFlags(log.Ltime | log.Lmicroseconds)
-
-	// Detect Cloud Foundry environment
-	isCloudFoundry := os.Getenv("PORT") != ""
-
-	log.Println("========================================")
-	log.Println("  OTLP Mock Receiver")
-	log.Println("  Practice environment for TAS logging")
-	log.Println("========================================")
-	if isCloudFoundry {
-		log.Printf("  Mode:          Cloud Foundry (multiplexed)")
-		log.Printf("  Endpoint:      :%d (gRPC + HTTP)", *httpPort)
-	} else {
-		log.Printf("  gRPC endpoint: localhost:%d", *grpcPort)
-		log.Printf("  HTTP endpoint: localhost:%d/v1/logs", *httpPort)
-	}
-	log.Printf("  Health check:  localhost:%d/health", *httpPort)
-	if *enableMetrics {
-		log.Printf("  Metrics:       localhost:%d/metrics", *httpPort)
-	}
-	if *sampleRate > 1 {
-		log.Printf("  Sampling:      1-in-%d (debug-only: %v)", *sampleRate, *sampleDebugOnly)
-	}
-	if appAllowlist != nil {
-		log.Printf("  Allowlist:     %s (%d apps)", *allowlistFile, len(appAllowlist.Apps()))
-	}
-	if jsonWriter != nil {
-		log.Printf("  Output:        %s (%s format)", *outputFile, *outputFormat)
-	}
-	log.Println("========================================")
-	log.Println("")
+		// Configure sampling
+		hashSampling := *sampleRate > 1 || *sampleSeverityRates != ""
+		if hashSampling && *sampleMaxPerSecond > 0 {
+			log.Fatalf("-sample-rate and -sample-severity-rates are mutually exclusive with -sample-max-per-second")
+		}
+		var samplingConfig *transform.SamplingConfig
+		if hashSampling || *sampleMaxPerSecond > 0 {
+			samplingConfig = &transform.SamplingConfig{
+				SampleRate:      *sampleRate,
+				SampleDebugOnly: *sampleDebugOnly,
+				TraceAware:      *sampleTraceAware,
+				MaxPerSecond:    *sampleMaxPerSecond,
+			}
+			if *sampleKeepSeverity != "" {
+				sev, err := transform.ParseSeverity(*sampleKeepSeverity)
+				if err != nil {
+					log.Fatalf("Invalid -sample-keep-severity: %v", err)
+				}
+				samplingConfig.KeepSeverity = sev
+			}
+			if *sampleSeverityRates != "" {
+				rates, err := transform.ParseSeverityRates(*sampleSeverityRates)
+				if err != nil {
+					log.Fatalf("Invalid -sample-severity-rates: %v", err)
+				}
+				samplingConfig.SeverityRates = rates
+			}
+			if err := samplingConfig.Validate(); err != nil {
+				log.Fatalf("Invalid sampling config: %v", err)
+			}
+			receiver.SetSamplingConfig(samplingConfig)
+		}
 
-	var grpcServer *grpc.Server
-	var httpServer *http.Server
+		// Configure transforms
+		transformConfig := transform.DefaultConfig()
+		if *transformConfigFile != "" {
+			cfg, err := transform.LoadConfig(*transformConfigFile)
+			if err != nil {
+				log.Fatalf("Failed to load transform config: %v", err)
+			}
+			transformConfig = cfg
+			if len(cfg.AggregateRules) > 0 {
+				receiver.SetAggregator(aggregate.New(cfg.AggregateRules))
+			}
+		}
+		if *normalizeSeverity && transformConfig.SeverityNormalization == nil {
+			transformConfig.SeverityNormalization = transform.DefaultSeverityNormalization()
+		}
+		if *scrubSecrets && transformConfig.SecretScrubbing == nil {
+			transformConfig.SecretScrubbing = transform.DefaultSecretScrubbing()
+		}
+		if *bodyTypeAttribute != "" {
+			transformConfig.BodyTypeAttribute = *bodyTypeAttribute
+		}
+		receiver.SetTransformConfig(transformConfig)
 
-	if isCloudFoundry {
-		// Cloud Foundry: use multiplexed server on single port
-		var err error
-		grpcServer, httpServer, err = receiver.StartMultiplexed(*httpPort, *verbose)
-		if err != nil {
-			log.Fatalf("Failed to start multiplexed server: %v", err)
+		// Configure the admin API
+		if *adminAPI {
+			token := *adminToken
+			if token == "" {
+				token = os.Getenv("ADMIN_TOKEN")
+			}
+			if token == "" {
+				log.Fatalf("-admin-api requires -admin-token or ADMIN_TOKEN")
+			}
+			receiver.SetAdminToken(token)
 		}
-	} else {
-		// Local development: use separate servers
-		var err error
-		grpcServer, err = receiver.StartGRPC(*grpcPort, *verbose)
-		if err != nil {
-			log.Fatalf("Failed to start gRPC server: %v", err)
+
+		// Configure routing
+		if *routingPersist && *routingConfigFile == "" {
+			log.Fatalf("-routing-persist requires -routing-config")
+		}
+		if *routingPersist && !*adminAPI {
+			log.Fatalf("-routing-persist requires -admin-api")
+		}
+		if *routingConfigFile != "" {
+			fc, err := routing.ReadConfig(*routingConfigFile)
+			if err != nil {
+				log.Fatalf("Failed to load routing config: %v", err)
+			}
+			r, err := fc.Build()
+			if err != nil {
+				log.Fatalf("Failed to load routing config: %v", err)
+			}
+			for _, f := range fc.Lint() {
+				slog.Warn("Routing config: lint finding", "rule", f.Rule, "severity", f.Severity, "message", f.Message)
+			}
+			receiver.SetRouter(r)
+			if *routingPersist {
+				receiver.SetRoutingEditor(routing.NewEditor(r, *routingConfigFile))
+			}
+		}
+		if *quarantine {
+			if *quarantineIndex == "" {
+				log.Fatalf("-quarantine requires a -quarantine-index")
+			}
+			receiver.SetQuarantineIndex(*quarantineIndex)
 		}
 
-		httpServer, err = receiver.StartHTTP(*httpPort, *verbose)
-		if err != nil {
-			log.Fatalf("Failed to start HTTP server: %v", err)
+		// Configure response delay
+		var delayConfig *delay.Config
+		if *delayConfigFile != "" {
+			var err error
+			delayConfig, err = delay.LoadConfig(*delayConfigFile)
+			if err != nil {
+				log.Fatalf("Failed to load delay config: %v", err)
+			}
+		}
+		if *delaySpec != "" {
+			dist, err := delay.Parse(*delaySpec)
+			if err != nil {
+				log.Fatalf("Invalid -delay: %v", err)
+			}
+			if delayConfig == nil {
+				delayConfig = delay.NewConfig(dist)
+			} else {
+				delayConfig.Default = dist
+			}
+		}
+		if delayConfig != nil {
+			receiver.SetDelayConfig(delayConfig)
 		}
-	}
 
-	// Start allowlist hot-reload watcher
-	stopWatcher := make(chan struct{})
-	if appAllowlist != nil && *allowlistFile != "" {
-		go appAllowlist.WatchFile(*allowlistFile, stopWatcher, nil, nil)
-		log.Printf("Watching %s for changes (hot-reload enabled)", *allowlistFile)
-	}
+		// Configure collector output comparison
+		var comparer *compare.Comparer
+		if *compareFile != "" {
+			comparer = compare.New(*compareGrace)
+			receiver.SetComparer(comparer)
+		}
 
-	// Wait for interrupt
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+		// Configure multiline stitching
+		if *multilineStart != "" {
+			start, err := regexp.Compile(*multilineStart)
+			if err != nil {
+				log.Fatalf("Invalid -multiline-start: %v", err)
+			}
+			receiver.SetMultiline(multiline.New(start, *multilineWindow))
+		}
 
-	log.Println("\nShutting down...")
-	close(stopWatcher)
-	if jsonWriter != nil {
-		jsonWriter.Close()
-	}
-	grpcServer.GracefulStop()
-	httpServer.Close()
+		// Configure deduplication
+		if *dedupWindow > 0 {
+			receiver.SetDeduper(dedup.New(*dedupWindow))
+		}
 
-	received, transformed, dropped := receiver.GetStats()
-	log.Printf("Final stats: received=%d transformed=%d dropped=%d", received, transformed, dropped)
+		// Configure allowlist
+		if *allowlistPersist && (*allowlistFile == "" || allowlist.IsURL(*allowlistFile)) {
+			log.Fatalf("-allowlist-persist requires an -allowlist file")
+		}
+		if *allowlistPersist && !*adminAPI {
+			log.Fatalf("-allowlist-persist requires -admin-api")
+		}
+		var appAllowlist *allowlist.Allowlist
+		if *cfAPI != "" && *allowlistFile != "" {
+			log.Fatalf("-cf-api and -allowlist are mutually exclusive")
+		}
+		var remoteAllowlist *allowlist.Remote
+		var cfSync *allowlist.CFSync
+		if *cfAPI != "" {
+			cfSync = &allowlist.CFSync{
+				API:               *cfAPI,
+				LabelSelector:     *cfLabelSelector,
+				ClientID:          *cfClientID,
+				ClientSecret:      os.Getenv("CF_CLIENT_SECRET"),
+				Username:          *cfUsername,
+				Password:          os.Getenv("CF_PASSWORD"),
+				SkipSSLValidation: *cfSkipSSL,
+			}
+			var err error
+			appAllowlist, err = cfSync.Load()
+			if err != nil {
+				log.Fatalf("Failed to sync allowlist: %v", err)
+			}
+		} else if allowlist.IsURL(*allowlistFile) {
+			remoteAllowlist = &allowlist.Remote{URL: *allowlistFile, Cache: *allowlistCache}
+			var err error
+			appAllowlist, err = remoteAllowlist.Load()
+			if err != nil {
+				log.Fatalf("Failed to load allowlist: %v", err)
+			}
+		} else if *allowlistFile != "" {
+			var err error
+			appAllowlist, err = allowlist.LoadFromFile(*allowlistFile)
+			if err != nil {
+				log.Fatalf("Failed to load allowlist: %v", err)
+			}
+			if *allowlistPersist {
+				appAllowlist.PersistTo(*allowlistFile)
+			}
+		}
+		if *denylistFile != "" {
+			if appAllowlist == nil {
+				appAllowlist = allowlist.NewDenylist(nil)
+			}
+			if err := appAllowlist.LoadDenylist(*denylistFile); err != nil {
+				log.Fatalf("Failed to load denylist: %v", err)
+			}
+		}
+		if appAllowlist != nil {
+			receiver.SetAllowlist(appAllowlist)
+		}
+		receiver.SetAllowlistReportOnly(*allowlistReportOnly)
+
+		// Configure metrics
+		var metricsInstance *metrics.Metrics
+		if *enableMetrics {
+			if *metricsAppLimit < 0 {
+				log.Fatalf("-metrics-app-limit must not be negative")
+			}
+			metricsInstance = metrics.New()
+			metricsInstance.LogsByApp.SetLimit(*metricsAppLimit)
+			receiver.SetMetrics(metricsInstance)
+			if appAllowlist != nil {
+				appAllowlist.SetMetrics(metricsInstance)
+			}
+		}
+
+		// Configure self-telemetry, exporting the metrics above if enabled
+		var selfTelemetry *telemetry.Telemetry
+		if *selfTelemetryEndpoint != "" {
+			var err error
+			selfTelemetry, err = telemetry.Start(telemetry.Config{
+				Endpoint: *selfTelemetryEndpoint,
+				Insecure: *selfTelemetryInsecure,
+				Interval: *selfTelemetryInterval,
+			}, metricsInstance)
+			if err != nil {
+				log.Fatalf("Invalid -self-telemetry-endpoint: %v", err)
+			}
+			receiver.SetTracerProvider(selfTelemetry.TracerProvider())
+		}
+
+		// Configure StatsD, sending the metrics above
+		var statsdEmitter *statsd.Emitter
+		if *statsdAddr != "" {
+			if metricsInstance == nil {
+				log.Fatalf("-statsd-addr requires -metrics")
+			}
+			var err error
+			statsdEmitter, err = statsd.New(statsd.Config{
+				Addr:     *statsdAddr,
+				Format:   *statsdFormat,
+				Prefix:   *statsdPrefix,
+				Interval: *statsdInterval,
+			}, metricsInstance.Registry())
+			if err != nil {
+				log.Fatalf("Failed to configure StatsD: %v", err)
+			}
+		}
+
+		receiver.SetNativeTypes(*outputNativeTypes)
+		receiver.SetPartialSuccess(*partialSuccess)
+
+		if *summaryInterval < 0 {
+			log.Fatalf("-summary-interval must not be negative")
+		}
+
+		// Each output gets its own queue; outputs are closed in order on shutdown
+		if *outputQueueSize < 0 {
+			log.Fatalf("-output-queue-size must not be negative")
+		}
+		if *outputQueuePolicy != output.QueueBlock && *outputQueuePolicy != output.QueueDropOldest {
+			log.Fatalf("-output-queue-policy must be %s or %s", output.QueueBlock, output.QueueDropOldest)
+		}
+		type namedSink struct {
+			name string
+			sink output.Sink
+		}
+		var sinks []namedSink
+		var filtered []string // Outputs given an -output-filter, as shown in the banner
+		_, fileFiltered := outputFilters["file"]
+		addSink := func(name string, sink output.Sink) output.Sink {
+			if filter, ok := outputFilters[name]; ok {
+				sink = output.NewFilteredSink(sink, filter)
+				filtered = append(filtered, fmt.Sprintf("%s (%s)", name, filter))
+				delete(outputFilters, name)
+			}
+			if *outputQueueSize > 0 {
+				queue, err := output.NewQueue(name, sink, *outputQueueSize, *outputQueuePolicy)
+				if err != nil {
+					log.Fatalf("Failed to create output queue: %v", err)
+				}
+				if metricsInstance != nil {
+					queue.SetMetrics(metricsInstance)
+				}
+				sink = queue
+			}
+			receiver.AddSink(sink)
+			sinks = append(sinks, namedSink{name, sink})
+			return sink
+		}
+
+		// Configure JSON output, split across files if the path has {severity}
+		// or {index}, or sharded with -output-shards
+		var jsonWriter *output.JSONWriter
+		var rotator interface{ SetRotateHandler(func(path string)) } // The file output, however many files it writes
+		var jsonSink output.Sink
+		if *outputFile != "" {
+			format, err := output.ParseFormat(*outputFormat)
+			if err != nil {
+				log.Fatalf("Invalid -output-format: %v", err)
+			}
+			if format.IsOTLP() && *outputSchema != "" {
+				log.Fatalf("-output-schema cannot be used with -output-format %s", format)
+			}
+			if *outputKeep < 0 {
+				log.Fatalf("-output-keep must not be negative")
+			}
+			if *outputGzipLevel < 1 || *outputGzipLevel > 9 {
+				log.Fatalf("-output-gzip-level must be 1 to 9")
+			}
+			if *outputFile == output.Stdout && *outputRotateInterval != "" {
+				log.Fatalf("-output-rotate-interval cannot be used with -output-file -")
+			}
+			var rotateInterval time.Duration
+			if *outputRotateInterval != "" {
+				var err error
+				if rotateInterval, err = output.ParseRotateInterval(*outputRotateInterval); err != nil {
+					log.Fatalf("Invalid -output-rotate-interval: %v", err)
+				}
+			}
+			var schema *output.Schema
+			if *outputSchema != "" {
+				if schema, err = output.LoadSchema(*outputSchema); err != nil {
+					log.Fatalf("Invalid -output-schema: %v", err)
+				}
+			}
+			open := func(path string) (*output.JSONWriter, error) {
+				w, err := output.NewJSONWriter(path, format, *outputBufferSize, *outputFlushInterval, 100*1024*1024)
+				if err != nil {
+					return nil, err
+				}
+				if rotateInterval > 0 {
+					w.SetRotateInterval(rotateInterval)
+				}
+				w.SetRetention(*outputKeep, *outputCompress)
+				if err := w.SetGzipLevel(*outputGzipLevel); err != nil {
+					w.Close()
+					return nil, err
+				}
+				if schema != nil {
+					w.SetSchema(schema)
+				}
+				if metricsInstance != nil {
+					w.SetMetrics(metricsInstance)
+				}
+				w.SetCheckpoint(*outputCheckpoint)
+				return w, nil
+			}
+			switch {
+			case *outputShards > 1:
+				shardWriter, err := output.NewShardWriter(*outputFile, *outputShards, format, open)
+				if err != nil {
+					log.Fatalf("Invalid -output-shards: %v", err)
+				}
+				rotator = shardWriter
+				jsonSink = addSink("file", shardWriter)
+			case output.IsSplitPath(*outputFile):
+				splitWriter := output.NewSplitWriter(*outputFile, format, open)
+				rotator = splitWriter
+				jsonSink = addSink("file", splitWriter)
+			default:
+				if jsonWriter, err = open(*outputFile); err != nil {
+					log.Fatalf("Failed to create JSON writer: %v", err)
+				}
+				rotator = jsonWriter
+				jsonSink = addSink("file", jsonWriter)
+			}
+			if *outputFile == output.Stdout {
+				receiver.SetConsoleBoxes(false)
+			}
+		}
+
+		if *outputSchema != "" && *outputFile == "" {
+			log.Fatalf("-output-schema requires -output-file")
+		}
+		if *outputShards < 1 {
+			log.Fatalf("-output-shards must be at least 1")
+		}
+		if *outputShards > 1 && *outputFile == "" {
+			log.Fatalf("-output-shards requires -output-file")
+		}
+		if *outputCheckpoint && (*outputFile == "" || *outputFile == output.Stdout) {
+			log.Fatalf("-output-checkpoint requires an -output-file other than -")
+		}
+
+		// Configure archiving of rotated output files
+		var archiver *output.Archiver
+		if *archiveBucket != "" {
+			if *outputFile == "" {
+				log.Fatalf("-archive-bucket requires -output-file")
+			}
+			if *outputFile == output.Stdout {
+				log.Fatalf("-archive-bucket cannot be used with -output-file -")
+			}
+			if os.Getenv("AWS_ACCESS_KEY_ID") == "" || os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
+				log.Fatalf("-archive-bucket requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+			}
+			var err error
+			archiver, err = output.NewArchiver(output.ArchiveConfig{
+				Endpoint:          *archiveEndpoint,
+				Bucket:            *archiveBucket,
+				Region:            *archiveRegion,
+				KeyPattern:        *archiveKey,
+				AccessKey:         os.Getenv("AWS_ACCESS_KEY_ID"),
+				SecretKey:         os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				SessionToken:      os.Getenv("AWS_SESSION_TOKEN"),
+				MaxRetries:        *archiveMaxRetries,
+				SkipSSLValidation: *archiveSkipSSL,
+			})
+			if err != nil {
+				log.Fatalf("Failed to configure archiving: %v", err)
+			}
+			if metricsInstance != nil {
+				archiver.SetMetrics(metricsInstance)
+			}
+			rotator.SetRotateHandler(archiver.Add)
+		}
+
+		// Configure Splunk HEC forwarding
+		var hecWriter *output.HECWriter
+		if *hecURL != "" {
+			if os.Getenv("HEC_TOKEN") == "" {
+				log.Fatalf("-hec-url requires the HEC token in HEC_TOKEN")
+			}
+			var err error
+			hecWriter, err = output.NewHECWriter(output.HECConfig{
+				URL:               *hecURL,
+				Token:             os.Getenv("HEC_TOKEN"),
+				Sourcetype:        *hecSourcetype,
+				BatchSize:         *hecBatchSize,
+				FlushInterval:     *hecFlushInterval,
+				MaxRetries:        *hecMaxRetries,
+				SkipSSLValidation: *hecSkipSSL,
+			})
+			if err != nil {
+				log.Fatalf("Failed to configure HEC output: %v", err)
+			}
+			if metricsInstance != nil {
+				hecWriter.SetMetrics(metricsInstance)
+			}
+			addSink("hec", hecWriter)
+		}
+
+		// Configure Elasticsearch/OpenSearch output
+		var esWriter *output.ESWriter
+		if *esURL != "" {
+			var err error
+			esWriter, err = output.NewESWriter(output.ESConfig{
+				URL:               *esURL,
+				IndexPattern:      *esIndex,
+				Username:          *esUsername,
+				Password:          os.Getenv("ES_PASSWORD"),
+				APIKey:            os.Getenv("ES_API_KEY"),
+				BatchSize:         *esBatchSize,
+				FlushInterval:     *esFlushInterval,
+				MaxRetries:        *esMaxRetries,
+				SkipSSLValidation: *esSkipSSL,
+			})
+			if err != nil {
+				log.Fatalf("Failed to configure Elasticsearch output: %v", err)
+			}
+			if metricsInstance != nil {
+				esWriter.SetMetrics(metricsInstance)
+			}
+			addSink("elasticsearch", esWriter)
+		}
+
+		// Configure Kafka output
+		var kafkaWriter *output.KafkaWriter
+		if *kafkaBrokers != "" {
+			var err error
+			kafkaWriter, err = output.NewKafkaWriter(output.KafkaConfig{
+				Brokers:       output.ParseBrokers(*kafkaBrokers),
+				Topic:         *kafkaTopic,
+				Format:        *kafkaFormat,
+				Acks:          *kafkaAcks,
+				BatchSize:     *kafkaBatchSize,
+				FlushInterval: *kafkaFlushInterval,
+				MaxRetries:    *kafkaMaxRetries,
+			})
+			if err != nil {
+				log.Fatalf("Failed to configure Kafka output: %v", err)
+			}
+			if metricsInstance != nil {
+				kafkaWriter.SetMetrics(metricsInstance)
+			}
+			addSink("kafka", kafkaWriter)
+		}
+
+		// Configure OTLP pass-through export
+		var otlpExporter *output.OTLPExporter
+		if *otlpExportEndpoint != "" {
+			headers, err := output.ParseHeaders(os.Getenv("OTLP_EXPORT_HEADERS"))
+			if err != nil {
+				log.Fatalf("Invalid OTLP_EXPORT_HEADERS: %v", err)
+			}
+			otlpExporter, err = output.NewOTLPExporter(output.OTLPExportConfig{
+				Endpoint:          *otlpExportEndpoint,
+				Protocol:          *otlpExportProtocol,
+				Insecure:          *otlpExportInsecure,
+				SkipSSLValidation: *otlpExportSkipSSL,
+				Headers:           headers,
+				BatchSize:         *otlpExportBatchSize,
+				FlushInterval:     *otlpExportFlushInterval,
+				MaxRetries:        *otlpExportMaxRetries,
+			})
+			if err != nil {
+				log.Fatalf("Failed to configure OTLP export: %v", err)
+			}
+			if metricsInstance != nil {
+				otlpExporter.SetMetrics(metricsInstance)
+			}
+			addSink("otlp-export", otlpExporter)
+		}
+
+		// Configure the SQLite store behind /api/logs
+		var sqliteWriter *output.SQLiteWriter
+		if *sqliteDB != "" {
+			var err error
+			sqliteWriter, err = output.NewSQLiteWriter(output.SQLiteConfig{
+				Path:          *sqliteDB,
+				BatchSize:     *sqliteBatchSize,
+				FlushInterval: *sqliteFlushInterval,
+			})
+			if err != nil {
+				log.Fatalf("Failed to configure SQLite output: %v", err)
+			}
+			if metricsInstance != nil {
+				sqliteWriter.SetMetrics(metricsInstance)
+			}
+			receiver.SetLogStore(sqliteWriter)
+			addSink("sqlite", sqliteWriter)
+		}
+
+		// Configure streaming to a named pipe or Unix socket
+		var streamWriter *output.StreamWriter
+		if *streamPath != "" {
+			cfg := output.StreamConfig{
+				Path:          *streamPath,
+				FlushInterval: *streamFlushInterval,
+				MaxRetries:    5,
+			}
+			var err error
+			if *streamSchema != "" {
+				if cfg.Schema, err = output.LoadSchema(*streamSchema); err != nil {
+					log.Fatalf("Invalid -stream-schema: %v", err)
+				}
+			}
+			streamWriter, err = output.NewStreamWriter(cfg)
+			if err != nil {
+				log.Fatalf("Failed to configure stream output: %v", err)
+			}
+			if metricsInstance != nil {
+				streamWriter.SetMetrics(metricsInstance)
+			}
+			addSink("stream", streamWriter)
+		}
+		if *streamSchema != "" && *streamPath == "" {
+			log.Fatalf("-stream-schema requires -stream-path")
+		}
+
+		// Configure the live tail behind /stream
+		var tail *output.LiveTail
+		if *liveTail {
+			tail = output.NewLiveTail(1000)
+			if metricsInstance != nil {
+				tail.SetMetrics(metricsInstance)
+			}
+			receiver.SetLiveTail(tail)
+			addSink("live-tail", tail)
+		}
+
+		// Configure sinks given by URI
+		for _, uri := range sinkURIs {
+			sink, err := output.OpenSink(uri)
+			if err != nil {
+				log.Fatalf("Invalid -output: %v", err)
+			}
+			inner := sink
+			if f, ok := sink.(*output.FilteredSink); ok {
+				inner = f.Sink
+			}
+			if m, ok := inner.(interface{ SetMetrics(*metrics.Metrics) }); ok && metricsInstance != nil {
+				m.SetMetrics(metricsInstance)
+			}
+			if w, ok := inner.(*output.JSONWriter); ok && w.Path() == output.Stdout {
+				receiver.SetConsoleBoxes(false)
+			}
+			if w, ok := inner.(*output.SQLiteWriter); ok && sqliteWriter == nil {
+				sqliteWriter = w // The first database is the one /api/logs queries
+				receiver.SetLogStore(w)
+			}
+			name, _, _ := strings.Cut(uri, "?")
+			addSink(name, sink)
+		}
+		for name := range outputFilters {
+			log.Fatalf("-output-filter %s: no such output is configured; want file, hec, elasticsearch, kafka, otlp-export, sqlite, stream, or live-tail", name)
+		}
+
+		// Configure continuous output verification
+		var verifier *verify.Verifier
+		if *verifyInterval > 0 {
+			if *outputFile == "" || metricsInstance == nil {
+				log.Fatalf("-verify-interval requires -output-file and -metrics")
+			}
+			if output.IsSplitPath(*outputFile) {
+				log.Fatalf("-verify-interval cannot be used with an -output-file split by {severity} or {index}")
+			}
+			if *outputShards > 1 {
+				log.Fatalf("-verify-interval cannot be used with -output-shards")
+			}
+			if *outputFile == output.Stdout {
+				log.Fatalf("-verify-interval cannot be used with -output-file -")
+			}
+			if strings.HasSuffix(*outputFile, ".gz") {
+				log.Fatalf("-verify-interval cannot be used with a gzipped -output-file")
+			}
+			if *outputSchema != "" {
+				log.Fatalf("-verify-interval cannot be used with -output-schema")
+			}
+			if output.Format(*outputFormat).IsOTLP() {
+				log.Fatalf("-verify-interval cannot be used with -output-format %s", *outputFormat)
+			}
+			if fileFiltered {
+				log.Fatalf("-verify-interval cannot be used with an -output-filter for file")
+			}
+			verifier = verify.New(jsonWriter.Path(), metricsInstance, jsonWriter)
+			if queue, ok := jsonSink.(*output.Queue); ok {
+				verifier.SetQueue(queue)
+			}
+			receiver.SetVerifier(verifier)
+		}
+
+		// Detect Cloud Foundry environment
+		isCloudFoundry := os.Getenv("PORT") != ""
+
+		var startup banner
+		if isCloudFoundry {
+			startup.add("Mode", "Cloud Foundry (multiplexed)")
+			startup.add("Endpoint", ":%d (gRPC + HTTP)", *httpPort)
+		} else {
+			startup.add("gRPC endpoint", "localhost:%d", *grpcPort)
+			startup.add("HTTP endpoint", "localhost:%d/v1/logs", *httpPort)
+		}
+		startup.add("Health check", "localhost:%d/health", *httpPort)
+		if *enableMetrics {
+			startup.add("Metrics", "localhost:%d/metrics", *httpPort)
+		}
+		if *adminAPI {
+			startup.add("Admin API", "localhost:%d/api/routing, /admin/allowlist (bearer token)", *httpPort)
+		}
+		if selfTelemetry != nil {
+			startup.add("Telemetry", "%s every %s", *selfTelemetryEndpoint, *selfTelemetryInterval)
+		}
+		if statsdEmitter != nil {
+			startup.add("StatsD", "%s (%s) every %s", *statsdAddr, *statsdFormat, *statsdInterval)
+		}
+		if samplingConfig != nil {
+			keep := transform.SeverityName(samplingConfig.KeepThreshold())
+			switch {
+			case samplingConfig.MaxPerSecond > 0:
+				startup.add("Sampling", "%g/s per app (keeping %s+)", samplingConfig.MaxPerSecond, keep)
+			case *sampleSeverityRates != "" && *sampleRate > 1:
+				startup.add("Sampling", "%s, otherwise 1-in-%d (keeping %s+)", *sampleSeverityRates, *sampleRate, keep)
+			case *sampleSeverityRates != "":
+				startup.add("Sampling", "%s (keeping %s+)", *sampleSeverityRates, keep)
+			default:
+				startup.add("Sampling", "1-in-%d (keeping %s+)", *sampleRate, keep)
+			}
+		}
+		if *transformConfigFile != "" {
+			startup.add("Transforms", "%s", *transformConfigFile)
+		}
+		if *routingConfigFile != "" && *routingPersist {
+			startup.add("Routing", "%s (admin API edits saved)", *routingConfigFile)
+		} else if *routingConfigFile != "" {
+			startup.add("Routing", "%s", *routingConfigFile)
+		}
+		if *quarantine {
+			startup.add("Quarantine", "%s", *quarantineIndex)
+		}
+		if delayConfig != nil {
+			startup.add("Delay", "%s", delayConfig)
+		}
+		if comparer != nil {
+			startup.add("Compare", "%s (grace %s)", *compareFile, *compareGrace)
+		}
+		if *multilineStart != "" {
+			startup.add("Multiline", "start %q (window %s)", *multilineStart, *multilineWindow)
+		}
+		if *dedupWindow > 0 {
+			startup.add("Dedup", "%s window", *dedupWindow)
+		}
+		if *allowlistPersist {
+			startup.add("Allowlist", "%s (%d apps, admin API edits saved)", *allowlistFile, len(appAllowlist.Apps()))
+		} else if *allowlistFile != "" {
+			startup.add("Allowlist", "%s (%d apps)", *allowlistFile, len(appAllowlist.Apps()))
+		}
+		if cfSync != nil {
+			startup.add("Allowlist", "apps labeled %s in %s (%d apps)", *cfLabelSelector, *cfAPI, len(appAllowlist.Apps()))
+		}
+		if *allowlistReportOnly {
+			startup.add("Allowlist", "report only, filtered records are kept")
+		}
+		if *denylistFile != "" {
+			startup.add("Denylist", "%s (%d apps)", *denylistFile, len(appAllowlist.Denied()))
+		}
+		if *outputFile == output.Stdout {
+			startup.add("Output", "stdout (%s format, console boxes off)", *outputFormat)
+		} else if *outputFile != "" && *outputSchema != "" {
+			startup.add("Output", "%s (%s format, schema %s)", *outputFile, *outputFormat, *outputSchema)
+		} else if *outputFile != "" {
+			startup.add("Output", "%s (%s format)", *outputFile, *outputFormat)
+		}
+		if *outputShards > 1 {
+			startup.add("Output shards", "%d files, %s to %s", *outputShards,
+				output.ShardPath(*outputFile, 0), output.ShardPath(*outputFile, *outputShards-1))
+		}
+		if archiver != nil {
+			startup.add("Archive", "%s (keys %s)", *archiveBucket, *archiveKey)
+		}
+		if hecWriter != nil {
+			startup.add("HEC", "%s (batches of %d)", *hecURL, *hecBatchSize)
+		}
+		if esWriter != nil {
+			startup.add("Elasticsearch", "%s (index %s, batches of %d)", *esURL, *esIndex, *esBatchSize)
+		}
+		if kafkaWriter != nil {
+			startup.add("Kafka", "%s on %s (%s, acks %s)", *kafkaTopic, *kafkaBrokers, *kafkaFormat, *kafkaAcks)
+		}
+		if otlpExporter != nil {
+			startup.add("OTLP export", "%s (%s, batches of %d)", otlpExporter.Endpoint(), *otlpExportProtocol, *otlpExportBatchSize)
+		}
+		if sqliteWriter != nil {
+			startup.add("SQLite", "%s (query at localhost:%d/api/logs)", sqliteWriter.Path(), *httpPort)
+		}
+		if streamWriter != nil {
+			startup.add("Stream", "%s (JSON lines to a named pipe or Unix socket)", streamWriter.Path())
+		}
+		if tail != nil {
+			startup.add("Live tail", "localhost:%d/stream (Server-Sent Events or WebSocket)", *httpPort)
+		}
+		for _, uri := range sinkURIs {
+			startup.add("Sink", "%s", uri)
+		}
+		for _, f := range filtered {
+			startup.add("Output filter", "%s", f)
+		}
+		if len(sinks) > 0 && *outputQueueSize > 0 {
+			startup.add("Output queue", "%d records per output (%s when full)", *outputQueueSize, *outputQueuePolicy)
+		}
+		if verifier != nil {
+			startup.add("Verify", "every %s", *verifyInterval)
+		}
+		if *summaryInterval > 0 {
+			startup.add("Summary", "every %s", *summaryInterval)
+		}
+		startup.log(*logFormat == logging.FormatPretty)
+
+		var grpcServer *grpc.Server
+		var httpServer *http.Server
+
+		if isCloudFoundry {
+			// Cloud Foundry: use multiplexed server on single port
+			var err error
+			grpcServer, httpServer, err = receiver.StartMultiplexed(*httpPort, *verbose)
+			if err != nil {
+				log.Fatalf("Failed to start multiplexed server: %v", err)
+			}
+		} else {
+			// Local development: use separate servers
+			var err error
+			grpcServer, err = receiver.StartGRPC(*grpcPort, *verbose)
+			if err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+
+			httpServer, err = receiver.StartHTTP(*httpPort, *verbose)
+			if err != nil {
+				log.Fatalf("Failed to start HTTP server: %v", err)
+			}
+		}
+
+		// Start allowlist hot-reload watcher
+		stopWatcher := make(chan struct{})
+		if appAllowlist != nil {
+			appAllowlist.SetPollInterval(*allowlistPollInterval)
+		}
+		if cfSync != nil {
+			go appAllowlist.WatchCF(cfSync, *cfSyncInterval, stopWatcher, nil)
+			slog.Info("Syncing allowlist from the CF API", "api", *cfAPI, "interval", *cfSyncInterval)
+		} else if remoteAllowlist != nil {
+			go appAllowlist.WatchRemote(remoteAllowlist, *allowlistInterval, stopWatcher, nil)
+			slog.Info("Fetching allowlist for changes", "url", *allowlistFile, "interval", *allowlistInterval)
+		} else if appAllowlist != nil && *allowlistFile != "" {
+			go appAllowlist.WatchFile(*allowlistFile, stopWatcher, nil, nil)
+			slog.Info("Watching allowlist for changes (hot-reload enabled)", "path", *allowlistFile)
+		}
+		if appAllowlist != nil && *denylistFile != "" {
+			go appAllowlist.WatchDenylist(*denylistFile, stopWatcher, nil, nil)
+			slog.Info("Watching denylist for changes (hot-reload enabled)", "path", *denylistFile)
+		}
+
+		// Start tailing collector output
+		if comparer != nil {
+			go compare.NewTailer(*compareFile, comparer).Run(time.Second, stopWatcher)
+		}
+
+		// Start reconciling counters with the output file
+		if verifier != nil {
+			go verifier.Run(*verifyInterval, stopWatcher)
+		}
+
+		// Start sending metrics to StatsD
+		if statsdEmitter != nil {
+			go statsdEmitter.Run(stopWatcher)
+		}
+
+		// Start logging throughput summaries
+		if *summaryInterval > 0 {
+			go receiver.RunSummary(*summaryInterval, stopWatcher)
+		}
+
+		// Start processing held records (stitched, aggregated, deduplicated) whose window has expired
+		go receiver.RunFlusher(*verbose, stopWatcher)
+
+		// Wait for interrupt
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		<-sigChan
+
+		slog.Info("Shutting down...")
+		close(stopWatcher)
+		receiver.FlushPending(*verbose)
+		for _, s := range sinks {
+			if err := s.sink.Close(); err != nil {
+				slog.Error("Output: final flush failed", "output", s.name, "err", err)
+			}
+		}
+		if archiver != nil {
+			if err := archiver.Close(); err != nil {
+				slog.Error("Archive: final upload failed", "err", err)
+			}
+		}
+		grpcServer.GracefulStop()
+		httpServer.Close()
+		if selfTelemetry != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := selfTelemetry.Shutdown(ctx); err != nil {
+				slog.Error("Self-telemetry: final export failed", "err", err)
+			}
+			cancel()
+		}
+		if statsdEmitter != nil {
+			if err := statsdEmitter.Close(); err != nil {
+				slog.Error("StatsD: final send failed", "err", err)
+			}
+		}
+
+		received, transformed, dropped := receiver.GetStats()
+		slog.Info("Final stats", "received", received, "transformed", transformed, "dropped", dropped)
+		slog.Info("Request latency", "summary", receiver.GetLatencySummary())
+		if comparer != nil {
+			slog.Info("Collector comparison", "report", comparer.Report())
+		}
+		if verifier != nil {
+			r := verifier.Check()
+			slog.Info("Output verification", "transformed", r.Transformed, "file", r.File, "drift", r.Drift)
+			verifier.Close()
+		}
+		return nil
+	}
 }
@@ -0,0 +1,116 @@
+// ABOUTME: Per-app ingest counters with a cap on how many apps get their own series.
+// ABOUTME: At each scrape the top N apps by records are exported by name, and the rest summed under _other.
+
+package metrics
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// OtherApps labels the records of apps outside the top N
+const OtherApps = "_other"
+
+// DefaultAppLimit is how many apps get their own series unless SetLimit says otherwise
+const DefaultAppLimit = 20
+
+// AppVolume counts the records and bytes received from each app. It is a
+// Prometheus collector exporting the top apps by records, so a workshop
+// with hundreds of apps keeps a bounded number of series.
+type AppVolume struct {
+	logsDesc  *prometheus.Desc
+	bytesDesc *prometheus.Desc
+
+	mu    sync.Mutex
+	limit int // Apps exported by name; 0 exports all
+	apps  map[string]*appVolume
+}
+
+// appVolume is one app's running totals
+type appVolume struct {
+	logs  float64
+	bytes float64
+}
+
+// NewAppVolume creates per-app counters exporting the top DefaultAppLimit apps
+func NewAppVolume() *AppVolume {
+	return &AppVolume{
+		logsDesc: prometheus.NewDesc("otlp_receiver_logs_received_by_app_total",
+			"Total log records received by app, for the top apps by records; the rest are summed under app=\""+OtherApps+"\"",
+			[]string{"app"}, nil),
+		bytesDesc: prometheus.NewDesc("otlp_receiver_bytes_received_by_app_total",
+			"Total encoded size of the log records received by app, grouped as otlp_receiver_logs_received_by_app_total",
+			[]string{"app"}, nil),
+		limit: DefaultAppLimit,
+		apps:  make(map[string]*appVolume),
+	}
+}
+
+// SetLimit exports the top n apps by name, or every app if n is 0
+func (v *AppVolume) SetLimit(n int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.limit = n
+}
+
+// Observe counts a record of size bytes from app
+func (v *AppVolume) Observe(app string, size int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	a := v.apps[app]
+	if a == nil {
+		a = &appVolume{}
+		v.apps[app] = a
+	}
+	a.logs++
+	a.bytes += float64(size)
+}
+
+// Describe implements prometheus.Collector
+func (v *AppVolume) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.logsDesc
+	ch <- v.bytesDesc
+}
+
+// Collect implements prometheus.Collector, exporting the top apps by
+// records, ties broken by name, and the rest as OtherApps
+func (v *AppVolume) Collect(ch chan<- prometheus.Metric) {
+	v.mu.Lock()
+	names := make([]string, 0, len(v.apps))
+	totals := make(map[string]appVolume, len(v.apps))
+	for name, a := range v.apps {
+		names = append(names, name)
+		totals[name] = *a
+	}
+	limit := v.limit
+	v.mu.Unlock()
+
+	sort.Slice(names, func(i, j int) bool {
+		if totals[names[i]].logs != totals[names[j]].logs {
+			return totals[names[i]].logs > totals[names[j]].logs
+		}
+		return names[i] < names[j]
+	})
+
+	var other appVolume
+	for i, name := range names {
+		a := totals[name]
+		if limit > 0 && i >= limit {
+			other.logs += a.logs
+			other.bytes += a.bytes
+			continue
+		}
+		v.emit(ch, name, a)
+	}
+	if other.logs > 0 {
+		v.emit(ch, OtherApps, other)
+	}
+}
+
+// emit sends one app's counters
+func (v *AppVolume) emit(ch chan<- prometheus.Metric, app string, a appVolume) {
+	ch <- prometheus.MustNewConstMetric(v.logsDesc, prometheus.CounterValue, a.logs, app)
+	ch <- prometheus.MustNewConstMetric(v.bytesDesc, prometheus.CounterValue, a.bytes, app)
+}
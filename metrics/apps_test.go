@@ -0,0 +1,67 @@
+// ABOUTME: Tests for the per-app ingest counters.
+// ABOUTME: Verifies the top apps by records are exported by name and the rest summed under _other.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAppVolume_TopAppsAndOther(t *testing.T) {
+	v := NewAppVolume()
+	v.SetLimit(2)
+	for range 3 {
+		v.Observe("checkout", 100)
+	}
+	v.Observe("payments", 50)
+	v.Observe("payments", 50)
+	v.Observe("cart", 10)
+	v.Observe("search", 20)
+
+	expected := `
+# HELP otlp_receiver_logs_received_by_app_total Total log records received by app, for the top apps by records; the rest are summed under app="_other"
+# TYPE otlp_receiver_logs_received_by_app_total counter
+otlp_receiver_logs_received_by_app_total{app="_other"} 2
+otlp_receiver_logs_received_by_app_total{app="checkout"} 3
+otlp_receiver_logs_received_by_app_total{app="payments"} 2
+# HELP otlp_receiver_bytes_received_by_app_total Total encoded size of the log records received by app, grouped as otlp_receiver_logs_received_by_app_total
+# TYPE otlp_receiver_bytes_received_by_app_total counter
+otlp_receiver_bytes_received_by_app_total{app="_other"} 30
+otlp_receiver_bytes_received_by_app_total{app="checkout"} 300
+otlp_receiver_bytes_received_by_app_total{app="payments"} 100
+`
+	if err := testutil.CollectAndCompare(v, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAppVolume_NoLimit(t *testing.T) {
+	v := NewAppVolume()
+	v.SetLimit(0)
+	for i := range DefaultAppLimit + 5 {
+		v.Observe(strings.Repeat("a", i+1), 1)
+	}
+	if got := testutil.CollectAndCount(v, "otlp_receiver_logs_received_by_app_total"); got != DefaultAppLimit+5 {
+		t.Errorf("exported %d apps, want all %d", got, DefaultAppLimit+5)
+	}
+}
+
+func TestAppVolume_TiesBrokenByName(t *testing.T) {
+	v := NewAppVolume()
+	v.SetLimit(1)
+	v.Observe("zebra", 1)
+	v.Observe("aardvark", 1)
+
+	expected := `
+# HELP otlp_receiver_logs_received_by_app_total Total log records received by app, for the top apps by records; the rest are summed under app="_other"
+# TYPE otlp_receiver_logs_received_by_app_total counter
+otlp_receiver_logs_received_by_app_total{app="_other"} 1
+otlp_receiver_logs_received_by_app_total{app="aardvark"} 1
+`
+	if err := testutil.CollectAndCompare(v, strings.NewReader(expected), "otlp_receiver_logs_received_by_app_total"); err != nil {
+		t.Error(err)
+	}
+}
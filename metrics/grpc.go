@@ -0,0 +1,58 @@
+// ABOUTME: gRPC server instrumentation that counts, times, and sizes the requests each method handles.
+// ABOUTME: A unary interceptor giving the LogsService the same visibility as the HTTP endpoints, error rates included, and a connection counter.
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// UnaryServerInterceptor counts each unary request by method and status
+// code, and records its handling duration and request message size
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if msg, ok := req.(proto.Message); ok {
+			m.GRPCMessageSize.WithLabelValues(info.FullMethod).Observe(float64(proto.Size(msg)))
+		}
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.GRPCRequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		m.GRPCRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return resp, err
+	}
+}
+
+// StatsHandler counts the gRPC server's open connections
+func (m *Metrics) StatsHandler() stats.Handler {
+	return connCounter{m}
+}
+
+// connCounter is a stats.Handler that only watches connections begin and end
+type connCounter struct {
+	m *Metrics
+}
+
+func (c connCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (c connCounter) HandleRPC(context.Context, stats.RPCStats) {}
+
+func (c connCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (c connCounter) HandleConn(_ context.Context, s stats.ConnStats) {
+	switch s.(type) {
+	case *stats.ConnBegin:
+		c.m.OpenConnections.WithLabelValues("grpc").Inc()
+	case *stats.ConnEnd:
+		c.m.OpenConnections.WithLabelValues("grpc").Dec()
+	}
+}
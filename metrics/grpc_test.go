@@ -0,0 +1,80 @@
+// ABOUTME: Tests for gRPC server instrumentation.
+// ABOUTME: Verifies requests are counted by method and status code, their duration and message size observed, and connections counted.
+
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := New()
+	intercept := m.UnaryServerInterceptor()
+	const method = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+
+	ok := func(ctx context.Context, req any) (any, error) { return "done", nil }
+	busy := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.ResourceExhausted, "slow down")
+	}
+
+	req := wrapperspb.String("hello") // 7 bytes on the wire
+	if resp, err := intercept(context.Background(), req, info, ok); resp != "done" || err != nil {
+		t.Errorf("interceptor returned %v, %v; want the handler's reply", resp, err)
+	}
+	intercept(context.Background(), req, info, ok)
+	if _, err := intercept(context.Background(), req, info, busy); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("interceptor error = %v, want the handler's", err)
+	}
+
+	if got := testutil.ToFloat64(m.GRPCRequests.WithLabelValues(method, "OK")); got != 2 {
+		t.Errorf("GRPCRequests{code=OK} = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.GRPCRequests.WithLabelValues(method, "ResourceExhausted")); got != 1 {
+		t.Errorf("GRPCRequests{code=ResourceExhausted} = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.GRPCRequestDuration); got != 1 {
+		t.Errorf("GRPCRequestDuration has %d series, want 1", got)
+	}
+
+	expected := `
+# HELP otlp_receiver_grpc_request_message_bytes Size of gRPC request messages, uncompressed, by method
+# TYPE otlp_receiver_grpc_request_message_bytes histogram
+otlp_receiver_grpc_request_message_bytes_bucket{method="` + method + `",le="256"} 3
+otlp_receiver_grpc_request_message_bytes_bucket{method="` + method + `",le="1024"} 3
+otlp_receiver_grpc_request_message_bytes_bucket{method="` + method + `",le="4096"} 3
+otlp_receiver_grpc_request_message_bytes_bucket{method="` + method + `",le="16384"} 3
+otlp_receiver_grpc_request_message_bytes_bucket{method="` + method + `",le="65536"} 3
+otlp_receiver_grpc_request_message_bytes_bucket{method="` + method + `",le="262144"} 3
+otlp_receiver_grpc_request_message_bytes_bucket{method="` + method + `",le="1.048576e+06"} 3
+otlp_receiver_grpc_request_message_bytes_bucket{method="` + method + `",le="4.194304e+06"} 3
+otlp_receiver_grpc_request_message_bytes_bucket{method="` + method + `",le="+Inf"} 3
+otlp_receiver_grpc_request_message_bytes_sum{method="` + method + `"} 21
+otlp_receiver_grpc_request_message_bytes_count{method="` + method + `"} 3
+`
+	if err := testutil.CollectAndCompare(m.GRPCMessageSize, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	m := New()
+	h := m.StatsHandler()
+	ctx := h.TagConn(context.Background(), &stats.ConnTagInfo{})
+	h.HandleConn(ctx, &stats.ConnBegin{})
+	h.HandleConn(ctx, &stats.ConnBegin{})
+	h.HandleConn(ctx, &stats.ConnEnd{})
+
+	if got := testutil.ToFloat64(m.OpenConnections.WithLabelValues("grpc")); got != 1 {
+		t.Errorf("OpenConnections{grpc} = %v, want 1", got)
+	}
+}
@@ -0,0 +1,58 @@
+// ABOUTME: HTTP server instrumentation that counts, times, and sizes the requests each endpoint serves.
+// ABOUTME: Wraps handlers in promhttp middleware curried with the endpoint's path, so the ingest path can be watched under load, and counts open connections.
+
+package metrics
+
+import (
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// InstrumentHandler counts the requests h serves by status code, and
+// records their duration and body size, all labelled with path. The path
+// should be a route pattern, like /api/apps/{name}/report, not the request
+// URL, to keep the number of series bounded.
+func (m *Metrics) InstrumentHandler(path string, h http.Handler) http.Handler {
+	labels := prometheus.Labels{"path": path}
+	h = promhttp.InstrumentHandlerCounter(m.HTTPRequests.MustCurryWith(labels), h)
+	h = promhttp.InstrumentHandlerDuration(m.HTTPRequestDuration.MustCurryWith(labels), h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := &countingBody{ReadCloser: r.Body}
+		r.Body = body
+		h.ServeHTTP(w, r)
+		// A body rejected unread is sized by its Content-Length
+		n := body.n
+		if n == 0 && r.ContentLength > 0 {
+			n = r.ContentLength
+		}
+		m.HTTPRequestBodySize.With(labels).Observe(float64(n))
+	})
+}
+
+// TrackConnState counts the HTTP server's open connections, for use as its
+// ConnState hook. Hijacked connections, like the live tail's WebSockets, are
+// no longer the server's and stop being counted.
+func (m *Metrics) TrackConnState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		m.OpenConnections.WithLabelValues("http").Inc()
+	case http.StateClosed, http.StateHijacked:
+		m.OpenConnections.WithLabelValues("http").Dec()
+	}
+}
+
+// countingBody counts the bytes read from a request body
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,97 @@
+// ABOUTME: Tests for HTTP server instrumentation.
+// ABOUTME: Verifies requests are counted by path and status, their duration and body size observed, and connections counted.
+
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentHandler(t *testing.T) {
+	m := New()
+	h := m.InstrumentHandler("/v1/logs", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if len(body) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader(strings.Repeat("x", 1000))))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader(strings.Repeat("x", 3000))))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/logs", nil))
+
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues("/v1/logs", "200")); got != 2 {
+		t.Errorf("HTTPRequests{code=200} = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues("/v1/logs", "400")); got != 1 {
+		t.Errorf("HTTPRequests{code=400} = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.HTTPRequestDuration); got != 1 {
+		t.Errorf("HTTPRequestDuration has %d series, want 1", got)
+	}
+
+	expected := `
+# HELP otlp_receiver_http_request_body_bytes Size of HTTP request bodies as sent, by endpoint path
+# TYPE otlp_receiver_http_request_body_bytes histogram
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="256"} 1
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="1024"} 2
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="4096"} 3
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="16384"} 3
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="65536"} 3
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="262144"} 3
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="1.048576e+06"} 3
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="4.194304e+06"} 3
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="+Inf"} 3
+otlp_receiver_http_request_body_bytes_sum{path="/v1/logs"} 4000
+otlp_receiver_http_request_body_bytes_count{path="/v1/logs"} 3
+`
+	if err := testutil.CollectAndCompare(m.HTTPRequestBodySize, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInstrumentHandler_UnreadBody(t *testing.T) {
+	m := New()
+	h := m.InstrumentHandler("/v1/logs", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader(strings.Repeat("x", 500))))
+
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues("/v1/logs", "415")); got != 1 {
+		t.Errorf("HTTPRequests{code=415} = %v, want 1", got)
+	}
+	expected := `
+# HELP otlp_receiver_http_request_body_bytes Size of HTTP request bodies as sent, by endpoint path
+# TYPE otlp_receiver_http_request_body_bytes histogram
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="256"} 0
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="1024"} 1
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="4096"} 1
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="16384"} 1
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="65536"} 1
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="262144"} 1
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="1.048576e+06"} 1
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="4.194304e+06"} 1
+otlp_receiver_http_request_body_bytes_bucket{path="/v1/logs",le="+Inf"} 1
+otlp_receiver_http_request_body_bytes_sum{path="/v1/logs"} 500
+otlp_receiver_http_request_body_bytes_count{path="/v1/logs"} 1
+`
+	if err := testutil.CollectAndCompare(m.HTTPRequestBodySize, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTrackConnState(t *testing.T) {
+	m := New()
+	for _, state := range []http.ConnState{http.StateNew, http.StateNew, http.StateNew, http.StateActive, http.StateIdle, http.StateClosed, http.StateHijacked} {
+		m.TrackConnState(nil, state)
+	}
+	if got := testutil.ToFloat64(m.OpenConnections.WithLabelValues("http")); got != 1 {
+		t.Errorf("OpenConnections{http} = %v, want 1", got)
+	}
+}
@@ -11,13 +11,67 @@ import (
 // Metrics holds all Prometheus metrics for the receiver
 type Metrics struct {
 	LogsReceived      prometheus.Counter
+	LogsByApp         *AppVolume
 	LogsTransformed   prometheus.Counter
 	LogsDropped       *prometheus.CounterVec
 	LogsBySeverity    *prometheus.CounterVec
 	LogsByIndex       *prometheus.CounterVec
+	RequestSize       prometheus.Histogram
+	BodySize          *prometheus.HistogramVec
+	DeliveredBodySize *prometheus.HistogramVec
 	TransformDuration prometheus.Histogram
 	PCIRedactions     prometheus.Counter
 	BodyTruncations   prometheus.Counter
+	SecretsScrubbed   *prometheus.CounterVec
+
+	RequestsByContentType *prometheus.CounterVec
+	HTTPRequests          *prometheus.CounterVec
+	HTTPRequestDuration   *prometheus.HistogramVec
+	HTTPRequestBodySize   *prometheus.HistogramVec
+	GRPCRequests          *prometheus.CounterVec
+	GRPCRequestDuration   *prometheus.HistogramVec
+	GRPCMessageSize       *prometheus.HistogramVec
+	OpenConnections       *prometheus.GaugeVec
+	LogsByBodyType        *prometheus.CounterVec
+	LogsAggregated        *prometheus.CounterVec
+	OutputDrift           *prometheus.GaugeVec
+	ProtocolMismatches    *prometheus.CounterVec
+	SamplingRatio         *prometheus.GaugeVec
+	LogsSampledKept       *prometheus.CounterVec
+	LogsSampledDropped    *prometheus.CounterVec
+	LogsFanoutCopies      prometheus.Counter
+	RoutingRuleMatches    *prometheus.CounterVec
+	RoutingDefault        prometheus.Counter
+	RoutingOverflow       *prometheus.CounterVec
+	RoutingDuration       prometheus.Histogram
+	LogsQuarantined       *prometheus.CounterVec
+	AllowlistFiltered     *prometheus.CounterVec
+	AllowlistWatchMode    *prometheus.GaugeVec
+	AllowlistReloads      *prometheus.CounterVec
+	AllowlistReloadErrors *prometheus.CounterVec
+	OutputRotations       *prometheus.CounterVec
+	OutputFilesDeleted    prometheus.Counter
+	OutputWriteErrors     *prometheus.CounterVec
+	OutputEntries         *prometheus.CounterVec
+	OutputBytes           *prometheus.CounterVec
+	OutputFlushes         *prometheus.CounterVec
+	OutputFlushDuration   *prometheus.HistogramVec
+	OutputDiscarded       prometheus.Counter
+	OutputRecoveryLost    prometheus.Counter
+	OutputQueueDepth      *prometheus.GaugeVec
+	OutputDropped         *prometheus.CounterVec
+	OutputWorkerBusy      *prometheus.CounterVec
+	OutputBatchPending    *prometheus.GaugeVec
+	OutputBuffered        *prometheus.GaugeVec
+	HECEvents             *prometheus.CounterVec
+	OTLPExportRecords     *prometheus.CounterVec
+	ESDocuments           *prometheus.CounterVec
+	KafkaMessages         *prometheus.CounterVec
+	SQLiteRows            *prometheus.CounterVec
+	StreamRecords         *prometheus.CounterVec
+	LiveTailClients       prometheus.Gauge
+	LiveTailDropped       prometheus.Counter
+	ArchiveFiles          *prometheus.CounterVec
 
 	registry *prometheus.Registry
 }
@@ -54,6 +108,24 @@ func New() *Metrics {
 			Help: "Total log records by routing index",
 		}, []string{"index"}),
 
+		RequestSize: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:    "otlp_receiver_request_payload_bytes",
+			Help:    "Size of export requests encoded as protobuf, whichever protocol and encoding they arrived in",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8), // 256B to 4MB
+		}),
+
+		BodySize: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "otlp_receiver_log_body_bytes",
+			Help:    "Size of log record bodies as received, before truncation, by severity",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8), // 64B to 1MB
+		}, []string{"severity"}),
+
+		DeliveredBodySize: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "otlp_receiver_delivered_body_bytes",
+			Help:    "Size of log record bodies as delivered, after truncation, by routing index",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8), // 64B to 1MB
+		}, []string{"index"}),
+
 		TransformDuration: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
 			Name:    "otlp_receiver_transform_duration_seconds",
 			Help:    "Time spent transforming log records",
@@ -69,7 +141,252 @@ func New() *Metrics {
 			Name: "otlp_receiver_body_truncations_total",
 			Help: "Total number of log bodies truncated",
 		}),
+
+		SecretsScrubbed: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_secrets_scrubbed_total",
+			Help: "Total log records with a sensitive key's value masked, by key",
+		}, []string{"key"}),
+
+		RequestsByContentType: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_http_requests_by_content_type_total",
+			Help: "Total OTLP/HTTP export requests by Content-Type",
+		}, []string{"content_type"}),
+
+		HTTPRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_http_requests_total",
+			Help: "Total HTTP requests by endpoint path and status code",
+		}, []string{"path", "code"}),
+
+		HTTPRequestDuration: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "otlp_receiver_http_request_duration_seconds",
+			Help:    "Time spent serving HTTP requests, by endpoint path",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"path"}),
+
+		HTTPRequestBodySize: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "otlp_receiver_http_request_body_bytes",
+			Help:    "Size of HTTP request bodies as sent, by endpoint path",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8), // 256B to 4MB
+		}, []string{"path"}),
+
+		GRPCRequests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_grpc_requests_total",
+			Help: "Total gRPC requests by method and status code",
+		}, []string{"method", "code"}),
+
+		GRPCRequestDuration: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "otlp_receiver_grpc_request_duration_seconds",
+			Help:    "Time spent handling gRPC requests, by method",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+
+		GRPCMessageSize: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "otlp_receiver_grpc_request_message_bytes",
+			Help:    "Size of gRPC request messages, uncompressed, by method",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8), // 256B to 4MB
+		}, []string{"method"}),
+
+		OpenConnections: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_receiver_open_connections",
+			Help: "Client connections open to the HTTP and gRPC servers, by protocol",
+		}, []string{"protocol"}),
+
+		LogsByBodyType: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_logs_by_body_type_total",
+			Help: "Total log records by detected body type",
+		}, []string{"body_type"}),
+
+		LogsAggregated: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_logs_aggregated_total",
+			Help: "Total log records collected into aggregation rollups",
+		}, []string{"rule"}),
+
+		OutputDrift: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_receiver_output_drift",
+			Help: "Records counted as transformed minus records found in the output file, by index",
+		}, []string{"index"}),
+
+		ProtocolMismatches: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_protocol_mismatches_total",
+			Help: "Total connections or requests on the multiplexed port that used the wrong protocol, by kind",
+		}, []string{"kind"}),
+
+		SamplingRatio: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_receiver_sampling_ratio",
+			Help: "Fraction of each app's sampled records kept under the per-second budget",
+		}, []string{"app"}),
+
+		LogsSampledKept: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_logs_sampled_kept_total",
+			Help: "Total log records subject to sampling that were kept, by app",
+		}, []string{"app"}),
+
+		LogsSampledDropped: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_logs_sampled_dropped_total",
+			Help: "Total log records subject to sampling that were dropped, by app",
+		}, []string{"app"}),
+
+		LogsFanoutCopies: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "otlp_receiver_logs_fanout_copies_total",
+			Help: "Total extra copies of log records delivered to additional indexes by continue routing rules",
+		}),
+
+		RoutingRuleMatches: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_routing_rule_matches_total",
+			Help: "Total log records sent to an index by each routing rule",
+		}, []string{"rule"}),
+
+		RoutingDefault: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "otlp_receiver_routing_default_total",
+			Help: "Total log records that matched no final routing rule and went to the default index",
+		}),
+
+		RoutingOverflow: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_routing_overflow_total",
+			Help: "Total log records sent to an overflow index for exceeding a routing rule's rate limit",
+		}, []string{"rule"}),
+
+		RoutingDuration: promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:    "otlp_receiver_routing_duration_seconds",
+			Help:    "Time spent evaluating routing rules for each log record",
+			Buckets: prometheus.ExponentialBuckets(0.000001, 4, 8),
+		}),
+
+		LogsQuarantined: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_logs_quarantined_total",
+			Help: "Total log records sent to the quarantine index, by validation failure",
+		}, []string{"reason"}),
+
+		AllowlistFiltered: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_allowlist_filtered_total",
+			Help: "Total log records the allowlist filtered, or would have in report-only mode, by app and reason",
+		}, []string{"app", "reason"}),
+
+		AllowlistWatchMode: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_receiver_allowlist_watch_mode",
+			Help: "1 for how each allowlist or denylist file is watched for changes (fsnotify or poll), 0 otherwise",
+		}, []string{"path", "mode"}),
+
+		AllowlistReloads: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_allowlist_reloads_total",
+			Help: "Total allowlist or denylist reloads that changed the list in effect, by file or URL",
+		}, []string{"source"}),
+
+		AllowlistReloadErrors: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_allowlist_reload_errors_total",
+			Help: "Total allowlist or denylist reloads that failed, keeping the list in effect, by file or URL",
+		}, []string{"source"}),
+
+		OutputRotations: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_output_rotations_total",
+			Help: "Total output file rotations, by sink and trigger (size, interval, or template)",
+		}, []string{"sink", "trigger"}),
+
+		OutputFilesDeleted: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "otlp_receiver_output_files_deleted_total",
+			Help: "Total rotated output files deleted to keep -output-keep generations",
+		}),
+
+		OutputWriteErrors: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_output_write_errors_total",
+			Help: "Total output errors, by sink and operation (open, write, sync, rotate, marshal, or checkpoint for files, send for forwarding outputs)",
+		}, []string{"sink", "op"}),
+
+		OutputEntries: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_output_entries_written_total",
+			Help: "Total entries each sink wrote or delivered",
+		}, []string{"sink"}),
+
+		OutputBytes: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_output_bytes_written_total",
+			Help: "Total bytes each sink wrote or delivered",
+		}, []string{"sink"}),
+
+		OutputFlushes: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_output_flushes_total",
+			Help: "Total flushes, or batch sends, each sink attempted, including failed ones",
+		}, []string{"sink"}),
+
+		OutputFlushDuration: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "otlp_receiver_output_flush_duration_seconds",
+			Help:    "Time each sink spent on a flush, or batch send",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"sink"}),
+
+		OutputDiscarded: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "otlp_receiver_output_discarded_total",
+			Help: "Total records discarded because writes kept failing and the output buffer was full",
+		}),
+
+		OutputRecoveryLost: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "otlp_receiver_output_recovery_lost_total",
+			Help: "Total entries found lost to a flush a crash interrupted, when -output-checkpoint repaired the file on reopening it",
+		}),
+
+		OutputQueueDepth: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_receiver_output_queue_depth",
+			Help: "Records waiting in each sink's output queue",
+		}, []string{"sink"}),
+
+		OutputDropped: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_output_dropped_total",
+			Help: "Total records dropped from a full output queue under -output-queue-policy drop-oldest, or during shutdown, by sink",
+		}, []string{"sink"}),
+
+		OutputWorkerBusy: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_output_worker_busy_seconds_total",
+			Help: "Total seconds each sink's output queue worker spent writing; its rate is the worker's utilization",
+		}, []string{"sink"}),
+
+		OutputBatchPending: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_receiver_output_batch_pending",
+			Help: "Records held by each forwarding output for its next batches",
+		}, []string{"sink"}),
+
+		OutputBuffered: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_receiver_output_buffer_length",
+			Help: "Entries buffered by each output file, as configured, waiting to be flushed",
+		}, []string{"file"}),
+
+		HECEvents: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_hec_events_total",
+			Help: "Total events forwarded to Splunk HEC, by result (sent, failed, or discarded)",
+		}, []string{"result"}),
+		OTLPExportRecords: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_otlp_export_records_total",
+			Help: "Total records re-exported over OTLP, by result (sent, failed, or discarded)",
+		}, []string{"result"}),
+		ESDocuments: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_es_documents_total",
+			Help: "Total documents written to Elasticsearch or OpenSearch, by result (sent, failed, or discarded)",
+		}, []string{"result"}),
+		KafkaMessages: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_kafka_messages_total",
+			Help: "Total messages published to Kafka, by result (sent, failed, or discarded)",
+		}, []string{"result"}),
+		SQLiteRows: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_sqlite_rows_total",
+			Help: "Total records stored in the SQLite database, by result (sent, failed, or discarded)",
+		}, []string{"result"}),
+		StreamRecords: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_stream_records_total",
+			Help: "Total records written to the named pipe or Unix socket stream, by result (sent, failed, or discarded)",
+		}, []string{"result"}),
+		LiveTailClients: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "otlp_receiver_live_tail_clients",
+			Help: "Clients connected to the /stream live tail",
+		}),
+		LiveTailDropped: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "otlp_receiver_live_tail_dropped_total",
+			Help: "Total entries live-tail clients missed because they read too slowly",
+		}),
+		ArchiveFiles: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_receiver_archive_files_total",
+			Help: "Total rotated output files uploaded to object storage, by result (sent, failed, or discarded)",
+		}, []string{"result"}),
 	}
+	m.LogsByApp = NewAppVolume()
+	reg.MustRegister(m.LogsByApp)
 
 	return m
 }
@@ -83,3 +400,34 @@ func (m *Metrics) Registry() *prometheus.Registry {
 func (m *Metrics) NewTransformTimer() *prometheus.Timer {
 	return prometheus.NewTimer(m.TransformDuration)
 }
+
+// TransformedCounts returns the current transformed total, fan-out copies, and
+// per-index counts. Each record is counted once per index it was delivered to.
+func (m *Metrics) TransformedCounts() (total, copies int64, byIndex map[string]int64) {
+	byIndex = make(map[string]int64)
+	families, err := m.registry.Gather()
+	if err != nil {
+		return 0, 0, byIndex
+	}
+	for _, mf := range families {
+		switch mf.GetName() {
+		case "otlp_receiver_logs_transformed_total":
+			for _, metric := range mf.GetMetric() {
+				total += int64(metric.GetCounter().GetValue())
+			}
+		case "otlp_receiver_logs_fanout_copies_total":
+			for _, metric := range mf.GetMetric() {
+				copies += int64(metric.GetCounter().GetValue())
+			}
+		case "otlp_receiver_logs_by_index_total":
+			for _, metric := range mf.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "index" {
+						byIndex[label.GetValue()] += int64(metric.GetCounter().GetValue())
+					}
+				}
+			}
+		}
+	}
+	return total, copies, byIndex
+}
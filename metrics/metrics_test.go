@@ -120,3 +120,21 @@ func TestBodyTruncationsIncrement(t *testing.T) {
 		t.Errorf("BodyTruncations = %v, want 1", got)
 	}
 }
+
+func TestTransformedCounts(t *testing.T) {
+	m := New()
+	if total, copies, byIndex := m.TransformedCounts(); total != 0 || copies != 0 || len(byIndex) != 0 {
+		t.Errorf("fresh counts = %d %d %v, want none", total, copies, byIndex)
+	}
+
+	m.LogsTransformed.Add(3)
+	m.LogsByIndex.WithLabelValues("tas_logs").Add(2)
+	m.LogsByIndex.WithLabelValues("tas_errors").Inc()
+	m.LogsByIndex.WithLabelValues("tas_archive").Inc()
+	m.LogsFanoutCopies.Inc()
+
+	total, copies, byIndex := m.TransformedCounts()
+	if total != 3 || copies != 1 || byIndex["tas_logs"] != 2 || byIndex["tas_errors"] != 1 || byIndex["tas_archive"] != 1 {
+		t.Errorf("counts = %d %d %v, want 3 1 {tas_logs:2 tas_errors:1 tas_archive:1}", total, copies, byIndex)
+	}
+}
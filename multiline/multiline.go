@@ -0,0 +1,129 @@
+// ABOUTME: Multiline assembler that stitches continuation lines into the preceding record.
+// ABOUTME: Buffers one record per app instance until a new start line arrives or the window expires.
+
+package multiline
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Record is a log record with the resource and scope it arrived under
+type Record struct {
+	Resource *resourcepb.Resource
+	Scope    *commonpb.InstrumentationScope
+	Log      *logspb.LogRecord
+	Lines    int // Number of original records stitched into this one
+}
+
+// pending is the record being assembled for one app instance
+type pending struct {
+	rec     Record
+	body    []byte
+	updated time.Time
+}
+
+// Assembler merges records whose body does not match Start into the previous
+// record from the same key, as long as they arrive within Window of each other
+type Assembler struct {
+	Start  *regexp.Regexp
+	Window time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pending
+}
+
+// New creates an assembler for the given start-line pattern and window
+func New(start *regexp.Regexp, window time.Duration) *Assembler {
+	return &Assembler{
+		Start:   start,
+		Window:  window,
+		pending: make(map[string]*pending),
+	}
+}
+
+// Add buffers a record under key. Returns records that are complete and ready
+// for the pipeline: the previous record for key once a new start line arrives.
+func (a *Assembler) Add(key string, rec Record) []Record {
+	return a.add(key, rec, time.Now())
+}
+
+func (a *Assembler) add(key string, rec Record, now time.Time) []Record {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	body, isString := rec.Log.GetBody().GetValue().(*commonpb.AnyValue_StringValue)
+	p := a.pending[key]
+
+	// Continuation: append to the record being assembled
+	if p != nil && isString && !a.Start.MatchString(body.StringValue) && now.Sub(p.updated) <= a.Window {
+		p.body = append(append(p.body, '\n'), body.StringValue...)
+		p.rec.Lines++
+		p.updated = now
+		return nil
+	}
+
+	var ready []Record
+	if p != nil {
+		ready = append(ready, p.finish())
+	}
+	next := &pending{rec: rec, updated: now}
+	next.rec.Lines = 1
+	if isString {
+		next.body = []byte(body.StringValue)
+	}
+	a.pending[key] = next
+	return ready
+}
+
+// Expired returns and removes records that have not been extended within the window
+func (a *Assembler) Expired() []Record {
+	return a.expired(time.Now())
+}
+
+func (a *Assembler) expired(now time.Time) []Record {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.drain(func(p *pending) bool { return now.Sub(p.updated) > a.Window })
+}
+
+// Flush returns and removes every buffered record, e.g. at shutdown
+func (a *Assembler) Flush() []Record {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.drain(func(*pending) bool { return true })
+}
+
+// drain removes pending records selected by done, oldest first. Caller holds the lock.
+func (a *Assembler) drain(done func(*pending) bool) []Record {
+	var finished []*pending
+	for key, p := range a.pending {
+		if done(p) {
+			finished = append(finished, p)
+			delete(a.pending, key)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].updated.Before(finished[j].updated) })
+
+	ready := make([]Record, 0, len(finished))
+	for _, p := range finished {
+		ready = append(ready, p.finish())
+	}
+	return ready
+}
+
+// finish writes the assembled body back to the record
+func (p *pending) finish() Record {
+	if p.rec.Lines > 1 {
+		p.rec.Log.Body = &commonpb.AnyValue{
+			Value: &commonpb.AnyValue_StringValue{StringValue: string(p.body)},
+		}
+	}
+	return p.rec
+}
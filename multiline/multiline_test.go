@@ -0,0 +1,104 @@
+// ABOUTME: Tests for the multiline assembler.
+// ABOUTME: Covers stitching, per-key isolation, window expiry, and flushing.
+
+package multiline
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+var timestampStart = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+
+func line(body string) Record {
+	return Record{Log: &logspb.LogRecord{
+		Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}},
+	}}
+}
+
+func TestAssembler_StitchesStackTrace(t *testing.T) {
+	a := New(timestampStart, time.Second)
+	now := time.Now()
+
+	inputs := []string{
+		"2024-01-15 10:30:00 ERROR request failed",
+		"java.lang.IllegalStateException: boom",
+		"\tat com.example.Service.handle(Service.java:42)",
+		"Caused by: java.io.IOException: closed",
+		"2024-01-15 10:30:01 INFO next request",
+	}
+	var ready []Record
+	for i, body := range inputs {
+		ready = append(ready, a.add("app/0", line(body), now.Add(time.Duration(i)*time.Millisecond))...)
+	}
+
+	if len(ready) != 1 {
+		t.Fatalf("expected 1 completed record, got %d", len(ready))
+	}
+	if ready[0].Lines != 4 {
+		t.Errorf("Lines = %d, want 4", ready[0].Lines)
+	}
+	want := "2024-01-15 10:30:00 ERROR request failed\njava.lang.IllegalStateException: boom\n\tat com.example.Service.handle(Service.java:42)\nCaused by: java.io.IOException: closed"
+	if got := ready[0].Log.GetBody().GetStringValue(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	rest := a.Flush()
+	if len(rest) != 1 || rest[0].Log.GetBody().GetStringValue() != "2024-01-15 10:30:01 INFO next request" {
+		t.Errorf("Flush returned %v", rest)
+	}
+}
+
+func TestAssembler_KeysAreIsolated(t *testing.T) {
+	a := New(timestampStart, time.Second)
+	now := time.Now()
+
+	a.add("app/0", line("2024-01-15 10:30:00 ERROR instance zero"), now)
+	a.add("app/1", line("\tat continuation from instance one"), now)
+
+	for _, rec := range a.Flush() {
+		if rec.Lines != 1 {
+			t.Errorf("record %q should not be stitched", rec.Log.GetBody().GetStringValue())
+		}
+	}
+}
+
+func TestAssembler_WindowExpiry(t *testing.T) {
+	a := New(timestampStart, 100*time.Millisecond)
+	now := time.Now()
+
+	a.add("app/0", line("2024-01-15 10:30:00 ERROR failed"), now)
+	if got := a.expired(now.Add(50 * time.Millisecond)); len(got) != 0 {
+		t.Fatalf("record expired early: %v", got)
+	}
+
+	// A continuation arriving after the window starts a new record
+	ready := a.add("app/0", line("\tat late.line"), now.Add(200*time.Millisecond))
+	if len(ready) != 1 || ready[0].Lines != 1 {
+		t.Errorf("late continuation should not be stitched, got %v", ready)
+	}
+
+	if got := a.expired(now.Add(400 * time.Millisecond)); len(got) != 1 {
+		t.Errorf("expected 1 expired record, got %d", len(got))
+	}
+	if got := a.Flush(); len(got) != 0 {
+		t.Errorf("expected nothing left to flush, got %d", len(got))
+	}
+}
+
+func TestAssembler_NonStringBodyNeverStitched(t *testing.T) {
+	a := New(timestampStart, time.Second)
+	now := time.Now()
+
+	a.add("app/0", line("2024-01-15 10:30:00 INFO start"), now)
+	kv := Record{Log: &logspb.LogRecord{Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 7}}}}
+	ready := a.add("app/0", kv, now)
+
+	if len(ready) != 1 || ready[0].Lines != 1 {
+		t.Errorf("non-string body should start a new record, got %v", ready)
+	}
+}
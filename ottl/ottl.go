@@ -0,0 +1,126 @@
+// ABOUTME: Runs OTTL statements with the collector's pkg/ottl in the log context and its standard functions.
+// ABOUTME: Converts a record and its resource to pdata and back, copying the shared resource on write.
+
+package ottl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	contribottl "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/ottlfuncs"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// Context is the log record, and the resource it belongs to, that statements run against
+type Context struct {
+	Resource *resourcepb.Resource // Replaced by a copy on the first write
+	Log      *logspb.LogRecord
+
+	resourceCopied bool
+}
+
+// ResourceCopied reports whether a statement wrote to the resource
+func (ctx *Context) ResourceCopied() bool {
+	return ctx.resourceCopied
+}
+
+// Statement is a parsed statement, as the collector's transform processor would run it
+type Statement struct {
+	Text string
+
+	stmt *contribottl.Statement[ottllog.TransformContext]
+}
+
+// parser is built once: the function table is the same for every statement
+var parser = sync.OnceValues(func() (contribottl.Parser[ottllog.TransformContext], error) {
+	return ottllog.NewParser(ottlfuncs.StandardFuncs[ottllog.TransformContext](), component.TelemetrySettings{Logger: zap.NewNop()})
+})
+
+// Parse parses a log context statement, rejecting unknown functions and paths
+// and syntax errors with the collector's own messages
+func Parse(text string) (*Statement, error) {
+	p, err := parser()
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := p.ParseStatement(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Statement{Text: text, stmt: stmt}, nil
+}
+
+// Execute runs the statement if its condition holds. Returns whether it ran.
+func (s *Statement) Execute(ctx *Context) (bool, error) {
+	logs, err := toLogs(ctx)
+	if err != nil {
+		return false, err
+	}
+	rl := logs.ResourceLogs().At(0)
+	sl := rl.ScopeLogs().At(0)
+	tCtx := ottllog.NewTransformContext(sl.LogRecords().At(0), sl.Scope(), rl.Resource(), sl, rl)
+	_, ran, err := s.stmt.Execute(context.Background(), tCtx)
+	if err != nil || !ran {
+		return false, err
+	}
+	return true, ctx.update(logs)
+}
+
+// toLogs converts the context's record and resource to pdata
+func toLogs(ctx *Context) (plog.Logs, error) {
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		Resource:  ctx.Resource,
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{ctx.Log}}},
+	}}}
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return plog.Logs{}, fmt.Errorf("encoding record: %w", err)
+	}
+	pr := plogotlp.NewExportRequest()
+	if err := pr.UnmarshalProto(data); err != nil {
+		return plog.Logs{}, fmt.Errorf("converting record: %w", err)
+	}
+	return pr.Logs(), nil
+}
+
+// update copies what a statement did back: the record in place, and the
+// resource as a new copy if it changed, so the shared one is never written
+func (ctx *Context) update(logs plog.Logs) error {
+	data, err := plogotlp.NewExportRequestFromLogs(logs).MarshalProto()
+	if err != nil {
+		return fmt.Errorf("converting record: %w", err)
+	}
+	var req collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("decoding record: %w", err)
+	}
+	rl := req.GetResourceLogs()[0]
+	lr := rl.GetScopeLogs()[0].GetLogRecords()[0]
+
+	// pdata always has a body; keep a missing one missing
+	if ctx.Log.Body == nil && lr.GetBody().GetValue() == nil {
+		lr.Body = nil
+	}
+	proto.Reset(ctx.Log)
+	proto.Merge(ctx.Log, lr)
+
+	before := ctx.Resource
+	if before == nil {
+		before = &resourcepb.Resource{}
+	}
+	if !proto.Equal(before, rl.GetResource()) {
+		ctx.Resource = rl.GetResource()
+		ctx.resourceCopied = true
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// ABOUTME: Tests for executing OTTL statements against log records.
+// ABOUTME: Covers editors, converters, where clauses, parse errors, and copy-on-write of the shared resource.
+
+package ottl
+
+import (
+	"bytes"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func kv(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func newContext(body string, attrs ...*commonpb.KeyValue) *Context {
+	return &Context{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{kv("space_name", "production"), kv("org", "acme")}},
+		Log: &logspb.LogRecord{
+			Body:       &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}},
+			Attributes: attrs,
+		},
+	}
+}
+
+func run(t *testing.T, statement string, ctx *Context) bool {
+	t.Helper()
+	stmt, err := Parse(statement)
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", statement, err)
+	}
+	ran, err := stmt.Execute(ctx)
+	if err != nil {
+		t.Fatalf("Execute(%s): %v", statement, err)
+	}
+	return ran
+}
+
+func attr(kvs []*commonpb.KeyValue, key string) *commonpb.AnyValue {
+	for _, kv := range kvs {
+		if kv.GetKey() == key {
+			return kv.GetValue()
+		}
+	}
+	return nil
+}
+
+func TestExecute_SetWhereResource(t *testing.T) {
+	ctx := newContext("hello")
+	if !run(t, `set(attributes["env"], "prod") where resource.attributes["space_name"] == "production"`, ctx) {
+		t.Fatal("condition should hold")
+	}
+	if got := attr(ctx.Log.Attributes, "env").GetStringValue(); got != "prod" {
+		t.Errorf("env = %q, want prod", got)
+	}
+
+	ctx = newContext("hello")
+	if run(t, `set(attributes["env"], "prod") where resource.attributes["space_name"] == "dev"`, ctx) {
+		t.Error("condition should not hold")
+	}
+	if attr(ctx.Log.Attributes, "env") != nil {
+		t.Error("env should not be set")
+	}
+}
+
+func TestExecute_TypedValues(t *testing.T) {
+	ctx := newContext("WARN disk almost full", kv("status", "503"))
+	run(t, `set(severity_number, SEVERITY_NUMBER_WARN) where IsMatch(body, "^WARN")`, ctx)
+	run(t, `set(attributes["code"], Int(attributes["status"]))`, ctx)
+	run(t, `set(attributes["ratio"], 0.5) where attributes["code"] >= 500`, ctx)
+
+	if ctx.Log.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_WARN {
+		t.Errorf("severity_number = %v, want WARN", ctx.Log.SeverityNumber)
+	}
+	if got := attr(ctx.Log.Attributes, "code").GetIntValue(); got != 503 {
+		t.Errorf("code = %d, want int 503", got)
+	}
+	if got := attr(ctx.Log.Attributes, "ratio").GetDoubleValue(); got != 0.5 {
+		t.Errorf("ratio = %v, want 0.5", got)
+	}
+}
+
+func TestExecute_ResourceCopyOnWrite(t *testing.T) {
+	ctx := newContext("hello")
+	shared := ctx.Resource
+
+	run(t, `delete_key(resource.attributes, "missing")`, ctx)
+	if ctx.ResourceCopied() {
+		t.Error("a no-op delete should not copy the resource")
+	}
+
+	run(t, `set(resource.attributes["space_name"], "prod")`, ctx)
+	if !ctx.ResourceCopied() || ctx.Resource == shared {
+		t.Fatal("writing the resource should copy it")
+	}
+	if got := attr(shared.Attributes, "space_name").GetStringValue(); got != "production" {
+		t.Errorf("shared resource modified: space_name = %q", got)
+	}
+	if got := attr(ctx.Resource.Attributes, "space_name").GetStringValue(); got != "prod" {
+		t.Errorf("copy space_name = %q, want prod", got)
+	}
+}
+
+func TestExecute_MapEditors(t *testing.T) {
+	ctx := newContext("hello", kv("password", "x"), kv("k8s.pod", "p"), kv("k8s.ns", "n"), kv("app", "web"), kv("level", "info"))
+
+	run(t, `delete_key(attributes, "password")`, ctx)
+	run(t, `delete_matching_keys(attributes, "^k8s\\.")`, ctx)
+	if len(ctx.Log.Attributes) != 2 {
+		t.Fatalf("attributes = %v, want app and level", ctx.Log.Attributes)
+	}
+	run(t, `keep_keys(attributes, ["app"])`, ctx)
+	if len(ctx.Log.Attributes) != 1 || attr(ctx.Log.Attributes, "app") == nil {
+		t.Errorf("attributes = %v, want only app", ctx.Log.Attributes)
+	}
+	run(t, `truncate_all(attributes, 2)`, ctx)
+	if got := attr(ctx.Log.Attributes, "app").GetStringValue(); got != "we" {
+		t.Errorf("app = %q, want we", got)
+	}
+}
+
+func TestExecute_ReplacePatternAndConcat(t *testing.T) {
+	ctx := newContext("login token=abc123 ok", kv("name", "Web"))
+
+	run(t, `replace_pattern(body, "token=\\S+", "token=***")`, ctx)
+	if got := ctx.Log.GetBody().GetStringValue(); got != "login token=*** ok" {
+		t.Errorf("body = %q", got)
+	}
+	run(t, `set(attributes["id"], Concat([resource.attributes["org"], ToLowerCase(attributes["name"])], "/"))`, ctx)
+	if got := attr(ctx.Log.Attributes, "id").GetStringValue(); got != "acme/web" {
+		t.Errorf("id = %q, want acme/web", got)
+	}
+}
+
+func TestExecute_BooleanLogic(t *testing.T) {
+	tests := map[string]bool{
+		`set(attributes["x"], "y") where body == "a" or body == "hello"`:                 true,
+		`set(attributes["x"], "y") where body == "hello" and attributes["env"] != nil`:   false,
+		`set(attributes["x"], "y") where not (body == "a" or attributes["env"] != nil)`:  true,
+		`set(attributes["x"], "y") where IsMatch(attributes["missing"], ".*")`:           false,
+		`set(attributes["x"], "y") where Len(body) > 3 and severity_text == ""`:          true,
+		`set(attributes["x"], "y") where severity_number == SEVERITY_NUMBER_UNSPECIFIED`: true,
+	}
+	for statement, want := range tests {
+		if got := run(t, statement, newContext("hello")); got != want {
+			t.Errorf("%s ran = %v, want %v", statement, got, want)
+		}
+	}
+}
+
+func TestExecute_RuntimeError(t *testing.T) {
+	stmt, err := Parse(`set(attributes["parsed"], ParseJSON(body))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.Execute(newContext("not json")); err == nil {
+		t.Error("parsing a non-JSON body should fail")
+	}
+}
+
+func TestExecute_KeepsOtherFields(t *testing.T) {
+	ctx := newContext("hello")
+	ctx.Log.Body = nil
+	ctx.Log.TimeUnixNano = 1700000000000000000
+	ctx.Log.TraceId = bytes.Repeat([]byte{1}, 16)
+
+	run(t, `set(attributes["env"], "prod")`, ctx)
+	if ctx.Log.Body != nil {
+		t.Errorf("body = %v, want it to stay missing", ctx.Log.Body)
+	}
+	if ctx.Log.TimeUnixNano != 1700000000000000000 || len(ctx.Log.TraceId) != 16 {
+		t.Errorf("record = %v, want time and trace ID kept", ctx.Log)
+	}
+	if ctx.ResourceCopied() {
+		t.Error("a record write should not copy the resource")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	statements := []string{
+		`set(attributes["a"], Foo(body))`,
+		`not_an_editor(attributes, "x")`,
+		`set(span.name, "x")`,
+		`set(attributes["a"], "b") where`,
+		`set(attributes["a"], "b") where body = "x"`,
+		`set(attributes["a"], "b"`,
+		`IsMatch(body, "x")`,
+	}
+	for _, s := range statements {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%s) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+// ABOUTME: Archives rotated output files to an S3-compatible bucket: AWS S3, MinIO, or GCS interoperability.
+// ABOUTME: Lays out object keys by date and file, optionally splitting each file by routed index; requests are SigV4 signed.
+
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// ArchiveConfig configures uploading rotated output files to object storage
+type ArchiveConfig struct {
+	Endpoint          string // Defaults to AWS S3 in Region; https://storage.googleapis.com for GCS, http://localhost:9000 for MinIO
+	Bucket            string
+	Region            string // Defaults to us-east-1
+	KeyPattern        string // Object key; see ArchiveKey
+	AccessKey         string // An AWS access key, or a GCS HMAC key
+	SecretKey         string
+	SessionToken      string       // For temporary AWS credentials
+	MaxRetries        int          // Attempts after the first before a file is given up on
+	SkipSSLValidation bool         // For labs with self-signed certificates
+	Client            *http.Client // Defaults to a client with a 5 minute timeout
+	Now               func() time.Time
+}
+
+// DefaultArchiveKey files each upload under its day, named for the time it
+// was last written, as size rotation reuses file names
+const DefaultArchiveKey = "%Y/%m/%d/%H%M%S-{file}"
+
+// unroutedIndex names the index of records without one when splitting by index
+const unroutedIndex = "unrouted"
+
+// Archiver uploads finished output files to an S3-compatible bucket from a
+// background goroutine, retrying failed uploads with backoff
+type Archiver struct {
+	*batcher[string]
+	cfg    ArchiveConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	metrics *metrics.Metrics
+}
+
+// NewArchiver validates cfg and starts uploading
+func NewArchiver(cfg ArchiveConfig) (*Archiver, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("archive bucket is required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("archive credentials are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	if u, err := url.Parse(cfg.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("archive endpoint %q must be an http or https URL", cfg.Endpoint)
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	if cfg.KeyPattern == "" {
+		cfg.KeyPattern = DefaultArchiveKey
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+		if cfg.SkipSSLValidation {
+			client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+		}
+	}
+
+	a := &Archiver{cfg: cfg, client: client}
+	a.batcher = newBatcher("Archive", 1, time.Minute, cfg.MaxRetries, a.send, a.count)
+	return a, nil
+}
+
+// ArchiveKey names the object for a file last written at t: date fields
+// are expanded as in ExpandPath, in UTC, {file} is the file's name, and
+// {index} the routed index of the records it holds
+func ArchiveKey(pattern, file, index string, t time.Time) string {
+	key := ExpandPath(pattern, t.UTC())
+	return strings.NewReplacer("{file}", file, "{index}", index).Replace(key)
+}
+
+// SetMetrics counts uploaded, failed, and discarded files in m, and reports those waiting to be uploaded
+func (a *Archiver) SetMetrics(m *metrics.Metrics) {
+	a.batcher.setMetrics(m, "archive")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.metrics = m
+}
+
+// Add queues a finished file for upload. Up to 100 files wait while the
+// bucket is unreachable, discarding the oldest beyond that.
+func (a *Archiver) Add(path string) {
+	a.add(path)
+}
+
+// Close uploads what is queued, with at most one attempt per file, and stops uploading
+func (a *Archiver) Close() error {
+	return a.close()
+}
+
+// send uploads one file: whole, or as one object per routed index if the
+// key pattern has {index}
+func (a *Archiver) send(batch []string) error {
+	size := 0
+	for _, path := range batch {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err // Pruned or shifted before its turn came
+		}
+		file := filepath.Base(path)
+		if !strings.Contains(a.cfg.KeyPattern, "{index}") {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err := a.put(ArchiveKey(a.cfg.KeyPattern, file, "", info.ModTime()), data, strings.HasSuffix(path, ".gz")); err != nil {
+				return err
+			}
+			size += len(data)
+			continue
+		}
+
+		parts, err := splitByIndex(path)
+		if err != nil {
+			return fmt.Errorf("splitting %s by index: %w", path, err)
+		}
+		for _, index := range sortedKeys(parts) {
+			if err := a.put(ArchiveKey(a.cfg.KeyPattern, file, index, info.ModTime()), parts[index], strings.HasSuffix(path, ".gz")); err != nil {
+				return err
+			}
+			size += len(parts[index])
+		}
+	}
+	a.count(resultSent, len(batch))
+	a.sent(len(batch), size)
+	return nil
+}
+
+// splitByIndex groups a JSONL output file's lines by routed index,
+// gzipping each group if the file is gzipped
+func splitByIndex(path string) (map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	zipped := strings.HasSuffix(path, ".gz")
+	if zipped {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	}
+
+	groups := make(map[string]*bytes.Buffer)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry struct {
+			Routing RoutingInfo `json:"routing"`
+		}
+		json.Unmarshal(line, &entry)
+		index := entry.Routing.Index
+		if index == "" {
+			index = unroutedIndex
+		}
+		if groups[index] == nil {
+			groups[index] = &bytes.Buffer{}
+		}
+		groups[index].Write(line)
+		groups[index].WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	parts := make(map[string][]byte, len(groups))
+	for index, buf := range groups {
+		if !zipped {
+			parts[index] = buf.Bytes()
+			continue
+		}
+		var zbuf bytes.Buffer
+		zw := gzip.NewWriter(&zbuf)
+		zw.Write(buf.Bytes())
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		parts[index] = zbuf.Bytes()
+	}
+	return parts, nil
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// put uploads one object with a signed PUT
+func (a *Archiver) put(key string, data []byte, zipped bool) error {
+	target := a.cfg.Endpoint + "/" + s3Escape(a.cfg.Bucket) + "/" + s3Escape(key)
+	req, err := http.NewRequest(http.MethodPut, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if zipped {
+		req.Header.Set("Content-Type", "application/gzip")
+	} else {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+	if a.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.cfg.SessionToken)
+	}
+	signV4(req, data, a.cfg.AccessKey, a.cfg.SecretKey, a.cfg.Region, a.cfg.Now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return retryableError{err: fmt.Errorf("put %s: %s", key, resp.Status)}
+	default:
+		return fmt.Errorf("put %s: %s: %s", key, resp.Status, s3ErrorCode(reply))
+	}
+}
+
+// s3ErrorCode pulls the code out of an S3 XML error reply, such as
+// SignatureDoesNotMatch or NoSuchBucket
+func s3ErrorCode(reply []byte) string {
+	_, rest, ok := bytes.Cut(reply, []byte("<Code>"))
+	if !ok {
+		return string(bytes.TrimSpace(reply))
+	}
+	code, _, _ := bytes.Cut(rest, []byte("</Code>"))
+	return string(code)
+}
+
+// signV4 signs an S3 request with AWS Signature Version 4, covering the host
+// and every header already set
+func signV4(req *http.Request, payload []byte, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	stamp, day := now.Format("20060102T150405Z"), now.Format("20060102")
+	sum := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := sortedKeys(headers)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	for _, part := range []string{region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as SigV4 requires
+func canonicalQuery(query url.Values) string {
+	var parts []string
+	for _, name := range sortedKeys(query) {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, queryEscape(name)+"="+queryEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters and slashes,
+// as S3 expects in object keys
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// queryEscape percent-encodes a query parameter as SigV4 requires, slashes included
+func queryEscape(s string) string {
+	return strings.ReplaceAll(s3Escape(s), "/", "%2F")
+}
+
+// count adds n files to the result's metric
+func (a *Archiver) count(result string, n int) {
+	a.mu.Lock()
+	m := a.metrics
+	a.mu.Unlock()
+	if m != nil && n > 0 {
+		m.ArchiveFiles.WithLabelValues(result).Add(float64(n))
+	}
+}
@@ -0,0 +1,251 @@
+// ABOUTME: Background batching with retry and backoff for outputs that forward records over the network.
+// ABOUTME: Holds a bounded queue, sends full batches at once and partial ones on an interval.
+
+package output
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// Forwarding results, as counted by the forwarding outputs' metrics
+const (
+	resultSent      = "sent"
+	resultFailed    = "failed"
+	resultDiscarded = "discarded"
+)
+
+// Backoff between attempts to send a batch, doubling up to the maximum
+const (
+	sendBackoff    = time.Second
+	maxSendBackoff = 30 * time.Second
+)
+
+// retryableError is a failure worth retrying: a network error, a throttle,
+// or a server error. If only some of a batch failed, send moves those items
+// to the front of the batch and sets retry to their count.
+type retryableError struct {
+	err   error
+	retry int
+}
+
+func (e retryableError) Error() string { return e.err.Error() }
+
+// batcher queues items and sends them in batches from a background
+// goroutine, so a slow or unreachable destination never holds up the receiver
+type batcher[T any] struct {
+	name       string // Log prefix, e.g. HEC
+	size       int
+	interval   time.Duration
+	maxRetries int
+	backoff    time.Duration // First delay between retries
+	send       func([]T) error
+	count      func(result string, n int)
+
+	mu       sync.Mutex
+	buffer   []T
+	metrics  *metrics.Metrics
+	sink     string        // Label for otlp_receiver_output_batch_pending
+	reported int           // Buffer length last added to the gauge
+	sending  sync.Mutex    // Held while sending, so a flush waits its turn
+	kick     chan struct{} // Asks the sender to send a full batch now
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// newBatcher starts sending batches of size items at least every interval
+func newBatcher[T any](name string, size int, interval time.Duration, maxRetries int, send func([]T) error, count func(string, int)) *batcher[T] {
+	b := &batcher[T]{
+		name:       name,
+		size:       size,
+		interval:   interval,
+		maxRetries: maxRetries,
+		backoff:    sendBackoff,
+		send:       send,
+		count:      count,
+		kick:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go b.sendLoop()
+	return b
+}
+
+// add queues an item for the next batch. While the destination is
+// unreachable, up to 100 batches are held, discarding the oldest items beyond that.
+func (b *batcher[T]) add(item T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.buffer) >= 100*b.size {
+		b.buffer = b.buffer[1:]
+		b.count(resultDiscarded, 1)
+	}
+	b.buffer = append(b.buffer, item)
+	b.reportPending()
+	if len(b.buffer) >= b.size {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flush sends what is queued now, with at most one attempt per batch,
+// after any batch the sender is retrying
+func (b *batcher[T]) flush() error {
+	b.sending.Lock()
+	defer b.sending.Unlock()
+	return b.drain()
+}
+
+// close sends what is queued, with at most one attempt per batch, and stops sending
+func (b *batcher[T]) close() error {
+	close(b.stop)
+	<-b.done
+	return b.drain()
+}
+
+// drain sends each queued batch once, counting failures, and returns the last error
+func (b *batcher[T]) drain() error {
+	var err error
+	for {
+		batch := b.take()
+		if len(batch) == 0 {
+			return err
+		}
+		if sendErr := b.attempt(batch); sendErr != nil {
+			failed := len(batch)
+			if retry, ok := sendErr.(retryableError); ok && retry.retry > 0 {
+				failed = retry.retry
+			}
+			b.count(resultFailed, failed)
+			err = sendErr
+		}
+	}
+}
+
+// sendLoop sends a batch whenever one fills or the interval passes
+func (b *batcher[T]) sendLoop() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		case <-b.kick:
+		}
+		b.sending.Lock()
+		for batch := b.take(); len(batch) > 0; batch = b.take() {
+			b.sendWithRetry(batch)
+			if len(batch) < b.size {
+				break
+			}
+		}
+		b.sending.Unlock()
+	}
+}
+
+// take removes and returns up to a batch of queued items
+func (b *batcher[T]) take() []T {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := min(len(b.buffer), b.size)
+	batch := b.buffer[:n:n]
+	b.buffer = b.buffer[n:]
+	b.reportPending()
+	return batch
+}
+
+// attempt sends a batch once, counting and timing it as a flush
+func (b *batcher[T]) attempt(batch []T) error {
+	start := time.Now()
+	err := b.send(batch)
+	if m, sink := b.output(); m != nil {
+		m.OutputFlushes.WithLabelValues(sink).Inc()
+		m.OutputFlushDuration.WithLabelValues(sink).Observe(time.Since(start).Seconds())
+		if err != nil {
+			m.OutputWriteErrors.WithLabelValues(sink, opSend).Inc()
+		}
+	}
+	return err
+}
+
+// sent counts entries a send delivered and the bytes it took, for send to call
+func (b *batcher[T]) sent(entries, bytes int) {
+	if m, sink := b.output(); m != nil {
+		m.OutputEntries.WithLabelValues(sink).Add(float64(entries))
+		m.OutputBytes.WithLabelValues(sink).Add(float64(bytes))
+	}
+}
+
+// output returns the metrics set by setMetrics and the sink label
+func (b *batcher[T]) output() (*metrics.Metrics, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.metrics, b.sink
+}
+
+// setMetrics reports the queued items, sends, and errors in m, labelled with sink
+func (b *batcher[T]) setMetrics(m *metrics.Metrics, sink string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.metrics, b.sink = m, sink
+	b.reported = 0
+	b.reportPending()
+}
+
+// reportPending moves the pending gauge by the change in queued items since
+// it was last reported, so outputs sharing a label add up. Caller must hold mu.
+func (b *batcher[T]) reportPending() {
+	if b.metrics == nil {
+		return
+	}
+	b.metrics.OutputBatchPending.WithLabelValues(b.sink).Add(float64(len(b.buffer) - b.reported))
+	b.reported = len(b.buffer)
+}
+
+// sendWithRetry sends a batch, retrying retryable failures with backoff
+// until maxRetries is used up or the batcher is closed
+func (b *batcher[T]) sendWithRetry(batch []T) {
+	delay := b.backoff
+	for attempt := 0; ; attempt++ {
+		err := b.attempt(batch)
+		if err == nil {
+			return
+		}
+		retry, ok := err.(retryableError)
+		if !ok || attempt >= b.maxRetries || b.stopped() {
+			slog.Error("Output: dropping a batch", "output", b.name, "records", len(batch), "err", err)
+			b.count(resultFailed, len(batch))
+			return
+		}
+		if retry.retry > 0 {
+			batch = batch[:retry.retry]
+		}
+		slog.Warn("Output: send failed, retrying", "output", b.name, "err", retry.err, "delay", delay)
+		select {
+		case <-time.After(delay):
+		case <-b.stop:
+			// close makes one more attempt
+		}
+		delay = min(2*delay, maxSendBackoff)
+	}
+}
+
+// stopped reports whether close has been called
+func (b *batcher[T]) stopped() bool {
+	select {
+	case <-b.stop:
+		return true
+	default:
+		return false
+	}
+}
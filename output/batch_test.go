@@ -0,0 +1,153 @@
+// ABOUTME: Tests for the batcher shared by the forwarding outputs.
+// ABOUTME: Covers sending full batches early, flushing on demand, discarding the oldest items when the queue is full, and the batcher's metrics.
+
+package output
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-mock-receiver/metrics"
+)
+
+func TestBatcher_DiscardsOldestWhenFull(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		sent      []int
+		discarded int
+	)
+	b := newBatcher("test", 1, time.Hour, 0, func(batch []int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, batch...)
+		return nil
+	}, func(result string, n int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if result == resultDiscarded {
+			discarded += n
+		}
+	})
+	// Hold the queue, as if the sender were stuck on an unreachable destination
+	b.mu.Lock()
+	b.buffer = make([]int, 0, 101)
+	for i := range 100 {
+		b.buffer = append(b.buffer, i)
+	}
+	b.mu.Unlock()
+
+	b.add(100)
+	if err := b.close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if discarded != 1 || len(sent) != 100 || sent[0] != 1 || !slices.Contains(sent, 100) {
+		t.Errorf("discarded %d and sent %d starting at %v, want the oldest discarded", discarded, len(sent), sent[:1])
+	}
+}
+
+func TestBatcher_SendsFullBatchesEarly(t *testing.T) {
+	batches := make(chan []string, 2)
+	b := newBatcher("test", 2, time.Hour, 0, func(batch []string) error {
+		batches <- batch
+		return nil
+	}, func(string, int) {})
+	defer b.close()
+
+	b.add("a")
+	b.add("b")
+	select {
+	case batch := <-batches:
+		if !slices.Equal(batch, []string{"a", "b"}) {
+			t.Errorf("batch = %v, want [a b]", batch)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a full batch was not sent before the interval")
+	}
+}
+
+func TestBatcher_FlushSendsPartialBatch(t *testing.T) {
+	var sent []string
+	b := newBatcher("test", 10, time.Hour, 0, func(batch []string) error {
+		sent = append(sent, batch...)
+		return nil
+	}, func(string, int) {})
+	defer b.close()
+
+	b.add("a")
+	b.add("b")
+	if err := b.flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if !slices.Equal(sent, []string{"a", "b"}) {
+		t.Errorf("sent %v after flush, want [a b]", sent)
+	}
+}
+
+func TestBatcher_ReportsPending(t *testing.T) {
+	b := newBatcher("test", 10, time.Hour, 0, func([]string) error { return nil }, func(string, int) {})
+	defer b.close()
+	b.add("a")
+	m := metrics.New()
+	b.setMetrics(m, "test")
+
+	b.add("b")
+	b.add("c")
+	if got := testutil.ToFloat64(m.OutputBatchPending.WithLabelValues("test")); got != 3 {
+		t.Errorf("pending = %v, want 3", got)
+	}
+	if err := b.flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if got := testutil.ToFloat64(m.OutputBatchPending.WithLabelValues("test")); got != 0 {
+		t.Errorf("pending after flush = %v, want 0", got)
+	}
+}
+
+func TestBatcher_CountsSends(t *testing.T) {
+	var b *batcher[string]
+	fail := true
+	b = newBatcher("test", 10, time.Hour, 0, func(batch []string) error {
+		if fail {
+			return errors.New("refused")
+		}
+		b.sent(len(batch), 5*len(batch))
+		return nil
+	}, func(string, int) {})
+	defer b.close()
+	m := metrics.New()
+	b.setMetrics(m, "test")
+
+	b.add("a")
+	if err := b.flush(); err == nil {
+		t.Fatal("flush should report the failed send")
+	}
+	fail = false
+	b.add("b")
+	b.add("c")
+	if err := b.flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	if got := testutil.ToFloat64(m.OutputFlushes.WithLabelValues("test")); got != 2 {
+		t.Errorf("flushes = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.OutputWriteErrors.WithLabelValues("test", opSend)); got != 1 {
+		t.Errorf("send errors = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.OutputEntries.WithLabelValues("test")); got != 2 {
+		t.Errorf("entries written = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.OutputBytes.WithLabelValues("test")); got != 10 {
+		t.Errorf("bytes written = %v, want 10", got)
+	}
+	if got := testutil.CollectAndCount(m.OutputFlushDuration); got != 1 {
+		t.Errorf("flush duration has %d series, want 1", got)
+	}
+}
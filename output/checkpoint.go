@@ -0,0 +1,209 @@
+// ABOUTME: Checkpoints that let a file output recover from a crash in the middle of a flush.
+// ABOUTME: Records each flush's offsets beside the file, then on reopening cuts back a partial flush and counts what was lost.
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// checkpointSuffix names the checkpoint kept beside an output file
+const checkpointSuffix = ".checkpoint"
+
+// checkpoint is a file's state as of its last flush. While a flush is being
+// written, Pending and End describe it, so a crash before it finished can
+// be told from one after.
+type checkpoint struct {
+	Sequence int64     `json:"sequence"`          // Complete flushes to the file
+	Offset   int64     `json:"offset"`            // File size after the last complete flush
+	Entries  int64     `json:"entries"`           // Entries, or OTLP records, written by complete flushes
+	Pending  int       `json:"pending,omitempty"` // Entries in the flush being written
+	End      int64     `json:"end,omitempty"`     // File size once the pending flush is written
+	Time     time.Time `json:"time"`
+}
+
+// SetCheckpoint records a checkpoint beside the file, as logs.jsonl.checkpoint,
+// around every flush. The file is checked against its checkpoint now and
+// whenever a new file is opened: a flush a crash interrupted is cut back,
+// and its entries are logged and counted as lost. Without a checkpoint, a
+// partial last line is cut from the line formats.
+func (w *JSONWriter) SetCheckpoint(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.path == Stdout {
+		return
+	}
+	w.checkpointing = enabled
+	if enabled {
+		w.recoverFile()
+	}
+}
+
+// checkpointPath returns the name of the checkpoint for an output file
+func checkpointPath(path string) string {
+	return path + checkpointSuffix
+}
+
+// readCheckpoint reads the checkpoint for path, returning nil if there is none
+func readCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(checkpointPath(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", checkpointPath(path), err)
+	}
+	return &c, nil
+}
+
+// save writes the checkpoint for path, replacing the old one whole so a
+// crash never leaves half of one
+func (c *checkpoint) save(path string) error {
+	c.Time = time.Now().UTC()
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := checkpointPath(path) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, checkpointPath(path))
+}
+
+// recoverFile checks the current file against its checkpoint, cutting back
+// what a crash left of an interrupted flush, and continues from it. Caller
+// must hold mu.
+func (w *JSONWriter) recoverFile() {
+	w.checkpointed = w.path
+	c, err := readCheckpoint(w.path)
+	if err != nil {
+		w.report(opCheckpoint, err)
+	}
+	var size int64
+	if info, err := os.Stat(w.path); err == nil {
+		size = info.Size()
+	}
+
+	var cut int64 // Bytes to cut from the end
+	lost := 0     // Entries lost with them
+	switch {
+	case c == nil:
+		c = &checkpoint{}
+		if cut, err = w.partialLine(size); err != nil {
+			w.report(opCheckpoint, err)
+		}
+	case c.Pending > 0 && size == c.End:
+		// Written in full; only the checkpoint after it was not
+		c.Sequence++
+		c.Entries += int64(c.Pending)
+	case c.Pending > 0 && size >= c.Offset && size < c.End:
+		cut, lost = size-c.Offset, c.Pending
+	case size < c.Offset:
+		slog.Warn("Output: file is shorter than its checkpoint; it was cut or replaced while the receiver was down",
+			"path", w.path, "missing_bytes", c.Offset-size)
+		c.Entries = 0
+	default:
+		// Appended to by something else while the receiver was down, so
+		// only a partial last line can be repaired
+		if cut, err = w.partialLine(size); err != nil {
+			w.report(opCheckpoint, err)
+		}
+	}
+
+	if cut > 0 && lost == 0 {
+		lost = 1 // A partial line
+	}
+	if cut > 0 {
+		if err := os.Truncate(w.path, size-cut); err != nil {
+			w.report(opCheckpoint, fmt.Errorf("repairing %s: %w", w.path, err))
+			return
+		}
+	}
+	if cut > 0 || lost > 0 {
+		slog.Warn("Output: recovered after an interrupted flush", "path", w.path, "cut_bytes", cut, "lost_entries", lost)
+	}
+	if lost > 0 && w.metrics != nil {
+		w.metrics.OutputRecoveryLost.Add(float64(lost))
+	}
+	c.Offset, c.Pending, c.End = size-cut, 0, 0
+	w.checkpoint = *c
+	w.saveCheckpoint()
+}
+
+// partialLine returns the length of an unterminated last line in a line
+// format, or 0 in a binary one, where a line means nothing
+func (w *JSONWriter) partialLine(size int64) (int64, error) {
+	if size == 0 || w.gzipped() || w.format == FormatOTLPProto {
+		return 0, nil
+	}
+	f, err := os.Open(w.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// Search back from the end, a block at a time, for the last newline
+	buf := make([]byte, 4096)
+	for end := size; end > 0; {
+		start := max(end-int64(len(buf)), 0)
+		block := buf[:end-start]
+		if _, err := f.ReadAt(block, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := len(block) - 1; i >= 0; i-- {
+			if block[i] == '\n' {
+				return size - (start + int64(i) + 1), nil
+			}
+		}
+		end = start
+	}
+	return size, nil
+}
+
+// beginFlush records that a flush of n entries ending the file at end is
+// being written. Caller must hold mu.
+func (w *JSONWriter) beginFlush(n int, offset, end int64) {
+	if !w.checkpointing {
+		return
+	}
+	w.checkpoint.Offset, w.checkpoint.Pending, w.checkpoint.End = offset, n, end
+	w.saveCheckpoint()
+}
+
+// endFlush records that the pending flush was written. Caller must hold mu.
+func (w *JSONWriter) endFlush() {
+	if !w.checkpointing {
+		return
+	}
+	w.checkpoint.Sequence++
+	w.checkpoint.Entries += int64(w.checkpoint.Pending)
+	w.checkpoint.Offset, w.checkpoint.Pending, w.checkpoint.End = w.checkpoint.End, 0, 0
+	w.saveCheckpoint()
+}
+
+// saveCheckpoint writes the checkpoint, reporting a failure. Caller must hold mu.
+func (w *JSONWriter) saveCheckpoint() {
+	if err := w.checkpoint.save(w.path); err != nil {
+		w.report(opCheckpoint, err)
+	}
+}
+
+// dropCheckpoint removes the checkpoint of a file that is finished, as by
+// rotation, so the next file starts afresh. Caller must hold mu.
+func (w *JSONWriter) dropCheckpoint(path string) {
+	if !w.checkpointing {
+		return
+	}
+	os.Remove(checkpointPath(path))
+	w.checkpointed = ""
+}
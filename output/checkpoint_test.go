@@ -0,0 +1,141 @@
+// ABOUTME: Tests for recovering file output from a crash with checkpoints.
+// ABOUTME: Covers checkpoints after flushes, cutting back interrupted flushes, partial last lines, and rotation.
+
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// openCheckpointed opens a writer with checkpoints that only flushes when asked
+func openCheckpointed(t *testing.T, path string, m *metrics.Metrics) *JSONWriter {
+	t.Helper()
+	w, err := NewJSONWriter(path, FormatJSONL, 100, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	w.SetMetrics(m)
+	w.SetCheckpoint(true)
+	return w
+}
+
+// mustCheckpoint reads the checkpoint for path
+func mustCheckpoint(t *testing.T, path string) checkpoint {
+	t.Helper()
+	c, err := readCheckpoint(path)
+	if err != nil || c == nil {
+		t.Fatalf("checkpoint for %s = %v, %v", path, c, err)
+	}
+	return *c
+}
+
+// appendFile appends data to path, as a flush would
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.WriteString(data)
+}
+
+func TestCheckpoint_RecordsEachFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	w := openCheckpointed(t, path, metrics.New())
+	w.Write(&LogEntry{Body: "one"})
+	w.Write(&LogEntry{Body: "two"})
+	w.Flush()
+	w.Write(&LogEntry{Body: "three"})
+	w.Flush()
+
+	info, _ := os.Stat(path)
+	c := mustCheckpoint(t, path)
+	if c.Sequence != 2 || c.Entries != 3 || c.Offset != info.Size() || c.Pending != 0 {
+		t.Errorf("checkpoint = %+v, want 2 flushes of 3 entries ending at %d", c, info.Size())
+	}
+}
+
+func TestCheckpoint_CutsBackInterruptedFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	good := `{"body":"one"}` + "\n"
+	appendFile(t, path, good)
+	c := checkpoint{Sequence: 1, Offset: int64(len(good)), Entries: 1, Pending: 3, End: int64(len(good)) + 100}
+	c.save(path)
+	appendFile(t, path, `{"body":"two"}`+"\n"+`{"bo`) // Cut off by a crash
+
+	m := metrics.New()
+	openCheckpointed(t, path, m)
+	data, _ := os.ReadFile(path)
+	if string(data) != good {
+		t.Errorf("file = %q, want only the complete flush", data)
+	}
+	if got := testutil.ToFloat64(m.OutputRecoveryLost); got != 3 {
+		t.Errorf("lost = %v, want the interrupted flush's 3", got)
+	}
+	if c := mustCheckpoint(t, path); c.Offset != int64(len(good)) || c.Entries != 1 || c.Pending != 0 {
+		t.Errorf("checkpoint = %+v, want it to continue from the complete flush", c)
+	}
+}
+
+func TestCheckpoint_KeepsFlushWrittenBeforeCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	flush := `{"body":"one"}` + "\n" + `{"body":"two"}` + "\n"
+	c := checkpoint{Pending: 2, End: int64(len(flush))}
+	c.save(path)
+	appendFile(t, path, flush) // Written, but the checkpoint after it was not
+
+	m := metrics.New()
+	openCheckpointed(t, path, m)
+	if c := mustCheckpoint(t, path); c.Entries != 2 || c.Sequence != 1 || c.Offset != int64(len(flush)) {
+		t.Errorf("checkpoint = %+v, want the flush counted", c)
+	}
+	if got := testutil.ToFloat64(m.OutputRecoveryLost); got != 0 {
+		t.Errorf("lost = %v, want 0", got)
+	}
+}
+
+func TestCheckpoint_CutsPartialLineWithoutCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	appendFile(t, path, `{"body":"one"}`+"\n"+strings.Repeat("x", 5000))
+
+	m := metrics.New()
+	w := openCheckpointed(t, path, m)
+	w.Write(&LogEntry{Body: "two"})
+	w.Flush()
+	data, _ := os.ReadFile(path)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"body":"two"`) {
+		t.Errorf("file = %q, want the partial line replaced by the next entry", data)
+	}
+	if got := testutil.ToFloat64(m.OutputRecoveryLost); got != 1 {
+		t.Errorf("lost = %v, want 1", got)
+	}
+}
+
+func TestCheckpoint_NewFileAfterRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	w, err := NewJSONWriter(path, FormatJSONL, 1, time.Hour, 10)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	w.SetCheckpoint(true)
+
+	w.Write(&LogEntry{Body: "one"})
+	w.Write(&LogEntry{Body: "two"}) // Rotates first
+	if c := mustCheckpoint(t, path); c.Entries != 1 || c.Sequence != 1 {
+		t.Errorf("checkpoint = %+v, want only the new file's flush", c)
+	}
+	if _, err := os.Stat(checkpointPath(path + ".1")); !os.IsNotExist(err) {
+		t.Error("the rotated file should have no checkpoint")
+	}
+}
@@ -0,0 +1,261 @@
+// ABOUTME: Elasticsearch and OpenSearch output that writes transformed records through the _bulk API.
+// ABOUTME: Maps each routed index to an index name pattern and retries documents the cluster pushes back on.
+
+package output
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// ESConfig configures writing to Elasticsearch or OpenSearch
+type ESConfig struct {
+	URL                string        // e.g. https://elastic.example.com:9200
+	IndexPattern       string        // Index name for a record; {index} is its routed index, and date fields as in ExpandPath
+	Username, Password string        // For basic auth
+	APIKey             string        // Encoded Elasticsearch API key, used instead of basic auth if set
+	BatchSize          int           // Documents per bulk request
+	FlushInterval      time.Duration // Longest a partial batch waits
+	MaxRetries         int           // Attempts after the first before documents are dropped
+	SkipSSLValidation  bool          // For labs with self-signed certificates
+	Client             *http.Client  // Defaults to a client with a 30 second timeout
+}
+
+// DefaultESIndexPattern sends each record to an index named for its route
+const DefaultESIndexPattern = "{index}"
+
+// esDocument is a log entry as indexed, with the @timestamp ECS and Kibana expect
+type esDocument struct {
+	Timestamp string `json:"@timestamp"`
+	*LogEntry
+}
+
+// esBulkResponse is the part of a _bulk reply that reports each document's result
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// ESWriter batches log entries and writes them to Elasticsearch or
+// OpenSearch with the _bulk API from a background goroutine. Documents the
+// cluster rejects with 429 or a server error are retried with backoff;
+// others, such as mapping conflicts, are logged and dropped.
+type ESWriter struct {
+	*batcher[*LogEntry]
+	cfg      ESConfig
+	endpoint string
+	client   *http.Client
+
+	mu      sync.Mutex
+	metrics *metrics.Metrics
+}
+
+func init() {
+	RegisterSink("es", openES)
+}
+
+// openES opens an Elasticsearch sink from a URI like
+// es://elastic@elastic.example.com:9200?index=logs-{index}, with the
+// password in ES_PASSWORD or an API key in ES_API_KEY
+func openES(u *url.URL) (Sink, error) {
+	q := newSinkQuery(u)
+	cfg := ESConfig{
+		URL:               baseURL(u, q),
+		IndexPattern:      q.string("index", DefaultESIndexPattern),
+		Username:          u.User.Username(),
+		Password:          os.Getenv("ES_PASSWORD"),
+		APIKey:            os.Getenv("ES_API_KEY"),
+		BatchSize:         q.int("batch-size", 100),
+		FlushInterval:     q.duration("flush-interval", 5*time.Second),
+		MaxRetries:        q.int("max-retries", defaultMaxRetries),
+		SkipSSLValidation: q.bool("skip-ssl-validation"),
+	}
+	if err := q.done(); err != nil {
+		return nil, err
+	}
+	return NewESWriter(cfg)
+}
+
+// NewESWriter validates cfg and starts writing
+func NewESWriter(cfg ESConfig) (*ESWriter, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Elasticsearch URL %q must be an http or https URL", cfg.URL)
+	}
+	if cfg.IndexPattern == "" {
+		cfg.IndexPattern = DefaultESIndexPattern
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+		if cfg.SkipSSLValidation {
+			client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+		}
+	}
+
+	w := &ESWriter{cfg: cfg, endpoint: strings.TrimSuffix(cfg.URL, "/") + "/_bulk", client: client}
+	w.batcher = newBatcher("Elasticsearch", cfg.BatchSize, cfg.FlushInterval, cfg.MaxRetries, w.send, w.count)
+	return w, nil
+}
+
+// ESIndex names the index for a record routed to index at t: {index} in the
+// pattern is replaced, date fields are expanded in UTC, and the result is
+// lowercased, as index names must be
+func ESIndex(pattern, index string, t time.Time) string {
+	return strings.ToLower(ExpandPath(strings.ReplaceAll(pattern, "{index}", index), t.UTC()))
+}
+
+// SetMetrics counts written, failed, and discarded documents in m, and reports those waiting to be sent
+func (w *ESWriter) SetMetrics(m *metrics.Metrics) {
+	w.batcher.setMetrics(m, "elasticsearch")
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.metrics = m
+}
+
+// Write queues a log entry for the next bulk request. While the cluster is
+// unreachable, up to 100 batches are held, discarding the oldest entries beyond that.
+func (w *ESWriter) Write(entry *LogEntry) error {
+	w.add(entry)
+	return nil
+}
+
+// Flush writes what is queued now, with at most one attempt per batch
+func (w *ESWriter) Flush() error {
+	return w.flush()
+}
+
+// Close writes what is queued, with at most one attempt per batch, and stops writing
+func (w *ESWriter) Close() error {
+	return w.close()
+}
+
+// send writes one batch with a bulk request. If only some documents fail
+// with a retryable status, they are moved to the front of the batch to be
+// sent again.
+func (w *ESWriter) send(batch []*LogEntry) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, entry := range batch {
+		ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+		if err != nil || ts.UnixNano() <= 0 {
+			ts = time.Now()
+		}
+		action := map[string]map[string]string{"create": {"_index": ESIndex(w.cfg.IndexPattern, entry.Routing.Index, ts)}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(esDocument{Timestamp: ts.UTC().Format(time.RFC3339Nano), LogEntry: entry}); err != nil {
+			return err
+		}
+	}
+
+	size := body.Len()
+	req, err := http.NewRequest(http.MethodPost, w.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case w.cfg.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+w.cfg.APIKey)
+	case w.cfg.Username != "":
+		req.SetBasicAuth(w.cfg.Username, w.cfg.Password)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return retryableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return retryableError{err: fmt.Errorf("post %s: %s", w.endpoint, resp.Status)}
+	case resp.StatusCode != http.StatusOK:
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("post %s: %s: %s", w.endpoint, resp.Status, bytes.TrimSpace(reply))
+	}
+	var bulk esBulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&bulk); err != nil {
+		return fmt.Errorf("post %s: reading reply: %w", w.endpoint, err)
+	}
+	if !bulk.Errors {
+		w.count(resultSent, len(batch))
+		w.sent(len(batch), size)
+		return nil
+	}
+	return w.itemFailures(batch, bulk, size)
+}
+
+// itemFailures counts each document of a bulk request that reported errors,
+// moving those to retry to the front of the batch
+func (w *ESWriter) itemFailures(batch []*LogEntry, bulk esBulkResponse, size int) error {
+	var sent, failed, retry int
+	var failedErr, retryErr string
+	for i, entry := range batch {
+		status, reason := http.StatusInternalServerError, "missing from the reply" // Retried
+		if i < len(bulk.Items) {
+			for _, result := range bulk.Items[i] {
+				status = result.Status
+				if result.Error != nil {
+					reason = result.Error.Type + ": " + result.Error.Reason
+				}
+			}
+		}
+		switch {
+		case status < 300:
+			sent++
+		case status == http.StatusTooManyRequests || status >= 500:
+			batch[retry] = entry
+			retry++
+			retryErr = reason
+		default:
+			failed++
+			failedErr = reason
+		}
+	}
+	w.count(resultSent, sent)
+	w.sent(sent, size)
+	if failed > 0 {
+		slog.Error("Elasticsearch: dropping documents", "documents", failed, "err", failedErr)
+		w.count(resultFailed, failed)
+	}
+	if retry > 0 {
+		return retryableError{err: fmt.Errorf("%d documents rejected: %s", retry, retryErr), retry: retry}
+	}
+	return nil
+}
+
+// count adds n documents to the result's metric
+func (w *ESWriter) count(result string, n int) {
+	w.mu.Lock()
+	m := w.metrics
+	w.mu.Unlock()
+	if m != nil && n > 0 {
+		m.ESDocuments.WithLabelValues(result).Add(float64(n))
+	}
+}
@@ -0,0 +1,148 @@
+// ABOUTME: Tests for writing records to a fake Elasticsearch _bulk endpoint.
+// ABOUTME: Covers index name patterns, the bulk format, and retrying only the documents the cluster pushed back on.
+
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// fakeES accepts bulk requests, answering each document with the next of
+// statuses and 201 once they run out
+type fakeES struct {
+	mu       sync.Mutex
+	statuses []int
+	indexed  map[string][]string // Bodies by index
+	requests int
+	user     string
+}
+
+func (f *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	f.user, _, _ = r.BasicAuth()
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var items []string
+	errors := false
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]map[string]string
+		json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan()
+		var doc map[string]any
+		json.Unmarshal(scanner.Bytes(), &doc)
+
+		status := http.StatusCreated
+		if len(f.statuses) > 0 {
+			status, f.statuses = f.statuses[0], f.statuses[1:]
+		}
+		if status == http.StatusCreated {
+			index := action["create"]["_index"]
+			f.indexed[index] = append(f.indexed[index], fmt.Sprint(doc["body"], "@", doc["@timestamp"]))
+			items = append(items, `{"create":{"status":201}}`)
+		} else {
+			errors = true
+			items = append(items, fmt.Sprintf(`{"create":{"status":%d,"error":{"type":"test_exception","reason":"status %d"}}}`, status, status))
+		}
+	}
+	fmt.Fprintf(w, `{"took":1,"errors":%t,"items":[%s]}`, errors, strings.Join(items, ","))
+}
+
+func newESTest(t *testing.T, f *fakeES, cfg ESConfig) (*ESWriter, *metrics.Metrics) {
+	t.Helper()
+	f.indexed = make(map[string][]string)
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	cfg.URL = srv.URL
+	w, err := NewESWriter(cfg)
+	if err != nil {
+		t.Fatalf("NewESWriter failed: %v", err)
+	}
+	w.backoff = time.Millisecond
+	m := metrics.New()
+	w.SetMetrics(m)
+	return w, m
+}
+
+func TestESIndex(t *testing.T) {
+	at := time.Date(2024, 1, 15, 23, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	for pattern, want := range map[string]string{
+		"{index}":               "tas_errors",
+		"logs-{index}-%Y.%m.%d": "logs-tas_errors-2024.01.16",
+		"TAS-{index}":           "tas-tas_errors",
+		"static":                "static",
+	} {
+		if got := ESIndex(pattern, "tas_errors", at); got != want {
+			t.Errorf("ESIndex(%s) = %s, want %s", pattern, got, want)
+		}
+	}
+}
+
+func TestESWriter_BulkWrites(t *testing.T) {
+	f := &fakeES{}
+	w, m := newESTest(t, f, ESConfig{IndexPattern: "logs-{index}-%Y.%m", Username: "elastic", Password: "changeme", BatchSize: 10, FlushInterval: time.Hour})
+
+	w.Write(&LogEntry{Timestamp: "2024-01-15T10:30:00Z", Body: "payment failed", Routing: RoutingInfo{Index: "tas_errors"}})
+	w.Write(&LogEntry{Timestamp: "2024-02-01T00:00:00Z", Body: "started", Routing: RoutingInfo{Index: "tas_logs"}})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if f.requests != 1 || f.user != "elastic" {
+		t.Errorf("got %d requests as %q, want one as elastic", f.requests, f.user)
+	}
+	if got := f.indexed["logs-tas_errors-2024.01"]; len(got) != 1 || got[0] != "payment failed@2024-01-15T10:30:00Z" {
+		t.Errorf("tas_errors index = %v", got)
+	}
+	if got := f.indexed["logs-tas_logs-2024.02"]; len(got) != 1 {
+		t.Errorf("tas_logs index = %v, indexed = %v", got, f.indexed)
+	}
+	if got := testutil.ToFloat64(m.ESDocuments.WithLabelValues("sent")); got != 2 {
+		t.Errorf("sent = %v, want 2", got)
+	}
+}
+
+func TestESWriter_RetriesRejectedDocuments(t *testing.T) {
+	// The second document is throttled, then accepted; the third has a mapping error
+	f := &fakeES{statuses: []int{201, 429, 400}}
+	w, m := newESTest(t, f, ESConfig{BatchSize: 3, FlushInterval: time.Hour, MaxRetries: 3})
+	defer w.Close()
+
+	for _, body := range []string{"one", "two", "three"} {
+		w.Write(&LogEntry{Timestamp: "2024-01-15T10:30:00Z", Body: body, Routing: RoutingInfo{Index: "tas_logs"}})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(m.ESDocuments.WithLabelValues("sent")) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("throttled document was not retried")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	want := []string{"one@2024-01-15T10:30:00Z", "two@2024-01-15T10:30:00Z"}
+	if got := f.indexed["tas_logs"]; strings.Join(got, " ") != strings.Join(want, " ") || f.requests != 2 {
+		t.Errorf("indexed %v in %d requests, want %v in 2", got, f.requests, want)
+	}
+	if got := testutil.ToFloat64(m.ESDocuments.WithLabelValues("failed")); got != 1 {
+		t.Errorf("failed = %v, want the mapping error dropped", got)
+	}
+}
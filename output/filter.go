@@ -0,0 +1,157 @@
+// ABOUTME: Per-sink filters that pass a sink only records from some indexes or at or above a severity.
+// ABOUTME: Declared as include-index, exclude-index, and min-severity parameters on a sink URI or -output-filter.
+
+package output
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/transform"
+)
+
+// Filter parameters, taken from a sink URI before its factory sees it
+var filterParams = []string{"include-index", "exclude-index", "min-severity"}
+
+// Filter selects records by routed index and severity
+type Filter struct {
+	Include     []string              // Indexes to pass; every index if empty
+	Exclude     []string              // Indexes to hold back, even if included
+	MinSeverity logspb.SeverityNumber // Lowest severity to pass; records without one are held back if set
+}
+
+// ParseFilter reads a filter from parameters like
+// include-index=tas_errors,tas_audit&min-severity=WARN
+func ParseFilter(spec string) (*Filter, error) {
+	values, err := url.ParseQuery(spec)
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: %w", spec, err)
+	}
+	f, err := filterFrom(values)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) > 0 {
+		return nil, fmt.Errorf("filter %q: unknown parameter %q; want %s", spec, sortedKeys(values)[0], strings.Join(filterParams, ", "))
+	}
+	if f == nil {
+		return nil, fmt.Errorf("filter %q: want one of %s", spec, strings.Join(filterParams, ", "))
+	}
+	return f, nil
+}
+
+// filterFrom takes the filter parameters out of values, returning nil if
+// there are none
+func filterFrom(values url.Values) (*Filter, error) {
+	if !slices.ContainsFunc(filterParams, values.Has) {
+		return nil, nil
+	}
+	f := &Filter{
+		Include: splitList(values["include-index"]),
+		Exclude: splitList(values["exclude-index"]),
+	}
+	if s := values.Get("min-severity"); s != "" {
+		sev, err := transform.ParseSeverity(s)
+		if err != nil {
+			return nil, fmt.Errorf("min-severity=%s: %w", s, err)
+		}
+		f.MinSeverity = sev
+	}
+	for _, name := range filterParams {
+		values.Del(name)
+	}
+	return f, nil
+}
+
+// splitList joins repeated parameters and splits comma-separated ones
+func splitList(values []string) []string {
+	var list []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// Match reports whether a record routed to index at sev passes
+func (f *Filter) Match(index string, sev logspb.SeverityNumber) bool {
+	if len(f.Include) > 0 && !slices.Contains(f.Include, index) {
+		return false
+	}
+	return !slices.Contains(f.Exclude, index) && sev >= f.MinSeverity
+}
+
+// String summarizes the filter for the startup banner
+func (f *Filter) String() string {
+	var parts []string
+	if len(f.Include) > 0 {
+		parts = append(parts, "only "+strings.Join(f.Include, ", "))
+	}
+	if len(f.Exclude) > 0 {
+		parts = append(parts, "not "+strings.Join(f.Exclude, ", "))
+	}
+	if f.MinSeverity > 0 {
+		parts = append(parts, transform.SeverityName(f.MinSeverity)+"+")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// FilteredSink passes its sink only the records its filter matches
+type FilteredSink struct {
+	Sink
+	filter *Filter
+}
+
+// NewFilteredSink filters what is written to sink
+func NewFilteredSink(sink Sink, f *Filter) *FilteredSink {
+	return &FilteredSink{Sink: sink, filter: f}
+}
+
+// Filter returns the sink's filter
+func (s *FilteredSink) Filter() *Filter {
+	return s.filter
+}
+
+// Write passes the entry on if it matches
+func (s *FilteredSink) Write(entry *LogEntry) error {
+	if !s.filter.Match(entry.Routing.Index, logspb.SeverityNumber(entry.SeverityNumber)) {
+		return nil
+	}
+	return s.Sink.Write(entry)
+}
+
+// TakesRecords reports whether the sink takes records as OTLP
+func (s *FilteredSink) TakesRecords() bool {
+	rs, ok := s.Sink.(RecordSink)
+	return ok && rs.TakesRecords()
+}
+
+// WriteRecord passes the record on if it matches, by its index attribute
+func (s *FilteredSink) WriteRecord(app string, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord) error {
+	if !s.TakesRecords() {
+		return errRecordsOnly
+	}
+	if !s.filter.Match(recordIndex(lr), lr.GetSeverityNumber()) {
+		return nil
+	}
+	return s.Sink.(RecordSink).WriteRecord(app, resource, scope, lr)
+}
+
+// recordIndex returns the index the receiver stamped on a routed record
+func recordIndex(lr *logspb.LogRecord) string {
+	for _, attr := range lr.GetAttributes() {
+		if attr.GetKey() == "index" {
+			return attr.GetValue().GetStringValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+// ABOUTME: Tests for per-sink filters by index and severity.
+// ABOUTME: Covers parsing, matching entries and records, and filter parameters on sink URIs.
+
+package output
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func TestFilter_Match(t *testing.T) {
+	f, err := ParseFilter("include-index=tas_errors,tas_audit&exclude-index=tas_audit&min-severity=WARN")
+	if err != nil {
+		t.Fatalf("ParseFilter failed: %v", err)
+	}
+	for _, tc := range []struct {
+		index string
+		sev   logspb.SeverityNumber
+		want  bool
+	}{
+		{"tas_errors", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, true},
+		{"tas_errors", logspb.SeverityNumber_SEVERITY_NUMBER_WARN, true},
+		{"tas_errors", logspb.SeverityNumber_SEVERITY_NUMBER_INFO4, false},
+		{"tas_errors", logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, false},
+		{"tas_audit", logspb.SeverityNumber_SEVERITY_NUMBER_FATAL, false},
+		{"tas_logs", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, false},
+	} {
+		if got := f.Match(tc.index, tc.sev); got != tc.want {
+			t.Errorf("Match(%s, %s) = %v, want %v", tc.index, tc.sev, got, tc.want)
+		}
+	}
+	if got := f.String(); got != "only tas_errors, tas_audit; not tas_audit; WARN+" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestParseFilter_Errors(t *testing.T) {
+	for spec, want := range map[string]string{
+		"min-severity=LOUD":           `unknown severity "LOUD"`,
+		"include-index=a&severity=10": `unknown parameter "severity"`,
+		"":                            "want one of include-index",
+	} {
+		if _, err := ParseFilter(spec); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseFilter(%q) = %v, want an error containing %q", spec, err, want)
+		}
+	}
+}
+
+func TestFilteredSink_PassesMatchingEntriesAndRecords(t *testing.T) {
+	f := &fakeSink{}
+	s := NewFilteredSink(f, &Filter{Include: []string{"tas_errors"}})
+	s.Write(&LogEntry{Body: "kept", Routing: RoutingInfo{Index: "tas_errors"}})
+	s.Write(&LogEntry{Body: "held back", Routing: RoutingInfo{Index: "tas_logs"}})
+	if got := f.written(); len(got) != 1 || got[0] != "kept" {
+		t.Errorf("sink got %v, want only the tas_errors entry", got)
+	}
+	if s.TakesRecords() || s.WriteRecord("web", nil, nil, testRecord("x")) == nil {
+		t.Error("filter should refuse records for a sink that takes entries")
+	}
+
+	records := &fakeSink{records: true}
+	rs := NewFilteredSink(records, &Filter{Include: []string{"tas_errors"}})
+	routed := func(body, index string) *logspb.LogRecord {
+		lr := testRecord(body)
+		lr.Attributes = []*commonpb.KeyValue{{Key: "index", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: index}}}}
+		return lr
+	}
+	rs.WriteRecord("web", &resourcepb.Resource{}, nil, routed("kept", "tas_errors"))
+	rs.WriteRecord("web", &resourcepb.Resource{}, nil, routed("held back", "tas_logs"))
+	if got := records.written(); len(got) != 1 || got[0] != "web:kept" {
+		t.Errorf("sink got %v, want only the tas_errors record", got)
+	}
+}
+
+func TestOpenSink_FilterParameters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	s, err := OpenSink("file://" + path + "?min-severity=ERROR&keep=2")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	fs, ok := s.(*FilteredSink)
+	if !ok {
+		t.Fatalf("OpenSink returned %T, want a filtered sink", s)
+	}
+	if fs.Filter().MinSeverity != logspb.SeverityNumber_SEVERITY_NUMBER_ERROR || fs.Sink.(*JSONWriter).keep != 2 {
+		t.Errorf("filter %v, keep %d; want both parameters applied", fs.Filter(), fs.Sink.(*JSONWriter).keep)
+	}
+}
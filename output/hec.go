@@ -0,0 +1,232 @@
+// ABOUTME: Splunk HTTP Event Collector output that forwards transformed records in batches.
+// ABOUTME: Sends each record to its routed index and sourcetype, retrying failed batches with backoff.
+
+package output
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// HECConfig configures forwarding to a Splunk HTTP Event Collector
+type HECConfig struct {
+	URL               string        // e.g. https://splunk.example.com:8088; /services/collector/event is added if there is no path
+	Token             string        // HEC token
+	Sourcetype        string        // For records whose route sets none
+	BatchSize         int           // Events per request
+	FlushInterval     time.Duration // Longest a partial batch waits
+	MaxRetries        int           // Attempts after the first before a batch is dropped
+	SkipSSLValidation bool          // For labs with self-signed certificates
+	Client            *http.Client  // Defaults to a client with a 30 second timeout
+}
+
+// hecEvent is one event in the HEC JSON format
+type hecEvent struct {
+	Time       *float64          `json:"time,omitempty"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	Sourcetype string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Event      string            `json:"event"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// HECWriter batches log entries and posts them to a Splunk HTTP Event
+// Collector from a background goroutine, so a slow or unreachable Splunk
+// never holds up the receiver
+type HECWriter struct {
+	*batcher[*LogEntry]
+	cfg      HECConfig
+	endpoint string
+	client   *http.Client
+
+	mu      sync.Mutex
+	metrics *metrics.Metrics
+}
+
+func init() {
+	RegisterSink("hec", openHEC)
+}
+
+// openHEC opens a HEC sink from a URI like
+// hec://splunk.example.com:8088?sourcetype=otel, with the token in HEC_TOKEN
+func openHEC(u *url.URL) (Sink, error) {
+	q := newSinkQuery(u)
+	cfg := HECConfig{
+		URL:               baseURL(u, q),
+		Token:             os.Getenv("HEC_TOKEN"),
+		Sourcetype:        q.string("sourcetype", "otel"),
+		BatchSize:         q.int("batch-size", 100),
+		FlushInterval:     q.duration("flush-interval", 5*time.Second),
+		MaxRetries:        q.int("max-retries", defaultMaxRetries),
+		SkipSSLValidation: q.bool("skip-ssl-validation"),
+	}
+	if err := q.done(); err != nil {
+		return nil, err
+	}
+	return NewHECWriter(cfg)
+}
+
+// NewHECWriter validates cfg and starts forwarding
+func NewHECWriter(cfg HECConfig) (*HECWriter, error) {
+	endpoint, err := hecEndpoint(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("HEC token is required")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+		if cfg.SkipSSLValidation {
+			client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+		}
+	}
+
+	w := &HECWriter{cfg: cfg, endpoint: endpoint, client: client}
+	w.batcher = newBatcher("HEC", cfg.BatchSize, cfg.FlushInterval, cfg.MaxRetries, w.send, w.count)
+	return w, nil
+}
+
+// hecEndpoint returns the event endpoint for a HEC base URL
+func hecEndpoint(base string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("HEC URL %q must be an http or https URL", base)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/services/collector/event"
+	}
+	return u.String(), nil
+}
+
+// SetMetrics counts forwarded, failed, and discarded events in m, and reports those waiting to be sent
+func (w *HECWriter) SetMetrics(m *metrics.Metrics) {
+	w.batcher.setMetrics(m, "hec")
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.metrics = m
+}
+
+// Write queues a log entry for the next batch. While Splunk is unreachable,
+// up to 100 batches are held, discarding the oldest entries beyond that.
+func (w *HECWriter) Write(entry *LogEntry) error {
+	w.add(entry)
+	return nil
+}
+
+// Flush sends what is queued now, with at most one attempt per batch
+func (w *HECWriter) Flush() error {
+	return w.flush()
+}
+
+// Close sends what is queued, with at most one attempt per batch, and stops forwarding
+func (w *HECWriter) Close() error {
+	return w.close()
+}
+
+// send posts one batch of events
+func (w *HECWriter) send(batch []*LogEntry) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, entry := range batch {
+		if err := enc.Encode(w.event(entry)); err != nil {
+			return err
+		}
+	}
+
+	size := body.Len()
+	req, err := http.NewRequest(http.MethodPost, w.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+w.cfg.Token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		w.count(resultSent, len(batch))
+		w.sent(len(batch), size)
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return retryableError{err: fmt.Errorf("post %s: %s: %s", w.endpoint, resp.Status, bytes.TrimSpace(reply))}
+	default:
+		return fmt.Errorf("post %s: %s: %s", w.endpoint, resp.Status, bytes.TrimSpace(reply))
+	}
+}
+
+// event converts a log entry to a HEC event, with its attributes as indexed fields
+func (w *HECWriter) event(entry *LogEntry) hecEvent {
+	ev := hecEvent{
+		Index:      entry.Routing.Index,
+		Sourcetype: entry.Routing.Sourcetype,
+		Source:     entry.Routing.Source,
+		Event:      entry.Body,
+		Fields:     make(map[string]string),
+	}
+	if ev.Sourcetype == "" {
+		ev.Sourcetype = w.cfg.Sourcetype
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp); err == nil && ts.UnixNano() > 0 {
+		secs := float64(ts.UnixMilli()) / 1000
+		ev.Time = &secs
+	}
+	for k, v := range entry.ResourceAttrs {
+		ev.Fields[k] = fieldValue(v)
+	}
+	for k, v := range entry.Attributes {
+		ev.Fields[k] = fieldValue(v)
+	}
+	// Already the event's metadata, and reserved as indexed field names
+	delete(ev.Fields, "index")
+	delete(ev.Fields, "sourcetype")
+	delete(ev.Fields, "source")
+	ev.Host = ev.Fields["host.name"]
+	if entry.Severity != "" {
+		ev.Fields["severity"] = entry.Severity
+	}
+	return ev
+}
+
+// fieldValue renders an attribute as an indexed field, which must be a
+// string: natively typed values are written as JSON
+func fieldValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// count adds n events to the result's metric
+func (w *HECWriter) count(result string, n int) {
+	w.mu.Lock()
+	m := w.metrics
+	w.mu.Unlock()
+	if m != nil {
+		m.HECEvents.WithLabelValues(result).Add(float64(n))
+	}
+}
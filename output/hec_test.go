@@ -0,0 +1,176 @@
+// ABOUTME: Tests for forwarding records to a fake Splunk HTTP Event Collector.
+// ABOUTME: Covers the event format, batching, retrying server errors, and dropping rejected batches.
+
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// fakeHEC records the events it is sent, failing with each status in fail first
+type fakeHEC struct {
+	mu       sync.Mutex
+	events   []hecEvent
+	requests int
+	auth     string
+	fail     []int
+}
+
+func (f *fakeHEC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	f.auth = r.Header.Get("Authorization")
+	if r.URL.Path != "/services/collector/event" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if len(f.fail) > 0 {
+		status := f.fail[0]
+		f.fail = f.fail[1:]
+		http.Error(w, `{"text":"Server is busy","code":9}`, status)
+		return
+	}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var ev hecEvent
+		json.Unmarshal(scanner.Bytes(), &ev)
+		f.events = append(f.events, ev)
+	}
+	w.Write([]byte(`{"text":"Success","code":0}`))
+}
+
+func (f *fakeHEC) received() ([]hecEvent, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]hecEvent(nil), f.events...), f.requests
+}
+
+func newHECTest(t *testing.T, f *fakeHEC, cfg HECConfig) (*HECWriter, *metrics.Metrics) {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	cfg.URL, cfg.Token = srv.URL, "secret"
+	w, err := NewHECWriter(cfg)
+	if err != nil {
+		t.Fatalf("NewHECWriter failed: %v", err)
+	}
+	w.backoff = time.Millisecond
+	m := metrics.New()
+	w.SetMetrics(m)
+	return w, m
+}
+
+func TestHECWriter_SendsBatches(t *testing.T) {
+	f := &fakeHEC{}
+	w, m := newHECTest(t, f, HECConfig{Sourcetype: "otel", BatchSize: 2, FlushInterval: time.Hour})
+
+	w.Write(&LogEntry{
+		Timestamp:     "2024-01-15T10:30:00.25Z",
+		Severity:      "ERROR",
+		Body:          "payment failed",
+		Attributes:    map[string]any{"cf_app_name": "payment-api", "sourcetype": "cf:error", "index": "tas_errors", "status": int64(503), "http": map[string]any{"method": "GET"}},
+		ResourceAttrs: map[string]any{"host.name": "diego-cell-1", "cf_app_name": "resource-name"},
+		Routing:       RoutingInfo{Index: "tas_errors", Rule: "errors", Sourcetype: "cf:error", Source: "tas:errors"},
+	})
+	w.Write(&LogEntry{Timestamp: "1970-01-01T00:00:00Z", Body: "no route sourcetype", Routing: RoutingInfo{Index: "tas_logs"}})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	events, requests := f.received()
+	if requests != 1 || len(events) != 2 || f.auth != "Splunk secret" {
+		t.Fatalf("got %d events in %d requests with auth %q, want one batch of 2", len(events), requests, f.auth)
+	}
+	ev := events[0]
+	if ev.Index != "tas_errors" || ev.Sourcetype != "cf:error" || ev.Source != "tas:errors" || ev.Event != "payment failed" || ev.Host != "diego-cell-1" {
+		t.Errorf("event = %+v", ev)
+	}
+	if ev.Time == nil || *ev.Time != 1705314600.25 {
+		t.Errorf("time = %v, want 1705314600.25", ev.Time)
+	}
+	if ev.Fields["cf_app_name"] != "payment-api" || ev.Fields["severity"] != "ERROR" || ev.Fields["sourcetype"] != "" || ev.Fields["index"] != "" {
+		t.Errorf("fields = %v, want record attributes over resource ones, without routing metadata", ev.Fields)
+	}
+	if ev.Fields["status"] != "503" || ev.Fields["http"] != `{"method":"GET"}` {
+		t.Errorf("fields = %v, want typed attributes as JSON", ev.Fields)
+	}
+	if events[1].Sourcetype != "otel" || events[1].Time != nil {
+		t.Errorf("second event = %+v, want the default sourcetype and no time", events[1])
+	}
+	if got := testutil.ToFloat64(m.HECEvents.WithLabelValues("sent")); got != 2 {
+		t.Errorf("sent = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.OutputEntries.WithLabelValues("hec")); got != 2 {
+		t.Errorf("entries written = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.OutputBytes.WithLabelValues("hec")); got == 0 {
+		t.Error("bytes written = 0, want the request body's size")
+	}
+}
+
+func TestHECWriter_RetriesServerErrors(t *testing.T) {
+	f := &fakeHEC{fail: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	w, _ := newHECTest(t, f, HECConfig{BatchSize: 1, FlushInterval: time.Hour, MaxRetries: 3})
+	defer w.Close()
+
+	w.Write(&LogEntry{Body: "eventually", Routing: RoutingInfo{Index: "tas_logs"}})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		events, requests := f.received()
+		if len(events) == 1 {
+			if requests != 3 {
+				t.Errorf("requests = %d, want 2 failures and a success", requests)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("event not delivered after %d requests", requests)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHECWriter_DropsRejectedBatches(t *testing.T) {
+	f := &fakeHEC{fail: []int{http.StatusBadRequest}}
+	w, m := newHECTest(t, f, HECConfig{BatchSize: 1, FlushInterval: time.Hour, MaxRetries: 3})
+
+	w.Write(&LogEntry{Body: "invalid"})
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(m.HECEvents.WithLabelValues("failed")) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("rejected batch was not dropped")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	w.Close()
+	if _, requests := f.received(); requests != 1 {
+		t.Errorf("requests = %d, want a 400 not retried", requests)
+	}
+}
+
+func TestHECEndpoint(t *testing.T) {
+	for base, want := range map[string]string{
+		"https://splunk:8088":                     "https://splunk:8088/services/collector/event",
+		"https://splunk:8088/":                    "https://splunk:8088/services/collector/event",
+		"http://cribl:10080/services/collector":   "http://cribl:10080/services/collector",
+		"https://hec.example.com/custom/path?x=1": "https://hec.example.com/custom/path?x=1",
+	} {
+		if got, err := hecEndpoint(base); err != nil || got != want {
+			t.Errorf("hecEndpoint(%s) = %s, %v, want %s", base, got, err, want)
+		}
+	}
+	if _, err := hecEndpoint("splunk:8088"); err == nil {
+		t.Error("a URL without a scheme should be rejected")
+	}
+}
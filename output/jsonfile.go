@@ -1,90 +1,403 @@
 // ABOUTME: JSON file output writer for transformed logs.
-// ABOUTME: Supports JSONL format with buffered writes and file rotation.
+// ABOUTME: Supports JSONL, or raw OTLP for replay, with buffered writes, size- or time-based file rotation, and retried failed writes.
 
 package output
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
+	"strings"
 	"sync"
 	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
 )
 
 // Format specifies the JSON output format
 type Format string
 
 const (
-	FormatJSONL Format = "jsonl" // Line-delimited JSON
-	FormatJSON  Format = "json"  // JSON array
+	FormatJSONL     Format = "jsonl"      // Line-delimited JSON
+	FormatJSON      Format = "json"       // JSON array
+	FormatOTLPJSON  Format = "otlp-json"  // Transformed records as OTLP JSON export requests, one per line
+	FormatOTLPProto Format = "otlp-proto" // Transformed records as protobuf export requests, each prefixed with its varint length
 )
 
+// ParseFormat checks an output format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatJSONL, FormatJSON, FormatOTLPJSON, FormatOTLPProto:
+		return f, nil
+	}
+	return "", fmt.Errorf("output format %q must be %s, %s, %s, or %s", s, FormatJSONL, FormatJSON, FormatOTLPJSON, FormatOTLPProto)
+}
+
+// IsOTLP reports whether the format writes records as OTLP rather than log entries
+func (f Format) IsOTLP() bool {
+	return f == FormatOTLPJSON || f == FormatOTLPProto
+}
+
 // RoutingInfo contains routing decision details
 type RoutingInfo struct {
-	Index string `json:"index"`
-	Rule  string `json:"rule"`
+	Index      string `json:"index"`
+	Rule       string `json:"rule"`
+	Sourcetype string `json:"sourcetype,omitempty"`
+	Source     string `json:"source,omitempty"`
 }
 
-// LogEntry represents a transformed log record for JSON output
+// TransformInfo describes one transform applied to the record
+type TransformInfo struct {
+	Type   string `json:"type"`
+	Rule   string `json:"rule,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// LogEntry represents a transformed log record for JSON output. Attribute
+// values are strings, or with native types the values Value converts to.
+// Trace and span IDs are lowercase hex, as the collector logs them.
 type LogEntry struct {
-	Timestamp      string            `json:"timestamp"`
-	Severity       string            `json:"severity"`
-	SeverityNumber int32             `json:"severity_number"`
-	Body           string            `json:"body"`
-	Attributes     map[string]string `json:"attributes,omitempty"`
-	ResourceAttrs  map[string]string `json:"resource_attributes,omitempty"`
-	Routing        RoutingInfo       `json:"routing"`
-	Transforms     []string          `json:"transforms_applied,omitempty"`
+	Timestamp            string          `json:"timestamp"`
+	ObservedTimestamp    string          `json:"observed_timestamp,omitempty"`
+	Severity             string          `json:"severity"`
+	SeverityNumber       int32           `json:"severity_number"`
+	Body                 string          `json:"body"`
+	TraceID              string          `json:"trace_id,omitempty"`
+	SpanID               string          `json:"span_id,omitempty"`
+	Flags                uint32          `json:"flags,omitempty"`
+	Attributes           map[string]any  `json:"attributes,omitempty"`
+	DroppedAttributes    uint32          `json:"dropped_attributes_count,omitempty"`
+	ResourceAttrs        map[string]any  `json:"resource_attributes,omitempty"`
+	ResourceDroppedAttrs uint32          `json:"resource_dropped_attributes_count,omitempty"`
+	ScopeName            string          `json:"scope_name,omitempty"`
+	ScopeVersion         string          `json:"scope_version,omitempty"`
+	Routing              RoutingInfo     `json:"routing"`
+	Transforms           []TransformInfo `json:"transforms_applied,omitempty"`
 }
 
-// JSONWriter writes log entries to a JSON file with buffering and rotation
+// JSONWriter writes log entries to a JSON file with buffering and rotation,
+// or in the OTLP formats, the transformed records themselves
 type JSONWriter struct {
-	mu            sync.Mutex
-	path          string
-	format        Format
-	bufferSize    int
-	flushInterval time.Duration
-	maxFileSize   int64
+	mu             sync.Mutex
+	pattern        string // Path as configured, possibly a date template
+	path           string // The file being written: pattern expanded for its period
+	format         Format
+	bufferSize     int
+	flushInterval  time.Duration
+	maxFileSize    int64
+	rotateInterval time.Duration // Rotate at each period boundary if set
+	period         time.Time     // Start of the period the file was opened in
+	keep           int           // Rotated files to keep; 0 keeps all
+	compress       bool          // Gzip files as they are rotated
+	gzipLevel      int           // Gzip each flush as it is written, at this level, if the path ends in .gz
+	checkpointing  bool          // Record a checkpoint around each flush
+	checkpointed   string        // The path checkpoint is for; another path is recovered before its first flush
+	checkpoint     checkpoint
+	schema         *Schema // Reshapes each entry if set
+	metrics        *metrics.Metrics
+	onError        func(error)  // Told of each write or rotation error; defaults to logging
+	onRotate       func(string) // Told of each finished file, after compression
+	now            func() time.Time
+
+	rotations sync.WaitGroup // Rotated files the rotation worker has yet to finish
+	queueMu   sync.Mutex     // Guards queue; taken after mu, never before
+	queue     []rotation     // Rotated files for the worker, oldest first; the worker runs while non-empty
 
-	buffer []*LogEntry
-	file   *os.File
-	stop   chan struct{}
-	done   chan struct{}
+	buffer   []bufferedRecord
+	buffered int          // Buffer length last added to otlp_receiver_output_buffer_length
+	zw       *gzip.Writer // Reused for each flush's gzip member
+	file     *os.File     // Nil after a failed open, until a flush reopens it
+	failures int          // Consecutive failed flushes
+	retryAt  time.Time    // No flush is attempted before this after a failure
+	stop     chan struct{}
+	done     chan struct{}
 }
 
-// NewJSONWriter creates a new JSON file writer
+// Operations whose errors are counted by otlp_receiver_output_write_errors_total
+const (
+	opOpen       = "open"
+	opWrite      = "write"
+	opSync       = "sync"
+	opRotate     = "rotate"
+	opMarshal    = "marshal"
+	opCheckpoint = "checkpoint"
+	opSend       = "send" // A forwarding output's batch
+)
+
+// fileSink labels the file output's metrics
+const fileSink = "file"
+
+// gzipSuffix on an output path gzips the file as it is written
+const gzipSuffix = ".gz"
+
+// Backoff between flush attempts after failures, doubling up to the maximum
+const (
+	retryBackoff    = time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// minMaxBuffered is the fewest entries held while writes fail before the
+// oldest are discarded; larger buffers hold 100 flushes' worth
+const minMaxBuffered = 10000
+
+func init() {
+	RegisterSink("file", openFile)
+}
+
+// openFile opens a JSON file sink from a URI like
+// file:///var/log/otlp/logs-%Y%m%d.jsonl?keep=7&compress, a relative path
+// like file:logs.jsonl, or file:- for stdout. A path with {severity} or
+// {index} is split across files, and shards=N writes N files round-robin.
+func openFile(u *url.URL) (Sink, error) {
+	path := u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+	if u.Host != "" || path == "" {
+		return nil, fmt.Errorf("want file:///absolute/path, file:relative/path, or file:-")
+	}
+	q := newSinkQuery(u)
+	bufferSize := q.int("buffer-size", 100)
+	flushInterval := q.duration("flush-interval", 5*time.Second)
+	keep := q.int("keep", 5)
+	compress := q.bool("compress")
+	checkpoint := q.bool("checkpoint")
+	shards := q.int("shards", 1)
+	gzipLevel := q.int("gzip-level", gzip.DefaultCompression)
+	rotate := q.string("rotate-interval", "")
+	schemaName := q.string("schema", "")
+	format, formatErr := ParseFormat(q.string("format", string(FormatJSONL)))
+	if err := q.done(); err != nil {
+		return nil, err
+	}
+	if formatErr != nil {
+		return nil, formatErr
+	}
+	if keep < 0 {
+		return nil, fmt.Errorf("keep must not be negative")
+	}
+	if shards < 1 {
+		return nil, fmt.Errorf("shards must be at least 1")
+	}
+	if gzipLevel != gzip.DefaultCompression && (gzipLevel < gzip.BestSpeed || gzipLevel > gzip.BestCompression) {
+		return nil, fmt.Errorf("gzip-level=%d: want %d to %d", gzipLevel, gzip.BestSpeed, gzip.BestCompression)
+	}
+	if format.IsOTLP() && schemaName != "" {
+		return nil, fmt.Errorf("a schema reshapes log entries, so it cannot be used with format=%s", format)
+	}
+	if path == Stdout && rotate != "" {
+		return nil, fmt.Errorf("stdout cannot be rotated")
+	}
+	var rotateInterval time.Duration
+	if rotate != "" {
+		var err error
+		if rotateInterval, err = ParseRotateInterval(rotate); err != nil {
+			return nil, err
+		}
+	}
+	var schema *Schema
+	if schemaName != "" {
+		var err error
+		if schema, err = LoadSchema(schemaName); err != nil {
+			return nil, err
+		}
+	}
+
+	open := func(path string) (*JSONWriter, error) {
+		w, err := NewJSONWriter(path, format, bufferSize, flushInterval, 100*1024*1024)
+		if err != nil {
+			return nil, err
+		}
+		if rotateInterval > 0 {
+			w.SetRotateInterval(rotateInterval)
+		}
+		w.SetRetention(keep, compress)
+		if gzipLevel != gzip.DefaultCompression {
+			w.SetGzipLevel(gzipLevel) // Checked above
+		}
+		if schema != nil {
+			w.SetSchema(schema)
+		}
+		w.SetCheckpoint(checkpoint)
+		return w, nil
+	}
+	if shards > 1 {
+		return NewShardWriter(path, shards, format, open)
+	}
+	if IsSplitPath(path) {
+		return NewSplitWriter(path, format, open), nil
+	}
+	return open(path)
+}
+
+// Stdout as the path streams entries to standard output instead of a file
+const Stdout = "-"
+
+// NewJSONWriter creates a new JSON file writer. The path may be a template
+// like logs-%Y%m%d-%H.jsonl, in which case a new file is started whenever
+// the expanded name changes, or Stdout, which is never rotated. A path
+// ending in .gz is gzipped as it is written.
 func NewJSONWriter(path string, format Format, bufferSize int, flushInterval time.Duration, maxFileSize int64) (*JSONWriter, error) {
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	now := time.Now()
+	current := ExpandPath(path, now)
+	file, err := openOutput(current)
 	if err != nil {
 		return nil, err
 	}
 
 	w := &JSONWriter{
-		path:          path,
+		pattern:       path,
+		path:          current,
 		format:        format,
 		bufferSize:    bufferSize,
 		flushInterval: flushInterval,
 		maxFileSize:   maxFileSize,
-		buffer:        make([]*LogEntry, 0, bufferSize),
+		period:        now,
+		now:           time.Now,
+		gzipLevel:     gzip.NoCompression,
+		buffer:        make([]bufferedRecord, 0, bufferSize),
 		file:          file,
 		stop:          make(chan struct{}),
 		done:          make(chan struct{}),
 	}
 
+	if strings.HasSuffix(path, gzipSuffix) {
+		w.gzipLevel = gzip.DefaultCompression
+	}
+
 	go w.flushLoop()
 
 	return w, nil
 }
 
-// Write adds a log entry to the buffer
-func (w *JSONWriter) Write(entry *LogEntry) {
+// SetGzipLevel sets the compression level, from 1 (fastest) to 9
+// (smallest), of a path ending in .gz
+func (w *JSONWriter) SetGzipLevel(level int) error {
+	if level < gzip.BestSpeed || level > gzip.BestCompression {
+		return fmt.Errorf("gzip level %d: want %d to %d", level, gzip.BestSpeed, gzip.BestCompression)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.gzipLevel != gzip.NoCompression {
+		w.gzipLevel, w.zw = level, nil
+	}
+	return nil
+}
+
+// gzipped reports whether the file is gzipped as it is written
+func (w *JSONWriter) gzipped() bool {
+	return w.gzipLevel != gzip.NoCompression
+}
+
+// bufferedRecord is a log entry, or in the OTLP formats a transformed record
+type bufferedRecord struct {
+	entry *LogEntry
+	otlp  otlpRecord
+}
+
+// Write adds a log entry to the buffer. While writes fail, as on a full
+// disk, entries are held for retry, discarding the oldest beyond a limit.
+func (w *JSONWriter) Write(entry *LogEntry) error {
+	if w.format.IsOTLP() {
+		return errRecordsOnly
+	}
+	w.add(bufferedRecord{entry: entry})
+	return nil
+}
+
+// TakesRecords reports whether the writer takes records, as in the OTLP formats
+func (w *JSONWriter) TakesRecords() bool {
+	return w.format.IsOTLP()
+}
+
+// WriteRecord adds a record to the buffer in the OTLP formats. The record
+// must not be changed afterwards.
+func (w *JSONWriter) WriteRecord(app string, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord) error {
+	if !w.format.IsOTLP() {
+		return fmt.Errorf("output format %s takes log entries, not OTLP records", w.format)
+	}
+	w.add(bufferedRecord{otlp: otlpRecord{resource, scope, lr}})
+	return nil
+}
+
+// add buffers an entry or record, flushing when the buffer is full
+func (w *JSONWriter) add(r bufferedRecord) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	w.buffer = append(w.buffer, entry)
+	if len(w.buffer) >= max(100*w.bufferSize, minMaxBuffered) {
+		w.buffer = w.buffer[1:]
+		if w.metrics != nil {
+			w.metrics.OutputDiscarded.Inc()
+		}
+	}
+	w.buffer = append(w.buffer, r)
 
 	if len(w.buffer) >= w.bufferSize {
 		w.flushLocked()
 	}
+	w.reportBuffered()
+}
+
+// Flush writes buffered entries now, whatever the backoff after a failed
+// write, and returns an error if any are still held
+func (w *JSONWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buffer) > 0 {
+		w.retryAt = time.Time{}
+		w.flushLocked()
+	}
+	if len(w.buffer) > 0 {
+		return fmt.Errorf("flushing %s: %d entries could not be written", w.path, len(w.buffer))
+	}
+	return nil
+}
+
+// SetErrorHandler calls fn for every write, rotation, or reopen error
+// instead of logging it: with the writer locked, or from the rotation worker
+// for compression errors
+func (w *JSONWriter) SetErrorHandler(fn func(error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = fn
+}
+
+// SetSchema writes each entry reshaped by s instead of as it is
+func (w *JSONWriter) SetSchema(s *Schema) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.schema = s
+}
+
+// Path returns the file currently being written
+func (w *JSONWriter) Path() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.path
+}
+
+// Pending returns the number of buffered entries not yet written, by routing index
+func (w *JSONWriter) Pending() map[string]int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	pending := make(map[string]int64)
+	for _, r := range w.buffer {
+		if r.entry != nil {
+			pending[r.entry.Routing.Index]++
+		}
+	}
+	return pending
 }
 
 // Close flushes remaining entries and closes the file
@@ -96,9 +409,16 @@ func (w *JSONWriter) Close() error {
 	defer w.mu.Unlock()
 
 	if len(w.buffer) > 0 {
+		w.retryAt = time.Time{} // One last attempt, whatever the backoff
 		w.flushLocked()
 	}
-
+	w.rotations.Wait()
+	if len(w.buffer) > 0 {
+		return fmt.Errorf("closing %s: %d entries could not be written", w.path, len(w.buffer))
+	}
+	if w.file == nil || w.file == os.Stdout {
+		return nil
+	}
 	return w.file.Close()
 }
 
@@ -123,47 +443,183 @@ func (w *JSONWriter) flushLoop() {
 	}
 }
 
-// flushLocked writes buffered entries to file. Caller must hold mu.
+// flushLocked writes buffered entries to file. If that fails, they stay
+// buffered and the flush is retried with backoff. Caller must hold mu.
 func (w *JSONWriter) flushLocked() {
-	if len(w.buffer) == 0 {
+	if len(w.buffer) == 0 || w.now().Before(w.retryAt) {
 		return
 	}
+	defer w.timeFlush(time.Now())
 
-	// Check for rotation before writing
+	// Check for rotation before writing, then open the new file, or reopen
+	// one that failed
 	w.rotateIfNeeded()
+	if w.checkpointing && w.checkpointed != w.path {
+		w.recoverFile()
+	}
+	if w.file == nil {
+		if w.open(); w.file == nil {
+			w.backoff()
+			return
+		}
+	}
+
+	data := w.encode()
+	if w.gzipped() {
+		var err error
+		if data, err = w.gzipMember(data); err != nil {
+			w.report(opMarshal, err)
+			w.backoff()
+			return
+		}
+	}
+	if err := w.writeAll(data, len(w.buffer)); err != nil {
+		// Reopen on retry, in case the file or its volume went away
+		w.report(opWrite, err)
+		w.closeFile()
+		w.backoff()
+		return
+	}
+	if w.path != Stdout { // Pipes and terminals cannot be synced
+		if err := w.file.Sync(); err != nil {
+			w.report(opSync, err) // Written, if not yet durable, so not retried
+		}
+		w.endFlush()
+	}
+
+	if w.failures > 0 {
+		slog.Info("Output: writing again", "path", w.path, "failed_flushes", w.failures)
+	}
+	if w.metrics != nil {
+		w.metrics.OutputEntries.WithLabelValues(fileSink).Add(float64(len(w.buffer)))
+		w.metrics.OutputBytes.WithLabelValues(fileSink).Add(float64(len(data)))
+	}
+	w.failures, w.retryAt = 0, time.Time{}
+	w.buffer = w.buffer[:0]
+	w.reportBuffered()
+}
+
+// timeFlush counts a flush attempted since start. Caller must hold mu.
+func (w *JSONWriter) timeFlush(start time.Time) {
+	if w.metrics != nil {
+		w.metrics.OutputFlushes.WithLabelValues(fileSink).Inc()
+		w.metrics.OutputFlushDuration.WithLabelValues(fileSink).Observe(time.Since(start).Seconds())
+	}
+}
 
-	for _, entry := range w.buffer {
-		data, err := json.Marshal(entry)
+// reportBuffered moves the buffer length gauge by the change since it was
+// last reported, so files sharing a configured path add up. Caller must hold mu.
+func (w *JSONWriter) reportBuffered() {
+	if w.metrics == nil {
+		return
+	}
+	w.metrics.OutputBuffered.WithLabelValues(w.pattern).Add(float64(len(w.buffer) - w.buffered))
+	w.buffered = len(w.buffer)
+}
+
+// encode renders the buffer as JSON lines, or in the OTLP formats as one
+// export request. Entries that cannot be encoded are reported and skipped.
+func (w *JSONWriter) encode() []byte {
+	if w.format.IsOTLP() {
+		batch := make([]otlpRecord, len(w.buffer))
+		for i, r := range w.buffer {
+			batch[i] = r.otlp
+		}
+		data, err := encodeOTLP(w.format, batch)
 		if err != nil {
+			w.report(opMarshal, err)
+		}
+		return data
+	}
+
+	var data []byte
+	for _, r := range w.buffer {
+		var doc any = r.entry
+		if w.schema != nil {
+			doc = w.schema.Apply(r.entry)
+		}
+		line, err := json.Marshal(doc)
+		if err != nil {
+			w.report(opMarshal, err)
 			continue
 		}
-		w.file.Write(data)
-		w.file.Write([]byte("\n"))
+		data = append(data, line...)
+		data = append(data, '\n')
 	}
+	return data
+}
 
-	w.file.Sync()
-	w.buffer = w.buffer[:0]
+// gzipMember compresses data as one complete gzip member. Members appended
+// one after another are a valid gzip file, so the file can be read after
+// every flush, and a failed write cut back, without a stream to finish.
+func (w *JSONWriter) gzipMember(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	if w.zw == nil {
+		zw, err := gzip.NewWriterLevel(&b, w.gzipLevel)
+		if err != nil {
+			return nil, err
+		}
+		w.zw = zw
+	} else {
+		w.zw.Reset(&b)
+	}
+	if _, err := w.zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
 }
 
-// rotateIfNeeded rotates the log file if it exceeds maxFileSize
-func (w *JSONWriter) rotateIfNeeded() {
+// writeAll appends data holding n entries to the file, cutting off anything
+// a failed write left so the file never ends in half a line
+func (w *JSONWriter) writeAll(data []byte, n int) error {
+	if w.path == Stdout {
+		_, err := w.file.Write(data)
+		return err
+	}
 	info, err := w.file.Stat()
 	if err != nil {
-		return
+		return err
+	}
+	w.beginFlush(n, info.Size(), info.Size()+int64(len(data)))
+	if _, err := w.file.Write(data); err != nil {
+		w.file.Truncate(info.Size())
+		return err
 	}
+	return nil
+}
 
-	if info.Size() < w.maxFileSize {
-		return
+// open opens the current path, leaving file nil and reporting why if it cannot
+func (w *JSONWriter) open() {
+	file, err := openOutput(w.path)
+	if err != nil {
+		w.report(opOpen, err)
 	}
+	w.file = file
+}
 
-	// Close current file
-	w.file.Close()
+// backoff delays the next flush after a failure, doubling each time
+func (w *JSONWriter) backoff() {
+	w.failures++
+	delay := min(retryBackoff<<min(w.failures-1, 10), maxRetryBackoff)
+	w.retryAt = w.now().Add(delay)
+}
 
-	// Rotate: rename current to .1
-	rotatedPath := w.path + ".1"
-	os.Remove(rotatedPath) // Remove old rotated file if exists
-	os.Rename(w.path, rotatedPath)
+// report counts an error and passes it to the error handler, or logs it
+func (w *JSONWriter) report(op string, err error) {
+	reportError(w.metrics, w.onError, op, err)
+}
 
-	// Open new file
-	w.file, _ = os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+// reportError counts an error in m, if set, and passes it to onError, or logs it
+func reportError(m *metrics.Metrics, onError func(error), op string, err error) {
+	if m != nil {
+		m.OutputWriteErrors.WithLabelValues(fileSink, op).Inc()
+	}
+	if onError != nil {
+		onError(err)
+		return
+	}
+	slog.Error("Output: file operation failed", "op", op, "err", err)
 }
@@ -1,15 +1,22 @@
 // ABOUTME: Tests for JSON file output writer.
-// ABOUTME: Covers JSON serialization, buffering, flushing, and file rotation.
+// ABOUTME: Covers JSON serialization, buffering, flushing, file rotation, gzip, schemas, and retrying failed writes.
 
 package output
 
 import (
 	"bufio"
+	"compress/gzip"
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-mock-receiver/metrics"
 )
 
 func TestLogEntry_JSONSerialization(t *testing.T) {
@@ -18,10 +25,10 @@ func TestLogEntry_JSONSerialization(t *testing.T) {
 		Severity:       "INFO",
 		SeverityNumber: 9,
 		Body:           "test message",
-		Attributes:     map[string]string{"key": "value"},
-		ResourceAttrs:  map[string]string{"app_name": "my-app"},
+		Attributes:     map[string]any{"key": "value"},
+		ResourceAttrs:  map[string]any{"app_name": "my-app"},
 		Routing:        RoutingInfo{Index: "tas_logs", Rule: "default"},
-		Transforms:     []string{"Renamed: application_name -> cf_app_name"},
+		Transforms:     []TransformInfo{{Type: "rename", Rule: "application_name", Detail: "Renamed: application_name -> cf_app_name"}},
 	}
 
 	data, err := json.Marshal(entry)
@@ -44,6 +51,9 @@ func TestLogEntry_JSONSerialization(t *testing.T) {
 	if decoded.Routing.Index != "tas_logs" {
 		t.Errorf("Routing.Index = %q, want %q", decoded.Routing.Index, "tas_logs")
 	}
+	if len(decoded.Transforms) != 1 || decoded.Transforms[0].Rule != "application_name" {
+		t.Errorf("Transforms = %+v, want the rename", decoded.Transforms)
+	}
 }
 
 func TestJSONLWriter_WritesOneJSONPerLine(t *testing.T) {
@@ -174,6 +184,73 @@ func TestJSONWriter_RotatesAtSizeThreshold(t *testing.T) {
 	}
 }
 
+// gunzipLines reads a gzipped JSONL file's lines, failing if it is not a complete gzip file
+func gunzipLines(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gunzip %s: %v", path, err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("gunzip %s: %v", path, err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestJSONWriter_GzipsAsItWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl.gz")
+	w, err := NewJSONWriter(path, FormatJSONL, 2, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	if err := w.SetGzipLevel(9); err != nil {
+		t.Fatalf("SetGzipLevel failed: %v", err)
+	}
+
+	// Each flush leaves a complete gzip file, readable while writing goes on
+	w.Write(&LogEntry{Body: "one"})
+	w.Write(&LogEntry{Body: "two"})
+	if lines := gunzipLines(t, path); len(lines) != 2 {
+		t.Fatalf("after one flush got %d lines, want 2", len(lines))
+	}
+	w.Write(&LogEntry{Body: "three"})
+	w.Flush()
+	lines := gunzipLines(t, path)
+	if len(lines) != 3 || !strings.Contains(lines[2], `"body":"three"`) {
+		t.Errorf("after two flushes got %q, want three entries", lines)
+	}
+
+	if err := w.SetGzipLevel(0); err == nil {
+		t.Error("SetGzipLevel(0) should fail")
+	}
+}
+
+func TestJSONWriter_GzipRotationIsNotCompressedTwice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl.gz")
+	w, err := NewJSONWriter(path, FormatJSONL, 1, time.Hour, 10)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	w.SetRetention(2, true)
+
+	w.Write(&LogEntry{Body: "one"})
+	w.Write(&LogEntry{Body: "two"})
+	if lines := gunzipLines(t, path+".1"); len(lines) != 1 || !strings.Contains(lines[0], `"body":"one"`) {
+		t.Errorf("rotated file = %q, want the first entry", lines)
+	}
+	if _, err := os.Stat(path + ".1.gz"); !os.IsNotExist(err) {
+		t.Error("a gzipped file should not be compressed again when rotated")
+	}
+}
+
 func TestJSONWriter_GracefulShutdownFlushesBuffer(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "logs.jsonl")
@@ -214,3 +291,253 @@ func TestJSONWriter_GracefulShutdownFlushesBuffer(t *testing.T) {
 		t.Errorf("Expected 2 lines, got %d", lines)
 	}
 }
+
+func TestJSONWriter_StreamsToStdout(t *testing.T) {
+	r, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	stdout := os.Stdout
+	os.Stdout = pw
+	defer func() { os.Stdout = stdout }()
+
+	// A one-byte size limit would rotate a file on every flush
+	w, err := NewJSONWriter(Stdout, FormatJSONL, 1, time.Hour, 1)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	w.Write(&LogEntry{Body: "msg1"})
+	w.Write(&LogEntry{Body: "msg2"})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Closing the writer leaves stdout open
+	if _, err := pw.Write([]byte("{\"body\":\"after\"}\n")); err != nil {
+		t.Fatalf("stdout was closed: %v", err)
+	}
+	pw.Close()
+
+	var bodies []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var entry LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		bodies = append(bodies, entry.Body)
+	}
+	if len(bodies) != 3 || bodies[0] != "msg1" || bodies[1] != "msg2" {
+		t.Errorf("bodies = %v, want msg1, msg2, after", bodies)
+	}
+	if _, err := os.Stat(Stdout); !os.IsNotExist(err) {
+		t.Errorf("a file named %s was created", Stdout)
+	}
+}
+
+func TestJSONWriter_WritesThroughSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hec.jsonl")
+	w, err := NewJSONWriter(path, FormatJSONL, 10, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	schema, _ := LoadSchema(SchemaHECEvent)
+	w.SetSchema(schema)
+	w.Write(&LogEntry{Timestamp: "2024-01-15T10:30:00Z", Severity: "INFO", Body: "msg1", Routing: RoutingInfo{Index: "tas_logs", Rule: "default"}})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if got := string(data); got != `{"event":"msg1","fields":{"severity":"INFO"},"index":"tas_logs","time":1705314600}`+"\n" {
+		t.Errorf("file = %q, want the entry as a HEC event", got)
+	}
+}
+
+func TestJSONWriter_PendingByIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	w, err := NewJSONWriter(path, FormatJSONL, 3, 1*time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	w.Write(&LogEntry{Body: "a", Routing: RoutingInfo{Index: "tas_logs"}})
+	w.Write(&LogEntry{Body: "b", Routing: RoutingInfo{Index: "tas_errors"}})
+	if p := w.Pending(); p["tas_logs"] != 1 || p["tas_errors"] != 1 {
+		t.Errorf("Pending = %v, want one per index", p)
+	}
+
+	w.Write(&LogEntry{Body: "c", Routing: RoutingInfo{Index: "tas_logs"}})
+	if p := w.Pending(); len(p) != 0 {
+		t.Errorf("Pending after flush = %v, want empty", p)
+	}
+}
+
+func TestJSONWriter_RetriesFailedWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	w, err := NewJSONWriter(path, FormatJSONL, 1, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	m := metrics.New()
+	w.SetMetrics(m)
+	var errs []error
+	w.SetErrorHandler(func(err error) { errs = append(errs, err) })
+	now, clock := fakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local))
+	w.now = clock
+
+	// A read-only handle fails every write, as a full disk would
+	readOnly, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.mu.Lock()
+	w.file.Close()
+	w.file = readOnly
+	w.mu.Unlock()
+
+	w.Write(&LogEntry{Body: "one", Routing: RoutingInfo{Index: "tas_logs"}})
+	w.Write(&LogEntry{Body: "two", Routing: RoutingInfo{Index: "tas_logs"}})
+	if got := w.Pending()["tas_logs"]; got != 2 {
+		t.Errorf("Pending = %d, want both entries held for retry", got)
+	}
+	if len(errs) != 1 || testutil.ToFloat64(m.OutputWriteErrors.WithLabelValues("file", "write")) != 1 {
+		t.Errorf("errors = %v, want one failed write, then backing off", errs)
+	}
+
+	// After the backoff the file is reopened and everything is written
+	*now = now.Add(2 * time.Second)
+	w.Write(&LogEntry{Body: "three", Routing: RoutingInfo{Index: "tas_logs"}})
+	if got := w.Pending(); len(got) != 0 {
+		t.Errorf("Pending after recovery = %v, want empty", got)
+	}
+	if got := countLines(t, path); got != 3 {
+		t.Errorf("file has %d lines, want 3", got)
+	}
+}
+
+func TestJSONWriter_SurvivesFailedReopen(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewJSONWriter(filepath.Join(dir, "%H", "logs.jsonl"), FormatJSONL, 1, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	m := metrics.New()
+	w.SetMetrics(m)
+	w.SetErrorHandler(func(error) {})
+	now, clock := fakeClock(time.Date(2024, 1, 15, 15, 0, 0, 0, time.Local))
+	w.now = clock
+
+	// A file where the next hour's directory should be
+	if err := os.WriteFile(filepath.Join(dir, "15"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.Write(&LogEntry{Body: "held"})
+	if got := testutil.ToFloat64(m.OutputWriteErrors.WithLabelValues("file", "open")); got != 1 {
+		t.Errorf("open errors = %v, want 1", got)
+	}
+
+	// Once the way is clear, the held entry is written
+	os.Remove(filepath.Join(dir, "15"))
+	*now = now.Add(2 * time.Second)
+	w.Write(&LogEntry{Body: "next"})
+	if err := w.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if got := countLines(t, filepath.Join(dir, "15", "logs.jsonl")); got != 2 {
+		t.Errorf("file has %d lines, want 2", got)
+	}
+}
+
+func TestJSONWriter_DiscardsOldestWhenFull(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	w, err := NewJSONWriter(path, FormatJSONL, 1, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	m := metrics.New()
+	w.SetMetrics(m)
+	w.SetErrorHandler(func(error) {})
+	w.now = func() time.Time { return time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local) }
+
+	w.mu.Lock()
+	w.retryAt = w.now().Add(time.Hour) // Still backing off from a failure
+	w.mu.Unlock()
+	for range minMaxBuffered + 5 {
+		w.Write(&LogEntry{Body: "held"})
+	}
+	if got := w.Pending()[""]; got != minMaxBuffered {
+		t.Errorf("Pending = %d, want the buffer capped at %d", got, minMaxBuffered)
+	}
+	if got := testutil.ToFloat64(m.OutputDiscarded); got != 5 {
+		t.Errorf("discarded = %v, want 5", got)
+	}
+	if got := testutil.ToFloat64(m.OutputBuffered.WithLabelValues(path)); got != minMaxBuffered {
+		t.Errorf("buffer length = %v, want %d", got, minMaxBuffered)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestJSONWriter_ReportsBufferLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	w, err := NewJSONWriter(path, FormatJSONL, 10, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	m := metrics.New()
+	w.SetMetrics(m)
+
+	for range 3 {
+		w.Write(&LogEntry{Body: "held"})
+	}
+	if got := testutil.ToFloat64(m.OutputBuffered.WithLabelValues(path)); got != 3 {
+		t.Errorf("buffer length = %v, want 3", got)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if got := testutil.ToFloat64(m.OutputBuffered.WithLabelValues(path)); got != 0 {
+		t.Errorf("buffer length after Flush = %v, want 0", got)
+	}
+}
+
+func TestJSONWriter_CountsFlushes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	w, err := NewJSONWriter(path, FormatJSONL, 10, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	m := metrics.New()
+	w.SetMetrics(m)
+
+	for range 3 {
+		w.Write(&LogEntry{Body: "counted"})
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+
+	if got := testutil.ToFloat64(m.OutputFlushes.WithLabelValues("file")); got != 1 {
+		t.Errorf("flushes = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.OutputEntries.WithLabelValues("file")); got != 3 {
+		t.Errorf("entries written = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(m.OutputBytes.WithLabelValues("file")); got != float64(info.Size()) {
+		t.Errorf("bytes written = %v, want the file's %d", got, info.Size())
+	}
+	if got := testutil.CollectAndCount(m.OutputFlushDuration); got != 1 {
+		t.Errorf("flush duration has %d series, want 1", got)
+	}
+}
@@ -0,0 +1,288 @@
+// ABOUTME: Kafka producer output that publishes transformed records to a topic, keyed by app name.
+// ABOUTME: Values are JSON entries or OTLP protobuf; batches are retried with backoff on transient broker errors.
+
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// Kafka message value formats
+const (
+	KafkaFormatJSON = "json" // The JSON output's entry
+	KafkaFormatOTLP = "otlp" // An ExportLogsServiceRequest holding the one record
+)
+
+// KafkaConfig configures publishing to a Kafka topic
+type KafkaConfig struct {
+	Brokers       []string      // host:port of each bootstrap broker
+	Topic         string        // Created on first use if the brokers allow it
+	Format        string        // json or otlp
+	Acks          string        // none, leader, or all
+	BatchSize     int           // Messages per produce
+	FlushInterval time.Duration // Longest a partial batch waits
+	MaxRetries    int           // Attempts after the first before messages are dropped
+}
+
+// messageWriter is the part of kafka.Writer the output uses, so tests can
+// stand in for a broker
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaWriter batches records and publishes them to a Kafka topic from a
+// background goroutine. Each message is keyed by the record's app, so an
+// app's records stay in order on one partition.
+type KafkaWriter struct {
+	*batcher[kafka.Message]
+	cfg    KafkaConfig
+	writer messageWriter
+
+	mu      sync.Mutex
+	metrics *metrics.Metrics
+}
+
+// ParseKafkaAcks parses the acknowledgment a produce waits for: none,
+// leader, or all in-sync replicas
+func ParseKafkaAcks(s string) (kafka.RequiredAcks, error) {
+	switch s {
+	case "none", "0":
+		return kafka.RequireNone, nil
+	case "leader", "1":
+		return kafka.RequireOne, nil
+	case "all", "-1":
+		return kafka.RequireAll, nil
+	}
+	return 0, fmt.Errorf("kafka acks %q: want none, leader, or all", s)
+}
+
+// ParseBrokers splits a comma-separated broker list
+func ParseBrokers(s string) []string {
+	var brokers []string
+	for _, b := range strings.Split(s, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	return brokers
+}
+
+func init() {
+	RegisterSink("kafka", openKafka)
+}
+
+// openKafka opens a Kafka sink from a URI like
+// kafka://broker1:9092,broker2:9092/otlp-logs?format=otlp
+func openKafka(u *url.URL) (Sink, error) {
+	q := newSinkQuery(u)
+	cfg := KafkaConfig{
+		Brokers:       ParseBrokers(u.Host),
+		Topic:         strings.TrimPrefix(u.Path, "/"),
+		Format:        q.string("format", KafkaFormatJSON),
+		Acks:          q.string("acks", "all"),
+		BatchSize:     q.int("batch-size", 100),
+		FlushInterval: q.duration("flush-interval", 5*time.Second),
+		MaxRetries:    q.int("max-retries", defaultMaxRetries),
+	}
+	if err := q.done(); err != nil {
+		return nil, err
+	}
+	return NewKafkaWriter(cfg)
+}
+
+// NewKafkaWriter validates cfg and starts publishing. Brokers are not
+// contacted until the first batch is sent.
+func NewKafkaWriter(cfg KafkaConfig) (*KafkaWriter, error) {
+	if len(cfg.Brokers) == 0 || cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka brokers and topic are required")
+	}
+	switch cfg.Format {
+	case "":
+		cfg.Format = KafkaFormatJSON
+	case KafkaFormatJSON, KafkaFormatOTLP:
+	default:
+		return nil, fmt.Errorf("kafka format %q must be json or otlp", cfg.Format)
+	}
+	if cfg.Acks == "" {
+		cfg.Acks = "all"
+	}
+	acks, err := ParseKafkaAcks(cfg.Acks)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+
+	return newKafkaWriter(cfg, &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Brokers...),
+		Topic:                  cfg.Topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           acks,
+		BatchSize:              cfg.BatchSize,
+		BatchTimeout:           time.Millisecond, // Batches are already gathered
+		MaxAttempts:            1,                // Retries are the batcher's
+		AllowAutoTopicCreation: true,
+	}), nil
+}
+
+// newKafkaWriter starts publishing through writer
+func newKafkaWriter(cfg KafkaConfig, writer messageWriter) *KafkaWriter {
+	w := &KafkaWriter{cfg: cfg, writer: writer}
+	w.batcher = newBatcher("Kafka", cfg.BatchSize, cfg.FlushInterval, cfg.MaxRetries, w.send, w.count)
+	return w
+}
+
+// Format returns the message value format, json or otlp
+func (w *KafkaWriter) Format() string {
+	return w.cfg.Format
+}
+
+// TakesRecords reports whether messages are OTLP, so records should be
+// written with WriteRecord rather than Write
+func (w *KafkaWriter) TakesRecords() bool {
+	return w.cfg.Format == KafkaFormatOTLP
+}
+
+// SetMetrics counts published, failed, and discarded messages in m, and reports those waiting to be sent
+func (w *KafkaWriter) SetMetrics(m *metrics.Metrics) {
+	w.batcher.setMetrics(m, "kafka")
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.metrics = m
+}
+
+// Write queues a log entry as a JSON message keyed by its app, for the json format
+func (w *KafkaWriter) Write(entry *LogEntry) error {
+	if w.TakesRecords() {
+		return errRecordsOnly
+	}
+	value, err := json.Marshal(entry)
+	if err != nil {
+		w.count(resultFailed, 1)
+		return fmt.Errorf("Kafka: failed to marshal entry: %w", err)
+	}
+	w.add(kafka.Message{Key: []byte(entryApp(entry)), Value: value})
+	return nil
+}
+
+// WriteRecord queues a record as an OTLP protobuf message keyed by app, for
+// the otlp format. The record is encoded at once, so it may change afterwards.
+func (w *KafkaWriter) WriteRecord(app string, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord) error {
+	value, err := proto.Marshal(exportRequest([]otlpRecord{{resource, scope, lr}}))
+	if err != nil {
+		w.count(resultFailed, 1)
+		return fmt.Errorf("Kafka: failed to marshal record: %w", err)
+	}
+	w.add(kafka.Message{Key: []byte(app), Value: value})
+	return nil
+}
+
+// Flush publishes what is queued now, with at most one attempt per batch
+func (w *KafkaWriter) Flush() error {
+	return w.flush()
+}
+
+// Close publishes what is queued, with at most one attempt per batch, and closes the producer
+func (w *KafkaWriter) Close() error {
+	err := w.close()
+	w.writer.Close()
+	return err
+}
+
+// send produces one batch. If only some messages fail with a transient
+// error, they are moved to the front of the batch to be sent again.
+func (w *KafkaWriter) send(batch []kafka.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := w.writer.WriteMessages(ctx, batch...)
+	if err == nil {
+		w.count(resultSent, len(batch))
+		w.sent(len(batch), messageBytes(batch))
+		return nil
+	}
+
+	var perMessage kafka.WriteErrors
+	if !errors.As(err, &perMessage) || len(perMessage) != len(batch) {
+		if transient(err) {
+			return retryableError{err: fmt.Errorf("produce to %s: %w", w.cfg.Topic, err)}
+		}
+		return fmt.Errorf("produce to %s: %w", w.cfg.Topic, err)
+	}
+	var sent, sentBytes, failed, retry int
+	var failedErr, retryErr error
+	for i, msgErr := range perMessage {
+		switch {
+		case msgErr == nil:
+			sent++
+			sentBytes += len(batch[i].Key) + len(batch[i].Value)
+		case transient(msgErr):
+			batch[retry] = batch[i]
+			retry++
+			retryErr = msgErr
+		default:
+			failed++
+			failedErr = msgErr
+		}
+	}
+	w.count(resultSent, sent)
+	w.sent(sent, sentBytes)
+	if failed > 0 {
+		slog.Error("Kafka: dropping messages", "messages", failed, "err", failedErr)
+		w.count(resultFailed, failed)
+	}
+	if retry > 0 {
+		return retryableError{err: fmt.Errorf("produce to %s: %d messages failed: %w", w.cfg.Topic, retry, retryErr), retry: retry}
+	}
+	return nil
+}
+
+// messageBytes returns the size of the messages' keys and values
+func messageBytes(batch []kafka.Message) int {
+	n := 0
+	for _, msg := range batch {
+		n += len(msg.Key) + len(msg.Value)
+	}
+	return n
+}
+
+// transient reports whether a produce error is worth retrying: the broker
+// says so, or it is not a broker error at all, as with a lost connection
+func transient(err error) bool {
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) {
+		return kafkaErr.Temporary()
+	}
+	return true
+}
+
+// count adds n messages to the result's metric
+func (w *KafkaWriter) count(result string, n int) {
+	w.mu.Lock()
+	m := w.metrics
+	w.mu.Unlock()
+	if m != nil && n > 0 {
+		m.KafkaMessages.WithLabelValues(result).Add(float64(n))
+	}
+}
@@ -0,0 +1,157 @@
+// ABOUTME: Tests for publishing records to Kafka through a fake producer.
+// ABOUTME: Covers app keys, JSON and OTLP values, acks parsing, and retrying only transiently failed messages.
+
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// fakeProducer records published messages, failing each write with the next of fail first
+type fakeProducer struct {
+	mu        sync.Mutex
+	published []kafka.Message
+	writes    int
+	fail      []func(msgs []kafka.Message) error
+}
+
+func (f *fakeProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes++
+	if len(f.fail) > 0 {
+		fail := f.fail[0]
+		f.fail = f.fail[1:]
+		if err := fail(msgs); err != nil {
+			if werrs, ok := err.(kafka.WriteErrors); ok {
+				for i, msg := range msgs {
+					if werrs[i] == nil {
+						f.published = append(f.published, msg)
+					}
+				}
+			}
+			return err
+		}
+	}
+	f.published = append(f.published, msgs...)
+	return nil
+}
+
+func (f *fakeProducer) Close() error { return nil }
+
+func (f *fakeProducer) messages() ([]kafka.Message, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]kafka.Message(nil), f.published...), f.writes
+}
+
+func newKafkaTest(t *testing.T, f *fakeProducer, cfg KafkaConfig) (*KafkaWriter, *metrics.Metrics) {
+	t.Helper()
+	w := newKafkaWriter(cfg, f)
+	w.backoff = time.Millisecond
+	m := metrics.New()
+	w.SetMetrics(m)
+	return w, m
+}
+
+func TestKafkaWriter_PublishesJSON(t *testing.T) {
+	f := &fakeProducer{}
+	w, m := newKafkaTest(t, f, KafkaConfig{Topic: "logs", Format: KafkaFormatJSON, BatchSize: 10, FlushInterval: time.Hour})
+
+	w.Write(&LogEntry{Body: "payment failed", ResourceAttrs: map[string]any{"application_name": "payment-api"}, Routing: RoutingInfo{Index: "tas_errors"}})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	msgs, _ := f.messages()
+	if len(msgs) != 1 || string(msgs[0].Key) != "payment-api" {
+		t.Fatalf("published %v, want one message keyed by app", msgs)
+	}
+	var entry LogEntry
+	if err := json.Unmarshal(msgs[0].Value, &entry); err != nil || entry.Body != "payment failed" || entry.Routing.Index != "tas_errors" {
+		t.Errorf("value = %s, %v", msgs[0].Value, err)
+	}
+	if got := testutil.ToFloat64(m.KafkaMessages.WithLabelValues("sent")); got != 1 {
+		t.Errorf("sent = %v, want 1", got)
+	}
+}
+
+func TestKafkaWriter_PublishesOTLP(t *testing.T) {
+	f := &fakeProducer{}
+	w, _ := newKafkaTest(t, f, KafkaConfig{Topic: "logs", Format: KafkaFormatOTLP, BatchSize: 10, FlushInterval: time.Hour})
+
+	lr := testRecord("over kafka")
+	w.WriteRecord("web", &resourcepb.Resource{}, nil, lr)
+	lr.Body = nil // Encoded already, so changes don't reach the message
+	w.Close()
+
+	msgs, _ := f.messages()
+	var req collogspb.ExportLogsServiceRequest
+	if len(msgs) != 1 || proto.Unmarshal(msgs[0].Value, &req) != nil {
+		t.Fatalf("published %v, want one OTLP message", msgs)
+	}
+	if body := req.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0].GetBody().GetStringValue(); body != "over kafka" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestKafkaWriter_RetriesTransientFailures(t *testing.T) {
+	f := &fakeProducer{fail: []func([]kafka.Message) error{
+		func([]kafka.Message) error { return errors.New("dial tcp: connection refused") },
+		func(msgs []kafka.Message) error {
+			// The first is published, the second hits a leader election, the third is too large
+			return kafka.WriteErrors{nil, kafka.LeaderNotAvailable, kafka.MessageSizeTooLarge}
+		},
+	}}
+	w, m := newKafkaTest(t, f, KafkaConfig{Topic: "logs", BatchSize: 3, FlushInterval: time.Hour, MaxRetries: 3})
+	defer w.Close()
+
+	for _, key := range []string{"one", "two", "three"} {
+		w.Write(&LogEntry{Body: key, Attributes: map[string]any{"cf_app_name": key}})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(m.KafkaMessages.WithLabelValues("sent")) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("transiently failed message was not retried")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	msgs, writes := f.messages()
+	if len(msgs) != 2 || string(msgs[0].Key) != "one" || string(msgs[1].Key) != "two" || writes != 3 {
+		t.Errorf("published %d messages in %d writes, want one and two in 3", len(msgs), writes)
+	}
+	if got := testutil.ToFloat64(m.KafkaMessages.WithLabelValues("failed")); got != 1 {
+		t.Errorf("failed = %v, want the oversized message dropped", got)
+	}
+}
+
+func TestNewKafkaWriter_Invalid(t *testing.T) {
+	for _, cfg := range []KafkaConfig{
+		{Topic: "logs"},
+		{Brokers: []string{"kafka:9092"}},
+		{Brokers: []string{"kafka:9092"}, Topic: "logs", Format: "avro"},
+		{Brokers: []string{"kafka:9092"}, Topic: "logs", Acks: "some"},
+	} {
+		if _, err := NewKafkaWriter(cfg); err == nil {
+			t.Errorf("NewKafkaWriter(%+v) should fail", cfg)
+		}
+	}
+	if got := ParseBrokers(" kafka-1:9092,,kafka-2:9092 "); len(got) != 2 || got[1] != "kafka-2:9092" {
+		t.Errorf("ParseBrokers = %v", got)
+	}
+}
@@ -0,0 +1,350 @@
+// ABOUTME: OTLP pass-through output that re-exports transformed records to a downstream endpoint.
+// ABOUTME: Sends batches over gRPC or HTTP protobuf, retrying failed batches with backoff.
+
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// OTLP export protocols
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// OTLPExportConfig configures re-exporting records to a downstream OTLP endpoint
+type OTLPExportConfig struct {
+	Endpoint          string            // host:port for gRPC; a URL for HTTP, with /v1/logs added if there is no path
+	Protocol          string            // grpc or http
+	Insecure          bool              // Plaintext gRPC, for collectors without TLS
+	SkipSSLValidation bool              // For labs with self-signed certificates
+	Headers           map[string]string // Sent with every export, e.g. for authentication
+	BatchSize         int               // Records per export
+	FlushInterval     time.Duration     // Longest a partial batch waits
+	MaxRetries        int               // Attempts after the first before a batch is dropped
+	Client            *http.Client      // For HTTP; defaults to a client with a 30 second timeout
+}
+
+// otlpRecord is a record queued for export with the resource and scope it was received under
+type otlpRecord struct {
+	resource *resourcepb.Resource
+	scope    *commonpb.InstrumentationScope
+	log      *logspb.LogRecord
+}
+
+// OTLPExporter batches transformed records and exports them to a downstream
+// OTLP endpoint from a background goroutine, so the mock can sit in front of
+// a real collector or backend
+type OTLPExporter struct {
+	*batcher[otlpRecord]
+	cfg      OTLPExportConfig
+	endpoint string
+	conn     *grpc.ClientConn // For gRPC
+	grpc     collogspb.LogsServiceClient
+	client   *http.Client // For HTTP
+
+	mu      sync.Mutex
+	metrics *metrics.Metrics
+}
+
+func init() {
+	RegisterSink("otlp", openOTLP)
+}
+
+// openOTLP opens an OTLP export sink from a URI like
+// otlp://collector:4317?insecure, or otlp://collector:4318?protocol=http,
+// with headers in OTLP_EXPORT_HEADERS
+func openOTLP(u *url.URL) (Sink, error) {
+	headers, err := ParseHeaders(os.Getenv("OTLP_EXPORT_HEADERS"))
+	if err != nil {
+		return nil, fmt.Errorf("OTLP_EXPORT_HEADERS: %w", err)
+	}
+	q := newSinkQuery(u)
+	cfg := OTLPExportConfig{
+		Endpoint:          u.Host,
+		Protocol:          q.string("protocol", ProtocolGRPC),
+		SkipSSLValidation: q.bool("skip-ssl-validation"),
+		Headers:           headers,
+		BatchSize:         q.int("batch-size", 100),
+		FlushInterval:     q.duration("flush-interval", 5*time.Second),
+		MaxRetries:        q.int("max-retries", defaultMaxRetries),
+	}
+	if cfg.Protocol == ProtocolHTTP {
+		cfg.Endpoint = baseURL(u, q)
+	} else {
+		cfg.Insecure = q.bool("insecure")
+	}
+	if err := q.done(); err != nil {
+		return nil, err
+	}
+	return NewOTLPExporter(cfg)
+}
+
+// NewOTLPExporter validates cfg and starts exporting
+func NewOTLPExporter(cfg OTLPExportConfig) (*OTLPExporter, error) {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+
+	e := &OTLPExporter{cfg: cfg}
+	switch cfg.Protocol {
+	case ProtocolGRPC, "":
+		if cfg.Endpoint == "" {
+			return nil, fmt.Errorf("OTLP export endpoint is required")
+		}
+		creds := insecure.NewCredentials()
+		if !cfg.Insecure {
+			creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: cfg.SkipSSLValidation})
+		}
+		conn, err := grpc.Dial(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("OTLP export endpoint %s: %w", cfg.Endpoint, err)
+		}
+		e.endpoint, e.conn, e.grpc = cfg.Endpoint, conn, collogspb.NewLogsServiceClient(conn)
+	case ProtocolHTTP:
+		endpoint, err := otlpHTTPEndpoint(cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		e.endpoint, e.client = endpoint, cfg.Client
+		if e.client == nil {
+			e.client = &http.Client{Timeout: 30 * time.Second}
+			if cfg.SkipSSLValidation {
+				e.client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("OTLP export protocol %q must be grpc or http", cfg.Protocol)
+	}
+
+	e.batcher = newBatcher("OTLP export", cfg.BatchSize, cfg.FlushInterval, cfg.MaxRetries, e.send, e.count)
+	return e, nil
+}
+
+// otlpHTTPEndpoint returns the logs endpoint for an OTLP/HTTP base URL
+func otlpHTTPEndpoint(base string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("OTLP export endpoint %q must be an http or https URL", base)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/logs"
+	}
+	return u.String(), nil
+}
+
+// ParseHeaders parses headers in the OTEL_EXPORTER_OTLP_HEADERS format:
+// comma-separated key=value pairs, with values URL-encoded
+func ParseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("header %q: want key=value", strings.TrimSpace(pair))
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", k, err)
+		}
+		headers[k] = value
+	}
+	return headers, nil
+}
+
+// Endpoint returns where records are exported
+func (e *OTLPExporter) Endpoint() string {
+	return e.endpoint
+}
+
+// SetMetrics counts exported, failed, and discarded records in m, and reports those waiting to be sent
+func (e *OTLPExporter) SetMetrics(m *metrics.Metrics) {
+	e.batcher.setMetrics(m, "otlp-export")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.metrics = m
+}
+
+// TakesRecords reports that the exporter takes records with WriteRecord
+func (e *OTLPExporter) TakesRecords() bool {
+	return true
+}
+
+// Write fails, as the exporter sends records as received, not log entries
+func (e *OTLPExporter) Write(entry *LogEntry) error {
+	return errRecordsOnly
+}
+
+// WriteRecord queues a record for the next batch. The exporter keeps the
+// record, which must not be changed afterwards; the resource and scope are only read.
+func (e *OTLPExporter) WriteRecord(app string, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord) error {
+	e.add(otlpRecord{resource, scope, lr})
+	return nil
+}
+
+// Flush exports what is queued now, with at most one attempt per batch
+func (e *OTLPExporter) Flush() error {
+	return e.flush()
+}
+
+// Close exports what is queued, with at most one attempt per batch, and closes the connection
+func (e *OTLPExporter) Close() error {
+	err := e.close()
+	if e.conn != nil {
+		e.conn.Close()
+	}
+	return err
+}
+
+// send exports one batch, counting records the endpoint rejects as failed
+func (e *OTLPExporter) send(batch []otlpRecord) error {
+	req := exportRequest(batch)
+	var (
+		resp *collogspb.ExportLogsServiceResponse
+		err  error
+	)
+	if e.grpc != nil {
+		resp, err = e.sendGRPC(req)
+	} else {
+		resp, err = e.sendHTTP(req)
+	}
+	if err != nil {
+		return err
+	}
+
+	rejected := min(int(resp.GetPartialSuccess().GetRejectedLogRecords()), len(batch))
+	if rejected > 0 {
+		slog.Warn("OTLP export: records rejected", "endpoint", e.endpoint, "rejected", rejected, "records", len(batch), "message", resp.GetPartialSuccess().GetErrorMessage())
+		e.count(resultFailed, rejected)
+	}
+	e.count(resultSent, len(batch)-rejected)
+	e.sent(len(batch)-rejected, proto.Size(req))
+	return nil
+}
+
+// sendGRPC calls LogsService/Export, retrying the codes the OTLP spec calls transient
+func (e *OTLPExporter) sendGRPC(req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if len(e.cfg.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.cfg.Headers))
+	}
+	resp, err := e.grpc.Export(ctx, req)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted:
+			return nil, retryableError{err: fmt.Errorf("export to %s: %w", e.endpoint, err)}
+		}
+		return nil, fmt.Errorf("export to %s: %w", e.endpoint, err)
+	}
+	return resp, nil
+}
+
+// sendHTTP posts the request as protobuf, retrying throttles and server errors
+func (e *OTLPExporter) sendHTTP(req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range e.cfg.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpResp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, retryableError{err: err}
+	}
+	defer httpResp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+
+	switch {
+	case httpResp.StatusCode == http.StatusOK:
+		// Anything but a protobuf reply is taken as full success
+		resp := &collogspb.ExportLogsServiceResponse{}
+		if httpResp.Header.Get("Content-Type") == "application/x-protobuf" && proto.Unmarshal(reply, resp) != nil {
+			resp.Reset()
+		}
+		return resp, nil
+	case httpResp.StatusCode == http.StatusTooManyRequests || httpResp.StatusCode >= 500:
+		return nil, retryableError{err: fmt.Errorf("post %s: %s", e.endpoint, httpResp.Status)}
+	default:
+		return nil, fmt.Errorf("post %s: %s", e.endpoint, httpResp.Status)
+	}
+}
+
+// exportRequest groups a batch's records under their resources and scopes,
+// in the order they were queued
+func exportRequest(batch []otlpRecord) *collogspb.ExportLogsServiceRequest {
+	req := &collogspb.ExportLogsServiceRequest{}
+	resources := make(map[*resourcepb.Resource]*logspb.ResourceLogs)
+	type scopeKey struct {
+		resource *resourcepb.Resource
+		scope    *commonpb.InstrumentationScope
+	}
+	scopes := make(map[scopeKey]*logspb.ScopeLogs)
+
+	for _, r := range batch {
+		rl := resources[r.resource]
+		if rl == nil {
+			rl = &logspb.ResourceLogs{Resource: r.resource}
+			resources[r.resource] = rl
+			req.ResourceLogs = append(req.ResourceLogs, rl)
+		}
+		key := scopeKey{r.resource, r.scope}
+		sl := scopes[key]
+		if sl == nil {
+			sl = &logspb.ScopeLogs{Scope: r.scope}
+			scopes[key] = sl
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		sl.LogRecords = append(sl.LogRecords, r.log)
+	}
+	return req
+}
+
+// count adds n records to the result's metric
+func (e *OTLPExporter) count(result string, n int) {
+	e.mu.Lock()
+	m := e.metrics
+	e.mu.Unlock()
+	if m != nil && n > 0 {
+		m.OTLPExportRecords.WithLabelValues(result).Add(float64(n))
+	}
+}
@@ -0,0 +1,217 @@
+// ABOUTME: Tests for re-exporting records to fake OTLP gRPC and HTTP endpoints.
+// ABOUTME: Covers grouping by resource and scope, headers, partial success, and retrying transient failures.
+
+package output
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// fakeCollector records the requests it is sent, failing with each code in fail first
+type fakeCollector struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	attempts int
+	auth     string
+	fail     []codes.Code
+	rejected int64
+}
+
+func (f *fakeCollector) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		f.auth = md.Get("authorization")[0]
+	}
+	if len(f.fail) > 0 {
+		code := f.fail[0]
+		f.fail = f.fail[1:]
+		return nil, status.Error(code, "busy")
+	}
+	f.requests = append(f.requests, req)
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if f.rejected > 0 {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: f.rejected, ErrorMessage: "too old"}
+	}
+	return resp, nil
+}
+
+func (f *fakeCollector) received() ([]*collogspb.ExportLogsServiceRequest, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*collogspb.ExportLogsServiceRequest(nil), f.requests...), f.attempts
+}
+
+// startCollector serves f over gRPC on a local port
+func startCollector(t *testing.T, f *fakeCollector) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(srv, f)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func newExporterTest(t *testing.T, cfg OTLPExportConfig) (*OTLPExporter, *metrics.Metrics) {
+	t.Helper()
+	e, err := NewOTLPExporter(cfg)
+	if err != nil {
+		t.Fatalf("NewOTLPExporter failed: %v", err)
+	}
+	e.backoff = time.Millisecond
+	m := metrics.New()
+	e.SetMetrics(m)
+	return e, m
+}
+
+func testRecord(body string) *logspb.LogRecord {
+	return &logspb.LogRecord{Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}}}
+}
+
+func TestOTLPExporter_GRPC(t *testing.T) {
+	f := &fakeCollector{rejected: 1}
+	addr := startCollector(t, f)
+	e, m := newExporterTest(t, OTLPExportConfig{
+		Endpoint: addr, Insecure: true, BatchSize: 3, FlushInterval: time.Hour,
+		Headers: map[string]string{"authorization": "Bearer secret"},
+	})
+
+	web := &resourcepb.Resource{Attributes: []*commonpb.KeyValue{{Key: "cf_app_name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "web"}}}}}
+	worker := &resourcepb.Resource{}
+	scope := &commonpb.InstrumentationScope{Name: "loggregator"}
+	e.WriteRecord("", web, scope, testRecord("one"))
+	e.WriteRecord("", worker, scope, testRecord("two"))
+	e.WriteRecord("", web, scope, testRecord("three"))
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	requests, _ := f.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want one batch", len(requests))
+	}
+	rls := requests[0].GetResourceLogs()
+	if len(rls) != 2 || len(rls[0].GetScopeLogs()) != 1 {
+		t.Fatalf("got %d resources, want records grouped under 2", len(rls))
+	}
+	if got := rls[0].GetScopeLogs()[0].GetLogRecords(); len(got) != 2 || got[1].GetBody().GetStringValue() != "three" {
+		t.Errorf("first resource's records = %v, want one and three", got)
+	}
+	if rls[0].GetScopeLogs()[0].GetScope().GetName() != "loggregator" || len(rls[0].GetResource().GetAttributes()) != 1 {
+		t.Errorf("resource and scope were not carried over: %v", rls[0])
+	}
+	if f.auth != "Bearer secret" {
+		t.Errorf("authorization = %q, want the configured header", f.auth)
+	}
+	if sent, failed := testutil.ToFloat64(m.OTLPExportRecords.WithLabelValues("sent")), testutil.ToFloat64(m.OTLPExportRecords.WithLabelValues("failed")); sent != 2 || failed != 1 {
+		t.Errorf("sent, failed = %v, %v, want 2, 1 after a partial success", sent, failed)
+	}
+}
+
+func TestOTLPExporter_RetriesUnavailable(t *testing.T) {
+	f := &fakeCollector{fail: []codes.Code{codes.Unavailable, codes.ResourceExhausted}}
+	addr := startCollector(t, f)
+	e, m := newExporterTest(t, OTLPExportConfig{Endpoint: addr, Insecure: true, BatchSize: 1, FlushInterval: time.Hour, MaxRetries: 3})
+	defer e.Close()
+
+	e.WriteRecord("", &resourcepb.Resource{}, nil, testRecord("eventually"))
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(m.OTLPExportRecords.WithLabelValues("sent")) != 1 {
+		if time.Now().After(deadline) {
+			_, attempts := f.received()
+			t.Fatalf("record not exported after %d attempts", attempts)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, attempts := f.received(); attempts != 3 {
+		t.Errorf("attempts = %d, want 2 failures and a success", attempts)
+	}
+}
+
+func TestOTLPExporter_HTTP(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		got  collogspb.ExportLogsServiceRequest
+		path string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		path = r.URL.Path
+		if r.Header.Get("Content-Type") != "application/x-protobuf" || proto.Unmarshal(body, &got) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	e, m := newExporterTest(t, OTLPExportConfig{Endpoint: srv.URL, Protocol: ProtocolHTTP, BatchSize: 10, FlushInterval: time.Hour})
+	e.WriteRecord("", &resourcepb.Resource{}, nil, testRecord("over http"))
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if path != "/v1/logs" {
+		t.Errorf("path = %s, want /v1/logs", path)
+	}
+	if body := got.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0].GetBody().GetStringValue(); body != "over http" {
+		t.Errorf("body = %q", body)
+	}
+	if sent := testutil.ToFloat64(m.OTLPExportRecords.WithLabelValues("sent")); sent != 1 {
+		t.Errorf("sent = %v, want 1", sent)
+	}
+}
+
+func TestNewOTLPExporter_Invalid(t *testing.T) {
+	for _, cfg := range []OTLPExportConfig{
+		{Endpoint: "collector:4318", Protocol: ProtocolHTTP},
+		{Endpoint: "collector:4317", Protocol: "thrift"},
+		{Protocol: ProtocolGRPC},
+	} {
+		if _, err := NewOTLPExporter(cfg); err == nil {
+			t.Errorf("NewOTLPExporter(%+v) should fail", cfg)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got, err := ParseHeaders("authorization=Bearer%20secret, x-tenant = acme,")
+	if err != nil || len(got) != 2 || got["authorization"] != "Bearer secret" || got["x-tenant"] != "acme" {
+		t.Errorf("ParseHeaders = %v, %v", got, err)
+	}
+	if _, err := ParseHeaders("no-value"); err == nil {
+		t.Error("a header without = should be rejected")
+	}
+}
@@ -0,0 +1,80 @@
+// ABOUTME: Raw OTLP encodings of transformed records for the file output, for byte-faithful replay.
+// ABOUTME: Writes export requests as varint-length-prefixed protobuf or as OTLP JSON lines with hex trace IDs.
+
+package output
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// encodeOTLP renders a batch of records as one export request in the format
+func encodeOTLP(format Format, batch []otlpRecord) ([]byte, error) {
+	req := exportRequest(batch)
+	switch format {
+	case FormatOTLPProto:
+		var buf bytes.Buffer
+		if _, err := protodelim.MarshalTo(&buf, req); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatOTLPJSON:
+		data, err := protojson.Marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		line, err := hexIDs(data)
+		if err != nil {
+			return nil, err
+		}
+		return append(line, '\n'), nil
+	}
+	return nil, fmt.Errorf("output format %s is not an OTLP format", format)
+}
+
+// hexIDs rewrites the base64 traceId and spanId values protojson writes to
+// the hex the OTLP JSON encoding calls for, compacting the document onto
+// one line
+func hexIDs(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // Keep nanosecond timestamps exact
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	rewriteIDs(doc)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// rewriteIDs walks a decoded JSON document converting base64 trace and span IDs in place
+func rewriteIDs(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if s, ok := val.(string); ok && (key == "traceId" || key == "spanId") {
+				if raw, err := base64.StdEncoding.DecodeString(s); err == nil {
+					v[key] = hex.EncodeToString(raw)
+				}
+				continue
+			}
+			rewriteIDs(val)
+		}
+	case []any:
+		for _, item := range v {
+			rewriteIDs(item)
+		}
+	}
+}
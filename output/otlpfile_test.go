@@ -0,0 +1,117 @@
+// ABOUTME: Tests for the raw OTLP file formats.
+// ABOUTME: Covers length-prefixed protobuf round trips, OTLP JSON lines with hex IDs, and the file writer in both.
+
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// tracedRecord is a record with trace context, whose IDs the JSON format writes in hex
+func tracedRecord(body string) *logspb.LogRecord {
+	lr := testRecord(body)
+	lr.TraceId = []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}
+	lr.SpanId = []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74}
+	lr.TimeUnixNano = 1705314600123456789
+	return lr
+}
+
+func TestEncodeOTLP_Proto(t *testing.T) {
+	resource := &resourcepb.Resource{}
+	batch := []otlpRecord{{resource, nil, tracedRecord("one")}, {resource, nil, tracedRecord("two")}}
+	data, err := encodeOTLP(FormatOTLPProto, batch)
+	if err != nil {
+		t.Fatalf("encodeOTLP failed: %v", err)
+	}
+
+	req := &collogspb.ExportLogsServiceRequest{}
+	r := bufio.NewReader(bytes.NewReader(data))
+	if err := protodelim.UnmarshalFrom(r, req); err != nil {
+		t.Fatalf("UnmarshalFrom failed: %v", err)
+	}
+	if !proto.Equal(req, exportRequest(batch)) {
+		t.Errorf("decoded %v, want the batch's export request", req)
+	}
+	if _, err := r.ReadByte(); err == nil {
+		t.Error("trailing bytes after the one request")
+	}
+}
+
+func TestEncodeOTLP_JSON(t *testing.T) {
+	scope := &commonpb.InstrumentationScope{Name: "test"}
+	data, err := encodeOTLP(FormatOTLPJSON, []otlpRecord{{&resourcepb.Resource{}, scope, tracedRecord("a <b> & c")}})
+	if err != nil {
+		t.Fatalf("encodeOTLP failed: %v", err)
+	}
+	line := string(data)
+	if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+		t.Errorf("got %q, want one line", line)
+	}
+	for _, want := range []string{
+		`"traceId":"5b8efff798038103d269b633813fc60c"`,
+		`"spanId":"eee19b7ec3c1b174"`,
+		`"timeUnixNano":"1705314600123456789"`,
+		`"stringValue":"a <b> & c"`,
+		`"scope":{"name":"test"}`,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("got %s, want %s", line, want)
+		}
+	}
+}
+
+func TestJSONWriter_OTLPFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.otlp")
+	w, err := NewJSONWriter(path, FormatOTLPProto, 10, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	if !w.TakesRecords() {
+		t.Error("otlp-proto format should take records")
+	}
+	if err := w.Write(&LogEntry{Body: "entry"}); err == nil {
+		t.Error("Write of an entry in the otlp-proto format should fail")
+	}
+	resource := &resourcepb.Resource{}
+	w.WriteRecord("web", resource, nil, testRecord("first flush"))
+	w.Flush()
+	w.WriteRecord("web", resource, nil, testRecord("second flush"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// One request per flush
+	data, _ := os.ReadFile(path)
+	r := bufio.NewReader(bytes.NewReader(data))
+	var bodies []string
+	for {
+		req := &collogspb.ExportLogsServiceRequest{}
+		if err := protodelim.UnmarshalFrom(r, req); err != nil {
+			break
+		}
+		bodies = append(bodies, req.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0].GetBody().GetStringValue())
+	}
+	if len(bodies) != 2 || bodies[0] != "first flush" || bodies[1] != "second flush" {
+		t.Errorf("file holds %v, want one request per flush", bodies)
+	}
+
+	entries, _ := NewJSONWriter(filepath.Join(t.TempDir(), "logs.jsonl"), FormatJSONL, 10, time.Hour, 100*1024*1024)
+	defer entries.Close()
+	if entries.TakesRecords() || entries.WriteRecord("web", resource, nil, testRecord("x")) == nil {
+		t.Error("jsonl format should refuse records")
+	}
+}
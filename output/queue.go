@@ -0,0 +1,253 @@
+// ABOUTME: Bounded asynchronous queue in front of a sink, so a slow sink never holds up record processing.
+// ABOUTME: A full queue either blocks writers or drops its oldest record, counting depth, drops, and writer busy time per sink.
+
+package output
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// What a full queue does with the next record
+const (
+	QueueBlock      = "block"       // The writer waits for room
+	QueueDropOldest = "drop-oldest" // The oldest queued record is dropped to make room
+)
+
+// queueItem is a queued log entry, or a record for a sink that takes records
+type queueItem struct {
+	entry    *LogEntry
+	app      string
+	resource *resourcepb.Resource
+	scope    *commonpb.InstrumentationScope
+	log      *logspb.LogRecord
+	queuedAt time.Time
+}
+
+// Queue hands records to a sink from its own goroutine, so the receiver
+// only waits on the sink when the queue is full and the policy is block
+type Queue struct {
+	name   string // Sink label for the queue metrics
+	sink   Sink
+	policy string
+	items  chan queueItem
+	done   chan struct{}
+
+	closeMu sync.RWMutex // Held for reading while queueing, so Close never closes items under a writer
+	closed  bool
+
+	mu      sync.Mutex
+	idle    *sync.Cond       // Signalled when the last queued record is written
+	queued  int              // Records queued or being written
+	writing time.Time        // When the record being written was queued
+	pending map[string]int64 // Queued entries not yet written, by routing index
+	metrics *metrics.Metrics
+}
+
+// NewQueue starts writing to sink through a queue of size records. Closing
+// the queue closes the sink.
+func NewQueue(name string, sink Sink, size int, policy string) (*Queue, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("output queue size must be positive")
+	}
+	if policy != QueueBlock && policy != QueueDropOldest {
+		return nil, fmt.Errorf("output queue policy %q must be %s or %s", policy, QueueBlock, QueueDropOldest)
+	}
+	q := &Queue{
+		name:    name,
+		sink:    sink,
+		policy:  policy,
+		items:   make(chan queueItem, size),
+		done:    make(chan struct{}),
+		pending: make(map[string]int64),
+	}
+	q.idle = sync.NewCond(&q.mu)
+	go q.writeLoop()
+	return q, nil
+}
+
+// SetMetrics reports queue depth, dropped records, and the writer's busy time in m
+func (q *Queue) SetMetrics(m *metrics.Metrics) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.metrics = m
+}
+
+// Write queues a log entry for the sink
+func (q *Queue) Write(entry *LogEntry) error {
+	q.enqueue(queueItem{entry: entry})
+	return nil
+}
+
+// TakesRecords reports whether the sink takes records as OTLP
+func (q *Queue) TakesRecords() bool {
+	rs, ok := q.sink.(RecordSink)
+	return ok && rs.TakesRecords()
+}
+
+// WriteRecord queues a record for a sink that takes records. The record
+// must not be changed afterwards.
+func (q *Queue) WriteRecord(app string, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord) error {
+	if !q.TakesRecords() {
+		return errRecordsOnly
+	}
+	q.enqueue(queueItem{app: app, resource: resource, scope: scope, log: lr})
+	return nil
+}
+
+// Pending returns the number of queued entries not yet written, by routing index
+func (q *Queue) Pending() map[string]int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := make(map[string]int64, len(q.pending))
+	for index, n := range q.pending {
+		pending[index] = n
+	}
+	return pending
+}
+
+// Backlog returns the number of records queued or being written, and how
+// long the oldest of them has waited
+func (q *Queue) Backlog() (records int, lag time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.queued == 0 {
+		return 0, 0
+	}
+	if !q.writing.IsZero() {
+		lag = time.Since(q.writing)
+	}
+	return q.queued, lag
+}
+
+// Flush waits for the queued records to reach the sink, then flushes it
+func (q *Queue) Flush() error {
+	q.mu.Lock()
+	for q.queued > 0 {
+		q.idle.Wait()
+	}
+	q.mu.Unlock()
+	return q.sink.Flush()
+}
+
+// Close writes the queued records to the sink and closes it. Records
+// queued afterwards are dropped.
+func (q *Queue) Close() error {
+	q.closeMu.Lock()
+	q.closed = true
+	close(q.items)
+	q.closeMu.Unlock()
+
+	<-q.done
+	return q.sink.Close()
+}
+
+// enqueue adds an item, waiting for room or dropping the oldest as the policy says
+func (q *Queue) enqueue(item queueItem) {
+	q.closeMu.RLock()
+	defer q.closeMu.RUnlock()
+	if q.closed {
+		q.dropped(1)
+		return
+	}
+
+	item.queuedAt = time.Now()
+	q.track(item, 1)
+	if q.policy == QueueBlock {
+		q.items <- item
+		q.depth()
+		return
+	}
+	for {
+		select {
+		case q.items <- item:
+			q.depth()
+			return
+		default:
+		}
+		select {
+		case oldest := <-q.items:
+			q.track(oldest, -1)
+			q.dropped(1)
+		default: // The writer took it first
+		}
+	}
+}
+
+// writeLoop writes queued items to the sink until the queue is closed
+func (q *Queue) writeLoop() {
+	defer close(q.done)
+	for item := range q.items {
+		q.depth()
+		q.mu.Lock()
+		q.writing = item.queuedAt
+		q.mu.Unlock()
+		start := time.Now()
+		var err error
+		if item.entry != nil {
+			err = q.sink.Write(item.entry)
+		} else {
+			err = q.sink.(RecordSink).WriteRecord(item.app, item.resource, item.scope, item.log)
+		}
+		q.busy(time.Since(start))
+		if err != nil {
+			slog.Error("Output: write failed", "output", q.name, "err", err)
+		}
+		q.track(item, -1) // Only now, so Pending never misses a record on its way
+	}
+}
+
+// track counts an item in or out of the queue
+func (q *Queue) track(item queueItem, n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queued += n
+	if item.entry != nil {
+		index := item.entry.Routing.Index
+		if q.pending[index] += int64(n); q.pending[index] == 0 {
+			delete(q.pending, index)
+		}
+	}
+	if q.queued == 0 {
+		q.writing = time.Time{} // Not to be mistaken for the next record's
+		q.idle.Broadcast()
+	}
+}
+
+// depth reports the number of queued records
+func (q *Queue) depth() {
+	q.mu.Lock()
+	m := q.metrics
+	q.mu.Unlock()
+	if m != nil {
+		m.OutputQueueDepth.WithLabelValues(q.name).Set(float64(len(q.items)))
+	}
+}
+
+// busy counts time the writer spent writing
+func (q *Queue) busy(d time.Duration) {
+	q.mu.Lock()
+	m := q.metrics
+	q.mu.Unlock()
+	if m != nil {
+		m.OutputWorkerBusy.WithLabelValues(q.name).Add(d.Seconds())
+	}
+}
+
+// dropped counts records dropped from a full or closed queue
+func (q *Queue) dropped(n int) {
+	q.mu.Lock()
+	m := q.metrics
+	q.mu.Unlock()
+	if m != nil {
+		m.OutputDropped.WithLabelValues(q.name).Add(float64(n))
+	}
+}
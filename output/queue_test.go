@@ -0,0 +1,247 @@
+// ABOUTME: Tests for the asynchronous output queue.
+// ABOUTME: Covers handing records to the sink, flushing, blocking and drop-oldest policies, and closing.
+
+package output
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// fakeSink records what it is given, holding each write until released if gated
+type fakeSink struct {
+	gate    chan struct{} // If set, each write waits for a value
+	records bool
+
+	mu      sync.Mutex
+	bodies  []string
+	flushes int
+	closed  bool
+}
+
+func (f *fakeSink) Write(entry *LogEntry) error {
+	if f.gate != nil {
+		<-f.gate
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bodies = append(f.bodies, entry.Body)
+	return nil
+}
+
+func (f *fakeSink) TakesRecords() bool { return f.records }
+
+func (f *fakeSink) WriteRecord(app string, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bodies = append(f.bodies, app+":"+lr.GetBody().GetStringValue())
+	return nil
+}
+
+func (f *fakeSink) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flushes++
+	return nil
+}
+
+func (f *fakeSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeSink) written() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.bodies...)
+}
+
+func TestQueue_FlushWaitsForQueuedRecords(t *testing.T) {
+	f := &fakeSink{}
+	q, err := NewQueue("test", f, 10, QueueBlock)
+	if err != nil {
+		t.Fatalf("NewQueue failed: %v", err)
+	}
+	defer q.Close()
+
+	q.Write(&LogEntry{Body: "one", Routing: RoutingInfo{Index: "tas_logs"}})
+	q.Write(&LogEntry{Body: "two", Routing: RoutingInfo{Index: "tas_logs"}})
+	if err := q.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if got := f.written(); len(got) != 2 || got[0] != "one" || got[1] != "two" {
+		t.Errorf("sink got %v, want [one two]", got)
+	}
+	if f.flushes != 1 {
+		t.Errorf("sink flushed %d times, want 1", f.flushes)
+	}
+	if pending := q.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %v after Flush, want none", pending)
+	}
+}
+
+func TestQueue_PendingUntilWritten(t *testing.T) {
+	f := &fakeSink{gate: make(chan struct{})}
+	q, _ := NewQueue("test", f, 10, QueueBlock)
+	q.Write(&LogEntry{Body: "held", Routing: RoutingInfo{Index: "tas_logs"}})
+	q.Write(&LogEntry{Body: "queued", Routing: RoutingInfo{Index: "tas_errors"}})
+
+	// One record is being written and one is queued; both count until written
+	pending := q.Pending()
+	if pending["tas_logs"] != 1 || pending["tas_errors"] != 1 {
+		t.Errorf("Pending() = %v, want one per index", pending)
+	}
+	close(f.gate)
+	q.Close()
+}
+
+func TestQueue_BacklogUntilWritten(t *testing.T) {
+	f := &fakeSink{gate: make(chan struct{})}
+	q, _ := NewQueue("test", f, 10, QueueBlock)
+	q.Write(&LogEntry{Body: "held"})
+	waitFor(t, func() bool { return len(q.items) == 0 })
+	q.Write(&LogEntry{Body: "queued"})
+	time.Sleep(20 * time.Millisecond)
+
+	if records, lag := q.Backlog(); records != 2 || lag < 20*time.Millisecond {
+		t.Errorf("Backlog() = %d, %s; want 2 records, the oldest waiting at least 20ms", records, lag)
+	}
+	close(f.gate)
+	q.Flush()
+	if records, lag := q.Backlog(); records != 0 || lag != 0 {
+		t.Errorf("Backlog() = %d, %s after Flush, want none", records, lag)
+	}
+	q.Close()
+}
+
+func TestQueue_DropOldestWhenFull(t *testing.T) {
+	f := &fakeSink{gate: make(chan struct{})}
+	q, _ := NewQueue("test", f, 2, QueueDropOldest)
+	m := metrics.New()
+	q.SetMetrics(m)
+
+	// The first is taken by the writer, which is held; the next two fill the queue
+	q.Write(&LogEntry{Body: "first"})
+	waitFor(t, func() bool { return len(q.items) == 0 })
+	q.Write(&LogEntry{Body: "second"})
+	q.Write(&LogEntry{Body: "third"})
+	q.Write(&LogEntry{Body: "fourth"})
+
+	if got := testutil.ToFloat64(m.OutputDropped.WithLabelValues("test")); got != 1 {
+		t.Errorf("dropped = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.OutputQueueDepth.WithLabelValues("test")); got != 2 {
+		t.Errorf("queue depth = %v, want 2", got)
+	}
+	close(f.gate)
+	q.Close()
+	if got := f.written(); len(got) != 3 || got[0] != "first" || got[1] != "third" || got[2] != "fourth" {
+		t.Errorf("sink got %v, want second dropped", got)
+	}
+}
+
+func TestQueue_BlockWaitsForRoom(t *testing.T) {
+	f := &fakeSink{gate: make(chan struct{})}
+	q, _ := NewQueue("test", f, 1, QueueBlock)
+	q.Write(&LogEntry{Body: "first"})
+	waitFor(t, func() bool { return len(q.items) == 0 }) // Taken by the held writer
+	q.Write(&LogEntry{Body: "second"})
+
+	written := make(chan struct{})
+	go func() {
+		q.Write(&LogEntry{Body: "third"})
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("Write returned while the queue was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(f.gate)
+	<-written
+	q.Close()
+	if got := f.written(); len(got) != 3 {
+		t.Errorf("sink got %v, want all three", got)
+	}
+}
+
+func TestQueue_PassesRecordsToRecordSinks(t *testing.T) {
+	f := &fakeSink{records: true}
+	q, _ := NewQueue("test", f, 10, QueueBlock)
+	if !q.TakesRecords() {
+		t.Fatal("queue should take records for a sink that does")
+	}
+	q.WriteRecord("web", &resourcepb.Resource{}, nil, testRecord("hello"))
+	q.Close()
+	if got := f.written(); len(got) != 1 || got[0] != "web:hello" {
+		t.Errorf("sink got %v, want [web:hello]", got)
+	}
+
+	entries, _ := NewQueue("test", &fakeSink{}, 10, QueueBlock)
+	defer entries.Close()
+	if entries.TakesRecords() || entries.WriteRecord("web", nil, nil, testRecord("hello")) == nil {
+		t.Error("queue should refuse records for a sink that takes entries")
+	}
+}
+
+func TestQueue_CloseDrainsAndDropsLaterWrites(t *testing.T) {
+	f := &fakeSink{}
+	q, _ := NewQueue("test", f, 10, QueueBlock)
+	m := metrics.New()
+	q.SetMetrics(m)
+	q.Write(&LogEntry{Body: "before"})
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	q.Write(&LogEntry{Body: "after"})
+
+	if got := f.written(); len(got) != 1 || got[0] != "before" || !f.closed {
+		t.Errorf("sink got %v, closed %v; want the queued record written and the sink closed", got, f.closed)
+	}
+	if got := testutil.ToFloat64(m.OutputDropped.WithLabelValues("test")); got != 1 {
+		t.Errorf("dropped = %v, want the write after Close", got)
+	}
+}
+
+func TestQueue_CountsWorkerBusyTime(t *testing.T) {
+	f := &fakeSink{gate: make(chan struct{})}
+	q, _ := NewQueue("test", f, 10, QueueBlock)
+	m := metrics.New()
+	q.SetMetrics(m)
+
+	q.Write(&LogEntry{Body: "slow"})
+	time.Sleep(20 * time.Millisecond) // The writer is held in the sink meanwhile
+	close(f.gate)
+	q.Close()
+	if got := testutil.ToFloat64(m.OutputWorkerBusy.WithLabelValues("test")); got < 0.02 {
+		t.Errorf("busy = %vs, want at least the 20ms the sink held the writer", got)
+	}
+}
+
+func TestNewQueue_RejectsBadPolicy(t *testing.T) {
+	if _, err := NewQueue("test", &fakeSink{}, 10, "drop-newest"); err == nil {
+		t.Error("NewQueue should reject an unknown policy")
+	}
+}
+
+// waitFor polls cond for up to a second
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
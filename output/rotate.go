@@ -0,0 +1,471 @@
+// ABOUTME: Output file rotation by size, by time interval, and by date-templated file names.
+// ABOUTME: Keeps a bounded number of rotated files, optionally gzipped off the write path by a rotation worker.
+
+package output
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// ParseRotateInterval parses hourly, daily, or a duration that divides a day
+// evenly, such as 15m or 6h
+func ParseRotateInterval(s string) (time.Duration, error) {
+	switch s {
+	case "hourly":
+		return time.Hour, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("rotate interval %q: want hourly, daily, or a duration", s)
+	}
+	if d < time.Minute || (24*time.Hour)%d != 0 {
+		return 0, fmt.Errorf("rotate interval %s must be at least a minute and divide a day evenly", d)
+	}
+	return d, nil
+}
+
+// ExpandPath fills in a path template's date fields from t: %Y year, %m
+// month, %d day, %H hour, %M minute, %S second, and %% a literal %. A path
+// without % is returned as is.
+func ExpandPath(pattern string, t time.Time) string {
+	if !strings.Contains(pattern, "%") {
+		return pattern
+	}
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 == len(pattern) {
+			b.WriteByte(pattern[i])
+			continue
+		}
+		i++
+		switch pattern[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(pattern[i])
+		}
+	}
+	return b.String()
+}
+
+// SetRotateInterval starts a new file at every interval boundary, counted
+// from local midnight. A templated path already rotates whenever its
+// expanded name changes; a plain one has each finished file renamed with
+// its period's start time, as logs.jsonl.20240115-14.
+func (w *JSONWriter) SetRotateInterval(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rotateInterval = d
+	w.period = periodStart(w.now(), d)
+}
+
+// periodStart returns the start of the interval containing t, counted from local midnight
+func periodStart(t time.Time, d time.Duration) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if d <= 0 || d >= 24*time.Hour {
+		return midnight
+	}
+	return midnight.Add(t.Sub(midnight).Truncate(d))
+}
+
+// periodSuffix formats a period's start for a rotated file name, as finely as the interval needs
+func periodSuffix(start time.Time, d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return start.Format("20060102")
+	case d%time.Hour == 0:
+		return start.Format("20060102-15")
+	default:
+		return start.Format("20060102-1504")
+	}
+}
+
+// SetRetention keeps the newest keep rotated files, 0 keeping all, and
+// gzips each file as it is rotated if compress is set and it is not gzipped
+// already. Size rotation shifts generations up, so filename.1 is always the
+// newest.
+func (w *JSONWriter) SetRetention(keep int, compress bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.keep, w.compress = keep, compress
+}
+
+// SetRotateHandler calls fn, from the rotation worker, with the name of each
+// file rotation finishes, in rotation order, after compressing it and before
+// retention runs. Writes continue meanwhile, unless size rotation needs the
+// previous file finished first.
+func (w *JSONWriter) SetRotateHandler(fn func(path string)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onRotate = fn
+}
+
+// SetMetrics counts rotations and deleted files in m, and reports the buffer's length
+func (w *JSONWriter) SetMetrics(m *metrics.Metrics) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.metrics = m
+	w.buffered = 0
+	w.reportBuffered()
+}
+
+// Rotation triggers, as counted by otlp_receiver_output_rotations_total
+const (
+	triggerSize     = "size"
+	triggerInterval = "interval"
+	triggerTemplate = "template"
+)
+
+// rotateIfNeeded starts a new file when the templated name changes, the
+// rotate interval's period ends, or the file exceeds maxFileSize
+func (w *JSONWriter) rotateIfNeeded() {
+	if w.path == Stdout {
+		return
+	}
+	now := w.now()
+	if next := ExpandPath(w.pattern, now); next != w.path {
+		finished := w.path
+		w.reopen(next)
+		w.finish(finished, triggerTemplate)
+		return
+	}
+	if w.rotateInterval > 0 && w.pattern == w.path {
+		if start := periodStart(now, w.rotateInterval); !start.Equal(w.period) {
+			rotated := w.path + "." + periodSuffix(w.period, w.rotateInterval)
+			w.period = start
+			if w.rotateTo(rotated) {
+				w.finish(rotated, triggerInterval)
+			}
+			return
+		}
+	}
+
+	if w.file == nil {
+		return
+	}
+	info, err := w.file.Stat()
+	if err != nil {
+		return
+	}
+
+	if info.Size() < w.maxFileSize {
+		return
+	}
+
+	// Rotate: shift older generations up and rename current to .1, once the
+	// worker is done with the previous .1
+	w.rotations.Wait()
+	w.shiftGenerations()
+	if w.rotateTo(w.path + ".1") {
+		w.finish(w.path+".1", triggerSize)
+	}
+}
+
+// shiftGenerations renames filename.N to filename.N+1, newest last, deleting
+// the generation beyond keep. Compressed generations shift the same way.
+func (w *JSONWriter) shiftGenerations() {
+	last := w.keep
+	if last == 0 {
+		for last = 1; generationExists(w.path, last); last++ {
+		}
+	} else {
+		for _, name := range []string{generation(w.path, last), generation(w.path, last) + ".gz"} {
+			if os.Remove(name) == nil {
+				w.deleted()
+			}
+		}
+	}
+	for i := last - 1; i >= 1; i-- {
+		for _, ext := range []string{"", ".gz"} {
+			os.Rename(generation(w.path, i)+ext, generation(w.path, i+1)+ext)
+		}
+	}
+}
+
+// generation returns the name of the nth size-rotated file
+func generation(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// generationExists reports whether the nth size-rotated file exists, compressed or not
+func generationExists(path string, n int) bool {
+	for _, ext := range []string{"", ".gz"} {
+		if _, err := os.Stat(generation(path, n) + ext); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// rotation is a finished file waiting for the rotation worker, with the
+// settings it was finished under
+type rotation struct {
+	name     string
+	compress bool
+	prune    bool   // Delete dated files beyond keep afterwards
+	pattern  string // The writer's path template
+	current  string // The file being written, never pruned
+	keep     int
+	onRotate func(string)
+	metrics  *metrics.Metrics
+	onError  func(error)
+}
+
+// finish counts a rotation and queues the rotated file for the rotation
+// worker, which compresses it if configured, hands it to the rotate
+// handler, and deletes dated files beyond keep without holding the lock.
+// Caller holds mu.
+func (w *JSONWriter) finish(rotated, trigger string) {
+	if w.metrics != nil {
+		w.metrics.OutputRotations.WithLabelValues(fileSink, trigger).Inc()
+	}
+	w.rotations.Add(1)
+	w.queueMu.Lock()
+	defer w.queueMu.Unlock()
+	w.queue = append(w.queue, rotation{
+		name:     rotated,
+		compress: w.compress && !w.gzipped(),
+		prune:    trigger != triggerSize && w.keep > 0,
+		pattern:  w.pattern,
+		current:  w.path,
+		keep:     w.keep,
+		onRotate: w.onRotate,
+		metrics:  w.metrics,
+		onError:  w.onError,
+	})
+	if len(w.queue) == 1 {
+		go w.finishRotations()
+	}
+}
+
+// finishRotations is the rotation worker: it finishes queued files one at a
+// time, oldest first, leaving each queued until it is done so finish starts
+// no second worker, and exits when the queue is empty
+func (w *JSONWriter) finishRotations() {
+	for {
+		w.queueMu.Lock()
+		r := w.queue[0]
+		w.queueMu.Unlock()
+
+		r.run()
+
+		w.queueMu.Lock()
+		w.queue = w.queue[1:]
+		empty := len(w.queue) == 0
+		w.queueMu.Unlock()
+		w.rotations.Done()
+		if empty {
+			return
+		}
+	}
+}
+
+// run compresses, hands off, and prunes one finished file
+func (r rotation) run() {
+	name := r.name
+	if r.compress {
+		if err := gzipFile(name); err != nil {
+			reportError(r.metrics, r.onError, opRotate, fmt.Errorf("compressing %s: %w", name, err))
+		} else {
+			name += ".gz"
+		}
+	}
+	if r.onRotate != nil {
+		r.onRotate(name)
+	}
+	if r.prune {
+		// Files newer than this one were rotated after it, and are pruned
+		// with their own rotation, so a lagging worker never counts them
+		var before time.Time
+		if info, err := os.Stat(name); err == nil {
+			before = info.ModTime()
+		}
+		for range prune(r.pattern, r.current, r.keep, before) {
+			countDeleted(r.metrics)
+		}
+	}
+}
+
+// datedSuffix matches the suffix interval rotation gives a finished file
+var datedSuffix = regexp.MustCompile(`^\.\d{8}(-\d{2}|-\d{4})?(\.gz)?$`)
+
+// prune deletes the oldest finished files of a template or rotate interval,
+// keeping the newest keep, and returns how many it deleted. Only files
+// modified no later than before count, unless it is zero. current, the file
+// being written, is never deleted.
+func prune(pattern, current string, keep int, before time.Time) int {
+	var matches []string
+	if pattern != current {
+		glob := templateGlob(pattern)
+		plain, _ := filepath.Glob(glob)
+		zipped, _ := filepath.Glob(glob + ".gz")
+		matches = append(plain, zipped...)
+	} else {
+		candidates, _ := filepath.Glob(current + ".*")
+		for _, name := range candidates {
+			if datedSuffix.MatchString(strings.TrimPrefix(name, current)) {
+				matches = append(matches, name)
+			}
+		}
+	}
+
+	type finished struct {
+		name string
+		mod  time.Time
+	}
+	var files []finished
+	for _, name := range matches {
+		if strings.HasSuffix(name, checkpointSuffix) || strings.HasSuffix(name, checkpointSuffix+".tmp") {
+			continue // A template like logs-%Y matches its checkpoints too
+		}
+		info, err := os.Stat(name)
+		if err != nil || name == current || (!before.IsZero() && info.ModTime().After(before)) {
+			continue
+		}
+		files = append(files, finished{name, info.ModTime()})
+	}
+	// Newest first, by modification time and then name
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].mod.Equal(files[j].mod) {
+			return files[i].mod.After(files[j].mod)
+		}
+		return files[i].name > files[j].name
+	})
+	var deleted int
+	for _, f := range files[min(keep, len(files)):] {
+		if os.Remove(f.name) == nil {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// deleted counts a rotated file removed by retention
+func (w *JSONWriter) deleted() {
+	countDeleted(w.metrics)
+}
+
+// countDeleted counts a rotated file removed by retention in m, if set
+func countDeleted(m *metrics.Metrics) {
+	if m != nil {
+		m.OutputFilesDeleted.Inc()
+	}
+}
+
+// templateGlob turns a path template into a glob matching every file it expands to
+func templateGlob(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] != '%' || i+1 == len(pattern):
+			b.WriteByte(pattern[i])
+		case pattern[i+1] == '%':
+			b.WriteByte('%')
+			i++
+		default:
+			b.WriteByte('*')
+			i++
+		}
+	}
+	return b.String()
+}
+
+// gzipFile compresses path to path.gz, keeping its modification time, and
+// removes the original
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+	return os.Remove(path)
+}
+
+// rotateTo closes the current file and renames it to rotated, reporting
+// whether it did; the next flush opens a new file in its place. If the
+// rename fails, writing continues in the current file.
+func (w *JSONWriter) rotateTo(rotated string) bool {
+	w.closeFile()
+	if err := os.Rename(w.path, rotated); err != nil {
+		w.report(opRotate, err)
+		return false
+	}
+	w.dropCheckpoint(w.path)
+	return true
+}
+
+// reopen closes the current file so the next flush continues in path
+func (w *JSONWriter) reopen(path string) {
+	w.closeFile()
+	w.dropCheckpoint(w.path)
+	w.path = path
+}
+
+// closeFile closes the current file, if open, leaving stdout open
+func (w *JSONWriter) closeFile() {
+	if w.file != nil && w.file != os.Stdout {
+		w.file.Close()
+	}
+	w.file = nil
+}
+
+// openOutput opens path for appending, creating it and, for templates that
+// name dated directories, its directory. Stdout is used as is.
+func openOutput(path string) (*os.File, error) {
+	if path == Stdout {
+		return os.Stdout, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
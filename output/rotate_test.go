@@ -0,0 +1,255 @@
+// ABOUTME: Tests for output file rotation by time interval and date-templated names.
+// ABOUTME: Covers retention of rotated generations and compression, using a fake clock to cross periods.
+
+package output
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// fakeClock returns a settable clock for a writer
+func fakeClock(t time.Time) (*time.Time, func() time.Time) {
+	now := t
+	return &now, func() time.Time { return now }
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	return strings.Count(string(data), "\n")
+}
+
+func TestExpandPath(t *testing.T) {
+	at := time.Date(2024, 1, 15, 9, 5, 7, 0, time.Local)
+	for pattern, want := range map[string]string{
+		"logs.jsonl":              "logs.jsonl",
+		"logs-%Y%m%d-%H.jsonl":    "logs-20240115-09.jsonl",
+		"/var/log/%Y/%m/%d.jsonl": "/var/log/2024/01/15.jsonl",
+		"logs-%H%M%S-100%%.jsonl": "logs-090507-100%.jsonl",
+		"logs-%q.jsonl":           "logs-%q.jsonl",
+		"trailing%":               "trailing%",
+	} {
+		if got := ExpandPath(pattern, at); got != want {
+			t.Errorf("ExpandPath(%s) = %s, want %s", pattern, got, want)
+		}
+	}
+}
+
+func TestParseRotateInterval(t *testing.T) {
+	for s, want := range map[string]time.Duration{"hourly": time.Hour, "daily": 24 * time.Hour, "15m": 15 * time.Minute, "6h": 6 * time.Hour} {
+		if got, err := ParseRotateInterval(s); err != nil || got != want {
+			t.Errorf("ParseRotateInterval(%s) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"weekly", "7m", "30s", "48h"} {
+		if _, err := ParseRotateInterval(s); err == nil {
+			t.Errorf("ParseRotateInterval(%s) should fail", s)
+		}
+	}
+}
+
+func TestJSONWriter_RotatesOnTemplatedName(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewJSONWriter(filepath.Join(dir, "logs-%Y%m%d-%H.jsonl"), FormatJSONL, 1, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	now, clock := fakeClock(time.Date(2024, 1, 15, 14, 59, 0, 0, time.Local))
+	w.now = clock
+
+	w.Write(&LogEntry{Body: "before the hour"})
+	*now = now.Add(2 * time.Minute)
+	w.Write(&LogEntry{Body: "after the hour"})
+	w.Write(&LogEntry{Body: "still after"})
+
+	if got := countLines(t, filepath.Join(dir, "logs-20240115-14.jsonl")); got != 1 {
+		t.Errorf("14:00 file has %d lines, want 1", got)
+	}
+	if got := countLines(t, filepath.Join(dir, "logs-20240115-15.jsonl")); got != 2 {
+		t.Errorf("15:00 file has %d lines, want 2", got)
+	}
+	if got := w.Path(); got != filepath.Join(dir, "logs-20240115-15.jsonl") {
+		t.Errorf("Path() = %s, want the 15:00 file", got)
+	}
+}
+
+func TestJSONWriter_RotatesOnInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	w, err := NewJSONWriter(path, FormatJSONL, 1, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	now, clock := fakeClock(time.Date(2024, 1, 15, 14, 10, 0, 0, time.Local))
+	w.now = clock
+	w.SetRotateInterval(time.Hour)
+
+	w.Write(&LogEntry{Body: "at 14:10"})
+	*now = now.Add(30 * time.Minute)
+	w.Write(&LogEntry{Body: "at 14:40"})
+	*now = now.Add(30 * time.Minute)
+	w.Write(&LogEntry{Body: "at 15:10"})
+
+	if got := countLines(t, path+".20240115-14"); got != 2 {
+		t.Errorf("rotated 14:00 file has %d lines, want 2", got)
+	}
+	if got := countLines(t, path); got != 1 {
+		t.Errorf("current file has %d lines, want 1", got)
+	}
+}
+
+func TestJSONWriter_KeepsGenerations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	w, err := NewJSONWriter(path, FormatJSONL, 1, time.Hour, 10)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	m := metrics.New()
+	w.SetMetrics(m)
+	w.SetRetention(3, false)
+
+	// Every write after the first rotates, as each line exceeds the size limit
+	for _, body := range []string{"one", "two", "three", "four", "five", "six"} {
+		w.Write(&LogEntry{Body: body})
+	}
+
+	for n, want := range map[int]string{1: "five", 2: "four", 3: "three"} {
+		data, err := os.ReadFile(generation(path, n))
+		if err != nil || !strings.Contains(string(data), `"body":"`+want+`"`) {
+			t.Errorf("%s = %q, %v, want %s", generation(path, n), data, err, want)
+		}
+	}
+	if _, err := os.Stat(generation(path, 4)); !os.IsNotExist(err) {
+		t.Errorf("generation 4 should have been deleted: %v", err)
+	}
+	if got := testutil.ToFloat64(m.OutputRotations.WithLabelValues("file", "size")); got != 5 {
+		t.Errorf("size rotations = %v, want 5", got)
+	}
+	if got := testutil.ToFloat64(m.OutputFilesDeleted); got != 2 {
+		t.Errorf("deleted files = %v, want 2", got)
+	}
+}
+
+func TestJSONWriter_CompressesRotated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	w, err := NewJSONWriter(path, FormatJSONL, 1, time.Hour, 10)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	w.SetRetention(2, true)
+
+	for _, body := range []string{"one", "two", "three", "four"} {
+		w.Write(&LogEntry{Body: body})
+	}
+	w.rotations.Wait()
+
+	for n, want := range map[int]string{1: "three", 2: "two"} {
+		f, err := os.Open(generation(path, n) + ".gz")
+		if err != nil {
+			t.Fatalf("generation %d: %v", n, err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("generation %d: %v", n, err)
+		}
+		data, _ := io.ReadAll(zr)
+		f.Close()
+		if !strings.Contains(string(data), `"body":"`+want+`"`) {
+			t.Errorf("generation %d = %q, want %s", n, data, want)
+		}
+		if _, err := os.Stat(generation(path, n)); !os.IsNotExist(err) {
+			t.Errorf("uncompressed generation %d should be removed", n)
+		}
+	}
+	if _, err := os.Stat(generation(path, 3) + ".gz"); !os.IsNotExist(err) {
+		t.Error("generation 3 should have been deleted")
+	}
+}
+
+func TestJSONWriter_PrunesTemplatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewJSONWriter(filepath.Join(dir, "logs-%Y%m%d-%H.jsonl"), FormatJSONL, 1, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	defer w.Close()
+	now, clock := fakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local))
+	w.now = clock
+	w.SetRetention(2, false)
+
+	for range 5 {
+		w.Write(&LogEntry{Body: "hourly"})
+		*now = now.Add(time.Hour)
+	}
+	w.Write(&LogEntry{Body: "current"})
+	w.rotations.Wait()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "logs-*.jsonl"))
+	var names []string
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	want := "logs-20240115-13.jsonl logs-20240115-14.jsonl logs-20240115-15.jsonl"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("files = %s, want the current file and the newest 2", got)
+	}
+}
+
+func TestJSONWriter_RotateHandlerDoesNotBlockWrites(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewJSONWriter(filepath.Join(dir, "logs-%Y%m%d-%H.jsonl"), FormatJSONL, 1, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatalf("NewJSONWriter failed: %v", err)
+	}
+	now, clock := fakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local))
+	w.Write(&LogEntry{Body: "first"}) // Rotates away from the file named for the real clock
+	w.now = clock
+	w.Write(&LogEntry{Body: "10:00"})
+
+	release := make(chan struct{})
+	var rotated []string
+	w.SetRotateHandler(func(path string) {
+		<-release
+		rotated = append(rotated, filepath.Base(path))
+	})
+	w.SetRetention(0, true)
+
+	written := make(chan struct{})
+	go func() {
+		for range 3 {
+			*now = now.Add(time.Hour)
+			w.Write(&LogEntry{Body: "next hour"})
+		}
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writes blocked on the rotate handler")
+	}
+	close(release)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	want := "logs-20240115-10.jsonl.gz logs-20240115-11.jsonl.gz logs-20240115-12.jsonl.gz"
+	if got := strings.Join(rotated, " "); got != want {
+		t.Errorf("rotated = %s, want %s in order", got, want)
+	}
+}
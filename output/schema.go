@@ -0,0 +1,292 @@
+// ABOUTME: Output schemas that reshape log entries for the file output: renaming, omitting, and nesting fields.
+// ABOUTME: Loaded from a YAML field mapping, or a preset such as hec-event for Splunk's HEC event format.
+
+package output
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// SchemaHECEvent shapes entries as Splunk HEC events, for files replayed to
+// a HEC event endpoint
+const SchemaHECEvent = "hec-event"
+
+// Timestamp formats for Schema.Time
+const (
+	TimeRFC3339 = "rfc3339" // As the entry has it, e.g. 2024-01-15T10:30:00.123Z
+	TimeEpoch   = "epoch"   // Seconds since the epoch, to the millisecond, as HEC wants
+)
+
+// schemaOmit as a target drops the field
+const schemaOmit = "-"
+
+// Schema maps log entry fields to output keys. A field is named by its
+// JSON key, such as body or resource_attributes, or one key inside
+// attributes, resource_attributes, or routing, such as routing.index;
+// that key is then moved out of its object. Dots in an output key nest it
+// in objects, and "-" omits the field. When two fields land on one key,
+// objects are merged and otherwise the field earlier in the entry wins.
+type Schema struct {
+	Preset string            `yaml:"preset"` // Starting mapping, overridden by Fields
+	Fields map[string]string `yaml:"fields"` // Entry field to output key
+	Rest   string            `yaml:"rest"`   // Where unmapped fields go: "" keeps their keys, a key nests them there, "-" omits them
+	Time   string            `yaml:"time"`   // Timestamp format: rfc3339 (default) or epoch
+
+	name string
+}
+
+// entryFields are a log entry's JSON keys, in the order they are written
+var entryFields = []string{
+	"timestamp", "observed_timestamp", "severity", "severity_number", "body", "trace_id", "span_id", "flags",
+	"attributes", "dropped_attributes_count", "resource_attributes", "resource_dropped_attributes_count",
+	"scope_name", "scope_version", "routing", "transforms_applied",
+}
+
+// objectFields are the entry fields whose keys can be mapped one by one
+var objectFields = []string{"attributes", "resource_attributes", "routing"}
+
+// schemaPresets are the built-in schemas by name
+var schemaPresets = map[string]Schema{
+	SchemaHECEvent: {
+		Fields: map[string]string{
+			"timestamp":           "time",
+			"routing.index":       "index",
+			"routing.sourcetype":  "sourcetype",
+			"routing.source":      "source",
+			"body":                "event",
+			"severity":            "fields.severity",
+			"attributes":          "fields",
+			"resource_attributes": "fields",
+
+			// Reserved as indexed field names, and already the event's metadata
+			"attributes.index":               "-",
+			"attributes.sourcetype":          "-",
+			"attributes.source":              "-",
+			"resource_attributes.index":      "-",
+			"resource_attributes.sourcetype": "-",
+			"resource_attributes.source":     "-",
+		},
+		Rest: schemaOmit,
+		Time: TimeEpoch,
+	},
+}
+
+// LoadSchema returns the preset with the given name, or reads a YAML schema file
+func LoadSchema(nameOrPath string) (*Schema, error) {
+	if _, ok := schemaPresets[nameOrPath]; ok {
+		return (&Schema{Preset: nameOrPath}).build(nameOrPath)
+	}
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		return nil, fmt.Errorf("output schema %q is neither a preset (%s) nor a readable file: %w",
+			nameOrPath, strings.Join(sortedKeys(schemaPresets), ", "), err)
+	}
+	var s Schema
+	if err := yaml.UnmarshalStrict(data, &s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", nameOrPath, err)
+	}
+	return s.build(nameOrPath)
+}
+
+// build layers the schema on its preset and checks every field and key
+func (s Schema) build(name string) (*Schema, error) {
+	out := Schema{Fields: make(map[string]string), Rest: s.Rest, Time: s.Time, name: name}
+	if s.Preset != "" {
+		preset, ok := schemaPresets[s.Preset]
+		if !ok {
+			return nil, fmt.Errorf("preset %q: want one of %s", s.Preset, strings.Join(sortedKeys(schemaPresets), ", "))
+		}
+		maps.Copy(out.Fields, preset.Fields)
+		out.Rest = cmp.Or(s.Rest, preset.Rest)
+		out.Time = cmp.Or(s.Time, preset.Time)
+	}
+	maps.Copy(out.Fields, s.Fields)
+	if out.Time == "" {
+		out.Time = TimeRFC3339
+	}
+	if out.Time != TimeRFC3339 && out.Time != TimeEpoch {
+		return nil, fmt.Errorf("time %q: want %s or %s", out.Time, TimeRFC3339, TimeEpoch)
+	}
+
+	for field, target := range out.Fields {
+		object, key, dotted := strings.Cut(field, ".")
+		switch {
+		case dotted && (!slices.Contains(objectFields, object) || key == ""):
+			return nil, fmt.Errorf("field %q: only keys of %s can be mapped", field, strings.Join(objectFields, ", "))
+		case !dotted && !slices.Contains(entryFields, field):
+			return nil, fmt.Errorf("field %q: want one of %s", field, strings.Join(entryFields, ", "))
+		}
+		if err := checkSchemaKey(target); err != nil {
+			return nil, fmt.Errorf("field %q: %w", field, err)
+		}
+	}
+	if out.Rest != "" {
+		if err := checkSchemaKey(out.Rest); err != nil {
+			return nil, fmt.Errorf("rest: %w", err)
+		}
+	}
+	return &out, nil
+}
+
+// checkSchemaKey rejects output keys with empty parts, like a. or a..b
+func checkSchemaKey(target string) error {
+	if target == schemaOmit {
+		return nil
+	}
+	if slices.Contains(strings.Split(target, "."), "") {
+		return fmt.Errorf("output key %q: want a key like message or event.message, or %q to omit", target, schemaOmit)
+	}
+	return nil
+}
+
+// String names the schema for the startup banner
+func (s *Schema) String() string {
+	return s.name
+}
+
+// Apply reshapes a log entry. The entry is not changed.
+func (s *Schema) Apply(entry *LogEntry) map[string]any {
+	values := s.entryValues(entry)
+	out := make(map[string]any)
+
+	// Keys moved out of their objects go first, so what is left of the
+	// object is what its own mapping places
+	for _, field := range objectFields {
+		object, _ := values[field].(map[string]any)
+		for _, key := range sortedKeys(object) {
+			if target, ok := s.Fields[field+"."+key]; ok {
+				placeSchemaValue(out, target, object[key])
+				delete(object, key)
+			}
+		}
+	}
+	for _, field := range entryFields {
+		value, ok := values[field]
+		if !ok {
+			continue
+		}
+		if object, isObject := value.(map[string]any); isObject && len(object) == 0 {
+			continue // Every key moved elsewhere
+		}
+		target, mapped := s.Fields[field]
+		switch {
+		case mapped:
+		case s.Rest == "":
+			target = field
+		case s.Rest == schemaOmit:
+			continue
+		default:
+			target = s.Rest + "." + field
+		}
+		placeSchemaValue(out, target, value)
+	}
+	return out
+}
+
+// entryValues returns the entry's fields as they are written, leaving out
+// empty ones as LogEntry's JSON does. Objects are copies, free to change.
+func (s *Schema) entryValues(entry *LogEntry) map[string]any {
+	values := map[string]any{
+		"timestamp":       entry.Timestamp,
+		"severity":        entry.Severity,
+		"severity_number": entry.SeverityNumber,
+		"body":            entry.Body,
+	}
+	if s.Time == TimeEpoch {
+		if ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp); err == nil {
+			values["timestamp"] = float64(ts.UnixMilli()) / 1000
+		} else {
+			delete(values, "timestamp") // No time is better than one HEC cannot read
+		}
+	}
+	for field, value := range map[string]string{
+		"observed_timestamp": entry.ObservedTimestamp,
+		"trace_id":           entry.TraceID,
+		"span_id":            entry.SpanID,
+		"scope_name":         entry.ScopeName,
+		"scope_version":      entry.ScopeVersion,
+	} {
+		if value != "" {
+			values[field] = value
+		}
+	}
+	for field, n := range map[string]uint32{
+		"flags":                             entry.Flags,
+		"dropped_attributes_count":          entry.DroppedAttributes,
+		"resource_dropped_attributes_count": entry.ResourceDroppedAttrs,
+	} {
+		if n != 0 {
+			values[field] = n
+		}
+	}
+	if len(entry.Attributes) > 0 {
+		values["attributes"] = maps.Clone(entry.Attributes)
+	}
+	if len(entry.ResourceAttrs) > 0 {
+		values["resource_attributes"] = maps.Clone(entry.ResourceAttrs)
+	}
+	routing := map[string]any{"index": entry.Routing.Index, "rule": entry.Routing.Rule}
+	if entry.Routing.Sourcetype != "" {
+		routing["sourcetype"] = entry.Routing.Sourcetype
+	}
+	if entry.Routing.Source != "" {
+		routing["source"] = entry.Routing.Source
+	}
+	values["routing"] = routing
+	if len(entry.Transforms) > 0 {
+		values["transforms_applied"] = entry.Transforms
+	}
+	return values
+}
+
+// placeSchemaValue sets a dotted key in out, merging objects and otherwise
+// keeping what is already there. Objects are copied before they are
+// changed, as attribute values may be shared with other outputs.
+func placeSchemaValue(out map[string]any, target string, value any) {
+	if target == schemaOmit {
+		return
+	}
+	parts := strings.Split(target, ".")
+	parent := out
+	for _, part := range parts[:len(parts)-1] {
+		existing, ok := parent[part]
+		if !ok {
+			existing = make(map[string]any)
+			parent[part] = existing
+		}
+		next, isObject := existing.(map[string]any)
+		if !isObject {
+			return // A value already holds this key
+		}
+		next = maps.Clone(next)
+		parent[part] = next
+		parent = next
+	}
+
+	last := parts[len(parts)-1]
+	object, isObject := value.(map[string]any)
+	existing, taken := parent[last]
+	if !taken {
+		parent[last] = value
+		return
+	}
+	into, ok := existing.(map[string]any)
+	if !isObject || !ok {
+		return
+	}
+	into = maps.Clone(into)
+	for k, v := range object {
+		if _, ok := into[k]; !ok {
+			into[k] = v
+		}
+	}
+	parent[last] = into
+}
@@ -0,0 +1,150 @@
+// ABOUTME: Tests for output schemas that reshape log entries.
+// ABOUTME: Covers renaming, moving, omitting, and nesting fields, the hec-event preset, and rejected mappings.
+
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// schemaEntry is an entry with a little of everything a schema can move
+func schemaEntry() *LogEntry {
+	return &LogEntry{
+		Timestamp:      "2024-01-15T10:30:00.123Z",
+		Severity:       "ERROR",
+		SeverityNumber: 17,
+		Body:           "payment failed",
+		Attributes:     map[string]any{"http.method": "POST", "index": "tas_errors", "status": int64(503)},
+		ResourceAttrs:  map[string]any{"application_name": "payments", "status": "ignored"},
+		Routing:        RoutingInfo{Index: "tas_errors", Rule: "errors", Sourcetype: "tas:app"},
+		Transforms:     []TransformInfo{{Type: "rename", Detail: "Renamed: a -> b"}},
+	}
+}
+
+// applyJSON applies a schema and returns the result as JSON
+func applyJSON(t *testing.T, s *Schema, entry *LogEntry) string {
+	t.Helper()
+	data, err := json.Marshal(s.Apply(entry))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(data)
+}
+
+// loadSchemaYAML writes a schema file and loads it
+func loadSchemaYAML(t *testing.T, yaml string) (*Schema, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schema.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadSchema(path)
+}
+
+func TestSchema_RenameMoveOmitAndNest(t *testing.T) {
+	s, err := loadSchemaYAML(t, `
+fields:
+  body: event.message
+  routing.index: index
+  attributes.http.method: event.method
+  transforms_applied: "-"
+  severity_number: "-"
+rest: event
+`)
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	entry := schemaEntry()
+	got := applyJSON(t, s, entry)
+	want := `{"event":{"attributes":{"index":"tas_errors","status":503},"message":"payment failed","method":"POST",` +
+		`"resource_attributes":{"application_name":"payments","status":"ignored"},` +
+		`"routing":{"rule":"errors","sourcetype":"tas:app"},"severity":"ERROR","timestamp":"2024-01-15T10:30:00.123Z"},"index":"tas_errors"}`
+	if got != want {
+		t.Errorf("Apply =\n%s\nwant\n%s", got, want)
+	}
+	if _, ok := entry.Attributes["http.method"]; !ok {
+		t.Error("Apply changed the entry's attributes")
+	}
+}
+
+func TestSchema_KeepsUnmappedFieldsByDefault(t *testing.T) {
+	s, err := loadSchemaYAML(t, "fields:\n  body: message\n")
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	var got map[string]any
+	json.Unmarshal([]byte(applyJSON(t, s, schemaEntry())), &got)
+	if got["message"] != "payment failed" || got["severity"] != "ERROR" || got["routing"] == nil || got["body"] != nil {
+		t.Errorf("Apply = %v, want body renamed and the rest kept", got)
+	}
+}
+
+func TestSchema_HECEventPreset(t *testing.T) {
+	s, err := LoadSchema(SchemaHECEvent)
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	got := applyJSON(t, s, schemaEntry())
+	want := `{"event":"payment failed","fields":{"application_name":"payments","http.method":"POST","severity":"ERROR","status":503},` +
+		`"index":"tas_errors","sourcetype":"tas:app","time":1705314600.123}`
+	if got != want {
+		t.Errorf("Apply =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSchema_MergesWithoutChangingSharedValues(t *testing.T) {
+	s, err := loadSchemaYAML(t, `
+fields:
+  attributes.request: out
+  attributes.extra: out.extra
+rest: "-"
+`)
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	request := map[string]any{"method": "GET"}
+	entry := &LogEntry{Attributes: map[string]any{"request": request, "extra": "x"}}
+	if got := applyJSON(t, s, entry); got != `{"out":{"extra":"x","method":"GET"}}` {
+		t.Errorf("Apply = %s, want the extra key merged in", got)
+	}
+	if len(request) != 1 {
+		t.Errorf("attribute value changed to %v", request)
+	}
+}
+
+func TestLoadSchema_PresetOverrides(t *testing.T) {
+	s, err := loadSchemaYAML(t, "preset: hec-event\nfields:\n  body: event.message\ntime: rfc3339\n")
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	got := applyJSON(t, s, schemaEntry())
+	if !strings.Contains(got, `"event":{"message":"payment failed"}`) || !strings.Contains(got, `"time":"2024-01-15T10:30:00.123Z"`) {
+		t.Errorf("Apply = %s, want the body nested and an rfc3339 time", got)
+	}
+}
+
+func TestLoadSchema_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		yaml string
+		want string
+	}{
+		{"fields:\n  mesage: body\n", `field "mesage": want one of`},
+		{"fields:\n  severity.text: level\n", "only keys of attributes, resource_attributes, routing"},
+		{"fields:\n  body: event..message\n", `output key "event..message"`},
+		{"rest: event.\n", "rest: output key"},
+		{"time: unix\n", `time "unix"`},
+		{"preset: splunk\n", `preset "splunk"`},
+		{"feilds:\n  body: message\n", "field feilds not found"},
+	} {
+		if _, err := loadSchemaYAML(t, tc.yaml); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("LoadSchema(%q) = %v, want an error containing %q", tc.yaml, err, tc.want)
+		}
+	}
+	if _, err := LoadSchema("no-such-preset"); err == nil || !strings.Contains(err.Error(), "hec-event") {
+		t.Errorf("LoadSchema of a missing file = %v, want the presets listed", err)
+	}
+}
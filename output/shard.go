@@ -0,0 +1,124 @@
+// ABOUTME: Shards the file output across N files written round-robin, each with its own buffer and lock.
+// ABOUTME: Lets concurrent writers under load tests append in parallel instead of queueing on one JSONWriter.
+
+package output
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// ShardPath names shard i of a file output, numbered before the
+// extension, as logs-000.jsonl or logs-002.jsonl.gz
+func ShardPath(path string, i int) string {
+	dir, base := filepath.Split(path)
+	name, ext := base, ""
+	if dot := strings.Index(base[min(1, len(base)):], "."); dot >= 0 {
+		name, ext = base[:dot+1], base[dot+1:]
+	}
+	return fmt.Sprintf("%s%s-%03d%s", dir, name, i, ext)
+}
+
+// ShardWriter writes records round-robin across shard files, each with its
+// own JSONWriter, so writers on different goroutines rarely wait on the
+// same lock. Records are spread with no regard to order or content.
+type ShardWriter struct {
+	writers []*JSONWriter
+	format  Format
+	next    atomic.Uint64
+}
+
+// NewShardWriter opens n shard files named by ShardPath with open, closing
+// any already open if one fails
+func NewShardWriter(path string, n int, format Format, open func(path string) (*JSONWriter, error)) (*ShardWriter, error) {
+	if n < 2 {
+		return nil, fmt.Errorf("shards must be at least 2")
+	}
+	if path == Stdout {
+		return nil, fmt.Errorf("stdout cannot be sharded")
+	}
+	if IsSplitPath(path) {
+		return nil, fmt.Errorf("a path split by {severity} or {index} cannot be sharded")
+	}
+	s := &ShardWriter{format: format}
+	for i := range n {
+		w, err := open(ShardPath(path, i))
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("opening shard %d: %w", i, err)
+		}
+		s.writers = append(s.writers, w)
+	}
+	return s, nil
+}
+
+// Paths returns each shard's current file
+func (s *ShardWriter) Paths() []string {
+	paths := make([]string, len(s.writers))
+	for i, w := range s.writers {
+		paths[i] = w.Path()
+	}
+	return paths
+}
+
+// SetRotateHandler calls fn with the name of each file rotation finishes,
+// in every shard
+func (s *ShardWriter) SetRotateHandler(fn func(path string)) {
+	for _, w := range s.writers {
+		w.SetRotateHandler(fn)
+	}
+}
+
+// SetMetrics counts every shard's write errors, rotations, and deleted files in m
+func (s *ShardWriter) SetMetrics(m *metrics.Metrics) {
+	for _, w := range s.writers {
+		w.SetMetrics(m)
+	}
+}
+
+// Write adds a log entry to the next shard's buffer
+func (s *ShardWriter) Write(entry *LogEntry) error {
+	return s.writer().Write(entry)
+}
+
+// TakesRecords reports whether the writer takes records, as in the OTLP formats
+func (s *ShardWriter) TakesRecords() bool {
+	return s.format.IsOTLP()
+}
+
+// WriteRecord adds a record to the next shard's buffer in the OTLP formats
+func (s *ShardWriter) WriteRecord(app string, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord) error {
+	return s.writer().WriteRecord(app, resource, scope, lr)
+}
+
+// Flush writes every shard's buffered entries now
+func (s *ShardWriter) Flush() error {
+	var errs []error
+	for _, w := range s.writers {
+		errs = append(errs, w.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes and closes every shard
+func (s *ShardWriter) Close() error {
+	var errs []error
+	for _, w := range s.writers {
+		errs = append(errs, w.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// writer returns the next shard in turn
+func (s *ShardWriter) writer() *JSONWriter {
+	return s.writers[(s.next.Add(1)-1)%uint64(len(s.writers))]
+}
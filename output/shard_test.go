@@ -0,0 +1,123 @@
+// ABOUTME: Tests for sharding the file output across files written round-robin.
+// ABOUTME: Covers shard file names, even spreading, concurrent writers, and what cannot be sharded.
+
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestShardPath(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		i    int
+		want string
+	}{
+		{"logs.jsonl", 0, "logs-000.jsonl"},
+		{"/var/log/otlp/logs.jsonl", 12, "/var/log/otlp/logs-012.jsonl"},
+		{"logs.jsonl.gz", 2, "logs-002.jsonl.gz"},
+		{"logs-%Y%m%d.jsonl", 1, "logs-%Y%m%d-001.jsonl"},
+		{"logs", 3, "logs-003"},
+		{"out.d/.logs", 0, "out.d/.logs-000"},
+	} {
+		if got := ShardPath(tc.path, tc.i); got != tc.want {
+			t.Errorf("ShardPath(%q, %d) = %q, want %q", tc.path, tc.i, got, tc.want)
+		}
+	}
+}
+
+func TestShardWriter_RoundRobin(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewShardWriter(filepath.Join(dir, "logs.jsonl"), 3, FormatJSONL, openTestWriter)
+	if err != nil {
+		t.Fatalf("NewShardWriter failed: %v", err)
+	}
+	for i := range 7 {
+		s.Write(&LogEntry{Body: fmt.Sprintf("m%d", i)})
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	for i, want := range [][]string{{"m0", "m3", "m6"}, {"m1", "m4"}, {"m2", "m5"}} {
+		path := filepath.Join(dir, fmt.Sprintf("logs-%03d.jsonl", i))
+		if got := readBodies(t, path); !slices.Equal(got, want) {
+			t.Errorf("shard %d holds %v, want %v", i, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "logs.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("unsharded logs.jsonl was created")
+	}
+}
+
+func TestShardWriter_ConcurrentWriters(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewShardWriter(filepath.Join(dir, "logs.jsonl"), 4, FormatJSONL, openTestWriter)
+	if err != nil {
+		t.Fatalf("NewShardWriter failed: %v", err)
+	}
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 250 {
+				s.Write(&LogEntry{Body: fmt.Sprintf("g%d-%d", g, i)})
+			}
+		}()
+	}
+	wg.Wait()
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	total := 0
+	for _, path := range s.Paths() {
+		n := len(readBodies(t, path))
+		if n != 500 {
+			t.Errorf("%s holds %d entries, want 500", filepath.Base(path), n)
+		}
+		total += n
+	}
+	if total != 2000 {
+		t.Errorf("shards hold %d entries, want 2000", total)
+	}
+}
+
+func TestNewShardWriter_Rejects(t *testing.T) {
+	dir := t.TempDir()
+	for name, tc := range map[string]struct {
+		path string
+		n    int
+	}{
+		"one shard": {filepath.Join(dir, "logs.jsonl"), 1},
+		"stdout":    {Stdout, 2},
+		"split":     {filepath.Join(dir, "logs-{severity}.jsonl"), 2},
+	} {
+		if _, err := NewShardWriter(tc.path, tc.n, FormatJSONL, openTestWriter); err == nil {
+			t.Errorf("%s: NewShardWriter succeeded, want an error", name)
+		}
+	}
+}
+
+func TestNewShardWriter_OpenFailure(t *testing.T) {
+	dir := t.TempDir()
+	opened := 0
+	open := func(path string) (*JSONWriter, error) {
+		if opened == 2 {
+			return nil, fmt.Errorf("disk full")
+		}
+		opened++
+		return openTestWriter(path)
+	}
+	_, err := NewShardWriter(filepath.Join(dir, "logs.jsonl"), 3, FormatJSONL, open)
+	if err == nil || !strings.Contains(err.Error(), "shard 2: disk full") {
+		t.Errorf("NewShardWriter error = %v, want shard 2: disk full", err)
+	}
+}
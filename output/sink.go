@@ -0,0 +1,219 @@
+// ABOUTME: Sink interface that every output implements, and a registry of sink factories by URI scheme.
+// ABOUTME: Lets new destinations be opened from a URI like kafka://broker:9092/topic without touching the receiver.
+
+package output
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Sink is a destination for transformed records. Writes are buffered;
+// Flush sends what is buffered now, and Close flushes and stops the sink.
+type Sink interface {
+	Write(entry *LogEntry) error
+	Flush() error
+	Close() error
+}
+
+// RecordSink is a sink that can take records as OTLP rather than as log
+// entries. TakesRecords reports whether it wants them that way, as the OTLP
+// exporter always does and a Kafka sink does in the otlp format.
+type RecordSink interface {
+	Sink
+	TakesRecords() bool
+	WriteRecord(app string, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord) error
+}
+
+// errRecordsOnly is returned by Write on sinks that only take OTLP records
+var errRecordsOnly = errors.New("sink takes OTLP records, not log entries")
+
+// SinkFactory opens a sink from a URI with its registered scheme
+type SinkFactory func(u *url.URL) (Sink, error)
+
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   = make(map[string]SinkFactory)
+)
+
+// RegisterSink makes OpenSink open URIs with scheme through factory.
+// Registering a scheme twice panics.
+func RegisterSink(scheme string, factory SinkFactory) {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+	if _, ok := sinkFactories[scheme]; ok {
+		panic("output: sink scheme " + scheme + " registered twice")
+	}
+	sinkFactories[scheme] = factory
+}
+
+// SinkSchemes returns the registered schemes, sorted
+func SinkSchemes() []string {
+	sinkFactoriesMu.RLock()
+	defer sinkFactoriesMu.RUnlock()
+	return sortedKeys(sinkFactories)
+}
+
+// OpenSink opens a sink from a URI such as file:///var/log/otlp/logs.jsonl
+// or kafka://broker:9092/topic. A % that does not start an escape is taken
+// literally, so file name templates like logs-%Y%m%d.jsonl need no escaping.
+// Filter parameters, such as min-severity, wrap the sink in a FilteredSink.
+func OpenSink(uri string) (Sink, error) {
+	u, err := url.Parse(escapePercents(uri))
+	if err != nil {
+		return nil, fmt.Errorf("sink %q: %w", uri, err)
+	}
+	sinkFactoriesMu.RLock()
+	factory := sinkFactories[u.Scheme]
+	sinkFactoriesMu.RUnlock()
+	if factory == nil {
+		return nil, fmt.Errorf("sink %q: unknown scheme %q; want one of %s", uri, u.Scheme, strings.Join(SinkSchemes(), ", "))
+	}
+	values := u.Query()
+	filter, err := filterFrom(values)
+	if err != nil {
+		return nil, fmt.Errorf("sink %q: %w", uri, err)
+	}
+	if filter != nil {
+		u.RawQuery = values.Encode()
+	}
+	sink, err := factory(u)
+	if err != nil {
+		return nil, fmt.Errorf("sink %q: %w", uri, err)
+	}
+	if filter != nil {
+		return NewFilteredSink(sink, filter), nil
+	}
+	return sink, nil
+}
+
+// escapePercents escapes each % not followed by two hex digits
+func escapePercents(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && (i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2])) {
+			b.WriteString("%25")
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// sinkQuery reads a sink URI's query parameters, keeping the first error.
+// Done reports that error, or any parameter that was never read.
+type sinkQuery struct {
+	values url.Values
+	read   []string
+	err    error
+}
+
+func newSinkQuery(u *url.URL) *sinkQuery {
+	return &sinkQuery{values: u.Query()}
+}
+
+// string returns the named parameter, or def if it is not set
+func (q *sinkQuery) string(name, def string) string {
+	q.read = append(q.read, name)
+	if !q.values.Has(name) {
+		return def
+	}
+	return q.values.Get(name)
+}
+
+// int returns the named parameter as an integer, or def if it is not set
+func (q *sinkQuery) int(name string, def int) int {
+	s := q.string(name, "")
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil && q.err == nil {
+		q.err = fmt.Errorf("%s=%s: want an integer", name, s)
+	}
+	return n
+}
+
+// duration returns the named parameter as a duration, or def if it is not set
+func (q *sinkQuery) duration(name string, def time.Duration) time.Duration {
+	s := q.string(name, "")
+	if s == "" {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil && q.err == nil {
+		q.err = fmt.Errorf("%s=%s: want a duration like 5s", name, s)
+	}
+	return d
+}
+
+// bool returns the named parameter as a boolean, false if it is not set
+// and true if it is set without a value
+func (q *sinkQuery) bool(name string) bool {
+	if !q.values.Has(name) {
+		q.read = append(q.read, name)
+		return false
+	}
+	s := q.string(name, "")
+	if s == "" {
+		return true
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil && q.err == nil {
+		q.err = fmt.Errorf("%s=%s: want true or false", name, s)
+	}
+	return b
+}
+
+// done returns the first bad value, or names a parameter no one read
+func (q *sinkQuery) done() error {
+	if q.err != nil {
+		return q.err
+	}
+	for _, name := range sortedKeys(q.values) {
+		if !slices.Contains(q.read, name) {
+			return fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	return nil
+}
+
+// defaultMaxRetries is how often a sink opened from a URI retries a failed
+// batch unless max-retries says otherwise, as with the output flags
+const defaultMaxRetries = 5
+
+// baseURL turns a sink URI into the https URL of the same host and path,
+// or http with the insecure parameter, dropping its credentials and query
+func baseURL(u *url.URL, q *sinkQuery) string {
+	scheme := "https"
+	if q.bool("insecure") {
+		scheme = "http"
+	}
+	return (&url.URL{Scheme: scheme, Host: u.Host, Path: u.Path}).String()
+}
+
+// entryApp returns the app a log entry came from, as the receiver names it
+func entryApp(entry *LogEntry) string {
+	for _, attrs := range []map[string]any{entry.Attributes, entry.ResourceAttrs} {
+		for _, key := range []string{"cf_app_name", "application_name"} {
+			if app, ok := attrs[key].(string); ok {
+				return app
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+// ABOUTME: Tests for the sink registry and opening each output from its URI.
+// ABOUTME: Covers URI parameters and defaults, unknown schemes and parameters, and literal percent signs.
+
+package output
+
+import (
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// closeSink closes a sink a test opened, ignoring what it could not send
+func closeSink(t *testing.T, s Sink) {
+	t.Cleanup(func() { s.Close() })
+}
+
+func TestOpenSink_File(t *testing.T) {
+	pattern := filepath.Join(t.TempDir(), "logs-%Y%m%d.jsonl")
+	s, err := OpenSink("file://" + pattern + "?keep=2&compress&flush-interval=1s&schema=hec-event&checkpoint")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	w := s.(*JSONWriter)
+	if w.pattern != pattern || w.keep != 2 || !w.compress || w.flushInterval != time.Second || w.bufferSize != 100 ||
+		w.schema.String() != SchemaHECEvent || !w.checkpointing {
+		t.Errorf("pattern %q keep %d compress %v flush %s buffer %d schema %v checkpoint %v, want the URI's settings",
+			w.pattern, w.keep, w.compress, w.flushInterval, w.bufferSize, w.schema, w.checkpointing)
+	}
+}
+
+func TestOpenSink_FileOTLPFormat(t *testing.T) {
+	s, err := OpenSink("file://" + filepath.Join(t.TempDir(), "logs.otlp") + "?format=otlp-proto")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	if w := s.(*JSONWriter); w.format != FormatOTLPProto || !w.TakesRecords() {
+		t.Errorf("format %s, want otlp-proto taking records", w.format)
+	}
+}
+
+func TestOpenSink_FileSplit(t *testing.T) {
+	pattern := filepath.Join(t.TempDir(), "logs-{severity}.jsonl")
+	s, err := OpenSink("file://" + pattern + "?keep=2")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	if _, ok := s.(*SplitWriter); !ok {
+		t.Fatalf("OpenSink returned %T, want a split writer", s)
+	}
+}
+
+func TestOpenSink_FileShards(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSink("file://" + filepath.Join(dir, "logs.jsonl") + "?shards=3")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	w, ok := s.(*ShardWriter)
+	if !ok {
+		t.Fatalf("OpenSink returned %T, want a shard writer", s)
+	}
+	if got := len(w.Paths()); got != 3 {
+		t.Errorf("opened %d shards, want 3", got)
+	}
+}
+
+func TestOpenSink_HEC(t *testing.T) {
+	t.Setenv("HEC_TOKEN", "secret")
+	s, err := OpenSink("hec://splunk:8088?batch-size=50&sourcetype=tas")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	w := s.(*HECWriter)
+	if w.endpoint != "https://splunk:8088/services/collector/event" || w.cfg.Token != "secret" ||
+		w.cfg.Sourcetype != "tas" || w.cfg.BatchSize != 50 || w.cfg.MaxRetries != defaultMaxRetries {
+		t.Errorf("endpoint %s, config %+v, want the URI's settings", w.endpoint, w.cfg)
+	}
+}
+
+func TestOpenSink_Elasticsearch(t *testing.T) {
+	t.Setenv("ES_PASSWORD", "changeme")
+	s, err := OpenSink("es://elastic@localhost:9200?insecure&index=logs-{index}-%Y.%m")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	w := s.(*ESWriter)
+	if w.endpoint != "http://localhost:9200/_bulk" || w.cfg.Username != "elastic" || w.cfg.Password != "changeme" ||
+		w.cfg.IndexPattern != "logs-{index}-%Y.%m" {
+		t.Errorf("endpoint %s, config %+v, want the URI's settings", w.endpoint, w.cfg)
+	}
+}
+
+func TestOpenSink_Kafka(t *testing.T) {
+	s, err := OpenSink("kafka://b1:9092,b2:9092/tas-logs?format=otlp&acks=leader")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	w := s.(*KafkaWriter)
+	if !slices.Equal(w.cfg.Brokers, []string{"b1:9092", "b2:9092"}) || w.cfg.Topic != "tas-logs" || w.cfg.Acks != "leader" {
+		t.Errorf("config %+v, want the URI's settings", w.cfg)
+	}
+	if !w.TakesRecords() {
+		t.Error("otlp format should take records")
+	}
+	if err := w.Write(&LogEntry{}); err == nil {
+		t.Error("Write of an entry in the otlp format should fail")
+	}
+}
+
+func TestOpenSink_OTLP(t *testing.T) {
+	s, err := OpenSink("otlp://collector:4318?protocol=http&max-retries=0")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	e := s.(*OTLPExporter)
+	if e.Endpoint() != "https://collector:4318/v1/logs" || e.cfg.MaxRetries != 0 {
+		t.Errorf("endpoint %s, config %+v, want the URI's settings", e.Endpoint(), e.cfg)
+	}
+}
+
+func TestOpenSink_SQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.db")
+	s, err := OpenSink("sqlite://" + path + "?batch-size=500")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	if w := s.(*SQLiteWriter); w.Path() != path || w.cfg.BatchSize != 500 || w.cfg.FlushInterval != time.Second {
+		t.Errorf("config %+v, want the URI's settings", w.cfg)
+	}
+}
+
+func TestOpenSink_Stream(t *testing.T) {
+	s, err := OpenSink("stream:///var/run/otlp.sock?schema=hec-event&flush-interval=250ms")
+	if err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	closeSink(t, s)
+	if w := s.(*StreamWriter); w.Path() != "/var/run/otlp.sock" || w.cfg.Schema == nil ||
+		w.cfg.FlushInterval != 250*time.Millisecond || w.cfg.MaxRetries != defaultMaxRetries {
+		t.Errorf("config %+v, want the URI's settings", w.cfg)
+	}
+}
+
+func TestOpenSink_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		uri  string
+		want string
+	}{
+		{"carrier-pigeon://coop", `unknown scheme "carrier-pigeon"`},
+		{"kafka://b1:9092/topic?acks=all&batchsize=5", `unknown parameter "batchsize"`},
+		{"kafka://b1:9092/topic?batch-size=lots", "batch-size=lots: want an integer"},
+		{"file://logs.jsonl", "want file:///absolute/path"},
+		{"file:-?rotate-interval=hourly", "stdout cannot be rotated"},
+		{"file:-?schema=splunk", `output schema "splunk"`},
+		{"file:-?format=xml", `output format "xml"`},
+		{"file:logs.jsonl.gz?gzip-level=10", "gzip-level=10: want 1 to 9"},
+		{"file:-?shards=2", "stdout cannot be sharded"},
+		{"file:logs.jsonl?shards=0", "shards must be at least 1"},
+		{"file:-?format=otlp-json&schema=hec-event", "cannot be used with format=otlp-json"},
+		{"kafka://b1:9092", "brokers and topic are required"},
+		{"sqlite://host/logs.db", "want sqlite:///absolute/path"},
+		{"stream://host/otlp.sock", "want stream:///absolute/path"},
+	} {
+		_, err := OpenSink(tc.uri)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("OpenSink(%q) = %v, want an error containing %q", tc.uri, err, tc.want)
+		}
+	}
+}
+
+// testSinkOpened is the URI the test-sink factory last opened
+var (
+	testSinkOpened   *url.URL
+	registerTestSink sync.Once
+)
+
+func TestRegisterSink(t *testing.T) {
+	registerTestSink.Do(func() {
+		RegisterSink("test-sink", func(u *url.URL) (Sink, error) {
+			testSinkOpened = u
+			return &JSONWriter{}, nil
+		})
+	})
+	testSinkOpened = nil
+	if _, err := OpenSink("test-sink://somewhere/else"); err != nil {
+		t.Fatalf("OpenSink failed: %v", err)
+	}
+	if testSinkOpened == nil || testSinkOpened.Host != "somewhere" {
+		t.Errorf("factory got %v, want the parsed URI", testSinkOpened)
+	}
+	if !slices.Contains(SinkSchemes(), "test-sink") {
+		t.Errorf("SinkSchemes() = %v, want test-sink listed", SinkSchemes())
+	}
+}
+
+func TestEscapePercents(t *testing.T) {
+	for in, want := range map[string]string{
+		"file:///logs-%Y%m%d.jsonl": "file:///logs-%25Y%25m%25d.jsonl",
+		"file:///my%20logs.jsonl":   "file:///my%20logs.jsonl",
+		"file:///100%":              "file:///100%25",
+	} {
+		if got := escapePercents(in); got != want {
+			t.Errorf("escapePercents(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
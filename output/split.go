@@ -0,0 +1,160 @@
+// ABOUTME: Splits the file output into one file per severity and/or routed index.
+// ABOUTME: Expands {severity} and {index} in the path, opening a JSONWriter for each file as records arrive.
+
+package output
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// IsSplitPath reports whether a file output path has {severity} or {index}
+// in it, so records are split across files
+func IsSplitPath(path string) bool {
+	return strings.Contains(path, "{severity}") || strings.Contains(path, "{index}")
+}
+
+// SeverityFile names the file a severity is split into: debug (with trace),
+// info, warn, error (with fatal), or unspecified
+func SeverityFile(sev logspb.SeverityNumber) string {
+	switch {
+	case sev >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return "error"
+	case sev >= logspb.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return "warn"
+	case sev >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return "info"
+	case sev >= logspb.SeverityNumber_SEVERITY_NUMBER_TRACE:
+		return "debug"
+	}
+	return "unspecified"
+}
+
+// SplitWriter writes each record to the file its severity and routed index
+// name, each file with its own JSONWriter, buffering, and rotation
+type SplitWriter struct {
+	pattern string
+	format  Format
+	open    func(path string) (*JSONWriter, error) // Opens a file with the output's settings
+
+	mu       sync.Mutex
+	writers  map[string]*JSONWriter // By path, with {severity} and {index} expanded
+	onRotate func(string)
+	metrics  *metrics.Metrics
+}
+
+// NewSplitWriter splits records across files named by pattern, opening
+// each with open when its first record arrives
+func NewSplitWriter(pattern string, format Format, open func(path string) (*JSONWriter, error)) *SplitWriter {
+	return &SplitWriter{pattern: pattern, format: format, open: open, writers: make(map[string]*JSONWriter)}
+}
+
+// SetRotateHandler calls fn with the name of each file rotation finishes,
+// in every file
+func (s *SplitWriter) SetRotateHandler(fn func(path string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRotate = fn
+	for _, w := range s.writers {
+		w.SetRotateHandler(fn)
+	}
+}
+
+// SetMetrics counts every file's write errors, rotations, and deleted files in m
+func (s *SplitWriter) SetMetrics(m *metrics.Metrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = m
+	for _, w := range s.writers {
+		w.SetMetrics(m)
+	}
+}
+
+// Write adds a log entry to its file's buffer
+func (s *SplitWriter) Write(entry *LogEntry) error {
+	w, err := s.writer(logspb.SeverityNumber(entry.SeverityNumber), entry.Routing.Index)
+	if err != nil {
+		return err
+	}
+	return w.Write(entry)
+}
+
+// TakesRecords reports whether the writer takes records, as in the OTLP formats
+func (s *SplitWriter) TakesRecords() bool {
+	return s.format.IsOTLP()
+}
+
+// WriteRecord adds a record to its file's buffer in the OTLP formats
+func (s *SplitWriter) WriteRecord(app string, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord) error {
+	w, err := s.writer(lr.GetSeverityNumber(), recordIndex(lr))
+	if err != nil {
+		return err
+	}
+	return w.WriteRecord(app, resource, scope, lr)
+}
+
+// Flush writes every file's buffered entries now
+func (s *SplitWriter) Flush() error {
+	var errs []error
+	for _, w := range s.all() {
+		errs = append(errs, w.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes and closes every file
+func (s *SplitWriter) Close() error {
+	var errs []error
+	for _, w := range s.all() {
+		errs = append(errs, w.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// writer returns the writer for a severity and index, opening its file if need be
+func (s *SplitWriter) writer(sev logspb.SeverityNumber, index string) (*JSONWriter, error) {
+	if index == "" {
+		index = unroutedIndex
+	}
+	path := strings.NewReplacer(
+		"{severity}", SeverityFile(sev),
+		"{index}", strings.NewReplacer("/", "_", `\`, "_").Replace(index),
+	).Replace(s.pattern)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w, ok := s.writers[path]; ok {
+		return w, nil
+	}
+	w, err := s.open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	if s.onRotate != nil {
+		w.SetRotateHandler(s.onRotate)
+	}
+	if s.metrics != nil {
+		w.SetMetrics(s.metrics)
+	}
+	s.writers[path] = w
+	return w, nil
+}
+
+// all returns the open writers
+func (s *SplitWriter) all() []*JSONWriter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writers := make([]*JSONWriter, 0, len(s.writers))
+	for _, path := range sortedKeys(s.writers) {
+		writers = append(writers, s.writers[path])
+	}
+	return writers
+}
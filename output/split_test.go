@@ -0,0 +1,99 @@
+// ABOUTME: Tests for splitting the file output by severity and routed index.
+// ABOUTME: Covers severity file names, one writer per expanded path, and records in the OTLP formats.
+
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// openTestWriter opens a JSONL writer that only writes when flushed
+func openTestWriter(path string) (*JSONWriter, error) {
+	return NewJSONWriter(path, FormatJSONL, 100, time.Hour, 100*1024*1024)
+}
+
+// readBodies returns the body of each line in a JSONL file
+func readBodies(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	var bodies []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		_, body, _ := strings.Cut(line, `"body":"`)
+		body, _, _ = strings.Cut(body, `"`)
+		bodies = append(bodies, body)
+	}
+	return bodies
+}
+
+func TestSeverityFile(t *testing.T) {
+	for sev, want := range map[logspb.SeverityNumber]string{
+		logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED: "unspecified",
+		logspb.SeverityNumber_SEVERITY_NUMBER_TRACE:       "debug",
+		logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG4:      "debug",
+		logspb.SeverityNumber_SEVERITY_NUMBER_INFO:        "info",
+		logspb.SeverityNumber_SEVERITY_NUMBER_WARN3:       "warn",
+		logspb.SeverityNumber_SEVERITY_NUMBER_ERROR:       "error",
+		logspb.SeverityNumber_SEVERITY_NUMBER_FATAL:       "error",
+	} {
+		if got := SeverityFile(sev); got != want {
+			t.Errorf("SeverityFile(%s) = %q, want %q", sev, got, want)
+		}
+	}
+}
+
+func TestSplitWriter_OneFilePerSeverityAndIndex(t *testing.T) {
+	dir := t.TempDir()
+	s := NewSplitWriter(filepath.Join(dir, "{index}-{severity}.jsonl"), FormatJSONL, openTestWriter)
+	var rotated []string
+	s.SetRotateHandler(func(path string) { rotated = append(rotated, path) })
+
+	s.Write(&LogEntry{Body: "e1", SeverityNumber: 17, Routing: RoutingInfo{Index: "tas_errors"}})
+	s.Write(&LogEntry{Body: "i1", SeverityNumber: 9, Routing: RoutingInfo{Index: "tas_logs"}})
+	s.Write(&LogEntry{Body: "e2", SeverityNumber: 21, Routing: RoutingInfo{Index: "tas_errors"}})
+	s.Write(&LogEntry{Body: "u1"})
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	if want := []string{"tas_errors-error.jsonl", "tas_logs-info.jsonl", "unrouted-unspecified.jsonl"}; !slices.Equal(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	if got := readBodies(t, filepath.Join(dir, "tas_errors-error.jsonl")); !slices.Equal(got, []string{"e1", "e2"}) {
+		t.Errorf("error file holds %v, want [e1 e2]", got)
+	}
+}
+
+func TestSplitWriter_RecordsBySeverity(t *testing.T) {
+	dir := t.TempDir()
+	open := func(path string) (*JSONWriter, error) {
+		return NewJSONWriter(path, FormatOTLPJSON, 100, time.Hour, 100*1024*1024)
+	}
+	s := NewSplitWriter(filepath.Join(dir, "{severity}.otlp.jsonl"), FormatOTLPJSON, open)
+	if !s.TakesRecords() {
+		t.Fatal("otlp-json split writer should take records")
+	}
+	warn := testRecord("slow")
+	warn.SeverityNumber = logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	s.WriteRecord("web", &resourcepb.Resource{}, nil, warn)
+	s.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "warn.otlp.jsonl"))
+	if err != nil || !strings.Contains(string(data), `"stringValue":"slow"`) {
+		t.Errorf("warn file = %q, %v; want the record", data, err)
+	}
+}
@@ -0,0 +1,296 @@
+// ABOUTME: SQLite output that stores transformed records in a local database file, indexed for searching.
+// ABOUTME: Gives a self-contained store that the /api/logs endpoints query, with no server to run.
+
+package output
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	_ "modernc.org/sqlite" // Pure Go, so no cgo or system library is needed
+
+	"otlp-mock-receiver/metrics"
+)
+
+// SQLiteConfig configures writing to a SQLite database
+type SQLiteConfig struct {
+	Path          string        // Database file, created if missing
+	BatchSize     int           // Rows per transaction
+	FlushInterval time.Duration // Longest a partial batch waits, and so how stale queries can be
+}
+
+// sqliteSchema creates the logs table and the indexes queries filter on
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS logs (
+	id              INTEGER PRIMARY KEY,
+	time_unix_nano  INTEGER NOT NULL,
+	app             TEXT NOT NULL,
+	severity_number INTEGER NOT NULL,
+	severity        TEXT NOT NULL,
+	index_name      TEXT NOT NULL,
+	body            TEXT NOT NULL,
+	entry           TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS logs_time ON logs (time_unix_nano);
+CREATE INDEX IF NOT EXISTS logs_app ON logs (app, time_unix_nano);
+CREATE INDEX IF NOT EXISTS logs_severity ON logs (severity_number, time_unix_nano);
+CREATE INDEX IF NOT EXISTS logs_index ON logs (index_name, time_unix_nano);
+`
+
+// LogQuery selects stored records. Empty fields match every record.
+type LogQuery struct {
+	App         string
+	Index       string
+	MinSeverity logspb.SeverityNumber
+	Since       time.Time // Records at or after
+	Until       time.Time // Records before
+	Contains    string    // Substring of the body, case-sensitive
+	Limit       int       // Most records returned, newest first
+}
+
+// LogCount is the number of stored records with one app, index, or severity
+type LogCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// Summary groupings, as the column each counts by
+var summaryColumns = map[string]string{
+	"app":      "app",
+	"index":    "index_name",
+	"severity": "severity",
+}
+
+// SQLiteWriter batches log entries and inserts them into a SQLite database
+// from a background goroutine, one transaction per batch. Each row keeps
+// the entry as written to the JSON output, with the fields queries filter
+// on in their own indexed columns.
+type SQLiteWriter struct {
+	*batcher[*LogEntry]
+	cfg SQLiteConfig
+	db  *sql.DB
+
+	mu      sync.Mutex
+	metrics *metrics.Metrics
+}
+
+func init() {
+	RegisterSink("sqlite", openSQLite)
+}
+
+// openSQLite opens a SQLite sink from a URI like
+// sqlite:///var/lib/otlp/logs.db?batch-size=500, or sqlite:logs.db for a
+// relative path
+func openSQLite(u *url.URL) (Sink, error) {
+	path := u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+	if u.Host != "" || path == "" {
+		return nil, fmt.Errorf("want sqlite:///absolute/path or sqlite:relative/path")
+	}
+	q := newSinkQuery(u)
+	cfg := SQLiteConfig{
+		Path:          path,
+		BatchSize:     q.int("batch-size", 100),
+		FlushInterval: q.duration("flush-interval", time.Second),
+	}
+	if err := q.done(); err != nil {
+		return nil, err
+	}
+	return NewSQLiteWriter(cfg)
+}
+
+// NewSQLiteWriter opens or creates the database and starts writing
+func NewSQLiteWriter(cfg SQLiteConfig) (*SQLiteWriter, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("SQLite database path is required")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	// WAL lets queries read while a batch is written; the busy timeout
+	// covers the moments they cannot
+	db, err := sql.Open("sqlite", "file:"+cfg.Path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", cfg.Path, err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open %s: %w", cfg.Path, err)
+	}
+
+	w := &SQLiteWriter{cfg: cfg, db: db}
+	w.batcher = newBatcher("SQLite", cfg.BatchSize, cfg.FlushInterval, defaultMaxRetries, w.send, w.count)
+	return w, nil
+}
+
+// Path returns the database file
+func (w *SQLiteWriter) Path() string {
+	return w.cfg.Path
+}
+
+// SetMetrics counts written, failed, and discarded rows in m, and reports those waiting to be sent
+func (w *SQLiteWriter) SetMetrics(m *metrics.Metrics) {
+	w.batcher.setMetrics(m, "sqlite")
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.metrics = m
+}
+
+// Write queues a log entry for the next transaction
+func (w *SQLiteWriter) Write(entry *LogEntry) error {
+	w.add(entry)
+	return nil
+}
+
+// Flush writes what is queued now
+func (w *SQLiteWriter) Flush() error {
+	return w.flush()
+}
+
+// Close writes what is queued and closes the database
+func (w *SQLiteWriter) Close() error {
+	err := w.close()
+	if closeErr := w.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// send inserts one batch in a transaction. A database that is locked or
+// cannot be written is retried, as a disk may come back.
+func (w *SQLiteWriter) send(batch []*LogEntry) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return retryableError{err: err}
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`INSERT INTO logs (time_unix_nano, app, severity_number, severity, index_name, body, entry)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return retryableError{err: err}
+	}
+	defer stmt.Close()
+
+	size := 0
+	for _, entry := range batch {
+		ts, err := time.Parse(time.RFC3339Nano, entry.Timestamp)
+		if err != nil || ts.UnixNano() <= 0 {
+			ts = time.Now()
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(ts.UnixNano(), entryApp(entry), entry.SeverityNumber, entry.Severity,
+			entry.Routing.Index, entry.Body, string(data)); err != nil {
+			return retryableError{err: err}
+		}
+		size += len(data)
+	}
+	if err := tx.Commit(); err != nil {
+		return retryableError{err: err}
+	}
+	w.count(resultSent, len(batch))
+	w.sent(len(batch), size)
+	return nil
+}
+
+// Query returns the stored entries q matches, newest first, as written
+func (w *SQLiteWriter) Query(q LogQuery) ([]json.RawMessage, error) {
+	where, args := q.where()
+	args = append(args, q.Limit)
+	rows, err := w.db.Query("SELECT entry FROM logs"+where+" ORDER BY time_unix_nano DESC, id DESC LIMIT ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []json.RawMessage{}
+	for rows.Next() {
+		var entry string
+		if err := rows.Scan(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, json.RawMessage(entry))
+	}
+	return entries, rows.Err()
+}
+
+// Summary counts the stored entries q matches by app, index, or severity,
+// most first. q's limit is ignored.
+func (w *SQLiteWriter) Summary(by string, q LogQuery) ([]LogCount, error) {
+	column, ok := summaryColumns[by]
+	if !ok {
+		return nil, fmt.Errorf("by=%s: want one of %s", by, strings.Join(sortedKeys(summaryColumns), ", "))
+	}
+	where, args := q.where()
+	rows, err := w.db.Query("SELECT "+column+", COUNT(*) FROM logs"+where+
+		" GROUP BY "+column+" ORDER BY COUNT(*) DESC, "+column, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []LogCount{}
+	for rows.Next() {
+		var c LogCount
+		if err := rows.Scan(&c.Key, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// where builds the WHERE clause and its arguments for the query's filters
+func (q LogQuery) where() (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+	if q.App != "" {
+		add("app = ?", q.App)
+	}
+	if q.Index != "" {
+		add("index_name = ?", q.Index)
+	}
+	if q.MinSeverity > 0 {
+		add("severity_number >= ?", int32(q.MinSeverity))
+	}
+	if !q.Since.IsZero() {
+		add("time_unix_nano >= ?", q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		add("time_unix_nano < ?", q.Until.UnixNano())
+	}
+	if q.Contains != "" {
+		add("instr(body, ?) > 0", q.Contains)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// count adds n rows to the result's metric
+func (w *SQLiteWriter) count(result string, n int) {
+	w.mu.Lock()
+	m := w.metrics
+	w.mu.Unlock()
+	if m != nil && n > 0 {
+		m.SQLiteRows.WithLabelValues(result).Add(float64(n))
+	}
+}
@@ -0,0 +1,142 @@
+// ABOUTME: Tests for storing records in SQLite and querying them back.
+// ABOUTME: Covers each query filter, newest-first ordering and limits, summaries, and reopening an existing database.
+
+package output
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// storeEntries writes entries to a new database and flushes them
+func storeEntries(t *testing.T, path string, entries ...*LogEntry) *SQLiteWriter {
+	t.Helper()
+	w, err := NewSQLiteWriter(SQLiteConfig{Path: path, BatchSize: 100, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewSQLiteWriter failed: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	for _, entry := range entries {
+		w.Write(entry)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	return w
+}
+
+// queryBodies returns the bodies of the entries q matches, in order
+func queryBodies(t *testing.T, w *SQLiteWriter, q LogQuery) []string {
+	t.Helper()
+	if q.Limit == 0 {
+		q.Limit = 100
+	}
+	logs, err := w.Query(q)
+	if err != nil {
+		t.Fatalf("Query(%+v) failed: %v", q, err)
+	}
+	var bodies []string
+	for _, raw := range logs {
+		var entry LogEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			t.Fatalf("stored entry %s: %v", raw, err)
+		}
+		bodies = append(bodies, entry.Body)
+	}
+	return bodies
+}
+
+// sqliteEntry is an entry from app at minute min past 10:00, routed to index
+func sqliteEntry(body, app, index string, sev int32, min int) *LogEntry {
+	return &LogEntry{
+		Timestamp:      time.Date(2024, 1, 15, 10, min, 0, 0, time.UTC).Format(time.RFC3339Nano),
+		Severity:       logspb.SeverityNumber(sev).String(),
+		SeverityNumber: sev,
+		Body:           body,
+		Attributes:     map[string]any{"cf_app_name": app},
+		Routing:        RoutingInfo{Index: index},
+	}
+}
+
+func TestSQLiteWriter_Query(t *testing.T) {
+	m := metrics.New()
+	w := storeEntries(t, filepath.Join(t.TempDir(), "logs.db"),
+		sqliteEntry("checkout started", "checkout", "tas_logs", 9, 0),
+		sqliteEntry("payment declined", "payment", "tas_errors", 17, 1),
+		sqliteEntry("checkout failed", "checkout", "tas_errors", 17, 2),
+		sqliteEntry("cache miss", "checkout", "tas_logs", 5, 3),
+	)
+	w.SetMetrics(m)
+
+	since := time.Date(2024, 1, 15, 10, 1, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name  string
+		query LogQuery
+		want  []string
+	}{
+		{"all, newest first", LogQuery{}, []string{"cache miss", "checkout failed", "payment declined", "checkout started"}},
+		{"app", LogQuery{App: "checkout"}, []string{"cache miss", "checkout failed", "checkout started"}},
+		{"index", LogQuery{Index: "tas_errors"}, []string{"checkout failed", "payment declined"}},
+		{"min severity", LogQuery{MinSeverity: logspb.SeverityNumber_SEVERITY_NUMBER_INFO}, []string{"checkout failed", "payment declined", "checkout started"}},
+		{"time range", LogQuery{Since: since, Until: since.Add(2 * time.Minute)}, []string{"checkout failed", "payment declined"}},
+		{"body", LogQuery{Contains: "checkout"}, []string{"checkout failed", "checkout started"}},
+		{"limit", LogQuery{App: "checkout", Limit: 1}, []string{"cache miss"}},
+		{"no match", LogQuery{App: "orders"}, nil},
+	} {
+		if got := queryBodies(t, w, tc.query); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	w.Write(sqliteEntry("late", "orders", "tas_logs", 9, 4))
+	w.Flush()
+	if got := testutil.ToFloat64(m.SQLiteRows.WithLabelValues(resultSent)); got != 1 {
+		t.Errorf("sent rows = %v, want 1 after metrics were set", got)
+	}
+}
+
+func TestSQLiteWriter_Summary(t *testing.T) {
+	w := storeEntries(t, filepath.Join(t.TempDir(), "logs.db"),
+		sqliteEntry("a", "checkout", "tas_logs", 9, 0),
+		sqliteEntry("b", "payment", "tas_errors", 17, 1),
+		sqliteEntry("c", "checkout", "tas_errors", 17, 2),
+	)
+	counts, err := w.Summary("index", LogQuery{})
+	if err != nil {
+		t.Fatalf("Summary failed: %v", err)
+	}
+	if want := []LogCount{{"tas_errors", 2}, {"tas_logs", 1}}; !slices.Equal(counts, want) {
+		t.Errorf("by index = %v, want %v", counts, want)
+	}
+	counts, _ = w.Summary("app", LogQuery{Index: "tas_errors"})
+	if want := []LogCount{{"checkout", 1}, {"payment", 1}}; !slices.Equal(counts, want) {
+		t.Errorf("by app in tas_errors = %v, want %v", counts, want)
+	}
+	if _, err := w.Summary("host", LogQuery{}); err == nil {
+		t.Error("Summary by an unknown column should fail")
+	}
+}
+
+func TestSQLiteWriter_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.db")
+	first, err := NewSQLiteWriter(SQLiteConfig{Path: path})
+	if err != nil {
+		t.Fatalf("NewSQLiteWriter failed: %v", err)
+	}
+	first.Write(sqliteEntry("before restart", "checkout", "tas_logs", 9, 0))
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	w := storeEntries(t, path, sqliteEntry("after restart", "checkout", "tas_logs", 9, 1))
+	if got := queryBodies(t, w, LogQuery{}); !slices.Equal(got, []string{"after restart", "before restart"}) {
+		t.Errorf("got %q, want both runs' records", got)
+	}
+}
@@ -0,0 +1,217 @@
+// ABOUTME: Stream output that writes JSON lines to a named pipe or Unix socket another local process reads.
+// ABOUTME: Lets a real forwarder, like a Splunk UF or fluent-bit, consume the transformed records live.
+
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// streamTimeout bounds connecting to a socket and each write, so a reader
+// that stops reading is let go rather than holding up the stream
+const streamTimeout = 10 * time.Second
+
+// StreamConfig configures streaming JSON lines to a local reader
+type StreamConfig struct {
+	Path          string        // A named pipe, or a Unix socket the reader listens on
+	BatchSize     int           // Entries per write
+	FlushInterval time.Duration // Longest a partial batch waits
+	MaxRetries    int           // Attempts after the first before a batch is dropped
+	Schema        *Schema       // Reshapes each entry, as for the file output
+}
+
+// streamConn is an open pipe or socket
+type streamConn interface {
+	io.WriteCloser
+	SetWriteDeadline(t time.Time) error
+}
+
+// StreamWriter batches log entries and writes them as JSON lines to a
+// named pipe or Unix socket from a background goroutine. Which one is
+// decided each time it connects, by what is at the path. With no reader,
+// batches are retried and then dropped, as for the forwarding outputs.
+type StreamWriter struct {
+	*batcher[*LogEntry]
+	cfg StreamConfig
+
+	connMu sync.Mutex
+	conn   streamConn // Nil until a reader is found, and after one goes away
+
+	mu      sync.Mutex
+	metrics *metrics.Metrics
+}
+
+func init() {
+	RegisterSink("stream", openStream)
+}
+
+// openStream opens a stream sink from a URI like
+// stream:///var/run/otlp.sock?schema=hec-event, or stream:otlp.fifo for a
+// relative path
+func openStream(u *url.URL) (Sink, error) {
+	path := u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+	if u.Host != "" || path == "" {
+		return nil, fmt.Errorf("want stream:///absolute/path or stream:relative/path")
+	}
+	q := newSinkQuery(u)
+	cfg := StreamConfig{
+		Path:          path,
+		BatchSize:     q.int("batch-size", 100),
+		FlushInterval: q.duration("flush-interval", time.Second),
+		MaxRetries:    q.int("max-retries", defaultMaxRetries),
+	}
+	schemaName := q.string("schema", "")
+	if err := q.done(); err != nil {
+		return nil, err
+	}
+	if schemaName != "" {
+		var err error
+		if cfg.Schema, err = LoadSchema(schemaName); err != nil {
+			return nil, err
+		}
+	}
+	return NewStreamWriter(cfg)
+}
+
+// NewStreamWriter starts streaming to cfg.Path. The reader need not be
+// there yet; each batch looks for it.
+func NewStreamWriter(cfg StreamConfig) (*StreamWriter, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("stream path is required")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	w := &StreamWriter{cfg: cfg}
+	w.batcher = newBatcher("Stream", cfg.BatchSize, cfg.FlushInterval, cfg.MaxRetries, w.send, w.count)
+	return w, nil
+}
+
+// Path returns the pipe or socket streamed to
+func (w *StreamWriter) Path() string {
+	return w.cfg.Path
+}
+
+// SetMetrics counts streamed, failed, and discarded entries in m, and reports those waiting to be sent
+func (w *StreamWriter) SetMetrics(m *metrics.Metrics) {
+	w.batcher.setMetrics(m, "stream")
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.metrics = m
+}
+
+// Write queues a log entry for the next batch. While no one reads, up to
+// 100 batches are held, discarding the oldest entries beyond that.
+func (w *StreamWriter) Write(entry *LogEntry) error {
+	w.add(entry)
+	return nil
+}
+
+// Flush writes what is queued now, with at most one attempt per batch
+func (w *StreamWriter) Flush() error {
+	return w.flush()
+}
+
+// Close writes what is queued, with at most one attempt per batch, and
+// closes the pipe or socket, which the reader sees as the end of the stream
+func (w *StreamWriter) Close() error {
+	err := w.close()
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	return err
+}
+
+// send writes one batch as JSON lines, connecting first if need be. A
+// failed write drops the connection, so the next attempt finds the reader
+// again; what it saw of the batch ends in a partial line.
+func (w *StreamWriter) send(batch []*LogEntry) error {
+	var data []byte
+	for _, entry := range batch {
+		var doc any = entry
+		if w.cfg.Schema != nil {
+			doc = w.cfg.Schema.Apply(entry)
+		}
+		line, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+	if w.conn == nil {
+		conn, err := dialStream(w.cfg.Path)
+		if err != nil {
+			return retryableError{err: err}
+		}
+		slog.Info("Stream: connected", "path", w.cfg.Path)
+		w.conn = conn
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(streamTimeout))
+	if _, err := w.conn.Write(data); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return retryableError{err: fmt.Errorf("write %s: %w", w.cfg.Path, err)}
+	}
+	w.count(resultSent, len(batch))
+	w.sent(len(batch), len(data))
+	return nil
+}
+
+// dialStream opens the named pipe or connects to the Unix socket at path
+func dialStream(path string) (streamConn, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("no reader: %w", err)
+	}
+	switch mode := info.Mode(); {
+	case mode&os.ModeNamedPipe != 0:
+		// Non-blocking, so opening fails rather than waits when no one reads
+		f, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if errors.Is(err, syscall.ENXIO) {
+			return nil, fmt.Errorf("no reader has %s open", path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	case mode&os.ModeSocket != 0:
+		return net.DialTimeout("unix", path, streamTimeout)
+	default:
+		return nil, fmt.Errorf("%s is not a named pipe or Unix socket", path)
+	}
+}
+
+// count adds n entries to the result's metric
+func (w *StreamWriter) count(result string, n int) {
+	w.mu.Lock()
+	m := w.metrics
+	w.mu.Unlock()
+	if m != nil && n > 0 {
+		m.StreamRecords.WithLabelValues(result).Add(float64(n))
+	}
+}
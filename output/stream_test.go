@@ -0,0 +1,228 @@
+//go:build unix
+
+// ABOUTME: Tests for streaming JSON lines to a named pipe or Unix socket.
+// ABOUTME: Covers both kinds of reader, a reader that is missing or goes away, schemas, and the URI form.
+
+package output
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// shortTempDir returns a directory whose paths fit in a Unix socket address
+func shortTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// newTestStream opens a stream writer that only writes when flushed
+func newTestStream(t *testing.T, path string) (*StreamWriter, *metrics.Metrics) {
+	t.Helper()
+	w, err := NewStreamWriter(StreamConfig{Path: path, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewStreamWriter failed: %v", err)
+	}
+	m := metrics.New()
+	w.SetMetrics(m)
+	return w, m
+}
+
+// readLines reads n lines, failing if they take more than a few seconds
+func readLines(t *testing.T, r *bufio.Reader, n int) []string {
+	t.Helper()
+	lines := make(chan []string, 1)
+	go func() {
+		var got []string
+		for range n {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			got = append(got, strings.TrimSpace(line))
+		}
+		lines <- got
+	}()
+	select {
+	case got := <-lines:
+		return got
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out reading %d lines", n)
+		return nil
+	}
+}
+
+func TestStreamWriter_UnixSocket(t *testing.T) {
+	path := filepath.Join(shortTempDir(t), "otlp.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	w, m := newTestStream(t, path)
+	w.Write(&LogEntry{Body: "first"})
+	w.Write(&LogEntry{Body: "second"})
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	defer conn.Close()
+	lines := readLines(t, bufio.NewReader(conn), 2)
+	if len(lines) != 2 || !strings.Contains(lines[0], `"body":"first"`) || !strings.Contains(lines[1], `"body":"second"`) {
+		t.Errorf("reader got %q, want the two entries in order", lines)
+	}
+	if got := testutil.ToFloat64(m.StreamRecords.WithLabelValues(resultSent)); got != 2 {
+		t.Errorf("sent = %v, want 2", got)
+	}
+
+	// Closing ends the reader's stream
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err == nil {
+		t.Error("reader still open after Close")
+	}
+}
+
+func TestStreamWriter_NamedPipe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otlp.fifo")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Fatalf("Mkfifo failed: %v", err)
+	}
+	w, m := newTestStream(t, path)
+	defer w.Close()
+
+	// No one reads yet
+	w.Write(&LogEntry{Body: "unread"})
+	if err := w.Flush(); err == nil || !strings.Contains(err.Error(), "no reader has") {
+		t.Errorf("Flush with no reader = %v, want no reader", err)
+	}
+	if got := testutil.ToFloat64(m.StreamRecords.WithLabelValues(resultFailed)); got != 1 {
+		t.Errorf("failed = %v, want 1", got)
+	}
+
+	r, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatalf("opening reader: %v", err)
+	}
+	defer r.Close()
+	w.Write(&LogEntry{Body: "read"})
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if lines := readLines(t, bufio.NewReader(r), 1); len(lines) != 1 || !strings.Contains(lines[0], `"body":"read"`) {
+		t.Errorf("reader got %q, want the entry written after it opened", lines)
+	}
+}
+
+func TestStreamWriter_ReconnectsAfterReaderGoes(t *testing.T) {
+	path := filepath.Join(shortTempDir(t), "otlp.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	w, _ := newTestStream(t, path)
+	defer w.Close()
+
+	w.Write(&LogEntry{Body: "before"})
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	first, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	first.Close()
+
+	// The first write after the reader goes fails and drops the connection;
+	// the next one connects again
+	for attempt := 0; attempt < 3; attempt++ {
+		w.Write(&LogEntry{Body: "after"})
+		if err := w.Flush(); err != nil {
+			continue
+		}
+		ln.(*net.UnixListener).SetDeadline(time.Now().Add(100 * time.Millisecond))
+		second, err := ln.Accept()
+		if err != nil {
+			continue // Still written to the old connection
+		}
+		defer second.Close()
+		if lines := readLines(t, bufio.NewReader(second), 1); len(lines) != 1 || !strings.Contains(lines[0], `"body":"after"`) {
+			t.Errorf("new reader got %q, want the entry written after it connected", lines)
+		}
+		return
+	}
+	t.Fatal("writer never connected to a new reader")
+}
+
+func TestStreamWriter_Schema(t *testing.T) {
+	path := filepath.Join(shortTempDir(t), "otlp.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	schema, err := LoadSchema("hec-event")
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	w, err := NewStreamWriter(StreamConfig{Path: path, FlushInterval: time.Hour, Schema: schema})
+	if err != nil {
+		t.Fatalf("NewStreamWriter failed: %v", err)
+	}
+	defer w.Close()
+	w.Write(&LogEntry{Body: "shaped", Routing: RoutingInfo{Index: "tas_logs"}})
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	defer conn.Close()
+	if lines := readLines(t, bufio.NewReader(conn), 1); len(lines) != 1 || !strings.Contains(lines[0], `"event":"shaped"`) {
+		t.Errorf("reader got %q, want a HEC event", lines)
+	}
+}
+
+func TestStreamWriter_NotAPipeOrSocket(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plain.jsonl")
+	os.WriteFile(path, nil, 0644)
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{path, "is not a named pipe or Unix socket"},
+		{filepath.Join(dir, "missing.sock"), "no reader"},
+	} {
+		w, _ := newTestStream(t, tc.path)
+		w.Write(&LogEntry{Body: "lost"})
+		if err := w.Flush(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Flush to %s = %v, want %q", filepath.Base(tc.path), err, tc.want)
+		}
+		w.Close()
+	}
+}
@@ -0,0 +1,164 @@
+// ABOUTME: Live-tail output that fans transformed entries out to connected clients as they are written.
+// ABOUTME: Each client has its own filter and bounded buffer, so a slow one misses entries rather than holding up the rest.
+
+package output
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// TailFilter selects the entries a live-tail client sees. Empty fields
+// match every entry.
+type TailFilter struct {
+	App         string
+	Index       string
+	MinSeverity logspb.SeverityNumber
+}
+
+// Matches reports whether the filter passes an entry
+func (f TailFilter) Matches(entry *LogEntry) bool {
+	if f.App != "" && entryApp(entry) != f.App {
+		return false
+	}
+	if f.Index != "" && entry.Routing.Index != f.Index {
+		return false
+	}
+	return f.MinSeverity == 0 || logspb.SeverityNumber(entry.SeverityNumber) >= f.MinSeverity
+}
+
+// TailClient is one subscriber to a live tail
+type TailClient struct {
+	filter  TailFilter
+	entries chan []byte
+	dropped atomic.Int64
+}
+
+// Entries delivers each matching entry as JSON, and is closed when the
+// client unsubscribes or the tail closes
+func (c *TailClient) Entries() <-chan []byte {
+	return c.entries
+}
+
+// Dropped returns how many entries the client missed because its buffer was full
+func (c *TailClient) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// LiveTail is a sink that hands each entry, as JSON, to every subscribed
+// client whose filter it matches. With no clients, writes cost next to
+// nothing.
+type LiveTail struct {
+	buffer int // Entries each client may have waiting
+
+	mu      sync.RWMutex
+	clients map[*TailClient]struct{}
+	closed  bool
+	metrics *metrics.Metrics
+}
+
+// NewLiveTail creates a live tail that buffers up to buffer entries per client
+func NewLiveTail(buffer int) *LiveTail {
+	if buffer <= 0 {
+		buffer = 1000
+	}
+	return &LiveTail{buffer: buffer, clients: make(map[*TailClient]struct{})}
+}
+
+// SetMetrics reports connected clients and the entries they missed in m
+func (t *LiveTail) SetMetrics(m *metrics.Metrics) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.metrics = m
+}
+
+// Subscribe adds a client that sees the entries f matches from now on.
+// After Close, the client's entries are already closed.
+func (t *LiveTail) Subscribe(f TailFilter) *TailClient {
+	c := &TailClient{filter: f, entries: make(chan []byte, t.buffer)}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		close(c.entries)
+		return c
+	}
+	t.clients[c] = struct{}{}
+	if t.metrics != nil {
+		t.metrics.LiveTailClients.Inc()
+	}
+	return c
+}
+
+// Unsubscribe removes a client and closes its entries
+func (t *LiveTail) Unsubscribe(c *TailClient) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.clients[c]; !ok {
+		return
+	}
+	delete(t.clients, c)
+	close(c.entries)
+	if t.metrics != nil {
+		t.metrics.LiveTailClients.Dec()
+	}
+}
+
+// Clients returns how many clients are subscribed
+func (t *LiveTail) Clients() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.clients)
+}
+
+// Write hands the entry to each client whose filter matches it. A client
+// whose buffer is full misses it.
+func (t *LiveTail) Write(entry *LogEntry) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var data []byte
+	for c := range t.clients {
+		if !c.filter.Matches(entry) {
+			continue
+		}
+		if data == nil {
+			var err error
+			if data, err = json.Marshal(entry); err != nil {
+				return err
+			}
+		}
+		select {
+		case c.entries <- data:
+		default:
+			c.dropped.Add(1)
+			if t.metrics != nil {
+				t.metrics.LiveTailDropped.Inc()
+			}
+		}
+	}
+	return nil
+}
+
+// Flush does nothing, as entries are handed over as they are written
+func (t *LiveTail) Flush() error {
+	return nil
+}
+
+// Close closes every client's entries, ending their streams
+func (t *LiveTail) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for c := range t.clients {
+		close(c.entries)
+		if t.metrics != nil {
+			t.metrics.LiveTailClients.Dec()
+		}
+	}
+	clear(t.clients)
+	t.closed = true
+	return nil
+}
@@ -0,0 +1,114 @@
+// ABOUTME: Tests for the live-tail output that fans entries out to subscribed clients.
+// ABOUTME: Covers client filters, slow clients missing entries, unsubscribing, and closing.
+
+package output
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// tailBodies returns the bodies of the entries waiting for a client
+func tailBodies(t *testing.T, c *TailClient) []string {
+	t.Helper()
+	var bodies []string
+	for {
+		select {
+		case data, ok := <-c.Entries():
+			if !ok {
+				return bodies
+			}
+			var entry LogEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				t.Fatalf("decode %s: %v", data, err)
+			}
+			bodies = append(bodies, entry.Body)
+		default:
+			return bodies
+		}
+	}
+}
+
+func TestTailFilter_Matches(t *testing.T) {
+	entry := &LogEntry{SeverityNumber: 13, Attributes: map[string]any{"cf_app_name": "checkout"},
+		Routing: RoutingInfo{Index: "tas_logs"}}
+	for _, tc := range []struct {
+		filter TailFilter
+		want   bool
+	}{
+		{TailFilter{}, true},
+		{TailFilter{App: "checkout", Index: "tas_logs", MinSeverity: 13}, true},
+		{TailFilter{App: "payment"}, false},
+		{TailFilter{Index: "tas_errors"}, false},
+		{TailFilter{MinSeverity: 17}, false},
+	} {
+		if got := tc.filter.Matches(entry); got != tc.want {
+			t.Errorf("%+v.Matches = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestLiveTail_FansOutByFilter(t *testing.T) {
+	tail := NewLiveTail(10)
+	all := tail.Subscribe(TailFilter{})
+	errors := tail.Subscribe(TailFilter{MinSeverity: 17})
+
+	tail.Write(&LogEntry{Body: "started", SeverityNumber: 9})
+	tail.Write(&LogEntry{Body: "failed", SeverityNumber: 17})
+
+	if got := tailBodies(t, all); len(got) != 2 || got[0] != "started" || got[1] != "failed" {
+		t.Errorf("unfiltered client got %v, want [started failed]", got)
+	}
+	if got := tailBodies(t, errors); len(got) != 1 || got[0] != "failed" {
+		t.Errorf("error client got %v, want [failed]", got)
+	}
+}
+
+func TestLiveTail_SlowClientMissesEntries(t *testing.T) {
+	tail := NewLiveTail(2)
+	m := metrics.New()
+	tail.SetMetrics(m)
+	slow := tail.Subscribe(TailFilter{})
+	for range 5 {
+		tail.Write(&LogEntry{Body: "x"})
+	}
+	if got := len(tailBodies(t, slow)); got != 2 {
+		t.Errorf("slow client got %d entries, want its buffer of 2", got)
+	}
+	if slow.Dropped() != 3 {
+		t.Errorf("Dropped() = %d, want 3", slow.Dropped())
+	}
+	if got := testutil.ToFloat64(m.LiveTailDropped); got != 3 {
+		t.Errorf("dropped metric = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(m.LiveTailClients); got != 1 {
+		t.Errorf("clients metric = %v, want 1", got)
+	}
+}
+
+func TestLiveTail_UnsubscribeAndClose(t *testing.T) {
+	tail := NewLiveTail(10)
+	left := tail.Subscribe(TailFilter{})
+	stayed := tail.Subscribe(TailFilter{})
+	tail.Unsubscribe(left)
+	tail.Unsubscribe(left) // Twice is harmless
+	if tail.Clients() != 1 {
+		t.Errorf("Clients() = %d, want 1", tail.Clients())
+	}
+	if _, ok := <-left.Entries(); ok {
+		t.Error("unsubscribed client's entries still open")
+	}
+
+	tail.Close()
+	if _, ok := <-stayed.Entries(); ok {
+		t.Error("client's entries still open after Close")
+	}
+	tail.Unsubscribe(stayed) // After Close is harmless
+	if _, ok := <-tail.Subscribe(TailFilter{}).Entries(); ok {
+		t.Error("client subscribed after Close got open entries")
+	}
+}
@@ -0,0 +1,39 @@
+// ABOUTME: Conversion of OTLP AnyValues to native Go values for log entry attributes.
+// ABOUTME: Keeps numbers, booleans, arrays, and nested key-value lists as their JSON types.
+
+package output
+
+import (
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// Value converts an OTLP value to the Go value it encodes as in JSON: a
+// string, int64, float64, bool, []byte (base64 in JSON), []any, or
+// map[string]any for a key-value list, recursively. An empty value is nil.
+func Value(v *commonpb.AnyValue) any {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_IntValue:
+		return val.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return val.DoubleValue
+	case *commonpb.AnyValue_BoolValue:
+		return val.BoolValue
+	case *commonpb.AnyValue_BytesValue:
+		return val.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, len(val.ArrayValue.GetValues()))
+		for i, item := range val.ArrayValue.GetValues() {
+			values[i] = Value(item)
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		kvs := make(map[string]any, len(val.KvlistValue.GetValues()))
+		for _, kv := range val.KvlistValue.GetValues() {
+			kvs[kv.GetKey()] = Value(kv.GetValue())
+		}
+		return kvs
+	}
+	return nil
+}
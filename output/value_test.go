@@ -0,0 +1,37 @@
+// ABOUTME: Tests for converting OTLP AnyValues to native Go values.
+// ABOUTME: Covers scalars, bytes, arrays, and nested key-value lists as they encode in JSON.
+
+package output
+
+import (
+	"encoding/json"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+func TestValue(t *testing.T) {
+	str := func(s string) *commonpb.AnyValue {
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+	}
+	v := &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: []*commonpb.KeyValue{
+		{Key: "code", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 503}}},
+		{Key: "ratio", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 0.25}}},
+		{Key: "ok", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: false}}},
+		{Key: "raw", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: []byte("hi")}}},
+		{Key: "tags", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: []*commonpb.AnyValue{str("a"), str("b")}}}}},
+		{Key: "empty", Value: &commonpb.AnyValue{}},
+	}}}}
+
+	data, err := json.Marshal(Value(v))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := `{"code":503,"empty":null,"ok":false,"ratio":0.25,"raw":"aGk=","tags":["a","b"]}`
+	if string(data) != want {
+		t.Errorf("Value encodes as %s, want %s", data, want)
+	}
+	if Value(nil) != nil {
+		t.Errorf("Value(nil) = %v, want nil", Value(nil))
+	}
+}
@@ -0,0 +1,114 @@
+// ABOUTME: Record-level acknowledgment tracking for OTLP export requests.
+// ABOUTME: Reports exactly which record indices were rejected and why.
+
+package receiver
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+)
+
+// RecordRejection describes a single log record the receiver did not accept
+type RecordRejection struct {
+	Index         int    `json:"index"`          // Position of the record across the whole request
+	ResourceIndex int    `json:"resource_index"` // Position of the ResourceLogs entry
+	ScopeIndex    int    `json:"scope_index"`    // Position of the ScopeLogs entry within the resource
+	RecordIndex   int    `json:"record_index"`   // Position of the LogRecord within the scope
+	Reason        string `json:"reason"`
+}
+
+// AckReport is the record-level acknowledgment for one export request.
+// Bitmap holds one character per record in request order: 1 = accepted, 0 = rejected.
+type AckReport struct {
+	ReceivedAt time.Time         `json:"received_at"`
+	Total      int               `json:"total"`
+	Accepted   int               `json:"accepted"`
+	Bitmap     string            `json:"bitmap"`
+	Rejected   []RecordRejection `json:"rejected,omitempty"`
+	DelayMs    float64           `json:"delay_ms,omitempty"` // Injected response delay
+
+	bits []byte
+}
+
+// lastAck holds the acknowledgment for the most recent export request
+var lastAck atomic.Pointer[AckReport]
+
+// partialSuccess reports rejected records in export responses
+var partialSuccess bool
+
+// SetPartialSuccess enables partial success in export responses. Off, the
+// responses are empty, as the collector expects for records the receiver
+// drops on purpose, and rejections show only at /debug/ack and in the log.
+func SetPartialSuccess(enabled bool) {
+	partialSuccess = enabled
+}
+
+// newAckReport creates an empty acknowledgment for an incoming request
+func newAckReport() *AckReport {
+	return &AckReport{ReceivedAt: time.Now().UTC()}
+}
+
+// accept records that the next record in the request was accepted
+func (a *AckReport) accept() {
+	a.bits = append(a.bits, '1')
+	a.Total++
+	a.Accepted++
+}
+
+// reject records that the next record in the request was rejected
+func (a *AckReport) reject(resourceIdx, scopeIdx, recordIdx int, reason string) {
+	a.Rejected = append(a.Rejected, RecordRejection{
+		Index:         a.Total,
+		ResourceIndex: resourceIdx,
+		ScopeIndex:    scopeIdx,
+		RecordIndex:   recordIdx,
+		Reason:        reason,
+	})
+	a.bits = append(a.bits, '0')
+	a.Total++
+}
+
+// finish freezes the bitmap once all records have been processed
+func (a *AckReport) finish() {
+	a.Bitmap = string(a.bits)
+}
+
+// Response builds the OTLP export response, setting partial success when any
+// record was rejected and SetPartialSuccess enabled it
+func (a *AckReport) Response() *collogspb.ExportLogsServiceResponse {
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if !partialSuccess || len(a.Rejected) == 0 {
+		return resp
+	}
+	resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+		RejectedLogRecords: int64(len(a.Rejected)),
+		ErrorMessage:       a.summary(),
+	}
+	return resp
+}
+
+// summary describes the rejected records, e.g. "rejected 2 of 5 log records: #1 filtered, #3 sampled"
+func (a *AckReport) summary() string {
+	parts := make([]string, 0, len(a.Rejected))
+	for _, r := range a.Rejected {
+		parts = append(parts, fmt.Sprintf("#%d %s", r.Index, r.Reason))
+	}
+	return fmt.Sprintf("rejected %d of %d log records: %s", len(a.Rejected), a.Total, strings.Join(parts, ", "))
+}
+
+// logAck logs the acknowledgment bitmap and each rejected record index
+func logAck(a *AckReport) {
+	if len(a.Rejected) == 0 {
+		return
+	}
+	slog.Info("Partial success", "accepted", a.Accepted, "total", a.Total, "bitmap", a.Bitmap)
+	for _, r := range a.Rejected {
+		slog.Info("Partial success: record rejected", "index", r.Index,
+			"resource", r.ResourceIndex, "scope", r.ScopeIndex, "record", r.RecordIndex, "reason", r.Reason)
+	}
+}
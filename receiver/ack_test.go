@@ -0,0 +1,62 @@
+// ABOUTME: Tests for record-level acknowledgment tracking.
+// ABOUTME: Covers bitmap construction and the opt-in partial-success response.
+
+package receiver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAckReport_AllAcceptedHasNoPartialSuccess(t *testing.T) {
+	ack := newAckReport()
+	ack.accept()
+	ack.accept()
+	ack.finish()
+
+	if ack.Bitmap != "11" {
+		t.Errorf("Bitmap = %q, want %q", ack.Bitmap, "11")
+	}
+	if resp := ack.Response(); resp.GetPartialSuccess() != nil {
+		t.Errorf("expected no partial success, got %v", resp.GetPartialSuccess())
+	}
+}
+
+func TestAckReport_RejectedRecordsReported(t *testing.T) {
+	ack := newAckReport()
+	ack.accept()
+	ack.reject(0, 0, 1, "filtered")
+	ack.accept()
+	ack.reject(1, 0, 0, "sampled")
+	ack.finish()
+
+	if ack.Bitmap != "1010" {
+		t.Errorf("Bitmap = %q, want %q", ack.Bitmap, "1010")
+	}
+	if ack.Total != 4 || ack.Accepted != 2 {
+		t.Errorf("Total/Accepted = %d/%d, want 4/2", ack.Total, ack.Accepted)
+	}
+	if len(ack.Rejected) != 2 {
+		t.Fatalf("expected 2 rejections, got %d", len(ack.Rejected))
+	}
+	if r := ack.Rejected[1]; r.Index != 3 || r.ResourceIndex != 1 || r.Reason != "sampled" {
+		t.Errorf("unexpected second rejection: %+v", r)
+	}
+
+	if ps := ack.Response().GetPartialSuccess(); ps != nil {
+		t.Errorf("partial success without -partial-success: %v", ps)
+	}
+
+	SetPartialSuccess(true)
+	defer SetPartialSuccess(false)
+	ps := ack.Response().GetPartialSuccess()
+	if ps == nil {
+		t.Fatal("expected partial success in response")
+	}
+	if ps.GetRejectedLogRecords() != 2 {
+		t.Errorf("RejectedLogRecords = %d, want 2", ps.GetRejectedLogRecords())
+	}
+	if !strings.Contains(ps.GetErrorMessage(), "#1 filtered") || !strings.Contains(ps.GetErrorMessage(), "#3 sampled") {
+		t.Errorf("ErrorMessage missing indices: %q", ps.GetErrorMessage())
+	}
+}
@@ -0,0 +1,37 @@
+// ABOUTME: Opt-in gate for the admin endpoints that edit the receiver's configuration while it runs.
+// ABOUTME: Edits are 404 unless -admin-api is set, and 401 without the admin token as a bearer token.
+
+package receiver
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// adminToken is the bearer token edits require. Empty disables the edit endpoints.
+var adminToken string
+
+// SetAdminToken enables the routing and allowlist edit endpoints, requiring
+// token as a bearer token. An empty token disables them.
+func SetAdminToken(token string) {
+	adminToken = token
+}
+
+// requireAdmin wraps an edit handler, answering 404 while the admin API is
+// off, so the ingest port does not advertise it, and 401 without the token
+func requireAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
@@ -0,0 +1,50 @@
+// ABOUTME: Tests for the admin API gate.
+// ABOUTME: Covers the 404 while it is off, the 401 without the bearer token, and passing through with it.
+
+package receiver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testAdminToken is the token tests of the edit endpoints send
+const testAdminToken = "test-token"
+
+// enableAdmin turns the admin API on for the rest of the test
+func enableAdmin(t *testing.T) {
+	t.Helper()
+	SetAdminToken(testAdminToken)
+	t.Cleanup(func() { SetAdminToken("") })
+}
+
+func TestRequireAdmin(t *testing.T) {
+	h := requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	serve := func(auth string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/edit", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		h(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("Bearer " + testAdminToken); code != http.StatusNotFound {
+		t.Errorf("admin API off = %d, want 404", code)
+	}
+	enableAdmin(t)
+	for auth, want := range map[string]int{
+		"":                         http.StatusUnauthorized,
+		"Bearer wrong":             http.StatusUnauthorized,
+		"Basic " + testAdminToken:  http.StatusUnauthorized,
+		"Bearer " + testAdminToken: http.StatusNoContent,
+	} {
+		if code := serve(auth); code != want {
+			t.Errorf("Authorization %q = %d, want %d", auth, code, want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// ABOUTME: Wires aggregation rules into the receive pipeline.
+// ABOUTME: Matching records are accepted and held; one rollup per group is processed when its window ends.
+
+package receiver
+
+import (
+	"context"
+	"fmt"
+
+	"otlp-mock-receiver/aggregate"
+	"otlp-mock-receiver/transform"
+)
+
+// actionAggregate is the action type for an aggregation rollup record
+const actionAggregate transform.ActionType = "aggregate"
+
+// SetAggregator enables rolling up records matched by aggregation rules
+func SetAggregator(a *aggregate.Aggregator) {
+	aggregator = a
+}
+
+// processRollup runs an aggregation rollup record through the pipeline
+func processRollup(r aggregate.Rollup, verbose bool) {
+	pre := []transform.TransformAction{{Type: actionAggregate, Rule: r.Rule, Detail: fmt.Sprintf("Aggregated %d records (%s)", r.Count, r.Rule)}}
+	processLogRecord(context.Background(), r.Resource, r.Scope, r.Log, pre, true, verbose)
+}
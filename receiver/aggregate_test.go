@@ -0,0 +1,57 @@
+// ABOUTME: Tests for aggregation in the receive pipeline.
+// ABOUTME: Covers acceptance of collected records and processing of the rollup.
+
+package receiver
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/aggregate"
+	"otlp-mock-receiver/dedup"
+	"otlp-mock-receiver/transform"
+)
+
+func TestProcessRequest_AggregatesMatchingRecords(t *testing.T) {
+	SetAggregator(aggregate.New([]*transform.AggregateRule{{
+		Name:           "not-found",
+		Body:           regexp.MustCompile(` 404 `),
+		GroupBy:        []string{"body"},
+		Window:         time.Minute,
+		CountAttribute: transform.DefaultAggregateCountAttribute,
+	}}))
+	defer SetAggregator(nil)
+	// Rollups must not be suppressed as duplicates of the record they copy
+	SetDeduper(dedup.New(time.Minute))
+	defer SetDeduper(nil)
+	before := stats.LogsTransformed.Load()
+
+	var records []*logspb.LogRecord
+	for _, body := range []string{`"GET /x" 404 0`, `"GET /x" 404 0`, `"GET /x" 404 0`, `"GET /" 200 12`} {
+		records = append(records, &logspb.LogRecord{
+			Attributes: []*commonpb.KeyValue{stringKV("cf_app_name", "aggregate-test")},
+			Body:       &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}},
+		})
+	}
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}},
+	}}}
+
+	ack := processRequest(req, false)
+	if ack.Bitmap != "1111" {
+		t.Errorf("Bitmap = %q, want 1111", ack.Bitmap)
+	}
+	if got := stats.LogsTransformed.Load() - before; got != 1 {
+		t.Errorf("LogsTransformed increased by %d before flush, want 1", got)
+	}
+
+	FlushPending(false)
+	if got := stats.LogsTransformed.Load() - before; got != 2 {
+		t.Errorf("LogsTransformed increased by %d, want 2 (200 line + rollup)", got)
+	}
+}
@@ -0,0 +1,158 @@
+// ABOUTME: Admin API for inspecting and editing the app allowlist while the receiver runs.
+// ABOUTME: Serves /admin/allowlist and keeps an audit log of every change made through it.
+
+package receiver
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"otlp-mock-receiver/allowlist"
+)
+
+// maxAllowlistAudit is how many changes the audit log keeps, dropping the oldest
+const maxAllowlistAudit = 100
+
+// allowlistEditMu serializes edits and guards allowlistAudit
+var allowlistEditMu sync.Mutex
+var allowlistAudit []AllowlistChange
+
+// AllowlistChange is one audit log entry for an edit made through the admin API
+type AllowlistChange struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"` // replace, add, or remove
+	Apps   []string  `json:"apps"`   // Entries in the request
+	Remote string    `json:"remote"` // Client address
+}
+
+// editableAllowlist returns the allowlist in effect, starting an empty one,
+// which allows every app, if none was configured
+func editableAllowlist() *allowlist.Allowlist {
+	allowlistMu.Lock()
+	defer allowlistMu.Unlock()
+	if appAllowlist == nil {
+		appAllowlist = allowlist.NewAllowlist(nil)
+	}
+	return appAllowlist
+}
+
+// handleAllowlistConfig returns the allowed apps, as a YAML allowlist would hold them
+func handleAllowlistConfig(w http.ResponseWriter, r *http.Request) {
+	var fc allowlist.FileConfig
+	if al := currentAllowlist(); al != nil {
+		fc = al.Config()
+	}
+	writeJSON(w, fc)
+}
+
+// handleReplaceAllowlist replaces the allowed apps and groups with those in the request body
+func handleReplaceAllowlist(w http.ResponseWriter, r *http.Request) {
+	fc, ok := readAllowlist(w, r)
+	if !ok {
+		return
+	}
+	editAllowlist(w, r, http.StatusOK, "replace", entryApps(fc.Apps), func(al *allowlist.Allowlist) error {
+		return al.Replace(*fc)
+	})
+}
+
+// handleAddAllowlist adds the apps in the request body
+func handleAddAllowlist(w http.ResponseWriter, r *http.Request) {
+	fc, ok := readAllowlist(w, r)
+	if !ok {
+		return
+	}
+	if len(fc.Groups) > 0 {
+		http.Error(w, "Groups can only be replaced, with PUT", http.StatusBadRequest)
+		return
+	}
+	editAllowlist(w, r, http.StatusCreated, "add", entryApps(fc.Apps), func(al *allowlist.Allowlist) error {
+		return al.Add(fc.Apps)
+	})
+}
+
+// handleRemoveAllowlist removes the entries named by the app query parameters
+func handleRemoveAllowlist(w http.ResponseWriter, r *http.Request) {
+	apps := r.URL.Query()["app"]
+	if len(apps) == 0 {
+		http.Error(w, "Missing app parameter", http.StatusBadRequest)
+		return
+	}
+	editAllowlist(w, r, http.StatusOK, "remove", apps, func(al *allowlist.Allowlist) error {
+		return al.Remove(apps)
+	})
+}
+
+// handleAllowlistAudit returns the changes made through the admin API, oldest first
+func handleAllowlistAudit(w http.ResponseWriter, r *http.Request) {
+	allowlistEditMu.Lock()
+	audit := append([]AllowlistChange{}, allowlistAudit...)
+	allowlistEditMu.Unlock()
+	writeJSON(w, audit)
+}
+
+// readAllowlist decodes a YAML or JSON allowlist from the request body,
+// replying with 400 if it cannot
+func readAllowlist(w http.ResponseWriter, r *http.Request) (*allowlist.FileConfig, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return nil, false
+	}
+	fc, err := allowlist.ParseConfig(body)
+	if err != nil {
+		http.Error(w, "Invalid allowlist: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return fc, true
+}
+
+// entryApps lists the app each entry names
+func entryApps(entries []*allowlist.AppEntry) []string {
+	apps := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry != nil {
+			apps = append(apps, entry.App)
+		}
+	}
+	return apps
+}
+
+// editAllowlist applies an edit, records it in the audit log, and replies
+// with the new allowed apps. Rejected edits leave the allowlist unchanged.
+func editAllowlist(w http.ResponseWriter, r *http.Request, status int, action string, apps []string, edit func(*allowlist.Allowlist) error) {
+	allowlistEditMu.Lock()
+	defer allowlistEditMu.Unlock()
+
+	al := editableAllowlist()
+	err := edit(al)
+	switch {
+	case errors.Is(err, allowlist.ErrEntryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, allowlist.ErrEntryExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, allowlist.ErrPersist):
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	allowlistAudit = append(allowlistAudit, AllowlistChange{Time: time.Now(), Action: action, Apps: apps, Remote: r.RemoteAddr})
+	if len(allowlistAudit) > maxAllowlistAudit {
+		allowlistAudit = allowlistAudit[len(allowlistAudit)-maxAllowlistAudit:]
+	}
+	slog.Info("Allowlist: edited", "action", action, "apps", strings.Join(apps, ","), "remote", r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, al.Config())
+}
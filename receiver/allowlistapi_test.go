@@ -0,0 +1,139 @@
+// ABOUTME: Tests for the allowlist admin API.
+// ABOUTME: Covers each edit taking effect on filtering, the audit log, and the status codes for rejected edits.
+
+package receiver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"otlp-mock-receiver/allowlist"
+)
+
+func allowlistRequest(t *testing.T, method, path, body string) (int, allowlist.FileConfig) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	newHTTPMux(false).ServeHTTP(rec, req)
+
+	var fc allowlist.FileConfig
+	if rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &fc); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, fc
+}
+
+func resetAllowlistAPI() {
+	SetAllowlist(nil)
+	allowlistAudit = nil
+}
+
+func TestAllowlistAPI_Edits(t *testing.T) {
+	resetAllowlistAPI()
+	defer resetAllowlistAPI()
+	enableAdmin(t)
+
+	// With no allowlist configured, the first edit starts one
+	code, fc := allowlistRequest(t, http.MethodGet, "/admin/allowlist", "")
+	if code != http.StatusOK || len(fc.Apps) != 0 {
+		t.Fatalf("GET = %d with %d apps, want 200 with none", code, len(fc.Apps))
+	}
+
+	code, fc = allowlistRequest(t, http.MethodPost, "/admin/allowlist", `{"apps": [{"app": "checkout"}, {"app": "payment-*", "index": "tas_payments"}]}`)
+	if code != http.StatusCreated || len(fc.Apps) != 2 {
+		t.Fatalf("POST = %d with %d apps, want 201 with 2", code, len(fc.Apps))
+	}
+	if al := currentAllowlist(); !al.IsAppAllowed("payment-api") || al.IsAppAllowed("orders") {
+		t.Error("added entries should decide which apps are allowed")
+	}
+
+	code, fc = allowlistRequest(t, http.MethodPut, "/admin/allowlist", "apps:\n  - app: orders\n")
+	if code != http.StatusOK || len(fc.Apps) != 1 || fc.Apps[0].App != "orders" {
+		t.Fatalf("PUT = %d with %+v, want 200 with orders", code, fc.Apps)
+	}
+
+	code, fc = allowlistRequest(t, http.MethodDelete, "/admin/allowlist?app=orders", "")
+	if code != http.StatusOK || len(fc.Apps) != 0 {
+		t.Fatalf("DELETE = %d with %+v, want 200 with none", code, fc.Apps)
+	}
+
+	rec := httptest.NewRecorder()
+	newHTTPMux(false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/allowlist/audit", nil))
+	var audit []AllowlistChange
+	if err := json.Unmarshal(rec.Body.Bytes(), &audit); err != nil {
+		t.Fatalf("decode audit: %v", err)
+	}
+	var actions []string
+	for _, change := range audit {
+		actions = append(actions, change.Action+" "+strings.Join(change.Apps, ","))
+	}
+	if got := strings.Join(actions, "; "); got != "add checkout,payment-*; replace orders; remove orders" {
+		t.Errorf("audit = %q", got)
+	}
+}
+
+func TestAllowlistAPI_Rejected(t *testing.T) {
+	resetAllowlistAPI()
+	defer resetAllowlistAPI()
+	enableAdmin(t)
+	SetAllowlist(allowlist.NewAllowlist([]string{"checkout"}))
+
+	for _, tc := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/admin/allowlist", `{"apps": [{"app": "checkout"}]}`, http.StatusConflict},
+		{http.MethodPost, "/admin/allowlist", `{"apps": [{"app": "/[z/"}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/admin/allowlist", `{"apps": [{"name": "orders"}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/admin/allowlist", `{"groups": [{"name": "g", "apps": ["orders"]}]}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/allowlist", `{"apps": [{"app": "api", "min_severity": "loud"}]}`, http.StatusBadRequest},
+		{http.MethodDelete, "/admin/allowlist?app=orders", "", http.StatusNotFound},
+		{http.MethodDelete, "/admin/allowlist", "", http.StatusBadRequest},
+	} {
+		if code, _ := allowlistRequest(t, tc.method, tc.path, tc.body); code != tc.want {
+			t.Errorf("%s %s %s = %d, want %d", tc.method, tc.path, tc.body, code, tc.want)
+		}
+	}
+	if apps := currentAllowlist().Apps(); len(apps) != 1 || len(allowlistAudit) != 0 {
+		t.Errorf("rejected edits changed the allowlist to %v or were audited", apps)
+	}
+}
+
+func TestAllowlistAPI_EditsRequireAdmin(t *testing.T) {
+	resetAllowlistAPI()
+	defer resetAllowlistAPI()
+	SetAllowlist(allowlist.NewAllowlist([]string{"checkout"}))
+
+	edits := []struct{ method, path, body string }{
+		{http.MethodPut, "/admin/allowlist", `{"apps": [{"app": "orders"}]}`},
+		{http.MethodPost, "/admin/allowlist", `{"apps": [{"app": "orders"}]}`},
+		{http.MethodDelete, "/admin/allowlist?app=checkout", ""},
+	}
+	for _, e := range edits {
+		if code, _ := allowlistRequest(t, e.method, e.path, e.body); code != http.StatusNotFound {
+			t.Errorf("%s %s with the admin API off = %d, want 404", e.method, e.path, code)
+		}
+	}
+
+	enableAdmin(t)
+	for _, e := range edits {
+		rec := httptest.NewRecorder()
+		newHTTPMux(false).ServeHTTP(rec, httptest.NewRequest(e.method, e.path, strings.NewReader(e.body)))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without the token = %d, want 401", e.method, e.path, rec.Code)
+		}
+	}
+
+	if code, fc := allowlistRequest(t, http.MethodGet, "/admin/allowlist", ""); code != http.StatusOK || len(fc.Apps) != 1 {
+		t.Errorf("GET = %d with %+v, want 200 with only checkout", code, fc.Apps)
+	}
+	if len(allowlistAudit) != 0 {
+		t.Errorf("refused edits were audited: %+v", allowlistAudit)
+	}
+}
@@ -0,0 +1,121 @@
+// ABOUTME: JSON API endpoints exposing receiver session statistics.
+// ABOUTME: Serves /api/stats (counters, latency percentiles), /api/apps (per-app activity and reports), /api/compare, and /api/verify.
+
+package receiver
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"otlp-mock-receiver/appstats"
+	"otlp-mock-receiver/latency"
+)
+
+// sessionStart marks when the receiver started, for uptime reporting
+var sessionStart = time.Now()
+
+// requestLatency tracks per-request handling latency across gRPC and HTTP
+var requestLatency = latency.New()
+
+// appTracker records per-app activity for onboarding checks
+var appTracker = appstats.NewTracker()
+
+// StatsResponse is the JSON body returned by /api/stats
+type StatsResponse struct {
+	UptimeSeconds    float64          `json:"uptime_seconds"`
+	LogsReceived     int64            `json:"logs_received"`
+	LogsTransformed  int64            `json:"logs_transformed"`
+	LogsDropped      int64            `json:"logs_dropped"`
+	LogsFiltered     int64            `json:"logs_filtered"`
+	LogsStitched     int64            `json:"logs_stitched"`
+	LogsDeduplicated int64            `json:"logs_deduplicated"`
+	LogsAggregated   int64            `json:"logs_aggregated"`
+	DroppedByReason  map[string]int64 `json:"dropped_by_reason"`    // See /api/drop-reasons
+	DropRules        map[string]int64 `json:"drop_rules,omitempty"` // Records dropped per drop rule
+	RequestLatency   latency.Summary  `json:"request_latency"`
+}
+
+// handleStats returns session counters and latency percentiles as JSON
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, StatsResponse{
+		UptimeSeconds:    time.Since(sessionStart).Seconds(),
+		LogsReceived:     stats.LogsReceived.Load(),
+		LogsTransformed:  stats.LogsTransformed.Load(),
+		LogsDropped:      stats.LogsDropped.Load(),
+		LogsFiltered:     stats.LogsFiltered.Load(),
+		LogsStitched:     stats.LogsStitched.Load(),
+		LogsDeduplicated: stats.LogsDeduplicated.Load(),
+		LogsAggregated:   stats.LogsAggregated.Load(),
+		DroppedByReason:  droppedByReason(),
+		DropRules:        dropRuleCounts(),
+		RequestLatency:   requestLatency.Summary(),
+	})
+}
+
+// dropRuleCounts returns how many records each configured drop rule has dropped
+func dropRuleCounts() map[string]int64 {
+	if len(transformConfig.DropRules) == 0 {
+		return nil
+	}
+	counts := make(map[string]int64, len(transformConfig.DropRules))
+	for _, rule := range transformConfig.DropRules {
+		counts[rule.Name] = rule.Dropped()
+	}
+	return counts
+}
+
+// AppsResponse is the JSON body returned by /api/apps
+type AppsResponse struct {
+	Apps []appstats.App `json:"apps"`
+}
+
+// handleApps lists every app seen this session with first/last seen timestamps
+func handleApps(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, AppsResponse{Apps: appTracker.Apps()})
+}
+
+// handleAppReport returns the onboarding report for one app: volume, severity
+// mix, index distribution, redactions, allowlist status, and schema violations
+func handleAppReport(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	report, ok := appTracker.Report(name)
+	if !ok {
+		http.Error(w, "No logs received from app "+name, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+// handleCompare returns the cross-check of received records against the collector's file output
+func handleCompare(w http.ResponseWriter, r *http.Request) {
+	if comparer == nil {
+		http.Error(w, "Comparison not enabled (start with -compare-file)", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, comparer.Report())
+}
+
+// handleVerify returns the latest reconciliation of counters with the output file
+func handleVerify(w http.ResponseWriter, r *http.Request) {
+	if verifier == nil {
+		http.Error(w, "Verification not enabled (start with -verify-interval)", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, verifier.Report())
+}
+
+// GetLatencySummary returns per-request handling latency percentiles for the session report
+func GetLatencySummary() latency.Summary {
+	return requestLatency.Summary()
+}
+
+// writeJSON encodes v as an indented JSON response
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
@@ -0,0 +1,128 @@
+// ABOUTME: Content-Type negotiation for OTLP/HTTP export requests.
+// ABOUTME: Decodes protobuf or JSON requests and encodes responses in the same format.
+
+package receiver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Media types accepted on /v1/logs
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// supportedContentTypes is reported to clients sending anything else
+var supportedContentTypes = []string{contentTypeProtobuf, contentTypeJSON}
+
+// negotiateContentType maps a Content-Type header to a supported media type.
+// A missing header is treated as protobuf. Returns false for unsupported types.
+func negotiateContentType(header string) (string, bool) {
+	if header == "" {
+		return contentTypeProtobuf, true
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", false
+	}
+	switch mediaType {
+	case contentTypeProtobuf, "application/protobuf":
+		return contentTypeProtobuf, true
+	case contentTypeJSON:
+		return contentTypeJSON, true
+	}
+	return "", false
+}
+
+// contentTypeLabel returns the metric label for a request's Content-Type header
+func contentTypeLabel(header string) string {
+	if header == "" {
+		return "none"
+	}
+	if mediaType, ok := negotiateContentType(header); ok {
+		return mediaType
+	}
+	return "unsupported"
+}
+
+// decodeRequest unmarshals an export request in the negotiated media type
+func decodeRequest(body []byte, mediaType string) (*collogspb.ExportLogsServiceRequest, error) {
+	req := &collogspb.ExportLogsServiceRequest{}
+	if mediaType == contentTypeJSON {
+		data, err := hexIDsToBase64(body)
+		if err != nil {
+			return nil, err
+		}
+		return req, protojson.Unmarshal(data, req)
+	}
+	return req, proto.Unmarshal(body, req)
+}
+
+// encodeResponse marshals an export response in the negotiated media type
+func encodeResponse(resp *collogspb.ExportLogsServiceResponse, mediaType string) ([]byte, error) {
+	if mediaType == contentTypeJSON {
+		return protojson.Marshal(resp)
+	}
+	return proto.Marshal(resp)
+}
+
+// hexIDsToBase64 rewrites traceId and spanId values from the hex encoding used
+// by OTLP/JSON to the base64 encoding protojson expects for bytes fields
+func hexIDsToBase64(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // Keep nanosecond timestamps exact
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	rewriteIDs(doc)
+	return json.Marshal(doc)
+}
+
+// rewriteIDs walks a decoded JSON document converting hex trace and span IDs in place
+func rewriteIDs(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if s, ok := val.(string); ok && (key == "traceId" || key == "spanId") {
+				if raw, err := hex.DecodeString(s); err == nil {
+					v[key] = base64.StdEncoding.EncodeToString(raw)
+				}
+				continue
+			}
+			rewriteIDs(val)
+		}
+	case []any:
+		for _, item := range v {
+			rewriteIDs(item)
+		}
+	}
+}
+
+// UnsupportedMediaTypeResponse is the JSON body returned with a 415
+type UnsupportedMediaTypeResponse struct {
+	Error       string   `json:"error"`
+	ContentType string   `json:"content_type"`
+	Supported   []string `json:"supported"`
+}
+
+// writeUnsupportedMediaType responds with a 415 listing the accepted content types
+func writeUnsupportedMediaType(w http.ResponseWriter, contentType string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnsupportedMediaType)
+	writeJSON(w, &UnsupportedMediaTypeResponse{
+		Error:       "unsupported Content-Type; configure the exporter with encoding: proto or json",
+		ContentType: contentType,
+		Supported:   supportedContentTypes,
+	})
+}
@@ -0,0 +1,113 @@
+// ABOUTME: Tests for OTLP/HTTP Content-Type negotiation.
+// ABOUTME: Covers media type mapping, JSON decoding with hex IDs, and 415 responses.
+
+package receiver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"", contentTypeProtobuf, true},
+		{"application/x-protobuf", contentTypeProtobuf, true},
+		{"application/protobuf", contentTypeProtobuf, true},
+		{"application/json; charset=utf-8", contentTypeJSON, true},
+		{"text/plain", "", false},
+		{"application/x-www-form-urlencoded", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := negotiateContentType(tt.header)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("negotiateContentType(%q) = %q, %v; want %q, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestContentTypeLabel(t *testing.T) {
+	tests := map[string]string{
+		"":                       "none",
+		"application/x-protobuf": contentTypeProtobuf,
+		"application/json":       contentTypeJSON,
+		"text/plain":             "unsupported",
+	}
+	for header, want := range tests {
+		if got := contentTypeLabel(header); got != want {
+			t.Errorf("contentTypeLabel(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestDecodeRequest_JSONHexIDs(t *testing.T) {
+	body := []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{
+		"timeUnixNano": "1700000000123456789",
+		"traceId": "5b8efff798038103d269b633813fc60c",
+		"spanId": "eee19b7ec3c1b174",
+		"body": {"stringValue": "hello"}
+	}]}]}]}`)
+
+	req, err := decodeRequest(body, contentTypeJSON)
+	if err != nil {
+		t.Fatalf("decodeRequest failed: %v", err)
+	}
+	lr := req.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0]
+	if got := len(lr.GetTraceId()); got != 16 {
+		t.Errorf("trace ID length = %d, want 16", got)
+	}
+	if lr.GetTraceId()[0] != 0x5b || lr.GetSpanId()[0] != 0xee {
+		t.Errorf("IDs decoded incorrectly: %x / %x", lr.GetTraceId(), lr.GetSpanId())
+	}
+	if lr.GetTimeUnixNano() != 1700000000123456789 {
+		t.Errorf("timestamp = %d, want exact nanoseconds", lr.GetTimeUnixNano())
+	}
+}
+
+func TestHandleLogs_JSONRoundTrip(t *testing.T) {
+	h := &httpHandler{}
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader([]byte(`{"resourceLogs":[]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.handleLogs(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != contentTypeJSON {
+		t.Errorf("Content-Type = %q, want %q", ct, contentTypeJSON)
+	}
+	if err := protojson.Unmarshal(rec.Body.Bytes(), &collogspb.ExportLogsServiceResponse{}); err != nil {
+		t.Errorf("response is not OTLP JSON: %v", err)
+	}
+}
+
+func TestHandleLogs_UnsupportedContentType(t *testing.T) {
+	h := &httpHandler{}
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader([]byte("hello")))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+
+	h.handleLogs(rec, req)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("status = %d, want 415", rec.Code)
+	}
+	var resp UnsupportedMediaTypeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if resp.ContentType != "text/plain" || len(resp.Supported) != 2 {
+		t.Errorf("unexpected 415 body: %+v", resp)
+	}
+}
@@ -0,0 +1,26 @@
+// ABOUTME: Wires windowed deduplication into the receive pipeline.
+// ABOUTME: Duplicates are rejected as they arrive; one summary per window is processed later.
+
+package receiver
+
+import (
+	"context"
+	"fmt"
+
+	"otlp-mock-receiver/dedup"
+	"otlp-mock-receiver/transform"
+)
+
+// actionDeduplicate is the action type for a duplicate summary record
+const actionDeduplicate transform.ActionType = "deduplicate"
+
+// SetDeduper enables suppression of identical records within a window
+func SetDeduper(d *dedup.Deduper) {
+	deduper = d
+}
+
+// processSummary runs a duplicate summary record through the pipeline
+func processSummary(s dedup.Summary, verbose bool) {
+	pre := []transform.TransformAction{{Type: actionDeduplicate, Detail: fmt.Sprintf("Deduplicated %d records", s.Count)}}
+	processLogRecord(context.Background(), s.Resource, s.Scope, s.Log, pre, true, verbose)
+}
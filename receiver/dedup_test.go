@@ -0,0 +1,45 @@
+// ABOUTME: Tests for deduplication in the receive pipeline.
+// ABOUTME: Covers rejection of duplicates and processing of the summary record.
+
+package receiver
+
+import (
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/dedup"
+)
+
+func TestProcessRequest_RejectsDuplicates(t *testing.T) {
+	SetDeduper(dedup.New(time.Minute))
+	defer SetDeduper(nil)
+	before := stats.LogsTransformed.Load()
+
+	var records []*logspb.LogRecord
+	for range 3 {
+		records = append(records, &logspb.LogRecord{
+			Attributes: []*commonpb.KeyValue{stringKV("cf_app_name", "storm-test")},
+			Body:       &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "connection refused"}},
+		})
+	}
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}},
+	}}}
+
+	ack := processRequest(req, false)
+	if ack.Bitmap != "100" {
+		t.Errorf("Bitmap = %q, want 100", ack.Bitmap)
+	}
+	if ack.Rejected[0].Reason != "duplicate" {
+		t.Errorf("Reason = %q, want duplicate", ack.Rejected[0].Reason)
+	}
+
+	FlushPending(false)
+	if got := stats.LogsTransformed.Load() - before; got != 2 {
+		t.Errorf("LogsTransformed increased by %d, want 2 (original + summary)", got)
+	}
+}
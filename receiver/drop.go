@@ -0,0 +1,78 @@
+// ABOUTME: Drop accounting shared by every pipeline stage that rejects records.
+// ABOUTME: Counts drops per reason in stats, metrics, and app activity, and serves /api/drop-reasons.
+
+package receiver
+
+import (
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"otlp-mock-receiver/drop"
+)
+
+// dropCounts holds records dropped per reason since startup
+var dropCounts = newDropCounts()
+
+func newDropCounts() map[drop.Reason]*atomic.Int64 {
+	counts := make(map[drop.Reason]*atomic.Int64)
+	for _, info := range drop.Taxonomy() {
+		counts[info.Reason] = new(atomic.Int64)
+	}
+	return counts
+}
+
+// dropRecord counts a dropped record everywhere drops are reported. Returns the
+// label used in partial-success messages.
+func dropRecord(appName string, reason drop.Reason, detail string) string {
+	label := reason.Label(detail)
+	dropCounts[reason].Add(1)
+	switch reason {
+	case drop.Filtered:
+		// Reported separately from logs_dropped in /api/stats
+		stats.LogsFiltered.Add(1)
+	case drop.Duplicate:
+		stats.LogsDropped.Add(1)
+		stats.LogsDeduplicated.Add(1)
+	default:
+		stats.LogsDropped.Add(1)
+	}
+	if metricsInstance != nil {
+		metricsInstance.LogsDropped.WithLabelValues(label).Inc()
+	}
+	appTracker.RecordDrop(appName, label)
+	slog.Debug("Log record dropped", "app", appName, "reason", label)
+	return label
+}
+
+// droppedByReason returns the non-zero drop counts keyed by reason
+func droppedByReason() map[string]int64 {
+	counts := make(map[string]int64)
+	for reason, n := range dropCounts {
+		if v := n.Load(); v > 0 {
+			counts[string(reason)] = v
+		}
+	}
+	return counts
+}
+
+// DropReason is one entry of the /api/drop-reasons response
+type DropReason struct {
+	drop.Info
+	Count int64 `json:"count"` // Records dropped for this reason since startup
+}
+
+// DropReasonsResponse is the JSON body returned by /api/drop-reasons
+type DropReasonsResponse struct {
+	Reasons []DropReason `json:"reasons"`
+}
+
+// handleDropReasons returns the drop-reason taxonomy with counts so far
+func handleDropReasons(w http.ResponseWriter, r *http.Request) {
+	taxonomy := drop.Taxonomy()
+	resp := DropReasonsResponse{Reasons: make([]DropReason, len(taxonomy))}
+	for i, info := range taxonomy {
+		resp.Reasons[i] = DropReason{Info: info, Count: dropCounts[info.Reason].Load()}
+	}
+	writeJSON(w, resp)
+}
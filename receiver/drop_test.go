@@ -0,0 +1,180 @@
+// ABOUTME: Tests for drop accounting and the /api/drop-reasons endpoint.
+// ABOUTME: Covers per-reason counters, metric labels, allowlist and routing drops, and the served taxonomy.
+
+package receiver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/allowlist"
+	"otlp-mock-receiver/drop"
+	"otlp-mock-receiver/metrics"
+	"otlp-mock-receiver/routing"
+)
+
+func TestDropRecord_CountsEverywhere(t *testing.T) {
+	m := metrics.New()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+	droppedBefore := stats.LogsDropped.Load()
+	filteredBefore := stats.LogsFiltered.Load()
+	ruleBefore := dropCounts[drop.Rule].Load()
+
+	if label := dropRecord("drop-test", drop.Rule, "health-checks"); label != "rule:health-checks" {
+		t.Errorf("label = %q, want rule:health-checks", label)
+	}
+	dropRecord("drop-test", drop.Filtered, "")
+
+	if got := stats.LogsDropped.Load() - droppedBefore; got != 1 {
+		t.Errorf("LogsDropped increased by %d, want 1", got)
+	}
+	if got := stats.LogsFiltered.Load() - filteredBefore; got != 1 {
+		t.Errorf("LogsFiltered increased by %d, want 1", got)
+	}
+	if got := dropCounts[drop.Rule].Load() - ruleBefore; got != 1 {
+		t.Errorf("rule drops increased by %d, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.LogsDropped.WithLabelValues("rule:health-checks")); got != 1 {
+		t.Errorf("logs_dropped_total{reason=rule:health-checks} = %v, want 1", got)
+	}
+	if report, _ := appTracker.Report("drop-test"); report.Dropped["filtered"] != 1 {
+		t.Errorf("app filtered drops = %d, want 1", report.Dropped["filtered"])
+	}
+}
+
+func TestProcessRequest_RoutedToDrop(t *testing.T) {
+	router := routing.NewRouter([]routing.RoutingRule{
+		{Name: "archive", Conditions: map[string]string{"cf_app_name": "^keep"}, Index: "tas_archive", Continue: true},
+		{Name: "noise", Conditions: map[string]string{"_severity": "<=debug"}, Index: routing.DropIndex},
+	})
+	router.SetDefaultIndex(routing.DropIndex)
+	SetRouter(router)
+	defer SetRouter(routing.DefaultRouter())
+	m := metrics.New()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	record := func(app string, sev logspb.SeverityNumber) *logspb.LogRecord {
+		return &logspb.LogRecord{
+			SeverityNumber: sev,
+			Attributes:     []*commonpb.KeyValue{stringKV("cf_app_name", app)},
+			Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "routed-drop"}},
+		}
+	}
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{
+			record("web", logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG),
+			record("web", logspb.SeverityNumber_SEVERITY_NUMBER_INFO),
+			record("keeper", logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG), // Archived copy survives the drop
+		}}},
+	}}}
+
+	ack := processRequest(req, false)
+	if ack.Bitmap != "001" {
+		t.Errorf("Bitmap = %q, want 001", ack.Bitmap)
+	}
+	if len(ack.Rejected) != 2 || ack.Rejected[0].Reason != "routed:noise" || ack.Rejected[1].Reason != "routed:default" {
+		t.Errorf("Rejected = %+v, want routed:noise and routed:default", ack.Rejected)
+	}
+	if got := testutil.ToFloat64(m.LogsDropped.WithLabelValues("routed:default")); got != 1 {
+		t.Errorf("logs_dropped_total{reason=routed:default} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.LogsByIndex.WithLabelValues("tas_archive")); got != 1 {
+		t.Errorf("logs_by_index_total{index=tas_archive} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.LogsByIndex.WithLabelValues(routing.DropIndex)); got != 0 {
+		t.Errorf("_drop should never count as an index, got %v", got)
+	}
+	// Rule matches count routing decisions, drops included
+	for rule, want := range map[string]float64{"noise": 2, "archive": 1} {
+		if got := testutil.ToFloat64(m.RoutingRuleMatches.WithLabelValues(rule)); got != want {
+			t.Errorf("routing_rule_matches_total{rule=%s} = %v, want %v", rule, got, want)
+		}
+	}
+	if got := testutil.ToFloat64(m.RoutingDefault); got != 1 {
+		t.Errorf("routing_default_total = %v, want 1", got)
+	}
+	families, _ := m.Registry().Gather()
+	for _, mf := range families {
+		if mf.GetName() == "otlp_receiver_routing_duration_seconds" {
+			if got := mf.GetMetric()[0].GetHistogram().GetSampleCount(); got != 3 {
+				t.Errorf("routing_duration_seconds count = %d, want 3", got)
+			}
+		}
+	}
+}
+
+func TestProcessRequest_ThrottledByRoutingRule(t *testing.T) {
+	SetRouter(routing.NewRouter([]routing.RoutingRule{
+		{Name: "noisy", Conditions: map[string]string{"cf_app_name": "^chatty$"}, Index: "tas_chatty", RateLimit: &routing.RateLimit{PerSecond: 1, OverflowIndex: routing.DropIndex}},
+	}))
+	defer SetRouter(routing.DefaultRouter())
+	m := metrics.New()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	var records []*logspb.LogRecord
+	for range 3 {
+		records = append(records, &logspb.LogRecord{
+			Attributes: []*commonpb.KeyValue{stringKV("cf_app_name", "chatty")},
+			Body:       &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "throttled"}},
+		})
+	}
+	ack := processRequest(&collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}},
+	}}}, false)
+
+	if ack.Bitmap != "100" || len(ack.Rejected) != 2 || ack.Rejected[0].Reason != "throttled:noisy" {
+		t.Errorf("Bitmap = %q, Rejected = %+v, want two throttled:noisy", ack.Bitmap, ack.Rejected)
+	}
+	if got := testutil.ToFloat64(m.RoutingOverflow.WithLabelValues("noisy")); got != 2 {
+		t.Errorf("routing_overflow_total{rule=noisy} = %v, want 2", got)
+	}
+}
+
+func TestProcessRequest_FilteredByResourceAppName(t *testing.T) {
+	SetAllowlist(allowlist.NewAllowlist([]string{"payment-service"}))
+	defer SetAllowlist(nil)
+
+	// As cmd/testlog and the TAS collector send it: the app name is on the resource
+	resourceLogs := func(app string) *logspb.ResourceLogs {
+		return &logspb.ResourceLogs{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringKV("application_name", app)}},
+			ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{{
+				Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "resource app"}},
+			}}}},
+		}
+	}
+	ack := processRequest(&collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{
+		resourceLogs("payment-service"), resourceLogs("order-service"),
+	}}, false)
+
+	if ack.Bitmap != "10" || len(ack.Rejected) != 1 || ack.Rejected[0].Reason != "filtered" {
+		t.Errorf("Bitmap = %q, Rejected = %+v, want order-service filtered", ack.Bitmap, ack.Rejected)
+	}
+}
+
+func TestHandleDropReasons(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleDropReasons(rec, httptest.NewRequest(http.MethodGet, "/api/drop-reasons", nil))
+
+	var resp DropReasonsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Reasons) != len(drop.Taxonomy()) {
+		t.Fatalf("got %d reasons, want %d", len(resp.Reasons), len(drop.Taxonomy()))
+	}
+	if r := resp.Reasons[0]; r.Reason != drop.Sampled || r.Stage != "sampling" {
+		t.Errorf("first reason = %+v, want sampled from sampling", r)
+	}
+}
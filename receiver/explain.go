@@ -0,0 +1,171 @@
+// ABOUTME: Dry-run endpoint that explains what the pipeline would do with one record.
+// ABOUTME: Serves POST /debug/transform without touching stats, metrics, app tracking, or output.
+
+package receiver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/allowlist"
+	"otlp-mock-receiver/drop"
+	"otlp-mock-receiver/output"
+	"otlp-mock-receiver/routing"
+	"otlp-mock-receiver/transform"
+)
+
+// TransformExplanation is the JSON body returned by /debug/transform
+type TransformExplanation struct {
+	Before           *output.LogEntry            `json:"before"`
+	After            *output.LogEntry            `json:"after,omitempty"` // As written to the JSON output for the final destination; absent if dropped
+	Actions          []transform.TransformAction `json:"actions,omitempty"`
+	Routing          []output.RoutingInfo        `json:"routing,omitempty"` // Every destination, the final one last
+	Dropped          string                      `json:"dropped,omitempty"` // Drop reason label, see /api/drop-reasons
+	SchemaViolations []string                    `json:"schema_violations,omitempty"`
+}
+
+// handleExplainTransform runs one OTLP JSON record through the pipeline and
+// reports the result without counting it anywhere
+func handleExplainTransform(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	rr, err := decodeExplainRecord(body)
+	if err != nil {
+		http.Error(w, "Invalid OTLP JSON log record: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, explainTransform(rr.resource, rr.scope, rr.lr))
+}
+
+// decodeExplainRecord accepts either a bare OTLP JSON log record or an export
+// request holding exactly one record, which also carries its resource and scope
+func decodeExplainRecord(body []byte) (resourceRecord, error) {
+	records, err := decodeJSONRecords(body)
+	if err != nil {
+		return resourceRecord{}, err
+	}
+	if len(records) != 1 {
+		return resourceRecord{}, fmt.Errorf("expected exactly one log record, got %d", len(records))
+	}
+	return records[0], nil
+}
+
+// resourceRecord is a log record with the resource and scope it arrived
+// under, both nil for a bare record
+type resourceRecord struct {
+	resource *resourcepb.Resource
+	scope    *commonpb.InstrumentationScope
+	lr       *logspb.LogRecord
+}
+
+// decodeJSONRecords decodes a bare OTLP JSON log record, or every record in
+// an OTLP JSON export request
+func decodeJSONRecords(body []byte) ([]resourceRecord, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+
+	if _, ok := fields["resourceLogs"]; !ok {
+		data, err := hexIDsToBase64(body)
+		if err != nil {
+			return nil, err
+		}
+		lr := &logspb.LogRecord{}
+		if err := protojson.Unmarshal(data, lr); err != nil {
+			return nil, err
+		}
+		return []resourceRecord{{lr: lr}}, nil
+	}
+
+	req, err := decodeRequest(body, contentTypeJSON)
+	if err != nil {
+		return nil, err
+	}
+	var records []resourceRecord
+	for _, rl := range req.GetResourceLogs() {
+		for _, sl := range rl.GetScopeLogs() {
+			for _, lr := range sl.GetLogRecords() {
+				records = append(records, resourceRecord{rl.GetResource(), sl.GetScope(), lr})
+			}
+		}
+	}
+	return records, nil
+}
+
+// explainTransform mirrors processLogRecord on a record nobody else will see.
+// Routing lists every destination including _drop; After is the entry for the
+// last one that receives the record.
+// Stages that depend on earlier traffic (multiline, the sampling budget,
+// aggregation, dedup) are skipped.
+func explainTransform(resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord) *TransformExplanation {
+	exp := &TransformExplanation{
+		Before:           buildLogEntry(resource, scope, lr, routing.Destination{}, nil),
+		SchemaViolations: checkSchema(resource, lr),
+	}
+
+	_, actions := normalizeRecord(lr)
+	policy := appPolicy(resource, lr)
+	if reason, detail := wouldDrop(resource, lr, policy); reason != "" {
+		exp.Actions = actions
+		exp.Dropped = reason.Label(detail)
+		return exp
+	}
+	if cfg := samplingFor(policy); cfg != nil {
+		stampSampling(cfg, getAppName(resource, lr), lr)
+	}
+
+	resource, transformed, actions := transformRecord(resource, lr, actions)
+	var routed []routing.Destination
+	if reasons := quarantineReasons(exp.SchemaViolations); len(reasons) > 0 {
+		routed = quarantine(transformed, reasons)
+	} else if policy.Index != "" {
+		routed = policyRoute(policy)
+	} else {
+		routed = currentRouter().DryRoute(resource, transformed)
+	}
+	for _, d := range routed {
+		exp.Routing = append(exp.Routing, routingInfo(d))
+	}
+	dests := routing.Deliverable(routed)
+	if len(dests) == 0 {
+		exp.Actions = actions
+		exp.Dropped = drop.Routed.Label(routing.Primary(routed).Rule)
+		return exp
+	}
+	final := routing.Primary(dests)
+	transform.SetAttribute(transformed, "index", final.Index)
+	stampSourcetype(transformed, final)
+
+	exp.After = buildLogEntry(resource, scope, transformed, final, actions)
+	exp.Actions = actions
+	return exp
+}
+
+// wouldDrop returns the reason processLogRecord's allowlist, sampling, or drop
+// rule checks would drop the record for, leaving drop rule counts untouched
+func wouldDrop(resource *resourcepb.Resource, lr *logspb.LogRecord, policy allowlist.Policy) (drop.Reason, string) {
+	if filter, _ := filterReason(resource, lr, policy); filter != "" && !allowlistReportOnly {
+		return drop.Filtered, ""
+	}
+	if !transform.ShouldSample(lr, samplingFor(policy)) {
+		return drop.Sampled, ""
+	}
+	if rule := transformConfig.MatchDropRule(resource, lr); rule != nil {
+		return drop.Rule, rule.Name
+	}
+	return "", ""
+}
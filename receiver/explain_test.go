@@ -0,0 +1,194 @@
+// ABOUTME: Tests for the /debug/transform dry-run endpoint.
+// ABOUTME: Covers both accepted input shapes, drop explanations, native attribute types, trace context, and that nothing is counted.
+
+package receiver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"otlp-mock-receiver/routing"
+	"otlp-mock-receiver/transform"
+)
+
+func explain(t *testing.T, body string) (int, TransformExplanation) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handleExplainTransform(rec, httptest.NewRequest(http.MethodPost, "/debug/transform", strings.NewReader(body)))
+
+	var exp TransformExplanation
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &exp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, exp
+}
+
+func TestExplainTransform_ExportRequest(t *testing.T) {
+	received, transformed := stats.LogsReceived.Load(), stats.LogsTransformed.Load()
+
+	code, exp := explain(t, `{"resourceLogs":[{
+		"resource": {"attributes": [{"key": "application_name", "value": {"stringValue": "explain-test"}}]},
+		"scopeLogs": [{"logRecords": [{
+			"severityText": "ERROR", "severityNumber": 17,
+			"body": {"stringValue": "card 4111-1111-1111-1111 declined"}
+		}]}]
+	}]}`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if !strings.Contains(exp.Before.Body, "4111-1111-1111-1111") {
+		t.Errorf("before body = %q, want the original", exp.Before.Body)
+	}
+	if exp.After == nil || strings.Contains(exp.After.Body, "4111") {
+		t.Fatalf("after = %+v, want the card number redacted", exp.After)
+	}
+	if len(exp.Routing) != 1 || exp.Routing[0].Index != "tas_errors" {
+		t.Errorf("routing = %+v, want tas_errors", exp.Routing)
+	}
+	if len(exp.Actions) == 0 || exp.Dropped != "" {
+		t.Errorf("actions = %v, dropped = %q", exp.Actions, exp.Dropped)
+	}
+	if stats.LogsReceived.Load() != received || stats.LogsTransformed.Load() != transformed {
+		t.Error("dry run should not touch stats")
+	}
+	if _, ok := appTracker.Report("explain-test"); ok {
+		t.Error("dry run should not track the app")
+	}
+}
+
+func TestExplainTransform_BareRecordDropped(t *testing.T) {
+	cfg := transform.DefaultConfig()
+	cfg.DropRules = []*transform.DropRule{{Name: "health-checks", Body: regexp.MustCompile(`^GET /health`)}}
+	SetTransformConfig(cfg)
+	defer SetTransformConfig(transform.DefaultConfig())
+
+	code, exp := explain(t, `{"body": {"stringValue": "GET /health 200"}}`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if exp.Dropped != "rule:health-checks" || exp.After != nil {
+		t.Errorf("dropped = %q, after = %+v, want rule:health-checks and no after", exp.Dropped, exp.After)
+	}
+	if got := cfg.DropRules[0].Dropped(); got != 0 {
+		t.Errorf("drop rule count = %d, want 0", got)
+	}
+}
+
+func TestExplainTransform_FanOut(t *testing.T) {
+	SetRouter(routing.NewRouter([]routing.RoutingRule{
+		{Name: "archive", Index: "tas_archive", Continue: true},
+	}))
+	defer SetRouter(routing.DefaultRouter())
+
+	code, exp := explain(t, `{"body": {"stringValue": "hello"}}`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if len(exp.Routing) != 2 || exp.Routing[0].Index != "tas_archive" || exp.Routing[1].Index != "tas_logs" {
+		t.Errorf("routing = %+v, want tas_archive then tas_logs", exp.Routing)
+	}
+	if exp.After.Routing.Index != "tas_logs" || exp.After.Attributes["index"] != "tas_logs" {
+		t.Errorf("after routing = %+v, index attribute %q, want the final destination", exp.After.Routing, exp.After.Attributes["index"])
+	}
+}
+
+func TestExplainTransform_Sourcetype(t *testing.T) {
+	SetRouter(routing.NewRouter([]routing.RoutingRule{
+		{Name: "archive", Index: "tas_archive", Continue: true, Sourcetype: "cf:archive"},
+		{Name: "errors", Conditions: map[string]string{"_severity": "error"}, Index: "tas_errors", Sourcetype: "cf:error", Source: "tas:errors"},
+	}))
+	defer SetRouter(routing.DefaultRouter())
+
+	code, exp := explain(t, `{"severityText": "ERROR", "severityNumber": 17, "body": {"stringValue": "boom"}}`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if len(exp.Routing) != 2 || exp.Routing[0].Sourcetype != "cf:archive" || exp.Routing[1].Source != "tas:errors" {
+		t.Errorf("routing = %+v, want each rule's sourcetype and source", exp.Routing)
+	}
+	if exp.After.Routing.Sourcetype != "cf:error" || exp.After.Attributes["sourcetype"] != "cf:error" || exp.After.Attributes["source"] != "tas:errors" {
+		t.Errorf("after routing = %+v, attributes = %v, want the errors rule's sourcetype and source", exp.After.Routing, exp.After.Attributes)
+	}
+}
+
+func TestExplainTransform_RejectsBadInput(t *testing.T) {
+	for _, body := range []string{
+		`not json`,
+		`{"resourceLogs": []}`,
+		`{"body": {"stringValue": 5}}`,
+	} {
+		if code, _ := explain(t, body); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, code)
+		}
+	}
+}
+
+func TestExplainTransform_NativeTypes(t *testing.T) {
+	body := `{"resourceLogs":[{
+		"resource": {"attributes": [{"key": "application_name", "value": {"stringValue": "explain-test"}}]},
+		"scopeLogs": [{"logRecords": [{
+			"body": {"stringValue": "request done"},
+			"attributes": [
+				{"key": "status", "value": {"intValue": "503"}},
+				{"key": "retried", "value": {"boolValue": true}},
+				{"key": "http", "value": {"kvlistValue": {"values": [{"key": "method", "value": {"stringValue": "GET"}}]}}}
+			]
+		}]}]
+	}]}`
+
+	_, exp := explain(t, body)
+	if exp.Before.Attributes["status"] != "503" || exp.Before.Attributes["http"] != "[kvlist: 1 items]" {
+		t.Errorf("attributes = %v, want values flattened to strings by default", exp.Before.Attributes)
+	}
+
+	SetNativeTypes(true)
+	defer SetNativeTypes(false)
+	_, exp = explain(t, body)
+	attrs := exp.Before.Attributes
+	nested, _ := attrs["http"].(map[string]any)
+	if attrs["status"] != float64(503) || attrs["retried"] != true || nested["method"] != "GET" {
+		t.Errorf("attributes = %v, want native types", attrs)
+	}
+}
+
+func TestExplainTransform_TraceContextAndScope(t *testing.T) {
+	_, exp := explain(t, `{"resourceLogs":[{
+		"resource": {"attributes": [{"key": "application_name", "value": {"stringValue": "explain-test"}}], "droppedAttributesCount": 2},
+		"scopeLogs": [{
+			"scope": {"name": "io.opentelemetry.java", "version": "1.32.0"},
+			"logRecords": [{
+				"timeUnixNano": "1705314600000000000", "observedTimeUnixNano": "1705314600500000000",
+				"traceId": "5b8efff798038103d269b633813fc60c", "spanId": "eee19b7ec3c1b174", "flags": 1,
+				"droppedAttributesCount": 3,
+				"body": {"stringValue": "correlated"}
+			}]
+		}]
+	}]}`)
+	e := exp.After
+	if e == nil {
+		t.Fatal("record was dropped")
+	}
+	if e.TraceID != "5b8efff798038103d269b633813fc60c" || e.SpanID != "eee19b7ec3c1b174" || e.Flags != 1 {
+		t.Errorf("trace %q span %q flags %d, want the record's trace context", e.TraceID, e.SpanID, e.Flags)
+	}
+	if e.ObservedTimestamp != "2024-01-15T10:30:00.5Z" || e.ScopeName != "io.opentelemetry.java" || e.ScopeVersion != "1.32.0" {
+		t.Errorf("observed %q scope %q %q, want the record's", e.ObservedTimestamp, e.ScopeName, e.ScopeVersion)
+	}
+	if e.DroppedAttributes != 3 || e.ResourceDroppedAttrs != 2 {
+		t.Errorf("dropped attributes %d, resource %d, want 3 and 2", e.DroppedAttributes, e.ResourceDroppedAttrs)
+	}
+
+	_, exp = explain(t, `{"body": {"stringValue": "uncorrelated"}}`)
+	data, _ := json.Marshal(exp.Before)
+	for _, key := range []string{"trace_id", "span_id", "flags", "observed_timestamp", "scope_name", "dropped_attributes_count"} {
+		if strings.Contains(string(data), `"`+key+`"`) {
+			t.Errorf("entry %s has %s, want it left out when unset", data, key)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// ABOUTME: Per-app accounting of records the allowlist filters, served at /admin/allowlist/stats.
+// ABOUTME: In report-only mode records are counted but kept, to preview an allowlist before enforcing it.
+
+package receiver
+
+import (
+	"cmp"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// allowlistReportOnly counts records the allowlist would filter without dropping them
+var allowlistReportOnly bool
+
+// filteredMu guards filteredApps
+var filteredMu sync.Mutex
+var filteredApps = make(map[string]*FilteredApp)
+
+// FilteredApp is how many records an app sent that the allowlist filtered
+type FilteredApp struct {
+	App      string           `json:"app"`
+	Records  int64            `json:"records"`
+	Reasons  map[string]int64 `json:"reasons"` // denied, not_allowed, or below_min_severity
+	LastSeen time.Time        `json:"last_seen"`
+}
+
+// AllowlistStats is the response for GET /admin/allowlist/stats
+type AllowlistStats struct {
+	Enforced bool          `json:"enforced"` // False in report-only mode, where the records were kept
+	Records  int64         `json:"records"`
+	Apps     []FilteredApp `json:"apps"` // Most records first
+}
+
+// SetAllowlistReportOnly keeps records the allowlist would filter, only counting them
+func SetAllowlistReportOnly(reportOnly bool) {
+	allowlistReportOnly = reportOnly
+}
+
+// recordFiltered counts a record the allowlist filtered for an app
+func recordFiltered(appName, reason string) {
+	filteredMu.Lock()
+	fa, ok := filteredApps[appName]
+	if !ok {
+		fa = &FilteredApp{App: appName, Reasons: make(map[string]int64)}
+		filteredApps[appName] = fa
+	}
+	fa.Records++
+	fa.Reasons[reason]++
+	fa.LastSeen = time.Now()
+	filteredMu.Unlock()
+
+	if metricsInstance != nil {
+		metricsInstance.AllowlistFiltered.WithLabelValues(appName, reason).Inc()
+	}
+}
+
+// allowlistStats returns the filtered record counts for every app, most records first
+func allowlistStats() AllowlistStats {
+	filteredMu.Lock()
+	defer filteredMu.Unlock()
+
+	st := AllowlistStats{Enforced: !allowlistReportOnly, Apps: make([]FilteredApp, 0, len(filteredApps))}
+	for _, fa := range filteredApps {
+		app := *fa
+		app.Reasons = maps.Clone(fa.Reasons)
+		st.Apps = append(st.Apps, app)
+		st.Records += fa.Records
+	}
+	slices.SortFunc(st.Apps, func(a, b FilteredApp) int {
+		return cmp.Or(cmp.Compare(b.Records, a.Records), cmp.Compare(a.App, b.App))
+	})
+	return st
+}
+
+// handleAllowlistStats returns how many records each app sent that the allowlist filtered
+func handleAllowlistStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, allowlistStats())
+}
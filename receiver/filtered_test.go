@@ -0,0 +1,130 @@
+// ABOUTME: Tests for per-app accounting of records the allowlist filters.
+// ABOUTME: Covers counts by reason, the stats endpoint, report-only mode, and the app report's allowlist status.
+
+package receiver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/allowlist"
+)
+
+func resetFilteredApps() {
+	filteredApps = make(map[string]*FilteredApp)
+	SetAllowlistReportOnly(false)
+}
+
+func filteredRequest() *collogspb.ExportLogsServiceRequest {
+	info := logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	return &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{
+			policyRecord("payments", info, "below minimum"),
+			policyRecord("orders", info, "not allowed"),
+			policyRecord("orders", info, "not allowed again"),
+			policyRecord("health-checker", info, "denied"),
+			policyRecord("web", info, "allowed"),
+		}}},
+	}}}
+}
+
+func TestAllowlistStats_Counts(t *testing.T) {
+	resetFilteredApps()
+	defer resetFilteredApps()
+	setPolicyAllowlist(t,
+		&allowlist.AppEntry{App: "payments", MinSeverity: "warn"},
+		&allowlist.AppEntry{App: "web"},
+		&allowlist.AppEntry{App: "health-checker"},
+	)
+	currentAllowlist().SetDenied([]string{"health-checker"})
+
+	if ack := processRequest(filteredRequest(), false); ack.Bitmap != "00001" {
+		t.Fatalf("Bitmap = %q, want only web kept", ack.Bitmap)
+	}
+
+	rec := httptest.NewRecorder()
+	newHTTPMux(false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/allowlist/stats", nil))
+	var st AllowlistStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !st.Enforced || st.Records != 4 || len(st.Apps) != 3 {
+		t.Fatalf("stats = %+v, want 4 enforced records from 3 apps", st)
+	}
+	for i, want := range []struct {
+		app     string
+		records int64
+		reason  string
+	}{
+		{"orders", 2, filterNotAllowed},
+		{"health-checker", 1, filterDenied},
+		{"payments", 1, filterBelowMinSeverity},
+	} {
+		if got := st.Apps[i]; got.App != want.app || got.Records != want.records || got.Reasons[want.reason] != want.records {
+			t.Errorf("Apps[%d] = %+v, want %s with %d %s", i, got, want.app, want.records, want.reason)
+		}
+	}
+}
+
+func TestAllowlistStats_ReportOnly(t *testing.T) {
+	resetFilteredApps()
+	defer resetFilteredApps()
+	setPolicyAllowlist(t, &allowlist.AppEntry{App: "web"})
+	SetAllowlistReportOnly(true)
+
+	if ack := processRequest(filteredRequest(), false); ack.Bitmap != "11111" {
+		t.Errorf("Bitmap = %q, want every record kept in report-only mode", ack.Bitmap)
+	}
+	if exp := explainTransform(nil, nil, policyRecord("orders", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "explained")); exp.Dropped != "" {
+		t.Errorf("explain Dropped = %q, want kept in report-only mode", exp.Dropped)
+	}
+	if st := allowlistStats(); st.Enforced || st.Records != 4 {
+		t.Errorf("stats = %+v, want 4 records counted but not enforced", st)
+	}
+}
+
+func TestAppReport_AllowlistStatusFromDecision(t *testing.T) {
+	SetAllowlist(allowlist.NewAllowlist([]string{"acme/prod/report-scoped"}))
+	defer SetAllowlist(nil)
+
+	req := func(space string) *collogspb.ExportLogsServiceRequest {
+		return &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				stringKV("organization_name", "acme"), stringKV("space_name", space),
+			}},
+			ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{
+				policyRecord("report-scoped", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "hello"),
+			}}},
+		}}}
+	}
+	status := func() string {
+		rec := httptest.NewRecorder()
+		newHTTPMux(false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/apps/report-scoped/report", nil))
+		var report struct {
+			AllowlistStatus string `json:"allowlist_status"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return report.AllowlistStatus
+	}
+
+	if ack := processRequest(req("prod"), false); ack.Bitmap != "1" {
+		t.Fatalf("Bitmap = %q, want the scoped app kept", ack.Bitmap)
+	}
+	if got := status(); got != "allowed" {
+		t.Errorf("allowlist_status = %q after a kept record, want allowed", got)
+	}
+
+	processRequest(req("dev"), false)
+	if got := status(); got != "not allowed" {
+		t.Errorf("allowlist_status = %q after a filtered record, want not allowed", got)
+	}
+}
@@ -0,0 +1,62 @@
+// ABOUTME: Processes records held back by stateful stages (multiline, aggregation, dedup).
+// ABOUTME: Runs periodically for expired windows and once at shutdown for everything pending.
+
+package receiver
+
+import (
+	"time"
+)
+
+// flushInterval is how often held records are checked for expiry
+const flushInterval = 100 * time.Millisecond
+
+// RunFlusher processes records whose window has expired until stop is closed
+func RunFlusher(verbose bool, stop <-chan struct{}) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			flush(verbose, false)
+		}
+	}
+}
+
+// FlushPending processes every held record, e.g. at shutdown
+func FlushPending(verbose bool) {
+	flush(verbose, true)
+}
+
+// flush processes expired (or, if all is set, all) held records. Stitched records
+// go first since they pass through aggregation and dedup on their way into the pipeline.
+func flush(verbose, all bool) {
+	if assembler != nil {
+		ready := assembler.Expired()
+		if all {
+			ready = assembler.Flush()
+		}
+		for _, rec := range ready {
+			processStitched(rec, verbose)
+		}
+	}
+	if aggregator != nil {
+		ready := aggregator.Expired()
+		if all {
+			ready = aggregator.Flush()
+		}
+		for _, r := range ready {
+			processRollup(r, verbose)
+		}
+	}
+	if deduper != nil {
+		ready := deduper.Expired()
+		if all {
+			ready = deduper.Flush()
+		}
+		for _, s := range ready {
+			processSummary(s, verbose)
+		}
+	}
+}
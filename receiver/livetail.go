@@ -0,0 +1,133 @@
+// ABOUTME: Live tail of transformed entries over Server-Sent Events or WebSocket.
+// ABOUTME: Serves /stream, filtered by app, index, and minimum severity, for watching records arrive during demos.
+
+package receiver
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"otlp-mock-receiver/output"
+	"otlp-mock-receiver/transform"
+)
+
+// sseKeepalive is how often an idle event stream gets a comment, so
+// proxies and load balancers keep it open
+const sseKeepalive = 15 * time.Second
+
+var liveTail *output.LiveTail
+
+// SetLiveTail serves /stream from the entries t is written
+func SetLiveTail(t *output.LiveTail) {
+	liveTail = t
+}
+
+// handleStream streams entries to a WebSocket client if the request asks
+// to upgrade, and as Server-Sent Events otherwise
+func handleStream(w http.ResponseWriter, r *http.Request) {
+	if liveTail == nil {
+		http.Error(w, "Live tail not enabled (start with -live-tail)", http.StatusNotFound)
+		return
+	}
+	filter, err := parseTailFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		streamWebSocket(w, r, filter)
+		return
+	}
+	streamEvents(w, r, filter)
+}
+
+// streamEvents sends each entry as the data of a Server-Sent Event
+func streamEvents(w http.ResponseWriter, r *http.Request, filter output.TailFilter) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	client := liveTail.Subscribe(filter)
+	defer liveTail.Unsubscribe(client)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keeps nginx from holding events back
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry, ok := <-client.Entries():
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", entry); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// streamWebSocket sends each entry as a WebSocket text message. Any origin
+// may connect, as with the rest of the API.
+func streamWebSocket(w http.ResponseWriter, r *http.Request, filter output.TailFilter) {
+	websocket.Server{
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			client := liveTail.Subscribe(filter)
+			defer liveTail.Unsubscribe(client)
+
+			// Nothing is expected from the client; reading notices it leave
+			gone := make(chan struct{})
+			go func() {
+				defer close(gone)
+				var discard []byte
+				for websocket.Message.Receive(ws, &discard) == nil {
+				}
+			}()
+			for {
+				select {
+				case <-gone:
+					return
+				case entry, ok := <-client.Entries():
+					if !ok {
+						return
+					}
+					if err := websocket.Message.Send(ws, string(entry)); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}.ServeHTTP(w, r)
+}
+
+// parseTailFilter reads app, index, and min-severity
+func parseTailFilter(values url.Values) (output.TailFilter, error) {
+	f := output.TailFilter{App: values.Get("app"), Index: values.Get("index")}
+	if s := values.Get("min-severity"); s != "" {
+		sev, err := transform.ParseSeverity(s)
+		if err != nil {
+			return f, fmt.Errorf("min-severity=%s: %w", s, err)
+		}
+		f.MinSeverity = sev
+	}
+	return f, nil
+}
@@ -0,0 +1,123 @@
+// ABOUTME: Tests for the /stream live tail over Server-Sent Events and WebSocket.
+// ABOUTME: Covers filtered delivery on both transports, bad parameters, and the tail not being enabled.
+
+package receiver
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"otlp-mock-receiver/output"
+)
+
+// startTail enables the live tail behind a test server
+func startTail(t *testing.T) (*output.LiveTail, *httptest.Server) {
+	t.Helper()
+	tail := output.NewLiveTail(10)
+	SetLiveTail(tail)
+	server := httptest.NewServer(newHTTPMux(false))
+	t.Cleanup(func() {
+		tail.Close()
+		server.Close()
+		SetLiveTail(nil)
+	})
+	return tail, server
+}
+
+// waitForClients waits until n clients have subscribed to the tail
+func waitForClients(t *testing.T, tail *output.LiveTail, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); tail.Clients() < n; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients subscribed, want %d", tail.Clients(), n)
+		}
+	}
+}
+
+func TestStream_NotEnabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newHTTPMux(false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without a tail = %d, want 404", rec.Code)
+	}
+}
+
+func TestStream_BadFilter(t *testing.T) {
+	startTail(t)
+	rec := httptest.NewRecorder()
+	newHTTPMux(false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream?min-severity=LOUD", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad min-severity = %d, want 400", rec.Code)
+	}
+}
+
+func TestStream_ServerSentEvents(t *testing.T) {
+	tail, server := startTail(t)
+	resp, err := http.Get(server.URL + "/stream?min-severity=ERROR")
+	if err != nil {
+		t.Fatalf("GET /stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	waitForClients(t, tail, 1)
+
+	tail.Write(&output.LogEntry{Body: "started", SeverityNumber: 9})
+	tail.Write(&output.LogEntry{Body: "failed", SeverityNumber: 17})
+
+	events := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				events <- data
+				return
+			}
+		}
+	}()
+	select {
+	case data := <-events:
+		if !strings.Contains(data, `"body":"failed"`) {
+			t.Errorf("first event = %s, want the error entry", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+}
+
+func TestStream_WebSocket(t *testing.T) {
+	tail, server := startTail(t)
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/stream?index=tas_errors", "", server.URL)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer ws.Close()
+	waitForClients(t, tail, 1)
+
+	tail.Write(&output.LogEntry{Body: "routine", Routing: output.RoutingInfo{Index: "tas_logs"}})
+	tail.Write(&output.LogEntry{Body: "paged", Routing: output.RoutingInfo{Index: "tas_errors"}})
+
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg string
+	if err := websocket.Message.Receive(ws, &msg); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if !strings.Contains(msg, `"body":"paged"`) {
+		t.Errorf("first message = %s, want the tas_errors entry", msg)
+	}
+
+	// Leaving unsubscribes
+	ws.Close()
+	for deadline := time.Now().Add(5 * time.Second); tail.Clients() > 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("client still subscribed after closing")
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// ABOUTME: Query API over the records stored by the SQLite output.
+// ABOUTME: Serves /api/logs to search stored entries and /api/logs/summary to count them by app, index, or severity.
+
+package receiver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"otlp-mock-receiver/output"
+	"otlp-mock-receiver/transform"
+)
+
+// Entries /api/logs returns unless limit says otherwise, and the most it will
+const (
+	defaultLogsLimit = 100
+	maxLogsLimit     = 1000
+)
+
+var logStore *output.SQLiteWriter
+
+// SetLogStore serves /api/logs from the records s stores
+func SetLogStore(s *output.SQLiteWriter) {
+	logStore = s
+}
+
+// LogsResponse is the JSON body returned by /api/logs
+type LogsResponse struct {
+	Count int               `json:"count"`
+	Logs  []json.RawMessage `json:"logs"` // Newest first, as written to the JSON output
+}
+
+// LogsSummaryResponse is the JSON body returned by /api/logs/summary
+type LogsSummaryResponse struct {
+	By     string            `json:"by"`
+	Counts []output.LogCount `json:"counts"`
+}
+
+// handleQueryLogs returns the stored entries matching the query parameters
+func handleQueryLogs(w http.ResponseWriter, r *http.Request) {
+	if logStore == nil {
+		http.Error(w, "Log store not enabled (start with -sqlite-db)", http.StatusNotFound)
+		return
+	}
+	q, err := parseLogQuery(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logs, err := logStore.Query(q)
+	if err != nil {
+		http.Error(w, "Query failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, LogsResponse{Count: len(logs), Logs: logs})
+}
+
+// handleLogsSummary counts the stored entries matching the query parameters
+// by app, index, or severity
+func handleLogsSummary(w http.ResponseWriter, r *http.Request) {
+	if logStore == nil {
+		http.Error(w, "Log store not enabled (start with -sqlite-db)", http.StatusNotFound)
+		return
+	}
+	values := r.URL.Query()
+	q, err := parseLogQuery(values, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	by := values.Get("by")
+	if by == "" {
+		by = "index"
+	}
+	counts, err := logStore.Summary(by, q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, LogsSummaryResponse{By: by, Counts: counts})
+}
+
+// parseLogQuery reads app, index, min-severity, since, until, q, and limit.
+// since and until take an RFC 3339 time or a duration before now, as 15m.
+func parseLogQuery(values url.Values, now time.Time) (output.LogQuery, error) {
+	q := output.LogQuery{
+		App:      values.Get("app"),
+		Index:    values.Get("index"),
+		Contains: values.Get("q"),
+		Limit:    defaultLogsLimit,
+	}
+	if s := values.Get("min-severity"); s != "" {
+		sev, err := transform.ParseSeverity(s)
+		if err != nil {
+			return q, fmt.Errorf("min-severity=%s: %w", s, err)
+		}
+		q.MinSeverity = sev
+	}
+	for name, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		s := values.Get(name)
+		if s == "" {
+			continue
+		}
+		if ago, err := time.ParseDuration(s); err == nil {
+			*t = now.Add(-ago)
+		} else if *t, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return q, fmt.Errorf("%s=%s: want a time like 2024-01-15T10:30:00Z or a duration like 15m", name, s)
+		}
+	}
+	if s := values.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxLogsLimit {
+			return q, fmt.Errorf("limit=%s: want 1 to %d", s, maxLogsLimit)
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
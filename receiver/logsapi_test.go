@@ -0,0 +1,92 @@
+// ABOUTME: Tests for the /api/logs query API over the SQLite store.
+// ABOUTME: Covers filtering by query parameters, summaries, bad parameters, and the store not being enabled.
+
+package receiver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"otlp-mock-receiver/output"
+)
+
+// logsRequest serves a GET of path, decoding a 200 reply into v
+func logsRequest(t *testing.T, path string, v any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	newHTTPMux(false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("decode %s: %v", rec.Body, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestLogsAPI(t *testing.T) {
+	var resp LogsResponse
+	if code := logsRequest(t, "/api/logs", &resp); code != http.StatusNotFound {
+		t.Errorf("without a store = %d, want 404", code)
+	}
+
+	store, err := output.NewSQLiteWriter(output.SQLiteConfig{Path: filepath.Join(t.TempDir(), "logs.db")})
+	if err != nil {
+		t.Fatalf("NewSQLiteWriter failed: %v", err)
+	}
+	defer store.Close()
+	now := time.Now().UTC()
+	store.Write(&output.LogEntry{Timestamp: now.Add(-time.Hour).Format(time.RFC3339Nano), SeverityNumber: 17, Body: "old failure",
+		Attributes: map[string]any{"cf_app_name": "checkout"}, Routing: output.RoutingInfo{Index: "tas_errors"}})
+	store.Write(&output.LogEntry{Timestamp: now.Format(time.RFC3339Nano), SeverityNumber: 17, Body: "new failure",
+		Attributes: map[string]any{"cf_app_name": "checkout"}, Routing: output.RoutingInfo{Index: "tas_errors"}})
+	store.Write(&output.LogEntry{Timestamp: now.Format(time.RFC3339Nano), SeverityNumber: 9, Body: "started",
+		Attributes: map[string]any{"cf_app_name": "payment"}, Routing: output.RoutingInfo{Index: "tas_logs"}})
+	store.Flush()
+	SetLogStore(store)
+	defer SetLogStore(nil)
+
+	if code := logsRequest(t, "/api/logs?app=checkout&min-severity=ERROR&since=30m", &resp); code != http.StatusOK {
+		t.Fatalf("GET /api/logs = %d, want 200", code)
+	}
+	var entry output.LogEntry
+	if resp.Count != 1 || json.Unmarshal(resp.Logs[0], &entry) != nil || entry.Body != "new failure" {
+		t.Errorf("got %d logs %s, want only the new failure", resp.Count, resp.Logs)
+	}
+
+	var summary LogsSummaryResponse
+	if code := logsRequest(t, "/api/logs/summary?by=app", &summary); code != http.StatusOK {
+		t.Fatalf("GET /api/logs/summary = %d, want 200", code)
+	}
+	if len(summary.Counts) != 2 || summary.Counts[0] != (output.LogCount{Key: "checkout", Count: 2}) {
+		t.Errorf("summary = %+v, want checkout 2 first", summary)
+	}
+
+	for _, path := range []string{
+		"/api/logs?limit=5000",
+		"/api/logs?since=yesterday",
+		"/api/logs?min-severity=LOUD",
+		"/api/logs/summary?by=host",
+	} {
+		if code := logsRequest(t, path, &resp); code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", path, code)
+		}
+	}
+}
+
+func TestParseLogQuery(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	values, _ := url.ParseQuery("index=tas_errors&q=timeout&since=2024-01-15T10:00:00Z&until=1h&limit=10")
+	q, err := parseLogQuery(values, now)
+	if err != nil {
+		t.Fatalf("parseLogQuery failed: %v", err)
+	}
+	if q.Index != "tas_errors" || q.Contains != "timeout" || q.Limit != 10 ||
+		!q.Since.Equal(now.Add(-2*time.Hour)) || !q.Until.Equal(now.Add(-time.Hour)) {
+		t.Errorf("query = %+v, want the parameters' filters", q)
+	}
+}
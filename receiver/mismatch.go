@@ -0,0 +1,112 @@
+// ABOUTME: Detection of protocol mismatches on the multiplexed single port.
+// ABOUTME: Answers gRPC-shaped requests that reached the HTTP server and closes TLS handshakes.
+
+package receiver
+
+import (
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Kinds of protocol mismatch, used as the metric label
+const (
+	mismatchTLS          = "tls"            // TLS handshake on the plaintext port
+	mismatchGRPCOverHTTP = "grpc_over_http" // gRPC Content-Type that missed the gRPC matcher
+	mismatchGRPCPath     = "grpc_path"      // gRPC method path without a gRPC Content-Type
+)
+
+const (
+	grpcContentType     = "application/grpc"
+	grpcServicePrefix   = "/opentelemetry.proto.collector."
+	grpcUnavailableCode = "14"
+
+	// matchReadTimeout bounds how long a connection that sends too little can stall matching
+	matchReadTimeout = 10 * time.Second
+)
+
+// Hints logged and returned to mismatched clients
+const (
+	tlsMismatchHint  = "TLS handshake on a plaintext port; configure the exporter with tls.insecure: true"
+	grpcOverHTTPHint = "gRPC requires HTTP/2 with prior knowledge; use the otlp exporter with tls.insecure: true, or otlphttp with /v1/logs"
+	grpcPathHint     = "this is the gRPC method path; send gRPC with Content-Type application/grpc, or POST OTLP/HTTP to /v1/logs"
+)
+
+// ProtocolMismatchResponse is the JSON body returned for a mismatched request
+type ProtocolMismatchResponse struct {
+	Error       string `json:"error"`
+	Path        string `json:"path"`
+	Proto       string `json:"proto"`
+	ContentType string `json:"content_type"`
+}
+
+// recordMismatch counts a mismatch and logs what the client should change
+func recordMismatch(kind, remote, hint string) {
+	if metricsInstance != nil {
+		metricsInstance.ProtocolMismatches.WithLabelValues(kind).Inc()
+	}
+	slog.Warn("Protocol mismatch", "kind", kind, "remote", remote, "hint", hint)
+}
+
+// isGRPCContentType reports whether a Content-Type header names a gRPC encoding
+func isGRPCContentType(header string) bool {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	return mediaType == grpcContentType || strings.HasPrefix(mediaType, grpcContentType+"+")
+}
+
+// rejectTLS accepts connections that opened with a TLS handshake and closes
+// them. A plaintext server can't answer in TLS, so the log carries the hint.
+func rejectTLS(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		recordMismatch(mismatchTLS, conn.RemoteAddr().String(), tlsMismatchHint)
+		conn.Close()
+	}
+}
+
+// grpcMismatchHandler answers gRPC-shaped requests that reached the HTTP server
+// instead of letting them 404 or fail to decode
+func grpcMismatchHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		switch {
+		case isGRPCContentType(contentType):
+			recordMismatch(mismatchGRPCOverHTTP, r.RemoteAddr, grpcOverHTTPHint)
+			if r.ProtoMajor == 2 {
+				// Trailers-only response so gRPC clients surface the message
+				w.Header().Set("Content-Type", grpcContentType)
+				w.Header().Set("Grpc-Status", grpcUnavailableCode)
+				w.Header().Set("Grpc-Message", grpcOverHTTPHint)
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			writeProtocolMismatch(w, r, http.StatusHTTPVersionNotSupported, grpcOverHTTPHint)
+		case strings.HasPrefix(r.URL.Path, grpcServicePrefix):
+			recordMismatch(mismatchGRPCPath, r.RemoteAddr, grpcPathHint)
+			writeProtocolMismatch(w, r, http.StatusUnsupportedMediaType, grpcPathHint)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// writeProtocolMismatch responds with the status and a JSON body describing the request
+func writeProtocolMismatch(w http.ResponseWriter, r *http.Request, status int, hint string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, &ProtocolMismatchResponse{
+		Error:       hint,
+		Path:        r.URL.Path,
+		Proto:       r.Proto,
+		ContentType: r.Header.Get("Content-Type"),
+	})
+}
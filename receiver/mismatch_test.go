@@ -0,0 +1,118 @@
+// ABOUTME: Tests for protocol mismatch handling on the multiplexed port.
+// ABOUTME: Covers gRPC Content-Type detection, HTTP-side responses, and TLS rejection.
+
+package receiver
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-mock-receiver/metrics"
+)
+
+func TestIsGRPCContentType(t *testing.T) {
+	tests := map[string]bool{
+		"application/grpc":        true,
+		"application/grpc+proto":  true,
+		"application/grpc-web":    false,
+		"application/x-protobuf":  false,
+		"application/json":        false,
+		"":                        false,
+		"application/grpc; x=y":   true,
+		"application/grpcfoo":     false,
+		"application/grpc+json ;": true,
+	}
+	for header, want := range tests {
+		if got := isGRPCContentType(header); got != want {
+			t.Errorf("isGRPCContentType(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestGRPCMismatchHandler(t *testing.T) {
+	m := metrics.New()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := grpcMismatchHandler(next)
+	exportPath := "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		protoMajor  int
+		wantStatus  int
+		wantGRPC    string
+	}{
+		{"gRPC over HTTP/1.1", exportPath, "application/grpc", 1, http.StatusHTTPVersionNotSupported, ""},
+		{"gRPC over HTTP/2", exportPath, "application/grpc+proto", 2, http.StatusOK, grpcUnavailableCode},
+		{"gRPC path as protobuf", exportPath, "application/x-protobuf", 1, http.StatusUnsupportedMediaType, ""},
+		{"OTLP/HTTP passes through", "/v1/logs", "application/x-protobuf", 1, http.StatusTeapot, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(""))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.ProtoMajor = tt.protoMajor
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Grpc-Status"); got != tt.wantGRPC {
+				t.Errorf("Grpc-Status = %q, want %q", got, tt.wantGRPC)
+			}
+		})
+	}
+
+	if got := testutil.ToFloat64(m.ProtocolMismatches.WithLabelValues(mismatchGRPCOverHTTP)); got != 2 {
+		t.Errorf("grpc_over_http mismatches = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.ProtocolMismatches.WithLabelValues(mismatchGRPCPath)); got != 1 {
+		t.Errorf("grpc_path mismatches = %v, want 1", got)
+	}
+}
+
+func TestRejectTLS(t *testing.T) {
+	m := metrics.New()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		rejectTLS(lis)
+		close(done)
+	}()
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+
+	lis.Close()
+	<-done
+	if got := testutil.ToFloat64(m.ProtocolMismatches.WithLabelValues(mismatchTLS)); got != 1 {
+		t.Errorf("tls mismatches = %v, want 1", got)
+	}
+}
@@ -0,0 +1,41 @@
+// ABOUTME: Wires the multiline assembler into the receive pipeline.
+// ABOUTME: Stitched records are processed when complete, on expiry, or at shutdown.
+
+package receiver
+
+import (
+	"context"
+	"fmt"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/multiline"
+	"otlp-mock-receiver/transform"
+)
+
+// actionStitch is the action type for a record stitched from continuation lines
+const actionStitch transform.ActionType = "stitch"
+
+// SetMultiline enables stitching of continuation lines into the preceding record
+func SetMultiline(a *multiline.Assembler) {
+	assembler = a
+}
+
+// instanceKey identifies the app instance a record came from, so lines from
+// different instances of the same app are never stitched together
+func instanceKey(resource *resourcepb.Resource, lr *logspb.LogRecord) string {
+	return getAppName(resource, lr) + "/" +
+		findAttribute(resource, lr, "cf_instance_id", "instance_id") + "/" +
+		findAttribute(resource, lr, "cf_source_type", "source_type")
+}
+
+// processStitched runs an assembled record through the pipeline
+func processStitched(rec multiline.Record, verbose bool) {
+	var pre []transform.TransformAction
+	if rec.Lines > 1 {
+		stats.LogsStitched.Add(int64(rec.Lines - 1))
+		pre = append(pre, transform.TransformAction{Type: actionStitch, Detail: fmt.Sprintf("Stitched %d lines", rec.Lines)})
+	}
+	processLogRecord(context.Background(), rec.Resource, rec.Scope, rec.Log, pre, false, verbose)
+}
@@ -0,0 +1,60 @@
+// ABOUTME: Tests for multiline stitching in the receive pipeline.
+// ABOUTME: Covers instance keys and stitched record accounting.
+
+package receiver
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/multiline"
+)
+
+func stringKV(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func TestInstanceKey(t *testing.T) {
+	resource := &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		stringKV("application_name", "orders"),
+		stringKV("instance_id", "2"),
+	}}
+	lr := &logspb.LogRecord{Attributes: []*commonpb.KeyValue{stringKV("source_type", "APP/PROC/WEB")}}
+
+	if got, want := instanceKey(resource, lr), "orders/2/APP/PROC/WEB"; got != want {
+		t.Errorf("instanceKey = %q, want %q", got, want)
+	}
+}
+
+func TestProcessRequest_StitchesContinuationLines(t *testing.T) {
+	SetMultiline(multiline.New(regexp.MustCompile(`^\S`), time.Minute))
+	defer SetMultiline(nil)
+	before := stats.LogsStitched.Load()
+
+	var records []*logspb.LogRecord
+	for _, body := range []string{"Exception in thread main", "\tat A.b(A.java:1)", "\tat C.d(C.java:2)"} {
+		records = append(records, &logspb.LogRecord{
+			Attributes: []*commonpb.KeyValue{stringKV("cf_app_name", "stitch-test")},
+			Body:       &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}},
+		})
+	}
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}},
+	}}}
+
+	ack := processRequest(req, false)
+	if ack.Accepted != 3 {
+		t.Errorf("Accepted = %d, want 3", ack.Accepted)
+	}
+
+	FlushPending(false)
+	if got := stats.LogsStitched.Load() - before; got != 2 {
+		t.Errorf("LogsStitched increased by %d, want 2", got)
+	}
+}
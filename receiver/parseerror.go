@@ -0,0 +1,116 @@
+// ABOUTME: Diagnostics for OTLP export requests that fail to decode.
+// ABOUTME: Builds a structured JSON error body with offset, leading bytes, and hints.
+
+package receiver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// headBytes is how many leading body bytes are echoed back in hex
+const headBytes = 16
+
+// ParseErrorResponse is the JSON body returned when an export request cannot be decoded
+type ParseErrorResponse struct {
+	Error       string   `json:"error"`
+	Detail      string   `json:"detail"`
+	ContentType string   `json:"content_type"`
+	BodyBytes   int      `json:"body_bytes"`
+	Offset      *int     `json:"offset,omitempty"` // First malformed byte, when it can be located
+	HeadHex     string   `json:"head_hex"`
+	Hints       []string `json:"hints,omitempty"`
+}
+
+// diagnoseParseError explains why body failed to decode as an ExportLogsServiceRequest
+// in the negotiated media type
+func diagnoseParseError(body []byte, contentType, mediaType string, err error) *ParseErrorResponse {
+	resp := &ParseErrorResponse{
+		Error:       "failed to parse OTLP protobuf",
+		Detail:      err.Error(),
+		ContentType: contentType,
+		BodyBytes:   len(body),
+		HeadHex:     fmt.Sprintf("% x", body[:min(len(body), headBytes)]),
+		Hints:       parseHints(body, mediaType),
+	}
+
+	off := -1
+	if mediaType == contentTypeJSON {
+		resp.Error = "failed to parse OTLP JSON"
+		off = locateJSONError(err)
+	} else {
+		md := (&collogspb.ExportLogsServiceRequest{}).ProtoReflect().Descriptor()
+		off = locateProtoError(body, md, 0)
+	}
+	if off >= 0 {
+		resp.Offset = &off
+	}
+	return resp
+}
+
+// locateJSONError returns the offset reported by a JSON syntax or type error, or -1
+func locateJSONError(err error) int {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return max(int(syntaxErr.Offset)-1, 0) // Offset counts the offending byte
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return int(typeErr.Offset)
+	}
+	return -1
+}
+
+// locateProtoError walks the wire format guided by the message descriptor and
+// returns the offset of the first field that cannot be decoded, or -1 if none is found
+func locateProtoError(b []byte, md protoreflect.MessageDescriptor, base int) int {
+	for pos := 0; pos < len(b); {
+		num, typ, n := protowire.ConsumeTag(b[pos:])
+		if n < 0 {
+			return base + pos
+		}
+		vn := protowire.ConsumeFieldValue(num, typ, b[pos+n:])
+		if vn < 0 {
+			return base + pos
+		}
+		if fd := md.Fields().ByNumber(num); fd != nil && fd.Message() != nil && typ == protowire.BytesType {
+			v, ln := protowire.ConsumeBytes(b[pos+n:])
+			if off := locateProtoError(v, fd.Message(), base+pos+n+ln-len(v)); off >= 0 {
+				return off
+			}
+		}
+		pos += n + vn
+	}
+	return -1
+}
+
+// parseHints suggests likely causes based on the body's leading bytes and the negotiated media type
+func parseHints(body []byte, mediaType string) []string {
+	var hints []string
+	trimmed := bytes.TrimSpace(body)
+	looksJSON := len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+
+	switch {
+	case len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b:
+		hints = append(hints, "body is gzip-compressed but the receiver does not decompress; set compression: none on the exporter")
+	case mediaType == contentTypeProtobuf && looksJSON:
+		hints = append(hints, "body looks like JSON; send it with Content-Type: application/json")
+	case mediaType == contentTypeJSON && !looksJSON:
+		hints = append(hints, "body is not JSON; send protobuf with Content-Type: application/x-protobuf")
+	}
+	return hints
+}
+
+// writeParseError responds with a 400 and a structured diagnostic body
+func writeParseError(w http.ResponseWriter, resp *ParseErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	writeJSON(w, resp)
+}
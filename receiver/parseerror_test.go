@@ -0,0 +1,129 @@
+// ABOUTME: Tests for OTLP decode diagnostics.
+// ABOUTME: Covers malformed offset location, content hints, and the 400 response body.
+
+package receiver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func validRequestBytes(t *testing.T) []byte {
+	t.Helper()
+	req := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			ScopeLogs: []*logspb.ScopeLogs{{
+				LogRecords: []*logspb.LogRecord{{
+					Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "hello"}},
+				}},
+			}},
+		}},
+	}
+	data, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return data
+}
+
+func TestLocateProtoError_ValidBody(t *testing.T) {
+	md := (&collogspb.ExportLogsServiceRequest{}).ProtoReflect().Descriptor()
+	if off := locateProtoError(validRequestBytes(t), md, 0); off != -1 {
+		t.Errorf("offset = %d, want -1 for a valid body", off)
+	}
+}
+
+func TestDiagnoseProtobuf_TruncatedBodyHasOffset(t *testing.T) {
+	data := validRequestBytes(t)
+	truncated := data[:len(data)-3]
+	err := proto.Unmarshal(truncated, &collogspb.ExportLogsServiceRequest{})
+	if err == nil {
+		t.Fatal("expected truncated body to fail")
+	}
+
+	resp := diagnoseParseError(truncated, "application/x-protobuf", contentTypeProtobuf, err)
+	if resp.Offset == nil {
+		t.Fatal("expected an offset for a truncated body")
+	}
+	if *resp.Offset >= len(truncated) {
+		t.Errorf("offset %d beyond body length %d", *resp.Offset, len(truncated))
+	}
+	if resp.BodyBytes != len(truncated) {
+		t.Errorf("BodyBytes = %d, want %d", resp.BodyBytes, len(truncated))
+	}
+	if len(resp.Hints) != 0 {
+		t.Errorf("expected no hints, got %v", resp.Hints)
+	}
+}
+
+func TestParseHints(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      []byte
+		mediaType string
+		want      string
+	}{
+		{"json sent as protobuf", []byte(`  {"resourceLogs": []}`), contentTypeProtobuf, "looks like JSON"},
+		{"protobuf sent as json", []byte{0x0a, 0x02}, contentTypeJSON, "not JSON"},
+		{"gzip body", []byte{0x1f, 0x8b, 0x08, 0x00}, contentTypeProtobuf, "gzip-compressed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hints := parseHints(tt.body, tt.mediaType)
+			if !strings.Contains(strings.Join(hints, "\n"), tt.want) {
+				t.Errorf("hints %v missing %q", hints, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleLogs_ParseErrorBody(t *testing.T) {
+	h := &httpHandler{}
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader([]byte(`{"resourceLogs":[]}`)))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+
+	h.handleLogs(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var resp ParseErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if resp.HeadHex == "" || resp.Detail == "" || len(resp.Hints) == 0 {
+		t.Errorf("incomplete diagnostics: %+v", resp)
+	}
+}
+
+func TestDiagnoseParseError_JSONSyntaxOffset(t *testing.T) {
+	body := []byte(`{"resourceLogs": [}`)
+	_, err := decodeRequest(body, contentTypeJSON)
+	if err == nil {
+		t.Fatal("expected malformed JSON to fail")
+	}
+
+	resp := diagnoseParseError(body, "application/json", contentTypeJSON, err)
+	if resp.Error != "failed to parse OTLP JSON" {
+		t.Errorf("Error = %q", resp.Error)
+	}
+	if resp.Offset == nil {
+		t.Fatal("expected an offset for a JSON syntax error")
+	}
+	if *resp.Offset != 18 {
+		t.Errorf("Offset = %d, want 18", *resp.Offset)
+	}
+}
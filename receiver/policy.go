@@ -0,0 +1,91 @@
+// ABOUTME: Applies the allowlist's filtering and per-app policy to records.
+// ABOUTME: A YAML allowlist entry or group can set an app's filtering, minimum severity, sample rate, and index.
+
+package receiver
+
+import (
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/allowlist"
+	"otlp-mock-receiver/routing"
+	"otlp-mock-receiver/transform"
+)
+
+// policyRule names the routing decision for records sent to their allowlist
+// entry's index; a group's is policyRule/<group>
+const policyRule = "allowlist"
+
+// appPolicy returns the allowlist policy for the record's app, if any
+func appPolicy(resource *resourcepb.Resource, lr *logspb.LogRecord) allowlist.Policy {
+	al := currentAllowlist()
+	if al == nil {
+		return allowlist.Policy{}
+	}
+	policy, _ := al.Policy(resource, lr)
+	return policy
+}
+
+// samplingFor returns the sampling config for an app: the receiver's, or with
+// the policy's sample rate in place of its rates and per-second budget
+func samplingFor(policy allowlist.Policy) *transform.SamplingConfig {
+	if policy.SampleRate == 0 {
+		return samplingConfig
+	}
+	cfg := transform.SamplingConfig{TraceAware: true}
+	if samplingConfig != nil {
+		cfg = *samplingConfig
+	}
+	cfg.SampleRate, cfg.SeverityRates, cfg.MaxPerSecond = policy.SampleRate, nil, 0
+	return &cfg
+}
+
+// Reasons the allowlist filters a record, as reported in its accounting
+const (
+	filterDenied           = "denied"
+	filterNotAllowed       = "not_allowed"
+	filterBelowMinSeverity = "below_min_severity"
+)
+
+// filterReason returns why the allowlist filters the record out, and a
+// description for logs, or empty if it passes: a denied app, one in a deny
+// group, one not allowed, or a severity below its policy's minimum
+func filterReason(resource *resourcepb.Resource, lr *logspb.LogRecord, policy allowlist.Policy) (string, string) {
+	switch al := currentAllowlist(); {
+	case al == nil:
+		return "", ""
+	case al.IsDenied(resource, lr):
+		return filterDenied, "in denylist"
+	case policy.Deny:
+		return filterDenied, "in deny group " + policy.Group
+	case !al.IsAllowed(resource, lr):
+		return filterNotAllowed, "not in allowlist"
+	case lr.GetSeverityNumber() < policy.MinSeverity:
+		return filterBelowMinSeverity, "below min severity " + transform.SeverityName(policy.MinSeverity)
+	}
+	return "", ""
+}
+
+// allowlistStatus names the allowlist's decision on a record, given its
+// filter reason, for app reports. A record below its app's minimum severity
+// is from an allowed app.
+func allowlistStatus(filter string) string {
+	switch {
+	case currentAllowlist() == nil:
+		return "no allowlist"
+	case filter == filterDenied:
+		return "denied"
+	case filter == filterNotAllowed:
+		return "not allowed"
+	}
+	return "allowed"
+}
+
+// policyRoute sends the record to its policy's index, bypassing the routing rules
+func policyRoute(policy allowlist.Policy) []routing.Destination {
+	rule := policyRule
+	if policy.Group != "" {
+		rule += "/" + policy.Group
+	}
+	return []routing.Destination{{Index: policy.Index, Rule: rule}}
+}
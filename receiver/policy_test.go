@@ -0,0 +1,152 @@
+// ABOUTME: Tests for applying allowlist filtering and per-app policy to records.
+// ABOUTME: Covers minimum severity drops, index overrides, sample rate overrides, policy groups, and explain output.
+
+package receiver
+
+import (
+	"testing"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/allowlist"
+	"otlp-mock-receiver/transform"
+)
+
+func setPolicyAllowlist(t *testing.T, entries ...*allowlist.AppEntry) {
+	t.Helper()
+	al, err := (&allowlist.FileConfig{Apps: entries}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetAllowlist(al)
+	t.Cleanup(func() { SetAllowlist(nil) })
+}
+
+func policyRecord(app string, sev logspb.SeverityNumber, body string) *logspb.LogRecord {
+	return &logspb.LogRecord{
+		SeverityNumber: sev,
+		Attributes:     []*commonpb.KeyValue{stringKV("cf_app_name", app)},
+		Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}},
+	}
+}
+
+func TestProcessRequest_AllowlistPolicy(t *testing.T) {
+	setPolicyAllowlist(t,
+		&allowlist.AppEntry{App: "payments", MinSeverity: "warn", Index: "tas_payments"},
+		&allowlist.AppEntry{App: "web"},
+	)
+
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{
+			policyRecord("payments", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "below minimum"),
+			policyRecord("payments", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "to policy index"),
+			policyRecord("web", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "through the rules"),
+		}}},
+	}}}
+	ack := processRequest(req, false)
+	if ack.Bitmap != "011" || len(ack.Rejected) != 1 || ack.Rejected[0].Reason != "filtered" {
+		t.Fatalf("Bitmap = %q, Rejected = %+v, want the INFO payments record filtered", ack.Bitmap, ack.Rejected)
+	}
+
+	exp := explainTransform(nil, nil, policyRecord("payments", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "explained"))
+	if len(exp.Routing) != 1 || exp.Routing[0].Index != "tas_payments" || exp.Routing[0].Rule != policyRule {
+		t.Errorf("payments Routing = %+v, want tas_payments by the allowlist", exp.Routing)
+	}
+	exp = explainTransform(nil, nil, policyRecord("web", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "explained"))
+	if len(exp.Routing) != 1 || exp.Routing[0].Index != "tas_errors" {
+		t.Errorf("web Routing = %+v, want tas_errors from the default rules", exp.Routing)
+	}
+	exp = explainTransform(nil, nil, policyRecord("payments", logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "explained"))
+	if exp.Dropped != "filtered" {
+		t.Errorf("payments DEBUG Dropped = %q, want filtered", exp.Dropped)
+	}
+}
+
+func TestSamplingFor_PolicyRate(t *testing.T) {
+	SetSamplingConfig(&transform.SamplingConfig{SampleRate: 2, SampleDebugOnly: true, MaxPerSecond: 50})
+	defer SetSamplingConfig(nil)
+
+	if cfg := samplingFor(allowlist.Policy{}); cfg != samplingConfig {
+		t.Error("no sample rate override should use the receiver's config")
+	}
+	cfg := samplingFor(allowlist.Policy{SampleRate: 10})
+	if cfg.SampleRate != 10 || cfg.MaxPerSecond != 0 || !cfg.SampleDebugOnly {
+		t.Errorf("override config = %+v, want rate 10 without a budget, keeping other settings", cfg)
+	}
+
+	SetSamplingConfig(nil)
+	if cfg := samplingFor(allowlist.Policy{SampleRate: 10}); cfg == nil || cfg.SampleRate != 10 {
+		t.Errorf("override without receiver sampling = %+v, want rate 10", cfg)
+	}
+}
+
+func TestProcessRequest_PolicySampleRate(t *testing.T) {
+	setPolicyAllowlist(t, &allowlist.AppEntry{App: "chatty", SampleRate: 1 << 30})
+
+	var records []*logspb.LogRecord
+	for _, body := range []string{"one", "two", "three"} {
+		records = append(records, policyRecord("chatty", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, body))
+	}
+	records = append(records, policyRecord("chatty", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "errors are never sampled"))
+	ack := processRequest(&collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}},
+	}}}, false)
+
+	if ack.Bitmap != "0001" || ack.Rejected[0].Reason != "sampled" {
+		t.Errorf("Bitmap = %q, Rejected = %+v, want the INFO records sampled out", ack.Bitmap, ack.Rejected)
+	}
+}
+
+func TestProcessRequest_MinSeverityBeforeSampling(t *testing.T) {
+	setPolicyAllowlist(t,
+		&allowlist.AppEntry{App: "chatty-*", MinSeverity: "warn"},
+		&allowlist.AppEntry{App: "*"},
+	)
+	SetSamplingConfig(&transform.SamplingConfig{MaxPerSecond: 1})
+	defer SetSamplingConfig(nil)
+
+	debugText := policyRecord("chatty-api", 0, "debug text only")
+	debugText.SeverityText = "debug"
+	ack := processRequest(&collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{
+			policyRecord("chatty-api", logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "cut"),
+			debugText,
+			policyRecord("chatty-api", logspb.SeverityNumber_SEVERITY_NUMBER_WARN, "within the budget"),
+			policyRecord("quiet-api", logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "other apps are unfiltered"),
+		}}},
+	}}}, false)
+
+	// The cut DEBUG records leave the chatty app's budget for its WARN record
+	if ack.Bitmap != "0011" {
+		t.Errorf("Bitmap = %q, want the DEBUG chatty records filtered and the rest kept", ack.Bitmap)
+	}
+	for _, r := range ack.Rejected {
+		if r.Reason != "filtered" {
+			t.Errorf("rejected %+v, want filtered", r)
+		}
+	}
+}
+
+func TestProcessRequest_PolicyGroups(t *testing.T) {
+	al, err := (&allowlist.FileConfig{Groups: []*allowlist.Group{
+		{Name: "payments", Apps: []string{"payment-*"}, Index: "tas_payments"},
+		{Name: "batch", Apps: []string{"batch-*"}, Filter: allowlist.FilterDeny},
+	}}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetAllowlist(al)
+	defer SetAllowlist(nil)
+
+	exp := explainTransform(nil, nil, policyRecord("payment-api", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "explained"))
+	if len(exp.Routing) != 1 || exp.Routing[0].Index != "tas_payments" || exp.Routing[0].Rule != "allowlist/payments" {
+		t.Errorf("payment-api Routing = %+v, want tas_payments by the payments group", exp.Routing)
+	}
+
+	lr := policyRecord("batch-nightly", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "denied")
+	if reason, why := filterReason(nil, lr, appPolicy(nil, lr)); reason != filterDenied || why != "in deny group batch" {
+		t.Errorf("filterReason = %q, %q, want denied by the batch group", reason, why)
+	}
+}
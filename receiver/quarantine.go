@@ -0,0 +1,52 @@
+// ABOUTME: Quarantine routing for malformed or suspicious records.
+// ABOUTME: Records failing validation bypass the routing rules and go to a quarantine index, tagged with why.
+
+package receiver
+
+import (
+	"slices"
+	"strings"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/routing"
+	"otlp-mock-receiver/transform"
+)
+
+// DefaultQuarantineIndex is where quarantined records go unless configured otherwise
+const DefaultQuarantineIndex = "tas_quarantine"
+
+// quarantineRule names the routing decision for quarantined records in logs, metrics, and output
+const quarantineRule = "quarantine"
+
+// quarantineViolations are the schema violations that quarantine a record
+var quarantineViolations = []string{violationMissingAppName, violationMissingTimestamp, violationInvalidUTF8Body}
+
+// quarantineIndex is where records failing validation go; empty disables quarantine
+var quarantineIndex string
+
+// SetQuarantineIndex sends records failing validation to index instead of
+// routing them; an empty index disables quarantine
+func SetQuarantineIndex(index string) {
+	quarantineIndex = index
+}
+
+// quarantineReasons returns the violations that quarantine a record, none if quarantine is disabled
+func quarantineReasons(violations []string) []string {
+	if quarantineIndex == "" {
+		return nil
+	}
+	var reasons []string
+	for _, v := range violations {
+		if slices.Contains(quarantineViolations, v) {
+			reasons = append(reasons, v)
+		}
+	}
+	return reasons
+}
+
+// quarantine tags the record with why it was quarantined and returns its only destination
+func quarantine(lr *logspb.LogRecord, reasons []string) []routing.Destination {
+	transform.SetAttribute(lr, "quarantine_reason", strings.Join(reasons, ","))
+	return []routing.Destination{{Index: quarantineIndex, Rule: quarantineRule}}
+}
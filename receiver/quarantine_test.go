@@ -0,0 +1,75 @@
+// ABOUTME: Tests for quarantine routing of records failing validation.
+// ABOUTME: Covers the reasons that quarantine a record, the tagged attribute, and that valid records route normally.
+
+package receiver
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/metrics"
+	"otlp-mock-receiver/routing"
+)
+
+func TestQuarantineReasons(t *testing.T) {
+	violations := []string{violationMissingAppName, violationMissingSeverity, violationInvalidUTF8Body}
+	if got := quarantineReasons(violations); got != nil {
+		t.Errorf("disabled quarantine reasons = %v, want none", got)
+	}
+
+	SetQuarantineIndex(DefaultQuarantineIndex)
+	defer SetQuarantineIndex("")
+	got := quarantineReasons(violations)
+	if len(got) != 2 || got[0] != violationMissingAppName || got[1] != violationInvalidUTF8Body {
+		t.Errorf("reasons = %v, want missing_app_name and invalid_utf8_body", got)
+	}
+}
+
+func TestProcessRequest_Quarantine(t *testing.T) {
+	SetQuarantineIndex(DefaultQuarantineIndex)
+	defer SetQuarantineIndex("")
+	SetRouter(routing.DefaultRouter())
+	m := metrics.New()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	body := &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "quarantine-test"}}
+	valid := &logspb.LogRecord{TimeUnixNano: 1, Body: body, SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+		Attributes: []*commonpb.KeyValue{stringKV("cf_app_name", "quarantine-test")}}
+	noApp := &logspb.LogRecord{TimeUnixNano: 1, Body: body, SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR}
+	noTime := &logspb.LogRecord{Body: body, Attributes: []*commonpb.KeyValue{stringKV("cf_app_name", "quarantine-test")}}
+
+	ack := processRequest(&collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{valid, noApp, noTime}}},
+	}}}, false)
+
+	if ack.Bitmap != "111" {
+		t.Errorf("Bitmap = %q, want quarantined records accepted", ack.Bitmap)
+	}
+	if got := testutil.ToFloat64(m.LogsByIndex.WithLabelValues(DefaultQuarantineIndex)); got != 2 {
+		t.Errorf("logs_by_index_total{index=tas_quarantine} = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.LogsByIndex.WithLabelValues("tas_errors")); got != 1 {
+		t.Errorf("logs_by_index_total{index=tas_errors} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.LogsQuarantined.WithLabelValues(violationMissingTimestamp)); got != 1 {
+		t.Errorf("logs_quarantined_total{reason=missing_timestamp} = %v, want 1", got)
+	}
+}
+
+func TestExplainTransform_Quarantine(t *testing.T) {
+	SetQuarantineIndex("tas_bad")
+	defer SetQuarantineIndex("")
+
+	code, exp := explain(t, `{"severityNumber": 9, "body": {"stringValue": "no app, no time"}}`)
+	if code != 200 || len(exp.Routing) != 1 || exp.Routing[0].Index != "tas_bad" || exp.Routing[0].Rule != "quarantine" {
+		t.Fatalf("routing = %+v, want tas_bad via quarantine", exp.Routing)
+	}
+	if got := exp.After.Attributes["quarantine_reason"]; got != "missing_app_name,missing_timestamp" {
+		t.Errorf("quarantine_reason = %q", got)
+	}
+}
@@ -5,12 +5,16 @@ package receiver
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -27,48 +31,133 @@ import (
 	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
 	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 
+	"otlp-mock-receiver/aggregate"
 	"otlp-mock-receiver/allowlist"
+	"otlp-mock-receiver/compare"
+	"otlp-mock-receiver/dedup"
+	"otlp-mock-receiver/delay"
+	"otlp-mock-receiver/drop"
 	"otlp-mock-receiver/metrics"
+	"otlp-mock-receiver/multiline"
 	"otlp-mock-receiver/output"
 	"otlp-mock-receiver/routing"
 	"otlp-mock-receiver/transform"
+	"otlp-mock-receiver/verify"
 )
 
 // Stats tracks receiver metrics
 type Stats struct {
-	LogsReceived    atomic.Int64
-	LogsTransformed atomic.Int64
-	LogsDropped     atomic.Int64
-	LogsFiltered    atomic.Int64
+	LogsReceived     atomic.Int64
+	LogsTransformed  atomic.Int64
+	LogsDropped      atomic.Int64
+	LogsFiltered     atomic.Int64
+	LogsStitched     atomic.Int64 // Continuation lines merged into a preceding record
+	LogsDeduplicated atomic.Int64
+	LogsAggregated   atomic.Int64 // Records collected into aggregation rollups
 }
 
 var stats Stats
 var samplingConfig *transform.SamplingConfig
+var transformConfig = transform.DefaultConfig()
 var router = routing.DefaultRouter()
+var routerMu sync.RWMutex         // Guards router, which the admin API replaces at runtime
+var routingEditor *routing.Editor // Started from router on first use unless set
 var appAllowlist *allowlist.Allowlist
+var allowlistMu sync.RWMutex // Guards appAllowlist, which the admin API starts if none was configured
 var metricsInstance *metrics.Metrics
-var jsonWriter *output.JSONWriter
+var delayConfig *delay.Config
+var comparer *compare.Comparer
+var verifier *verify.Verifier
+var assembler *multiline.Assembler
+var deduper *dedup.Deduper
+var aggregator *aggregate.Aggregator
+var sinks []output.Sink
+var console = log.Default() // Prints the per-record boxes
+var nativeTypes bool        // Keep attribute value types in output entries
 
 // SetMetrics configures Prometheus metrics for the receiver
 func SetMetrics(m *metrics.Metrics) {
 	metricsInstance = m
 }
 
-// SetJSONWriter configures the JSON file output writer
-func SetJSONWriter(w *output.JSONWriter) {
-	jsonWriter = w
+// AddSink sends every transformed record to s, as well as to the sinks added before it
+func AddSink(s output.Sink) {
+	sinks = append(sinks, s)
 }
 
-// SetSamplingConfig configures sampling for the receiver
-func SetSamplingConfig(cfg *transform.SamplingConfig) {
-	samplingConfig = cfg
+// SetConsoleBoxes turns the per-record console boxes on, printed at INFO
+// through the default slog handler, or off, as when entries stream to stdout
+// or logs are structured and the boxes would get in their way
+func SetConsoleBoxes(enabled bool) {
+	if enabled {
+		console = slog.NewLogLogger(slog.Default().Handler(), slog.LevelInfo)
+	} else {
+		console = log.New(io.Discard, "", 0)
+	}
+}
+
+// SetNativeTypes keeps attribute values' OTLP types in output entries, as
+// numbers, booleans, arrays, and objects, instead of flattening them to strings
+func SetNativeTypes(enabled bool) {
+	nativeTypes = enabled
+}
+
+// SetTransformConfig configures the transformation pipeline
+func SetTransformConfig(cfg *transform.Config) {
+	transformConfig = cfg
+}
+
+// SetDelayConfig configures response delay injection
+func SetDelayConfig(cfg *delay.Config) {
+	delayConfig = cfg
+}
+
+// SetComparer enables cross-checking received records against collector output
+func SetComparer(c *compare.Comparer) {
+	comparer = c
+}
+
+// SetVerifier enables reconciling transformed counters with the output file
+func SetVerifier(v *verify.Verifier) {
+	verifier = v
+}
+
+// SetRouter replaces the default routing rules, discarding any routing editor
+func SetRouter(r *routing.Router) {
+	routerMu.Lock()
+	defer routerMu.Unlock()
+	router = r
+	routingEditor = nil
+}
+
+// currentRouter returns the routing rules in effect
+func currentRouter() *routing.Router {
+	routerMu.RLock()
+	defer routerMu.RUnlock()
+	return router
+}
+
+// SetRoutingEditor configures the editor behind the routing admin API
+func SetRoutingEditor(e *routing.Editor) {
+	routerMu.Lock()
+	defer routerMu.Unlock()
+	routingEditor = e
 }
 
 // SetAllowlist configures the app allowlist for filtering
 func SetAllowlist(al *allowlist.Allowlist) {
+	allowlistMu.Lock()
+	defer allowlistMu.Unlock()
 	appAllowlist = al
 }
 
+// currentAllowlist returns the app allowlist in effect, or nil if none
+func currentAllowlist() *allowlist.Allowlist {
+	allowlistMu.RLock()
+	defer allowlistMu.RUnlock()
+	return appAllowlist
+}
+
 // LogsService implements the OTLP Logs gRPC service
 type LogsService struct {
 	collogspb.UnimplementedLogsServiceServer
@@ -77,81 +166,186 @@ type LogsService struct {
 
 // Export handles incoming OTLP log export requests
 func (s *LogsService) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
-	for _, resourceLogs := range req.GetResourceLogs() {
+	ack := processRequest(req, s.verbose)
+	return ack.Response(), nil
+}
+
+// processRequest runs every log record in the request through the pipeline and
+// returns the record-level acknowledgment
+func processRequest(req *collogspb.ExportLogsServiceRequest, verbose bool) *AckReport {
+	start := time.Now()
+	defer func() { requestLatency.Record(time.Since(start)) }()
+	ctx, span := startExportSpan()
+
+	if metricsInstance != nil {
+		metricsInstance.RequestSize.Observe(float64(proto.Size(req)))
+	}
+
+	ack := newAckReport()
+	var wait time.Duration
+	for ri, resourceLogs := range req.GetResourceLogs() {
 		resource := resourceLogs.GetResource()
 
-		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+		for si, scopeLogs := range resourceLogs.GetScopeLogs() {
 			scope := scopeLogs.GetScope()
 
-			for _, logRecord := range scopeLogs.GetLogRecords() {
+			for li, logRecord := range scopeLogs.GetLogRecords() {
 				stats.LogsReceived.Add(1)
 				if metricsInstance != nil {
 					metricsInstance.LogsReceived.Inc()
+					metricsInstance.LogsByApp.Observe(getAppName(resource, logRecord), proto.Size(logRecord))
+				}
+				if comparer != nil {
+					comparer.ObserveReceived(logRecord)
+				}
+				if assembler != nil {
+					// Buffered records are acknowledged now and processed once complete
+					ack.accept()
+					rec := multiline.Record{Resource: resource, Scope: scope, Log: logRecord}
+					for _, ready := range assembler.Add(instanceKey(resource, logRecord), rec) {
+						processStitched(ready, verbose)
+					}
+					continue
+				}
+				dests, reason := processLogRecord(ctx, resource, scope, logRecord, nil, false, verbose)
+				if reason != "" {
+					ack.reject(ri, si, li, reason)
+					continue
+				}
+				ack.accept()
+				// Simulate backend latency for the slowest destination in the request
+				if delayConfig != nil {
+					for _, d := range dests {
+						wait = max(wait, delayConfig.Sample(getAppName(resource, logRecord), d.Index))
+					}
 				}
-				processLogRecord(resource, scope, logRecord, s.verbose)
 			}
 		}
 	}
+	// Requests with no delivered records still see the default latency
+	if delayConfig != nil && ack.Accepted == 0 {
+		wait = delayConfig.Sample("", "")
+	}
+	ack.DelayMs = float64(wait) / float64(time.Millisecond)
+	ack.finish()
+	lastAck.Store(ack)
+	time.Sleep(wait)
+	endExportSpan(span, ack)
 
-	return &collogspb.ExportLogsServiceResponse{}, nil
+	if verbose {
+		logAck(ack)
+	}
+	return ack
 }
 
-func processLogRecord(resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord, verbose bool) {
-	// Record severity metric
+// processLogRecord runs a single record through the pipeline, tracing its
+// stages under ctx's span. Rollups (dedup summaries, aggregates) skip the
+// windowed stages that already counted them. Returns the routed
+// destinations, or the rejection reason if the record was not accepted.
+func processLogRecord(ctx context.Context, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord, preActions []transform.TransformAction, rollup, verbose bool) (dests []routing.Destination, reason string) {
+	// Check the record as received, before normalization fills anything in
+	violations := checkSchema(resource, lr)
+
+	bodyType, normalizeActions := normalizeRecord(lr)
+	preActions = append(preActions, normalizeActions...)
+	if metricsInstance != nil {
+		metricsInstance.LogsByBodyType.WithLabelValues(bodyType).Inc()
+	}
+
+	appName := getAppName(resource, lr)
+	severity := lr.GetSeverityText()
+	if severity == "" {
+		severity = "UNSPECIFIED"
+	}
+	appTracker.Observe(appName, time.Now().UTC(), severity, len(lr.GetBody().GetStringValue()), violations)
+
+	// Record severity and body size metrics
 	if metricsInstance != nil {
-		severity := lr.GetSeverityText()
-		if severity == "" {
-			severity = "UNSPECIFIED"
-		}
 		metricsInstance.LogsBySeverity.WithLabelValues(severity).Inc()
+		metricsInstance.BodySize.WithLabelValues(severity).Observe(float64(bodyBytes(lr)))
+	}
+
+	// Check the allowlist first, so filtered records never count against a sampling budget
+	policy := appPolicy(resource, lr)
+	filter, why := filterReason(resource, lr, policy)
+	appTracker.RecordAllowlist(appName, allowlistStatus(filter))
+	if filter != "" {
+		recordFiltered(appName, filter)
+		if allowlistReportOnly {
+			if verbose {
+				console.Printf("│ [WOULD FILTER] %s (%s)", appName, why)
+			}
+		} else {
+			reason := dropRecord(appName, drop.Filtered, "")
+			if verbose {
+				console.Printf("│ [FILTERED] %s (%s)", appName, why)
+			}
+			return nil, reason
+		}
 	}
 
 	// Check sampling before processing
-	if !transform.ShouldSample(lr, samplingConfig) {
-		stats.LogsDropped.Add(1)
-		if metricsInstance != nil {
-			metricsInstance.LogsDropped.WithLabelValues("sampled").Inc()
+	if !sample(samplingFor(policy), appName, lr) {
+		reason := dropRecord(appName, drop.Sampled, "")
+		if verbose {
+			console.Printf("│ [SAMPLED OUT] Log dropped by sampling (severity: %s)", lr.GetSeverityText())
 		}
+		return nil, reason
+	}
+
+	// Check drop rules before processing
+	if rule := transformConfig.ShouldDrop(resource, lr); rule != nil {
+		reason := dropRecord(appName, drop.Rule, rule.Name)
 		if verbose {
-			log.Printf("│ [SAMPLED OUT] Log dropped by sampling (severity: %s)", lr.GetSeverityText())
+			console.Printf("│ [DROPPED] %s (drop rule %s)", appName, rule.Name)
 		}
-		return
+		return nil, reason
 	}
 
-	// Check allowlist before processing
-	if appAllowlist != nil && !appAllowlist.IsAllowed(lr) {
-		stats.LogsFiltered.Add(1)
-		if metricsInstance != nil {
-			metricsInstance.LogsDropped.WithLabelValues("filtered").Inc()
+	// Collect records matching an aggregation rule into a rollup emitted later
+	if aggregator != nil && !rollup {
+		if rule := aggregator.Add(resource, scope, appName, lr); rule != nil {
+			stats.LogsAggregated.Add(1)
+			if metricsInstance != nil {
+				metricsInstance.LogsAggregated.WithLabelValues(rule.Name).Inc()
+			}
+			if verbose {
+				console.Printf("│ [AGGREGATED] %s (aggregation rule %s)", appName, rule.Name)
+			}
+			return nil, ""
 		}
+	}
+
+	// Suppress repeats of a record seen within the dedup window
+	if deduper != nil && !rollup && deduper.Check(resource, scope, appName, lr) {
+		reason := dropRecord(appName, drop.Duplicate, "")
 		if verbose {
-			appName := getAppName(lr)
-			log.Printf("│ [FILTERED] %s (not in allowlist)", appName)
+			console.Printf("│ [DUPLICATE] %s (suppressed within dedup window)", appName)
 		}
-		return
+		return nil, reason
 	}
 
-	log.Println("┌─────────────────────────────────────────")
-	log.Printf("│ LOG #%d", stats.LogsReceived.Load())
-	log.Println("├─────────────────────────────────────────")
+	console.Println("┌─────────────────────────────────────────")
+	console.Printf("│ LOG #%d", stats.LogsReceived.Load())
+	console.Println("├─────────────────────────────────────────")
 
 	// Print resource attributes (app metadata from TAS)
 	if resource != nil && len(resource.GetAttributes()) > 0 {
-		log.Println("│ Resource Attributes:")
+		console.Println("│ Resource Attributes:")
 		for _, attr := range resource.GetAttributes() {
-			log.Printf("│   %s = %s", attr.GetKey(), formatValue(attr.GetValue()))
+			console.Printf("│   %s = %s", attr.GetKey(), formatValue(attr.GetValue()))
 		}
 	}
 
 	// Print scope (instrumentation library info)
 	if scope != nil && scope.GetName() != "" {
-		log.Printf("│ Scope: %s (v%s)", scope.GetName(), scope.GetVersion())
+		console.Printf("│ Scope: %s (v%s)", scope.GetName(), scope.GetVersion())
 	}
 
 	// Print log details
-	log.Println("│")
-	log.Printf("│ Severity: %s (%d)", lr.GetSeverityText(), lr.GetSeverityNumber())
-	log.Printf("│ Timestamp: %d", lr.GetTimeUnixNano())
+	console.Println("│")
+	console.Printf("│ Severity: %s (%d)", lr.GetSeverityText(), lr.GetSeverityNumber())
+	console.Printf("│ Timestamp: %d", lr.GetTimeUnixNano())
 
 	// Print body
 	body := lr.GetBody()
@@ -160,95 +354,278 @@ func processLogRecord(resource *resourcepb.Resource, scope *commonpb.Instrumenta
 		if len(bodyStr) > 200 && !verbose {
 			bodyStr = bodyStr[:200] + "..."
 		}
-		log.Printf("│ Body: %s", bodyStr)
+		console.Printf("│ Body: %s", bodyStr)
 	}
 
 	// Print log attributes
 	if len(lr.GetAttributes()) > 0 {
-		log.Println("│ Attributes:")
+		console.Println("│ Attributes:")
 		for _, attr := range lr.GetAttributes() {
-			log.Printf("│   %s = %s", attr.GetKey(), formatValue(attr.GetValue()))
+			console.Printf("│   %s = %s", attr.GetKey(), formatValue(attr.GetValue()))
 		}
 	}
 
 	// Apply transformations
-	log.Println("│")
-	log.Println("│ ─── Applying Transforms ───")
+	console.Println("│")
+	console.Println("│ ─── Applying Transforms ───")
 
 	var timer *prometheus.Timer
 	if metricsInstance != nil {
 		timer = metricsInstance.NewTransformTimer()
 	}
 
-	transformed, actions := transform.Apply(lr)
+	span := startStageSpan(ctx, "transform", appName)
+	shared := resource
+	resource, transformed, actions := transformRecord(resource, lr, preActions)
+	if len(actions) == 0 {
+		console.Println("│   ✓ No transformations applied")
+	}
+	redactions := 0
 	for _, action := range actions {
-		log.Printf("│   ✓ %s", action)
+		console.Printf("│   ✓ %s", action)
+		if action.Type == transform.ActionRedactPCI {
+			redactions++
+		}
 		// Track specific transform actions in metrics
 		if metricsInstance != nil {
-			if strings.HasPrefix(action, "Redacted PCI") {
+			switch action.Type {
+			case transform.ActionRedactPCI:
 				metricsInstance.PCIRedactions.Inc()
-			} else if strings.HasPrefix(action, "Truncated body") {
+			case transform.ActionTruncateBody:
 				metricsInstance.BodyTruncations.Inc()
+			case transform.ActionScrubSecret:
+				metricsInstance.SecretsScrubbed.WithLabelValues(action.Rule).Inc()
 			}
 		}
 	}
-
-	// Apply routing
-	index, ruleName := router.Route(transformed)
-	transform.SetAttribute(transformed, "index", index)
-	log.Printf("│   ✓ Routed to: %s (rule: %s)", index, ruleName)
+	span.End()
+
+	// Apply routing, once per destination when continue rules copy the record.
+	// Quarantined records and apps whose allowlist entry sets an index skip the rules.
+	span = startStageSpan(ctx, "route", appName)
+	var routed []routing.Destination
+	if reasons := quarantineReasons(violations); len(reasons) > 0 {
+		routed = quarantine(transformed, reasons)
+		if metricsInstance != nil {
+			for _, r := range reasons {
+				metricsInstance.LogsQuarantined.WithLabelValues(r).Inc()
+			}
+		}
+	} else if policy.Index != "" {
+		routed = policyRoute(policy)
+	} else {
+		routeStart := time.Now()
+		routed = currentRouter().RouteRecord(resource, transformed)
+		recordRouting(routed, time.Since(routeStart))
+	}
+	console.Printf("│   ✓ Routed to: %s", formatDestinations(routed))
+	span.End()
 
 	if timer != nil {
 		timer.ObserveDuration()
 	}
 
+	dests = routing.Deliverable(routed)
+	if len(dests) == 0 {
+		final, kind := routing.Primary(routed), drop.Routed
+		if final.Overflow {
+			kind = drop.Throttled
+		}
+		reason := dropRecord(appName, kind, final.Rule)
+		console.Println("└─────────────────────────────────────────")
+		console.Println("")
+		return nil, reason
+	}
+	indexes := make([]string, len(dests))
+	for i, d := range dests {
+		indexes[i] = d.Index
+	}
+
 	stats.LogsTransformed.Add(1)
+	appTracker.RecordDelivery(appName, indexes, redactions)
+	slog.Debug("Log record delivered", "app", appName, "severity", severity, "indexes", strings.Join(indexes, ","), "transforms", len(actions))
 	if metricsInstance != nil {
 		metricsInstance.LogsTransformed.Inc()
-		metricsInstance.LogsByIndex.WithLabelValues(index).Inc()
+		metricsInstance.LogsFanoutCopies.Add(float64(len(dests) - 1))
+		size := float64(bodyBytes(transformed))
+		for _, index := range indexes {
+			metricsInstance.LogsByIndex.WithLabelValues(index).Inc()
+			metricsInstance.DeliveredBodySize.WithLabelValues(index).Observe(size)
+		}
 	}
 
-	// Write to JSON file if configured
-	if jsonWriter != nil {
-		entry := buildLogEntry(resource, transformed, index, ruleName, actions)
-		jsonWriter.Write(entry)
+	// Write one copy per destination to the configured outputs. The final
+	// destination goes last, leaving its index on the record.
+	span = startStageSpan(ctx, "write", appName)
+	for _, d := range dests {
+		transform.SetAttribute(transformed, "index", d.Index)
+		writeOutputs(appName, resource, scope, transformed, d, actions)
 	}
+	span.End()
+	stampSourcetype(transformed, routing.Primary(dests))
 
 	// Show transformed result
 	if verbose {
-		log.Println("│")
-		log.Println("│ ─── After Transform ───")
+		console.Println("│")
+		console.Println("│ ─── After Transform ───")
 		if transformed.GetBody() != nil {
-			log.Printf("│ Body: %s", formatValue(transformed.GetBody()))
+			console.Printf("│ Body: %s", formatValue(transformed.GetBody()))
+		}
+		if resource != shared {
+			console.Println("│ Resource Attributes:")
+			for _, attr := range resource.GetAttributes() {
+				console.Printf("│   %s = %s", attr.GetKey(), formatValue(attr.GetValue()))
+			}
 		}
 		if len(transformed.GetAttributes()) > 0 {
-			log.Println("│ Attributes:")
+			console.Println("│ Attributes:")
 			for _, attr := range transformed.GetAttributes() {
-				log.Printf("│   %s = %s", attr.GetKey(), formatValue(attr.GetValue()))
+				console.Printf("│   %s = %s", attr.GetKey(), formatValue(attr.GetValue()))
 			}
 		}
 	}
 
-	log.Println("└─────────────────────────────────────────")
-	log.Println("")
+	console.Println("└─────────────────────────────────────────")
+	console.Println("")
+	return dests, ""
+}
+
+// writeOutputs sends one destination's copy of a transformed record to each
+// sink: as a log entry, or as OTLP to sinks that take records
+func writeOutputs(appName string, resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord, d routing.Destination, actions []transform.TransformAction) {
+	var entry *output.LogEntry
+	var exported *logspb.LogRecord // Shared by record sinks, which only read it
+	for _, s := range sinks {
+		var err error
+		if rs, ok := s.(output.RecordSink); ok && rs.TakesRecords() {
+			if exported == nil {
+				exported = proto.Clone(lr).(*logspb.LogRecord)
+				stampSourcetype(exported, d)
+			}
+			err = rs.WriteRecord(appName, resource, scope, exported)
+		} else {
+			if entry == nil {
+				entry = buildLogEntry(resource, scope, lr, d, actions)
+			}
+			err = s.Write(entry)
+		}
+		if err != nil {
+			slog.Error("Output: write failed", "err", err)
+		}
+	}
+}
+
+// recordRouting counts the rules that sent a record somewhere, or the default
+// if no final rule matched, any sent to an overflow index, and how long routing took
+func recordRouting(dests []routing.Destination, elapsed time.Duration) {
+	if metricsInstance == nil {
+		return
+	}
+	metricsInstance.RoutingDuration.Observe(elapsed.Seconds())
+	for _, d := range dests {
+		if d.Rule == "default" {
+			metricsInstance.RoutingDefault.Inc()
+		} else {
+			metricsInstance.RoutingRuleMatches.WithLabelValues(d.Rule).Inc()
+		}
+		if d.Overflow {
+			metricsInstance.RoutingOverflow.WithLabelValues(d.Rule).Inc()
+		}
+	}
+}
+
+// formatDestinations describes where a record was routed, e.g.
+// "tas_archive (rule: archive), tas_logs (rule: default)"
+func formatDestinations(dests []routing.Destination) string {
+	parts := make([]string, len(dests))
+	for i, d := range dests {
+		parts[i] = fmt.Sprintf("%s (rule: %s", d.Index, d.Rule)
+		if d.Overflow {
+			parts[i] += ", over rate limit"
+		}
+		if d.Sourcetype != "" {
+			parts[i] += ", sourcetype: " + d.Sourcetype
+		}
+		if d.Source != "" {
+			parts[i] += ", source: " + d.Source
+		}
+		parts[i] += ")"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// stampSourcetype sets the sourcetype and source attributes a destination's
+// rule configures, leaving the record's own values where it sets none
+func stampSourcetype(lr *logspb.LogRecord, d routing.Destination) {
+	if d.Sourcetype != "" {
+		transform.SetAttribute(lr, "sourcetype", d.Sourcetype)
+	}
+	if d.Source != "" {
+		transform.SetAttribute(lr, "source", d.Source)
+	}
+}
+
+// routingInfo is the JSON output's view of a destination
+func routingInfo(d routing.Destination) output.RoutingInfo {
+	return output.RoutingInfo{Index: d.Index, Rule: d.Rule, Sourcetype: d.Sourcetype, Source: d.Source}
+}
+
+// normalizeRecord infers missing severity and tags the body type, ahead of
+// metrics, sampling, and drop rules so they all see the result
+func normalizeRecord(lr *logspb.LogRecord) (bodyType string, actions []transform.TransformAction) {
+	if action, ok := transform.NormalizeSeverity(lr, transformConfig.SeverityNormalization); ok {
+		actions = append(actions, action)
+	}
+	bodyType = transform.ClassifyBody(lr.GetBody())
+	if transformConfig.BodyTypeAttribute != "" {
+		transform.SetAttribute(lr, transformConfig.BodyTypeAttribute, bodyType)
+	}
+	return bodyType, actions
+}
+
+// transformRecord runs the record through its transform profile. OTTL
+// statements and resource rules act on a per-record copy of the shared
+// resource, which is returned in its place if they changed it.
+func transformRecord(resource *resourcepb.Resource, lr *logspb.LogRecord, actions []transform.TransformAction) (*resourcepb.Resource, *logspb.LogRecord, []transform.TransformAction) {
+	cfg, profile := transformConfig.ForRecord(resource, lr)
+	if profile != "" {
+		actions = append(actions, transform.TransformAction{Type: transform.ActionProfile, Rule: profile, Detail: "Applied profile: " + profile})
+	}
+	resource, statementActions := transform.ApplyStatements(resource, lr, cfg)
+	actions = append(actions, statementActions...)
+	resource, resourceActions := transform.ApplyToResource(resource, lr, cfg)
+	actions = append(actions, resourceActions...)
+	transformed, pipelineActions := transform.ApplyWithConfig(resource, lr, cfg)
+	return resource, transformed, append(actions, pipelineActions...)
 }
 
 // buildLogEntry creates a LogEntry from a transformed log record
-func buildLogEntry(resource *resourcepb.Resource, lr *logspb.LogRecord, index, ruleName string, actions []string) *output.LogEntry {
+func buildLogEntry(resource *resourcepb.Resource, scope *commonpb.InstrumentationScope, lr *logspb.LogRecord, dest routing.Destination, actions []transform.TransformAction) *output.LogEntry {
 	// Convert timestamp from nanoseconds to ISO8601
 	ts := time.Unix(0, int64(lr.GetTimeUnixNano())).UTC().Format(time.RFC3339Nano)
+	var observed string
+	if lr.GetObservedTimeUnixNano() != 0 {
+		observed = time.Unix(0, int64(lr.GetObservedTimeUnixNano())).UTC().Format(time.RFC3339Nano)
+	}
 
 	// Extract attributes
-	attrs := make(map[string]string)
+	attrs := make(map[string]any)
 	for _, attr := range lr.GetAttributes() {
-		attrs[attr.GetKey()] = formatValue(attr.GetValue())
+		attrs[attr.GetKey()] = entryValue(attr.GetValue())
+	}
+	// Each copy carries its own rule's sourcetype and source
+	if dest.Sourcetype != "" {
+		attrs["sourcetype"] = dest.Sourcetype
+	}
+	if dest.Source != "" {
+		attrs["source"] = dest.Source
 	}
 
 	// Extract resource attributes
-	resourceAttrs := make(map[string]string)
+	resourceAttrs := make(map[string]any)
 	if resource != nil {
 		for _, attr := range resource.GetAttributes() {
-			resourceAttrs[attr.GetKey()] = formatValue(attr.GetValue())
+			resourceAttrs[attr.GetKey()] = entryValue(attr.GetValue())
 		}
 	}
 
@@ -258,29 +635,72 @@ func buildLogEntry(resource *resourcepb.Resource, lr *logspb.LogRecord, index, r
 		body = formatValue(lr.GetBody())
 	}
 
+	var transforms []output.TransformInfo
+	for _, action := range actions {
+		transforms = append(transforms, output.TransformInfo{Type: string(action.Type), Rule: action.Rule, Detail: action.Detail})
+	}
+
 	return &output.LogEntry{
-		Timestamp:      ts,
-		Severity:       lr.GetSeverityText(),
-		SeverityNumber: int32(lr.GetSeverityNumber()),
-		Body:           body,
-		Attributes:     attrs,
-		ResourceAttrs:  resourceAttrs,
-		Routing:        output.RoutingInfo{Index: index, Rule: ruleName},
-		Transforms:     actions,
+		Timestamp:            ts,
+		ObservedTimestamp:    observed,
+		Severity:             lr.GetSeverityText(),
+		SeverityNumber:       int32(lr.GetSeverityNumber()),
+		Body:                 body,
+		TraceID:              hex.EncodeToString(lr.GetTraceId()),
+		SpanID:               hex.EncodeToString(lr.GetSpanId()),
+		Flags:                lr.GetFlags(),
+		Attributes:           attrs,
+		DroppedAttributes:    lr.GetDroppedAttributesCount(),
+		ResourceAttrs:        resourceAttrs,
+		ResourceDroppedAttrs: resource.GetDroppedAttributesCount(),
+		ScopeName:            scope.GetName(),
+		ScopeVersion:         scope.GetVersion(),
+		Routing:              routingInfo(dest),
+		Transforms:           transforms,
 	}
 }
 
-// getAppName extracts the application name from log attributes
-func getAppName(lr *logspb.LogRecord) string {
-	for _, attr := range lr.GetAttributes() {
-		key := attr.GetKey()
-		if key == "cf_app_name" || key == "application_name" {
-			return attr.GetValue().GetStringValue()
+// bodyBytes returns the length of a string or bytes body, or the encoded
+// size of any other body
+func bodyBytes(lr *logspb.LogRecord) int {
+	switch v := lr.GetBody().GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return len(v.StringValue)
+	case *commonpb.AnyValue_BytesValue:
+		return len(v.BytesValue)
+	case nil:
+		return 0
+	}
+	return proto.Size(lr.GetBody())
+}
+
+// getAppName extracts the application name from log attributes, falling back
+// to resource attributes where TAS usually puts it
+func getAppName(resource *resourcepb.Resource, lr *logspb.LogRecord) string {
+	return findAttribute(resource, lr, "cf_app_name", "application_name")
+}
+
+// findAttribute returns the first of keys found on the record, then on the resource
+func findAttribute(resource *resourcepb.Resource, lr *logspb.LogRecord, keys ...string) string {
+	for _, attrs := range [][]*commonpb.KeyValue{lr.GetAttributes(), resource.GetAttributes()} {
+		for _, attr := range attrs {
+			if slices.Contains(keys, attr.GetKey()) {
+				return attr.GetValue().GetStringValue()
+			}
 		}
 	}
 	return ""
 }
 
+// entryValue converts an attribute value for an output entry: flattened to
+// a string as on the console, or with its type kept under SetNativeTypes
+func entryValue(v *commonpb.AnyValue) any {
+	if nativeTypes {
+		return output.Value(v)
+	}
+	return formatValue(v)
+}
+
 func formatValue(v *commonpb.AnyValue) string {
 	if v == nil {
 		return "<nil>"
@@ -312,19 +732,38 @@ func StartGRPC(port int, verbose bool) (*grpc.Server, error) {
 		return nil, fmt.Errorf("failed to listen on port %d: %w", port, err)
 	}
 
-	server := grpc.NewServer()
+	server := grpc.NewServer(grpcServerOptions()...)
 	collogspb.RegisterLogsServiceServer(server, &LogsService{verbose: verbose})
 
 	go func() {
-		log.Printf("gRPC server listening on :%d", port)
+		slog.Info("gRPC server listening", "port", port)
 		if err := server.Serve(lis); err != nil {
-			log.Printf("gRPC server error: %v", err)
+			slog.Error("gRPC server error", "err", err)
 		}
 	}()
 
 	return server, nil
 }
 
+// grpcServerOptions instruments the LogsService and counts its
+// connections if metrics are enabled
+func grpcServerOptions() []grpc.ServerOption {
+	if metricsInstance == nil {
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(metricsInstance.UnaryServerInterceptor()),
+		grpc.StatsHandler(metricsInstance.StatsHandler()),
+	}
+}
+
+// trackConnections counts the server's open connections if metrics are enabled
+func trackConnections(server *http.Server) {
+	if metricsInstance != nil {
+		server.ConnState = metricsInstance.TrackConnState
+	}
+}
+
 // StartMultiplexed starts both gRPC and HTTP servers on the same port using cmux.
 // This is useful for Cloud Foundry deployments where only one port is available.
 func StartMultiplexed(port int, verbose bool) (*grpc.Server, *http.Server, error) {
@@ -335,47 +774,46 @@ func StartMultiplexed(port int, verbose bool) (*grpc.Server, *http.Server, error
 
 	// Create cmux multiplexer
 	m := cmux.New(lis)
+	m.SetReadTimeout(matchReadTimeout)
 
-	// Match gRPC (HTTP/2 with content-type application/grpc)
-	grpcL := m.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
+	// Match gRPC (HTTP/2 with content-type application/grpc or application/grpc+<codec>)
+	grpcL := m.MatchWithWriters(cmux.HTTP2MatchHeaderFieldPrefixSendSettings("content-type", grpcContentType))
+	// Match TLS handshakes, which a plaintext port can only reject
+	tlsL := m.Match(cmux.TLS())
 	// Match HTTP (everything else)
 	httpL := m.Match(cmux.Any())
 
 	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpcServerOptions()...)
 	collogspb.RegisterLogsServiceServer(grpcServer, &LogsService{verbose: verbose})
 
 	// Create HTTP server with h2c support for HTTP/2 cleartext
-	mux := http.NewServeMux()
-	handler := &httpHandler{verbose: verbose}
-	mux.HandleFunc("/v1/logs", handler.handleLogs)
-	mux.HandleFunc("/health", handleHealth)
-	if metricsInstance != nil {
-		mux.Handle("/metrics", promhttp.HandlerFor(metricsInstance.Registry(), promhttp.HandlerOpts{}))
-	}
-
+	mux := newHTTPMux(verbose)
 	h2s := &http2.Server{}
 	httpServer := &http.Server{
-		Handler: h2c.NewHandler(mux, h2s),
+		Handler: h2c.NewHandler(grpcMismatchHandler(mux), h2s),
 	}
+	trackConnections(httpServer)
 
 	// Start servers
+	go rejectTLS(tlsL)
+
 	go func() {
 		if err := grpcServer.Serve(grpcL); err != nil {
-			log.Printf("gRPC server error: %v", err)
+			slog.Error("gRPC server error", "err", err)
 		}
 	}()
 
 	go func() {
 		if err := httpServer.Serve(httpL); err != http.ErrServerClosed {
-			log.Printf("HTTP server error: %v", err)
+			slog.Error("HTTP server error", "err", err)
 		}
 	}()
 
 	go func() {
-		log.Printf("Multiplexed gRPC+HTTP server listening on :%d", port)
+		slog.Info("Multiplexed gRPC+HTTP server listening", "port", port)
 		if err := m.Serve(); err != nil {
-			log.Printf("cmux error: %v", err)
+			slog.Error("cmux error", "err", err)
 		}
 	}()
 
@@ -384,32 +822,78 @@ func StartMultiplexed(port int, verbose bool) (*grpc.Server, *http.Server, error
 
 // StartHTTP starts the HTTP server for OTLP/HTTP log ingestion
 func StartHTTP(port int, verbose bool) (*http.Server, error) {
-	mux := http.NewServeMux()
-
-	handler := &httpHandler{verbose: verbose}
-	mux.HandleFunc("/v1/logs", handler.handleLogs)
-	mux.HandleFunc("/health", handleHealth)
-
-	// Add Prometheus metrics endpoint if metrics are configured
-	if metricsInstance != nil {
-		mux.Handle("/metrics", promhttp.HandlerFor(metricsInstance.Registry(), promhttp.HandlerOpts{}))
-	}
-
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
-		Handler: mux,
+		Handler: newHTTPMux(verbose),
 	}
+	trackConnections(server)
 
 	go func() {
-		log.Printf("HTTP server listening on :%d", port)
+		slog.Info("HTTP server listening", "port", port)
 		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			log.Printf("HTTP server error: %v", err)
+			slog.Error("HTTP server error", "err", err)
 		}
 	}()
 
 	return server, nil
 }
 
+// newHTTPMux registers the OTLP/HTTP, health, debug, and metrics endpoints,
+// each instrumented when metrics are enabled
+func newHTTPMux(verbose bool) *http.ServeMux {
+	mux := http.NewServeMux()
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.Handle(pattern, instrumentHTTP(pattern, h))
+	}
+
+	handler := &httpHandler{verbose: verbose}
+	handle("/v1/logs", handler.handleLogs)
+	handle("/health", handleHealth)
+	handle("/debug/ack", handleLastAck)
+	handle("POST /debug/transform", handleExplainTransform)
+	handle("POST /debug/route", handleCheckRoutes)
+	handle("/api/stats", handleStats)
+	handle("/api/apps", handleApps)
+	handle("GET /api/apps/{name}/report", handleAppReport)
+	handle("/api/compare", handleCompare)
+	handle("/api/verify", handleVerify)
+	handle("/api/drop-reasons", handleDropReasons)
+	handle("GET /api/logs", handleQueryLogs)
+	handle("GET /api/logs/summary", handleLogsSummary)
+	handle("GET /stream", handleStream)
+	handle("GET /api/routing", handleRoutingConfig)
+	handle("POST /api/routing/rules", requireAdmin(handleAddRoutingRule))
+	handle("PUT /api/routing/rules/{name}", requireAdmin(handleUpdateRoutingRule))
+	handle("DELETE /api/routing/rules/{name}", requireAdmin(handleDeleteRoutingRule))
+	handle("PUT /api/routing/order", requireAdmin(handleReorderRouting))
+	handle("GET /admin/allowlist", handleAllowlistConfig)
+	handle("PUT /admin/allowlist", requireAdmin(handleReplaceAllowlist))
+	handle("POST /admin/allowlist", requireAdmin(handleAddAllowlist))
+	handle("DELETE /admin/allowlist", requireAdmin(handleRemoveAllowlist))
+	handle("GET /admin/allowlist/audit", handleAllowlistAudit)
+	handle("GET /admin/allowlist/stats", handleAllowlistStats)
+
+	// Add Prometheus metrics endpoint if metrics are configured
+	if metricsInstance != nil {
+		mux.Handle("/metrics", instrumentHTTP("/metrics", promhttp.HandlerFor(metricsInstance.Registry(), promhttp.HandlerOpts{})))
+	}
+
+	return mux
+}
+
+// instrumentHTTP counts, times, and sizes a route's requests if metrics are
+// enabled, labelled with the pattern's path
+func instrumentHTTP(pattern string, h http.Handler) http.Handler {
+	if metricsInstance == nil {
+		return h
+	}
+	path := pattern
+	if _, p, ok := strings.Cut(pattern, " "); ok {
+		path = p // Without the method
+	}
+	return metricsInstance.InstrumentHandler(path, h)
+}
+
 type httpHandler struct {
 	verbose bool
 }
@@ -420,6 +904,17 @@ func (h *httpHandler) handleLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	contentType := r.Header.Get("Content-Type")
+	if metricsInstance != nil {
+		metricsInstance.RequestsByContentType.WithLabelValues(contentTypeLabel(contentType)).Inc()
+	}
+	mediaType, ok := negotiateContentType(contentType)
+	if !ok {
+		slog.Warn("Rejected OTLP request with unsupported Content-Type", "content_type", contentType)
+		writeUnsupportedMediaType(w, contentType)
+		return
+	}
+
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, "Failed to read body", http.StatusBadRequest)
@@ -427,30 +922,33 @@ func (h *httpHandler) handleLogs(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	// Parse as protobuf
-	req := &collogspb.ExportLogsServiceRequest{}
-	if err := proto.Unmarshal(body, req); err != nil {
-		log.Printf("Failed to unmarshal OTLP request: %v", err)
-		http.Error(w, "Failed to parse OTLP", http.StatusBadRequest)
+	req, err := decodeRequest(body, mediaType)
+	if err != nil {
+		slog.Warn("Failed to unmarshal OTLP request", "err", err)
+		writeParseError(w, diagnoseParseError(body, contentType, mediaType, err))
 		return
 	}
 
-	// Process logs
-	for _, resourceLogs := range req.GetResourceLogs() {
-		resource := resourceLogs.GetResource()
-		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
-			scope := scopeLogs.GetScope()
-			for _, logRecord := range scopeLogs.GetLogRecords() {
-				stats.LogsReceived.Add(1)
-				if metricsInstance != nil {
-					metricsInstance.LogsReceived.Inc()
-				}
-				processLogRecord(resource, scope, logRecord, h.verbose)
-			}
-		}
-	}
+	ack := processRequest(req, h.verbose)
 
+	data, err := encodeResponse(ack.Response(), mediaType)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
 	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// handleLastAck returns the record-level acknowledgment of the most recent export request
+func handleLastAck(w http.ResponseWriter, r *http.Request) {
+	ack := lastAck.Load()
+	if ack == nil {
+		http.Error(w, "No export requests received yet", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, ack)
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,68 @@
+// ABOUTME: Tests for the core receive pipeline.
+// ABOUTME: Covers request and body size histograms around body truncation.
+
+package receiver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+
+	"otlp-mock-receiver/metrics"
+	"otlp-mock-receiver/transform"
+)
+
+func TestProcessRequest_SizeHistograms(t *testing.T) {
+	cfg := transform.DefaultConfig()
+	cfg.MaxBodyLength = 100
+	SetTransformConfig(cfg)
+	defer SetTransformConfig(transform.DefaultConfig())
+	m := metrics.New()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{
+			policyRecord("sized-app", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, strings.Repeat("x", 1000)),
+		}}},
+	}}}
+	size := proto.Size(req)
+	processRequest(req, false)
+
+	if got := histogramSum(t, m, "otlp_receiver_request_payload_bytes"); got != float64(size) {
+		t.Errorf("request_payload_bytes sum = %v, want %d", got, size)
+	}
+	// Bodies are sized as received, and again as delivered after truncation
+	if got := histogramSum(t, m, "otlp_receiver_log_body_bytes"); got != 1000 {
+		t.Errorf("log_body_bytes sum = %v, want 1000", got)
+	}
+	if got := histogramSum(t, m, "otlp_receiver_delivered_body_bytes"); got != float64(100+len("...[TRUNCATED]")) {
+		t.Errorf("delivered_body_bytes sum = %v, want the truncated body's size", got)
+	}
+	if got := testutil.CollectAndCount(m.DeliveredBodySize); got != 1 {
+		t.Errorf("delivered_body_bytes has %d series, want one for tas_logs", got)
+	}
+}
+
+// histogramSum returns the sum of every series of the named histogram
+func histogramSum(t *testing.T, m *metrics.Metrics, name string) float64 {
+	t.Helper()
+	families, err := m.Registry().Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	var sum float64
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			sum += metric.GetHistogram().GetSampleSum()
+		}
+	}
+	return sum
+}
@@ -0,0 +1,105 @@
+// ABOUTME: Dry-run routing of sample records, for checking a rule set before sending real traffic.
+// ABOUTME: Serves POST /debug/route and backs the route-test command; nothing is counted or written.
+
+package receiver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"otlp-mock-receiver/routing"
+)
+
+// RouteResult is where one sample record would be routed
+type RouteResult struct {
+	Name     string            `json:"name"` // Fixture name, or "record N" counting from 1
+	Routing  routing.Expected  `json:"routing"`
+	Expected *routing.Expected `json:"expected,omitempty"` // From a fixture
+	Mismatch bool              `json:"mismatch,omitempty"` // Routing differs from Expected
+}
+
+// RouteCheckResponse is the JSON body returned by /debug/route
+type RouteCheckResponse struct {
+	Results    []RouteResult `json:"results"`
+	Mismatches int           `json:"mismatches"`
+}
+
+// handleCheckRoutes routes the sample records in the request body and reports
+// where each would go
+func handleCheckRoutes(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	results, err := CheckRoutes(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := RouteCheckResponse{Results: results}
+	for _, res := range results {
+		if res.Mismatch {
+			resp.Mismatches++
+		}
+	}
+	writeJSON(w, resp)
+}
+
+// CheckRoutes routes each sample record in data without ingesting it. data
+// holds one or more JSON documents, such as JSONL: bare OTLP JSON log records,
+// OTLP JSON export requests, or gen-fixtures lines, whose expected routing is
+// compared with the actual. Records are transformed first, as on ingest, since
+// routing sees the transformed attributes.
+func CheckRoutes(data []byte) ([]RouteResult, error) {
+	var results []RouteResult
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for doc := 1; ; doc++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("document %d: %w", doc, err)
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("document %d: %w", doc, err)
+		}
+		if _, ok := fields["expected"]; ok {
+			var f routing.Fixture
+			if err := json.Unmarshal(raw, &f); err != nil {
+				return nil, fmt.Errorf("document %d: invalid fixture: %w", doc, err)
+			}
+			res := RouteResult{Name: f.Name, Routing: routeSample(resourceRecord{lr: f.LogRecord()}), Expected: &f.Expected}
+			res.Mismatch = !res.Routing.Equal(f.Expected)
+			results = append(results, res)
+			continue
+		}
+
+		records, err := decodeJSONRecords(raw)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: invalid OTLP JSON log record: %w", doc, err)
+		}
+		for _, rr := range records {
+			results = append(results, RouteResult{Name: fmt.Sprintf("record %d", len(results)+1), Routing: routeSample(rr)})
+		}
+	}
+	if len(results) == 0 {
+		return nil, errors.New("no records to route")
+	}
+	return results, nil
+}
+
+// routeSample transforms and routes a record the way processLogRecord would
+func routeSample(rr resourceRecord) routing.Expected {
+	_, actions := normalizeRecord(rr.lr)
+	resource, transformed, _ := transformRecord(rr.resource, rr.lr, actions)
+	return routing.NewExpected(currentRouter().DryRoute(resource, transformed))
+}
@@ -0,0 +1,108 @@
+// ABOUTME: Tests for dry-run routing of sample records.
+// ABOUTME: Covers each accepted document shape, fixture mismatches, and the /debug/route endpoint.
+
+package receiver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"otlp-mock-receiver/routing"
+)
+
+func TestCheckRoutes_Records(t *testing.T) {
+	SetRouter(routing.DefaultRouter())
+	defer SetRouter(routing.DefaultRouter())
+
+	// A bare record, then an export request with two records; application_name
+	// and space_name are renamed by the transforms before routing sees them
+	results, err := CheckRoutes([]byte(`{"severityNumber": 17}
+{"resourceLogs": [{
+  "resource": {"attributes": [{"key": "cf_org_name", "value": {"stringValue": "acme"}}]},
+  "scopeLogs": [{"logRecords": [
+    {"attributes": [{"key": "application_name", "value": {"stringValue": "security-scan"}}]},
+    {"attributes": [{"key": "space_name", "value": {"stringValue": "production"}}]}
+  ]}]
+}]}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"record 1 tas_errors", "record 2 tas_security", "record 3 tas_prod"}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, res := range results {
+		if got := res.Name + " " + res.Routing.Index; got != want[i] || res.Mismatch {
+			t.Errorf("result %d = %s (mismatch %v), want %s", i, got, res.Mismatch, want[i])
+		}
+	}
+}
+
+func TestCheckRoutes_Fixtures(t *testing.T) {
+	SetRouter(routing.DefaultRouter())
+	defer SetRouter(routing.DefaultRouter())
+
+	var fixtures strings.Builder
+	for _, f := range routing.DefaultRouter().GenerateFixtures() {
+		line, _ := json.Marshal(f)
+		fixtures.Write(append(line, '\n'))
+	}
+	results, err := CheckRoutes([]byte(fixtures.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		if res.Mismatch || res.Expected == nil {
+			t.Errorf("%s: routing %+v, expected %+v", res.Name, res.Routing, res.Expected)
+		}
+	}
+
+	// Without the security rule its match fixture falls through to the default
+	SetRouter(routing.NewRouter([]routing.RoutingRule{{Name: "audit-app", Conditions: map[string]string{"cf_app_name": "^audit-"}, Index: "tas_audit"}}))
+	results, _ = CheckRoutes([]byte(fixtures.String()))
+	for _, res := range results {
+		if res.Name == "security-app/match" && (!res.Mismatch || res.Routing.Rule != "default") {
+			t.Errorf("security-app/match = %+v, want a mismatch routed to the default", res)
+		}
+		if res.Name == "audit-app/match" && res.Mismatch {
+			t.Errorf("audit-app/match should still match, got %+v", res.Routing)
+		}
+	}
+}
+
+func TestCheckRoutes_Invalid(t *testing.T) {
+	for _, body := range []string{"", `{"severityNumber": 17} {"bad`, `{"severityNumber": "loud"}`, `[1]`} {
+		if _, err := CheckRoutes([]byte(body)); err == nil {
+			t.Errorf("CheckRoutes(%q) accepted", body)
+		}
+	}
+}
+
+func TestHandleCheckRoutes(t *testing.T) {
+	SetRouter(routing.NewRouter(nil))
+	defer SetRouter(routing.DefaultRouter())
+
+	rec := httptest.NewRecorder()
+	body := `{"name": "errors/match", "severity_number": 17, "attributes": {}, "expected": {"index": "tas_errors", "rule": "error-severity"}}`
+	newHTTPMux(false).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/route", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var resp RouteCheckResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Mismatches != 1 || resp.Results[0].Routing.Index != "tas_logs" {
+		t.Errorf("response = %+v, want one mismatch routed to tas_logs", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	newHTTPMux(false).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/route", strings.NewReader("nope")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid body status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,135 @@
+// ABOUTME: Admin API for listing and editing routing rules while the receiver runs.
+// ABOUTME: Serves /api/routing and /api/routing/rules, swapping in a rebuilt router after each edit.
+
+package receiver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"otlp-mock-receiver/routing"
+)
+
+// routingEditMu serializes edits so routers are swapped in the order they were built
+var routingEditMu sync.Mutex
+
+// RoutingOrder is the request body for PUT /api/routing/order
+type RoutingOrder struct {
+	Rules []string `json:"rules"` // Every rule name, in the new evaluation order
+}
+
+// currentEditor returns the routing editor, starting one from the current
+// router without persistence if none was configured
+func currentEditor() *routing.Editor {
+	routerMu.Lock()
+	defer routerMu.Unlock()
+	if routingEditor == nil {
+		routingEditor = routing.NewEditor(router, "")
+	}
+	return routingEditor
+}
+
+// handleRoutingConfig returns the routing rules in effect, as a config file would hold them
+func handleRoutingConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, currentEditor().Config())
+}
+
+// handleAddRoutingRule adds the rule in the request body
+func handleAddRoutingRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := readRoutingRule(w, r)
+	if !ok {
+		return
+	}
+	editRouting(w, http.StatusCreated, "added rule "+rule.Name, func(e *routing.Editor) (*routing.Router, error) {
+		return e.AddRule(rule)
+	})
+}
+
+// handleUpdateRoutingRule replaces the named rule with the one in the request
+// body, which keeps the name unless it sets another
+func handleUpdateRoutingRule(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	rule, ok := readRoutingRule(w, r)
+	if !ok {
+		return
+	}
+	if rule.Name == "" {
+		rule.Name = name
+	}
+	editRouting(w, http.StatusOK, "updated rule "+name, func(e *routing.Editor) (*routing.Router, error) {
+		return e.UpdateRule(name, rule)
+	})
+}
+
+// handleDeleteRoutingRule removes the named rule
+func handleDeleteRoutingRule(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	editRouting(w, http.StatusOK, "deleted rule "+name, func(e *routing.Editor) (*routing.Router, error) {
+		return e.DeleteRule(name)
+	})
+}
+
+// handleReorderRouting sets the rules' evaluation order
+func handleReorderRouting(w http.ResponseWriter, r *http.Request) {
+	var order RoutingOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		http.Error(w, "Invalid order: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	editRouting(w, http.StatusOK, "reordered rules", func(e *routing.Editor) (*routing.Router, error) {
+		return e.Reorder(order.Rules)
+	})
+}
+
+// readRoutingRule decodes a YAML or JSON rule from the request body, replying
+// with 400 if it cannot
+func readRoutingRule(w http.ResponseWriter, r *http.Request) (*routing.RuleFile, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return nil, false
+	}
+	rule, err := routing.ParseRule(body)
+	if err != nil {
+		http.Error(w, "Invalid rule: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return rule, true
+}
+
+// editRouting applies an edit, swaps in the resulting router, and replies
+// with the new config. Validation errors leave the rules in effect unchanged.
+func editRouting(w http.ResponseWriter, status int, change string, edit func(*routing.Editor) (*routing.Router, error)) {
+	routingEditMu.Lock()
+	defer routingEditMu.Unlock()
+
+	editor := currentEditor()
+	r, err := edit(editor)
+	switch {
+	case errors.Is(err, routing.ErrRuleNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, routing.ErrRuleExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, routing.ErrPersist):
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	routerMu.Lock()
+	router = r
+	routerMu.Unlock()
+	slog.Info("Routing: edited", "change", change)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, editor.Config())
+}
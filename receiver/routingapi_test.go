@@ -0,0 +1,131 @@
+// ABOUTME: Tests for the routing admin API.
+// ABOUTME: Covers listing, each edit taking effect on routing, and the status codes for rejected edits.
+
+package receiver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/routing"
+)
+
+func routingRequest(t *testing.T, method, path, body string) (int, routing.FileConfig) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	newHTTPMux(false).ServeHTTP(rec, req)
+
+	var fc routing.FileConfig
+	if rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &fc); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, fc
+}
+
+func routedIndex(app string) string {
+	lr := &logspb.LogRecord{Attributes: []*commonpb.KeyValue{stringKV("cf_app_name", app)}}
+	return routing.Primary(currentRouter().RouteRecord(nil, lr)).Index
+}
+
+func TestRoutingAPI_Edits(t *testing.T) {
+	SetRouter(routing.DefaultRouter())
+	defer SetRouter(routing.DefaultRouter())
+	enableAdmin(t)
+
+	code, fc := routingRequest(t, http.MethodGet, "/api/routing", "")
+	if code != http.StatusOK || len(fc.Rules) != 4 {
+		t.Fatalf("GET = %d with %d rules, want 200 with 4", code, len(fc.Rules))
+	}
+
+	code, fc = routingRequest(t, http.MethodPost, "/api/routing/rules", `{"name": "payments", "conditions": {"cf_app_name": "^payments-"}, "index": "tas_payments"}`)
+	if code != http.StatusCreated || len(fc.Rules) != 5 {
+		t.Fatalf("POST = %d with %d rules, want 201 with 5", code, len(fc.Rules))
+	}
+	if got := routedIndex("payments-api"); got != "tas_payments" {
+		t.Errorf("after add routed to %s, want tas_payments", got)
+	}
+
+	// The name comes from the path when the body leaves it out
+	code, _ = routingRequest(t, http.MethodPut, "/api/routing/rules/payments", "conditions: {cf_app_name: ^payments-}\nindex: tas_pay\n")
+	if code != http.StatusOK {
+		t.Fatalf("PUT = %d, want 200", code)
+	}
+	if got := routedIndex("payments-api"); got != "tas_pay" {
+		t.Errorf("after update routed to %s, want tas_pay", got)
+	}
+
+	code, fc = routingRequest(t, http.MethodPut, "/api/routing/order", `{"rules": ["payments", "error-severity", "security-app", "audit-app", "production-space"]}`)
+	if code != http.StatusOK || fc.Rules[0].Name != "payments" {
+		t.Fatalf("reorder = %d, first rule %+v", code, fc.Rules[0])
+	}
+
+	code, _ = routingRequest(t, http.MethodDelete, "/api/routing/rules/payments", "")
+	if code != http.StatusOK {
+		t.Fatalf("DELETE = %d, want 200", code)
+	}
+	if got := routedIndex("payments-api"); got != "tas_logs" {
+		t.Errorf("after delete routed to %s, want tas_logs", got)
+	}
+}
+
+func TestRoutingAPI_Rejected(t *testing.T) {
+	SetRouter(routing.DefaultRouter())
+	defer SetRouter(routing.DefaultRouter())
+	enableAdmin(t)
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/api/routing/rules", `{"name": "audit-app", "conditions": {"x": "y"}, "index": "i"}`, http.StatusConflict},
+		{http.MethodPut, "/api/routing/rules/nope", `{"conditions": {"x": "y"}, "index": "i"}`, http.StatusNotFound},
+		{http.MethodDelete, "/api/routing/rules/nope", "", http.StatusNotFound},
+		{http.MethodPost, "/api/routing/rules", `{"name": "bad", "conditions": {"cf_app_name": "("}, "index": "i"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/routing/rules", `{"name": "bad", "idx": "i"}`, http.StatusBadRequest},
+		{http.MethodPut, "/api/routing/order", `{"rules": ["audit-app"]}`, http.StatusBadRequest},
+		{http.MethodPut, "/api/routing/order", `not json`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code, _ := routingRequest(t, tt.method, tt.path, tt.body); code != tt.want {
+			t.Errorf("%s %s %s = %d, want %d", tt.method, tt.path, tt.body, code, tt.want)
+		}
+	}
+	if _, fc := routingRequest(t, http.MethodGet, "/api/routing", ""); len(fc.Rules) != 4 {
+		t.Errorf("rules after rejected edits = %d, want 4", len(fc.Rules))
+	}
+}
+
+func TestRoutingAPI_EditsRequireAdmin(t *testing.T) {
+	SetRouter(routing.DefaultRouter())
+	defer SetRouter(routing.DefaultRouter())
+
+	add := `{"name": "payments", "conditions": {"cf_app_name": "^payments-"}, "index": "tas_payments"}`
+	if code, _ := routingRequest(t, http.MethodPost, "/api/routing/rules", add); code != http.StatusNotFound {
+		t.Errorf("POST with the admin API off = %d, want 404", code)
+	}
+	if code, fc := routingRequest(t, http.MethodGet, "/api/routing", ""); code != http.StatusOK || len(fc.Rules) != 4 {
+		t.Errorf("GET with the admin API off = %d with %d rules, want 200 with 4", code, len(fc.Rules))
+	}
+
+	enableAdmin(t)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/routing/rules", strings.NewReader(add))
+	req.Header.Set("Authorization", "Bearer wrong")
+	newHTTPMux(false).ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST with a wrong token = %d, want 401", rec.Code)
+	}
+	if got := routedIndex("payments-api"); got != "tas_logs" {
+		t.Errorf("after rejected edits routed to %s, want tas_logs", got)
+	}
+}
@@ -0,0 +1,92 @@
+// ABOUTME: Wires hash sampling and the per-app records-per-second budget into the receive pipeline.
+// ABOUTME: Kept records are stamped with the rate they stand for; kept and dropped counts are exported per app.
+
+package receiver
+
+import (
+	"math"
+	"strconv"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/sampling"
+	"otlp-mock-receiver/transform"
+)
+
+// Attributes stamped on records that pass sampling
+const (
+	samplingRateAttribute     = "sampling.rate"
+	samplingDecisionAttribute = "sampling.decision"
+)
+
+// Values of the sampling.decision attribute
+const (
+	decisionSampled = "sampled" // Subject to sampling and kept, standing for sampling.rate records
+	decisionExempt  = "exempt"  // At or above the keep threshold, never sampled
+)
+
+var rateSampler *sampling.Budget
+
+// SetSamplingConfig configures sampling for the receiver
+func SetSamplingConfig(cfg *transform.SamplingConfig) {
+	samplingConfig = cfg
+	rateSampler = nil
+	if cfg != nil && cfg.MaxPerSecond > 0 {
+		rateSampler = sampling.NewBudget(cfg.MaxPerSecond)
+	}
+}
+
+// sample reports whether the record survives sampling under cfg, counting the
+// decision and stamping kept records with their sampling attributes
+func sample(cfg *transform.SamplingConfig, appName string, lr *logspb.LogRecord) bool {
+	if cfg == nil {
+		return true
+	}
+	if !cfg.AlwaysKeep(lr) {
+		keep := transform.ShouldSample(lr, cfg) && (cfg.MaxPerSecond == 0 || withinBudget(appName))
+		if metricsInstance != nil {
+			if keep {
+				metricsInstance.LogsSampledKept.WithLabelValues(appName).Inc()
+			} else {
+				metricsInstance.LogsSampledDropped.WithLabelValues(appName).Inc()
+			}
+		}
+		if !keep {
+			return false
+		}
+	}
+	stampSampling(cfg, appName, lr)
+	return true
+}
+
+// withinBudget reports whether a record fits its app's per-second budget,
+// updating the app's effective sampling ratio
+func withinBudget(appName string) bool {
+	if rateSampler == nil {
+		return true
+	}
+	keep := rateSampler.Allow(appName)
+	if metricsInstance != nil {
+		metricsInstance.SamplingRatio.WithLabelValues(appName).Set(rateSampler.Ratio(appName))
+	}
+	return keep
+}
+
+// stampSampling sets sampling.rate and sampling.decision on a kept record.
+// Under the per-second budget the rate is the inverse of the app's current ratio.
+func stampSampling(cfg *transform.SamplingConfig, appName string, lr *logspb.LogRecord) {
+	if cfg.AlwaysKeep(lr) {
+		transform.SetAttribute(lr, samplingRateAttribute, "1")
+		transform.SetAttribute(lr, samplingDecisionAttribute, decisionExempt)
+		return
+	}
+
+	rate := strconv.Itoa(cfg.Rate(lr))
+	if rateSampler != nil && cfg.MaxPerSecond > 0 {
+		if ratio := rateSampler.Ratio(appName); ratio > 0 {
+			rate = strconv.FormatFloat(math.Round(100/ratio)/100, 'f', -1, 64)
+		}
+	}
+	transform.SetAttribute(lr, samplingRateAttribute, rate)
+	transform.SetAttribute(lr, samplingDecisionAttribute, decisionSampled)
+}
@@ -0,0 +1,115 @@
+// ABOUTME: Tests for the per-app sampling budget in the receive pipeline.
+// ABOUTME: Covers over-budget rejection, severity exemptions, decision attributes, and sampling metrics.
+
+package receiver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-mock-receiver/metrics"
+	"otlp-mock-receiver/transform"
+)
+
+func TestProcessRequest_SamplingBudget(t *testing.T) {
+	SetSamplingConfig(&transform.SamplingConfig{MaxPerSecond: 2})
+	defer SetSamplingConfig(nil)
+	m := metrics.New()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	var records []*logspb.LogRecord
+	for _, sev := range []logspb.SeverityNumber{
+		logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+		logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	} {
+		records = append(records, &logspb.LogRecord{
+			SeverityNumber: sev,
+			Attributes:     []*commonpb.KeyValue{stringKV("cf_app_name", "budget-test")},
+			Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "tick"}},
+		})
+	}
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}},
+	}}}
+
+	ack := processRequest(req, false)
+	if ack.Bitmap != "11010" {
+		t.Errorf("Bitmap = %q, want 11010 (ERROR is never sampled)", ack.Bitmap)
+	}
+	if ack.Rejected[0].Reason != "sampled" {
+		t.Errorf("Reason = %q, want sampled", ack.Rejected[0].Reason)
+	}
+	if got := testutil.ToFloat64(m.SamplingRatio.WithLabelValues("budget-test")); got != 0.5 {
+		t.Errorf("sampling_ratio = %v, want 0.5", got)
+	}
+	kept := testutil.ToFloat64(m.LogsSampledKept.WithLabelValues("budget-test"))
+	dropped := testutil.ToFloat64(m.LogsSampledDropped.WithLabelValues("budget-test"))
+	if kept != 2 || dropped != 2 {
+		t.Errorf("sampled kept/dropped = %v/%v, want 2/2 (ERROR is not counted)", kept, dropped)
+	}
+}
+
+func attrString(lr *logspb.LogRecord, key string) string {
+	for _, kv := range lr.GetAttributes() {
+		if kv.GetKey() == key {
+			return kv.GetValue().GetStringValue()
+		}
+	}
+	return ""
+}
+
+func TestSample_StampsKeptRecords(t *testing.T) {
+	SetSamplingConfig(&transform.SamplingConfig{SampleRate: 4, SampleDebugOnly: true})
+	defer SetSamplingConfig(nil)
+	m := metrics.New()
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	kept := 0
+	for i := range 100 {
+		lr := &logspb.LogRecord{
+			SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+			Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprintf("debug %d", i)}},
+		}
+		if !sample(samplingConfig, "stamp-test", lr) {
+			if attrString(lr, "sampling.decision") != "" {
+				t.Fatal("dropped records should not be stamped")
+			}
+			continue
+		}
+		kept++
+		if rate, decision := attrString(lr, "sampling.rate"), attrString(lr, "sampling.decision"); rate != "4" || decision != "sampled" {
+			t.Errorf("kept DEBUG stamped %s/%s, want 4/sampled", rate, decision)
+		}
+	}
+	if got := testutil.ToFloat64(m.LogsSampledKept.WithLabelValues("stamp-test")); got != float64(kept) {
+		t.Errorf("sampled_kept = %v, want %d", got, kept)
+	}
+	if got := testutil.ToFloat64(m.LogsSampledDropped.WithLabelValues("stamp-test")); got != float64(100-kept) {
+		t.Errorf("sampled_dropped = %v, want %d", got, 100-kept)
+	}
+
+	info := &logspb.LogRecord{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO}
+	if !sample(samplingConfig, "stamp-test", info) {
+		t.Fatal("INFO is always kept with SampleDebugOnly")
+	}
+	if rate, decision := attrString(info, "sampling.rate"), attrString(info, "sampling.decision"); rate != "1" || decision != "exempt" {
+		t.Errorf("INFO stamped %s/%s, want 1/exempt", rate, decision)
+	}
+}
+
+func TestSample_DisabledLeavesRecordAlone(t *testing.T) {
+	lr := &logspb.LogRecord{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG}
+	if !sample(samplingConfig, "any", lr) || len(lr.GetAttributes()) != 0 {
+		t.Errorf("without sampling config: attributes = %v, want none", lr.GetAttributes())
+	}
+}
@@ -0,0 +1,82 @@
+// ABOUTME: Schema checks for incoming log records.
+// ABOUTME: Flags missing TAS metadata and empty fields for onboarding reports.
+
+package receiver
+
+import (
+	"unicode/utf8"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Schema violation names reported per app
+const (
+	violationMissingAppName   = "missing_app_name"
+	violationMissingOrgName   = "missing_org_name"
+	violationMissingSpaceName = "missing_space_name"
+	violationMissingTimestamp = "missing_timestamp"
+	violationMissingSeverity  = "missing_severity"
+	violationEmptyBody        = "empty_body"
+	violationInvalidUTF8Body  = "invalid_utf8_body"
+)
+
+// checkSchema returns the schema violations for a record as received
+func checkSchema(resource *resourcepb.Resource, lr *logspb.LogRecord) []string {
+	var violations []string
+	if getAppName(resource, lr) == "" {
+		violations = append(violations, violationMissingAppName)
+	}
+	if !hasAnyAttribute(resource, lr, "cf_org_name", "organization_name") {
+		violations = append(violations, violationMissingOrgName)
+	}
+	if !hasAnyAttribute(resource, lr, "cf_space_name", "space_name") {
+		violations = append(violations, violationMissingSpaceName)
+	}
+	if lr.GetTimeUnixNano() == 0 && lr.GetObservedTimeUnixNano() == 0 {
+		violations = append(violations, violationMissingTimestamp)
+	}
+	if lr.GetSeverityNumber() == logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED {
+		violations = append(violations, violationMissingSeverity)
+	}
+	if body := lr.GetBody(); body.GetValue() == nil || (isStringBody(body) && body.GetStringValue() == "") {
+		violations = append(violations, violationEmptyBody)
+	}
+	if !validUTF8Body(lr.GetBody()) {
+		violations = append(violations, violationInvalidUTF8Body)
+	}
+	return violations
+}
+
+// validUTF8Body reports whether a string or bytes body is valid UTF-8. Protobuf
+// decoding rejects invalid UTF-8 strings, so over the wire only bytes bodies fail.
+func validUTF8Body(v *commonpb.AnyValue) bool {
+	switch body := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return utf8.ValidString(body.StringValue)
+	case *commonpb.AnyValue_BytesValue:
+		return utf8.Valid(body.BytesValue)
+	}
+	return true
+}
+
+// isStringBody reports whether the body holds a string value
+func isStringBody(v *commonpb.AnyValue) bool {
+	_, ok := v.GetValue().(*commonpb.AnyValue_StringValue)
+	return ok
+}
+
+// hasAnyAttribute reports whether any of the keys is set on the record or its resource
+func hasAnyAttribute(resource *resourcepb.Resource, lr *logspb.LogRecord, keys ...string) bool {
+	for _, attrs := range [][]*commonpb.KeyValue{lr.GetAttributes(), resource.GetAttributes()} {
+		for _, attr := range attrs {
+			for _, key := range keys {
+				if attr.GetKey() == key && attr.GetValue().GetStringValue() != "" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+// ABOUTME: Tests for record schema checks.
+// ABOUTME: Covers missing TAS metadata, timestamps, severity, and bodies.
+
+package receiver
+
+import (
+	"slices"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func strAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func TestCheckSchema_CompleteRecordHasNoViolations(t *testing.T) {
+	resource := &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		strAttr("application_name", "payment-service"),
+		strAttr("organization_name", "acme"),
+		strAttr("space_name", "production"),
+	}}
+	lr := &logspb.LogRecord{
+		TimeUnixNano:   1,
+		SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "ok"}},
+	}
+
+	if v := checkSchema(resource, lr); len(v) != 0 {
+		t.Errorf("expected no violations, got %v", v)
+	}
+}
+
+func TestCheckSchema_EmptyRecordReportsEverything(t *testing.T) {
+	v := checkSchema(nil, &logspb.LogRecord{})
+
+	want := []string{
+		violationMissingAppName,
+		violationMissingOrgName,
+		violationMissingSpaceName,
+		violationMissingTimestamp,
+		violationMissingSeverity,
+		violationEmptyBody,
+	}
+	for _, w := range want {
+		if !slices.Contains(v, w) {
+			t.Errorf("missing violation %q in %v", w, v)
+		}
+	}
+}
+
+func TestCheckSchema_NonStringBodyIsNotEmpty(t *testing.T) {
+	lr := &logspb.LogRecord{Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 0}}}
+
+	if slices.Contains(checkSchema(nil, lr), violationEmptyBody) {
+		t.Error("int body should not be reported as empty")
+	}
+}
+
+func TestCheckSchema_InvalidUTF8Body(t *testing.T) {
+	for _, body := range []*commonpb.AnyValue{
+		{Value: &commonpb.AnyValue_StringValue{StringValue: "bad \xff byte"}},
+		{Value: &commonpb.AnyValue_BytesValue{BytesValue: []byte{0xc3, 0x28}}},
+	} {
+		if !slices.Contains(checkSchema(nil, &logspb.LogRecord{Body: body}), violationInvalidUTF8Body) {
+			t.Errorf("body %v should be reported as invalid UTF-8", body)
+		}
+	}
+	lr := &logspb.LogRecord{Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: []byte("héllo")}}}
+	if slices.Contains(checkSchema(nil, lr), violationInvalidUTF8Body) {
+		t.Error("valid UTF-8 bytes should not be reported")
+	}
+}
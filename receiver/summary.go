@@ -0,0 +1,118 @@
+// ABOUTME: Periodic one-line throughput summary in the receiver's log, enabled with -summary-interval.
+// ABOUTME: Reports received and transformed rates, drops by reason, and output queue backlog since the previous summary.
+
+package receiver
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"otlp-mock-receiver/drop"
+	"otlp-mock-receiver/output"
+)
+
+// summarizer remembers the counters at the previous summary, so each summary
+// covers only its own interval
+type summarizer struct {
+	at          time.Time
+	received    int64
+	transformed int64
+	dropped     map[drop.Reason]int64
+	active      bool // The previous interval had records or a backlog
+}
+
+func newSummarizer(now time.Time) *summarizer {
+	return &summarizer{
+		at:          now,
+		received:    stats.LogsReceived.Load(),
+		transformed: stats.LogsTransformed.Load(),
+		dropped:     dropSnapshot(),
+	}
+}
+
+// RunSummary logs a throughput summary every interval until stop is closed
+func RunSummary(interval time.Duration, stop <-chan struct{}) {
+	s := newSummarizer(time.Now())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if attrs, ok := s.summarize(now); ok {
+				slog.LogAttrs(context.Background(), slog.LevelInfo, "Summary", attrs...)
+			}
+		}
+	}
+}
+
+// summarize describes the interval since the previous call as log
+// attributes. Idle intervals are skipped, except the first, which shows the
+// load falling to zero.
+func (s *summarizer) summarize(now time.Time) ([]slog.Attr, bool) {
+	received := stats.LogsReceived.Load()
+	transformed := stats.LogsTransformed.Load()
+	dropped := dropSnapshot()
+	seconds := now.Sub(s.at).Seconds()
+
+	attrs := []slog.Attr{
+		slog.Float64("received_per_sec", perSecond(received-s.received, seconds)),
+		slog.Float64("transformed_per_sec", perSecond(transformed-s.transformed, seconds)),
+	}
+
+	var total int64
+	var reasons []slog.Attr
+	for _, info := range drop.Taxonomy() {
+		if n := dropped[info.Reason] - s.dropped[info.Reason]; n > 0 {
+			total += n
+			reasons = append(reasons, slog.Int64(string(info.Reason), n))
+		}
+	}
+	attrs = append(attrs, slog.Int64("dropped", total))
+	if len(reasons) > 0 {
+		attrs = append(attrs, slog.Attr{Key: "dropped_by", Value: slog.GroupValue(reasons...)})
+	}
+
+	queued, lag, queues := outputBacklog()
+	if queues {
+		attrs = append(attrs, slog.Int("queued", queued), slog.Duration("lag", lag.Round(time.Millisecond)))
+	}
+
+	active := received != s.received || transformed != s.transformed || total > 0 || queued > 0
+	report := active || s.active
+	s.at, s.received, s.transformed, s.dropped, s.active = now, received, transformed, dropped, active
+	return attrs, report
+}
+
+// perSecond returns a rate rounded to one decimal place
+func perSecond(n int64, seconds float64) float64 {
+	return math.Round(float64(n)/seconds*10) / 10
+}
+
+// dropSnapshot returns the drop counts so far, by reason
+func dropSnapshot() map[drop.Reason]int64 {
+	counts := make(map[drop.Reason]int64, len(dropCounts))
+	for reason, n := range dropCounts {
+		counts[reason] = n.Load()
+	}
+	return counts
+}
+
+// outputBacklog returns the records waiting in the output queues and the
+// longest any of them has waited. ok is false if no output has a queue.
+func outputBacklog() (records int, lag time.Duration, ok bool) {
+	for _, s := range sinks {
+		q, isQueue := s.(*output.Queue)
+		if !isQueue {
+			continue
+		}
+		ok = true
+		n, l := q.Backlog()
+		records += n
+		lag = max(lag, l)
+	}
+	return records, lag, ok
+}
@@ -0,0 +1,65 @@
+// ABOUTME: Tests for the periodic throughput summary.
+// ABOUTME: Covers per-interval rates, drops by reason, output queue backlog, and skipping idle intervals.
+
+package receiver
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"otlp-mock-receiver/drop"
+	"otlp-mock-receiver/output"
+)
+
+func TestSummarize_Interval(t *testing.T) {
+	start := time.Now()
+	s := newSummarizer(start)
+	stats.LogsReceived.Add(20)
+	stats.LogsTransformed.Add(18)
+	dropRecord("summary-app", drop.Sampled, "")
+	dropRecord("summary-app", drop.Rule, "health-checks")
+
+	attrs, ok := s.summarize(start.Add(10 * time.Second))
+	want := "received_per_sec=2 transformed_per_sec=1.8 dropped=2 dropped_by=[sampled=1 rule=1]"
+	if got := summaryString(attrs); !ok || got != want {
+		t.Errorf("summarize() = %q, %v; want %q", got, ok, want)
+	}
+
+	// The next interval has nothing new, and is reported once to show it
+	attrs, ok = s.summarize(start.Add(20 * time.Second))
+	want = "received_per_sec=0 transformed_per_sec=0 dropped=0"
+	if got := summaryString(attrs); !ok || got != want {
+		t.Errorf("summarize() = %q, %v; want %q", got, ok, want)
+	}
+	if _, ok := s.summarize(start.Add(30 * time.Second)); ok {
+		t.Error("second idle interval should be skipped")
+	}
+}
+
+func TestSummarize_QueueBacklog(t *testing.T) {
+	saved := sinks
+	defer func() { sinks = saved }()
+	q, err := output.NewQueue("test", output.NewLiveTail(10), 10, output.QueueBlock)
+	if err != nil {
+		t.Fatalf("NewQueue failed: %v", err)
+	}
+	defer q.Close()
+	sinks = []output.Sink{q}
+
+	attrs, _ := newSummarizer(time.Now().Add(-time.Second)).summarize(time.Now())
+	want := "received_per_sec=0 transformed_per_sec=0 dropped=0 queued=0 lag=0s"
+	if got := summaryString(attrs); got != want {
+		t.Errorf("summarize() = %q, want %q", got, want)
+	}
+}
+
+// summaryString renders attributes as space-separated key=value pairs
+func summaryString(attrs []slog.Attr) string {
+	parts := make([]string, len(attrs))
+	for i, a := range attrs {
+		parts[i] = a.String()
+	}
+	return strings.Join(parts, " ")
+}
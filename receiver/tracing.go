@@ -0,0 +1,47 @@
+// ABOUTME: Spans for the receiver's own processing, exported with -self-telemetry-endpoint.
+// ABOUTME: Each export request gets a span, with transform, route, and write spans for every record it delivers.
+
+package receiver
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of the receiver's spans
+const tracerName = "otlp-mock-receiver/receiver"
+
+// tracer starts the processing spans; a no-op until SetTracerProvider
+var tracer trace.Tracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// SetTracerProvider starts processing spans from tp, or stops them if tp is nil
+func SetTracerProvider(tp trace.TracerProvider) {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	tracer = tp.Tracer(tracerName)
+}
+
+// startExportSpan starts the span covering one export request
+func startExportSpan() (context.Context, trace.Span) {
+	return tracer.Start(context.Background(), "Export", trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// endExportSpan records how the request was acknowledged and ends its span
+func endExportSpan(span trace.Span, ack *AckReport) {
+	span.SetAttributes(
+		attribute.Int("log_records", ack.Total),
+		attribute.Int("log_records.accepted", ack.Accepted),
+		attribute.Int("log_records.rejected", len(ack.Rejected)),
+	)
+	span.End()
+}
+
+// startStageSpan starts the span for one pipeline stage of an app's record
+func startStageSpan(ctx context.Context, stage, app string) trace.Span {
+	_, span := tracer.Start(ctx, stage, trace.WithAttributes(attribute.String("app", app)))
+	return span
+}
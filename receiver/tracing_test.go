@@ -0,0 +1,57 @@
+// ABOUTME: Tests for the receiver's processing spans.
+// ABOUTME: Checks an export's span parents a transform, route, and write span for each delivered record.
+
+package receiver
+
+import (
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestProcessRequest_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer SetTracerProvider(nil)
+
+	processRequest(&collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{
+			policyRecord("traced-app", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "first"),
+			policyRecord("traced-app", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "second"),
+		}}},
+	}}}, false)
+
+	spans := recorder.Ended()
+	var export sdktrace.ReadOnlySpan
+	stages := make(map[string]int)
+	for _, span := range spans {
+		if span.Name() == "Export" {
+			export = span
+		}
+	}
+	if export == nil {
+		t.Fatalf("no Export span among %d spans", len(spans))
+	}
+	for _, span := range spans {
+		if span.Name() == "Export" {
+			continue
+		}
+		if span.Parent().SpanID() != export.SpanContext().SpanID() {
+			t.Errorf("%s span is not a child of the Export span", span.Name())
+		}
+		stages[span.Name()]++
+	}
+	for _, stage := range []string{"transform", "route", "write"} {
+		if stages[stage] != 2 {
+			t.Errorf("%d %s spans, want one per record", stages[stage], stage)
+		}
+	}
+	for _, attr := range export.Attributes() {
+		if attr.Key == "log_records.accepted" && attr.Value.AsInt64() != 2 {
+			t.Errorf("log_records.accepted = %d, want 2", attr.Value.AsInt64())
+		}
+	}
+}
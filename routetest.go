@@ -0,0 +1,85 @@
+// ABOUTME: route-test command that reports where sample records would be routed.
+// ABOUTME: Checks a routing config against records or gen-fixtures output before sending real traffic.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"otlp-mock-receiver/receiver"
+	"otlp-mock-receiver/routing"
+	"otlp-mock-receiver/transform"
+)
+
+// routeTestFlags registers the route-test command
+func routeTestFlags(fs *flag.FlagSet) func(args []string) error {
+	routingConfigFile := fs.String("routing-config", "", "Path to YAML or JSON routing rules file (default: the built-in rules)")
+	transformConfigFile := fs.String("transform-config", "", "Path to YAML transform config file applied before routing")
+
+	return func(args []string) error {
+		if *routingConfigFile != "" {
+			r, err := routing.LoadConfig(*routingConfigFile)
+			if err != nil {
+				return err
+			}
+			receiver.SetRouter(r)
+		}
+		if *transformConfigFile != "" {
+			cfg, err := transform.LoadConfig(*transformConfigFile)
+			if err != nil {
+				return err
+			}
+			receiver.SetTransformConfig(cfg)
+		}
+
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+		mismatches := 0
+		for _, path := range args {
+			data, err := readInput(path)
+			if err != nil {
+				return err
+			}
+			results, err := receiver.CheckRoutes(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			for _, res := range results {
+				line := fmt.Sprintf("%-40s %s", res.Name, describeRouting(res.Routing))
+				if res.Mismatch {
+					mismatches++
+					line += "  MISMATCH, expected " + describeRouting(*res.Expected)
+				}
+				fmt.Println(line)
+			}
+		}
+		if mismatches > 0 {
+			return fmt.Errorf("%d fixture(s) routed differently than expected", mismatches)
+		}
+		return nil
+	}
+}
+
+// readInput reads a file, or stdin for "-"
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// describeRouting formats a routing decision, e.g.
+// "tas_errors (rule: error-severity) + copy to tas_archive (rule: archive)"
+func describeRouting(e routing.Expected) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (rule: %s)", e.Index, e.Rule)
+	for _, c := range e.Copies {
+		fmt.Fprintf(&b, " + copy to %s (rule: %s)", c.Index, c.Rule)
+	}
+	return b.String()
+}
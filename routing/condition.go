@@ -0,0 +1,180 @@
+// ABOUTME: Compiled routing conditions and the boolean logic combining them.
+// ABOUTME: A rule matches when all its conditions hold, none of its negated ones do, and any OR group matches.
+
+package routing
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/cel"
+)
+
+// condition is one compiled attribute regex, numeric comparison, or severity test
+type condition struct {
+	Attr     string
+	Pattern  *regexp.Regexp    // nil for _severity and numeric comparisons
+	Numeric  *numericCondition // nil unless the condition compares numbers
+	Severity severityRange
+}
+
+// matches reports whether the record satisfies the condition. A missing
+// attribute never matches, nor does a non-numeric one in a numeric comparison.
+func (c condition) matches(lr *logspb.LogRecord) bool {
+	if c.Numeric != nil {
+		v, ok := getNumericValue(lr, c.Attr)
+		return ok && c.Numeric.holds(v)
+	}
+	if c.Pattern == nil {
+		return c.Severity.Contains(lr.GetSeverityNumber())
+	}
+	value := getAttributeValue(lr, c.Attr)
+	return value != "" && c.Pattern.MatchString(value)
+}
+
+// compileConditions compiles attribute → pattern conditions in attribute order
+func compileConditions(conds map[string]string) ([]condition, error) {
+	attrs := make([]string, 0, len(conds))
+	for attr := range conds {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+
+	compiled := make([]condition, 0, len(conds))
+	for _, attr := range attrs {
+		c := condition{Attr: attr}
+		if attr == "_severity" {
+			sr, err := parseSeverityCondition(conds[attr])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", attr, err)
+			}
+			c.Severity = sr
+		} else if nc, ok := parseNumericCondition(conds[attr]); ok {
+			c.Numeric = &nc
+		} else {
+			re, err := regexp.Compile(conds[attr])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", attr, err)
+			}
+			c.Pattern = re
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// matchesAll reports whether every condition holds
+func matchesAll(lr *logspb.LogRecord, conds []condition) bool {
+	for _, c := range conds {
+		if !c.matches(lr) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesNone reports whether no condition holds
+func matchesNone(lr *logspb.LogRecord, conds []condition) bool {
+	for _, c := range conds {
+		if c.matches(lr) {
+			return false
+		}
+	}
+	return true
+}
+
+// compiledRule is a routing rule with pre-compiled conditions
+type compiledRule struct {
+	Name       string
+	All        []condition   // Conditions: every one must hold
+	Not        []condition   // None may hold
+	Any        [][]condition // If set, at least one group must hold entirely
+	Index      string
+	Priority   int
+	Continue   bool
+	Sourcetype string
+	Source     string
+	Split      *Split
+	Limit      *limiter        // nil means no rate limit
+	Window     *compiledWindow // nil means always active
+	Expr       *cel.Program    // nil means no expression
+}
+
+// destination is where the rule sends a matching record: its overflow index
+// if limiting and the rule is over its rate limit, its split index if the
+// record falls in the split's share, otherwise its own
+func (cr *compiledRule) destination(lr *logspb.LogRecord, limit bool) Destination {
+	d := Destination{Index: cr.Index, Rule: cr.Name, Sourcetype: cr.Sourcetype, Source: cr.Source}
+	switch {
+	case limit && cr.Limit != nil && !cr.Limit.allow():
+		d.Index, d.Overflow = cr.Limit.overflow, true
+	case cr.Split != nil && cr.Split.Takes(lr):
+		d.Index = cr.Split.Index
+	}
+	return d
+}
+
+// compileRule compiles a rule's conditions, naming the offending one on error
+func compileRule(rule RoutingRule) (compiledRule, error) {
+	cr := compiledRule{Name: rule.Name, Index: rule.Index, Priority: rule.Priority, Continue: rule.Continue, Sourcetype: rule.Sourcetype, Source: rule.Source, Split: rule.Split}
+	if rule.RateLimit != nil {
+		cr.Limit = newLimiter(rule.RateLimit)
+	}
+	var err error
+	if cr.All, err = compileConditions(rule.Conditions); err != nil {
+		return cr, fmt.Errorf("conditions.%w", err)
+	}
+	if cr.Not, err = compileConditions(rule.Not); err != nil {
+		return cr, fmt.Errorf("not.%w", err)
+	}
+	for i, group := range rule.Any {
+		if len(group) == 0 {
+			return cr, fmt.Errorf("any[%d]: group has no conditions", i)
+		}
+		conds, err := compileConditions(group)
+		if err != nil {
+			return cr, fmt.Errorf("any[%d].%w", i, err)
+		}
+		cr.Any = append(cr.Any, conds)
+	}
+	if rule.Window != nil {
+		if cr.Window, err = compileWindow(rule.Window); err != nil {
+			return cr, fmt.Errorf("window.%w", err)
+		}
+	}
+	if rule.Expr != "" {
+		if cr.Expr, err = cel.Compile(rule.Expr); err != nil {
+			return cr, fmt.Errorf("expr: %w", err)
+		}
+	}
+	return cr, nil
+}
+
+// matches reports whether the record satisfies the rule. An expression that
+// fails on the record, e.g. reading a missing key, does not match.
+func (cr compiledRule) matches(resource *resourcepb.Resource, lr *logspb.LogRecord) bool {
+	if !matchesAll(lr, cr.All) || !matchesNone(lr, cr.Not) {
+		return false
+	}
+	if cr.Window != nil && !cr.Window.contains(recordTime(lr)) {
+		return false
+	}
+	if cr.Expr != nil {
+		if ok, err := cr.Expr.Eval(cel.NewActivation(resource, lr)); err != nil || !ok {
+			return false
+		}
+	}
+	if len(cr.Any) == 0 {
+		return true
+	}
+	for _, group := range cr.Any {
+		if matchesAll(lr, group) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+// ABOUTME: Tests for routing condition logic.
+// ABOUTME: Covers negated conditions, OR groups, their combination with plain conditions, and compile errors.
+
+package routing
+
+import (
+	"strings"
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestRouter_NegatedCondition(t *testing.T) {
+	router := NewRouter([]RoutingRule{{
+		Name:       "non-prod-payments",
+		Conditions: map[string]string{"cf_app_name": "^payments"},
+		Not:        map[string]string{"cf_space_name": "^production$"},
+		Index:      "tas_nonprod",
+	}})
+
+	for _, tc := range []struct {
+		attrs map[string]string
+		want  string
+	}{
+		{map[string]string{"cf_app_name": "payments-api", "cf_space_name": "staging"}, "tas_nonprod"},
+		{map[string]string{"cf_app_name": "payments-api"}, "tas_nonprod"}, // Absent attribute is not production
+		{map[string]string{"cf_app_name": "payments-api", "cf_space_name": "production"}, "tas_logs"},
+		{map[string]string{"cf_app_name": "orders", "cf_space_name": "staging"}, "tas_logs"},
+	} {
+		if index, _ := route(router, makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, tc.attrs)); index != tc.want {
+			t.Errorf("%v routed to %s, want %s", tc.attrs, index, tc.want)
+		}
+	}
+}
+
+func TestRouter_AnyGroups(t *testing.T) {
+	router := NewRouter([]RoutingRule{{
+		Name:       "noisy",
+		Conditions: map[string]string{"cf_space_name": "^production$"},
+		Any: []map[string]string{
+			{"_severity": "==warn"},
+			{"cf_app_name": "^legacy-", "cf_source_type": "^APP"},
+		},
+		Index: "tas_noisy",
+	}})
+
+	for _, tc := range []struct {
+		sev   logspb.SeverityNumber
+		attrs map[string]string
+		want  string
+	}{
+		{logspb.SeverityNumber_SEVERITY_NUMBER_WARN, map[string]string{"cf_space_name": "production"}, "tas_noisy"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_space_name": "production", "cf_app_name": "legacy-billing", "cf_source_type": "APP/PROC/WEB"}, "tas_noisy"},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_space_name": "production", "cf_app_name": "legacy-billing"}, "tas_logs"}, // Group needs both
+		{logspb.SeverityNumber_SEVERITY_NUMBER_WARN, map[string]string{"cf_space_name": "staging"}, "tas_logs"},                                     // AND still applies
+	} {
+		if index, _ := route(router, makeLogRecord(tc.sev, tc.attrs)); index != tc.want {
+			t.Errorf("%s %v routed to %s, want %s", severityName(tc.sev), tc.attrs, index, tc.want)
+		}
+	}
+}
+
+func TestCompileRule_NamesCondition(t *testing.T) {
+	for _, tc := range []struct {
+		rule RoutingRule
+		want string
+	}{
+		{RoutingRule{Conditions: map[string]string{"a": "("}}, "conditions.a:"},
+		{RoutingRule{Not: map[string]string{"_severity": "loud"}}, "not._severity:"},
+		{RoutingRule{Any: []map[string]string{{"a": "x"}, {"b": "["}}}, "any[1].b:"},
+		{RoutingRule{Any: []map[string]string{{}}}, "any[0]: group has no conditions"},
+	} {
+		if _, err := compileRule(tc.rule); err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+			t.Errorf("error = %v, want prefix %q", err, tc.want)
+		}
+	}
+}
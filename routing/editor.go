@@ -0,0 +1,136 @@
+// ABOUTME: Runtime editing of routing rules: add, update, delete, and reorder.
+// ABOUTME: Each edit is validated by building a new router, and optionally saved back to the config file.
+
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// Editor errors callers can distinguish from validation failures
+var (
+	ErrRuleNotFound = errors.New("rule not found")
+	ErrRuleExists   = errors.New("rule already exists")
+	ErrPersist      = errors.New("saving routing config failed")
+)
+
+// Editor holds the routing config being edited at runtime. Every edit builds
+// a complete new router, leaving the config unchanged if the result is invalid.
+type Editor struct {
+	mu     sync.Mutex
+	config FileConfig
+	path   string // Edits are saved here if set
+}
+
+// NewEditor starts editing the given router's config, saving each edit to path if it is not empty
+func NewEditor(r *Router, path string) *Editor {
+	return &Editor{config: *r.Config(), path: path}
+}
+
+// Config returns a copy of the config as last edited
+func (e *Editor) Config() FileConfig {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fc := e.config
+	fc.Rules = slices.Clone(fc.Rules)
+	return fc
+}
+
+// ParseRule decodes one rule from YAML or JSON, rejecting unknown fields
+func ParseRule(data []byte) (*RuleFile, error) {
+	var rf RuleFile
+	if err := yaml.UnmarshalStrict(data, &rf); err != nil {
+		return nil, err
+	}
+	return &rf, nil
+}
+
+// AddRule appends a rule and returns the resulting router
+func (e *Editor) AddRule(rule *RuleFile) (*Router, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.find(rule.Name) >= 0 {
+		return nil, fmt.Errorf("rule %q: %w", rule.Name, ErrRuleExists)
+	}
+	fc := e.config
+	fc.Rules = append(slices.Clone(fc.Rules), rule)
+	return e.apply(fc)
+}
+
+// UpdateRule replaces the named rule, which may be renamed, and returns the resulting router
+func (e *Editor) UpdateRule(name string, rule *RuleFile) (*Router, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := e.find(name)
+	if i < 0 {
+		return nil, fmt.Errorf("rule %q: %w", name, ErrRuleNotFound)
+	}
+	fc := e.config
+	fc.Rules = slices.Clone(fc.Rules)
+	fc.Rules[i] = rule
+	return e.apply(fc)
+}
+
+// DeleteRule removes the named rule and returns the resulting router
+func (e *Editor) DeleteRule(name string) (*Router, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := e.find(name)
+	if i < 0 {
+		return nil, fmt.Errorf("rule %q: %w", name, ErrRuleNotFound)
+	}
+	fc := e.config
+	fc.Rules = slices.Delete(slices.Clone(fc.Rules), i, i+1)
+	return e.apply(fc)
+}
+
+// Reorder sets the evaluation order: names must list every rule once, and
+// each rule's priority becomes its position, starting at 1
+func (e *Editor) Reorder(names []string) (*Router, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(names) != len(e.config.Rules) {
+		return nil, fmt.Errorf("order lists %d rules, config has %d", len(names), len(e.config.Rules))
+	}
+	fc := e.config
+	fc.Rules = make([]*RuleFile, len(names))
+	for pos, name := range names {
+		i := e.find(name)
+		if i < 0 {
+			return nil, fmt.Errorf("rule %q: %w", name, ErrRuleNotFound)
+		}
+		if slices.Index(names, name) != pos {
+			return nil, fmt.Errorf("rule %q: listed more than once", name)
+		}
+		rule := *e.config.Rules[i]
+		rule.Priority = pos + 1
+		fc.Rules[pos] = &rule
+	}
+	return e.apply(fc)
+}
+
+// find returns the index of the named rule, or -1
+func (e *Editor) find(name string) int {
+	return slices.IndexFunc(e.config.Rules, func(rf *RuleFile) bool { return rf.Name == name })
+}
+
+// apply builds a router from the edited config, saves it if persisting, and
+// keeps it as the current config
+func (e *Editor) apply(fc FileConfig) (*Router, error) {
+	router, err := fc.Build()
+	if err != nil {
+		return nil, err
+	}
+	if e.path != "" {
+		if err := fc.Save(e.path); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrPersist, err)
+		}
+	}
+	e.config = fc
+	return router, nil
+}
@@ -0,0 +1,155 @@
+// ABOUTME: Tests for runtime routing rule editing.
+// ABOUTME: Covers each edit, rejected edits leaving the config unchanged, and saving back to the file.
+
+package routing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func ruleNames(fc FileConfig) string {
+	names := make([]string, len(fc.Rules))
+	for i, rf := range fc.Rules {
+		names[i] = rf.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestEditor_Edits(t *testing.T) {
+	e := NewEditor(DefaultRouter(), "")
+	if got := ruleNames(e.Config()); got != "error-severity,security-app,audit-app,production-space" {
+		t.Fatalf("initial rules = %s", got)
+	}
+
+	r, err := e.AddRule(&RuleFile{Name: "payments", Conditions: map[string]string{"cf_app_name": "^payments-"}, Index: "tas_payments", Priority: 0})
+	if err != nil {
+		t.Fatalf("AddRule: %v", err)
+	}
+	// Priority 0 evaluates before the ERROR rule
+	if index, _ := route(r, makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, map[string]string{"cf_app_name": "payments-api"})); index != "tas_payments" {
+		t.Errorf("after add routed to %s, want tas_payments", index)
+	}
+
+	r, err = e.UpdateRule("payments", &RuleFile{Name: "payments", Conditions: map[string]string{"cf_app_name": "^payments-"}, Index: "tas_payments_v2", Priority: 0})
+	if err != nil {
+		t.Fatalf("UpdateRule: %v", err)
+	}
+	if index, _ := route(r, makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_app_name": "payments-api"})); index != "tas_payments_v2" {
+		t.Errorf("after update routed to %s, want tas_payments_v2", index)
+	}
+
+	r, err = e.Reorder([]string{"error-severity", "payments", "security-app", "audit-app", "production-space"})
+	if err != nil {
+		t.Fatalf("Reorder: %v", err)
+	}
+	if index, _ := route(r, makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, map[string]string{"cf_app_name": "payments-api"})); index != "tas_errors" {
+		t.Errorf("after reorder routed to %s, want tas_errors", index)
+	}
+	if rules := r.Rules(); rules[1].Name != "payments" || rules[1].Priority != 2 {
+		t.Errorf("second rule = %s priority %d, want payments priority 2", rules[1].Name, rules[1].Priority)
+	}
+
+	if _, err = e.DeleteRule("payments"); err != nil {
+		t.Fatalf("DeleteRule: %v", err)
+	}
+	if got := ruleNames(e.Config()); got != "error-severity,security-app,audit-app,production-space" {
+		t.Errorf("after delete rules = %s", got)
+	}
+}
+
+func TestEditor_Rejected(t *testing.T) {
+	e := NewEditor(DefaultRouter(), "")
+	before := ruleNames(e.Config())
+
+	for name, edit := range map[string]func() error{
+		"duplicate": func() error {
+			_, err := e.AddRule(&RuleFile{Name: "audit-app", Conditions: map[string]string{"x": "y"}, Index: "i"})
+			if !errors.Is(err, ErrRuleExists) {
+				t.Errorf("duplicate add error = %v, want ErrRuleExists", err)
+			}
+			return err
+		},
+		"missing": func() error {
+			_, err := e.DeleteRule("nope")
+			if !errors.Is(err, ErrRuleNotFound) {
+				t.Errorf("missing delete error = %v, want ErrRuleNotFound", err)
+			}
+			return err
+		},
+		"invalid": func() error {
+			_, err := e.UpdateRule("audit-app", &RuleFile{Name: "audit-app", Conditions: map[string]string{"cf_app_name": "("}, Index: "i"})
+			return err
+		},
+		"rename onto another": func() error {
+			_, err := e.UpdateRule("audit-app", &RuleFile{Name: "security-app", Conditions: map[string]string{"x": "y"}, Index: "i"})
+			return err
+		},
+		"partial order": func() error {
+			_, err := e.Reorder([]string{"audit-app"})
+			return err
+		},
+		"repeated order": func() error {
+			_, err := e.Reorder([]string{"audit-app", "audit-app", "security-app", "error-severity"})
+			return err
+		},
+	} {
+		if err := edit(); err == nil {
+			t.Errorf("%s: edit accepted", name)
+		}
+	}
+	if got := ruleNames(e.Config()); got != before {
+		t.Errorf("rules after rejected edits = %s, want %s", got, before)
+	}
+}
+
+func TestEditor_Persist(t *testing.T) {
+	path := writeConfig(t, "routes.yaml", `
+default_index: tas_main
+rules:
+  - name: errors
+    conditions: {_severity: error}
+    index: tas_errors
+`)
+	r, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEditor(r, path)
+	if _, err := e.AddRule(&RuleFile{Name: "archive", Index: "tas_archive", Continue: true, Split: &Split{Index: "tas_archive_v2", Percent: 5}}); err != nil {
+		t.Fatalf("AddRule: %v", err)
+	}
+
+	reloaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("reloading saved config: %v", err)
+	}
+	if got := ruleNames(*reloaded.Config()); got != "errors,archive" || reloaded.DefaultIndex() != "tas_main" {
+		t.Errorf("saved rules = %s, default %s", got, reloaded.DefaultIndex())
+	}
+	if split := reloaded.Rules()[1].Split; split == nil || split.Percent != 5 {
+		t.Errorf("saved split = %+v", split)
+	}
+
+	// A failed save leaves the config unchanged
+	e.path = t.TempDir() + "/missing/routes.yaml"
+	if _, err := e.DeleteRule("archive"); !errors.Is(err, ErrPersist) {
+		t.Errorf("delete with unwritable path error = %v, want ErrPersist", err)
+	}
+	if got := ruleNames(e.Config()); got != "errors,archive" {
+		t.Errorf("rules after failed save = %s", got)
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	rf, err := ParseRule([]byte(`{"name": "a", "conditions": {"cf_app_name": "x"}, "index": "tas_a", "window": {"days": ["sat", "sun"]}}`))
+	if err != nil || rf.Name != "a" || rf.Window == nil || len(rf.Window.Days) != 2 {
+		t.Errorf("ParseRule JSON = %+v, %v", rf, err)
+	}
+	if _, err := ParseRule([]byte("name: a\nidx: b\n")); err == nil || !strings.Contains(err.Error(), "idx") {
+		t.Errorf("ParseRule with unknown field error = %v", err)
+	}
+}
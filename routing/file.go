@@ -0,0 +1,175 @@
+// ABOUTME: YAML/JSON routing config file loading.
+// ABOUTME: Rules are validated up front so a bad rule fails startup by name instead of panicking.
+
+package routing
+
+import (
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// FileConfig is the YAML representation of a routing config file. JSON is
+// valid YAML, so the same loader reads both.
+type FileConfig struct {
+	DefaultIndex string      `yaml:"default_index,omitempty" json:"default_index,omitempty"` // Where unmatched records go (default tas_logs); _drop discards them
+	DefaultSplit *Split      `yaml:"default_split,omitempty" json:"default_split,omitempty"` // Send a percentage of unmatched records to another index
+	Rules        []*RuleFile `yaml:"rules" json:"rules"`
+}
+
+// RuleFile is one routing rule in a config file
+type RuleFile struct {
+	Name       string              `yaml:"name" json:"name"`
+	Conditions map[string]string   `yaml:"conditions,omitempty" json:"conditions,omitempty"` // Attribute -> regex, or _severity -> severity condition
+	Not        map[string]string   `yaml:"not,omitempty" json:"not,omitempty"`               // Conditions none of which may match
+	Any        []map[string]string `yaml:"any,omitempty" json:"any,omitempty"`               // OR groups, one of which must match
+	Index      string              `yaml:"index" json:"index"`
+	Priority   int                 `yaml:"priority,omitempty" json:"priority,omitempty"`     // Lower = higher priority; ties keep file order
+	Continue   bool                `yaml:"continue,omitempty" json:"continue,omitempty"`     // Copy matches here and keep evaluating later rules
+	Sourcetype string              `yaml:"sourcetype,omitempty" json:"sourcetype,omitempty"` // Splunk sourcetype stamped on matched records
+	Source     string              `yaml:"source,omitempty" json:"source,omitempty"`         // Splunk source stamped on matched records
+	Split      *Split              `yaml:"split,omitempty" json:"split,omitempty"`           // Send a percentage of matches to another index
+	RateLimit  *RateLimit          `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"` // Send matches over a records-per-second cap to an overflow index
+	Window     *TimeWindow         `yaml:"window,omitempty" json:"window,omitempty"`         // Only match records timestamped within these days and hours
+	Expr       string              `yaml:"expr,omitempty" json:"expr,omitempty"`             // CEL expression over severity, body, attributes, and resource
+}
+
+// LoadConfig reads a routing config file and builds a router from its rules
+func LoadConfig(path string) (*Router, error) {
+	fc, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return fc.Build()
+}
+
+// ReadConfig parses a routing config file without validating its rules
+func ReadConfig(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fc FileConfig
+	if err := yaml.UnmarshalStrict(data, &fc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &fc, nil
+}
+
+// Build validates every rule and builds the router
+func (fc *FileConfig) Build() (*Router, error) {
+	if len(fc.Rules) == 0 && fc.DefaultIndex == "" && fc.DefaultSplit == nil {
+		return nil, fmt.Errorf("rules: at least one rule is required unless default_index or default_split is set")
+	}
+
+	rules := make([]RoutingRule, 0, len(fc.Rules))
+	names := make(map[string]bool)
+	for i, rf := range fc.Rules {
+		if rf == nil || rf.Name == "" {
+			return nil, fmt.Errorf("rules[%d]: name is required", i)
+		}
+		rule := rf.rule()
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rf.Name, err)
+		}
+		if names[rf.Name] {
+			return nil, fmt.Errorf("rule %q: duplicate rule name", rf.Name)
+		}
+		names[rf.Name] = true
+		rules = append(rules, rule)
+	}
+
+	router := NewRouter(rules)
+	if fc.DefaultIndex != "" {
+		router.SetDefaultIndex(fc.DefaultIndex)
+	}
+	if fc.DefaultSplit != nil {
+		if err := fc.DefaultSplit.Validate(); err != nil {
+			return nil, fmt.Errorf("default_%w", err)
+		}
+		if fc.DefaultSplit.Index == router.DefaultIndex() {
+			return nil, fmt.Errorf("default_split.index must differ from the default index")
+		}
+		router.SetDefaultSplit(fc.DefaultSplit)
+	}
+	return router, nil
+}
+
+// rule converts the file's rule to a RoutingRule
+func (rf *RuleFile) rule() RoutingRule {
+	return RoutingRule{Name: rf.Name, Conditions: rf.Conditions, Not: rf.Not, Any: rf.Any, Index: rf.Index, Priority: rf.Priority, Continue: rf.Continue, Sourcetype: rf.Sourcetype, Source: rf.Source, Split: rf.Split, RateLimit: rf.RateLimit, Window: rf.Window, Expr: rf.Expr}
+}
+
+// Config returns the router's rules and defaults as a config file would hold them
+func (r *Router) Config() *FileConfig {
+	fc := &FileConfig{DefaultSplit: r.defaultSplit, Rules: make([]*RuleFile, 0, len(r.config))}
+	if r.defaultIndex != builtinDefaultIndex {
+		fc.DefaultIndex = r.defaultIndex
+	}
+	for _, rule := range r.config {
+		fc.Rules = append(fc.Rules, &RuleFile{
+			Name: rule.Name, Conditions: rule.Conditions, Not: rule.Not, Any: rule.Any, Index: rule.Index, Priority: rule.Priority,
+			Continue: rule.Continue, Sourcetype: rule.Sourcetype, Source: rule.Source, Split: rule.Split, RateLimit: rule.RateLimit, Window: rule.Window, Expr: rule.Expr,
+		})
+	}
+	return fc
+}
+
+// Save writes the config to path as YAML, replacing the file atomically
+func (fc *FileConfig) Save(path string) error {
+	data, err := yaml.Marshal(fc)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Validate checks the rule has an index and conditions, and that every condition compiles
+func (rule *RoutingRule) Validate() error {
+	if rule.Index == "" {
+		return fmt.Errorf("index is required")
+	}
+	if rule.Index == DropIndex && rule.Continue {
+		return fmt.Errorf("index %s cannot be combined with continue", DropIndex)
+	}
+	if rule.Index == DropIndex && (rule.Sourcetype != "" || rule.Source != "") {
+		return fmt.Errorf("index %s cannot set sourcetype or source", DropIndex)
+	}
+	if rule.Split != nil {
+		if err := rule.Split.Validate(); err != nil {
+			return err
+		}
+		if rule.Split.Index == rule.Index {
+			return fmt.Errorf("split.index must differ from index")
+		}
+		if rule.Split.Index == DropIndex && rule.Continue {
+			return fmt.Errorf("split.index %s cannot be combined with continue", DropIndex)
+		}
+	}
+	if rule.RateLimit != nil {
+		if err := rule.RateLimit.Validate(); err != nil {
+			return err
+		}
+		if rule.Index == DropIndex {
+			return fmt.Errorf("index %s cannot set rate_limit", DropIndex)
+		}
+		if rule.RateLimit.Overflow() == rule.Index {
+			return fmt.Errorf("rate_limit.overflow_index must differ from index")
+		}
+		if rule.RateLimit.Overflow() == DropIndex && rule.Continue {
+			return fmt.Errorf("rate_limit.overflow_index %s cannot be combined with continue", DropIndex)
+		}
+	}
+	// A catch-all is only useful as a copy; a final one would shadow every later rule
+	if len(rule.Conditions) == 0 && len(rule.Not) == 0 && len(rule.Any) == 0 && rule.Window == nil && rule.Expr == "" && !rule.Continue {
+		return fmt.Errorf("at least one condition, window, or expr is required unless the rule sets continue")
+	}
+	_, err := compileRule(*rule)
+	return err
+}
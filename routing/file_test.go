@@ -0,0 +1,297 @@
+// ABOUTME: Tests for routing config file loading.
+// ABOUTME: Covers YAML and JSON rule files, priority ordering, and validation errors.
+
+package routing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_YAML(t *testing.T) {
+	router, err := LoadConfig(writeConfig(t, "routes.yaml", `
+rules:
+  - name: payments
+    conditions:
+      cf_app_name: ^payments-
+    index: tas_payments
+    priority: 2
+  - name: errors
+    conditions:
+      _severity: error
+    index: tas_errors
+    priority: 1
+  - name: staging
+    conditions:
+      cf_space_name: ^staging$
+    index: tas_staging
+    priority: 2
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	var names []string
+	for _, rule := range router.Rules() {
+		names = append(names, rule.Name)
+	}
+	if got := strings.Join(names, ","); got != "errors,payments,staging" {
+		t.Errorf("rule order = %s, want errors,payments,staging (ties keep file order)", got)
+	}
+
+	lr := makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_app_name": "payments-api"})
+	if index, rule := route(router, lr); index != "tas_payments" || rule != "payments" {
+		t.Errorf("Route = %s/%s, want tas_payments/payments", index, rule)
+	}
+}
+
+func TestLoadConfig_JSON(t *testing.T) {
+	router, err := LoadConfig(writeConfig(t, "routes.json", `{
+  "rules": [
+    {"name": "audit", "conditions": {"cf_app_name": "^audit-"}, "index": "tas_audit", "priority": 1}
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	lr := makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, map[string]string{"cf_app_name": "audit-log"})
+	if index, _ := route(router, lr); index != "tas_audit" {
+		t.Errorf("Route = %s, want tas_audit (file rules replace the defaults)", index)
+	}
+}
+
+func TestLoadConfig_NotAndAny(t *testing.T) {
+	router, err := LoadConfig(writeConfig(t, "routes.yaml", `
+rules:
+  - name: nonprod-noise
+    not:
+      cf_space_name: ^production$
+    any:
+      - _severity: <=debug
+      - cf_app_name: ^healthcheck-
+    index: tas_noise
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	lr := makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_app_name": "healthcheck-web", "cf_space_name": "dev"})
+	if index, _ := route(router, lr); index != "tas_noise" {
+		t.Errorf("Route = %s, want tas_noise", index)
+	}
+}
+
+func TestLoadConfig_ContinueCatchAll(t *testing.T) {
+	router, err := LoadConfig(writeConfig(t, "routes.yaml", `
+rules:
+  - name: archive
+    index: tas_archive
+    continue: true
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	dests := router.Route(makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, nil))
+	if len(dests) != 2 || dests[0].Index != "tas_archive" || Primary(dests).Index != "tas_logs" {
+		t.Errorf("Route = %v, want a tas_archive copy then tas_logs", dests)
+	}
+}
+
+func TestLoadConfig_SourcetypeAndSource(t *testing.T) {
+	router, err := LoadConfig(writeConfig(t, "routes.yaml", `
+rules:
+  - name: archive
+    index: tas_archive
+    continue: true
+    sourcetype: cf:archive
+  - name: errors
+    conditions: {_severity: error}
+    index: tas_errors
+    sourcetype: cf:error
+    source: tas:errors
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	dests := router.Route(makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, nil))
+	want := []Destination{
+		{Index: "tas_archive", Rule: "archive", Sourcetype: "cf:archive"},
+		{Index: "tas_errors", Rule: "errors", Sourcetype: "cf:error", Source: "tas:errors"},
+	}
+	if !slices.Equal(dests, want) {
+		t.Errorf("Route = %v, want %v", dests, want)
+	}
+
+	// The default destination sets neither
+	dests = router.Route(makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, nil))
+	if final := Primary(dests); final.Sourcetype != "" || final.Source != "" {
+		t.Errorf("default destination = %+v, want no sourcetype or source", final)
+	}
+}
+
+func TestLoadConfig_Split(t *testing.T) {
+	router, err := LoadConfig(writeConfig(t, "routes.yaml", `
+default_split: {index: tas_logs_v2, percent: 10}
+rules:
+  - name: errors
+    conditions: {_severity: error}
+    index: tas_errors
+    split: {index: tas_errors_v2, percent: 25, by: cf_app_name}
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	counts := map[string]int{}
+	for i := range 2000 {
+		lr := splitRecord(i)
+		counts[Primary(router.Route(lr)).Index]++
+		lr.SeverityNumber = logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+		lr.Attributes = makeLogRecord(0, map[string]string{"cf_app_name": fmt.Sprintf("app-%d", i)}).Attributes
+		dest := Primary(router.Route(lr))
+		if dest.Rule != "errors" {
+			t.Fatalf("split destination rule = %q, want errors", dest.Rule)
+		}
+		counts[dest.Index]++
+	}
+	for index, want := range map[string]int{"tas_logs": 1800, "tas_logs_v2": 200, "tas_errors": 1500, "tas_errors_v2": 500} {
+		if got := counts[index]; got < want-60 || got > want+60 {
+			t.Errorf("%s got %d records, want about %d", index, got, want)
+		}
+	}
+}
+
+func TestLoadConfig_DefaultIndexDrop(t *testing.T) {
+	router, err := LoadConfig(writeConfig(t, "routes.yaml", `
+default_index: _drop
+rules:
+  - name: errors
+    conditions: {_severity: error}
+    index: tas_errors
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if router.DefaultIndex() != DropIndex {
+		t.Errorf("DefaultIndex = %q, want _drop", router.DefaultIndex())
+	}
+	if dests := Deliverable(router.Route(makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, nil))); len(dests) != 0 {
+		t.Errorf("unmatched record delivered to %v, want dropped", dests)
+	}
+
+	// A default index alone is a valid config
+	if router, err := LoadConfig(writeConfig(t, "routes.yaml", `default_index: tas_main`)); err != nil || router.DefaultIndex() != "tas_main" {
+		t.Errorf("default_index only: %v", err)
+	}
+}
+
+func TestLoadConfig_ValidationNamesRule(t *testing.T) {
+	for _, tc := range []struct {
+		name, config, want string
+	}{
+		{"bad regex", `
+rules:
+  - name: broken
+    conditions: {cf_app_name: "^(unclosed"}
+    index: tas_x
+`, `rule "broken": conditions.cf_app_name`},
+		{"bad severity", `
+rules:
+  - name: warnings
+    conditions: {_severity: warning}
+    index: tas_warn
+`, `rule "warnings": conditions._severity`},
+		{"missing index", `
+rules:
+  - name: nowhere
+    conditions: {cf_app_name: x}
+`, `rule "nowhere": index is required`},
+		{"no conditions", `
+rules:
+  - name: everything
+    index: tas_all
+`, `rule "everything": at least one condition`},
+		{"duplicate name", `
+rules:
+  - {name: a, conditions: {cf_app_name: x}, index: one}
+  - {name: a, conditions: {cf_app_name: y}, index: two}
+`, `rule "a": duplicate`},
+		{"missing name", `
+rules:
+  - {conditions: {cf_app_name: x}, index: one}
+`, `rules[0]: name is required`},
+		{"bad any group", `
+rules:
+  - name: grouped
+    any:
+      - {cf_app_name: x}
+      - {cf_space_name: "[z"}
+    index: tas_x
+`, `rule "grouped": any[1].cf_space_name`},
+		{"drop copy", `
+rules:
+  - {name: discard, index: _drop, continue: true}
+`, `rule "discard": index _drop cannot be combined with continue`},
+		{"drop sourcetype", `
+rules:
+  - {name: discard, conditions: {cf_app_name: x}, index: _drop, sourcetype: noise}
+`, `rule "discard": index _drop cannot set sourcetype or source`},
+		{"split to own index", `
+rules:
+  - {name: logs, conditions: {cf_app_name: x}, index: tas_logs, split: {index: tas_logs, percent: 10}}
+`, `rule "logs": split.index must differ from index`},
+		{"split percent", `
+rules:
+  - {name: logs, conditions: {cf_app_name: x}, index: tas_logs, split: {index: tas_v2, percent: 100}}
+`, `rule "logs": split.percent 100 must be above 0 and below 100`},
+		{"default split percent", `default_split: {index: tas_v2, percent: 0}`, `default_split.percent 0`},
+		{"rate limit", `
+rules:
+  - {name: logs, conditions: {cf_app_name: x}, index: tas_logs, rate_limit: {per_second: 0}}
+`, `rule "logs": rate_limit.per_second 0 must be above 0`},
+		{"rate limit overflow to own index", `
+rules:
+  - {name: overflow, conditions: {cf_app_name: x}, index: tas_overflow, rate_limit: {per_second: 5}}
+`, `rule "overflow": rate_limit.overflow_index must differ from index`},
+		{"rate limit dropping copies", `
+rules:
+  - {name: archive, index: tas_archive, continue: true, rate_limit: {per_second: 5, overflow_index: _drop}}
+`, `rule "archive": rate_limit.overflow_index _drop cannot be combined with continue`},
+		{"bad window", `
+rules:
+  - {name: office, conditions: {cf_app_name: x}, index: tas_x, window: {days: [mon-fry]}}
+`, `rule "office": window.days: unknown day "fry"`},
+		{"bad expr", `
+rules:
+  - {name: cel, expr: 'severity >= "warn"', index: tas_x}
+`, `rule "cel": expr: ERROR: <input>:1:10: found no matching overload for '_>=_' applied to '(int, string)'`},
+		{"no rules", `rules: []`, `at least one rule`},
+		{"unknown field", `
+rules:
+  - {name: a, conditions: {cf_app_name: x}, idx: one}
+`, `idx`},
+	} {
+		_, err := LoadConfig(writeConfig(t, "routes.yaml", tc.config))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: error = %v, want it to contain %q", tc.name, err, tc.want)
+		}
+	}
+}
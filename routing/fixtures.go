@@ -0,0 +1,341 @@
+// ABOUTME: Synthetic record generation covering every routing rule.
+// ABOUTME: Emits a match case and one near-miss per condition, with the routing the router gives each.
+
+package routing
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// Fixture cases
+const (
+	CaseMatch    = "match"
+	CaseNearMiss = "near-miss"
+	CaseDefault  = "default"
+)
+
+// fixtureApp is the app name used unless a rule conditions on cf_app_name
+const fixtureApp = "fixture-app"
+
+// Fixture is a synthetic record aimed at one rule, with the routing it actually gets
+type Fixture struct {
+	Name           string            `json:"name"`
+	Rule           string            `json:"rule"` // Rule the record is aimed at
+	Case           string            `json:"case"`
+	Condition      string            `json:"condition,omitempty"` // Condition a near-miss fails
+	SeverityText   string            `json:"severity_text"`
+	SeverityNumber int32             `json:"severity_number"`
+	Body           string            `json:"body"`
+	Attributes     map[string]string `json:"attributes"`
+	Timestamp      string            `json:"timestamp,omitempty"` // RFC 3339, set for rules with a time window
+	Expected       Expected          `json:"expected"`
+}
+
+// Expected is the routing decision a fixture gets from the router: its final
+// destination, plus any copies made by continue rules
+type Expected struct {
+	Index      string        `json:"index"`
+	Rule       string        `json:"rule"`
+	Sourcetype string        `json:"sourcetype,omitempty"`
+	Source     string        `json:"source,omitempty"`
+	Copies     []Destination `json:"copies,omitempty"`
+}
+
+// NewExpected summarizes a Route result
+func NewExpected(dests []Destination) Expected {
+	final := Primary(dests)
+	return Expected{Index: final.Index, Rule: final.Rule, Sourcetype: final.Sourcetype, Source: final.Source, Copies: dests[:len(dests)-1]}
+}
+
+// Equal reports whether two routing decisions match, copies included
+func (e Expected) Equal(other Expected) bool {
+	return e.Index == other.Index && e.Rule == other.Rule && e.Sourcetype == other.Sourcetype &&
+		e.Source == other.Source && slices.Equal(e.Copies, other.Copies)
+}
+
+// LogRecord builds the OTLP record the fixture describes
+func (f *Fixture) LogRecord() *logspb.LogRecord {
+	lr := &logspb.LogRecord{
+		SeverityText:   f.SeverityText,
+		SeverityNumber: logspb.SeverityNumber(f.SeverityNumber),
+		Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: f.Body}},
+	}
+	if ts, err := time.Parse(time.RFC3339, f.Timestamp); err == nil {
+		lr.TimeUnixNano = uint64(ts.UnixNano())
+	}
+	keys := make([]string, 0, len(f.Attributes))
+	for key := range f.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{
+			Key:   key,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: f.Attributes[key]}},
+		})
+	}
+	return lr
+}
+
+// GenerateFixtures returns fixtures for every rule in priority order: one record
+// satisfying the rule and, per condition, one record that fails only that
+// condition. Negated conditions get a near-miss that matches them, and OR
+// groups one that satisfies none of the first group's conditions. Rules with a
+// time window get a timestamp inside it, and a near-miss outside it. A record
+// matching no rule exercises the default. Expected routing comes from the
+// router itself, so shadowed rules show up as a match case routed elsewhere.
+func (r *Router) GenerateFixtures() []Fixture {
+	fixtures := []Fixture{r.fixture("default", "", CaseDefault, "", newFixtureRecord())}
+	for _, rule := range r.Rules() {
+		fixtures = append(fixtures, r.ruleFixtures(rule)...)
+	}
+	return fixtures
+}
+
+// ruleFixtures builds the match and near-miss fixtures for one rule
+func (r *Router) ruleFixtures(rule RoutingRule) []Fixture {
+	// The match case satisfies the conditions and the first OR group; negated
+	// attributes are left out, and a negated severity moves it outside that range
+	base := newFixtureRecord()
+	base.satisfy(rule.Conditions)
+	var anyGroup map[string]string
+	if len(rule.Any) > 0 {
+		anyGroup = rule.Any[0]
+		base.satisfy(anyGroup)
+	}
+	if cond, ok := rule.Not["_severity"]; ok && !base.severitySet {
+		sr, _ := parseSeverityCondition(cond) // Validated by NewRouter
+		base.severity = severityOutside(sr)
+	}
+
+	var window *compiledWindow
+	if rule.Window != nil {
+		window, _ = compileWindow(rule.Window) // Validated by NewRouter
+		base.time, _ = window.find(true)
+	}
+
+	fixtures := []Fixture{r.fixture(rule.Name+"/match", rule.Name, CaseMatch, "", base)}
+	nearMiss := func(condition string, miss fixtureRecord) {
+		fixtures = append(fixtures, r.fixture(rule.Name+"/near-miss/"+condition, rule.Name, CaseNearMiss, condition, miss))
+	}
+
+	for _, attr := range sortedKeys(rule.Conditions) {
+		miss := base.clone()
+		miss.fail(attr, rule.Conditions[attr])
+		nearMiss(attr, miss)
+	}
+	for _, attr := range sortedKeys(rule.Not) {
+		miss := base.clone()
+		miss.satisfy(map[string]string{attr: rule.Not[attr]})
+		nearMiss("not."+attr, miss)
+	}
+	if anyGroup != nil {
+		miss := base.clone()
+		for _, attr := range sortedKeys(anyGroup) {
+			if _, shared := rule.Conditions[attr]; !shared {
+				miss.fail(attr, anyGroup[attr])
+			}
+		}
+		nearMiss("any", miss)
+	}
+	if window != nil {
+		miss := base.clone()
+		if t, ok := window.find(false); ok {
+			miss.time = t
+			nearMiss("window", miss)
+		}
+	}
+	return fixtures
+}
+
+// fixtureRecord is the attributes and severity a fixture is being built from
+type fixtureRecord struct {
+	attrs       map[string]string
+	severity    logspb.SeverityNumber
+	severitySet bool      // A severity condition chose the severity
+	time        time.Time // Zero unless the rule has a time window
+}
+
+func newFixtureRecord() fixtureRecord {
+	return fixtureRecord{attrs: make(map[string]string), severity: logspb.SeverityNumber_SEVERITY_NUMBER_INFO}
+}
+
+func (fr fixtureRecord) clone() fixtureRecord {
+	attrs := make(map[string]string, len(fr.attrs))
+	for k, v := range fr.attrs {
+		attrs[k] = v
+	}
+	fr.attrs = attrs
+	return fr
+}
+
+// satisfy sets values matching each condition: the lowest severity in a
+// severity range, a number on or just past a numeric bound, a short matching
+// value for a regex
+func (fr *fixtureRecord) satisfy(conds map[string]string) {
+	for _, attr := range sortedKeys(conds) {
+		if attr == "_severity" {
+			sr, _ := parseSeverityCondition(conds[attr]) // Validated by NewRouter
+			fr.severity, fr.severitySet = sr.Min, true
+			continue
+		}
+		if nc, ok := parseNumericCondition(conds[attr]); ok {
+			fr.attrs[attr] = formatNumber(nc.matching())
+			continue
+		}
+		fr.attrs[attr] = matchingValue(conds[attr])
+	}
+}
+
+// fail changes the record so the condition no longer holds
+func (fr *fixtureRecord) fail(attr, pattern string) {
+	if attr == "_severity" {
+		sr, _ := parseSeverityCondition(pattern) // Validated by NewRouter
+		fr.severity = severityOutside(sr)
+		return
+	}
+	if nc, ok := parseNumericCondition(pattern); ok {
+		fr.attrs[attr] = formatNumber(nc.failing())
+		return
+	}
+	if v, ok := nonMatchingValue(pattern, fr.attrs[attr]); ok {
+		fr.attrs[attr] = v
+	} else {
+		delete(fr.attrs, attr) // Pattern matches anything: only a missing attribute fails it
+	}
+}
+
+// sortedKeys returns a condition map's attributes in order
+func sortedKeys(conds map[string]string) []string {
+	keys := make([]string, 0, len(conds))
+	for k := range conds {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fixture builds a fixture from a record and routes it
+func (r *Router) fixture(name, rule, c, condition string, fr fixtureRecord) Fixture {
+	all := map[string]string{"cf_app_name": fixtureApp}
+	for k, v := range fr.attrs {
+		all[k] = v
+	}
+
+	f := Fixture{
+		Name:           name,
+		Rule:           rule,
+		Case:           c,
+		Condition:      condition,
+		SeverityText:   severityName(fr.severity),
+		SeverityNumber: int32(fr.severity),
+		Body:           "routing fixture " + name,
+		Attributes:     all,
+	}
+	if !fr.time.IsZero() {
+		f.Timestamp = fr.time.Format(time.RFC3339)
+	}
+	f.Expected = NewExpected(r.DryRoute(nil, f.LogRecord()))
+	return f
+}
+
+// severityOutside returns a severity just outside the range: the start of the
+// level below it, else the number above it, else unspecified
+func severityOutside(sr severityRange) logspb.SeverityNumber {
+	switch {
+	case sr.Min > minSeverity:
+		below := sr.Min - 1
+		return below - (below-1)%4
+	case sr.Max < maxSeverity:
+		return sr.Max + 1
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+	}
+}
+
+// matchingValue returns a short non-empty string the pattern matches
+func matchingValue(pattern string) string {
+	re := regexp.MustCompile(pattern)
+	if parsed, err := syntax.Parse(pattern, syntax.Perl); err == nil {
+		if v := example(parsed.Simplify()); v != "" && re.MatchString(v) {
+			return v
+		}
+	}
+	for _, v := range []string{"x", "fixture", "0"} {
+		if re.MatchString(v) {
+			return v
+		}
+	}
+	return "fixture"
+}
+
+// nonMatchingValue returns a non-empty value close to a matching one that the
+// pattern rejects, or false if the pattern accepts every candidate
+func nonMatchingValue(pattern, matching string) (string, bool) {
+	re := regexp.MustCompile(pattern)
+	candidates := []string{"x" + matching, matching + "x"}
+	if len(matching) > 1 {
+		candidates = append(candidates, matching[1:], matching[:len(matching)-1])
+	}
+	candidates = append(candidates, strings.ToUpper(matching), "near-miss")
+	for _, v := range candidates {
+		if v != "" && !re.MatchString(v) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// example builds a string matched by the regex, taking the shortest choice at
+// each repetition and the first branch of each alternation
+func example(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return string(re.Rune)
+	case syntax.OpCharClass:
+		return string(classRune(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return "x"
+	case syntax.OpCapture:
+		return example(re.Sub[0])
+	case syntax.OpPlus:
+		return example(re.Sub[0])
+	case syntax.OpRepeat:
+		return strings.Repeat(example(re.Sub[0]), re.Min)
+	case syntax.OpConcat:
+		var b strings.Builder
+		for _, sub := range re.Sub {
+			b.WriteString(example(sub))
+		}
+		return b.String()
+	case syntax.OpAlternate:
+		return example(re.Sub[0])
+	}
+	// Empty matches, anchors, boundaries, and optional repetitions
+	return ""
+}
+
+// classRune picks a readable rune from a character class given as lo/hi pairs
+func classRune(ranges []rune) rune {
+	for _, preferred := range "ax0A-_ " {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= preferred && preferred <= ranges[i+1] {
+				return preferred
+			}
+		}
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if ranges[i+1] >= '!' {
+			return max(ranges[i], '!')
+		}
+	}
+	return ranges[0]
+}
@@ -0,0 +1,148 @@
+// ABOUTME: Tests for routing fixture generation.
+// ABOUTME: Covers regex examples, near-miss values, and expected routing per case.
+
+package routing
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestMatchingValue(t *testing.T) {
+	for _, pattern := range []string{`^security-`, `^production$`, `^(prod|staging)-\d{2}$`, `[^/]+\.example\.com`, `.*`, `^$|^x+$`, `(?i)^AUDIT`} {
+		v := matchingValue(pattern)
+		if v == "" || !regexp.MustCompile(pattern).MatchString(v) {
+			t.Errorf("matchingValue(%q) = %q, which does not match", pattern, v)
+		}
+	}
+}
+
+func TestNonMatchingValue(t *testing.T) {
+	for _, pattern := range []string{`^security-`, `^production$`, `error`, `\d+`} {
+		v, ok := nonMatchingValue(pattern, matchingValue(pattern))
+		if !ok || v == "" || regexp.MustCompile(pattern).MatchString(v) {
+			t.Errorf("nonMatchingValue(%q) = %q, %v", pattern, v, ok)
+		}
+	}
+	if _, ok := nonMatchingValue(`.*`, "x"); ok {
+		t.Error("a pattern matching everything has no near-miss value")
+	}
+}
+
+func TestGenerateFixtures_DefaultRouter(t *testing.T) {
+	fixtures := DefaultRouter().GenerateFixtures()
+
+	// default + (match + one near-miss) for each of the four single-condition rules
+	if len(fixtures) != 9 {
+		t.Fatalf("expected 9 fixtures, got %d", len(fixtures))
+	}
+	byName := make(map[string]Fixture)
+	for _, f := range fixtures {
+		byName[f.Name] = f
+	}
+
+	for _, rule := range DefaultRouter().Rules() {
+		match := byName[rule.Name+"/match"]
+		if match.Expected.Rule != rule.Name || match.Expected.Index != rule.Index {
+			t.Errorf("%s match routed to %+v", rule.Name, match.Expected)
+		}
+	}
+	if f := byName["default"]; f.Expected.Rule != "default" || f.Expected.Index != "tas_logs" {
+		t.Errorf("default fixture routed to %+v", f.Expected)
+	}
+	if f := byName["error-severity/near-miss/_severity"]; f.SeverityText != "WARN" || f.Expected.Rule != "default" {
+		t.Errorf("severity near-miss = %s routed to %+v", f.SeverityText, f.Expected)
+	}
+	if f := byName["security-app/near-miss/cf_app_name"]; f.Expected.Rule != "default" || f.Attributes["cf_app_name"] == "" {
+		t.Errorf("app near-miss %v routed to %+v", f.Attributes, f.Expected)
+	}
+}
+
+func TestGenerateFixtures_ShadowedRule(t *testing.T) {
+	r := NewRouter([]RoutingRule{
+		{Name: "all-prod", Conditions: map[string]string{"cf_space_name": "prod"}, Index: "prod", Priority: 1},
+		{Name: "prod-payments", Conditions: map[string]string{"cf_space_name": "^prod$", "cf_app_name": "^payments$"}, Index: "payments", Priority: 2},
+	})
+
+	var shadowed *Fixture
+	nearMisses := 0
+	for _, f := range r.GenerateFixtures() {
+		if f.Rule == "prod-payments" {
+			if f.Case == CaseMatch {
+				shadowed = &f
+			} else {
+				nearMisses++
+			}
+		}
+	}
+	if shadowed == nil || shadowed.Expected.Rule != "all-prod" {
+		t.Errorf("shadowed rule's match case should route to all-prod, got %+v", shadowed)
+	}
+	if nearMisses != 2 {
+		t.Errorf("expected one near-miss per condition, got %d", nearMisses)
+	}
+}
+
+func TestGenerateFixtures_NotAndAny(t *testing.T) {
+	r := NewRouter([]RoutingRule{{
+		Name:  "nonprod-warnings",
+		Not:   map[string]string{"cf_space_name": "^production$"},
+		Any:   []map[string]string{{"_severity": "==warn"}, {"cf_app_name": "^legacy-"}},
+		Index: "tas_nonprod",
+	}})
+
+	byName := make(map[string]Fixture)
+	for _, f := range r.GenerateFixtures() {
+		byName[f.Name] = f
+	}
+
+	if f := byName["nonprod-warnings/match"]; f.Expected.Rule != "nonprod-warnings" || f.SeverityText != "WARN" {
+		t.Errorf("match = %s %v routed to %+v", f.SeverityText, f.Attributes, f.Expected)
+	}
+	if f := byName["nonprod-warnings/near-miss/not.cf_space_name"]; f.Attributes["cf_space_name"] != "production" || f.Expected.Rule != "default" {
+		t.Errorf("not near-miss = %v routed to %+v", f.Attributes, f.Expected)
+	}
+	if f := byName["nonprod-warnings/near-miss/any"]; f.SeverityText == "WARN" || f.Expected.Rule != "default" {
+		t.Errorf("any near-miss = %s %v routed to %+v", f.SeverityText, f.Attributes, f.Expected)
+	}
+}
+
+func TestGenerateFixtures_Window(t *testing.T) {
+	r := NewRouter([]RoutingRule{{
+		Name:       "night-shift",
+		Conditions: map[string]string{"cf_app_name": "^batch-"},
+		Window:     &TimeWindow{Hours: "22:00-06:00", Timezone: "Europe/Berlin"},
+		Index:      "tas_batch",
+	}})
+
+	byName := make(map[string]Fixture)
+	for _, f := range r.GenerateFixtures() {
+		byName[f.Name] = f
+	}
+
+	if f := byName["night-shift/match"]; f.Timestamp == "" || f.Expected.Rule != "night-shift" {
+		t.Errorf("match at %q routed to %+v", f.Timestamp, f.Expected)
+	}
+	if f := byName["night-shift/near-miss/window"]; f.Timestamp == "" || f.Expected.Rule != "default" {
+		t.Errorf("window near-miss at %q routed to %+v", f.Timestamp, f.Expected)
+	}
+	if f := byName["default"]; f.Timestamp != "" {
+		t.Errorf("default fixture timestamp = %q, want none", f.Timestamp)
+	}
+}
+
+func TestExpected_Equal(t *testing.T) {
+	routed := NewExpected([]Destination{{Index: "tas_archive", Rule: "archive"}, {Index: "tas_logs", Rule: "default"}})
+	var decoded Expected
+	data, _ := json.Marshal(routed)
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !routed.Equal(decoded) {
+		t.Errorf("%+v should equal its JSON round trip %+v", routed, decoded)
+	}
+	if noCopies := NewExpected([]Destination{{Index: "tas_logs", Rule: "default"}}); noCopies.Equal(routed) {
+		t.Error("decisions with different copies should differ")
+	}
+}
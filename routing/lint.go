@@ -0,0 +1,202 @@
+// ABOUTME: Routing config linting: invalid rules, duplicate priorities, and shadowed rules.
+// ABOUTME: Reports every problem as a finding instead of stopping at the first or panicking.
+
+package routing
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+// Finding severities
+const (
+	LintError   = "error"   // The config will not load
+	LintWarning = "warning" // The config loads but probably does not do what was meant
+)
+
+// Finding is one problem Lint found in a routing config
+type Finding struct {
+	Rule     string `json:"rule,omitempty"` // Empty for problems with the config as a whole
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// String formats the finding, e.g. `rule "payments": warning: shares priority 2 with "audit"`
+func (f Finding) String() string {
+	if f.Rule == "" {
+		return f.Severity + ": " + f.Message
+	}
+	return fmt.Sprintf("rule %q: %s: %s", f.Rule, f.Severity, f.Message)
+}
+
+// Lint checks the config without building a router, reporting every invalid
+// rule (bad regexes, severities, windows, and expressions included), rules
+// sharing a priority, whose relative order then depends on file order, and
+// rules that can never match because an earlier final rule matches every
+// record they would. Priority 0 is the unset default, so rules left at it are
+// not reported as sharing a priority. Shadowing is only reported when it
+// follows from the rules' conditions alone, so overlaps that depend on the
+// records sent are not flagged.
+func (fc *FileConfig) Lint() []Finding {
+	var findings []Finding
+	var valid []RoutingRule
+	names := make(map[string]bool)
+	for i, rf := range fc.Rules {
+		if rf == nil || rf.Name == "" {
+			findings = append(findings, Finding{Severity: LintError, Message: fmt.Sprintf("rules[%d]: name is required", i)})
+			continue
+		}
+		if names[rf.Name] {
+			findings = append(findings, Finding{Rule: rf.Name, Severity: LintError, Message: "duplicate rule name"})
+			continue
+		}
+		names[rf.Name] = true
+		rule := rf.rule()
+		if err := rule.Validate(); err != nil {
+			findings = append(findings, Finding{Rule: rf.Name, Severity: LintError, Message: err.Error()})
+			continue
+		}
+		valid = append(valid, rule)
+	}
+	// Rule errors are reported above; Build would only repeat the first
+	if len(findings) == 0 {
+		if _, err := fc.Build(); err != nil {
+			findings = append(findings, Finding{Severity: LintError, Message: err.Error()})
+		}
+	}
+
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].Priority < valid[j].Priority
+	})
+	for i, rule := range valid {
+		if rule.Priority != 0 && i > 0 && valid[i-1].Priority == rule.Priority {
+			findings = append(findings, Finding{Rule: rule.Name, Severity: LintWarning,
+				Message: fmt.Sprintf("shares priority %d with %q, so file order decides which is evaluated first", rule.Priority, valid[i-1].Name)})
+		}
+		for _, earlier := range valid[:i] {
+			if !earlier.Continue && covers(earlier, rule) {
+				findings = append(findings, Finding{Rule: rule.Name, Severity: LintWarning,
+					Message: fmt.Sprintf("unreachable: %q (priority %d) comes first and matches every record this rule would", earlier.Name, earlier.Priority)})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// covers reports whether rule a matches every record rule b matches, judging
+// by their conditions alone
+func covers(a, b RoutingRule) bool {
+	if a.Window != nil && !reflect.DeepEqual(a.Window, b.Window) {
+		return false
+	}
+	if a.Expr != "" && a.Expr != b.Expr {
+		return false
+	}
+	for attr, cond := range a.Conditions {
+		if !impliedBy(attr, cond, b.Conditions) {
+			return false
+		}
+	}
+	// b must exclude at least what a excludes
+	for attr, cond := range a.Not {
+		if other, ok := b.Not[attr]; !ok || other != cond {
+			return false
+		}
+	}
+	if len(a.Any) == 0 || reflect.DeepEqual(a.Any, b.Any) {
+		return true
+	}
+	for _, group := range a.Any {
+		if impliesAll(b.Conditions, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// impliesAll reports whether conditions holding means every one of want holds
+func impliesAll(conds, want map[string]string) bool {
+	for attr, cond := range want {
+		if !impliedBy(attr, cond, conds) {
+			return false
+		}
+	}
+	return true
+}
+
+// impliedBy reports whether conds holding means cond on attr holds
+func impliedBy(attr, cond string, conds map[string]string) bool {
+	other, ok := conds[attr]
+	if !ok {
+		return false
+	}
+	if strings.TrimSpace(other) == strings.TrimSpace(cond) {
+		return true
+	}
+	if attr == "_severity" {
+		want, errWant := parseSeverityCondition(cond)
+		have, errHave := parseSeverityCondition(other)
+		return errWant == nil && errHave == nil && want.Min <= have.Min && have.Max <= want.Max
+	}
+	want, wantNumeric := parseNumericCondition(cond)
+	have, haveNumeric := parseNumericCondition(other)
+	if wantNumeric || haveNumeric {
+		return wantNumeric && haveNumeric && want.covers(have)
+	}
+
+	re, err := regexp.Compile(cond)
+	if err != nil {
+		return false
+	}
+	if matchesEverything(re) {
+		return true
+	}
+	value, ok := exactValue(other)
+	return ok && re.MatchString(value)
+}
+
+// matchesEverything reports whether the regex matches any string: it matches
+// the empty string and nothing anchors it, so it matches at the start of any input
+func matchesEverything(re *regexp.Regexp) bool {
+	if !re.MatchString("") {
+		return false
+	}
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	return err == nil && !anchored(parsed)
+}
+
+// anchored reports whether the regex contains a line, text, or word boundary
+func anchored(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	}
+	for _, sub := range re.Sub {
+		if anchored(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// exactValue returns the one value a pattern like ^prod$ matches
+func exactValue(pattern string) (string, bool) {
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	parsed = parsed.Simplify()
+	if parsed.Op != syntax.OpConcat || len(parsed.Sub) != 3 {
+		return "", false
+	}
+	begin, lit, end := parsed.Sub[0], parsed.Sub[1], parsed.Sub[2]
+	if begin.Op != syntax.OpBeginText || end.Op != syntax.OpEndText || lit.Op != syntax.OpLiteral || lit.Flags&syntax.FoldCase != 0 {
+		return "", false
+	}
+	return string(lit.Rune), true
+}
@@ -0,0 +1,116 @@
+// ABOUTME: Tests for routing config linting.
+// ABOUTME: Covers collected rule errors, duplicate priorities, and shadowed-rule detection.
+
+package routing
+
+import (
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v2"
+)
+
+func lint(t *testing.T, config string) []string {
+	t.Helper()
+	var fc FileConfig
+	if err := yaml.UnmarshalStrict([]byte(config), &fc); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range fc.Lint() {
+		got = append(got, f.String())
+	}
+	return got
+}
+
+func TestLint_ReportsEveryInvalidRule(t *testing.T) {
+	got := lint(t, `
+rules:
+  - {name: broken, conditions: {cf_app_name: "^(unclosed"}, index: tas_x}
+  - {name: fine, conditions: {cf_app_name: ok}, index: tas_ok}
+  - {name: warnings, conditions: {_severity: warning}, index: tas_warn}
+  - {name: fine, conditions: {cf_app_name: again}, index: tas_again}
+  - {conditions: {cf_app_name: x}, index: tas_x}
+`)
+	want := []string{
+		`rule "broken": error: conditions.cf_app_name`,
+		`rule "warnings": error: conditions._severity`,
+		`rule "fine": error: duplicate rule name`,
+		`error: rules[4]: name is required`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("findings = %q, want %d", got, len(want))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("finding %d = %q, want prefix %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLint_ConfigErrors(t *testing.T) {
+	got := lint(t, `default_split: {index: tas_logs, percent: 10}`)
+	if len(got) != 1 || got[0] != "error: default_split.index must differ from the default index" {
+		t.Errorf("findings = %q", got)
+	}
+}
+
+func TestLint_DuplicatePriorities(t *testing.T) {
+	got := lint(t, `
+rules:
+  - {name: payments, conditions: {cf_app_name: ^payments-}, index: tas_payments, priority: 2}
+  - {name: errors, conditions: {_severity: error}, index: tas_errors, priority: 1}
+  - {name: staging, conditions: {cf_space_name: ^staging$}, index: tas_staging, priority: 2}
+  - {name: audit, conditions: {cf_app_name: ^audit-}, index: tas_audit}
+  - {name: security, conditions: {cf_app_name: ^security-}, index: tas_security}
+`)
+	want := `rule "staging": warning: shares priority 2 with "payments", so file order decides which is evaluated first`
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("findings = %q, want [%q]", got, want)
+	}
+}
+
+func TestLint_ShadowedRules(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		first, second string
+		shadowed      bool
+	}{
+		{"same condition", `{cf_app_name: ^api$}`, `{cf_app_name: ^api$, cf_space_name: prod}`, true},
+		{"exact value matched", `{cf_space_name: prod}`, `{cf_space_name: ^prod$}`, true},
+		{"exact value not matched", `{cf_space_name: ^prod}`, `{cf_space_name: ^staging$}`, false},
+		{"match-anything pattern", `{cf_app_name: ".*"}`, `{cf_app_name: ^api-}`, true},
+		{"anchored pattern", `{cf_app_name: ^a*$}`, `{cf_app_name: ^api-}`, false},
+		{"wider severity", `{_severity: warn}`, `{_severity: error}`, true},
+		{"narrower severity", `{_severity: error}`, `{_severity: warn}`, false},
+		{"wider numeric", `{status: ">=500"}`, `{status: "500..599"}`, true},
+		{"open bound", `{status: ">500"}`, `{status: "500..599"}`, false},
+		{"numeric vs regex", `{status: ">=500"}`, `{status: ^5}`, false},
+		{"extra condition first", `{cf_app_name: ^api$, cf_space_name: prod}`, `{cf_app_name: ^api$}`, false},
+		{"unrelated", `{cf_app_name: ^api$}`, `{cf_space_name: ^prod$}`, false},
+	} {
+		got := lint(t, `
+rules:
+  - {name: first, conditions: `+tc.first+`, index: tas_first, priority: 1}
+  - {name: second, conditions: `+tc.second+`, index: tas_second, priority: 2}
+`)
+		shadowed := len(got) == 1 && strings.HasPrefix(got[0], `rule "second": warning: unreachable: "first" (priority 1)`)
+		if shadowed != tc.shadowed || (!shadowed && len(got) > 0) {
+			t.Errorf("%s: findings = %q, want shadowed %v", tc.name, got, tc.shadowed)
+		}
+	}
+}
+
+func TestLint_ContinueAndNotDoNotShadow(t *testing.T) {
+	got := lint(t, `
+rules:
+  - {name: archive, conditions: {cf_app_name: ^api$}, index: tas_archive, continue: true, priority: 1}
+  - {name: nonprod, conditions: {cf_app_name: ^api$}, not: {cf_space_name: ^prod$}, index: tas_nonprod, priority: 2}
+  - {name: api, conditions: {cf_app_name: ^api$}, index: tas_api, priority: 3}
+  - {name: api-nonprod, conditions: {cf_app_name: ^api$}, not: {cf_space_name: ^prod$}, index: tas_x, priority: 4}
+`)
+	want := `rule "api-nonprod": warning: unreachable: "nonprod" (priority 2) comes first and matches every record this rule would`
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("findings = %q, want [%q]", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+// ABOUTME: Numeric comparison conditions for routing rules, e.g. >=500 or 200..299.
+// ABOUTME: Compares int, double, and numeric string attribute values against a bound or inclusive range.
+
+package routing
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// numericOps are the comparison prefixes, longest first so >= wins over >
+var numericOps = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// numericCondition compares an attribute's value with Value, or for the ".."
+// op checks it falls in Value..High inclusive
+type numericCondition struct {
+	Op    string
+	Value float64
+	High  float64
+}
+
+// parseNumericCondition recognizes a comparison with a number (>=500, >2000,
+// <=1.5, ==200, !=404) or a numeric range (500..599). Anything else is not
+// numeric and is treated as a regex.
+func parseNumericCondition(cond string) (numericCondition, bool) {
+	cond = strings.TrimSpace(cond)
+	if lo, hi, ok := strings.Cut(cond, ".."); ok {
+		low, errLow := parseNumber(lo)
+		high, errHigh := parseNumber(hi)
+		if errLow != nil || errHigh != nil || low > high {
+			return numericCondition{}, false
+		}
+		return numericCondition{Op: "..", Value: low, High: high}, true
+	}
+	for _, op := range numericOps {
+		rest, ok := strings.CutPrefix(cond, op)
+		if !ok {
+			continue
+		}
+		v, err := parseNumber(rest)
+		if err != nil {
+			return numericCondition{}, false
+		}
+		return numericCondition{Op: op, Value: v}, true
+	}
+	return numericCondition{}, false
+}
+
+// parseNumber parses a finite decimal number
+func parseNumber(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err == nil && (math.IsNaN(v) || math.IsInf(v, 0)) {
+		return 0, strconv.ErrRange
+	}
+	return v, err
+}
+
+// holds reports whether v satisfies the comparison
+func (nc numericCondition) holds(v float64) bool {
+	switch nc.Op {
+	case ">=":
+		return v >= nc.Value
+	case "<=":
+		return v <= nc.Value
+	case "==":
+		return v == nc.Value
+	case "!=":
+		return v != nc.Value
+	case ">":
+		return v > nc.Value
+	case "<":
+		return v < nc.Value
+	default:
+		return nc.Value <= v && v <= nc.High
+	}
+}
+
+// matching returns a value satisfying the comparison, on or just past its bound
+func (nc numericCondition) matching() float64 {
+	switch nc.Op {
+	case ">", "!=":
+		return nc.Value + 1
+	case "<":
+		return nc.Value - 1
+	default:
+		return nc.Value
+	}
+}
+
+// failing returns a value just outside the comparison
+func (nc numericCondition) failing() float64 {
+	switch nc.Op {
+	case ">=", "..":
+		return nc.Value - 1
+	case "<=", "==":
+		return nc.Value + 1
+	default:
+		return nc.Value
+	}
+}
+
+// covers reports whether every value satisfying other satisfies nc
+func (nc numericCondition) covers(other numericCondition) bool {
+	if nc.Op == "!=" {
+		return (other.Op == "!=" && other.Value == nc.Value) || (other.Op != "!=" && !other.holds(nc.Value))
+	}
+	if other.Op == "!=" {
+		return false
+	}
+	lo, loOpen, hi, hiOpen := nc.bounds()
+	otherLo, otherLoOpen, otherHi, otherHiOpen := other.bounds()
+	return (lo < otherLo || (lo == otherLo && (!loOpen || otherLoOpen))) &&
+		(otherHi < hi || (otherHi == hi && (!hiOpen || otherHiOpen)))
+}
+
+// bounds returns the interval a comparison other than != accepts, and whether each end is open
+func (nc numericCondition) bounds() (lo float64, loOpen bool, hi float64, hiOpen bool) {
+	switch nc.Op {
+	case ">=":
+		return nc.Value, false, math.Inf(1), true
+	case ">":
+		return nc.Value, true, math.Inf(1), true
+	case "<=":
+		return math.Inf(-1), true, nc.Value, false
+	case "<":
+		return math.Inf(-1), true, nc.Value, true
+	case "==":
+		return nc.Value, false, nc.Value, false
+	default:
+		return nc.Value, false, nc.High, false
+	}
+}
+
+// formatNumber formats a number the way it would be written in a condition
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// getNumericValue retrieves an int, double, or numeric string attribute value by key
+func getNumericValue(lr *logspb.LogRecord, key string) (float64, bool) {
+	for _, attr := range lr.GetAttributes() {
+		if attr.GetKey() != key {
+			continue
+		}
+		switch v := attr.GetValue().GetValue().(type) {
+		case *commonpb.AnyValue_IntValue:
+			return float64(v.IntValue), true
+		case *commonpb.AnyValue_DoubleValue:
+			return v.DoubleValue, !math.IsNaN(v.DoubleValue)
+		case *commonpb.AnyValue_StringValue:
+			n, err := parseNumber(v.StringValue)
+			return n, err == nil
+		}
+		return 0, false
+	}
+	return 0, false
+}
@@ -0,0 +1,104 @@
+// ABOUTME: Tests for numeric routing conditions.
+// ABOUTME: Covers each comparison, ranges, value types, regex fallback, and generated fixtures.
+
+package routing
+
+import (
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func numericRecord(key string, value *commonpb.AnyValue) *logspb.LogRecord {
+	return &logspb.LogRecord{Attributes: []*commonpb.KeyValue{{Key: key, Value: value}}}
+}
+
+func intValue(v int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+}
+
+func TestNumericCondition(t *testing.T) {
+	for _, tc := range []struct {
+		cond  string
+		value float64
+		want  bool
+	}{
+		{">=500", 500, true},
+		{">=500", 499, false},
+		{">2000", 2000, false},
+		{">2000", 2000.5, true},
+		{"<=1.5", 1.5, true},
+		{"<100", 100, false},
+		{"==200", 200, true},
+		{"!=200", 200, false},
+		{"!=200", 404, true},
+		{"500..599", 599, true},
+		{"500..599", 600, false},
+		{" >= -1 ", -1, true},
+	} {
+		nc, ok := parseNumericCondition(tc.cond)
+		if !ok {
+			t.Errorf("%q: not numeric", tc.cond)
+			continue
+		}
+		if got := nc.holds(tc.value); got != tc.want {
+			t.Errorf("%q holds(%v) = %v, want %v", tc.cond, tc.value, got, tc.want)
+		}
+		if !nc.holds(nc.matching()) || nc.holds(nc.failing()) {
+			t.Errorf("%q: matching %v or failing %v is wrong", tc.cond, nc.matching(), nc.failing())
+		}
+	}
+}
+
+func TestNumericCondition_RegexFallback(t *testing.T) {
+	for _, cond := range []string{"^5", "<html>", ">=warn", "599..500", "1..x", "==NaN", ">Inf", "[>]=500"} {
+		if nc, ok := parseNumericCondition(cond); ok {
+			t.Errorf("%q parsed as numeric %+v", cond, nc)
+		}
+	}
+}
+
+func TestRouter_NumericConditions(t *testing.T) {
+	router := NewRouter([]RoutingRule{
+		{Name: "server-errors", Conditions: map[string]string{"http.status_code": ">=500"}, Index: "tas_5xx", Priority: 1},
+		{Name: "slow", Conditions: map[string]string{"response_time_ms": ">2000"}, Index: "tas_slow", Priority: 2},
+	})
+
+	for _, tc := range []struct {
+		name string
+		lr   *logspb.LogRecord
+		want string
+	}{
+		{"int", numericRecord("http.status_code", intValue(503)), "tas_5xx"},
+		{"int below", numericRecord("http.status_code", intValue(404)), "tas_logs"},
+		{"string", makeLogRecord(0, map[string]string{"http.status_code": "502"}), "tas_5xx"},
+		{"non-numeric string", makeLogRecord(0, map[string]string{"http.status_code": "5xx"}), "tas_logs"},
+		{"double", numericRecord("response_time_ms", &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 2000.1}}), "tas_slow"},
+		{"bool", numericRecord("response_time_ms", &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}), "tas_logs"},
+		{"missing", makeLogRecord(0, nil), "tas_logs"},
+	} {
+		if index, _ := route(router, tc.lr); index != tc.want {
+			t.Errorf("%s: routed to %s, want %s", tc.name, index, tc.want)
+		}
+	}
+}
+
+func TestGenerateFixtures_Numeric(t *testing.T) {
+	router := NewRouter([]RoutingRule{
+		{Name: "slow", Conditions: map[string]string{"response_time_ms": ">2000"}, Not: map[string]string{"http.status_code": "500..599"}, Index: "tas_slow"},
+	})
+	got := map[string]string{}
+	for _, f := range router.GenerateFixtures() {
+		got[f.Name] = f.Attributes["response_time_ms"] + "/" + f.Attributes["http.status_code"] + " " + f.Expected.Rule
+	}
+	for name, want := range map[string]string{
+		"slow/match":                          "2001/ slow",
+		"slow/near-miss/response_time_ms":     "2000/ default",
+		"slow/near-miss/not.http.status_code": "2001/500 default",
+	} {
+		if got[name] != want {
+			t.Errorf("%s = %q, want %q", name, got[name], want)
+		}
+	}
+}
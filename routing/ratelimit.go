@@ -0,0 +1,50 @@
+// ABOUTME: Per-rule rate limits that spill matches over the limit to an overflow index.
+// ABOUTME: Models index-level quota protection with a token bucket per rule.
+
+package routing
+
+import (
+	"fmt"
+
+	"otlp-mock-receiver/sampling"
+)
+
+// DefaultOverflowIndex is where records over a rule's rate limit go unless it names another
+const DefaultOverflowIndex = "tas_overflow"
+
+// RateLimit caps how many records per second a rule sends to its index
+type RateLimit struct {
+	PerSecond     float64 `yaml:"per_second" json:"per_second"`                             // Matches per second the rule's index accepts; bursts of up to one second's worth pass
+	OverflowIndex string  `yaml:"overflow_index,omitempty" json:"overflow_index,omitempty"` // Where matches over the limit go, DefaultOverflowIndex if empty; DropIndex discards them
+}
+
+// Validate checks the limit is positive
+func (rl *RateLimit) Validate() error {
+	if rl.PerSecond <= 0 {
+		return fmt.Errorf("rate_limit.per_second %v must be above 0", rl.PerSecond)
+	}
+	return nil
+}
+
+// Overflow returns the index records over the limit go to
+func (rl *RateLimit) Overflow() string {
+	if rl.OverflowIndex == "" {
+		return DefaultOverflowIndex
+	}
+	return rl.OverflowIndex
+}
+
+// limiter is a rule's token bucket
+type limiter struct {
+	budget   *sampling.Budget
+	overflow string
+}
+
+func newLimiter(rl *RateLimit) *limiter {
+	return &limiter{budget: sampling.NewBudget(rl.PerSecond), overflow: rl.Overflow()}
+}
+
+// allow spends a token if one is available, reporting whether the record is within the limit
+func (l *limiter) allow() bool {
+	return l.budget.Allow("")
+}
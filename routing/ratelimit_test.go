@@ -0,0 +1,66 @@
+// ABOUTME: Tests for per-rule rate limits.
+// ABOUTME: Covers spilling to the overflow index, dry runs leaving the limit alone, and config round trips.
+
+package routing
+
+import (
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestRouter_RateLimit(t *testing.T) {
+	router := NewRouter([]RoutingRule{
+		{Name: "errors", Conditions: map[string]string{"_severity": "error"}, Index: "tas_errors", Priority: 2, RateLimit: &RateLimit{PerSecond: 2}},
+		{Name: "archive", Index: "tas_archive", Priority: 1, Continue: true, RateLimit: &RateLimit{PerSecond: 1, OverflowIndex: "tas_archive_spill"}},
+	})
+	lr := makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, nil)
+
+	// Dry runs spend nothing, so the full burst is still available afterwards
+	for range 5 {
+		if d := Primary(router.DryRoute(nil, lr)); d.Index != "tas_errors" || d.Overflow {
+			t.Fatalf("dry run routed to %+v", d)
+		}
+	}
+
+	var got []string
+	for range 4 {
+		for _, d := range router.Route(lr) {
+			if d.Overflow {
+				got = append(got, d.Rule+":"+d.Index)
+			}
+		}
+	}
+	// errors allows a burst of 2, archive of 1; the record is archived first
+	want := []string{"archive:tas_archive_spill", "archive:tas_archive_spill", "errors:tas_overflow", "archive:tas_archive_spill", "errors:tas_overflow"}
+	if len(got) != len(want) {
+		t.Fatalf("overflow = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("overflow = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestRouter_RateLimitConfigRoundTrip(t *testing.T) {
+	path := writeConfig(t, "routes.yaml", `
+rules:
+  - name: errors
+    conditions: {_severity: error}
+    index: tas_errors
+    rate_limit: {per_second: 50, overflow_index: _drop}
+`)
+	router, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := router.Config().Rules[0].RateLimit
+	if rl == nil || rl.PerSecond != 50 || rl.Overflow() != DropIndex {
+		t.Errorf("rate limit = %+v", rl)
+	}
+	if (&RateLimit{PerSecond: 1}).Overflow() != DefaultOverflowIndex {
+		t.Error("overflow should default to DefaultOverflowIndex")
+	}
+}
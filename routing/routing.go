@@ -1,35 +1,56 @@
 // ABOUTME: Log routing logic for determining Splunk index destination.
-// ABOUTME: Uses configurable rules with regex matching and priority ordering.
+// ABOUTME: Uses configurable rules with regex and severity conditions and priority ordering.
 
 package routing
 
 import (
-	"regexp"
+	"fmt"
+	"slices"
 	"sort"
 
 	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 )
 
 // RoutingRule defines a single routing rule (for configuration)
 type RoutingRule struct {
-	Name       string            // Rule name for logging
-	Conditions map[string]string // Attribute name → regex pattern
-	Index      string            // Target Splunk index
-	Priority   int               // Lower = higher priority
+	Name       string              // Rule name for logging
+	Conditions map[string]string   // Attribute name → regex pattern, all must match
+	Not        map[string]string   // Attribute name → regex pattern, none may match
+	Any        []map[string]string // OR groups: if set, at least one must match entirely
+	Index      string              // Target Splunk index
+	Priority   int                 // Lower = higher priority
+	Continue   bool                // Keep evaluating later rules after a match, copying the record to each
+	Sourcetype string              // Splunk sourcetype for matched records, if set
+	Source     string              // Splunk source for matched records, if set
+	Split      *Split              // Sends a share of matched records to an alternate index
+	RateLimit  *RateLimit          // Sends matches over a records-per-second cap to an overflow index
+	Window     *TimeWindow         // If set, the rule only matches records timestamped within it
+	Expr       string              // CEL expression that must also hold, if set
 }
 
-// compiledRule is a routing rule with pre-compiled regexes
-type compiledRule struct {
-	Name       string
-	Conditions map[string]*regexp.Regexp // Pre-compiled patterns
-	Index      string
-	Priority   int
+// builtinDefaultIndex is where unmatched records go unless a default index is set
+const builtinDefaultIndex = "tas_logs"
+
+// DropIndex is the destination that discards a record instead of delivering it
+const DropIndex = "_drop"
+
+// Destination is an index a record is routed to, the rule that sent it there,
+// and the Splunk sourcetype and source that rule sets
+type Destination struct {
+	Index      string `json:"index"`
+	Rule       string `json:"rule"`
+	Sourcetype string `json:"sourcetype,omitempty"`
+	Source     string `json:"source,omitempty"`
+	Overflow   bool   `json:"overflow,omitempty"` // Over the rule's rate limit, so sent to its overflow index
 }
 
 // Router holds routing rules and applies them to logs
 type Router struct {
 	rules        []compiledRule
+	config       []RoutingRule // Rules as configured, in priority order
 	defaultIndex string
+	defaultSplit *Split
 }
 
 // NewRouter creates a router with custom rules, pre-compiling regex patterns
@@ -37,35 +58,47 @@ func NewRouter(rules []RoutingRule) *Router {
 	// Sort rules by priority (lower = higher priority)
 	sorted := make([]RoutingRule, len(rules))
 	copy(sorted, rules)
-	sort.Slice(sorted, func(i, j int) bool {
+	sort.SliceStable(sorted, func(i, j int) bool {
 		return sorted[i].Priority < sorted[j].Priority
 	})
 
-	// Compile all regex patterns
+	// Compile all conditions
 	compiled := make([]compiledRule, len(sorted))
 	for i, rule := range sorted {
-		compiled[i] = compiledRule{
-			Name:       rule.Name,
-			Conditions: make(map[string]*regexp.Regexp),
-			Index:      rule.Index,
-			Priority:   rule.Priority,
-		}
-		for attr, pattern := range rule.Conditions {
-			// Severity patterns are not regexes, store nil
-			if attr == "_severity" {
-				compiled[i].Conditions[attr] = nil
-				continue
-			}
-			compiled[i].Conditions[attr] = regexp.MustCompile(pattern)
+		cr, err := compileRule(rule)
+		if err != nil {
+			panic(fmt.Sprintf("routing rule %q: %v", rule.Name, err))
 		}
+		compiled[i] = cr
 	}
 
 	return &Router{
 		rules:        compiled,
-		defaultIndex: "tas_logs",
+		config:       sorted,
+		defaultIndex: builtinDefaultIndex,
 	}
 }
 
+// SetDefaultIndex sets where records no final rule matched go; DropIndex discards them
+func (r *Router) SetDefaultIndex(index string) {
+	r.defaultIndex = index
+}
+
+// SetDefaultSplit sends a share of the records no final rule matched to another index
+func (r *Router) SetDefaultSplit(split *Split) {
+	r.defaultSplit = split
+}
+
+// DefaultIndex returns where records no final rule matched go
+func (r *Router) DefaultIndex() string {
+	return r.defaultIndex
+}
+
+// Rules returns the router's rules in priority order
+func (r *Router) Rules() []RoutingRule {
+	return r.config
+}
+
 // DefaultRouter creates a router with the default TAS routing rules
 func DefaultRouter() *Router {
 	return NewRouter([]RoutingRule{
@@ -96,57 +129,63 @@ func DefaultRouter() *Router {
 	})
 }
 
-// Route determines which index a log should be sent to.
-// Returns the index name and the rule name that matched.
-func (r *Router) Route(lr *logspb.LogRecord) (index string, ruleName string) {
-	for _, rule := range r.rules {
-		if r.matchesRule(lr, rule) {
-			return rule.Index, rule.Name
-		}
-	}
-	return r.defaultIndex, "default"
+// Route determines which indexes a log should be sent to, in rule order. The
+// last destination is the first matching rule without Continue, or the default
+// index; any before it are copies from matching Continue rules. A record reaches
+// each index at most once.
+func (r *Router) Route(lr *logspb.LogRecord) []Destination {
+	return r.RouteRecord(nil, lr)
+}
+
+// RouteRecord routes a record with the resource it belongs to, which CEL
+// expressions can read. Route is RouteRecord without a resource. Each match
+// counts against its rule's rate limit.
+func (r *Router) RouteRecord(resource *resourcepb.Resource, lr *logspb.LogRecord) []Destination {
+	return r.route(resource, lr, true)
 }
 
-// matchesRule checks if a log matches all conditions of a rule
-func (r *Router) matchesRule(lr *logspb.LogRecord, rule compiledRule) bool {
-	for attrName, compiledPattern := range rule.Conditions {
-		// Special handling for severity (stored as nil pattern)
-		if attrName == "_severity" {
-			if !r.matchesSeverity(lr, "error") { // severity rules always check for error+
-				return false
-			}
+// DryRoute is RouteRecord for records that are not being delivered: it leaves
+// rate limits untouched and routes as if every rule were within its limit
+func (r *Router) DryRoute(resource *resourcepb.Resource, lr *logspb.LogRecord) []Destination {
+	return r.route(resource, lr, false)
+}
+
+func (r *Router) route(resource *resourcepb.Resource, lr *logspb.LogRecord, limit bool) []Destination {
+	final := Destination{Index: r.defaultIndex, Rule: "default"}
+	if r.defaultSplit != nil && r.defaultSplit.Takes(lr) {
+		final.Index = r.defaultSplit.Index
+	}
+	var copies []Destination
+	for _, rule := range r.rules {
+		if !rule.matches(resource, lr) {
 			continue
 		}
-
-		// Regular attribute matching with pre-compiled regex
-		value := getAttributeValue(lr, attrName)
-		if value == "" {
-			return false
+		dest := rule.destination(lr, limit)
+		if !rule.Continue {
+			final = dest
+			break
 		}
-
-		if !compiledPattern.MatchString(value) {
-			return false
+		if !slices.ContainsFunc(copies, func(d Destination) bool { return d.Index == dest.Index }) {
+			copies = append(copies, dest)
 		}
 	}
-	return true
-}
-
-// matchesSeverity checks if the log severity matches the pattern
-func (r *Router) matchesSeverity(lr *logspb.LogRecord, pattern string) bool {
-	severity := lr.GetSeverityNumber()
-
-	switch pattern {
-	case "error":
-		return severity >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
-	case "warn":
-		return severity >= logspb.SeverityNumber_SEVERITY_NUMBER_WARN
-	case "info":
-		return severity >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO
-	case "debug":
-		return severity >= logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
-	default:
-		return false
+	copies = slices.DeleteFunc(copies, func(d Destination) bool { return d.Index == final.Index })
+	return append(copies, final)
+}
+
+// Primary returns the final destination of a Route result
+func Primary(dests []Destination) Destination {
+	return dests[len(dests)-1]
+}
+
+// Deliverable returns the destinations of a Route result that receive the
+// record: all of them unless the final one is DropIndex, in which case only
+// the copies. Empty means the record is dropped.
+func Deliverable(dests []Destination) []Destination {
+	if Primary(dests).Index == DropIndex {
+		return dests[:len(dests)-1]
 	}
+	return dests
 }
 
 // getAttributeValue retrieves a string attribute value by key
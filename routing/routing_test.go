@@ -4,10 +4,12 @@
 package routing
 
 import (
+	"slices"
 	"testing"
 
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 )
 
 // Helper to create a log record with severity and attributes
@@ -26,6 +28,12 @@ func makeLogRecord(severity logspb.SeverityNumber, attrs map[string]string) *log
 	return lr
 }
 
+// route returns the final index and rule a record is routed to
+func route(r *Router, lr *logspb.LogRecord) (string, string) {
+	d := Primary(r.Route(lr))
+	return d.Index, d.Rule
+}
+
 func TestRouter_ErrorSeverityRoutesToTasErrors(t *testing.T) {
 	router := DefaultRouter()
 
@@ -33,7 +41,7 @@ func TestRouter_ErrorSeverityRoutesToTasErrors(t *testing.T) {
 		"cf_app_name": "my-app",
 	})
 
-	index, rule := router.Route(lr)
+	index, rule := route(router, lr)
 
 	if index != "tas_errors" {
 		t.Errorf("ERROR severity should route to tas_errors, got %q", index)
@@ -50,7 +58,7 @@ func TestRouter_FatalSeverityRoutesToTasErrors(t *testing.T) {
 		"cf_app_name": "my-app",
 	})
 
-	index, rule := router.Route(lr)
+	index, rule := route(router, lr)
 
 	if index != "tas_errors" {
 		t.Errorf("FATAL severity should route to tas_errors, got %q", index)
@@ -67,7 +75,7 @@ func TestRouter_SecurityAppRoutesToTasSecurity(t *testing.T) {
 		"cf_app_name": "security-scanner",
 	})
 
-	index, rule := router.Route(lr)
+	index, rule := route(router, lr)
 
 	if index != "tas_security" {
 		t.Errorf("security-* apps should route to tas_security, got %q", index)
@@ -84,7 +92,7 @@ func TestRouter_AuditAppRoutesToTasAudit(t *testing.T) {
 		"cf_app_name": "audit-logger",
 	})
 
-	index, rule := router.Route(lr)
+	index, rule := route(router, lr)
 
 	if index != "tas_audit" {
 		t.Errorf("audit-* apps should route to tas_audit, got %q", index)
@@ -102,7 +110,7 @@ func TestRouter_ProductionSpaceRoutesToTasProd(t *testing.T) {
 		"cf_space_name": "production",
 	})
 
-	index, rule := router.Route(lr)
+	index, rule := route(router, lr)
 
 	if index != "tas_prod" {
 		t.Errorf("production space should route to tas_prod, got %q", index)
@@ -120,7 +128,7 @@ func TestRouter_DefaultFallback(t *testing.T) {
 		"cf_space_name": "development",
 	})
 
-	index, rule := router.Route(lr)
+	index, rule := route(router, lr)
 
 	if index != "tas_logs" {
 		t.Errorf("default should route to tas_logs, got %q", index)
@@ -138,7 +146,7 @@ func TestRouter_PriorityErrorBeforeSecurityApp(t *testing.T) {
 		"cf_app_name": "security-scanner",
 	})
 
-	index, rule := router.Route(lr)
+	index, rule := route(router, lr)
 
 	if index != "tas_errors" {
 		t.Errorf("ERROR from security app should route to tas_errors (priority), got %q", index)
@@ -157,7 +165,7 @@ func TestRouter_PrioritySecurityBeforeProduction(t *testing.T) {
 		"cf_space_name": "production",
 	})
 
-	index, rule := router.Route(lr)
+	index, rule := route(router, lr)
 
 	if index != "tas_security" {
 		t.Errorf("security app in production should route to tas_security (priority), got %q", index)
@@ -181,7 +189,7 @@ func TestRouter_CustomRules(t *testing.T) {
 		"cf_app_name": "custom-app",
 	})
 
-	index, rule := router.Route(lr)
+	index, rule := route(router, lr)
 
 	if index != "custom_index" {
 		t.Errorf("custom rule should route to custom_index, got %q", index)
@@ -190,3 +198,69 @@ func TestRouter_CustomRules(t *testing.T) {
 		t.Errorf("expected rule 'custom-rule', got %q", rule)
 	}
 }
+
+func TestRouter_ContinueCopiesRecord(t *testing.T) {
+	router := NewRouter([]RoutingRule{
+		{Name: "archive", Index: "tas_archive", Priority: 0, Continue: true},
+		{Name: "audit-copy", Conditions: map[string]string{"cf_app_name": "^audit-"}, Index: "tas_audit", Priority: 1, Continue: true},
+		{Name: "errors", Conditions: map[string]string{"_severity": "error"}, Index: "tas_errors", Priority: 2},
+		{Name: "audit-errors", Conditions: map[string]string{"cf_app_name": "^audit-"}, Index: "tas_audit", Priority: 3},
+	})
+
+	for _, tc := range []struct {
+		sev  logspb.SeverityNumber
+		app  string
+		want []Destination
+	}{
+		{logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "web", []Destination{{Index: "tas_archive", Rule: "archive"}, {Index: "tas_logs", Rule: "default"}}},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "web", []Destination{{Index: "tas_archive", Rule: "archive"}, {Index: "tas_errors", Rule: "errors"}}},
+		{logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "audit-log", []Destination{{Index: "tas_archive", Rule: "archive"}, {Index: "tas_audit", Rule: "audit-copy"}, {Index: "tas_errors", Rule: "errors"}}},
+		// The final rule's index is not delivered twice, and stays last
+		{logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "audit-log", []Destination{{Index: "tas_archive", Rule: "archive"}, {Index: "tas_audit", Rule: "audit-errors"}}},
+	} {
+		got := router.Route(makeLogRecord(tc.sev, map[string]string{"cf_app_name": tc.app}))
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s %s routed to %v, want %v", severityName(tc.sev), tc.app, got, tc.want)
+		}
+	}
+}
+
+func TestDeliverable(t *testing.T) {
+	archive := Destination{Index: "tas_archive", Rule: "archive"}
+	dropped := Destination{Index: DropIndex, Rule: "noise"}
+	logs := Destination{Index: "tas_logs", Rule: "default"}
+
+	if got := Deliverable([]Destination{archive, logs}); !slices.Equal(got, []Destination{archive, logs}) {
+		t.Errorf("Deliverable without _drop = %v", got)
+	}
+	if got := Deliverable([]Destination{archive, dropped}); !slices.Equal(got, []Destination{archive}) {
+		t.Errorf("Deliverable with copies = %v, want only the copy", got)
+	}
+	if got := Deliverable([]Destination{dropped}); len(got) != 0 {
+		t.Errorf("Deliverable of a dropped record = %v, want none", got)
+	}
+}
+
+func TestRouteRecord_Expr(t *testing.T) {
+	router := NewRouter([]RoutingRule{{
+		Name:       "prod-5xx",
+		Conditions: map[string]string{"cf_app_name": "^payments-"},
+		Expr:       `resource.cf_space_name == "production" && int(attributes.status) >= 500`,
+		Index:      "tas_prod_errors",
+	}})
+	prod := &resourcepb.Resource{Attributes: makeLogRecord(0, map[string]string{"cf_space_name": "production"}).Attributes}
+
+	lr := makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_app_name": "payments-api", "status": "503"})
+	if got := Primary(router.RouteRecord(prod, lr)); got.Rule != "prod-5xx" {
+		t.Errorf("prod 503 routed by %s, want prod-5xx", got.Rule)
+	}
+	// Route has no resource, so the expression's missing key fails the rule
+	if got := Primary(router.Route(lr)); got.Rule != "default" {
+		t.Errorf("without resource routed by %s, want default", got.Rule)
+	}
+
+	lr = makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_app_name": "payments-api", "status": "200"})
+	if got := Primary(router.RouteRecord(prod, lr)); got.Rule != "default" {
+		t.Errorf("prod 200 routed by %s, want default", got.Rule)
+	}
+}
@@ -0,0 +1,117 @@
+// ABOUTME: Severity conditions for routing rules.
+// ABOUTME: Parses levels, comparisons, exact matches, and ranges into an inclusive severity number range.
+
+package routing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+const (
+	minSeverity = logspb.SeverityNumber_SEVERITY_NUMBER_TRACE
+	maxSeverity = logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4
+)
+
+// severityLevelStarts maps level names to the first severity number of the level
+var severityLevelStarts = map[string]logspb.SeverityNumber{
+	"trace": logspb.SeverityNumber_SEVERITY_NUMBER_TRACE,
+	"debug": logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	"info":  logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	"warn":  logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	"error": logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	"fatal": logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+}
+
+// severityRange is the inclusive range of severity numbers a condition matches
+type severityRange struct {
+	Min, Max logspb.SeverityNumber
+}
+
+// Contains reports whether sev falls in the range
+func (r severityRange) Contains(sev logspb.SeverityNumber) bool {
+	return r.Min <= sev && sev <= r.Max
+}
+
+// parseSeverityCondition parses a _severity condition:
+//
+//	error         error and above (same as >=error)
+//	>=warn, >warn, <=info, <info
+//	==debug       DEBUG through DEBUG4 only
+//	info..warn    INFO through WARN4
+//
+// Levels are trace, debug, info, warn, error, fatal, or a severity number 1-24
+// standing for itself alone.
+func parseSeverityCondition(cond string) (severityRange, error) {
+	cond = strings.ToLower(strings.TrimSpace(cond))
+
+	if lo, hi, ok := strings.Cut(cond, ".."); ok {
+		from, err := parseSeverityLevel(lo)
+		if err != nil {
+			return severityRange{}, err
+		}
+		to, err := parseSeverityLevel(hi)
+		if err != nil {
+			return severityRange{}, err
+		}
+		if from.Min > to.Max {
+			return severityRange{}, fmt.Errorf("range %q is empty", cond)
+		}
+		return severityRange{from.Min, to.Max}, nil
+	}
+
+	for _, op := range []string{">=", "<=", "==", ">", "<"} {
+		rest, ok := strings.CutPrefix(cond, op)
+		if !ok {
+			continue
+		}
+		level, err := parseSeverityLevel(rest)
+		if err != nil {
+			return severityRange{}, err
+		}
+		var r severityRange
+		switch op {
+		case ">=":
+			r = severityRange{level.Min, maxSeverity}
+		case "<=":
+			r = severityRange{minSeverity, level.Max}
+		case "==":
+			r = level
+		case ">":
+			r = severityRange{level.Max + 1, maxSeverity}
+		case "<":
+			r = severityRange{minSeverity, level.Min - 1}
+		}
+		if r.Min > r.Max {
+			return severityRange{}, fmt.Errorf("%q matches no severity", cond)
+		}
+		return r, nil
+	}
+
+	level, err := parseSeverityLevel(cond)
+	if err != nil {
+		return severityRange{}, err
+	}
+	return severityRange{level.Min, maxSeverity}, nil
+}
+
+// parseSeverityLevel parses a level name into its four severity numbers, or a
+// number into itself
+func parseSeverityLevel(s string) (severityRange, error) {
+	s = strings.TrimSpace(s)
+	if start, ok := severityLevelStarts[s]; ok {
+		return severityRange{start, start + 3}, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= int(minSeverity) && n <= int(maxSeverity) {
+		return severityRange{logspb.SeverityNumber(n), logspb.SeverityNumber(n)}, nil
+	}
+	return severityRange{}, fmt.Errorf("unknown severity %q (want trace, debug, info, warn, error, fatal, or 1-24)", s)
+}
+
+// severityName returns the short name of a severity number, e.g. WARN2
+func severityName(sev logspb.SeverityNumber) string {
+	return strings.TrimPrefix(sev.String(), "SEVERITY_NUMBER_")
+}
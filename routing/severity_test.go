@@ -0,0 +1,79 @@
+// ABOUTME: Tests for routing severity conditions.
+// ABOUTME: Covers bare levels, comparisons, exact matches, ranges, numbers, and invalid conditions.
+
+package routing
+
+import (
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestParseSeverityCondition(t *testing.T) {
+	for _, tc := range []struct {
+		cond     string
+		min, max logspb.SeverityNumber
+	}{
+		{"error", 17, 24},
+		{">=warn", 13, 24},
+		{">warn", 17, 24},
+		{"<=info", 1, 12},
+		{"<info", 1, 8},
+		{"==debug", 5, 8},
+		{"info..warn", 9, 16},
+		{" INFO .. Warn ", 9, 16},
+		{"==18", 18, 18},
+		{">=14", 14, 24},
+		{"trace..fatal", 1, 24},
+	} {
+		got, err := parseSeverityCondition(tc.cond)
+		if err != nil {
+			t.Errorf("%q: %v", tc.cond, err)
+			continue
+		}
+		if got.Min != tc.min || got.Max != tc.max {
+			t.Errorf("%q = %d..%d, want %d..%d", tc.cond, got.Min, got.Max, tc.min, tc.max)
+		}
+	}
+}
+
+func TestParseSeverityCondition_Invalid(t *testing.T) {
+	for _, cond := range []string{"", "warning", ">=", "=>warn", "warn..info", ">fatal", "<trace", "0", "25", "info..bogus"} {
+		if r, err := parseSeverityCondition(cond); err == nil {
+			t.Errorf("%q should be rejected, got %d..%d", cond, r.Min, r.Max)
+		}
+	}
+}
+
+func TestRouter_WarnOnlyRule(t *testing.T) {
+	router := NewRouter([]RoutingRule{
+		{Name: "warnings", Conditions: map[string]string{"_severity": "==warn"}, Index: "tas_warnings", Priority: 1},
+	})
+
+	for sev, want := range map[logspb.SeverityNumber]string{
+		logspb.SeverityNumber_SEVERITY_NUMBER_INFO:  "tas_logs",
+		logspb.SeverityNumber_SEVERITY_NUMBER_WARN:  "tas_warnings",
+		logspb.SeverityNumber_SEVERITY_NUMBER_WARN4: "tas_warnings",
+		logspb.SeverityNumber_SEVERITY_NUMBER_ERROR: "tas_logs",
+	} {
+		if index, _ := route(router, makeLogRecord(sev, nil)); index != want {
+			t.Errorf("%s routed to %s, want %s", severityName(sev), index, want)
+		}
+	}
+}
+
+func TestSeverityOutside(t *testing.T) {
+	for _, tc := range []struct {
+		r    severityRange
+		want logspb.SeverityNumber
+	}{
+		{severityRange{17, 24}, 13},
+		{severityRange{18, 18}, 17},
+		{severityRange{1, 12}, 13},
+		{severityRange{1, 24}, 0},
+	} {
+		if got := severityOutside(tc.r); got != tc.want || tc.r.Contains(got) {
+			t.Errorf("severityOutside(%v) = %d, want %d", tc.r, got, tc.want)
+		}
+	}
+}
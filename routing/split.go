@@ -0,0 +1,61 @@
+// ABOUTME: Percentage splits that send a share of a rule's matches to an alternate index.
+// ABOUTME: Records are bucketed by a hash of their trace ID, body, or a chosen attribute, so the split is deterministic.
+
+package routing
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// splitBuckets is the resolution of a split: percentages are honored to 0.01%
+const splitBuckets = 10000
+
+// Split sends Percent of a rule's matching records to Index instead of the rule's own
+type Split struct {
+	Index   string  `yaml:"index" json:"index"`               // Alternate index
+	Percent float64 `yaml:"percent" json:"percent"`           // Share of matches sent to Index, above 0 and below 100
+	By      string  `yaml:"by,omitempty" json:"by,omitempty"` // Attribute whose value picks the bucket; empty uses the trace ID, then the body
+}
+
+// Validate checks the split has an index and a percentage strictly between 0 and 100
+func (s *Split) Validate() error {
+	if s.Index == "" {
+		return fmt.Errorf("split.index is required")
+	}
+	if s.Percent <= 0 || s.Percent >= 100 {
+		return fmt.Errorf("split.percent %v must be above 0 and below 100", s.Percent)
+	}
+	return nil
+}
+
+// Takes reports whether the record falls in the split's share. The same key
+// always lands in the same bucket, and raising Percent only adds buckets.
+func (s *Split) Takes(lr *logspb.LogRecord) bool {
+	return splitBucket(s.key(lr)) < uint64(s.Percent*splitBuckets/100)
+}
+
+// key is the bytes the record is bucketed by
+func (s *Split) key(lr *logspb.LogRecord) []byte {
+	if s.By != "" {
+		return []byte(getAttributeValue(lr, s.By))
+	}
+	if id := lr.GetTraceId(); len(id) > 0 {
+		return id
+	}
+	return []byte(lr.GetBody().GetStringValue())
+}
+
+// splitBucket hashes key into [0, splitBuckets)
+func splitBucket(key []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(key)
+	// Mix the hash so short keys differing in one byte spread across buckets
+	sum := h.Sum64()
+	sum ^= sum >> 33
+	sum *= 0xff51afd7ed558ccd
+	sum ^= sum >> 33
+	return sum % splitBuckets
+}
@@ -0,0 +1,110 @@
+// ABOUTME: Tests for percentage-split routing.
+// ABOUTME: Covers the share taken, determinism, nesting as the percentage grows, and the bucketing key.
+
+package routing
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// splitRecord is record i of a stream with distinct trace IDs
+func splitRecord(i int) *logspb.LogRecord {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%d", i))
+	return &logspb.LogRecord{
+		TraceId: sum[:16],
+		Body:    &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "request"}},
+	}
+}
+
+func TestSplit_TakesPercentage(t *testing.T) {
+	for _, percent := range []float64{1, 10, 50, 90} {
+		s := &Split{Index: "tas_logs_v2", Percent: percent}
+		taken := 0
+		for i := range 10000 {
+			if s.Takes(splitRecord(i)) {
+				taken++
+			}
+		}
+		if got := float64(taken) / 100; math.Abs(got-percent) > 1.5 {
+			t.Errorf("%v%%: took %v%%", percent, got)
+		}
+	}
+}
+
+func TestSplit_DeterministicAndNested(t *testing.T) {
+	ten := &Split{Index: "tas_logs_v2", Percent: 10}
+	twenty := &Split{Index: "tas_logs_v2", Percent: 20}
+	for i := range 1000 {
+		lr := splitRecord(i)
+		if ten.Takes(lr) != ten.Takes(splitRecord(i)) {
+			t.Fatalf("record %d: split is not deterministic", i)
+		}
+		// Raising the percentage keeps every record already moved
+		if ten.Takes(lr) && !twenty.Takes(lr) {
+			t.Errorf("record %d: taken at 10%% but not at 20%%", i)
+		}
+	}
+}
+
+func TestSplit_Key(t *testing.T) {
+	// Without a trace ID the body decides, so identical bodies go together
+	s := &Split{Index: "tas_logs_v2", Percent: 50}
+	bodies := map[bool]bool{}
+	for range 100 {
+		lr := makeLogRecord(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, nil)
+		lr.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "same body"}}
+		bodies[s.Takes(lr)] = true
+	}
+	if len(bodies) != 1 {
+		t.Error("records with the same body should share a bucket")
+	}
+
+	// With By, every record of an app goes the same way regardless of trace
+	s.By = "cf_app_name"
+	apps := map[string]map[bool]bool{}
+	for i := range 1000 {
+		app := fmt.Sprintf("app-%d", i%20)
+		lr := splitRecord(i)
+		lr.Attributes = makeLogRecord(0, map[string]string{"cf_app_name": app}).Attributes
+		if apps[app] == nil {
+			apps[app] = map[bool]bool{}
+		}
+		apps[app][s.Takes(lr)] = true
+	}
+	moved := 0
+	for app, sides := range apps {
+		if len(sides) != 1 {
+			t.Errorf("%s was split across both indexes", app)
+		}
+		if sides[true] {
+			moved++
+		}
+	}
+	if moved == 0 || moved == len(apps) {
+		t.Errorf("%d of %d apps moved, want some but not all", moved, len(apps))
+	}
+}
+
+func TestSplit_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		split Split
+		ok    bool
+	}{
+		{Split{Index: "tas_v2", Percent: 10}, true},
+		{Split{Index: "tas_v2", Percent: 0.5}, true},
+		{Split{Percent: 10}, false},
+		{Split{Index: "tas_v2", Percent: 0}, false},
+		{Split{Index: "tas_v2", Percent: 100}, false},
+		{Split{Index: "tas_v2", Percent: -5}, false},
+	} {
+		if err := tc.split.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%+v: Validate() = %v, want ok=%v", tc.split, err, tc.ok)
+		}
+	}
+}
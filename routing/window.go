@@ -0,0 +1,153 @@
+// ABOUTME: Time windows that limit a routing rule to certain days and hours.
+// ABOUTME: Evaluated against the record's timestamp, or the receive time if it has none.
+
+package routing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// now is the receive time used for records without a timestamp; tests replace it
+var now = time.Now
+
+// TimeWindow limits a rule to records timestamped within certain days and hours
+type TimeWindow struct {
+	Days     []string `yaml:"days,omitempty" json:"days,omitempty"`         // mon..sun or ranges like mon-fri; empty means every day
+	Hours    string   `yaml:"hours,omitempty" json:"hours,omitempty"`       // HH:MM-HH:MM, end exclusive, may cross midnight; empty means all day
+	Timezone string   `yaml:"timezone,omitempty" json:"timezone,omitempty"` // IANA name, default UTC
+}
+
+// weekdays maps day names to time.Weekday
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// compiledWindow is a time window with parsed days, hours, and location
+type compiledWindow struct {
+	days       [7]bool
+	start, end int // Minutes after midnight; start == end means all day
+	loc        *time.Location
+}
+
+// compileWindow parses a time window, naming the offending field on error
+func compileWindow(w *TimeWindow) (*compiledWindow, error) {
+	if len(w.Days) == 0 && w.Hours == "" {
+		return nil, fmt.Errorf("days or hours is required")
+	}
+
+	cw := &compiledWindow{loc: time.UTC}
+	if w.Timezone != "" {
+		loc, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("timezone: %w", err)
+		}
+		cw.loc = loc
+	}
+
+	if len(w.Days) == 0 {
+		cw.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, spec := range w.Days {
+		if err := cw.addDays(spec); err != nil {
+			return nil, fmt.Errorf("days: %w", err)
+		}
+	}
+
+	if w.Hours != "" {
+		from, to, ok := strings.Cut(w.Hours, "-")
+		if !ok {
+			return nil, fmt.Errorf("hours: %q is not HH:MM-HH:MM", w.Hours)
+		}
+		var err error
+		if cw.start, err = parseClock(from); err != nil {
+			return nil, fmt.Errorf("hours: %w", err)
+		}
+		if cw.end, err = parseClock(to); err != nil {
+			return nil, fmt.Errorf("hours: %w", err)
+		}
+		if cw.start == cw.end {
+			return nil, fmt.Errorf("hours: %q is empty", w.Hours)
+		}
+	}
+	return cw, nil
+}
+
+// addDays enables one day or an inclusive range of days, which may wrap past sunday
+func (cw *compiledWindow) addDays(spec string) error {
+	from, to, isRange := strings.Cut(strings.ToLower(strings.TrimSpace(spec)), "-")
+	first, ok := weekdays[from]
+	if !ok {
+		return fmt.Errorf("unknown day %q", from)
+	}
+	last := first
+	if isRange {
+		if last, ok = weekdays[to]; !ok {
+			return fmt.Errorf("unknown day %q", to)
+		}
+	}
+	for d := first; ; d = (d + 1) % 7 {
+		cw.days[d] = true
+		if d == last {
+			return nil
+		}
+	}
+}
+
+// parseClock parses HH:MM into minutes after midnight; 24:00 is the end of the day
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if strings.TrimSpace(s) == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("%q is not HH:MM", s)
+}
+
+// contains reports whether t falls within the window. A window crossing
+// midnight belongs to the day it starts on.
+func (cw *compiledWindow) contains(t time.Time) bool {
+	t = t.In(cw.loc)
+	day := t.Weekday()
+	minute := t.Hour()*60 + t.Minute()
+	switch {
+	case cw.start == cw.end:
+		return cw.days[day]
+	case cw.start < cw.end:
+		return cw.days[day] && minute >= cw.start && minute < cw.end
+	case minute >= cw.start:
+		return cw.days[day]
+	default:
+		// Early morning belongs to the previous day's window
+		return cw.days[(day+6)%7] && minute < cw.end
+	}
+}
+
+// fixtureWeek is the Monday from which fixture timestamps are searched
+var fixtureWeek = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// find returns the first minute of the fixture week, in the window's
+// time zone, that is inside (or outside) the window; false if there is none
+func (cw *compiledWindow) find(inside bool) (time.Time, bool) {
+	start := time.Date(fixtureWeek.Year(), fixtureWeek.Month(), fixtureWeek.Day(), 0, 0, 0, 0, cw.loc)
+	for t := start; t.Before(start.AddDate(0, 0, 7)); t = t.Add(time.Minute) {
+		if cw.contains(t) == inside {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// recordTime is the record's timestamp, or the receive time if it has none
+func recordTime(lr *logspb.LogRecord) time.Time {
+	if ts := lr.GetTimeUnixNano(); ts != 0 {
+		return time.Unix(0, int64(ts))
+	}
+	return now()
+}
@@ -0,0 +1,105 @@
+// ABOUTME: Tests for time-window routing rules.
+// ABOUTME: Covers days, hours, overnight windows, time zones, and the receive-time fallback.
+
+package routing
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// at is a record with the given severity timestamped t
+func at(sev logspb.SeverityNumber, t time.Time) *logspb.LogRecord {
+	lr := makeLogRecord(sev, nil)
+	lr.TimeUnixNano = uint64(t.UnixNano())
+	return lr
+}
+
+func TestWindow_Contains(t *testing.T) {
+	// 2024-01-01 is a Monday
+	day := func(d, hour, minute int) time.Time {
+		return time.Date(2024, time.January, d, hour, minute, 0, 0, time.UTC)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		window TimeWindow
+		t      time.Time
+		want   bool
+	}{
+		{"business hours", TimeWindow{Days: []string{"mon-fri"}, Hours: "09:00-17:00"}, day(1, 9, 0), true},
+		{"end is exclusive", TimeWindow{Days: []string{"mon-fri"}, Hours: "09:00-17:00"}, day(1, 17, 0), false},
+		{"before hours", TimeWindow{Days: []string{"mon-fri"}, Hours: "09:00-17:00"}, day(1, 8, 59), false},
+		{"weekend", TimeWindow{Days: []string{"mon-fri"}, Hours: "09:00-17:00"}, day(6, 12, 0), false},
+		{"days only", TimeWindow{Days: []string{"sat", "sun"}}, day(7, 23, 59), true},
+		{"hours only", TimeWindow{Hours: "00:00-06:00"}, day(3, 5, 30), true},
+		{"wrapping days", TimeWindow{Days: []string{"fri-mon"}}, day(7, 12, 0), true},
+		{"wrapping days excludes", TimeWindow{Days: []string{"fri-mon"}}, day(3, 12, 0), false},
+		{"overnight evening", TimeWindow{Days: []string{"fri"}, Hours: "22:00-06:00"}, day(5, 23, 0), true},
+		{"overnight next morning", TimeWindow{Days: []string{"fri"}, Hours: "22:00-06:00"}, day(6, 5, 59), true},
+		{"overnight previous morning", TimeWindow{Days: []string{"fri"}, Hours: "22:00-06:00"}, day(5, 5, 0), false},
+		{"until midnight", TimeWindow{Hours: "18:00-24:00"}, day(2, 23, 59), true},
+		// 14:00 UTC is 09:00 in New York in January
+		{"time zone", TimeWindow{Hours: "09:00-17:00", Timezone: "America/New_York"}, day(2, 14, 0), true},
+		{"time zone excludes", TimeWindow{Hours: "09:00-17:00", Timezone: "America/New_York"}, day(2, 13, 59), false},
+	} {
+		cw, err := compileWindow(&tc.window)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := cw.contains(tc.t); got != tc.want {
+			t.Errorf("%s: contains(%s) = %v, want %v", tc.name, tc.t.Format(time.RFC3339), got, tc.want)
+		}
+	}
+}
+
+func TestWindow_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		window TimeWindow
+		want   string
+	}{
+		{TimeWindow{}, "days or hours is required"},
+		{TimeWindow{Days: []string{"monday"}}, `days: unknown day "monday"`},
+		{TimeWindow{Days: []string{"mon-xyz"}}, `days: unknown day "xyz"`},
+		{TimeWindow{Hours: "9-17"}, "hours:"},
+		{TimeWindow{Hours: "09:00"}, "hours:"},
+		{TimeWindow{Hours: "09:00-09:00"}, "is empty"},
+		{TimeWindow{Hours: "09:00-17:00", Timezone: "Mars/Olympus"}, "timezone:"},
+	} {
+		_, err := compileWindow(&tc.window)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: error = %v, want it to contain %q", tc.window, err, tc.want)
+		}
+	}
+}
+
+func TestRoute_Window(t *testing.T) {
+	router := NewRouter([]RoutingRule{{
+		Name:       "business-debug",
+		Conditions: map[string]string{"_severity": "==debug"},
+		Window:     &TimeWindow{Days: []string{"mon-fri"}, Hours: "09:00-17:00"},
+		Index:      "tas_debug",
+	}})
+	debug := logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+
+	if index, _ := route(router, at(debug, time.Date(2024, time.January, 2, 10, 0, 0, 0, time.UTC))); index != "tas_debug" {
+		t.Errorf("Tuesday 10:00 routed to %s, want tas_debug", index)
+	}
+	if index, _ := route(router, at(debug, time.Date(2024, time.January, 2, 20, 0, 0, 0, time.UTC))); index != "tas_logs" {
+		t.Errorf("Tuesday 20:00 routed to %s, want tas_logs", index)
+	}
+
+	// Without a timestamp the receive time decides
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2024, time.January, 3, 11, 0, 0, 0, time.UTC) }
+	if index, _ := route(router, makeLogRecord(debug, nil)); index != "tas_debug" {
+		t.Errorf("received Wednesday 11:00 routed to %s, want tas_debug", index)
+	}
+	now = func() time.Time { return time.Date(2024, time.January, 6, 11, 0, 0, 0, time.UTC) }
+	if index, _ := route(router, makeLogRecord(debug, nil)); index != "tas_logs" {
+		t.Errorf("received Saturday 11:00 routed to %s, want tas_logs", index)
+	}
+}
@@ -0,0 +1,91 @@
+// ABOUTME: Rate-based adaptive sampling with a per-app records-per-second budget.
+// ABOUTME: A token bucket per app absorbs short bursts, then keeps only what the budget allows.
+
+package sampling
+
+import (
+	"sync"
+	"time"
+)
+
+// RatioWindow is how long kept/seen counts accumulate before the effective ratio is updated
+const RatioWindow = 10 * time.Second
+
+// bucket is one app's token bucket and effective ratio
+type bucket struct {
+	tokens float64
+	last   time.Time
+
+	windowStart time.Time
+	seen, kept  int
+	ratio       float64 // Of the last complete window, -1 before the first closes
+}
+
+// Budget keeps at most PerSecond records per second for each app. Each app's
+// bucket holds up to one second's worth of tokens and refills continuously,
+// so a burst after a quiet spell passes before sampling kicks in.
+type Budget struct {
+	PerSecond float64
+
+	mu   sync.Mutex
+	apps map[string]*bucket
+}
+
+// NewBudget creates a budget of perSecond records per second per app
+func NewBudget(perSecond float64) *Budget {
+	return &Budget{PerSecond: perSecond, apps: make(map[string]*bucket)}
+}
+
+// capacity is the most tokens a bucket holds: one second's worth, at least one record
+func (b *Budget) capacity() float64 {
+	return max(b.PerSecond, 1)
+}
+
+// Allow reports whether a record from app fits the budget, spending a token if so
+func (b *Budget) Allow(app string) bool {
+	return b.allow(app, time.Now())
+}
+
+func (b *Budget) allow(app string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bk, ok := b.apps[app]
+	if !ok {
+		bk = &bucket{tokens: b.capacity(), last: now, windowStart: now, ratio: -1}
+		b.apps[app] = bk
+	}
+
+	bk.tokens = min(b.capacity(), bk.tokens+now.Sub(bk.last).Seconds()*b.PerSecond)
+	bk.last = now
+
+	if now.Sub(bk.windowStart) >= RatioWindow {
+		bk.ratio = float64(bk.kept) / float64(bk.seen)
+		bk.windowStart, bk.seen, bk.kept = now, 0, 0
+	}
+
+	bk.seen++
+	if bk.tokens < 1 {
+		return false
+	}
+	bk.tokens--
+	bk.kept++
+	return true
+}
+
+// Ratio returns the fraction of app's records kept over the last complete
+// window, or so far if the first window is still open. Apps not seen yet keep everything.
+func (b *Budget) Ratio(app string) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bk, ok := b.apps[app]
+	switch {
+	case !ok || bk.seen == 0 && bk.ratio < 0:
+		return 1
+	case bk.ratio >= 0:
+		return bk.ratio
+	default:
+		return float64(bk.kept) / float64(bk.seen)
+	}
+}
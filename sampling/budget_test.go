@@ -0,0 +1,88 @@
+// ABOUTME: Tests for the per-app records-per-second sampling budget.
+// ABOUTME: Covers burst absorption, refill, per-app isolation, and the effective ratio.
+
+package sampling
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBudget_BurstThenRefill(t *testing.T) {
+	b := NewBudget(5)
+	now := time.Unix(1700000000, 0)
+
+	kept := 0
+	for range 20 {
+		if b.allow("web", now) {
+			kept++
+		}
+	}
+	if kept != 5 {
+		t.Errorf("burst kept %d, want 5", kept)
+	}
+
+	// 200ms refills one token
+	if !b.allow("web", now.Add(200*time.Millisecond)) {
+		t.Error("refilled token should be spent")
+	}
+	if b.allow("web", now.Add(200*time.Millisecond)) {
+		t.Error("bucket should be empty again")
+	}
+
+	// A long quiet spell refills at most one second's worth
+	kept = 0
+	for range 20 {
+		if b.allow("web", now.Add(time.Minute)) {
+			kept++
+		}
+	}
+	if kept != 5 {
+		t.Errorf("after idle kept %d, want 5", kept)
+	}
+}
+
+func TestBudget_PerApp(t *testing.T) {
+	b := NewBudget(1)
+	now := time.Unix(1700000000, 0)
+
+	if !b.allow("a", now) || b.allow("a", now) {
+		t.Error("app a should get exactly one record")
+	}
+	if !b.allow("b", now) {
+		t.Error("app b has its own budget")
+	}
+}
+
+func TestBudget_FractionalRateKeepsOne(t *testing.T) {
+	b := NewBudget(0.5)
+	now := time.Unix(1700000000, 0)
+
+	if !b.allow("slow", now) || b.allow("slow", now.Add(time.Second)) {
+		t.Error("0.5/s should keep the first record, then one every two seconds")
+	}
+	if !b.allow("slow", now.Add(2*time.Second)) {
+		t.Error("two seconds should refill a token")
+	}
+}
+
+func TestBudget_Ratio(t *testing.T) {
+	b := NewBudget(2)
+	now := time.Unix(1700000000, 0)
+
+	if got := b.Ratio("web"); got != 1 {
+		t.Errorf("unseen app ratio = %v, want 1", got)
+	}
+	for range 8 {
+		b.allow("web", now)
+	}
+	if got := b.Ratio("web"); got != 0.25 {
+		t.Errorf("running ratio = %v, want 0.25", got)
+	}
+
+	// Closing the window freezes its ratio while the next one fills
+	b.allow("web", now.Add(RatioWindow))
+	if got := b.Ratio("web"); got != 0.25 {
+		t.Errorf("ratio after window = %v, want 0.25", got)
+	}
+}
@@ -0,0 +1,270 @@
+// ABOUTME: StatsD and DogStatsD emission of the receiver's metrics over UDP, for Datadog-agent based monitoring.
+// ABOUTME: Gathers the Prometheus registry every interval, sending counter deltas, gauges, and histograms as timers.
+
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Line formats
+const (
+	FormatStatsD    = "statsd"    // Label values appended to the metric name
+	FormatDogStatsD = "dogstatsd" // Labels sent as tags
+)
+
+// DefaultPrefix replaces the otlp_receiver_ prefix of every metric name
+const DefaultPrefix = "otlp_receiver."
+
+// maxPacket keeps datagrams under a typical network MTU, as the Datadog agent recommends
+const maxPacket = 1432
+
+// Config says where, how, and how often to send metrics
+type Config struct {
+	Addr     string        // StatsD server or Datadog agent, host:port
+	Format   string        // FormatStatsD or FormatDogStatsD
+	Prefix   string        // Replaces otlp_receiver_ in each metric name
+	Interval time.Duration // How often metrics are sent
+}
+
+// Emitter sends a registry's metrics to a StatsD server
+type Emitter struct {
+	cfg      Config
+	gatherer prometheus.Gatherer
+
+	mu      sync.Mutex
+	conn    net.Conn
+	last    map[string]float64 // Counter values and histogram bucket counts at the previous send, by series
+	failing bool               // The previous send failed, so recovery is logged
+}
+
+// New returns an emitter sending the metrics g gathers to cfg.Addr
+func New(cfg Config, g prometheus.Gatherer) (*Emitter, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("an address is required")
+	}
+	if cfg.Format != FormatStatsD && cfg.Format != FormatDogStatsD {
+		return nil, fmt.Errorf("format %q must be %s or %s", cfg.Format, FormatStatsD, FormatDogStatsD)
+	}
+	if cfg.Interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Emitter{cfg: cfg, gatherer: g, conn: conn, last: make(map[string]float64)}, nil
+}
+
+// Run sends metrics every interval until stop is closed
+func (e *Emitter) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			e.Send()
+		}
+	}
+}
+
+// Send gathers the metrics and sends what changed since the previous send
+func (e *Emitter) Send() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	var lines []string
+	for _, mf := range families {
+		lines = append(lines, e.lines(mf)...)
+	}
+	err = e.write(lines)
+	switch {
+	case err != nil && !e.failing:
+		slog.Warn("StatsD: send failed", "addr", e.cfg.Addr, "err", err)
+	case err == nil && e.failing:
+		slog.Info("StatsD: sending again", "addr", e.cfg.Addr)
+	}
+	e.failing = err != nil
+	return err
+}
+
+// Close sends a last time, so counts from shutdown are not lost, and closes the connection
+func (e *Emitter) Close() error {
+	err := e.Send()
+	return errors.Join(err, e.conn.Close())
+}
+
+// lines renders one metric family. Caller holds mu.
+func (e *Emitter) lines(mf *dto.MetricFamily) []string {
+	var lines []string
+	name := e.cfg.Prefix + strings.TrimPrefix(mf.GetName(), "otlp_receiver_")
+	for _, m := range mf.GetMetric() {
+		key := seriesKey(mf.GetName(), m)
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			v := m.GetCounter().GetValue()
+			// Deltas below zero are the top-N regrouping of the per-app counters, not new records
+			if delta := v - e.last[key]; delta > 0 {
+				lines = append(lines, e.line(name, m, formatValue(delta), "c", 1))
+			}
+			e.last[key] = v
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			v := m.GetGauge().GetValue()
+			if mf.GetType() == dto.MetricType_UNTYPED {
+				v = m.GetUntyped().GetValue()
+			}
+			if v < 0 && e.cfg.Format == FormatStatsD {
+				// Plain StatsD reads a signed gauge as a change, so set it from zero
+				lines = append(lines, e.line(name, m, "0", "g", 1))
+			}
+			lines = append(lines, e.line(name, m, formatValue(v), "g", 1))
+		case dto.MetricType_HISTOGRAM:
+			lines = append(lines, e.timers(name, key, m)...)
+		}
+	}
+	return lines
+}
+
+// timers renders the observations a histogram gained as timers at their
+// buckets' upper bounds, one line per bucket with a sample rate standing in
+// for the count. Durations in seconds are sent in milliseconds. Caller holds mu.
+func (e *Emitter) timers(name, key string, m *dto.Metric) []string {
+	scale := 1.0
+	if strings.HasSuffix(name, "_seconds") {
+		name = strings.TrimSuffix(name, "_seconds") + "_ms"
+		scale = 1000
+	}
+	var lines []string
+	var below, bound float64 // Cumulative count and upper bound of the previous bucket
+	for i, b := range m.GetHistogram().GetBucket() {
+		bucketKey := key + "\xffle=" + strconv.Itoa(i)
+		count := float64(b.GetCumulativeCount())
+		if !math.IsInf(b.GetUpperBound(), 1) {
+			bound = b.GetUpperBound() // Observations over the last bound are sent at it
+		}
+		n := (count - below) - e.last[bucketKey]
+		if n > 0 {
+			lines = append(lines, e.line(name, m, formatValue(bound*scale), "ms", n))
+		}
+		e.last[bucketKey] = count - below
+		below = count
+	}
+	if inf := m.GetHistogram().GetSampleCount(); float64(inf) > below {
+		// The +Inf bucket is implicit in gathered histograms
+		bucketKey := key + "\xffle=+Inf"
+		n := float64(inf) - below - e.last[bucketKey]
+		if n > 0 {
+			lines = append(lines, e.line(name, m, formatValue(bound*scale), "ms", n))
+		}
+		e.last[bucketKey] = float64(inf) - below
+	}
+	return lines
+}
+
+// line renders one StatsD line for a series, with labels as tags or name segments.
+// n above 1 is sent as a sample rate of 1/n, so the server counts the value n times.
+func (e *Emitter) line(name string, m *dto.Metric, value, kind string, n float64) string {
+	var b strings.Builder
+	b.WriteString(name)
+	if e.cfg.Format == FormatStatsD {
+		for _, l := range m.GetLabel() {
+			b.WriteByte('.')
+			b.WriteString(sanitize(l.GetValue(), "_-"))
+		}
+	}
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(kind)
+	if n > 1 {
+		b.WriteString("|@")
+		b.WriteString(strconv.FormatFloat(1/n, 'g', 6, 64))
+	}
+	if e.cfg.Format == FormatDogStatsD && len(m.GetLabel()) > 0 {
+		b.WriteString("|#")
+		for i, l := range m.GetLabel() {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(l.GetName())
+			b.WriteByte(':')
+			b.WriteString(sanitize(l.GetValue(), "_-./:"))
+		}
+	}
+	return b.String()
+}
+
+// write sends lines in as few datagrams as fit under maxPacket. Caller holds mu.
+func (e *Emitter) write(lines []string) error {
+	var errs []error
+	var packet strings.Builder
+	flush := func() {
+		if packet.Len() == 0 {
+			return
+		}
+		if _, err := e.conn.Write([]byte(packet.String())); err != nil {
+			errs = append(errs, err)
+		}
+		packet.Reset()
+	}
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxPacket {
+			flush()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	flush()
+	if len(errs) > 0 {
+		return errs[0] // A refused datagram fails the ones after it the same way
+	}
+	return nil
+}
+
+// seriesKey identifies a series by metric name and label pairs
+func seriesKey(name string, m *dto.Metric) string {
+	pairs := make([]string, len(m.GetLabel()))
+	for i, l := range m.GetLabel() {
+		pairs[i] = l.GetName() + "=" + l.GetValue()
+	}
+	sort.Strings(pairs)
+	return name + "\xff" + strings.Join(pairs, "\xff")
+}
+
+// sanitize replaces characters StatsD would misread with underscores, keeping
+// letters, digits, and those in allowed
+func sanitize(s, allowed string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(allowed, r) {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// formatValue renders a number without exponent or trailing zeros
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,167 @@
+// ABOUTME: Tests for StatsD and DogStatsD emission.
+// ABOUTME: Listens on a local UDP port and checks the lines sent for counters, gauges, and histograms.
+
+package statsd
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// listen returns a UDP listener on a free local port
+func listen(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// receive returns the sorted lines of the datagrams waiting on conn
+func receive(t *testing.T, conn *net.UDPConn) []string {
+	t.Helper()
+	var lines []string
+	buf := make([]byte, 65536)
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, err := conn.Read(buf)
+		if err != nil {
+			break
+		}
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// testRegistry returns a registry with one labelled metric of each type
+func testRegistry() (*prometheus.Registry, *prometheus.CounterVec, *prometheus.GaugeVec, *prometheus.HistogramVec) {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "otlp_receiver_logs_received_total"}, []string{"app"})
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "otlp_receiver_output_drift"}, []string{"output"})
+	hist := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "otlp_receiver_transform_duration_seconds",
+		Buckets: []float64{0.001, 0.01},
+	}, []string{"app"})
+	reg.MustRegister(counter, gauge, hist)
+	return reg, counter, gauge, hist
+}
+
+func TestSend_DogStatsD(t *testing.T) {
+	conn := listen(t)
+	reg, counter, gauge, hist := testRegistry()
+	e, err := New(Config{Addr: conn.LocalAddr().String(), Format: FormatDogStatsD, Prefix: DefaultPrefix, Interval: time.Hour}, reg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer e.Close()
+
+	counter.WithLabelValues("my app").Add(3)
+	gauge.WithLabelValues("file").Set(-2)
+	hist.WithLabelValues("a").Observe(0.0005)
+	hist.WithLabelValues("a").Observe(0.0005)
+	hist.WithLabelValues("a").Observe(0.005)
+	hist.WithLabelValues("a").Observe(5)
+	if err := e.Send(); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	got := receive(t, conn)
+	want := []string{
+		"otlp_receiver.logs_received_total:3|c|#app:my_app",
+		"otlp_receiver.output_drift:-2|g|#output:file",
+		"otlp_receiver.transform_duration_ms:10|ms|#app:a",
+		"otlp_receiver.transform_duration_ms:10|ms|#app:a",
+		"otlp_receiver.transform_duration_ms:1|ms|@0.5|#app:a",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSend_SendsCounterDeltas(t *testing.T) {
+	conn := listen(t)
+	reg, counter, _, hist := testRegistry()
+	e, err := New(Config{Addr: conn.LocalAddr().String(), Format: FormatDogStatsD, Interval: time.Hour}, reg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer e.Close()
+
+	counter.WithLabelValues("a").Add(3)
+	hist.WithLabelValues("a").Observe(0.0005)
+	e.Send()
+	receive(t, conn)
+
+	counter.WithLabelValues("a").Add(2)
+	e.Send()
+	got := receive(t, conn)
+	if len(got) != 1 || got[0] != "logs_received_total:2|c|#app:a" {
+		t.Errorf("lines = %q, want only the counter's increase", got)
+	}
+}
+
+func TestSend_StatsD(t *testing.T) {
+	conn := listen(t)
+	reg, counter, gauge, _ := testRegistry()
+	e, err := New(Config{Addr: conn.LocalAddr().String(), Format: FormatStatsD, Prefix: "lab.", Interval: time.Hour}, reg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer e.Close()
+
+	counter.WithLabelValues("my.app").Inc()
+	gauge.WithLabelValues("file").Set(-2)
+	e.Send()
+	got := receive(t, conn)
+	want := []string{
+		"lab.logs_received_total.my_app:1|c",
+		"lab.output_drift.file:-2|g",
+		"lab.output_drift.file:0|g",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestWrite_SplitsPackets(t *testing.T) {
+	conn := listen(t)
+	e, err := New(Config{Addr: conn.LocalAddr().String(), Format: FormatStatsD, Interval: time.Hour}, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer e.Close()
+
+	line := strings.Repeat("x", 500) + ":1|c"
+	if err := e.write([]string{line, line, line}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	buf := make([]byte, 65536)
+	var packets int
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, err := conn.Read(buf)
+		if err != nil {
+			break
+		}
+		if n > maxPacket {
+			t.Errorf("packet of %d bytes, want at most %d", n, maxPacket)
+		}
+		packets++
+	}
+	if packets != 2 {
+		t.Errorf("sent %d packets, want 2", packets)
+	}
+}
+
+func TestNew_RejectsUnknownFormat(t *testing.T) {
+	if _, err := New(Config{Addr: "127.0.0.1:8125", Format: "graphite", Interval: time.Second}, prometheus.NewRegistry()); err == nil {
+		t.Error("New should reject an unknown format")
+	}
+}
@@ -0,0 +1,107 @@
+// ABOUTME: Self-telemetry: exports the receiver's own metrics and processing spans over OTLP with the OpenTelemetry SDK.
+// ABOUTME: Metrics are bridged from the Prometheus registry, so /metrics and the OTLP export always agree.
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// ServiceName is the service.name the receiver reports itself as, unless
+// OTEL_SERVICE_NAME or OTEL_RESOURCE_ATTRIBUTES says otherwise
+const ServiceName = "otlp-mock-receiver"
+
+// Config says where and how often to send the receiver's own telemetry
+type Config struct {
+	Endpoint string        // OTLP gRPC endpoint, host:port
+	Insecure bool          // Plaintext gRPC, for collectors without TLS
+	Interval time.Duration // How often metrics are exported
+}
+
+// Telemetry holds the SDK providers exporting the receiver's own telemetry
+type Telemetry struct {
+	tracer *sdktrace.TracerProvider
+	meter  *sdkmetric.MeterProvider // Nil if there is no registry to export
+}
+
+// Start exports spans, and the metrics in m if it is not nil, to
+// cfg.Endpoint. Headers, a sampler, and other exporter settings are taken
+// from the standard OTEL_* environment variables.
+func Start(cfg Config, m *metrics.Metrics) (*Telemetry, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("an endpoint is required")
+	}
+	if cfg.Interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	ctx := context.Background()
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("building resource: %w", err)
+	}
+
+	traceOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		traceOpts = append(traceOpts, otlptracegrpc.WithInsecure())
+	}
+	spans, err := otlptracegrpc.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating span exporter: %w", err)
+	}
+	t := &Telemetry{
+		tracer: sdktrace.NewTracerProvider(sdktrace.WithBatcher(spans), sdktrace.WithResource(res)),
+	}
+	if m == nil {
+		return t, nil
+	}
+
+	metricOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithInsecure())
+	}
+	exporter, err := otlpmetricgrpc.New(ctx, metricOpts...)
+	if err != nil {
+		t.tracer.Shutdown(ctx)
+		return nil, fmt.Errorf("creating metric exporter: %w", err)
+	}
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(cfg.Interval),
+		sdkmetric.WithProducer(otelprom.NewMetricProducer(otelprom.WithGatherer(m.Registry()))),
+	)
+	t.meter = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res))
+	return t, nil
+}
+
+// TracerProvider returns the provider the receiver's spans are started from
+func (t *Telemetry) TracerProvider() trace.TracerProvider {
+	return t.tracer
+}
+
+// Shutdown exports what is pending, a last round of metrics included, and stops exporting
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	err := t.tracer.Shutdown(ctx)
+	if t.meter != nil {
+		err = errors.Join(err, t.meter.Shutdown(ctx))
+	}
+	return err
+}
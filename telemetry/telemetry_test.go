@@ -0,0 +1,129 @@
+// ABOUTME: Tests for self-telemetry export.
+// ABOUTME: Runs a fake OTLP collector and checks spans and bridged Prometheus metrics reach it.
+
+package telemetry
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+
+	"otlp-mock-receiver/metrics"
+)
+
+// fakeCollector records the span and metric names it is sent
+type fakeCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	mu      sync.Mutex
+	spans   []string
+	metrics map[string]bool
+}
+
+func (c *fakeCollector) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				c.spans = append(c.spans, span.GetName())
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// metricsService adapts the collector to the metrics service, whose Export
+// has the same name as the trace service's
+type metricsService struct {
+	*fakeCollector
+}
+
+func (s metricsService) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rm := range req.GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				s.metrics[m.GetName()] = true
+			}
+		}
+	}
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+// startCollector serves a fake collector on a free port and returns its address
+func startCollector(t *testing.T) (*fakeCollector, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	c := &fakeCollector{metrics: make(map[string]bool)}
+	server := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(server, c)
+	colmetricspb.RegisterMetricsServiceServer(server, metricsService{c})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return c, lis.Addr().String()
+}
+
+func TestStart_ExportsSpansAndMetrics(t *testing.T) {
+	c, addr := startCollector(t)
+	m := metrics.New()
+	m.LogsReceived.Add(3)
+
+	tel, err := Start(Config{Endpoint: addr, Insecure: true, Interval: time.Hour}, m)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	_, span := tel.TracerProvider().Tracer("test").Start(context.Background(), "Export")
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tel.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.spans) != 1 || c.spans[0] != "Export" {
+		t.Errorf("spans = %v, want [Export]", c.spans)
+	}
+	if !c.metrics["otlp_receiver_logs_received_total"] {
+		t.Errorf("metrics = %v, want the Prometheus registry's", c.metrics)
+	}
+}
+
+func TestStart_WithoutMetrics(t *testing.T) {
+	c, addr := startCollector(t)
+	tel, err := Start(Config{Endpoint: addr, Insecure: true, Interval: time.Hour}, nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := tel.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.metrics) != 0 {
+		t.Errorf("metrics = %v, want none without a registry", c.metrics)
+	}
+}
+
+func TestStart_RejectsBadConfig(t *testing.T) {
+	if _, err := Start(Config{Interval: time.Second}, nil); err == nil {
+		t.Error("Start should require an endpoint")
+	}
+	if _, err := Start(Config{Endpoint: "localhost:4317"}, nil); err == nil {
+		t.Error("Start should require a positive interval")
+	}
+}
@@ -0,0 +1,44 @@
+// ABOUTME: Structured record of what each transform did to a log record.
+// ABOUTME: Actions carry a type and the rule responsible, plus a human-readable detail.
+
+package transform
+
+// ActionType identifies the kind of change a transform made
+type ActionType string
+
+// Action types produced by the built-in transforms
+const (
+	ActionNormalizeSeverity ActionType = "normalize_severity"
+	ActionProfile           ActionType = "profile"
+	ActionStatement         ActionType = "ottl"
+	ActionStatementError    ActionType = "ottl_error"
+	ActionResourceRename    ActionType = "resource_rename"
+	ActionResourceDelete    ActionType = "resource_delete"
+	ActionResourceEnrich    ActionType = "resource_enrich"
+	ActionRename            ActionType = "rename"
+	ActionDelete            ActionType = "delete"
+	ActionScrubSecret       ActionType = "scrub_secret"
+	ActionRedactPCI         ActionType = "redact_pci"
+	ActionRedactXML         ActionType = "redact_xml"
+	ActionParseLogfmt       ActionType = "parse_logfmt"
+	ActionExtract           ActionType = "extract"
+	ActionTruncateBody      ActionType = "truncate_body"
+	ActionEnrich            ActionType = "enrich"
+	ActionTruncateAttribute ActionType = "truncate_attribute"
+	ActionDropAttribute     ActionType = "drop_attribute"
+	ActionDropBody          ActionType = "drop_body"
+	ActionRecordLimit       ActionType = "record_limit"
+)
+
+// TransformAction is one change made to a record. Rule names the config entry
+// responsible (attribute key, pattern, profile, statement, ...) where there is one.
+type TransformAction struct {
+	Type   ActionType `json:"type"`
+	Rule   string     `json:"rule,omitempty"`
+	Detail string     `json:"detail"`
+}
+
+// String returns the human-readable detail
+func (a TransformAction) String() string {
+	return a.Detail
+}
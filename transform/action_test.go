@@ -0,0 +1,52 @@
+// ABOUTME: Tests for structured transform actions.
+// ABOUTME: Covers action types and rules reported by the built-in pipeline.
+
+package transform
+
+import (
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// details returns the human-readable detail of each action
+func details(actions []TransformAction) []string {
+	out := make([]string, len(actions))
+	for i, a := range actions {
+		out[i] = a.Detail
+	}
+	return out
+}
+
+func TestApply_ActionsAreTyped(t *testing.T) {
+	lr := makeLogRecord(map[string]string{"application_name": "payments", "diego_cell_ip": "10.0.0.1"})
+	lr.Body = stringBody("card 4111-1111-1111-1111 password=hunter2")
+
+	cfg := DefaultConfig()
+	cfg.SecretScrubbing = DefaultSecretScrubbing()
+	_, actions := ApplyWithConfig(nil, lr, cfg)
+	want := map[ActionType]string{
+		ActionRename:      "application_name",
+		ActionDelete:      "diego_cell_ip",
+		ActionScrubSecret: "password",
+		ActionRedactPCI:   DefaultConfig().PCIPatterns[0].String(),
+	}
+	for _, a := range actions {
+		if rule, ok := want[a.Type]; ok && a.Rule == rule {
+			delete(want, a.Type)
+		}
+		if a.String() != a.Detail || a.Detail == "" {
+			t.Errorf("action %+v should render as its detail", a)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing actions %v in %+v", want, actions)
+	}
+}
+
+func TestApply_NoActions(t *testing.T) {
+	lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "hello")
+	if _, actions := Apply(lr); len(actions) != 0 {
+		t.Errorf("actions = %v, want none", actions)
+	}
+}
@@ -0,0 +1,102 @@
+// ABOUTME: Aggregation rules that roll up matching records into one counted record.
+// ABOUTME: Rules only decide membership and grouping; the aggregate package holds the windows.
+
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// DefaultAggregateCountAttribute is set on rollup records when a rule names no attribute
+const DefaultAggregateCountAttribute = "aggregate_count"
+
+// AggregateRule collects records whose body matches Body and that satisfy When,
+// emitting one record per group every Window. At least one of Body or When must be set.
+type AggregateRule struct {
+	Name           string
+	Body           *regexp.Regexp // nil = any body
+	When           *Match         // nil = any record
+	GroupBy        []string       // Attribute keys, or "body", identifying a group
+	Window         time.Duration
+	CountAttribute string // Set on the rollup to the number of records collected
+}
+
+// Matches reports whether the rule collects the record
+func (r *AggregateRule) Matches(res *resourcepb.Resource, lr *logspb.LogRecord) bool {
+	if r.Body != nil && !r.Body.MatchString(lr.GetBody().GetStringValue()) {
+		return false
+	}
+	return r.When.Matches(res, lr)
+}
+
+// GroupKey returns the record's group-by values joined into one key
+func (r *AggregateRule) GroupKey(lr *logspb.LogRecord) string {
+	values := make([]string, len(r.GroupBy))
+	for i, field := range r.GroupBy {
+		if field == "body" {
+			values[i] = lr.GetBody().GetStringValue()
+		} else {
+			values[i] = getAttributeValue(lr, field)
+		}
+	}
+	return strings.Join(values, "\x00")
+}
+
+// AggregateRuleFile is the YAML representation of an AggregateRule
+type AggregateRuleFile struct {
+	Name           string     `yaml:"name"`
+	Body           string     `yaml:"body"`
+	When           *MatchFile `yaml:"when"`
+	GroupBy        []string   `yaml:"group_by"`        // Default [body]
+	Window         string     `yaml:"window"`          // Go duration, required
+	CountAttribute string     `yaml:"count_attribute"` // Default aggregate_count
+}
+
+// Build compiles the rule's body pattern and condition and parses its window
+func (af *AggregateRuleFile) Build() (*AggregateRule, error) {
+	if af.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if af.Body == "" && af.When == nil {
+		return nil, fmt.Errorf("at least one of body or when is required")
+	}
+	if af.Window == "" {
+		return nil, fmt.Errorf("window is required")
+	}
+	window, err := time.ParseDuration(af.Window)
+	if err != nil || window <= 0 {
+		return nil, fmt.Errorf("invalid window %q", af.Window)
+	}
+
+	rule := &AggregateRule{
+		Name:           af.Name,
+		GroupBy:        af.GroupBy,
+		Window:         window,
+		CountAttribute: af.CountAttribute,
+	}
+	if len(rule.GroupBy) == 0 {
+		rule.GroupBy = []string{"body"}
+	}
+	if rule.CountAttribute == "" {
+		rule.CountAttribute = DefaultAggregateCountAttribute
+	}
+	if af.Body != "" {
+		re, err := regexp.Compile(af.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body regex %q: %w", af.Body, err)
+		}
+		rule.Body = re
+	}
+	when, err := af.When.Build()
+	if err != nil {
+		return nil, fmt.Errorf("when: %w", err)
+	}
+	rule.When = when
+	return rule, nil
+}
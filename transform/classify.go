@@ -0,0 +1,70 @@
+// ABOUTME: Lightweight log body type detection.
+// ABOUTME: Classifies bodies as json, xml, stacktrace, logfmt, or plaintext.
+
+package transform
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// Body types reported by ClassifyBody
+const (
+	BodyTypeJSON       = "json"
+	BodyTypeXML        = "xml"
+	BodyTypeStacktrace = "stacktrace"
+	BodyTypeLogfmt     = "logfmt"
+	BodyTypePlaintext  = "plaintext"
+	BodyTypeStructured = "structured" // Non-string OTLP body (kvlist, array, number, ...)
+	BodyTypeEmpty      = "empty"
+)
+
+// stackFrame matches a single frame line from Java, Python, Go, or .NET stack traces
+var stackFrame = regexp.MustCompile(`(?m)^\s+at \S+|^\s*File ".*", line \d+|^goroutine \d+ \[|^Traceback \(most recent call last\)|^\s*\.\.\. \d+ more$|^Caused by: `)
+
+// logfmtPair matches one key=value pair, with an optional quoted value
+var logfmtPair = regexp.MustCompile(`(?:^|\s)[A-Za-z_][\w.\-]*=(?:"(?:[^"\\]|\\.)*"|\S*)`)
+
+// ClassifyBody detects the format of a log body
+func ClassifyBody(body *commonpb.AnyValue) string {
+	if body == nil || body.GetValue() == nil {
+		return BodyTypeEmpty
+	}
+	sv, ok := body.GetValue().(*commonpb.AnyValue_StringValue)
+	if !ok {
+		return BodyTypeStructured
+	}
+
+	s := strings.TrimSpace(sv.StringValue)
+	switch {
+	case s == "":
+		return BodyTypeEmpty
+	case (s[0] == '{' || s[0] == '[') && json.Valid([]byte(s)):
+		return BodyTypeJSON
+	case s[0] == '<' && s[len(s)-1] == '>':
+		return BodyTypeXML
+	case stackFrame.MatchString(sv.StringValue):
+		return BodyTypeStacktrace
+	case isLogfmt(s):
+		return BodyTypeLogfmt
+	}
+	return BodyTypePlaintext
+}
+
+// isLogfmt reports whether a body is mostly key=value pairs: at least two pairs,
+// with no more than a fifth of the text outside them
+func isLogfmt(s string) bool {
+	pairs := logfmtPair.FindAllStringIndex(s, -1)
+	if len(pairs) < 2 {
+		return false
+	}
+	covered := 0
+	for _, p := range pairs {
+		covered += p[1] - p[0]
+	}
+	rest := len(s) - covered
+	return rest*5 <= len(s)
+}
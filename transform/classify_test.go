@@ -0,0 +1,48 @@
+// ABOUTME: Tests for log body type detection.
+// ABOUTME: Covers each body type and borderline plaintext cases.
+
+package transform
+
+import (
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+func TestClassifyBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"json object", `{"level":"info","msg":"started"}`, BodyTypeJSON},
+		{"json array", ` [1, 2, 3] `, BodyTypeJSON},
+		{"broken json", `{"level":"info"`, BodyTypePlaintext},
+		{"xml", `<event><id>1</id></event>`, BodyTypeXML},
+		{"java stacktrace", "java.lang.NullPointerException\n\tat com.example.A.b(A.java:10)", BodyTypeStacktrace},
+		{"java frame only", "\tat com.example.A.b(A.java:10)", BodyTypeStacktrace},
+		{"python traceback", "Traceback (most recent call last):\n  File \"app.py\", line 3, in <module>", BodyTypeStacktrace},
+		{"go panic", "panic: boom\n\ngoroutine 1 [running]:\nmain.main()", BodyTypeStacktrace},
+		{"logfmt", `level=info msg="request done" status=200 duration=12ms`, BodyTypeLogfmt},
+		{"prose with one pair", `Connected to db host=10.0.0.5 after several retries`, BodyTypePlaintext},
+		{"plaintext", `Server started on port 8080`, BodyTypePlaintext},
+		{"whitespace", "   ", BodyTypeEmpty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyBody(stringBody(tt.body)); got != tt.want {
+				t.Errorf("ClassifyBody(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyBody_NonStringBodies(t *testing.T) {
+	if got := ClassifyBody(nil); got != BodyTypeEmpty {
+		t.Errorf("nil body = %q, want %q", got, BodyTypeEmpty)
+	}
+	kv := &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{}}}
+	if got := ClassifyBody(kv); got != BodyTypeStructured {
+		t.Errorf("kvlist body = %q, want %q", got, BodyTypeStructured)
+	}
+}
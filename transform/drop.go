@@ -0,0 +1,87 @@
+// ABOUTME: Drop rules that discard records by body or attribute conditions.
+// ABOUTME: Evaluated after sampling and the allowlist, before any transforms run.
+
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// DropRule discards records whose body matches Body and that satisfy When.
+// At least one of the two must be set.
+type DropRule struct {
+	Name string
+	Body *regexp.Regexp // nil = any body
+	When *Match         // nil = any record
+
+	dropped atomic.Int64
+}
+
+// Matches reports whether the rule drops the record
+func (r *DropRule) Matches(res *resourcepb.Resource, lr *logspb.LogRecord) bool {
+	if r.Body != nil && !r.Body.MatchString(lr.GetBody().GetStringValue()) {
+		return false
+	}
+	return r.When.Matches(res, lr)
+}
+
+// Dropped returns how many records the rule has dropped
+func (r *DropRule) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// ShouldDrop returns the first drop rule matching the record, counting the drop, or nil
+func (cfg *Config) ShouldDrop(res *resourcepb.Resource, lr *logspb.LogRecord) *DropRule {
+	rule := cfg.MatchDropRule(res, lr)
+	if rule != nil {
+		rule.dropped.Add(1)
+	}
+	return rule
+}
+
+// MatchDropRule returns the first drop rule matching the record without counting it, or nil
+func (cfg *Config) MatchDropRule(res *resourcepb.Resource, lr *logspb.LogRecord) *DropRule {
+	for _, rule := range cfg.DropRules {
+		if rule.Matches(res, lr) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// DropRuleFile is the YAML representation of a DropRule
+type DropRuleFile struct {
+	Name string     `yaml:"name"`
+	Body string     `yaml:"body"`
+	When *MatchFile `yaml:"when"`
+}
+
+// Build compiles the rule's body pattern and condition
+func (df *DropRuleFile) Build() (*DropRule, error) {
+	if df.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if df.Body == "" && df.When == nil {
+		return nil, fmt.Errorf("at least one of body or when is required")
+	}
+
+	rule := &DropRule{Name: df.Name}
+	if df.Body != "" {
+		re, err := regexp.Compile(df.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body regex %q: %w", df.Body, err)
+		}
+		rule.Body = re
+	}
+	when, err := df.When.Build()
+	if err != nil {
+		return nil, fmt.Errorf("when: %w", err)
+	}
+	rule.When = when
+	return rule, nil
+}
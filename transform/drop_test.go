@@ -0,0 +1,96 @@
+// ABOUTME: Tests for drop rules.
+// ABOUTME: Covers body and attribute conditions, first-match order, and per-rule counts.
+
+package transform
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestDropRule_BodyAndAttributeConditions(t *testing.T) {
+	rule := &DropRule{
+		Name: "health-checks",
+		Body: regexp.MustCompile(`"GET /health`),
+		When: &Match{Attributes: map[string]*regexp.Regexp{"cf_source_type": regexp.MustCompile(`^RTR$`)}},
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		body   string
+		want   bool
+	}{
+		{"router health check", "RTR", `app - "GET /health HTTP/1.1" 200`, true},
+		{"router other path", "RTR", `app - "GET /api HTTP/1.1" 200`, false},
+		{"app log mentioning health", "APP/PROC/WEB", `"GET /health`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lr := makeLogRecord(map[string]string{"cf_source_type": tt.source})
+			lr.Body = stringBody(tt.body)
+			if got := rule.Matches(nil, lr); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldDrop_FirstMatchCounted(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DropRules = []*DropRule{
+		{Name: "noisy", Body: regexp.MustCompile(`heartbeat`)},
+		{Name: "catch-all", Body: regexp.MustCompile(`.`)},
+	}
+
+	lr := makeLogRecord(nil)
+	lr.Body = stringBody("heartbeat ok")
+	rule := cfg.ShouldDrop(nil, lr)
+	if rule == nil || rule.Name != "noisy" {
+		t.Fatalf("expected noisy rule, got %v", rule)
+	}
+	if cfg.DropRules[0].Dropped() != 1 || cfg.DropRules[1].Dropped() != 0 {
+		t.Errorf("counts = %d/%d, want 1/0", cfg.DropRules[0].Dropped(), cfg.DropRules[1].Dropped())
+	}
+
+	empty := makeLogRecord(nil)
+	if rule := cfg.ShouldDrop(nil, empty); rule != nil {
+		t.Errorf("record with empty body should be kept, dropped by %s", rule.Name)
+	}
+}
+
+func TestMatchDropRule_DoesNotCount(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DropRules = []*DropRule{{Name: "noisy", Body: regexp.MustCompile(`heartbeat`)}}
+
+	lr := makeLogRecord(nil)
+	lr.Body = stringBody("heartbeat ok")
+	if rule := cfg.MatchDropRule(nil, lr); rule == nil || rule.Name != "noisy" {
+		t.Fatalf("expected noisy rule, got %v", rule)
+	}
+	if got := cfg.DropRules[0].Dropped(); got != 0 {
+		t.Errorf("Dropped() = %d, want 0", got)
+	}
+}
+
+func TestDropRuleFile_Build(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    DropRuleFile
+		wantErr bool
+	}{
+		{"body only", DropRuleFile{Name: "a", Body: `x`}, false},
+		{"when only", DropRuleFile{Name: "a", When: &MatchFile{Apps: []string{"noisy"}}}, false},
+		{"missing name", DropRuleFile{Body: `x`}, true},
+		{"no conditions", DropRuleFile{Name: "a"}, true},
+		{"bad regex", DropRuleFile{Name: "a", Body: `(`}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.file.Build()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
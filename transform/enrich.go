@@ -0,0 +1,84 @@
+// ABOUTME: Static attribute enrichment (add-fields) transform.
+// ABOUTME: Injects configured constant attributes onto every or matching records, or their resource.
+
+package transform
+
+import (
+	"sort"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Enrichment adds constant attributes to records matching an optional condition
+type Enrichment struct {
+	Attributes map[string]string // Attribute name → constant value
+	When       *Match            // nil = every record
+	Overwrite  bool              // Replace existing values instead of skipping them
+	Resource   bool              // Add to the record's resource instead of the record
+}
+
+// enrich applies the enrichment to a record. Returns one action per attribute added.
+func (e *Enrichment) enrich(res *resourcepb.Resource, lr *logspb.LogRecord) []TransformAction {
+	if e.Resource || !e.When.Matches(res, lr) {
+		return nil
+	}
+	var actions []TransformAction
+	lr.Attributes, actions = e.add(lr.Attributes, ActionEnrich, "Added: ")
+	return actions
+}
+
+// enrichResource applies a resource enrichment for a record to out, the copy
+// of its resource res. Returns one action per attribute added.
+func (e *Enrichment) enrichResource(res, out *resourcepb.Resource, lr *logspb.LogRecord) []TransformAction {
+	if !e.Resource || !e.When.Matches(res, lr) {
+		return nil
+	}
+	var actions []TransformAction
+	out.Attributes, actions = e.add(out.Attributes, ActionResourceEnrich, "Added resource: ")
+	return actions
+}
+
+// add sets the enrichment's attributes on attrs, reporting each as an action of
+// the given type with the detail prefix
+func (e *Enrichment) add(attrs []*commonpb.KeyValue, typ ActionType, prefix string) ([]*commonpb.KeyValue, []TransformAction) {
+	// Sort keys so actions are reported in a stable order
+	keys := make([]string, 0, len(e.Attributes))
+	for key := range e.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var actions []TransformAction
+	for _, key := range keys {
+		if !e.Overwrite && findKey(attrs, key) != nil {
+			continue
+		}
+		attrs = setKey(attrs, key, e.Attributes[key])
+		actions = append(actions, TransformAction{Type: typ, Rule: key, Detail: prefix + key + "=" + e.Attributes[key]})
+	}
+	return attrs, actions
+}
+
+// hasAttribute reports whether the record has an attribute with the given key
+func hasAttribute(lr *logspb.LogRecord, key string) bool {
+	return findKey(lr.GetAttributes(), key) != nil
+}
+
+// EnrichmentFile is the YAML representation of an Enrichment
+type EnrichmentFile struct {
+	Attributes map[string]string `yaml:"attributes"`
+	When       *MatchFile        `yaml:"when"`
+	Overwrite  bool              `yaml:"overwrite"`
+	Resource   bool              `yaml:"resource"`
+}
+
+// Build compiles the enrichment's condition
+func (ef *EnrichmentFile) Build() (*Enrichment, error) {
+	when, err := ef.When.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &Enrichment{Attributes: ef.Attributes, When: when, Overwrite: ef.Overwrite, Resource: ef.Resource}, nil
+}
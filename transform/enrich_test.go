@@ -0,0 +1,58 @@
+// ABOUTME: Tests for static attribute enrichment.
+// ABOUTME: Covers unconditional, conditional, and overwrite behavior.
+
+package transform
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEnrichment_AddsToEveryRecord(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Enrichments = []*Enrichment{{Attributes: map[string]string{"environment": "lab", "pipeline_version": "2"}}}
+
+	lr := makeLogRecord(map[string]string{"cf_app_name": "my-app"})
+	_, actions := ApplyWithConfig(nil, lr, cfg)
+
+	if getAttr(lr, "environment") != "lab" || getAttr(lr, "pipeline_version") != "2" {
+		t.Errorf("enriched attributes missing: %v", lr.GetAttributes())
+	}
+	if !slices.Contains(details(actions), "Added: environment=lab") || !slices.Contains(details(actions), "Added: pipeline_version=2") {
+		t.Errorf("actions missing enrichment: %v", actions)
+	}
+}
+
+func TestEnrichment_OnlyMatchingRecords(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Enrichments = []*Enrichment{{
+		Attributes: map[string]string{"tier": "gold"},
+		When:       &Match{Apps: []string{"payment-service"}},
+	}}
+
+	match := makeLogRecord(map[string]string{"cf_app_name": "payment-service"})
+	ApplyWithConfig(nil, match, cfg)
+	if getAttr(match, "tier") != "gold" {
+		t.Error("matching record should be enriched")
+	}
+
+	other := makeLogRecord(map[string]string{"cf_app_name": "other"})
+	ApplyWithConfig(nil, other, cfg)
+	if getAttr(other, "tier") != "" {
+		t.Error("non-matching record should not be enriched")
+	}
+}
+
+func TestEnrichment_ExistingValueKeptUnlessOverwrite(t *testing.T) {
+	keep := &Enrichment{Attributes: map[string]string{"environment": "lab"}}
+	lr := makeLogRecord(map[string]string{"environment": "prod"})
+	if actions := keep.enrich(nil, lr); len(actions) != 0 || getAttr(lr, "environment") != "prod" {
+		t.Errorf("existing value should be kept, got %q (actions %v)", getAttr(lr, "environment"), actions)
+	}
+
+	overwrite := &Enrichment{Attributes: map[string]string{"environment": "lab"}, Overwrite: true}
+	overwrite.enrich(nil, lr)
+	if getAttr(lr, "environment") != "lab" {
+		t.Errorf("overwrite should replace value, got %q", getAttr(lr, "environment"))
+	}
+}
@@ -0,0 +1,97 @@
+// ABOUTME: Field extraction rules that parse the log body into attributes.
+// ABOUTME: Rules use named-capture regexes or grok expressions, optionally gated by a Match.
+
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Extraction sets an attribute for each named capture group that matches the body
+type Extraction struct {
+	Name    string
+	Pattern *regexp.Regexp
+	When    *Match // nil = every record
+}
+
+// extract applies the rule to a record. Returns the action taken, or false if nothing matched.
+func (e *Extraction) extract(res *resourcepb.Resource, lr *logspb.LogRecord) (TransformAction, bool) {
+	if !e.When.Matches(res, lr) {
+		return TransformAction{}, false
+	}
+	body := lr.GetBody().GetStringValue()
+	if body == "" {
+		return TransformAction{}, false
+	}
+	match := e.Pattern.FindStringSubmatch(body)
+	if match == nil {
+		return TransformAction{}, false
+	}
+
+	fields := 0
+	for i, name := range e.Pattern.SubexpNames() {
+		if name == "" || match[i] == "" {
+			continue
+		}
+		SetAttribute(lr, name, match[i])
+		fields++
+	}
+	if fields == 0 {
+		return TransformAction{}, false
+	}
+	return TransformAction{Type: ActionExtract, Rule: e.Name, Detail: "Extracted " + strconv.Itoa(fields) + " fields (" + e.Name + ")"}, true
+}
+
+// ExtractionFile is the YAML representation of an Extraction. Exactly one of
+// Regex or Grok must be set.
+type ExtractionFile struct {
+	Name  string     `yaml:"name"`
+	Regex string     `yaml:"regex"`
+	Grok  string     `yaml:"grok"`
+	When  *MatchFile `yaml:"when"`
+}
+
+// Build compiles the rule's pattern and condition. Custom grok patterns take
+// precedence over the built-in library.
+func (ef *ExtractionFile) Build(grokPatterns map[string]string) (*Extraction, error) {
+	if (ef.Regex == "") == (ef.Grok == "") {
+		return nil, fmt.Errorf("exactly one of regex or grok is required")
+	}
+
+	var re *regexp.Regexp
+	var err error
+	if ef.Grok != "" {
+		re, err = CompileGrok(ef.Grok, grokPatterns)
+	} else if re, err = regexp.Compile(ef.Regex); err != nil {
+		err = fmt.Errorf("invalid regex %q: %w", ef.Regex, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(namedGroups(re)) == 0 {
+		return nil, fmt.Errorf("pattern has no named capture groups")
+	}
+
+	when, err := ef.When.Build()
+	if err != nil {
+		return nil, fmt.Errorf("when: %w", err)
+	}
+
+	return &Extraction{Name: ef.Name, Pattern: re, When: when}, nil
+}
+
+// namedGroups returns the names of the pattern's named capture groups
+func namedGroups(re *regexp.Regexp) []string {
+	var names []string
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,104 @@
+// ABOUTME: Tests for body field extraction rules.
+// ABOUTME: Covers regex and grok rules, conditions, and ordering relative to redaction.
+
+package transform
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+func stringBody(body string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}}
+}
+
+func TestExtraction_SetsNamedCaptures(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Extractions = []*Extraction{{
+		Name:    "timing",
+		Pattern: regexp.MustCompile(`status=(?P<status_code>\d+) took (?P<duration_ms>\d+)ms`),
+	}}
+
+	lr := makeLogRecord(nil)
+	lr.Body = stringBody("request done status=503 took 87ms")
+	_, actions := ApplyWithConfig(nil, lr, cfg)
+
+	if getAttr(lr, "status_code") != "503" || getAttr(lr, "duration_ms") != "87" {
+		t.Errorf("extracted attributes wrong: %v", lr.GetAttributes())
+	}
+	if !slices.Contains(details(actions), "Extracted 2 fields (timing)") {
+		t.Errorf("actions missing extraction: %v", actions)
+	}
+}
+
+func TestExtraction_NoMatchNoAction(t *testing.T) {
+	x := &Extraction{Name: "timing", Pattern: regexp.MustCompile(`took (?P<duration_ms>\d+)ms`)}
+	lr := makeLogRecord(nil)
+	lr.Body = stringBody("nothing to see")
+
+	if action, ok := x.extract(nil, lr); ok {
+		t.Errorf("expected no action, got %q", action)
+	}
+	if len(lr.GetAttributes()) != 0 {
+		t.Errorf("expected no attributes, got %v", lr.GetAttributes())
+	}
+}
+
+func TestExtraction_WhenCondition(t *testing.T) {
+	x := &Extraction{
+		Name:    "rtr",
+		Pattern: regexp.MustCompile(`" (?P<status_code>\d{3}) `),
+		When:    &Match{Attributes: map[string]*regexp.Regexp{"cf_source_type": regexp.MustCompile(`^RTR$`)}},
+	}
+
+	app := makeLogRecord(map[string]string{"cf_source_type": "APP/PROC/WEB"})
+	app.Body = stringBody(`"GET / HTTP/1.1" 200 0`)
+	if _, ok := x.extract(nil, app); ok {
+		t.Error("non-router record should not be extracted")
+	}
+
+	rtr := makeLogRecord(map[string]string{"cf_source_type": "RTR"})
+	rtr.Body = stringBody(`"GET / HTTP/1.1" 200 0`)
+	if _, ok := x.extract(nil, rtr); !ok || getAttr(rtr, "status_code") != "200" {
+		t.Errorf("router record should be extracted, got %v", rtr.GetAttributes())
+	}
+}
+
+func TestExtraction_RunsAfterRedaction(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Extractions = []*Extraction{{Name: "card", Pattern: regexp.MustCompile(`card=(?P<card>\S+)`)}}
+
+	lr := makeLogRecord(nil)
+	lr.Body = stringBody("card=4111-1111-1111-1111")
+	ApplyWithConfig(nil, lr, cfg)
+
+	if got := getAttr(lr, "card"); got != "[PCI-REDACTED]" {
+		t.Errorf("card = %q, want redacted value", got)
+	}
+}
+
+func TestExtractionFile_Build(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    ExtractionFile
+		wantErr bool
+	}{
+		{"regex", ExtractionFile{Regex: `(?P<x>\d+)`}, false},
+		{"grok", ExtractionFile{Grok: `%{INT:x}`}, false},
+		{"both", ExtractionFile{Regex: `(?P<x>\d+)`, Grok: `%{INT:x}`}, true},
+		{"neither", ExtractionFile{}, true},
+		{"no named groups", ExtractionFile{Regex: `(\d+)`}, true},
+		{"bad regex", ExtractionFile{Regex: `(?P<x>`}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.file.Build(nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// ABOUTME: YAML transform config file loading.
+// ABOUTME: File settings are layered on top of DefaultConfig.
+
+package transform
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+
+	"otlp-mock-receiver/ottl"
+)
+
+// FileConfig is the YAML representation of a transform config file
+type FileConfig struct {
+	FieldRenames        map[string]string `yaml:"field_renames"`
+	FieldsToDelete      []string          `yaml:"fields_to_delete"`
+	MaxBodyLength       *int              `yaml:"max_body_length"`
+	MaxBodyUnit         string            `yaml:"max_body_unit"` // bytes (default) or runes
+	MaxAttributeLength  *int              `yaml:"max_attribute_length"`
+	OversizedAttributes string            `yaml:"oversized_attributes"` // truncate (default) or drop
+	MaxRecordBytes      *int              `yaml:"max_record_bytes"`
+	PCIPatterns         []string          `yaml:"pci_patterns"`
+	XMLRedactElements   []string          `yaml:"xml_redact_elements"`
+	SecretKeys          *[]string         `yaml:"secret_keys"` // Enables scrubbing of these keys; [] disables
+
+	ResourceFieldRenames   map[string]string `yaml:"resource_field_renames"`
+	ResourceFieldsToDelete []string          `yaml:"resource_fields_to_delete"`
+
+	SeverityNormalization *SeverityNormalizationFile `yaml:"severity_normalization"`
+	Conditions            *ConditionsFile            `yaml:"conditions"`
+	Profiles              map[string]*ProfileFile    `yaml:"profiles"`
+	BodyTypeAttribute     *string                    `yaml:"body_type_attribute"` // "" disables tagging
+	Drop                  []*DropRuleFile            `yaml:"drop"`
+	Logfmt                *LogfmtFile                `yaml:"logfmt"`
+	GrokPatterns          map[string]string          `yaml:"grok_patterns"`
+	Extract               []*ExtractionFile          `yaml:"extract"`
+	Enrich                []*EnrichmentFile          `yaml:"enrich"`
+	Aggregate             []*AggregateRuleFile       `yaml:"aggregate"`
+	LogStatements         []string                   `yaml:"log_statements"` // OTTL, log context
+}
+
+// ConditionsFile gates each rule type on match conditions
+type ConditionsFile struct {
+	Rename   *MatchFile `yaml:"rename"`
+	Delete   *MatchFile `yaml:"delete"`
+	Redact   *MatchFile `yaml:"redact"`
+	Truncate *MatchFile `yaml:"truncate"`
+}
+
+// SeverityNormalizationFile configures severity inference
+type SeverityNormalizationFile struct {
+	Enabled     *bool             `yaml:"enabled"`      // Default true when the section is present
+	InspectBody *bool             `yaml:"inspect_body"` // Default true
+	Mapping     map[string]string `yaml:"mapping"`      // Keyword -> severity name or number, merged over defaults
+}
+
+// LoadConfig reads a YAML transform config file and applies it on top of DefaultConfig
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fc FileConfig
+	if err := yaml.UnmarshalStrict(data, &fc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return fc.Build()
+}
+
+// Build converts the file representation into a Config layered over DefaultConfig
+func (fc *FileConfig) Build() (*Config, error) {
+	cfg := DefaultConfig()
+
+	if fc.FieldRenames != nil {
+		cfg.FieldRenames = fc.FieldRenames
+	}
+	if fc.FieldsToDelete != nil {
+		cfg.FieldsToDelete = fc.FieldsToDelete
+	}
+	if fc.ResourceFieldRenames != nil {
+		cfg.ResourceFieldRenames = fc.ResourceFieldRenames
+	}
+	if fc.ResourceFieldsToDelete != nil {
+		cfg.ResourceFieldsToDelete = fc.ResourceFieldsToDelete
+	}
+	if fc.MaxBodyLength != nil {
+		cfg.MaxBodyLength = *fc.MaxBodyLength
+	}
+	switch fc.MaxBodyUnit {
+	case "", TruncateBytes, TruncateRunes:
+		cfg.MaxBodyUnit = fc.MaxBodyUnit
+	default:
+		return nil, fmt.Errorf("max_body_unit: must be %s or %s, got %q", TruncateBytes, TruncateRunes, fc.MaxBodyUnit)
+	}
+	if fc.MaxAttributeLength != nil {
+		cfg.MaxAttributeLength = *fc.MaxAttributeLength
+	}
+	switch fc.OversizedAttributes {
+	case "", AttributeTruncate, AttributeDrop:
+		cfg.OversizedAttributes = fc.OversizedAttributes
+	default:
+		return nil, fmt.Errorf("oversized_attributes: must be %s or %s, got %q", AttributeTruncate, AttributeDrop, fc.OversizedAttributes)
+	}
+	if fc.MaxRecordBytes != nil {
+		cfg.MaxRecordBytes = *fc.MaxRecordBytes
+	}
+	if fc.PCIPatterns != nil {
+		patterns, err := compilePatterns(fc.PCIPatterns)
+		if err != nil {
+			return nil, fmt.Errorf("pci_patterns: %w", err)
+		}
+		cfg.PCIPatterns = patterns
+	}
+	if fc.SecretKeys != nil {
+		cfg.SecretScrubbing = nil
+		if len(*fc.SecretKeys) > 0 {
+			s, err := NewSecretScrubbing(*fc.SecretKeys)
+			if err != nil {
+				return nil, fmt.Errorf("secret_keys: %w", err)
+			}
+			cfg.SecretScrubbing = s
+		}
+	}
+	for _, element := range fc.XMLRedactElements {
+		x, err := NewXMLRedaction(element)
+		if err != nil {
+			return nil, fmt.Errorf("xml_redact_elements: %w", err)
+		}
+		cfg.XMLRedactions = append(cfg.XMLRedactions, x)
+	}
+
+	if sn := fc.SeverityNormalization; sn != nil {
+		if sn.Enabled != nil && !*sn.Enabled {
+			cfg.SeverityNormalization = nil
+		} else {
+			if cfg.SeverityNormalization == nil {
+				cfg.SeverityNormalization = DefaultSeverityNormalization()
+			}
+			if sn.InspectBody != nil {
+				cfg.SeverityNormalization.InspectBody = *sn.InspectBody
+			}
+			for word, name := range sn.Mapping {
+				sev, err := ParseSeverity(name)
+				if err != nil {
+					return nil, fmt.Errorf("severity_normalization.mapping[%s]: %w", word, err)
+				}
+				cfg.SeverityNormalization.Keywords[strings.ToLower(word)] = sev
+			}
+		}
+	}
+
+	if c := fc.Conditions; c != nil {
+		gates := []struct {
+			name string
+			file *MatchFile
+			dst  **Match
+		}{
+			{"rename", c.Rename, &cfg.RenameWhen},
+			{"delete", c.Delete, &cfg.DeleteWhen},
+			{"redact", c.Redact, &cfg.RedactWhen},
+			{"truncate", c.Truncate, &cfg.TruncateWhen},
+		}
+		for _, g := range gates {
+			m, err := g.file.Build()
+			if err != nil {
+				return nil, fmt.Errorf("conditions.%s: %w", g.name, err)
+			}
+			*g.dst = m
+		}
+	}
+
+	if len(fc.Profiles) > 0 {
+		cfg.Profiles = make(map[string]*Profile, len(fc.Profiles))
+		for app, pf := range fc.Profiles {
+			if pf == nil {
+				pf = &ProfileFile{}
+			}
+			profile, err := pf.Build()
+			if err != nil {
+				return nil, fmt.Errorf("profiles.%s: %w", app, err)
+			}
+			cfg.Profiles[strings.ToLower(app)] = profile
+		}
+	}
+
+	if fc.BodyTypeAttribute != nil {
+		cfg.BodyTypeAttribute = *fc.BodyTypeAttribute
+	}
+
+	names := make(map[string]bool)
+	for i, df := range fc.Drop {
+		rule, err := df.Build()
+		if err != nil {
+			return nil, fmt.Errorf("drop[%d]: %w", i, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("drop[%d]: duplicate rule name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+		cfg.DropRules = append(cfg.DropRules, rule)
+	}
+
+	if fc.Logfmt != nil {
+		p, err := fc.Logfmt.Build()
+		if err != nil {
+			return nil, fmt.Errorf("logfmt.when: %w", err)
+		}
+		cfg.Logfmt = p
+	}
+
+	for i, xf := range fc.Extract {
+		if xf.Name == "" {
+			xf.Name = fmt.Sprintf("extract[%d]", i)
+		}
+		x, err := xf.Build(fc.GrokPatterns)
+		if err != nil {
+			return nil, fmt.Errorf("extract[%d]: %w", i, err)
+		}
+		cfg.Extractions = append(cfg.Extractions, x)
+	}
+
+	for i, ef := range fc.Enrich {
+		e, err := ef.Build()
+		if err != nil {
+			return nil, fmt.Errorf("enrich[%d].when: %w", i, err)
+		}
+		cfg.Enrichments = append(cfg.Enrichments, e)
+	}
+
+	names = make(map[string]bool)
+	for i, af := range fc.Aggregate {
+		rule, err := af.Build()
+		if err != nil {
+			return nil, fmt.Errorf("aggregate[%d]: %w", i, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("aggregate[%d]: duplicate rule name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+		cfg.AggregateRules = append(cfg.AggregateRules, rule)
+	}
+
+	for i, text := range fc.LogStatements {
+		stmt, err := ottl.Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("log_statements[%d]: %w", i, err)
+		}
+		cfg.Statements = append(cfg.Statements, stmt)
+	}
+
+	return cfg, nil
+}
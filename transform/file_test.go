@@ -0,0 +1,384 @@
+// ABOUTME: Tests for YAML transform config loading.
+// ABOUTME: Covers layering over defaults and validation errors.
+
+package transform
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transforms.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_EmptyFileMatchesDefaults(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, ""))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	def := DefaultConfig()
+	if cfg.MaxBodyLength != def.MaxBodyLength {
+		t.Errorf("MaxBodyLength = %d, want %d", cfg.MaxBodyLength, def.MaxBodyLength)
+	}
+	if len(cfg.FieldRenames) != len(def.FieldRenames) {
+		t.Errorf("FieldRenames has %d entries, want %d", len(cfg.FieldRenames), len(def.FieldRenames))
+	}
+	if cfg.SeverityNormalization != nil || cfg.SecretScrubbing != nil || cfg.BodyTypeAttribute != "" {
+		t.Error("severity normalization, secret scrubbing, and body type tagging should be off by default")
+	}
+}
+
+func TestLoadConfig_OverridesAndSeverityMapping(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+max_body_length: 100
+severity_normalization:
+  inspect_body: false
+  mapping:
+    sev3: ERROR
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.MaxBodyLength != 100 {
+		t.Errorf("MaxBodyLength = %d, want 100", cfg.MaxBodyLength)
+	}
+	if cfg.SeverityNormalization.InspectBody {
+		t.Error("InspectBody should be false")
+	}
+	if got := cfg.SeverityNormalization.Keywords["sev3"]; got != logspb.SeverityNumber_SEVERITY_NUMBER_ERROR {
+		t.Errorf("sev3 mapped to %v, want ERROR", got)
+	}
+	if _, ok := cfg.SeverityNormalization.Keywords["warn"]; !ok {
+		t.Error("custom mapping should merge with default keywords")
+	}
+}
+
+func TestLoadConfig_DisableSeverityNormalization(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, "severity_normalization:\n  enabled: false\n"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.SeverityNormalization != nil {
+		t.Error("severity normalization should be disabled")
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	tests := map[string]string{
+		"unknown severity": "severity_normalization:\n  mapping:\n    x: LOUD\n",
+		"invalid regex":    "pci_patterns: ['(']\n",
+		"unknown key":      "not_a_setting: true\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadConfig(writeConfig(t, content)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadConfig_Conditions(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+conditions:
+  truncate:
+    apps: [chatty-app]
+    min_severity: debug
+    attributes:
+      cf_space_name: ^dev
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	m := cfg.TruncateWhen
+	if m == nil {
+		t.Fatal("TruncateWhen should be set")
+	}
+	if m.MinSeverity != logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG {
+		t.Errorf("MinSeverity = %v, want DEBUG", m.MinSeverity)
+	}
+	if len(m.Apps) != 1 || m.Apps[0] != "chatty-app" {
+		t.Errorf("Apps = %v", m.Apps)
+	}
+	if cfg.RenameWhen != nil {
+		t.Error("RenameWhen should remain nil")
+	}
+}
+
+func TestLoadConfig_InvalidConditionRegex(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, "conditions:\n  redact:\n    attributes:\n      x: '('\n"))
+	if err == nil || !strings.Contains(err.Error(), "conditions.redact") {
+		t.Errorf("expected conditions.redact error, got %v", err)
+	}
+}
+
+func TestLoadConfig_Enrich(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+enrich:
+  - attributes: {environment: lab}
+  - attributes: {tier: gold}
+    overwrite: true
+    when:
+      apps: [payment-service]
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Enrichments) != 2 {
+		t.Fatalf("expected 2 enrichments, got %d", len(cfg.Enrichments))
+	}
+	if e := cfg.Enrichments[1]; !e.Overwrite || e.When == nil || e.Attributes["tier"] != "gold" {
+		t.Errorf("unexpected second enrichment: %+v", e)
+	}
+}
+
+func TestLoadConfig_Extract(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+grok_patterns:
+  TICKET: '[A-Z]+-\d+'
+extract:
+  - name: gorouter
+    grok: '%{GOROUTER}'
+    when:
+      attributes: {cf_source_type: '^RTR'}
+  - regex: 'ticket (?P<ticket>\S+)'
+  - grok: 'ref %{TICKET:ref}'
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Extractions) != 3 {
+		t.Fatalf("expected 3 extractions, got %d", len(cfg.Extractions))
+	}
+	if cfg.Extractions[0].Name != "gorouter" || cfg.Extractions[0].When == nil {
+		t.Errorf("unexpected first extraction: %+v", cfg.Extractions[0])
+	}
+	if cfg.Extractions[1].Name != "extract[1]" {
+		t.Errorf("unnamed rule should default to its position, got %q", cfg.Extractions[1].Name)
+	}
+}
+
+func TestLoadConfig_ExtractInvalid(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, `
+extract:
+  - grok: '%{NOPE:x}'
+`))
+	if err == nil || !strings.Contains(err.Error(), "extract[0]") {
+		t.Errorf("expected extract[0] error, got %v", err)
+	}
+}
+
+func TestLoadConfig_Drop(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+drop:
+  - name: health-checks
+    body: 'GET /health'
+    when:
+      attributes: {cf_source_type: '^RTR$'}
+  - name: noisy-app
+    when:
+      apps: [chatty]
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.DropRules) != 2 || cfg.DropRules[0].Name != "health-checks" {
+		t.Errorf("unexpected drop rules: %+v", cfg.DropRules)
+	}
+
+	_, err = LoadConfig(writeConfig(t, `
+drop:
+  - {name: dup, body: a}
+  - {name: dup, body: b}
+`))
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("expected duplicate name error, got %v", err)
+	}
+}
+
+func TestLoadConfig_BodyTypeAttribute(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `max_body_length: 100`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.BodyTypeAttribute != "" {
+		t.Errorf("default BodyTypeAttribute = %q, want disabled", cfg.BodyTypeAttribute)
+	}
+
+	cfg, err = LoadConfig(writeConfig(t, `body_type_attribute: body_type`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.BodyTypeAttribute != "body_type" {
+		t.Errorf("BodyTypeAttribute = %q, want body_type", cfg.BodyTypeAttribute)
+	}
+}
+
+func TestLoadConfig_Aggregate(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+aggregate:
+  - name: not-found
+    body: '" 404 '
+    window: 30s
+  - name: errors-by-status
+    when:
+      attributes: {status: '^5'}
+    group_by: [status]
+    window: 1m
+    count_attribute: hits
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.AggregateRules) != 2 {
+		t.Fatalf("expected 2 aggregate rules, got %d", len(cfg.AggregateRules))
+	}
+	nf := cfg.AggregateRules[0]
+	if nf.Window != 30*time.Second || len(nf.GroupBy) != 1 || nf.GroupBy[0] != "body" || nf.CountAttribute != "aggregate_count" {
+		t.Errorf("defaults not applied: %+v", nf)
+	}
+	if cfg.AggregateRules[1].CountAttribute != "hits" {
+		t.Errorf("CountAttribute = %q, want hits", cfg.AggregateRules[1].CountAttribute)
+	}
+
+	for _, bad := range []string{
+		"aggregate:\n  - {name: a, body: x}\n",
+		"aggregate:\n  - {name: a, body: x, window: soon}\n",
+		"aggregate:\n  - {name: a, window: 1s}\n",
+	} {
+		if _, err := LoadConfig(writeConfig(t, bad)); err == nil || !strings.Contains(err.Error(), "aggregate[0]") {
+			t.Errorf("expected aggregate[0] error for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestLoadConfig_Logfmt(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+logfmt:
+  keys: [level, status]
+  prefix: "log."
+  when:
+    apps: [go-api]
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Logfmt == nil || !cfg.Logfmt.Keys["status"] || cfg.Logfmt.Prefix != "log." || cfg.Logfmt.When == nil {
+		t.Errorf("unexpected logfmt config: %+v", cfg.Logfmt)
+	}
+
+	cfg, err = LoadConfig(writeConfig(t, `max_body_length: 100`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Logfmt != nil {
+		t.Error("logfmt parsing should be disabled by default")
+	}
+}
+
+func TestLoadConfig_ResourceTransforms(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+resource_field_renames:
+  application_name: cf_app_name
+resource_fields_to_delete: [process_id]
+enrich:
+  - attributes: {environment: lab}
+    resource: true
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.ResourceFieldRenames["application_name"] != "cf_app_name" || len(cfg.ResourceFieldsToDelete) != 1 {
+		t.Errorf("resource rules not loaded: %v %v", cfg.ResourceFieldRenames, cfg.ResourceFieldsToDelete)
+	}
+	if len(cfg.Enrichments) != 1 || !cfg.Enrichments[0].Resource {
+		t.Errorf("resource enrichment not loaded: %+v", cfg.Enrichments)
+	}
+}
+
+func TestLoadConfig_XMLRedactElements(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `xml_redact_elements: [CardNumber, CVV]`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.XMLRedactions) != 2 || cfg.XMLRedactions[1].Element != "CVV" {
+		t.Errorf("unexpected XML redactions: %+v", cfg.XMLRedactions)
+	}
+
+	if _, err := LoadConfig(writeConfig(t, `xml_redact_elements: ["Card Number"]`)); err == nil || !strings.Contains(err.Error(), "xml_redact_elements") {
+		t.Errorf("expected xml_redact_elements error, got %v", err)
+	}
+}
+
+func TestLoadConfig_SecretKeys(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `secret_keys: [token, Client_Secret]`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !slices.Equal(cfg.SecretScrubbing.Keys, []string{"token", "client_secret"}) {
+		t.Errorf("Keys = %v", cfg.SecretScrubbing.Keys)
+	}
+
+	cfg, err = LoadConfig(writeConfig(t, `secret_keys: []`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.SecretScrubbing != nil {
+		t.Error("empty secret_keys should disable scrubbing")
+	}
+
+	cfg, _ = LoadConfig(writeConfig(t, `max_body_length: 100`))
+	if cfg.SecretScrubbing != nil {
+		t.Error("scrubbing should be off without secret_keys")
+	}
+}
+
+func TestLoadConfig_MaxBodyUnit(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, "max_body_unit: runes\n"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.MaxBodyUnit != TruncateRunes {
+		t.Errorf("MaxBodyUnit = %q, want runes", cfg.MaxBodyUnit)
+	}
+
+	_, err = LoadConfig(writeConfig(t, "max_body_unit: chars\n"))
+	if err == nil || !strings.Contains(err.Error(), "max_body_unit") {
+		t.Errorf("expected max_body_unit error, got %v", err)
+	}
+}
+
+func TestLoadConfig_SizeLimits(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+max_attribute_length: 256
+oversized_attributes: drop
+max_record_bytes: 10000
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.MaxAttributeLength != 256 || cfg.OversizedAttributes != AttributeDrop || cfg.MaxRecordBytes != 10000 {
+		t.Errorf("limits = %d %q %d", cfg.MaxAttributeLength, cfg.OversizedAttributes, cfg.MaxRecordBytes)
+	}
+
+	_, err = LoadConfig(writeConfig(t, "oversized_attributes: ignore\n"))
+	if err == nil || !strings.Contains(err.Error(), "oversized_attributes") {
+		t.Errorf("expected oversized_attributes error, got %v", err)
+	}
+}
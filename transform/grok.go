@@ -0,0 +1,105 @@
+// ABOUTME: Grok pattern expansion into Go regular expressions.
+// ABOUTME: Includes a built-in pattern library covering common log formats and gorouter access logs.
+
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxGrokDepth bounds pattern nesting so self-referencing patterns fail instead of looping
+const maxGrokDepth = 16
+
+// grokRef matches %{NAME} and %{NAME:field}
+var grokRef = regexp.MustCompile(`%\{([A-Za-z0-9_]+)(?::([^}]*))?\}`)
+
+// fieldName is the set of names usable as capture groups and attribute keys
+var fieldName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// GrokPatterns is the built-in pattern library, a subset of the Logstash core patterns
+var GrokPatterns = map[string]string{
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"INT":               `(?:[+-]?[0-9]+)`,
+	"BASE10NUM":         `(?:[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+))`,
+	"NUMBER":            `%{BASE10NUM}`,
+	"POSINT":            `\b[1-9][0-9]*\b`,
+	"NONNEGINT":         `\b[0-9]+\b`,
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)`,
+	"IPV6":              `(?:[A-Fa-f0-9]{0,4}:){2,7}[A-Fa-f0-9]{0,4}`,
+	"IP":                `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"TIMESTAMP_ISO8601": `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?)`,
+
+	// Cloud Foundry gorouter access log, e.g.
+	// app.example.com - [2024-01-15T10:30:00.123Z] "GET /api/users HTTP/1.1" 200 0 1234 "-" "curl/8.0" "10.0.0.1:5432" "10.0.1.5:61001" ... response_time:0.012 ...
+	"GOROUTER": `%{IPORHOST:host} - \[%{TIMESTAMP_ISO8601:request_time}\] "%{WORD:method} %{URIPATHPARAM:path} HTTP/%{NUMBER:http_version}" %{INT:status_code} %{INT:request_bytes} %{INT:response_bytes} %{QUOTEDSTRING:referer} %{QUOTEDSTRING:user_agent} "%{HOSTPORT:remote_addr}" "%{NOTSPACE:backend_addr}"(?:.*? response_time:%{NUMBER:response_time})?`,
+}
+
+// CompileGrok expands a grok expression into a regular expression. %{NAME:field}
+// becomes a named capture group; custom patterns take precedence over the built-ins.
+func CompileGrok(expr string, custom map[string]string) (*regexp.Regexp, error) {
+	expanded, err := expandGrok(expr, custom, 0)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grok expansion: %w", err)
+	}
+	return re, nil
+}
+
+// expandGrok replaces each %{...} reference with its pattern, recursively
+func expandGrok(expr string, custom map[string]string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok patterns nested more than %d deep (recursive pattern?)", maxGrokDepth)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range grokRef.FindAllStringSubmatchIndex(expr, -1) {
+		b.WriteString(expr[last:m[0]])
+		last = m[1]
+
+		name := expr[m[2]:m[3]]
+		pattern, ok := custom[name]
+		if !ok {
+			pattern, ok = GrokPatterns[name]
+		}
+		if !ok {
+			return "", fmt.Errorf("unknown grok pattern %q", name)
+		}
+		inner, err := expandGrok(pattern, custom, depth+1)
+		if err != nil {
+			return "", err
+		}
+
+		if m[4] < 0 {
+			b.WriteString("(?:" + inner + ")")
+			continue
+		}
+		field := expr[m[4]:m[5]]
+		if !fieldName.MatchString(field) {
+			return "", fmt.Errorf("invalid field name %q in %%{%s:%s} (use letters, digits, and underscores)", field, name, field)
+		}
+		b.WriteString("(?P<" + field + ">" + inner + ")")
+	}
+	b.WriteString(expr[last:])
+	return b.String(), nil
+}
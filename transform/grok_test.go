@@ -0,0 +1,90 @@
+// ABOUTME: Tests for grok pattern expansion.
+// ABOUTME: Covers named fields, custom patterns, and the built-in gorouter pattern.
+
+package transform
+
+import (
+	"testing"
+)
+
+func TestCompileGrok_NamedFields(t *testing.T) {
+	re, err := CompileGrok(`%{IPV4:client} %{WORD:method} %{URIPATHPARAM:path} took %{INT}ms`, nil)
+	if err != nil {
+		t.Fatalf("CompileGrok failed: %v", err)
+	}
+
+	m := re.FindStringSubmatch("10.0.0.7 GET /api/users?id=3 took 42ms")
+	if m == nil {
+		t.Fatal("expected a match")
+	}
+	got := map[string]string{}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			got[name] = m[i]
+		}
+	}
+	want := map[string]string{"client": "10.0.0.7", "method": "GET", "path": "/api/users?id=3"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unnamed reference should not capture, got fields %v", got)
+	}
+}
+
+func TestCompileGrok_CustomPatternOverridesBuiltin(t *testing.T) {
+	re, err := CompileGrok(`%{WORD:code}`, map[string]string{"WORD": `[A-Z]{3}-\d+`})
+	if err != nil {
+		t.Fatalf("CompileGrok failed: %v", err)
+	}
+	if m := re.FindStringSubmatch("ticket ABC-123"); m == nil || m[1] != "ABC-123" {
+		t.Errorf("custom pattern not used, got %v", m)
+	}
+}
+
+func TestCompileGrok_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		custom map[string]string
+	}{
+		{"unknown pattern", `%{NOPE:x}`, nil},
+		{"invalid field name", `%{WORD:http.status}`, nil},
+		{"recursive pattern", `%{LOOP}`, map[string]string{"LOOP": `a%{LOOP}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CompileGrok(tt.expr, tt.custom); err == nil {
+				t.Errorf("CompileGrok(%q) should fail", tt.expr)
+			}
+		})
+	}
+}
+
+func TestCompileGrok_Gorouter(t *testing.T) {
+	re, err := CompileGrok(`%{GOROUTER}`, nil)
+	if err != nil {
+		t.Fatalf("CompileGrok failed: %v", err)
+	}
+
+	line := `myapp.apps.example.com - [2024-01-15T10:30:00.123456789Z] "GET /api/users?page=2 HTTP/1.1" 200 0 1234 "-" "curl/8.4.0" "10.0.0.1:54321" "10.0.1.5:61001" x_forwarded_for:"-" x_forwarded_proto:"https" vcap_request_id:"abc" response_time:0.012345 gorouter_time:0.000123 app_id:"guid"`
+	m := re.FindStringSubmatch(line)
+	if m == nil {
+		t.Fatal("gorouter line did not match")
+	}
+	want := map[string]string{
+		"host":          "myapp.apps.example.com",
+		"method":        "GET",
+		"path":          "/api/users?page=2",
+		"status_code":   "200",
+		"response_time": "0.012345",
+		"backend_addr":  "10.0.1.5:61001",
+	}
+	for name, value := range want {
+		if got := m[re.SubexpIndex(name)]; got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
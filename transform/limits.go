@@ -0,0 +1,129 @@
+// ABOUTME: Attribute value and total record size limits, simulating backend field-size limits.
+// ABOUTME: Oversized attributes are truncated or dropped; oversized records lose body, then attributes.
+
+package transform
+
+import (
+	"sort"
+	"strconv"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// What to do with attribute values over MaxAttributeLength
+const (
+	AttributeTruncate = "truncate"
+	AttributeDrop     = "drop"
+)
+
+// limitAttributes truncates (or with AttributeDrop, removes) attribute values
+// over maxLen bytes. Non-string values can't be cut, so they're always removed.
+// Returns one action per attribute changed.
+func limitAttributes(lr *logspb.LogRecord, maxLen int, mode string) []TransformAction {
+	var actions []TransformAction
+	kept := lr.Attributes[:0]
+	for _, kv := range lr.Attributes {
+		size := valueSize(kv.GetValue())
+		if size <= maxLen {
+			kept = append(kept, kv)
+			continue
+		}
+		sv, ok := kv.GetValue().GetValue().(*commonpb.AnyValue_StringValue)
+		if mode == AttributeDrop || !ok {
+			actions = append(actions, dropAttributeAction(kv.GetKey(), size))
+			continue
+		}
+		cut := byteOffset(sv.StringValue, maxLen)
+		kv.Value = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: sv.StringValue[:cut]}}
+		kept = append(kept, kv)
+		actions = append(actions, TransformAction{
+			Type:   ActionTruncateAttribute,
+			Rule:   kv.GetKey(),
+			Detail: "Truncated attribute: " + kv.GetKey() + " from " + strconv.Itoa(size) + " to " + strconv.Itoa(cut) + " bytes",
+		})
+	}
+	lr.Attributes = kept
+	return actions
+}
+
+// limitRecord shrinks a record over maxBytes, measured as body plus attribute
+// keys and values. The body is truncated first, or removed if it is not a
+// string and so can't be cut; attributes are dropped, largest first, only
+// when they alone exceed the limit. Returns the actions taken.
+func limitRecord(lr *logspb.LogRecord, maxBytes int) []TransformAction {
+	size := recordSize(lr)
+	if size <= maxBytes {
+		return nil
+	}
+	actions := []TransformAction{{
+		Type:   ActionRecordLimit,
+		Detail: "Record over size limit: " + strconv.Itoa(size) + " > " + strconv.Itoa(maxBytes) + " bytes",
+	}}
+
+	attrBytes := size - valueSize(lr.GetBody())
+	if attrBytes > maxBytes {
+		// Largest first, by key for a stable order among equals
+		sorted := append([]*commonpb.KeyValue(nil), lr.Attributes...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			si, sj := attributeSize(sorted[i]), attributeSize(sorted[j])
+			if si != sj {
+				return si > sj
+			}
+			return sorted[i].GetKey() < sorted[j].GetKey()
+		})
+		for _, kv := range sorted {
+			if attrBytes <= maxBytes {
+				break
+			}
+			lr.Attributes, _ = deleteKey(lr.Attributes, kv.GetKey())
+			attrBytes -= attributeSize(kv)
+			actions = append(actions, dropAttributeAction(kv.GetKey(), valueSize(kv.GetValue())))
+		}
+	}
+
+	if _, isString := lr.GetBody().GetValue().(*commonpb.AnyValue_StringValue); !isString && valueSize(lr.GetBody()) > maxBytes-attrBytes {
+		actions = append(actions, dropBody(lr))
+	} else if action, ok := truncateBody(lr, maxBytes-attrBytes, TruncateBytes); ok {
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// dropBody removes a structured or bytes body, which can't be cut, noting its size
+func dropBody(lr *logspb.LogRecord) TransformAction {
+	size := valueSize(lr.GetBody())
+	lr.Body = nil
+	SetAttribute(lr, OriginalBodyBytesAttribute, strconv.Itoa(size))
+	return TransformAction{Type: ActionDropBody, Detail: "Dropped body: not a string (" + strconv.Itoa(size) + " bytes)"}
+}
+
+// dropAttributeAction reports an attribute removed for its size
+func dropAttributeAction(key string, size int) TransformAction {
+	return TransformAction{Type: ActionDropAttribute, Rule: key, Detail: "Dropped attribute: " + key + " (" + strconv.Itoa(size) + " bytes)"}
+}
+
+// recordSize approximates a record's indexed size: body plus attribute keys and values
+func recordSize(lr *logspb.LogRecord) int {
+	size := valueSize(lr.GetBody())
+	for _, kv := range lr.GetAttributes() {
+		size += attributeSize(kv)
+	}
+	return size
+}
+
+func attributeSize(kv *commonpb.KeyValue) int {
+	return len(kv.GetKey()) + valueSize(kv.GetValue())
+}
+
+// valueSize returns a string's length in bytes, or the encoded size of any other value
+func valueSize(v *commonpb.AnyValue) int {
+	if v == nil {
+		return 0
+	}
+	if sv, ok := v.GetValue().(*commonpb.AnyValue_StringValue); ok {
+		return len(sv.StringValue)
+	}
+	return proto.Size(v)
+}
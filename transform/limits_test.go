@@ -0,0 +1,144 @@
+// ABOUTME: Tests for attribute value and record size limits.
+// ABOUTME: Covers truncating and dropping oversized attributes and shrinking oversized records.
+
+package transform
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestLimitAttributes_Truncate(t *testing.T) {
+	lr := makeLogRecord(map[string]string{"short": "ok", "long": strings.Repeat("é", 6)})
+
+	actions := limitAttributes(lr, 5, AttributeTruncate)
+	if got := getAttr(lr, "long"); got != "éé" {
+		t.Errorf("long = %q, want cut to 4 bytes on a rune boundary", got)
+	}
+	if getAttr(lr, "short") != "ok" {
+		t.Error("short attribute should be untouched")
+	}
+	if len(actions) != 1 || actions[0].Detail != "Truncated attribute: long from 12 to 4 bytes" {
+		t.Errorf("actions = %v", actions)
+	}
+}
+
+func TestLimitAttributes_Drop(t *testing.T) {
+	lr := makeLogRecord(map[string]string{"short": "ok", "long": strings.Repeat("x", 20)})
+	lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{
+		Key:   "list",
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{}}},
+	})
+
+	actions := limitAttributes(lr, 10, AttributeDrop)
+	if len(lr.Attributes) != 2 || getAttr(lr, "short") != "ok" {
+		t.Errorf("only long should be dropped, got %v", lr.Attributes)
+	}
+	if len(actions) != 1 || actions[0].Detail != "Dropped attribute: long (20 bytes)" {
+		t.Errorf("actions = %v", actions)
+	}
+}
+
+func TestLimitRecord_TruncatesBodyFirst(t *testing.T) {
+	lr := makeLogRecord(map[string]string{"app": "web"}) // 6 bytes
+	lr.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: strings.Repeat("a", 50)}}
+
+	actions := limitRecord(lr, 26)
+	if got := lr.GetBody().GetStringValue(); got != strings.Repeat("a", 20)+truncateMarker {
+		t.Errorf("body = %q, want 20 bytes kept", got)
+	}
+	if getAttr(lr, "app") != "web" {
+		t.Error("attributes should be kept when the body can absorb the excess")
+	}
+	if len(actions) != 2 || actions[0].Detail != "Record over size limit: 56 > 26 bytes" {
+		t.Errorf("actions = %v", actions)
+	}
+}
+
+func TestLimitRecord_FitsWithinLimit(t *testing.T) {
+	var fields []*commonpb.KeyValue
+	for i := 0; i < 40; i++ {
+		fields = append(fields, &commonpb.KeyValue{
+			Key:   "field" + strconv.Itoa(i),
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: strings.Repeat("v", 20)}},
+		})
+	}
+	bodies := map[string]*commonpb.AnyValue{
+		"string": {Value: &commonpb.AnyValue_StringValue{StringValue: strings.Repeat("a", 1000)}},
+		"kvlist": {Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: fields}}},
+	}
+
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			lr := makeLogRecord(map[string]string{"app": "web"})
+			lr.Body = body
+
+			limitRecord(lr, 200)
+			lr.Attributes, _ = deleteKey(lr.Attributes, OriginalBodyBytesAttribute)
+			if sv, ok := lr.GetBody().GetValue().(*commonpb.AnyValue_StringValue); ok {
+				sv.StringValue = strings.TrimSuffix(sv.StringValue, truncateMarker)
+			}
+			if size := recordSize(lr); size > 200 {
+				t.Errorf("record size = %d, want <= 200", size)
+			}
+			if getAttr(lr, "app") != "web" {
+				t.Error("attributes should be kept when the body can absorb the excess")
+			}
+		})
+	}
+}
+
+func TestLimitRecord_DropsStructuredBody(t *testing.T) {
+	lr := makeLogRecord(map[string]string{"app": "web"})
+	lr.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: make([]byte, 100)}}
+	size := valueSize(lr.Body)
+
+	actions := limitRecord(lr, 50)
+	if lr.Body != nil {
+		t.Errorf("body = %v, want dropped", lr.Body)
+	}
+	if getAttr(lr, OriginalBodyBytesAttribute) != strconv.Itoa(size) {
+		t.Errorf("original_body_bytes = %q, want %d", getAttr(lr, OriginalBodyBytesAttribute), size)
+	}
+	if len(actions) != 2 || actions[1].Type != ActionDropBody {
+		t.Errorf("actions = %v", actions)
+	}
+}
+
+func TestLimitRecord_DropsLargestAttributes(t *testing.T) {
+	lr := makeLogRecord(map[string]string{
+		"app":   "web",                   // 6 bytes
+		"trace": strings.Repeat("t", 30), // 35 bytes
+		"user":  strings.Repeat("u", 10), // 14 bytes
+	})
+	lr.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "hello"}}
+
+	limitRecord(lr, 22)
+	if getAttr(lr, "trace") != "" {
+		t.Error("the largest attribute should be dropped")
+	}
+	if getAttr(lr, "app") != "web" || getAttr(lr, "user") == "" {
+		t.Errorf("remaining attributes should be kept, got %v", lr.Attributes)
+	}
+	if got := lr.GetBody().GetStringValue(); got != "he"+truncateMarker {
+		t.Errorf("body = %q, want the remaining 2 bytes", got)
+	}
+}
+
+func TestApplyWithConfig_SizeLimits(t *testing.T) {
+	cfg := &Config{MaxAttributeLength: 4, MaxRecordBytes: 1000}
+	lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "fits")
+	SetAttribute(lr, "request_id", "abcdefgh")
+
+	_, actions := ApplyWithConfig(nil, lr, cfg)
+	if getAttr(lr, "request_id") != "abcd" {
+		t.Errorf("request_id = %q, want abcd", getAttr(lr, "request_id"))
+	}
+	if len(actions) != 1 || !strings.HasPrefix(actions[0].Detail, "Truncated attribute: request_id") {
+		t.Errorf("actions = %v", actions)
+	}
+}
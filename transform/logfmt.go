@@ -0,0 +1,132 @@
+// ABOUTME: Logfmt parsing transform that turns key=value bodies into attributes.
+// ABOUTME: Only bodies classified as logfmt are parsed; an optional key allowlist limits what is kept.
+
+package transform
+
+import (
+	"strconv"
+	"strings"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// LogfmtParsing sets an attribute for each key=value pair in a logfmt body
+type LogfmtParsing struct {
+	Keys   map[string]bool // Keys to keep (empty = all)
+	Prefix string          // Prepended to each attribute name
+	When   *Match          // nil = every record
+}
+
+// parse applies the transform to a record. Returns the action taken, or false if nothing was parsed.
+func (p *LogfmtParsing) parse(res *resourcepb.Resource, lr *logspb.LogRecord) (TransformAction, bool) {
+	if !p.When.Matches(res, lr) || ClassifyBody(lr.GetBody()) != BodyTypeLogfmt {
+		return TransformAction{}, false
+	}
+
+	fields := 0
+	for _, pair := range ParseLogfmt(lr.GetBody().GetStringValue()) {
+		if len(p.Keys) > 0 && !p.Keys[pair[0]] {
+			continue
+		}
+		SetAttribute(lr, p.Prefix+pair[0], pair[1])
+		fields++
+	}
+	if fields == 0 {
+		return TransformAction{}, false
+	}
+	return TransformAction{Type: ActionParseLogfmt, Detail: "Parsed " + strconv.Itoa(fields) + " logfmt fields"}, true
+}
+
+// ParseLogfmt splits a logfmt line into key/value pairs in order. Quoted values
+// are unescaped; bare keys without "=" and tokens that aren't valid keys are skipped.
+func ParseLogfmt(s string) [][2]string {
+	var pairs [][2]string
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' && s[i] != '\t' {
+			i++
+		}
+		key := s[start:i]
+		if i >= len(s) || s[i] != '=' {
+			continue // Bare word
+		}
+		i++ // Skip '='
+
+		var value string
+		if i < len(s) && s[i] == '"' {
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				end = len(s) - 1 // Unterminated: take the rest of the line
+			}
+			quoted := s[i : end+1]
+			if v, err := strconv.Unquote(quoted); err == nil {
+				value = v
+			} else {
+				value = strings.Trim(quoted, `"`)
+			}
+			i = end + 1
+		} else {
+			start := i
+			for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+				i++
+			}
+			value = s[start:i]
+		}
+
+		if isLogfmtKey(key) {
+			pairs = append(pairs, [2]string{key, value})
+		}
+	}
+	return pairs
+}
+
+// isLogfmtKey reports whether a token is a plausible logfmt key
+func isLogfmtKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '.' || r == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// LogfmtFile is the YAML representation of LogfmtParsing
+type LogfmtFile struct {
+	Keys   []string   `yaml:"keys"`
+	Prefix string     `yaml:"prefix"`
+	When   *MatchFile `yaml:"when"`
+}
+
+// Build compiles the parser's condition and key allowlist
+func (lf *LogfmtFile) Build() (*LogfmtParsing, error) {
+	when, err := lf.When.Build()
+	if err != nil {
+		return nil, err
+	}
+	p := &LogfmtParsing{Prefix: lf.Prefix, When: when}
+	if len(lf.Keys) > 0 {
+		p.Keys = make(map[string]bool, len(lf.Keys))
+		for _, key := range lf.Keys {
+			p.Keys[key] = true
+		}
+	}
+	return p, nil
+}
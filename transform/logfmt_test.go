@@ -0,0 +1,75 @@
+// ABOUTME: Tests for the logfmt parsing transform.
+// ABOUTME: Covers quoting, key allowlists, prefixes, and non-logfmt bodies.
+
+package transform
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		line string
+		want [][2]string
+	}{
+		{`level=info msg=started port=8080`, [][2]string{{"level", "info"}, {"msg", "started"}, {"port", "8080"}}},
+		{`msg="request done" path=/api err="said \"no\""`, [][2]string{{"msg", "request done"}, {"path", "/api"}, {"err", `said "no"`}}},
+		{`empty= next=1`, [][2]string{{"empty", ""}, {"next", "1"}}},
+		{`bare words a=1 9x=2`, [][2]string{{"a", "1"}}},
+		{`msg="unterminated value`, [][2]string{{"msg", "unterminated value"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := ParseLogfmt(tt.line); !slices.Equal(got, tt.want) {
+				t.Errorf("ParseLogfmt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogfmtParsing_SetsAttributes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Logfmt = &LogfmtParsing{}
+
+	lr := makeLogRecord(nil)
+	lr.Body = stringBody(`level=warn msg="slow query" duration_ms=812`)
+	_, actions := ApplyWithConfig(nil, lr, cfg)
+
+	if getAttr(lr, "level") != "warn" || getAttr(lr, "msg") != "slow query" || getAttr(lr, "duration_ms") != "812" {
+		t.Errorf("parsed attributes wrong: %v", lr.GetAttributes())
+	}
+	if !slices.Contains(details(actions), "Parsed 3 logfmt fields") {
+		t.Errorf("actions missing logfmt parse: %v", actions)
+	}
+}
+
+func TestLogfmtParsing_KeysAndPrefix(t *testing.T) {
+	p := &LogfmtParsing{Keys: map[string]bool{"status": true, "duration_ms": true}, Prefix: "app."}
+
+	lr := makeLogRecord(nil)
+	lr.Body = stringBody(`method=GET status=503 duration_ms=87 user=alice`)
+	if action, _ := p.parse(nil, lr); action.Type != ActionParseLogfmt || action.Detail != "Parsed 2 logfmt fields" {
+		t.Errorf("action = %+v", action)
+	}
+	if getAttr(lr, "app.status") != "503" || getAttr(lr, "app.duration_ms") != "87" {
+		t.Errorf("allowlisted keys missing: %v", lr.GetAttributes())
+	}
+	if getAttr(lr, "app.user") != "" || getAttr(lr, "user") != "" {
+		t.Errorf("key outside allowlist was set: %v", lr.GetAttributes())
+	}
+}
+
+func TestLogfmtParsing_IgnoresOtherBodies(t *testing.T) {
+	p := &LogfmtParsing{}
+	for _, body := range []string{
+		`User logged in from host=10.0.0.1 after several attempts`,
+		`{"level":"info","msg":"json"}`,
+	} {
+		lr := makeLogRecord(nil)
+		lr.Body = stringBody(body)
+		if action, ok := p.parse(nil, lr); ok {
+			t.Errorf("parse(%q) = %q, want no action", body, action)
+		}
+	}
+}
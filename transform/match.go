@@ -0,0 +1,90 @@
+// ABOUTME: Match conditions that gate transform rules to specific records.
+// ABOUTME: Supports attribute regexes, a minimum severity, and app names.
+
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Match gates a transform rule on record properties. All set conditions must hold;
+// a nil Match matches every record.
+type Match struct {
+	Attributes  map[string]*regexp.Regexp // Attribute name → pattern
+	MinSeverity logspb.SeverityNumber     // 0 = any severity
+	Apps        []string                  // App names, case-insensitive (empty = any app)
+}
+
+// Matches reports whether the record satisfies every condition. App names and
+// attributes are looked up on the record, then on its resource, where TAS
+// sends them.
+func (m *Match) Matches(res *resourcepb.Resource, lr *logspb.LogRecord) bool {
+	if m == nil {
+		return true
+	}
+
+	if m.MinSeverity != logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED && lr.GetSeverityNumber() < m.MinSeverity {
+		return false
+	}
+
+	if len(m.Apps) > 0 {
+		appName := getAppName(res, lr)
+		found := false
+		for _, app := range m.Apps {
+			if strings.EqualFold(app, appName) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for attr, pattern := range m.Attributes {
+		value := findValue(res, lr, attr)
+		if value == "" || !pattern.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchFile is the YAML representation of a Match
+type MatchFile struct {
+	Attributes  map[string]string `yaml:"attributes"`
+	MinSeverity string            `yaml:"min_severity"`
+	Apps        []string          `yaml:"apps"`
+}
+
+// Build compiles the match conditions
+func (mf *MatchFile) Build() (*Match, error) {
+	if mf == nil {
+		return nil, nil
+	}
+
+	m := &Match{
+		Attributes: make(map[string]*regexp.Regexp),
+		Apps:       mf.Apps,
+	}
+	for attr, pattern := range mf.Attributes {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: invalid regex %q: %w", attr, pattern, err)
+		}
+		m.Attributes[attr] = re
+	}
+	if mf.MinSeverity != "" {
+		sev, err := ParseSeverity(mf.MinSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("min_severity: %w", err)
+		}
+		m.MinSeverity = sev
+	}
+	return m, nil
+}
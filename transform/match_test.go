@@ -0,0 +1,118 @@
+// ABOUTME: Tests for transform match conditions.
+// ABOUTME: Covers attribute, severity, and app gating of transform rules.
+
+package transform
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestMatch_NilMatchesAll(t *testing.T) {
+	var m *Match
+	if !m.Matches(nil, makeLogRecord(nil)) {
+		t.Error("nil match should match every record")
+	}
+}
+
+func TestMatch_Conditions(t *testing.T) {
+	m := &Match{
+		Attributes:  map[string]*regexp.Regexp{"cf_space_name": regexp.MustCompile("^dev")},
+		MinSeverity: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		Apps:        []string{"Chatty-App"},
+	}
+
+	tests := []struct {
+		name     string
+		severity logspb.SeverityNumber
+		attrs    map[string]string
+		want     bool
+	}{
+		{"all conditions hold", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_app_name": "chatty-app", "cf_space_name": "dev-1"}, true},
+		{"app via application_name", logspb.SeverityNumber_SEVERITY_NUMBER_WARN, map[string]string{"application_name": "chatty-app", "cf_space_name": "dev"}, true},
+		{"severity too low", logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, map[string]string{"cf_app_name": "chatty-app", "cf_space_name": "dev"}, false},
+		{"other app", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_app_name": "quiet-app", "cf_space_name": "dev"}, false},
+		{"attribute mismatch", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_app_name": "chatty-app", "cf_space_name": "production"}, false},
+		{"attribute missing", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, map[string]string{"cf_app_name": "chatty-app"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lr := makeLogRecord(tt.attrs)
+			lr.SeverityNumber = tt.severity
+			if got := m.Matches(nil, lr); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch_ResourceAttributes(t *testing.T) {
+	m := &Match{
+		Attributes: map[string]*regexp.Regexp{"cf_space_name": regexp.MustCompile("^dev")},
+		Apps:       []string{"chatty-app"},
+	}
+	res := makeResource(map[string]string{"application_name": "chatty-app", "cf_space_name": "dev"})
+	if !m.Matches(res, makeLogRecord(nil)) {
+		t.Error("app name and attribute on the resource should match")
+	}
+	if m.Matches(res, makeLogRecord(map[string]string{"cf_space_name": "production"})) {
+		t.Error("the record's own attribute should win over the resource's")
+	}
+	if m.Matches(nil, makeLogRecord(nil)) {
+		t.Error("without a resource the record has no app name")
+	}
+}
+
+func TestApplyWithConfig_TruncateOnlyForMatchingApp(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxBodyLength = 10
+	cfg.TruncateWhen = &Match{Apps: []string{"chatty-app"}}
+
+	longBody := strings.Repeat("x", 50)
+
+	chatty := makeLogRecord(map[string]string{"cf_app_name": "chatty-app"})
+	chatty.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: longBody}}
+	ApplyWithConfig(nil, chatty, cfg)
+	if !strings.HasSuffix(chatty.GetBody().GetStringValue(), "...[TRUNCATED]") {
+		t.Error("chatty-app body should be truncated")
+	}
+
+	quiet := makeLogRecord(map[string]string{"cf_app_name": "quiet-app"})
+	quiet.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: longBody}}
+	ApplyWithConfig(nil, quiet, cfg)
+	if quiet.GetBody().GetStringValue() != longBody {
+		t.Error("quiet-app body should not be truncated")
+	}
+
+	// TAS sends the app name on the resource
+	onResource := makeLogRecord(nil)
+	onResource.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: longBody}}
+	ApplyWithConfig(makeResource(map[string]string{"application_name": "chatty-app"}), onResource, cfg)
+	if !strings.HasSuffix(onResource.GetBody().GetStringValue(), "...[TRUNCATED]") {
+		t.Error("body should be truncated for chatty-app named on the resource")
+	}
+}
+
+func TestApplyWithConfig_DeleteGatedBySeverity(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DeleteWhen = &Match{MinSeverity: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR}
+
+	info := makeLogRecord(map[string]string{"diego_cell_ip": "10.0.0.1"})
+	info.SeverityNumber = logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	ApplyWithConfig(nil, info, cfg)
+	if getAttr(info, "diego_cell_ip") == "" {
+		t.Error("INFO record should keep diego_cell_ip")
+	}
+
+	errRec := makeLogRecord(map[string]string{"diego_cell_ip": "10.0.0.1"})
+	errRec.SeverityNumber = logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	ApplyWithConfig(nil, errRec, cfg)
+	if getAttr(errRec, "diego_cell_ip") != "" {
+		t.Error("ERROR record should have diego_cell_ip deleted")
+	}
+}
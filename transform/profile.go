@@ -0,0 +1,94 @@
+// ABOUTME: Per-app transform override profiles.
+// ABOUTME: Derives a record's effective config from the base config and its app's profile.
+
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Profile overrides parts of the base config for one app, modeling a tenant-specific pipeline
+type Profile struct {
+	MaxBodyLength    *int             // Replaces MaxBodyLength when set
+	ExtraDeletes     []string         // Appended to FieldsToDelete
+	PCIPatterns      []*regexp.Regexp // Replaces PCIPatterns when non-nil
+	ExtraPCIPatterns []*regexp.Regexp // Appended to PCIPatterns
+}
+
+// ForRecord selects the effective config for a record based on its app name,
+// from the record or its resource. Returns the base config and "" when no
+// profile applies.
+func (cfg *Config) ForRecord(res *resourcepb.Resource, lr *logspb.LogRecord) (*Config, string) {
+	if len(cfg.Profiles) == 0 {
+		return cfg, ""
+	}
+
+	name := strings.ToLower(getAppName(res, lr))
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return cfg, ""
+	}
+	return cfg.withProfile(profile), name
+}
+
+// withProfile returns a copy of cfg with the profile's overrides applied
+func (cfg *Config) withProfile(p *Profile) *Config {
+	derived := *cfg
+	if p.MaxBodyLength != nil {
+		derived.MaxBodyLength = *p.MaxBodyLength
+	}
+	if len(p.ExtraDeletes) > 0 {
+		derived.FieldsToDelete = append(append([]string{}, cfg.FieldsToDelete...), p.ExtraDeletes...)
+	}
+	if p.PCIPatterns != nil {
+		derived.PCIPatterns = p.PCIPatterns
+	}
+	if len(p.ExtraPCIPatterns) > 0 {
+		derived.PCIPatterns = append(append([]*regexp.Regexp{}, derived.PCIPatterns...), p.ExtraPCIPatterns...)
+	}
+	return &derived
+}
+
+// ProfileFile is the YAML representation of a Profile
+type ProfileFile struct {
+	MaxBodyLength    *int     `yaml:"max_body_length"`
+	ExtraDeletes     []string `yaml:"extra_deletes"`
+	PCIPatterns      []string `yaml:"pci_patterns"`
+	ExtraPCIPatterns []string `yaml:"extra_pci_patterns"`
+}
+
+// Build compiles the profile's redaction patterns
+func (pf *ProfileFile) Build() (*Profile, error) {
+	p := &Profile{
+		MaxBodyLength: pf.MaxBodyLength,
+		ExtraDeletes:  pf.ExtraDeletes,
+	}
+	var err error
+	if pf.PCIPatterns != nil {
+		if p.PCIPatterns, err = compilePatterns(pf.PCIPatterns); err != nil {
+			return nil, fmt.Errorf("pci_patterns: %w", err)
+		}
+	}
+	if p.ExtraPCIPatterns, err = compilePatterns(pf.ExtraPCIPatterns); err != nil {
+		return nil, fmt.Errorf("extra_pci_patterns: %w", err)
+	}
+	return p, nil
+}
+
+// compilePatterns compiles a list of regexes, naming the first invalid one
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
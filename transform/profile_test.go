@@ -0,0 +1,96 @@
+// ABOUTME: Tests for per-app transform profiles.
+// ABOUTME: Covers profile selection and override layering over the base config.
+
+package transform
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+func TestForRecord_NoProfilesReturnsBase(t *testing.T) {
+	cfg := DefaultConfig()
+	got, name := cfg.ForRecord(nil, makeLogRecord(map[string]string{"cf_app_name": "any"}))
+	if got != cfg || name != "" {
+		t.Errorf("expected base config and no profile, got %q", name)
+	}
+}
+
+func TestForRecord_SelectsProfileCaseInsensitive(t *testing.T) {
+	maxLen := 10
+	cfg := DefaultConfig()
+	cfg.Profiles = map[string]*Profile{
+		"chatty-app": {
+			MaxBodyLength:    &maxLen,
+			ExtraDeletes:     []string{"request_headers"},
+			ExtraPCIPatterns: []*regexp.Regexp{regexp.MustCompile(`acct-\d+`)},
+		},
+	}
+
+	derived, name := cfg.ForRecord(nil, makeLogRecord(map[string]string{"application_name": "Chatty-App"}))
+	if name != "chatty-app" {
+		t.Fatalf("profile = %q, want chatty-app", name)
+	}
+	if derived.MaxBodyLength != 10 {
+		t.Errorf("MaxBodyLength = %d, want 10", derived.MaxBodyLength)
+	}
+	if n := len(derived.FieldsToDelete); n != len(cfg.FieldsToDelete)+1 {
+		t.Errorf("FieldsToDelete has %d entries, want base + 1", n)
+	}
+	if n := len(derived.PCIPatterns); n != len(cfg.PCIPatterns)+1 {
+		t.Errorf("PCIPatterns has %d entries, want base + 1", n)
+	}
+	if cfg.MaxBodyLength != 32768 || len(cfg.FieldsToDelete) != 3 {
+		t.Error("base config should not be modified")
+	}
+}
+
+func TestForRecord_AppNameOnResource(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Profiles = map[string]*Profile{"chatty-app": {ExtraDeletes: []string{"request_headers"}}}
+
+	res := makeResource(map[string]string{"application_name": "chatty-app"})
+	if _, name := cfg.ForRecord(res, makeLogRecord(nil)); name != "chatty-app" {
+		t.Errorf("profile = %q, want chatty-app from the resource", name)
+	}
+	// The record's own app name wins over the resource's
+	lr := makeLogRecord(map[string]string{"cf_app_name": "quiet-app"})
+	if _, name := cfg.ForRecord(res, lr); name != "" {
+		t.Errorf("profile = %q, want none for the record's app", name)
+	}
+}
+
+func TestForRecord_ProfileAppliedByApply(t *testing.T) {
+	cfg, err := (&FileConfig{Profiles: map[string]*ProfileFile{
+		"legacy-app": {PCIPatterns: []string{`secret-\w+`}},
+	}}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lr := makeLogRecord(map[string]string{"cf_app_name": "legacy-app"})
+	lr.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "token secret-abc card 4111-1111-1111-1111"}}
+
+	effective, _ := cfg.ForRecord(nil, lr)
+	ApplyWithConfig(nil, lr, effective)
+
+	body := lr.GetBody().GetStringValue()
+	if strings.Contains(body, "secret-abc") {
+		t.Errorf("profile pattern should redact, got %q", body)
+	}
+	if !strings.Contains(body, "4111-1111-1111-1111") {
+		t.Errorf("profile replaces default patterns, card should remain, got %q", body)
+	}
+}
+
+func TestProfileFile_InvalidPattern(t *testing.T) {
+	_, err := (&FileConfig{Profiles: map[string]*ProfileFile{
+		"bad": {ExtraPCIPatterns: []string{"("}},
+	}}).Build()
+	if err == nil || !strings.Contains(err.Error(), "profiles.bad") {
+		t.Errorf("expected profiles.bad error, got %v", err)
+	}
+}
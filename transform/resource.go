@@ -0,0 +1,64 @@
+// ABOUTME: Rename, delete, and enrich transforms over resource attributes.
+// ABOUTME: TAS puts app metadata on the resource, which every record in a ResourceLogs shares.
+
+package transform
+
+import (
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// ApplyToResource runs the resource attribute rules for one record. Rule
+// conditions are evaluated against the record and the resource as received. The shared resource is never
+// modified: a transformed copy is returned if any rule applied.
+func ApplyToResource(res *resourcepb.Resource, lr *logspb.LogRecord, cfg *Config) (*resourcepb.Resource, []TransformAction) {
+	if !cfg.hasResourceRules() {
+		return res, nil
+	}
+
+	out := &resourcepb.Resource{}
+	if res != nil {
+		out = proto.Clone(res).(*resourcepb.Resource)
+	}
+	var actions []TransformAction
+
+	if cfg.RenameWhen.Matches(res, lr) {
+		for oldKey, newKey := range cfg.ResourceFieldRenames {
+			if renameKey(out.Attributes, oldKey, newKey) {
+				actions = append(actions, TransformAction{Type: ActionResourceRename, Rule: oldKey, Detail: "Renamed resource: " + oldKey + " -> " + newKey})
+			}
+		}
+	}
+
+	if cfg.DeleteWhen.Matches(res, lr) {
+		for _, key := range cfg.ResourceFieldsToDelete {
+			var deleted bool
+			if out.Attributes, deleted = deleteKey(out.Attributes, key); deleted {
+				actions = append(actions, TransformAction{Type: ActionResourceDelete, Rule: key, Detail: "Deleted resource: " + key})
+			}
+		}
+	}
+
+	for _, e := range cfg.Enrichments {
+		actions = append(actions, e.enrichResource(res, out, lr)...)
+	}
+
+	if len(actions) == 0 {
+		return res, nil
+	}
+	return out, actions
+}
+
+// hasResourceRules reports whether any rule targets resource attributes
+func (cfg *Config) hasResourceRules() bool {
+	if len(cfg.ResourceFieldRenames) > 0 || len(cfg.ResourceFieldsToDelete) > 0 {
+		return true
+	}
+	for _, e := range cfg.Enrichments {
+		if e.Resource {
+			return true
+		}
+	}
+	return false
+}
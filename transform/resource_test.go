@@ -0,0 +1,88 @@
+// ABOUTME: Tests for resource attribute transforms.
+// ABOUTME: Covers renames, deletes, resource enrichment, conditions, and copy-on-write.
+
+package transform
+
+import (
+	"slices"
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func makeResource(attrs map[string]string) *resourcepb.Resource {
+	return &resourcepb.Resource{Attributes: makeLogRecord(attrs).Attributes}
+}
+
+func resourceAttr(res *resourcepb.Resource, key string) string {
+	return getAttr(&logspb.LogRecord{Attributes: res.GetAttributes()}, key)
+}
+
+func TestApplyToResource_RenameDeleteEnrich(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ResourceFieldRenames = map[string]string{"application_name": "cf_app_name"}
+	cfg.ResourceFieldsToDelete = []string{"process_id"}
+	cfg.Enrichments = []*Enrichment{
+		{Attributes: map[string]string{"environment": "lab"}, Resource: true},
+		{Attributes: map[string]string{"pipeline": "mock"}},
+	}
+
+	shared := makeResource(map[string]string{"application_name": "my-app", "process_id": "42"})
+	lr := makeLogRecord(nil)
+	res, actions := ApplyToResource(shared, lr, cfg)
+
+	if resourceAttr(res, "cf_app_name") != "my-app" || resourceAttr(res, "process_id") != "" || resourceAttr(res, "environment") != "lab" {
+		t.Errorf("transformed resource wrong: %v", res.GetAttributes())
+	}
+	if resourceAttr(res, "pipeline") != "" {
+		t.Error("record enrichment should not touch the resource")
+	}
+	want := []string{"Renamed resource: application_name -> cf_app_name", "Deleted resource: process_id", "Added resource: environment=lab"}
+	if !slices.Equal(details(actions), want) {
+		t.Errorf("actions = %v, want %v", actions, want)
+	}
+
+	// The shared resource is untouched, so the next record in the batch sees the original
+	if resourceAttr(shared, "application_name") != "my-app" || resourceAttr(shared, "process_id") != "42" {
+		t.Errorf("shared resource was modified: %v", shared.GetAttributes())
+	}
+}
+
+func TestApplyToResource_NoRulesNoCopy(t *testing.T) {
+	shared := makeResource(map[string]string{"application_name": "my-app"})
+	res, actions := ApplyToResource(shared, makeLogRecord(nil), DefaultConfig())
+	if res != shared || actions != nil {
+		t.Errorf("expected the original resource and no actions, got %v", actions)
+	}
+
+	cfg := DefaultConfig()
+	cfg.ResourceFieldsToDelete = []string{"missing"}
+	if res, actions := ApplyToResource(shared, makeLogRecord(nil), cfg); res != shared || actions != nil {
+		t.Errorf("rules that change nothing should return the original, got %v", actions)
+	}
+}
+
+func TestApplyToResource_Conditions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ResourceFieldsToDelete = []string{"space_name"}
+	cfg.DeleteWhen = &Match{Apps: []string{"payments"}}
+
+	shared := makeResource(map[string]string{"space_name": "prod"})
+	if _, actions := ApplyToResource(shared, makeLogRecord(map[string]string{"cf_app_name": "other"}), cfg); actions != nil {
+		t.Errorf("non-matching record should not delete, got %v", actions)
+	}
+	res, _ := ApplyToResource(shared, makeLogRecord(map[string]string{"cf_app_name": "payments"}), cfg)
+	if resourceAttr(res, "space_name") != "" {
+		t.Error("matching record should delete space_name from its resource")
+	}
+}
+
+func TestApplyToResource_NilResource(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Enrichments = []*Enrichment{{Attributes: map[string]string{"environment": "lab"}, Resource: true}}
+	res, _ := ApplyToResource(nil, makeLogRecord(nil), cfg)
+	if resourceAttr(res, "environment") != "lab" {
+		t.Errorf("enrichment should create a resource, got %v", res)
+	}
+}
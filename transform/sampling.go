@@ -0,0 +1,150 @@
+// ABOUTME: Hash-based log sampling with severity exemptions and per-level rates.
+// ABOUTME: Records at or above the keep threshold are never sampled; others keep 1 in N by trace ID or content hash.
+
+package transform
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// SamplingConfig controls log sampling behavior
+type SamplingConfig struct {
+	// SampleRate: keep 1 in N logs (1 = keep all, 10 = keep 10%)
+	SampleRate int
+	// SampleDebugOnly: when true, only sample DEBUG severity logs
+	SampleDebugOnly bool
+	// KeepSeverity: records at or above are never sampled, overriding
+	// SampleDebugOnly (0 = ERROR, or INFO with SampleDebugOnly)
+	KeepSeverity logspb.SeverityNumber
+	// SeverityRates: keep 1 in N per severity level (TRACE, DEBUG, INFO, ...),
+	// overriding SampleRate for that level
+	SeverityRates map[logspb.SeverityNumber]int
+	// TraceAware: decide records carrying a trace ID by the trace ID, so a
+	// trace's records are kept or dropped together
+	TraceAware bool
+	// MaxPerSecond: keep at most N logs per second per app instead (0 = off)
+	MaxPerSecond float64
+}
+
+// traceRandomnessBits is how many rightmost trace ID bits W3C Trace Context
+// level 2 requires to be random, and OTel consistent probability sampling uses
+const traceRandomnessBits = 56
+
+// KeepThreshold returns the lowest severity that is never sampled
+func (cfg *SamplingConfig) KeepThreshold() logspb.SeverityNumber {
+	switch {
+	case cfg.KeepSeverity != logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED:
+		return cfg.KeepSeverity
+	case cfg.SampleDebugOnly:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	}
+}
+
+// AlwaysKeep reports whether the record's severity exempts it from sampling
+func (cfg *SamplingConfig) AlwaysKeep(lr *logspb.LogRecord) bool {
+	return lr.GetSeverityNumber() >= cfg.KeepThreshold()
+}
+
+// rateFor returns the 1-in-N rate for a severity
+func (cfg *SamplingConfig) rateFor(sev logspb.SeverityNumber) int {
+	if rate, ok := cfg.SeverityRates[severityLevel(sev)]; ok {
+		return rate
+	}
+	return cfg.SampleRate
+}
+
+// Rate returns the 1-in-N rate the record is sampled at, 1 if it is always kept
+func (cfg *SamplingConfig) Rate(lr *logspb.LogRecord) int {
+	if cfg == nil || cfg.AlwaysKeep(lr) {
+		return 1
+	}
+	return max(cfg.rateFor(lr.GetSeverityNumber()), 1)
+}
+
+// Validate rejects per-level rates for levels that are always kept, which would never apply
+func (cfg *SamplingConfig) Validate() error {
+	for level := range cfg.SeverityRates {
+		if level >= cfg.KeepThreshold() {
+			return fmt.Errorf("%s is always kept (threshold %s), so its sampling rate would never apply",
+				SeverityName(level), SeverityName(cfg.KeepThreshold()))
+		}
+	}
+	return nil
+}
+
+// severityLevel returns the first severity number of sev's level, e.g. WARN for WARN3
+func severityLevel(sev logspb.SeverityNumber) logspb.SeverityNumber {
+	if sev <= logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED {
+		return sev
+	}
+	return sev - (sev-1)%4
+}
+
+// ParseSeverityRates parses per-level sampling rates such as "INFO=10,DEBUG=100".
+// Each severity stands for its whole level (WARN covers WARN through WARN4).
+func ParseSeverityRates(spec string) (map[logspb.SeverityNumber]int, error) {
+	rates := make(map[logspb.SeverityNumber]int)
+	for _, part := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: expected SEVERITY=N", part)
+		}
+		sev, err := ParseSeverity(name)
+		if err != nil {
+			return nil, err
+		}
+		rate, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || rate < 1 {
+			return nil, fmt.Errorf("%q: rate must be a positive integer", part)
+		}
+		rates[severityLevel(sev)] = rate
+	}
+	return rates, nil
+}
+
+// ShouldSample determines if a log should be kept based on sampling config.
+// Returns true if the log should be kept, false if it should be dropped.
+func ShouldSample(lr *logspb.LogRecord, cfg *SamplingConfig) bool {
+	// Rate of 1 means keep all
+	rate := cfg.Rate(lr)
+	if rate == 1 {
+		return true
+	}
+
+	if cfg.TraceAware {
+		if r, ok := traceRandomness(lr.GetTraceId()); ok {
+			// Thresholds nest, so a trace kept at 1-in-100 is kept at 1-in-10 too
+			return r < (1<<traceRandomnessBits)/uint64(rate)
+		}
+	}
+
+	// Deterministic sampling based on log content hash
+	h := fnv.New32a()
+	if body := lr.GetBody(); body != nil {
+		h.Write([]byte(body.GetStringValue()))
+	}
+	hash := h.Sum32()
+
+	return hash%uint32(rate) == 0
+}
+
+// traceRandomness returns the random rightmost bits of a trace ID, or false if
+// the record has no valid trace ID
+func traceRandomness(traceID []byte) (uint64, bool) {
+	if len(traceID) != 16 || !slices.ContainsFunc(traceID, func(b byte) bool { return b != 0 }) {
+		return 0, false
+	}
+	var r uint64
+	for _, b := range traceID[16-traceRandomnessBits/8:] {
+		r = r<<8 | uint64(b)
+	}
+	return r, true
+}
@@ -0,0 +1,132 @@
+// ABOUTME: Tests for severity thresholds and per-level sampling rates.
+// ABOUTME: Covers keep thresholds, level grouping, rate parsing, and validation.
+
+package transform
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestSampling_KeepSeverityThreshold(t *testing.T) {
+	cfg := &SamplingConfig{SampleRate: 1000, SampleDebugOnly: true, KeepSeverity: logspb.SeverityNumber_SEVERITY_NUMBER_WARN}
+
+	kept := 0
+	for i := range 100 {
+		lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, fmt.Sprintf("info %d", i))
+		if ShouldSample(lr, cfg) {
+			kept++
+		}
+	}
+	if kept > 5 {
+		t.Errorf("INFO below a WARN threshold should be sampled despite SampleDebugOnly, kept %d/100", kept)
+	}
+	if !ShouldSample(makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_WARN2, "slow"), cfg) {
+		t.Error("WARN2 is at the threshold and should be kept")
+	}
+}
+
+func TestSampling_SeverityRates(t *testing.T) {
+	cfg := &SamplingConfig{
+		SampleRate: 1,
+		SeverityRates: map[logspb.SeverityNumber]int{
+			logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG: 1000,
+		},
+	}
+
+	keptInfo, keptDebug := 0, 0
+	for i := range 100 {
+		body := fmt.Sprintf("msg %d", i)
+		if ShouldSample(makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, body), cfg) {
+			keptInfo++
+		}
+		// DEBUG3 falls in the DEBUG level
+		if ShouldSample(makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG3, body), cfg) {
+			keptDebug++
+		}
+	}
+	if keptInfo != 100 {
+		t.Errorf("INFO uses SampleRate 1 and should all be kept, kept %d", keptInfo)
+	}
+	if keptDebug > 5 {
+		t.Errorf("DEBUG at 1-in-1000 kept %d/100", keptDebug)
+	}
+}
+
+func TestParseSeverityRates(t *testing.T) {
+	rates, err := ParseSeverityRates("info=10, DEBUG2=100")
+	if err != nil {
+		t.Fatalf("ParseSeverityRates failed: %v", err)
+	}
+	if rates[logspb.SeverityNumber_SEVERITY_NUMBER_INFO] != 10 || rates[logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG] != 100 {
+		t.Errorf("rates = %v, want INFO=10 DEBUG=100", rates)
+	}
+
+	for _, spec := range []string{"INFO", "LOUD=10", "INFO=0", "INFO=x"} {
+		if _, err := ParseSeverityRates(spec); err == nil {
+			t.Errorf("ParseSeverityRates(%q) should fail", spec)
+		}
+	}
+}
+
+func TestSamplingConfig_Validate(t *testing.T) {
+	info := map[logspb.SeverityNumber]int{logspb.SeverityNumber_SEVERITY_NUMBER_INFO: 10}
+
+	if err := (&SamplingConfig{SampleDebugOnly: true, SeverityRates: info}).Validate(); err == nil {
+		t.Error("an INFO rate with INFO always kept should be rejected")
+	}
+	if err := (&SamplingConfig{SampleDebugOnly: true, KeepSeverity: logspb.SeverityNumber_SEVERITY_NUMBER_WARN, SeverityRates: info}).Validate(); err != nil {
+		t.Errorf("an INFO rate below a WARN threshold is valid: %v", err)
+	}
+}
+
+// withTrace gives lr the i-th of a stream of well-spread trace IDs
+func withTrace(lr *logspb.LogRecord, i int) *logspb.LogRecord {
+	sum := sha256.Sum256([]byte(strconv.Itoa(i)))
+	lr.TraceId = sum[:16]
+	return lr
+}
+
+func TestSampling_TraceAwareKeepsTracesTogether(t *testing.T) {
+	cfg := &SamplingConfig{SampleRate: 4, TraceAware: true}
+
+	for i := range 50 {
+		first := ShouldSample(withTrace(makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "start"), i), cfg)
+		for _, body := range []string{"query", "retry", "done"} {
+			if got := ShouldSample(withTrace(makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, body), i), cfg); got != first {
+				t.Fatalf("trace %d: %q decided %v, first record %v", i, body, got, first)
+			}
+		}
+	}
+}
+
+func TestSampling_TraceAwareConsistentAcrossRates(t *testing.T) {
+	coarse := &SamplingConfig{SampleRate: 10, TraceAware: true}
+	fine := &SamplingConfig{SampleRate: 100, TraceAware: true}
+
+	keptFine := 0
+	for i := range 1000 {
+		lr := withTrace(makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "x"), i)
+		if ShouldSample(lr, fine) {
+			keptFine++
+			if !ShouldSample(lr, coarse) {
+				t.Fatalf("trace %d kept at 1-in-100 but not at 1-in-10", i)
+			}
+		}
+	}
+	if keptFine == 0 || keptFine > 30 {
+		t.Errorf("1-in-100 kept %d/1000 traces", keptFine)
+	}
+}
+
+func TestTraceRandomness_InvalidIDs(t *testing.T) {
+	for _, id := range [][]byte{nil, make([]byte, 16), {1, 2, 3}} {
+		if _, ok := traceRandomness(id); ok {
+			t.Errorf("traceRandomness(%x) should report no trace ID", id)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// ABOUTME: Secret scrubbing that masks values of sensitive keys in structured bodies.
+// ABOUTME: Handles JSON and logfmt text bodies and OTLP kvlist bodies, independent of PCI regexes.
+
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// SecretMask replaces the value of a sensitive key
+const SecretMask = "[SECRET-REDACTED]"
+
+// DefaultSecretKeys returns the key names scrubbed by default
+func DefaultSecretKeys() []string {
+	return []string{"password", "authorization", "api_key", "set-cookie"}
+}
+
+// SecretScrubbing masks the values of sensitive keys, matched case-insensitively
+type SecretScrubbing struct {
+	Keys []string // Lowercase key names
+
+	keys   map[string]bool
+	json   *regexp.Regexp // "key": value
+	logfmt *regexp.Regexp // key=value
+}
+
+// NewSecretScrubbing builds a scrubber for the given key names
+func NewSecretScrubbing(keys []string) (*SecretScrubbing, error) {
+	s := &SecretScrubbing{keys: make(map[string]bool)}
+	var quoted []string
+	for _, key := range keys {
+		if key == "" || strings.ContainsAny(key, " \t\"=") {
+			return nil, fmt.Errorf("invalid secret key %q", key)
+		}
+		key = strings.ToLower(key)
+		if s.keys[key] {
+			continue
+		}
+		s.keys[key] = true
+		s.Keys = append(s.Keys, key)
+		quoted = append(quoted, regexp.QuoteMeta(key))
+	}
+	if len(quoted) > 0 {
+		alt := `(?i:` + strings.Join(quoted, "|") + `)`
+		s.json = regexp.MustCompile(`"(` + alt + `)"\s*:\s*("(?:[^"\\]|\\.)*"|[^\s,}\]]+)`)
+		s.logfmt = regexp.MustCompile(`(?:^|\s)(` + alt + `)=("(?:[^"\\]|\\.)*"|\S+)`)
+	}
+	return s, nil
+}
+
+// DefaultSecretScrubbing returns a scrubber for DefaultSecretKeys
+func DefaultSecretScrubbing() *SecretScrubbing {
+	return mustSecretScrubbing(DefaultSecretKeys())
+}
+
+// mustSecretScrubbing builds a scrubber from known-valid keys
+func mustSecretScrubbing(keys []string) *SecretScrubbing {
+	s, err := NewSecretScrubbing(keys)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// scrub masks sensitive values in the record's body. Returns one action per key scrubbed.
+func (s *SecretScrubbing) scrub(lr *logspb.LogRecord) []TransformAction {
+	if len(s.keys) == 0 || lr.GetBody() == nil {
+		return nil
+	}
+
+	found := make(map[string]bool)
+	switch v := lr.GetBody().GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		body := s.replace(v.StringValue, s.json, `"`+SecretMask+`"`, found)
+		body = s.replace(body, s.logfmt, SecretMask, found)
+		if len(found) > 0 {
+			lr.Body = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}}
+		}
+	case *commonpb.AnyValue_KvlistValue:
+		s.scrubValue(lr.GetBody(), found)
+	}
+
+	keys := make([]string, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	actions := make([]TransformAction, len(keys))
+	for i, key := range keys {
+		actions[i] = TransformAction{Type: ActionScrubSecret, Rule: key, Detail: "Scrubbed secret: " + key}
+	}
+	return actions
+}
+
+// replace swaps the value (second group) of each match for mask, noting the
+// key (first group) in found
+func (s *SecretScrubbing) replace(str string, re *regexp.Regexp, mask string, found map[string]bool) string {
+	matches := re.FindAllStringSubmatchIndex(str, -1)
+	if matches == nil {
+		return str
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		keyStart, keyEnd, valStart, valEnd := m[2], m[3], m[4], m[5]
+		if str[valStart:valEnd] == mask {
+			continue
+		}
+		found[strings.ToLower(str[keyStart:keyEnd])] = true
+		b.WriteString(str[last:valStart])
+		b.WriteString(mask)
+		last = valEnd
+	}
+	b.WriteString(str[last:])
+	return b.String()
+}
+
+// scrubValue masks sensitive keys anywhere in a kvlist or array value
+func (s *SecretScrubbing) scrubValue(v *commonpb.AnyValue, found map[string]bool) {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_KvlistValue:
+		for _, kv := range val.KvlistValue.GetValues() {
+			key := strings.ToLower(kv.GetKey())
+			if s.keys[key] {
+				if kv.GetValue().GetStringValue() != SecretMask {
+					kv.Value = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: SecretMask}}
+					found[key] = true
+				}
+				continue
+			}
+			s.scrubValue(kv.GetValue(), found)
+		}
+	case *commonpb.AnyValue_ArrayValue:
+		for _, elem := range val.ArrayValue.GetValues() {
+			s.scrubValue(elem, found)
+		}
+	}
+}
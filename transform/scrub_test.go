@@ -0,0 +1,121 @@
+// ABOUTME: Tests for secret scrubbing.
+// ABOUTME: Covers JSON, logfmt, and kvlist bodies, key matching, and config.
+
+package transform
+
+import (
+	"slices"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+func TestSecretScrubbing_TextBodies(t *testing.T) {
+	s, err := NewSecretScrubbing(DefaultSecretKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		actions []string
+	}{
+		{
+			"json",
+			`{"user":"alice","password":"hunter2","nested":{"API_KEY": 12345}}`,
+			`{"user":"alice","password":"[SECRET-REDACTED]","nested":{"API_KEY": "[SECRET-REDACTED]"}}`,
+			[]string{"Scrubbed secret: api_key", "Scrubbed secret: password"},
+		},
+		{
+			"json escaped quotes",
+			`{"authorization":"Bearer \"abc\"","ok":true}`,
+			`{"authorization":"[SECRET-REDACTED]","ok":true}`,
+			[]string{"Scrubbed secret: authorization"},
+		},
+		{
+			"logfmt",
+			`level=info user=alice password=hunter2 Set-Cookie="sid=1; Path=/" status=200`,
+			`level=info user=alice password=[SECRET-REDACTED] Set-Cookie=[SECRET-REDACTED] status=200`,
+			[]string{"Scrubbed secret: password", "Scrubbed secret: set-cookie"},
+		},
+		{
+			"longer key names untouched",
+			`old_password=x {"password_hint":"pet"}`,
+			`old_password=x {"password_hint":"pet"}`,
+			nil,
+		},
+		{
+			"already masked",
+			`{"password":"[SECRET-REDACTED]"}`,
+			`{"password":"[SECRET-REDACTED]"}`,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lr := makeLogRecord(nil)
+			lr.Body = stringBody(tt.body)
+			actions := s.scrub(lr)
+			if got := lr.GetBody().GetStringValue(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(details(actions), tt.actions) {
+				t.Errorf("actions = %v, want %v", actions, tt.actions)
+			}
+		})
+	}
+}
+
+func TestSecretScrubbing_KvlistBody(t *testing.T) {
+	s, _ := NewSecretScrubbing([]string{"Password"})
+	kv := func(k string, v *commonpb.AnyValue) *commonpb.KeyValue { return &commonpb.KeyValue{Key: k, Value: v} }
+	kvlist := func(kvs ...*commonpb.KeyValue) *commonpb.AnyValue {
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: kvs}}}
+	}
+
+	inner := kvlist(kv("PASSWORD", stringBody("s3cret")))
+	lr := makeLogRecord(nil)
+	lr.Body = kvlist(
+		kv("user", stringBody("alice")),
+		kv("creds", &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: []*commonpb.AnyValue{inner}}}}),
+	)
+
+	actions := s.scrub(lr)
+	if got := inner.GetKvlistValue().GetValues()[0].GetValue().GetStringValue(); got != SecretMask {
+		t.Errorf("nested password = %q, want masked", got)
+	}
+	if got := lr.GetBody().GetKvlistValue().GetValues()[0].GetValue().GetStringValue(); got != "alice" {
+		t.Errorf("user = %q, want untouched", got)
+	}
+	if !slices.Equal(details(actions), []string{"Scrubbed secret: password"}) {
+		t.Errorf("actions = %v", actions)
+	}
+}
+
+func TestSecretScrubbing_DefaultInPipeline(t *testing.T) {
+	lr := makeLogRecord(nil)
+	lr.Body = stringBody(`login failed user=bob password=letmein`)
+	if _, actions := Apply(lr); len(actions) != 0 || lr.GetBody().GetStringValue() != `login failed user=bob password=letmein` {
+		t.Fatalf("scrubbing should be off by default: %v", actions)
+	}
+
+	cfg := DefaultConfig()
+	cfg.SecretScrubbing = DefaultSecretScrubbing()
+	_, actions := ApplyWithConfig(nil, lr, cfg)
+	if lr.GetBody().GetStringValue() != `login failed user=bob password=[SECRET-REDACTED]` {
+		t.Errorf("body = %q", lr.GetBody().GetStringValue())
+	}
+	if !slices.Contains(details(actions), "Scrubbed secret: password") {
+		t.Errorf("actions missing scrub: %v", actions)
+	}
+}
+
+func TestNewSecretScrubbing_InvalidKey(t *testing.T) {
+	for _, key := range []string{"", "two words", `a"b`, "k=v"} {
+		if _, err := NewSecretScrubbing([]string{key}); err == nil {
+			t.Errorf("NewSecretScrubbing(%q) should fail", key)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// ABOUTME: Severity normalization for records arriving without a severity number.
+// ABOUTME: Infers severity from severity_text or leading body tokens via a keyword mapping.
+
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// SeverityNormalization controls severity inference for SEVERITY_NUMBER_UNSPECIFIED records
+type SeverityNormalization struct {
+	// Keywords maps lowercase severity words (e.g. "warn", "fatal") to severity numbers
+	Keywords map[string]logspb.SeverityNumber
+
+	// InspectBody enables inference from the leading body token ("ERROR", "[debug]")
+	// when severity_text is empty or unrecognized
+	InspectBody bool
+}
+
+// DefaultSeverityKeywords returns the built-in keyword mapping
+func DefaultSeverityKeywords() map[string]logspb.SeverityNumber {
+	return map[string]logspb.SeverityNumber{
+		"trace":       logspb.SeverityNumber_SEVERITY_NUMBER_TRACE,
+		"debug":       logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+		"dbg":         logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+		"info":        logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		"information": logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		"notice":      logspb.SeverityNumber_SEVERITY_NUMBER_INFO2,
+		"warn":        logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+		"warning":     logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+		"err":         logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+		"error":       logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+		"crit":        logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+		"critical":    logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+		"fatal":       logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+		"panic":       logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+		"emerg":       logspb.SeverityNumber_SEVERITY_NUMBER_FATAL2,
+	}
+}
+
+// DefaultSeverityNormalization returns normalization with the built-in
+// keywords, inspecting the body
+func DefaultSeverityNormalization() *SeverityNormalization {
+	return &SeverityNormalization{Keywords: DefaultSeverityKeywords(), InspectBody: true}
+}
+
+// NormalizeSeverity fills in the severity number of a record that arrived as
+// SEVERITY_NUMBER_UNSPECIFIED. Returns the action taken, or false if unchanged.
+func NormalizeSeverity(lr *logspb.LogRecord, cfg *SeverityNormalization) (TransformAction, bool) {
+	if cfg == nil || lr.GetSeverityNumber() != logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED {
+		return TransformAction{}, false
+	}
+
+	if sev, ok := cfg.lookup(lr.GetSeverityText()); ok {
+		lr.SeverityNumber = sev
+		return TransformAction{
+			Type:   ActionNormalizeSeverity,
+			Rule:   "severity_text",
+			Detail: "Normalized severity: " + SeverityName(sev) + " (from severity_text)",
+		}, true
+	}
+
+	if !cfg.InspectBody {
+		return TransformAction{}, false
+	}
+	if sev, ok := cfg.lookup(leadingToken(lr.GetBody().GetStringValue())); ok {
+		lr.SeverityNumber = sev
+		if lr.GetSeverityText() == "" {
+			lr.SeverityText = SeverityName(sev)
+		}
+		return TransformAction{
+			Type:   ActionNormalizeSeverity,
+			Rule:   "body",
+			Detail: "Normalized severity: " + SeverityName(sev) + " (from body)",
+		}, true
+	}
+	return TransformAction{}, false
+}
+
+// lookup finds the severity for a keyword, ignoring case
+func (cfg *SeverityNormalization) lookup(word string) (logspb.SeverityNumber, bool) {
+	if word == "" {
+		return 0, false
+	}
+	sev, ok := cfg.Keywords[strings.ToLower(word)]
+	return sev, ok
+}
+
+// leadingToken returns the first word of a body with surrounding brackets and punctuation removed,
+// so "[debug] starting", "ERROR: failed", and "<warn> slow" yield "debug", "ERROR", and "warn"
+func leadingToken(body string) string {
+	fields := strings.Fields(body)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.Trim(fields[0], "[]()<>{}:|-")
+}
+
+// SeverityName returns the short name of a severity number (e.g. "WARN", "ERROR2")
+func SeverityName(sev logspb.SeverityNumber) string {
+	return strings.TrimPrefix(sev.String(), "SEVERITY_NUMBER_")
+}
+
+// ParseSeverity parses a severity name ("warn", "ERROR2") or number ("13")
+func ParseSeverity(s string) (logspb.SeverityNumber, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if _, ok := logspb.SeverityNumber_name[int32(n)]; ok {
+			return logspb.SeverityNumber(n), nil
+		}
+		return 0, fmt.Errorf("severity number %d out of range", n)
+	}
+	name := "SEVERITY_NUMBER_" + strings.ToUpper(strings.TrimSpace(s))
+	if n, ok := logspb.SeverityNumber_value[name]; ok {
+		return logspb.SeverityNumber(n), nil
+	}
+	return 0, fmt.Errorf("unknown severity %q", s)
+}
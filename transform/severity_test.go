@@ -0,0 +1,117 @@
+// ABOUTME: Tests for severity normalization.
+// ABOUTME: Covers inference from severity_text, body tokens, and custom mappings.
+
+package transform
+
+import (
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func defaultNormalization() *SeverityNormalization {
+	return &SeverityNormalization{Keywords: DefaultSeverityKeywords(), InspectBody: true}
+}
+
+func TestNormalizeSeverity_FromSeverityText(t *testing.T) {
+	lr := &logspb.LogRecord{SeverityText: "Warning"}
+
+	action, _ := NormalizeSeverity(lr, defaultNormalization())
+
+	if lr.GetSeverityNumber() != logspb.SeverityNumber_SEVERITY_NUMBER_WARN {
+		t.Errorf("SeverityNumber = %v, want WARN", lr.GetSeverityNumber())
+	}
+	if action.Detail != "Normalized severity: WARN (from severity_text)" || action.Rule != "severity_text" {
+		t.Errorf("unexpected action %+v", action)
+	}
+}
+
+func TestNormalizeSeverity_FromBodyTokens(t *testing.T) {
+	tests := []struct {
+		body string
+		want logspb.SeverityNumber
+	}{
+		{"ERROR failed to connect", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR},
+		{"[debug] cache miss", logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG},
+		{"WARN: slow response", logspb.SeverityNumber_SEVERITY_NUMBER_WARN},
+		{"<info> started", logspb.SeverityNumber_SEVERITY_NUMBER_INFO},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, tt.body)
+
+			if _, ok := NormalizeSeverity(lr, defaultNormalization()); !ok {
+				t.Fatal("expected severity to be normalized")
+			}
+			if lr.GetSeverityNumber() != tt.want {
+				t.Errorf("SeverityNumber = %v, want %v", lr.GetSeverityNumber(), tt.want)
+			}
+			if lr.GetSeverityText() != SeverityName(tt.want) {
+				t.Errorf("SeverityText = %q, want %q", lr.GetSeverityText(), SeverityName(tt.want))
+			}
+		})
+	}
+}
+
+func TestNormalizeSeverity_BodyInspectionDisabled(t *testing.T) {
+	cfg := defaultNormalization()
+	cfg.InspectBody = false
+	lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "ERROR boom")
+
+	if action, ok := NormalizeSeverity(lr, cfg); ok {
+		t.Errorf("expected no action, got %q", action)
+	}
+	if lr.GetSeverityNumber() != logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED {
+		t.Errorf("severity should remain unspecified, got %v", lr.GetSeverityNumber())
+	}
+}
+
+func TestNormalizeSeverity_ExistingSeverityUntouched(t *testing.T) {
+	lr := &logspb.LogRecord{
+		SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		SeverityText:   "ERROR",
+		Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "ERROR x"}},
+	}
+
+	if action, ok := NormalizeSeverity(lr, defaultNormalization()); ok {
+		t.Errorf("expected no action, got %q", action)
+	}
+	if lr.GetSeverityNumber() != logspb.SeverityNumber_SEVERITY_NUMBER_INFO {
+		t.Errorf("severity changed to %v", lr.GetSeverityNumber())
+	}
+}
+
+func TestNormalizeSeverity_UnknownTokenNoOp(t *testing.T) {
+	lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "GET /health 200")
+
+	if action, ok := NormalizeSeverity(lr, defaultNormalization()); ok {
+		t.Errorf("expected no action, got %q", action)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    logspb.SeverityNumber
+		wantErr bool
+	}{
+		{"warn", logspb.SeverityNumber_SEVERITY_NUMBER_WARN, false},
+		{"ERROR2", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR2, false},
+		{"17", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, false},
+		{"loud", 0, true},
+		{"99", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSeverity(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSeverity(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSeverity(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
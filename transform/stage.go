@@ -0,0 +1,133 @@
+// ABOUTME: Transform pipeline stages and registration of custom Go stages.
+// ABOUTME: Built-in stages run in a fixed order; embedders insert their own after any named stage.
+
+package transform
+
+import (
+	"fmt"
+	"sync"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Stage is one step of the transform pipeline. Apply modifies the record in
+// place and returns the actions taken.
+type Stage interface {
+	Apply(lr *logspb.LogRecord) (actions []TransformAction)
+}
+
+// StageFunc adapts a function to a Stage
+type StageFunc func(lr *logspb.LogRecord) []TransformAction
+
+// Apply calls f(lr)
+func (f StageFunc) Apply(lr *logspb.LogRecord) []TransformAction {
+	return f(lr)
+}
+
+// Built-in stage names, in pipeline order
+const (
+	StageRename   = "rename"
+	StageDelete   = "delete"
+	StageRedact   = "redact"
+	StageParse    = "parse"
+	StageTruncate = "truncate"
+	StageEnrich   = "enrich"
+	StageLimits   = "limits"
+)
+
+// NamedStage is a stage with the name it's registered under
+type NamedStage struct {
+	Name string
+	Stage
+}
+
+// registeredStage is a custom stage and the stage it runs after
+type registeredStage struct {
+	NamedStage
+	after string
+}
+
+var (
+	stagesMu   sync.RWMutex
+	registered []registeredStage
+)
+
+// RegisterStage adds a custom stage to every config's pipeline, running right
+// after the stage named after ("" = before all built-in stages). Stages
+// registered after the same stage run in registration order.
+func RegisterStage(name, after string, s Stage) error {
+	stagesMu.Lock()
+	defer stagesMu.Unlock()
+
+	known := map[string]bool{"": true}
+	for _, stage := range (&Config{}).builtinStages(nil) {
+		known[stage.Name] = true
+	}
+	for _, r := range registered {
+		known[r.Name] = true
+	}
+	if name == "" {
+		return fmt.Errorf("stage name is required")
+	}
+	if known[name] {
+		return fmt.Errorf("stage %q already exists", name)
+	}
+	if !known[after] {
+		return fmt.Errorf("unknown stage %q", after)
+	}
+	registered = append(registered, registeredStage{NamedStage{name, s}, after})
+	return nil
+}
+
+// builtinStages returns the config's built-in stages in order, for records of res
+func (cfg *Config) builtinStages(res *resourcepb.Resource) []NamedStage {
+	return []NamedStage{
+		{StageRename, forResource(res, cfg.renameFields)},
+		{StageDelete, forResource(res, cfg.deleteFields)},
+		{StageRedact, forResource(res, cfg.redact)},
+		{StageParse, forResource(res, cfg.parseBody)},
+		{StageTruncate, forResource(res, cfg.truncate)},
+		{StageEnrich, forResource(res, cfg.enrich)},
+		{StageLimits, forResource(res, cfg.limitSizes)},
+	}
+}
+
+// forResource adapts a built-in stage, whose rule conditions also see the
+// record's resource, to a Stage for records of res
+func forResource(res *resourcepb.Resource, f func(*resourcepb.Resource, *logspb.LogRecord) []TransformAction) Stage {
+	return StageFunc(func(lr *logspb.LogRecord) []TransformAction {
+		return f(res, lr)
+	})
+}
+
+// Pipeline returns the config's stages in run order for records of res, which
+// may be nil, with registered custom stages inserted after the stages they name
+func (cfg *Config) Pipeline(res *resourcepb.Resource) []NamedStage {
+	stagesMu.RLock()
+	defer stagesMu.RUnlock()
+
+	builtin := cfg.builtinStages(res)
+	if len(registered) == 0 {
+		return builtin
+	}
+	pipeline := make([]NamedStage, 0, len(builtin)+len(registered))
+	pipeline = appendAfter(pipeline, "")
+	for _, stage := range builtin {
+		pipeline = append(pipeline, stage)
+		pipeline = appendAfter(pipeline, stage.Name)
+	}
+	return pipeline
+}
+
+// appendAfter appends the custom stages that run after name, and those that
+// run after them in turn. Caller holds stagesMu.
+func appendAfter(pipeline []NamedStage, name string) []NamedStage {
+	for _, r := range registered {
+		if r.after == name {
+			pipeline = append(pipeline, r.NamedStage)
+			pipeline = appendAfter(pipeline, r.Name)
+		}
+	}
+	return pipeline
+}
@@ -0,0 +1,100 @@
+// ABOUTME: Tests for pipeline stages and custom stage registration.
+// ABOUTME: Covers built-in order, insertion points, chaining, and registration errors.
+
+package transform
+
+import (
+	"slices"
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// resetStages removes registered stages when a test ends
+func resetStages(t *testing.T) {
+	t.Cleanup(func() {
+		stagesMu.Lock()
+		registered = nil
+		stagesMu.Unlock()
+	})
+}
+
+func stageNames(stages []NamedStage) []string {
+	names := make([]string, len(stages))
+	for i, s := range stages {
+		names[i] = s.Name
+	}
+	return names
+}
+
+func TestPipeline_BuiltinOrder(t *testing.T) {
+	want := []string{StageRename, StageDelete, StageRedact, StageParse, StageTruncate, StageEnrich, StageLimits}
+	if got := stageNames(DefaultConfig().Pipeline(nil)); !slices.Equal(got, want) {
+		t.Errorf("pipeline = %v, want %v", got, want)
+	}
+}
+
+func TestRegisterStage_InsertionPoints(t *testing.T) {
+	resetStages(t)
+	noop := StageFunc(func(*logspb.LogRecord) []TransformAction { return nil })
+
+	for _, r := range []struct{ name, after string }{
+		{"first", ""},
+		{"tag", StageRedact},
+		{"after-tag", "tag"},
+		{"also-after-redact", StageRedact},
+	} {
+		if err := RegisterStage(r.name, r.after, noop); err != nil {
+			t.Fatalf("RegisterStage(%q, %q): %v", r.name, r.after, err)
+		}
+	}
+
+	want := []string{"first", StageRename, StageDelete, StageRedact, "tag", "after-tag", "also-after-redact",
+		StageParse, StageTruncate, StageEnrich, StageLimits}
+	if got := stageNames(DefaultConfig().Pipeline(nil)); !slices.Equal(got, want) {
+		t.Errorf("pipeline = %v, want %v", got, want)
+	}
+}
+
+func TestRegisterStage_Errors(t *testing.T) {
+	resetStages(t)
+	noop := StageFunc(func(*logspb.LogRecord) []TransformAction { return nil })
+
+	if err := RegisterStage("", "", noop); err == nil {
+		t.Error("empty name should be rejected")
+	}
+	if err := RegisterStage(StageRedact, "", noop); err == nil {
+		t.Error("built-in name should be rejected")
+	}
+	if err := RegisterStage("custom", "missing", noop); err == nil {
+		t.Error("unknown insertion point should be rejected")
+	}
+	if err := RegisterStage("custom", StageEnrich, noop); err != nil {
+		t.Fatalf("RegisterStage: %v", err)
+	}
+	if err := RegisterStage("custom", StageEnrich, noop); err == nil {
+		t.Error("duplicate name should be rejected")
+	}
+}
+
+func TestApplyWithConfig_RunsCustomStage(t *testing.T) {
+	resetStages(t)
+	// Runs after redaction, so it sees the redacted body
+	err := RegisterStage("copy-body", StageRedact, StageFunc(func(lr *logspb.LogRecord) []TransformAction {
+		SetAttribute(lr, "body_copy", lr.GetBody().GetStringValue())
+		return []TransformAction{{Detail: "Copied body"}}
+	}))
+	if err != nil {
+		t.Fatalf("RegisterStage: %v", err)
+	}
+
+	lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "card 4111-1111-1111-1111")
+	_, actions := ApplyWithConfig(nil, lr, DefaultConfig())
+
+	if got := getAttr(lr, "body_copy"); got != "card [PCI-REDACTED]" {
+		t.Errorf("body_copy = %q, want the redacted body", got)
+	}
+	if last := actions[len(actions)-1]; last.Detail != "Copied body" || last.Type != "copy-body" {
+		t.Errorf("actions = %+v, want custom action typed by its stage after redaction", actions)
+	}
+}
@@ -0,0 +1,34 @@
+// ABOUTME: Runs OTTL statements from the transform config against a record and its resource.
+// ABOUTME: Lets the mock validate the exact statements users will put in the collector's transform processor.
+
+package transform
+
+import (
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-mock-receiver/ottl"
+)
+
+// ApplyStatements runs the config's OTTL statements in order. The shared
+// resource is never modified: a copy is returned if a statement wrote to it.
+// A statement that fails is reported and skipped, like the collector's
+// error_mode: ignore.
+func ApplyStatements(res *resourcepb.Resource, lr *logspb.LogRecord, cfg *Config) (*resourcepb.Resource, []TransformAction) {
+	if len(cfg.Statements) == 0 {
+		return res, nil
+	}
+
+	ctx := &ottl.Context{Resource: res, Log: lr}
+	var actions []TransformAction
+	for _, stmt := range cfg.Statements {
+		ran, err := stmt.Execute(ctx)
+		switch {
+		case err != nil:
+			actions = append(actions, TransformAction{Type: ActionStatementError, Rule: stmt.Text, Detail: "OTTL error: " + stmt.Text + ": " + err.Error()})
+		case ran:
+			actions = append(actions, TransformAction{Type: ActionStatement, Rule: stmt.Text, Detail: "OTTL: " + stmt.Text})
+		}
+	}
+	return ctx.Resource, actions
+}
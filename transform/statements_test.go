@@ -0,0 +1,52 @@
+// ABOUTME: Tests for running OTTL statements from the transform config.
+// ABOUTME: Covers config loading, actions for executed and failed statements, and resource copies.
+
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func TestApplyStatements(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `
+log_statements:
+  - set(attributes["env"], "prod") where resource.attributes["space_name"] == "production"
+  - set(resource.attributes["team"], "payments")
+  - set(attributes["parsed"], ParseJSON(body))
+  - delete_key(attributes, "env") where body == "never"
+`))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	shared := &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		{Key: "space_name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "production"}}},
+	}}
+	lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "hello")
+
+	res, actions := ApplyStatements(shared, lr, cfg)
+	if getAttr(lr, "env") != "prod" {
+		t.Error("env should be set from the resource condition")
+	}
+	if res == shared || len(shared.Attributes) != 1 {
+		t.Error("resource writes should go to a copy")
+	}
+	if len(actions) != 3 {
+		t.Fatalf("actions = %v, want 2 executed and 1 error", actions)
+	}
+	if actions[2].Type != ActionStatementError || !strings.HasPrefix(actions[2].Detail, "OTTL error: set(attributes[\"parsed\"], ParseJSON(body)): ") {
+		t.Errorf("actions[2] = %+v", actions[2])
+	}
+}
+
+func TestLoadConfig_InvalidStatement(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, "log_statements:\n  - 'set(attributes[\"a\"], \"b\") where'\n  - flatten(attributes)\n"))
+	if err == nil || !strings.Contains(err.Error(), "log_statements[0]") {
+		t.Errorf("expected log_statements[0] error, got %v", err)
+	}
+}
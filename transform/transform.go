@@ -4,22 +4,18 @@
 package transform
 
 import (
-	"hash/fnv"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
-)
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 
-// SamplingConfig controls log sampling behavior
-type SamplingConfig struct {
-	// SampleRate: keep 1 in N logs (1 = keep all, 10 = keep 10%)
-	SampleRate int
-	// SampleDebugOnly: when true, only sample DEBUG severity logs
-	SampleDebugOnly bool
-}
+	"otlp-mock-receiver/ottl"
+)
 
 // Config holds transformation configuration
 type Config struct {
@@ -29,17 +25,66 @@ type Config struct {
 	// Fields to delete
 	FieldsToDelete []string
 
-	// Max body length (0 = no limit)
+	// Resource attribute renames and deletes, gated by RenameWhen/DeleteWhen (empty = none)
+	ResourceFieldRenames   map[string]string
+	ResourceFieldsToDelete []string
+
+	// Max body length (0 = no limit), counted in MaxBodyUnit
 	MaxBodyLength int
+	MaxBodyUnit   string // TruncateBytes (default) or TruncateRunes
+
+	// Attribute value and record size limits in bytes (0 = no limit), gated by TruncateWhen
+	MaxAttributeLength  int
+	OversizedAttributes string // AttributeTruncate (default) or AttributeDrop
+	MaxRecordBytes      int
+
+	// Sensitive keys whose values are masked in structured bodies (nil = disabled)
+	SecretScrubbing *SecretScrubbing
 
 	// PCI patterns to redact
 	PCIPatterns []*regexp.Regexp
 
+	// XML elements whose contents are redacted, gated by RedactWhen
+	XMLRedactions []*XMLRedaction
+
 	// App allowlist (empty = allow all)
 	AllowedApps []string
 
 	// Sampling configuration
 	Sampling *SamplingConfig
+
+	// Severity inference for records without a severity number (nil = disabled)
+	SeverityNormalization *SeverityNormalization
+
+	// Optional conditions gating each rule type (nil = apply to every record)
+	RenameWhen   *Match
+	DeleteWhen   *Match
+	RedactWhen   *Match
+	TruncateWhen *Match
+
+	// Per-app overrides keyed by lowercase app name, selected with ForRecord
+	Profiles map[string]*Profile
+
+	// Attribute set to the detected body type ("" = don't tag)
+	BodyTypeAttribute string
+
+	// Rules that discard matching records before any transforms run
+	DropRules []*DropRule
+
+	// Parses logfmt bodies into attributes (nil = disabled)
+	Logfmt *LogfmtParsing
+
+	// Body parsing rules that set attributes from named captures
+	Extractions []*Extraction
+
+	// Constant attributes added after all other transforms
+	Enrichments []*Enrichment
+
+	// Rules that roll up matching records into one record per window
+	AggregateRules []*AggregateRule
+
+	// OTTL statements run before every other transform, as the collector would upstream
+	Statements []*ottl.Statement
 }
 
 // DefaultConfig returns the default transformation config for CF/TAS field standardization
@@ -76,72 +121,169 @@ var defaultConfig = DefaultConfig()
 
 // Apply runs the transformation pipeline on a log record.
 // Returns the transformed log and a list of actions taken.
-func Apply(lr *logspb.LogRecord) (*logspb.LogRecord, []string) {
-	return ApplyWithConfig(lr, defaultConfig)
+func Apply(lr *logspb.LogRecord) (*logspb.LogRecord, []TransformAction) {
+	return ApplyWithConfig(nil, lr, defaultConfig)
 }
 
-// ApplyWithConfig runs transformations with a custom config
-func ApplyWithConfig(lr *logspb.LogRecord, cfg *Config) (*logspb.LogRecord, []string) {
-	var actions []string
+// ApplyWithConfig runs the config's pipeline stages, including registered
+// custom stages, on a log record. Rule conditions also see the record's
+// resource, which may be nil. Actions from custom stages that leave Type
+// empty are typed with the stage's name.
+func ApplyWithConfig(res *resourcepb.Resource, lr *logspb.LogRecord, cfg *Config) (*logspb.LogRecord, []TransformAction) {
+	var actions []TransformAction
+	for _, stage := range cfg.Pipeline(res) {
+		for _, action := range stage.Apply(lr) {
+			if action.Type == "" {
+				action.Type = ActionType(stage.Name)
+			}
+			actions = append(actions, action)
+		}
+	}
+	return lr, actions
+}
 
-	// 1. Rename fields
+// renameFields renames attributes per FieldRenames
+func (cfg *Config) renameFields(res *resourcepb.Resource, lr *logspb.LogRecord) []TransformAction {
+	if !cfg.RenameWhen.Matches(res, lr) {
+		return nil
+	}
+	var actions []TransformAction
 	for oldKey, newKey := range cfg.FieldRenames {
 		if renameAttribute(lr, oldKey, newKey) {
-			actions = append(actions, "Renamed: "+oldKey+" -> "+newKey)
+			actions = append(actions, TransformAction{Type: ActionRename, Rule: oldKey, Detail: "Renamed: " + oldKey + " -> " + newKey})
 		}
 	}
+	return actions
+}
 
-	// 2. Delete fields
+// deleteFields removes the attributes in FieldsToDelete
+func (cfg *Config) deleteFields(res *resourcepb.Resource, lr *logspb.LogRecord) []TransformAction {
+	if !cfg.DeleteWhen.Matches(res, lr) {
+		return nil
+	}
+	var actions []TransformAction
 	for _, key := range cfg.FieldsToDelete {
 		if deleteAttribute(lr, key) {
-			actions = append(actions, "Deleted: "+key)
+			actions = append(actions, TransformAction{Type: ActionDelete, Rule: key, Detail: "Deleted: " + key})
 		}
 	}
+	return actions
+}
 
-	// 3. PCI redaction
+// redact applies secret, PCI, and XML element redaction
+func (cfg *Config) redact(res *resourcepb.Resource, lr *logspb.LogRecord) []TransformAction {
+	if !cfg.RedactWhen.Matches(res, lr) {
+		return nil
+	}
+	var actions []TransformAction
+	if cfg.SecretScrubbing != nil {
+		actions = append(actions, cfg.SecretScrubbing.scrub(lr)...)
+	}
 	for i, pattern := range cfg.PCIPatterns {
 		if redactPattern(lr, pattern, "[PCI-REDACTED]") {
-			actions = append(actions, "Redacted PCI pattern #"+strconv.Itoa(i+1))
+			actions = append(actions, TransformAction{Type: ActionRedactPCI, Rule: pattern.String(), Detail: "Redacted PCI pattern #" + strconv.Itoa(i+1)})
 		}
 	}
+	for _, x := range cfg.XMLRedactions {
+		if x.redact(lr) {
+			actions = append(actions, TransformAction{Type: ActionRedactXML, Rule: x.Element, Detail: "Redacted XML element " + x.Element})
+		}
+	}
+	return actions
+}
 
-	// 4. Truncate body
-	if cfg.MaxBodyLength > 0 {
-		if truncateBody(lr, cfg.MaxBodyLength) {
-			actions = append(actions, "Truncated body to max length")
+// parseBody sets attributes parsed from the (redacted) body
+func (cfg *Config) parseBody(res *resourcepb.Resource, lr *logspb.LogRecord) []TransformAction {
+	var actions []TransformAction
+	if cfg.Logfmt != nil {
+		if action, ok := cfg.Logfmt.parse(res, lr); ok {
+			actions = append(actions, action)
 		}
 	}
+	for _, e := range cfg.Extractions {
+		if action, ok := e.extract(res, lr); ok {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
 
-	if len(actions) == 0 {
-		actions = append(actions, "No transformations applied")
+// truncate cuts the body to MaxBodyLength
+func (cfg *Config) truncate(res *resourcepb.Resource, lr *logspb.LogRecord) []TransformAction {
+	if cfg.MaxBodyLength <= 0 || !cfg.TruncateWhen.Matches(res, lr) {
+		return nil
+	}
+	if action, ok := truncateBody(lr, cfg.MaxBodyLength, cfg.MaxBodyUnit); ok {
+		return []TransformAction{action}
 	}
+	return nil
+}
 
-	return lr, actions
+// enrich adds static attributes
+func (cfg *Config) enrich(res *resourcepb.Resource, lr *logspb.LogRecord) []TransformAction {
+	var actions []TransformAction
+	for _, e := range cfg.Enrichments {
+		actions = append(actions, e.enrich(res, lr)...)
+	}
+	return actions
+}
+
+// limitSizes enforces attribute and record size limits on the final record
+func (cfg *Config) limitSizes(res *resourcepb.Resource, lr *logspb.LogRecord) []TransformAction {
+	if (cfg.MaxAttributeLength <= 0 && cfg.MaxRecordBytes <= 0) || !cfg.TruncateWhen.Matches(res, lr) {
+		return nil
+	}
+	var actions []TransformAction
+	if cfg.MaxAttributeLength > 0 {
+		actions = append(actions, limitAttributes(lr, cfg.MaxAttributeLength, cfg.OversizedAttributes)...)
+	}
+	if cfg.MaxRecordBytes > 0 {
+		actions = append(actions, limitRecord(lr, cfg.MaxRecordBytes)...)
+	}
+	return actions
 }
 
 // renameAttribute renames an attribute key. Returns true if renamed.
 func renameAttribute(lr *logspb.LogRecord, oldKey, newKey string) bool {
-	for _, attr := range lr.GetAttributes() {
-		if attr.GetKey() == oldKey {
-			attr.Key = newKey
-			return true
-		}
-	}
-	return false
+	return renameKey(lr.GetAttributes(), oldKey, newKey)
 }
 
 // deleteAttribute removes an attribute by key. Returns true if deleted.
 func deleteAttribute(lr *logspb.LogRecord, key string) bool {
-	attrs := lr.GetAttributes()
+	attrs, deleted := deleteKey(lr.GetAttributes(), key)
+	lr.Attributes = attrs
+	return deleted
+}
+
+// renameKey renames the first key-value with oldKey. Returns true if renamed.
+func renameKey(attrs []*commonpb.KeyValue, oldKey, newKey string) bool {
+	if kv := findKey(attrs, oldKey); kv != nil {
+		kv.Key = newKey
+		return true
+	}
+	return false
+}
+
+// deleteKey removes the first key-value with key. Returns the remaining attributes and whether one was removed.
+func deleteKey(attrs []*commonpb.KeyValue, key string) ([]*commonpb.KeyValue, bool) {
 	for i, attr := range attrs {
 		if attr.GetKey() == key {
 			// Remove by replacing with last element and truncating
 			attrs[i] = attrs[len(attrs)-1]
-			lr.Attributes = attrs[:len(attrs)-1]
-			return true
+			return attrs[:len(attrs)-1], true
 		}
 	}
-	return false
+	return attrs, false
+}
+
+// findKey returns the first key-value with key, or nil
+func findKey(attrs []*commonpb.KeyValue, key string) *commonpb.KeyValue {
+	for _, attr := range attrs {
+		if attr.GetKey() == key {
+			return attr
+		}
+	}
+	return nil
 }
 
 // redactPattern applies regex redaction to the log body. Returns true if any matches replaced.
@@ -167,23 +309,74 @@ func redactPattern(lr *logspb.LogRecord, pattern *regexp.Regexp, replacement str
 	return true
 }
 
-// truncateBody truncates the log body if it exceeds maxLen. Returns true if truncated.
-func truncateBody(lr *logspb.LogRecord, maxLen int) bool {
+// Units MaxBodyLength can be counted in
+const (
+	TruncateBytes = "bytes"
+	TruncateRunes = "runes"
+)
+
+// OriginalBodyBytesAttribute records a truncated body's length before truncation
+const OriginalBodyBytesAttribute = "original_body_bytes"
+
+// truncateMarker is appended to truncated bodies
+const truncateMarker = "...[TRUNCATED]"
+
+// truncateBody cuts the log body to maxLen bytes or runes, never splitting a
+// UTF-8 sequence, and records the original size. Returns the action taken, or
+// false if the body fit.
+func truncateBody(lr *logspb.LogRecord, maxLen int, unit string) (TransformAction, bool) {
 	body := lr.GetBody()
 	if body == nil {
-		return false
+		return TransformAction{}, false
 	}
 
 	str := body.GetStringValue()
-	if len(str) <= maxLen {
-		return false
+	var cut int
+	if unit == TruncateRunes {
+		cut = runeOffset(str, maxLen)
+	} else {
+		cut = byteOffset(str, maxLen)
+	}
+	if cut >= len(str) {
+		return TransformAction{}, false
 	}
 
-	truncated := str[:maxLen] + "...[TRUNCATED]"
 	lr.Body = &commonpb.AnyValue{
-		Value: &commonpb.AnyValue_StringValue{StringValue: truncated},
+		Value: &commonpb.AnyValue_StringValue{StringValue: str[:cut] + truncateMarker},
 	}
-	return true
+	SetAttribute(lr, OriginalBodyBytesAttribute, strconv.Itoa(len(str)))
+	return TransformAction{
+		Type:   ActionTruncateBody,
+		Detail: "Truncated body from " + strconv.Itoa(len(str)) + " to " + strconv.Itoa(cut) + " bytes",
+	}, true
+}
+
+// byteOffset returns the largest cut of at most maxLen bytes that doesn't split
+// a UTF-8 sequence, or len(s) if s fits
+func byteOffset(s string, maxLen int) int {
+	if len(s) <= maxLen {
+		return len(s)
+	}
+	// Back off over at most one sequence's continuation bytes; invalid input
+	// with longer runs is cut at maxLen
+	for i := maxLen; i >= 0 && i > maxLen-utf8.UTFMax; i-- {
+		if utf8.RuneStart(s[i]) {
+			return i
+		}
+	}
+	return maxLen
+}
+
+// runeOffset returns the byte offset just past the first maxLen runes, or len(s) if s fits
+func runeOffset(s string, maxLen int) int {
+	n := 0
+	for i := range s {
+		if n == maxLen {
+			return i
+		}
+		n++
+	}
+	return len(s)
 }
 
 // ShouldAllow checks if a log should be allowed based on app allowlist.
@@ -216,25 +409,37 @@ func getAttributeValue(lr *logspb.LogRecord, key string) string {
 	return ""
 }
 
-// SetAttribute sets or updates an attribute value
-func SetAttribute(lr *logspb.LogRecord, key, value string) {
-	// Check if exists
-	for _, attr := range lr.GetAttributes() {
-		if attr.GetKey() == key {
-			attr.Value = &commonpb.AnyValue{
-				Value: &commonpb.AnyValue_StringValue{StringValue: value},
+// findValue returns the string value of the first of keys found on the
+// record, then on the resource
+func findValue(res *resourcepb.Resource, lr *logspb.LogRecord, keys ...string) string {
+	for _, attrs := range [][]*commonpb.KeyValue{lr.GetAttributes(), res.GetAttributes()} {
+		for _, attr := range attrs {
+			if slices.Contains(keys, attr.GetKey()) {
+				return attr.GetValue().GetStringValue()
 			}
-			return
 		}
 	}
+	return ""
+}
 
-	// Add new attribute
-	lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{
-		Key: key,
-		Value: &commonpb.AnyValue{
-			Value: &commonpb.AnyValue_StringValue{StringValue: value},
-		},
-	})
+// getAppName returns the record's app name, which TAS sends on the resource
+func getAppName(res *resourcepb.Resource, lr *logspb.LogRecord) string {
+	return findValue(res, lr, "cf_app_name", "application_name")
+}
+
+// SetAttribute sets or updates an attribute value
+func SetAttribute(lr *logspb.LogRecord, key, value string) {
+	lr.Attributes = setKey(lr.Attributes, key, value)
+}
+
+// setKey sets or updates a string key-value, returning the (possibly grown) attributes
+func setKey(attrs []*commonpb.KeyValue, key, value string) []*commonpb.KeyValue {
+	v := &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}
+	if kv := findKey(attrs, key); kv != nil {
+		kv.Value = v
+		return attrs
+	}
+	return append(attrs, &commonpb.KeyValue{Key: key, Value: v})
 }
 
 // DetermineIndex returns which Splunk index a log should route to.
@@ -255,33 +460,3 @@ func DetermineIndex(lr *logspb.LogRecord) string {
 	// Default index
 	return "tas_logs"
 }
-
-// ShouldSample determines if a log should be kept based on sampling config.
-// Returns true if the log should be kept, false if it should be dropped.
-func ShouldSample(lr *logspb.LogRecord, cfg *SamplingConfig) bool {
-	// No sampling config or rate of 1 means keep all
-	if cfg == nil || cfg.SampleRate <= 1 {
-		return true
-	}
-
-	severity := lr.GetSeverityNumber()
-
-	// ERROR and above are never sampled
-	if severity >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR {
-		return true
-	}
-
-	// If SampleDebugOnly is true, only sample DEBUG logs (severity < INFO)
-	if cfg.SampleDebugOnly && severity >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO {
-		return true
-	}
-
-	// Deterministic sampling based on log content hash
-	h := fnv.New32a()
-	if body := lr.GetBody(); body != nil {
-		h.Write([]byte(body.GetStringValue()))
-	}
-	hash := h.Sum32()
-
-	return hash%uint32(cfg.SampleRate) == 0
-}
@@ -5,6 +5,7 @@ package transform
 
 import (
 	"testing"
+	"unicode/utf8"
 
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
@@ -90,11 +91,8 @@ func TestFieldRenames_MissingField_NoOp(t *testing.T) {
 
 	// Actions should not mention any renames
 	for _, action := range actions {
-		if action != "No transformations applied" && action != "Deleted: diego_cell_ip" && action != "Deleted: process_id" && action != "Deleted: source_id" {
-			// Allow deletion actions but no renames
-			if len(action) > 8 && action[:8] == "Renamed:" {
-				t.Errorf("unexpected rename action: %s", action)
-			}
+		if action.Type == ActionRename {
+			t.Errorf("unexpected rename action: %s", action)
 		}
 	}
 }
@@ -250,3 +248,54 @@ func TestSampling_DeterministicForSameContent(t *testing.T) {
 		}
 	}
 }
+
+func TestTruncateBody_CutsOnRuneBoundary(t *testing.T) {
+	body := "héllo wörld" // é and ö are two bytes each
+	lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, body)
+
+	// Byte 9 falls inside ö, so the cut backs off to byte 8
+	action, ok := truncateBody(lr, 9, TruncateBytes)
+	got := lr.GetBody().GetStringValue()
+	if got != "héllo w"+truncateMarker {
+		t.Errorf("body = %q, want %q", got, "héllo w"+truncateMarker)
+	}
+	if !utf8.ValidString(got) {
+		t.Error("truncated body should be valid UTF-8")
+	}
+	if !ok || action.Type != ActionTruncateBody || action.Detail != "Truncated body from 13 to 8 bytes" {
+		t.Errorf("action = %+v", action)
+	}
+	if v := getAttr(lr, OriginalBodyBytesAttribute); v != "13" {
+		t.Errorf("%s = %q, want 13", OriginalBodyBytesAttribute, v)
+	}
+}
+
+func TestTruncateBody_Runes(t *testing.T) {
+	lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "日本語のログ")
+
+	truncateBody(lr, 3, TruncateRunes)
+	if got := lr.GetBody().GetStringValue(); got != "日本語"+truncateMarker {
+		t.Errorf("body = %q, want 3 runes", got)
+	}
+	if v := getAttr(lr, OriginalBodyBytesAttribute); v != "18" {
+		t.Errorf("%s = %q, want 18", OriginalBodyBytesAttribute, v)
+	}
+}
+
+func TestTruncateBody_FitsUnchanged(t *testing.T) {
+	for _, unit := range []string{TruncateBytes, TruncateRunes} {
+		lr := makeLogRecordWithSeverity(logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "日本")
+		if action, ok := truncateBody(lr, 6, unit); ok {
+			t.Errorf("%s: body within the limit was truncated: %s", unit, action)
+		}
+		if getAttr(lr, OriginalBodyBytesAttribute) != "" {
+			t.Errorf("%s: %s should not be set", unit, OriginalBodyBytesAttribute)
+		}
+	}
+}
+
+func TestByteOffset_MultibyteAtStart(t *testing.T) {
+	if got := byteOffset("日本", 2); got != 0 {
+		t.Errorf("byteOffset inside the first rune = %d, want 0", got)
+	}
+}
@@ -0,0 +1,36 @@
+// ABOUTME: XML-aware redaction that blanks the contents of configured elements.
+// ABOUTME: Catches SOAP/XML payload fields that numeric PCI regexes miss, e.g. <CardNumber>.
+
+package transform
+
+import (
+	"fmt"
+	"regexp"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// xmlElementName matches a valid (unprefixed) XML element name
+var xmlElementName = regexp.MustCompile(`^[A-Za-z_][\w.\-]*$`)
+
+// XMLRedaction blanks the contents of every occurrence of an element, in any namespace
+type XMLRedaction struct {
+	Element string
+	pattern *regexp.Regexp
+}
+
+// NewXMLRedaction builds a redaction for the named element
+func NewXMLRedaction(element string) (*XMLRedaction, error) {
+	if !xmlElementName.MatchString(element) {
+		return nil, fmt.Errorf("invalid element name %q", element)
+	}
+	name := `(?:[\w.\-]+:)?` + regexp.QuoteMeta(element)
+	// Opening tag (not self-closing), contents up to the nearest matching close tag
+	pattern := regexp.MustCompile(`(<` + name + `(?:\s[^>]*[^/>])?\s*>)(?s:.*?)(</` + name + `\s*>)`)
+	return &XMLRedaction{Element: element, pattern: pattern}, nil
+}
+
+// redact blanks the element's contents in the body. Returns true if any were replaced.
+func (x *XMLRedaction) redact(lr *logspb.LogRecord) bool {
+	return redactPattern(lr, x.pattern, "${1}[XML-REDACTED]${2}")
+}
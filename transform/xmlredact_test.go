@@ -0,0 +1,73 @@
+// ABOUTME: Tests for XML element redaction.
+// ABOUTME: Covers namespaces, attributes, nesting, self-closing tags, and conditions.
+
+package transform
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestXMLRedaction(t *testing.T) {
+	x, err := NewXMLRedaction("CardNumber")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"plain", `<Pay><CardNumber>4111111111111111</CardNumber></Pay>`, `<Pay><CardNumber>[XML-REDACTED]</CardNumber></Pay>`},
+		{"namespaced", `<ns1:CardNumber>4111</ns1:CardNumber>`, `<ns1:CardNumber>[XML-REDACTED]</ns1:CardNumber>`},
+		{"attributes", `<CardNumber type="visa">4111</CardNumber >`, `<CardNumber type="visa">[XML-REDACTED]</CardNumber >`},
+		{"nested and multiline", "<CardNumber>\n  <Digits>4111</Digits>\n</CardNumber>", `<CardNumber>[XML-REDACTED]</CardNumber>`},
+		{"every occurrence", `<CardNumber>1</CardNumber><CardNumber>2</CardNumber>`, `<CardNumber>[XML-REDACTED]</CardNumber><CardNumber>[XML-REDACTED]</CardNumber>`},
+		{"embedded in text", `SOAP request: <CardNumber>4111</CardNumber> sent`, `SOAP request: <CardNumber>[XML-REDACTED]</CardNumber> sent`},
+		{"self-closing", `<CardNumber/><Other>keep</Other>`, `<CardNumber/><Other>keep</Other>`},
+		{"longer name", `<CardNumberType>visa</CardNumberType>`, `<CardNumberType>visa</CardNumberType>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lr := makeLogRecord(nil)
+			lr.Body = stringBody(tt.body)
+			x.redact(lr)
+			if got := lr.GetBody().GetStringValue(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXMLRedaction_InvalidElement(t *testing.T) {
+	for _, name := range []string{"", "1Card", "Card Number", "<Card>"} {
+		if _, err := NewXMLRedaction(name); err == nil {
+			t.Errorf("NewXMLRedaction(%q) should fail", name)
+		}
+	}
+}
+
+func TestXMLRedaction_InPipeline(t *testing.T) {
+	cfg := DefaultConfig()
+	x, _ := NewXMLRedaction("CVV")
+	cfg.XMLRedactions = []*XMLRedaction{x}
+
+	lr := makeLogRecord(nil)
+	lr.Body = stringBody(`<Payment><CVV>123</CVV></Payment>`)
+	_, actions := ApplyWithConfig(nil, lr, cfg)
+	if lr.GetBody().GetStringValue() != `<Payment><CVV>[XML-REDACTED]</CVV></Payment>` {
+		t.Errorf("body = %q", lr.GetBody().GetStringValue())
+	}
+	if !slices.Contains(details(actions), "Redacted XML element CVV") {
+		t.Errorf("actions missing XML redaction: %v", actions)
+	}
+
+	cfg.RedactWhen = &Match{Apps: []string{"legacy-soap"}}
+	other := makeLogRecord(map[string]string{"cf_app_name": "other"})
+	other.Body = stringBody(`<CVV>123</CVV>`)
+	ApplyWithConfig(nil, other, cfg)
+	if other.GetBody().GetStringValue() != `<CVV>123</CVV>` {
+		t.Error("redact condition should gate XML redaction")
+	}
+}
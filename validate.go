@@ -0,0 +1,48 @@
+// ABOUTME: validate command that lints routing config files.
+// ABOUTME: Reports invalid rules, duplicate priorities, and shadowed rules without starting the receiver.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"otlp-mock-receiver/routing"
+)
+
+// validateFlags registers the validate command
+func validateFlags(fs *flag.FlagSet) func(args []string) error {
+	strict := fs.Bool("strict", false, "Fail on warnings as well as errors")
+
+	return func(args []string) error {
+		if len(args) == 0 {
+			return errors.New("no routing config file given")
+		}
+		errs, warnings := 0, 0
+		for _, path := range args {
+			fc, err := routing.ReadConfig(path)
+			if err != nil {
+				fmt.Printf("%s: error: %v\n", path, err)
+				errs++
+				continue
+			}
+			findings := fc.Lint()
+			for _, f := range findings {
+				fmt.Printf("%s: %s\n", path, f)
+				if f.Severity == routing.LintError {
+					errs++
+				} else {
+					warnings++
+				}
+			}
+			if len(findings) == 0 {
+				fmt.Printf("%s: OK (%d rules)\n", path, len(fc.Rules))
+			}
+		}
+		if errs > 0 || (*strict && warnings > 0) {
+			return fmt.Errorf("%d error(s), %d warning(s)", errs, warnings)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,311 @@
+// ABOUTME: Continuous verification that output files contain what the metrics say was written.
+// ABOUTME: Reconciles transformed counters per index with lines in the JSON output and flags drift.
+
+package verify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"otlp-mock-receiver/metrics"
+	"otlp-mock-receiver/output"
+)
+
+// IndexReport reconciles one routing index
+type IndexReport struct {
+	Index    string `json:"index"`
+	Metrics  int64  `json:"metrics"`  // Counted by otlp_receiver_logs_by_index_total
+	File     int64  `json:"file"`     // Lines found in the output file
+	Buffered int64  `json:"buffered"` // Held in the writer's buffer
+	Drift    int64  `json:"drift"`    // Metrics - File - Buffered
+}
+
+// Report is the result of the latest check
+type Report struct {
+	CheckedAt   time.Time     `json:"checked_at"`
+	Checks      int           `json:"checks"`
+	Transformed int64         `json:"transformed"` // otlp_receiver_logs_transformed_total
+	Copies      int64         `json:"copies"`      // otlp_receiver_logs_fanout_copies_total, one extra line each
+	File        int64         `json:"file"`
+	Buffered    int64         `json:"buffered"`
+	Drift       int64         `json:"drift"`
+	Unparseable int64         `json:"unparseable"` // Output lines without a routing index
+	Indexes     []IndexReport `json:"indexes"`
+	Drifting    []string      `json:"drifting"` // Indexes (or "total") with drift on two consecutive checks
+}
+
+// totalKey tracks drift of the transformed total alongside the indexes
+const totalKey = "total"
+
+// Verifier tails the JSON output file, counting records per index
+type Verifier struct {
+	path    string
+	metrics *metrics.Metrics
+	writer  *output.JSONWriter
+	queue   *output.Queue // Holds records on their way to the writer, if set
+
+	mu          sync.Mutex
+	file        *os.File
+	offset      int64
+	partial     []byte
+	counts      map[string]int64 // Lines read per index
+	total       int64
+	unparseable int64
+	lastDrift   map[string]int64
+	alerted     map[string]int64 // Drift last reported per key
+	report      Report
+}
+
+// New creates a verifier for the writer's output file. Only lines written after
+// this call are counted, matching counters that start at zero.
+func New(path string, m *metrics.Metrics, w *output.JSONWriter) *Verifier {
+	v := &Verifier{
+		path:      path,
+		metrics:   m,
+		writer:    w,
+		counts:    make(map[string]int64),
+		lastDrift: make(map[string]int64),
+		alerted:   make(map[string]int64),
+	}
+	if f, err := os.Open(path); err == nil {
+		v.file = f
+		if info, err := f.Stat(); err == nil {
+			v.offset = info.Size()
+		}
+	}
+	return v
+}
+
+// SetQueue counts records waiting in the writer's output queue as pending
+func (v *Verifier) SetQueue(q *output.Queue) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.queue = q
+}
+
+// Run checks every interval until stop is closed
+func (v *Verifier) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			v.Check()
+		}
+	}
+}
+
+// Check reconciles the counters with the output file, logging drift that
+// persisted since the previous check, and returns the report
+func (v *Verifier) Check() Report {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// Read in pipeline order (counters, then queue, then buffer, then file)
+	// so a record moving along between reads is never missed
+	transformed, copies, byIndex := v.metrics.TransformedCounts()
+	var queued map[string]int64
+	if v.queue != nil {
+		queued = v.queue.Pending()
+	}
+	pending := v.writer.Pending()
+	for index, n := range queued {
+		pending[index] += n
+	}
+	if err := v.poll(); err != nil && !os.IsNotExist(err) {
+		slog.Error("Verify: failed to read", "path", v.path, "err", err)
+	}
+
+	r := Report{
+		CheckedAt:   time.Now().UTC(),
+		Checks:      v.report.Checks + 1,
+		Transformed: transformed,
+		Copies:      copies,
+		File:        v.total,
+		Unparseable: v.unparseable,
+		Drifting:    []string{},
+	}
+	indexes := make(map[string]bool)
+	for _, m := range []map[string]int64{byIndex, pending, v.counts} {
+		for index := range m {
+			indexes[index] = true
+		}
+	}
+	for index := range indexes {
+		ir := IndexReport{Index: index, Metrics: byIndex[index], File: v.counts[index], Buffered: pending[index]}
+		ir.Drift = ir.Metrics - ir.File - ir.Buffered
+		r.Buffered += ir.Buffered
+		r.Indexes = append(r.Indexes, ir)
+	}
+	sort.Slice(r.Indexes, func(i, j int) bool { return r.Indexes[i].Index < r.Indexes[j].Index })
+	r.Drift = r.Transformed + r.Copies - r.File - r.Buffered
+
+	drift := map[string]int64{totalKey: r.Drift}
+	for _, ir := range r.Indexes {
+		drift[ir.Index] = ir.Drift
+		v.metrics.OutputDrift.WithLabelValues(ir.Index).Set(float64(ir.Drift))
+	}
+	r.Drifting = v.alert(drift, r)
+
+	v.lastDrift = drift
+	v.report = r
+	return r
+}
+
+// alert logs keys whose drift persisted across two checks, once per change, and
+// recoveries of previously reported keys. Returns the persisting keys.
+func (v *Verifier) alert(drift map[string]int64, r Report) []string {
+	drifting := []string{}
+	keys := make([]string, 0, len(drift))
+	for key := range drift {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		d := drift[key]
+		if d != 0 && v.lastDrift[key] != 0 {
+			drifting = append(drifting, key)
+			if v.alerted[key] != d {
+				v.alerted[key] = d
+				slog.Warn("Verify: DRIFT between counters and output file", append([]any{"key", key, "path", v.path, "drift", d}, summarize(key, r)...)...)
+			}
+		} else if d == 0 && v.alerted[key] != 0 {
+			delete(v.alerted, key)
+			slog.Info("Verify: back in sync with output file", "key", key, "path", v.path)
+		}
+	}
+	return drifting
+}
+
+// summarize returns the counts behind a key's drift as log attributes
+func summarize(key string, r Report) []any {
+	if key == totalKey {
+		return countAttrs(r.Transformed+r.Copies, r.File, r.Buffered)
+	}
+	for _, ir := range r.Indexes {
+		if ir.Index == key {
+			return countAttrs(ir.Metrics, ir.File, ir.Buffered)
+		}
+	}
+	return nil
+}
+
+func countAttrs(m, f, b int64) []any {
+	return []any{"metrics", m, "file", f, "buffered", b}
+}
+
+// Report returns the result of the latest check
+func (v *Verifier) Report() Report {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.report
+}
+
+// poll reads lines appended since the last poll, following rotation by
+// draining the renamed or finished file before switching to the new one.
+// Caller holds mu.
+func (v *Verifier) poll() error {
+	if v.writer != nil {
+		v.path = v.writer.Path() // Moves on when a templated file name changes
+	}
+	if v.file == nil {
+		f, err := os.Open(v.path)
+		if err != nil {
+			return err
+		}
+		v.file, v.offset, v.partial = f, 0, nil
+	}
+	if err := v.readFile(); err != nil {
+		return err
+	}
+
+	current, err := os.Stat(v.path)
+	if err != nil {
+		return err
+	}
+	held, err := v.file.Stat()
+	if err != nil {
+		return err
+	}
+	if os.SameFile(current, held) {
+		return nil
+	}
+
+	// Rotated: anything written before the rename is in the held file
+	if err := v.readFile(); err != nil {
+		return err
+	}
+	v.file.Close()
+	v.file = nil
+	return v.poll()
+}
+
+// readFile reads the held file from the last offset, restarting if it shrank
+func (v *Verifier) readFile() error {
+	info, err := v.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < v.offset {
+		v.offset, v.partial = 0, nil
+	}
+	if info.Size() == v.offset {
+		return nil
+	}
+	if _, err := v.file.Seek(v.offset, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(v.file)
+	if err != nil {
+		return err
+	}
+	v.offset += int64(len(data))
+
+	data = append(v.partial, data...)
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		v.partial = data
+		return nil
+	}
+	v.partial = append([]byte(nil), data[end+1:]...)
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		v.countLine(line)
+	}
+	return nil
+}
+
+// countLine counts one output line under its routing index
+func (v *Verifier) countLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	v.total++
+	var entry struct {
+		Routing output.RoutingInfo `json:"routing"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil || entry.Routing.Index == "" {
+		v.unparseable++
+		return
+	}
+	v.counts[entry.Routing.Index]++
+}
+
+// Close releases the output file
+func (v *Verifier) Close() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.file != nil {
+		v.file.Close()
+		v.file = nil
+	}
+}
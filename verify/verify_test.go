@@ -0,0 +1,167 @@
+// ABOUTME: Tests for continuous output verification.
+// ABOUTME: Covers in-sync counting, persistent drift detection, buffering, queueing, and rotation.
+
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"otlp-mock-receiver/metrics"
+	"otlp-mock-receiver/output"
+)
+
+// setup returns a verifier over a fresh writer with an hour-long flush interval,
+// so tests control when entries reach the file
+func setup(t *testing.T, bufferSize int) (*Verifier, *metrics.Metrics, *output.JSONWriter, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+	w, err := output.NewJSONWriter(path, output.FormatJSONL, bufferSize, time.Hour, 100*1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	m := metrics.New()
+	v := New(path, m, w)
+	t.Cleanup(v.Close)
+	return v, m, w, path
+}
+
+// deliver counts and writes one record the way the receiver does
+func deliver(m *metrics.Metrics, w *output.JSONWriter, index string) {
+	m.LogsTransformed.Inc()
+	m.LogsByIndex.WithLabelValues(index).Inc()
+	w.Write(&output.LogEntry{Body: "x", Routing: output.RoutingInfo{Index: index, Rule: "r"}})
+}
+
+func TestVerifier_InSync(t *testing.T) {
+	v, m, w, _ := setup(t, 2)
+	deliver(m, w, "tas_logs")
+	deliver(m, w, "tas_errors")
+	deliver(m, w, "tas_logs") // Stays buffered
+
+	r := v.Check()
+	if r.Transformed != 3 || r.File != 2 || r.Buffered != 1 || r.Drift != 0 {
+		t.Errorf("report = %+v, want 3 transformed, 2 in file, 1 buffered, no drift", r)
+	}
+	if len(r.Indexes) != 2 || r.Indexes[0].Index != "tas_errors" || r.Indexes[1].Buffered != 1 {
+		t.Errorf("indexes = %+v", r.Indexes)
+	}
+	if len(r.Drifting) != 0 {
+		t.Errorf("Drifting = %v, want none", r.Drifting)
+	}
+}
+
+// heldSink passes entries to the writer once released
+type heldSink struct {
+	*output.JSONWriter
+	release chan struct{}
+}
+
+func (h heldSink) Write(entry *output.LogEntry) error {
+	<-h.release
+	return h.JSONWriter.Write(entry)
+}
+
+func TestVerifier_CountsQueuedAsBuffered(t *testing.T) {
+	v, m, w, _ := setup(t, 1)
+	held := heldSink{w, make(chan struct{})}
+	q, err := output.NewQueue("file", held, 10, output.QueueBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.SetQueue(q)
+	for range 2 {
+		m.LogsTransformed.Inc()
+		m.LogsByIndex.WithLabelValues("tas_logs").Inc()
+		q.Write(&output.LogEntry{Body: "x", Routing: output.RoutingInfo{Index: "tas_logs", Rule: "r"}})
+	}
+
+	r := v.Check()
+	if r.Buffered != 2 || r.Drift != 0 {
+		t.Errorf("report = %+v, want 2 buffered in the queue, no drift", r)
+	}
+	close(held.release)
+	q.Flush()
+	if r := v.Check(); r.File != 2 || r.Buffered != 0 || r.Drift != 0 {
+		t.Errorf("report = %+v after flush, want 2 in file, no drift", r)
+	}
+}
+
+func TestVerifier_FanOutCopies(t *testing.T) {
+	v, m, w, _ := setup(t, 1)
+	deliver(m, w, "tas_logs")
+	// A record copied to tas_archive by a continue rule: one record, two lines
+	m.LogsFanoutCopies.Inc()
+	m.LogsByIndex.WithLabelValues("tas_archive").Inc()
+	w.Write(&output.LogEntry{Body: "x", Routing: output.RoutingInfo{Index: "tas_archive", Rule: "archive"}})
+
+	r := v.Check()
+	if r.Transformed != 1 || r.Copies != 1 || r.File != 2 || r.Drift != 0 {
+		t.Errorf("report = %+v, want 1 transformed, 1 copy, 2 in file, no drift", r)
+	}
+}
+
+func TestVerifier_IgnoresExistingContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+	os.WriteFile(path, []byte(`{"routing":{"index":"old"}}`+"\n"), 0644)
+	w, _ := output.NewJSONWriter(path, output.FormatJSONL, 1, time.Hour, 100*1024*1024)
+	defer w.Close()
+	v := New(path, metrics.New(), w)
+	defer v.Close()
+
+	if r := v.Check(); r.File != 0 || r.Drift != 0 {
+		t.Errorf("lines from before startup should not count: %+v", r)
+	}
+}
+
+func TestVerifier_PersistentDrift(t *testing.T) {
+	v, m, w, _ := setup(t, 1)
+	deliver(m, w, "tas_logs")
+	// Counted but never written, as with a silent write failure
+	m.LogsTransformed.Inc()
+	m.LogsByIndex.WithLabelValues("tas_logs").Inc()
+
+	if r := v.Check(); r.Drift != 1 || len(r.Drifting) != 0 {
+		t.Errorf("first check: drift %d, drifting %v; want 1 and not yet reported", r.Drift, r.Drifting)
+	}
+	r := v.Check()
+	if !slices.Equal(r.Drifting, []string{"tas_logs", "total"}) {
+		t.Errorf("second check: drifting %v, want [tas_logs total]", r.Drifting)
+	}
+
+	// The missing record shows up: back in sync
+	w.Write(&output.LogEntry{Routing: output.RoutingInfo{Index: "tas_logs"}})
+	if r := v.Check(); r.Drift != 0 || len(r.Drifting) != 0 {
+		t.Errorf("after recovery: %+v", r)
+	}
+}
+
+func TestVerifier_FollowsRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+	w, _ := output.NewJSONWriter(path, output.FormatJSONL, 1, time.Hour, 200)
+	defer w.Close()
+	m := metrics.New()
+	v := New(path, m, w)
+	defer v.Close()
+
+	// Each entry is ~100 bytes, so the file rotates every couple of writes;
+	// checking only at the end exercises draining the rotated file
+	for range 3 {
+		deliver(m, w, "tas_logs")
+	}
+	v.Check()
+	for range 3 {
+		deliver(m, w, "tas_logs")
+	}
+	r := v.Check()
+	if r.File != 6 || r.Drift != 0 {
+		t.Errorf("report after rotation = %+v, want 6 in file and no drift", r)
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("expected the writer to have rotated: %v", err)
+	}
+}